@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package asm gives llgo programs inline assembly, for the code of devices
+// and system calls that Go can't express: the compiler recognizes the calls
+// of Inline and Constraint, and lowers them to LLVM inline assembly, which is
+// emitted in place of the calls. The assembly is written for the target, in
+// the AT&T syntax on x86, eg.
+//
+//	asm.Inline("wfi")
+//	n := asm.Constraint("syscall", "={rax},{rax},{rdi},{rsi},{rdx},~{rcx},~{r11},~{memory}",
+//		1, 1, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+//
+// The code and the constraints of a call must be constants, and the operands
+// of Constraint must be passed as separate arguments, not as a slice. The
+// functions can't be called by the Go toolchain, for which they panic.
+package asm
+
+// Inline executes the assembly code, which has no operands.
+func Inline(code string) {
+	panic("asm.Inline is only supported by llgo")
+}
+
+// Constraint executes the assembly code with the operands args, and returns
+// its output, if it has one, or else 0. constraints binds the output and the
+// operands to registers or memory, and lists the registers and the memory
+// that the assembly clobbers, as the constraints of LLVM inline assembly do
+// (see "Inline Assembler Expressions" in the LLVM Language Reference): the
+// first constraint is the one of the output if it starts with "=", and the
+// next ones are those of args, in order.
+func Constraint(code, constraints string, args ...uintptr) uintptr {
+	panic("asm.Constraint is only supported by llgo")
+}
@@ -41,8 +41,15 @@ type Context struct {
 //
 // If an error occurs, Import returns a non-nil error and a non-nil
 // *Package containing partial information.
+//
+// Files are selected by the GOOS, GOARCH and build tags of the context, or of
+// build.Default if it has none.
 func (ctxt Context) Import(path string, srcDir string, mode ImportMode) (ret Package, err error) {
-	pkg, err := build.Import(path, srcDir, mode)
+	bctx := ctxt.Context
+	if bctx == nil {
+		bctx = &build.Default
+	}
+	pkg, err := bctx.Import(path, srcDir, mode)
 	if err != nil {
 		return
 	}
@@ -17,6 +17,17 @@
 package llgo
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/internal/build"
+	"github.com/goplus/llgo/internal/mod"
 	"github.com/goplus/llgo/x/gocmd"
 	"github.com/goplus/mod/gopmod"
 )
@@ -30,16 +41,358 @@ func NotFound(err error) bool {
 
 // -----------------------------------------------------------------------------
 
+// BuildDir builds the Go package in dir, which must be in a Go module. If it
+// is a main package, it is linked to an executable.
 func BuildDir(dir string, conf *Config, build *gocmd.BuildConfig) (err error) {
-	panic("todo")
+	m, pkgPath, err := mod.Load(dir)
+	if err != nil {
+		return
+	}
+	return buildPkgs(m.Root(), nil, []string{pkgPath}, conf, build)
 }
 
+// BuildPkgPath builds the Go package pkgPath, which is resolved in the module
+// that contains workDir, or in GOPATH if workDir isn't in a module. If it is
+// a main package, it is linked to an executable.
 func BuildPkgPath(workDir, pkgPath string, conf *Config, build *gocmd.BuildConfig) (err error) {
-	panic("todo")
+	dir, env, err := pkgPathEnv(workDir)
+	if err != nil {
+		return
+	}
+	return buildPkgs(dir, env, []string{pkgPath}, conf, build)
+}
+
+// pkgPathEnv returns the directory and the environment in which package paths
+// are resolved for workDir.
+func pkgPathEnv(workDir string) (dir string, env []string, err error) {
+	if workDir == "" {
+		workDir = "."
+	}
+	m, _, err := mod.Load(workDir)
+	if err == nil {
+		return m.Root(), nil, nil
+	}
+	if NotFound(err) {
+		return workDir, append(os.Environ(), "GO111MODULE=off"), nil
+	}
+	return
 }
 
+// BuildFiles builds the Go files of a main package to an executable. They are
+// loaded in the module that contains them, or in a temporary module if there
+// is none.
 func BuildFiles(files []string, conf *Config, build *gocmd.BuildConfig) (err error) {
-	panic("todo")
+	dir, patterns, cleanup, err := filesEnv(files)
+	if err != nil {
+		return
+	}
+	defer cleanup()
+	return buildPkgs(dir, nil, patterns, conf, build)
+}
+
+// filesEnv returns the directory in which Go files are loaded, and the
+// patterns that specify them. cleanup removes the temporary module, if any.
+func filesEnv(files []string) (dir string, patterns []string, cleanup func(), err error) {
+	if len(files) == 0 {
+		err = errors.New("no Go files listed")
+		return
+	}
+	patterns = make([]string, len(files))
+	for i, file := range files {
+		if patterns[i], err = filepath.Abs(file); err != nil {
+			return
+		}
+	}
+	cleanup = func() {}
+	dir = filepath.Dir(patterns[0])
+	m, _, err := mod.Load(dir)
+	if err == nil {
+		dir = m.Root()
+	} else if NotFound(err) {
+		if dir, err = mod.TempModule(); err == nil {
+			tmp := dir
+			cleanup = func() { os.RemoveAll(tmp) }
+		}
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+// InstallDir builds the Go package in dir, which must be in a Go module. If it
+// is a main package, the executable is installed to GOBIN, or $GOPATH/bin if
+// GOBIN isn't set.
+func InstallDir(dir string, conf *Config, install *gocmd.InstallConfig) (err error) {
+	m, pkgPath, err := mod.Load(dir)
+	if err != nil {
+		return
+	}
+	return installPkgs(m.Root(), nil, []string{pkgPath}, conf, install)
+}
+
+// InstallPkgPath builds the Go package pkgPath as BuildPkgPath does, and
+// installs its executable as InstallDir does. As with go install, if pkgPath
+// has a version suffix (eg. example.com/cmd@v1.2.0 or example.com/cmd@latest),
+// it is built in module mode outside of any module, so the go.mod of workDir
+// is ignored.
+func InstallPkgPath(workDir, pkgPath string, conf *Config, install *gocmd.InstallConfig) (err error) {
+	if strings.Contains(pkgPath, "@") {
+		return installVersion(pkgPath, conf, install)
+	}
+	dir, env, err := pkgPathEnv(workDir)
+	if err != nil {
+		return
+	}
+	return installPkgs(dir, env, []string{pkgPath}, conf, install)
+}
+
+func installVersion(query string, conf *Config, install *gocmd.InstallConfig) (err error) {
+	dir, err := mod.TempModule()
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	if err = mod.Get(dir, query); err != nil {
+		return
+	}
+	pkgPath, _, _ := strings.Cut(query, "@")
+	env := append(os.Environ(), "GOWORK=off")
+	return installPkgs(dir, env, []string{pkgPath}, conf, install)
+}
+
+func installPkgs(dir string, env, patterns []string, conf *Config, install *gocmd.InstallConfig) error {
+	bin, err := gobin()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(bin, 0755); err != nil {
+		return err
+	}
+	return buildPkgs(dir, env, patterns, conf, &gocmd.BuildConfig{
+		Output:       bin + string(filepath.Separator),
+		ForceRebuild: install.ForceRebuild,
+	})
+}
+
+// gobin returns the directory to which executables are installed.
+func gobin() (string, error) {
+	out, err := exec.Command("go", "env", "GOBIN", "GOPATH").Output()
+	if err != nil {
+		return "", err
+	}
+	vals := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(vals) != 2 {
+		return "", fmt.Errorf("unexpected output of go env: %q", out)
+	}
+	if vals[0] != "" {
+		return vals[0], nil
+	}
+	gopath := filepath.SplitList(vals[1])
+	if len(gopath) == 0 {
+		return "", errors.New("GOPATH is not set")
+	}
+	return filepath.Join(gopath[0], "bin"), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// RunDir builds the main package in dir, which must be in a Go module, to a
+// temporary executable and runs it with args. It returns the exit code of the
+// program, or an error if it couldn't be built or started.
+func RunDir(dir string, args []string, conf *Config, run *gocmd.RunConfig) (exitCode int, err error) {
+	m, pkgPath, err := mod.Load(dir)
+	if err != nil {
+		return
+	}
+	return runPkgs(m.Root(), []string{pkgPath}, args, conf, run)
+}
+
+// RunFiles builds the Go files of a main package as BuildFiles does, to a
+// temporary executable, and runs it as RunDir does.
+func RunFiles(files, args []string, conf *Config, run *gocmd.RunConfig) (exitCode int, err error) {
+	dir, patterns, cleanup, err := filesEnv(files)
+	if err != nil {
+		return
+	}
+	defer cleanup()
+	return runPkgs(dir, patterns, args, conf, run)
+}
+
+func runPkgs(dir string, patterns, args []string, conf *Config, run *gocmd.RunConfig) (exitCode int, err error) {
+	bconf, err := buildConfig(dir, nil, conf)
+	if err != nil {
+		return
+	}
+	bconf.ForceRebuild = run.ForceRebuild
+	bconf.NeedMain = true
+	return buildAndRun(func(exe string) error {
+		bconf.Output = exe
+		return build.Do(patterns, bconf)
+	}, args, run)
+}
+
+// buildAndRun builds an executable to a temporary file by doBuild, and runs it
+// with args.
+func buildAndRun(doBuild func(exe string) error, args []string, run *gocmd.RunConfig) (exitCode int, err error) {
+	tmpDir, err := os.MkdirTemp("", "llgo-run-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	exe := filepath.Join(tmpDir, "main")
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	if err = doBuild(exe); err != nil {
+		return
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Env = run.Env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = run.Stdin, run.Stdout, run.Stderr
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if err = cmd.Run(); err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			// A program killed by a signal has no exit code: as with go run,
+			// the signal is reported, and the exit code is 1.
+			if exitCode = e.ExitCode(); exitCode < 0 {
+				fmt.Fprintln(cmd.Stderr, e)
+				exitCode = 1
+			}
+			return exitCode, nil
+		}
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+// TestDir builds the test binary of the Go package in dir, which must be in a
+// Go module, and runs it. It returns the exit code of the test binary, which
+// is 0 if all tests pass.
+func TestDir(dir string, conf *Config, test *gocmd.TestConfig) (exitCode int, err error) {
+	m, pkgPath, err := mod.Load(dir)
+	if err != nil {
+		return
+	}
+	return testPkgs(m.Root(), nil, []string{pkgPath}, conf, test)
+}
+
+// TestPkgPath tests the Go package pkgPath, which is resolved as BuildPkgPath
+// does, as TestDir does.
+func TestPkgPath(workDir, pkgPath string, conf *Config, test *gocmd.TestConfig) (exitCode int, err error) {
+	dir, env, err := pkgPathEnv(workDir)
+	if err != nil {
+		return
+	}
+	return testPkgs(dir, env, []string{pkgPath}, conf, test)
+}
+
+func testPkgs(dir string, env, patterns []string, conf *Config, test *gocmd.TestConfig) (exitCode int, err error) {
+	var args []string
+	if test.Run != "" {
+		args = append(args, "-test.run="+test.Run)
+	}
+	if test.Verbose {
+		args = append(args, "-test.v")
+	}
+	if test.CoverProfile != "" {
+		profile, err := filepath.Abs(test.CoverProfile)
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, "-test.coverprofile="+profile)
+	}
+	bconf, err := buildConfig(dir, env, conf)
+	if err != nil {
+		return
+	}
+	bconf.ForceRebuild = test.ForceRebuild
+	bconf.Cover = test.Cover || test.CoverProfile != ""
+	return buildAndRun(func(exe string) error {
+		bconf.Output = exe
+		return build.Test(patterns, bconf)
+	}, args, &test.RunConfig)
+}
+
+// -----------------------------------------------------------------------------
+
+func buildPkgs(dir string, env, patterns []string, conf *Config, bc *gocmd.BuildConfig) error {
+	bconf, err := buildConfig(dir, env, conf)
+	if err != nil {
+		return err
+	}
+	if bc.Emit != "" {
+		if bconf.OutputKind, err = build.ParseOutputKind(bc.Emit); err != nil {
+			return err
+		}
+	}
+	bconf.Output = bc.Output
+	bconf.ForceRebuild = bc.ForceRebuild
+	bconf.BuildMode = bc.BuildMode
+	bconf.Work = bc.Work
+	bconf.TrimPath = bc.TrimPath
+	return build.Do(patterns, bconf)
+}
+
+// buildConfig returns the configuration of building packages, which are loaded
+// in dir with env, by conf.
+func buildConfig(dir string, env []string, conf *Config) (*build.Config, error) {
+	bconf := &build.Config{
+		Dir:    dir,
+		Env:    env,
+		Target: conf.Target,
+		Passes: conf.Passes,
+
+		DeadCodeElim: conf.DeadCodeElim,
+
+		WasmExecModel: conf.WasmExecModel,
+		Baremetal:     conf.Baremetal,
+		LinkerScript:  conf.LinkerScript,
+		LTO:           conf.LTO,
+		GC:            conf.GC,
+		RelocModel:    conf.RelocModel,
+		Sanitizer:     conf.Sanitizer,
+		Race:          conf.Race,
+		Hardening:     conf.Hardening,
+		Static:        conf.Static,
+		CPUModel:      conf.CPUModel,
+		Features:      conf.Features,
+		FloatABI:      conf.FloatABI,
+		Tags:          conf.Tags,
+		ModFlag:       conf.ModFlag,
+		Diagnostics:   conf.Diagnostics,
+
+		Conf: &cl.Config{
+			MathIntrinsics:  conf.MathIntrinsics,
+			DebugInfo:       conf.DebugInfo,
+			ContinueOnError: conf.ContinueOnError,
+			Preempt:         conf.Preempt,
+			PreemptLoops:    conf.PreemptLoops,
+			Reflect:         conf.Reflect,
+			Verify:          conf.Verify,
+		},
+	}
+	if conf.OptLevel != "" {
+		var err error
+		if bconf.OptLevel, err = build.ParseOptLevel(conf.OptLevel); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Overlay != "" {
+		var err error
+		if bconf.Overlay, err = build.ReadOverlay(conf.Overlay); err != nil {
+			return nil, err
+		}
+	}
+	return bconf, nil
 }
 
 // -----------------------------------------------------------------------------
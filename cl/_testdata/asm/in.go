@@ -0,0 +1,13 @@
+package main
+
+import "github.com/goplus/llgo/asm"
+
+func write(fd, p, n uintptr) uintptr {
+	return asm.Constraint("syscall", "={rax},{rax},{rdi},{rsi},{rdx},~{rcx},~{r11},~{memory}", 1, fd, p, n)
+}
+
+func main() {
+	asm.Inline("nop")
+	asm.Constraint("mfence", "~{memory}")
+	write(1, 0, 0)
+}
@@ -0,0 +1,35 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/asm.init"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.write(i64 %0, i64 %1, i64 %2) {
+_llgo_0:
+  %3 = call i64 asm sideeffect "syscall", "={rax},{rax},{rdi},{rsi},{rdx},~{rcx},~{r11},~{memory}"(i64 1, i64 %0, i64 %1, i64 %2)
+  ret i64 %3
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  call void asm sideeffect "nop", ""()
+  call void asm sideeffect "mfence", "~{memory}"()
+  %3 = call fastcc i64 @main.write(i64 1, i64 0, i64 0)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/asm.init"()
@@ -0,0 +1,17 @@
+package main
+
+import "sync/atomic"
+
+func incr(p *int64) int64 {
+	return atomic.AddInt64(p, 1)
+}
+
+func cas(p *int32) bool {
+	return atomic.CompareAndSwapInt32(p, 0, 1)
+}
+
+func main() {
+	var n uint32
+	atomic.StoreUint32(&n, 100)
+	_ = atomic.SwapUint32(&n, atomic.LoadUint32(&n)+1)
+}
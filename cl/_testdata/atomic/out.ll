@@ -0,0 +1,46 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"sync/atomic.init"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @main.incr(ptr %0) {
+_llgo_0:
+  %1 = atomicrmw add ptr %0, i64 1 seq_cst, align 8
+  %2 = add i64 %1, 1
+  ret i64 %2
+}
+
+define i1 @main.cas(ptr %0) {
+_llgo_0:
+  %1 = cmpxchg ptr %0, i32 0, i32 1 seq_cst seq_cst, align 4
+  %2 = extractvalue { i32, i1 } %1, 1
+  ret i1 %2
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = alloca i32, align 4
+  call void @main.init()
+  store i32 0, ptr %3, align 4
+  store atomic i32 100, ptr %3 seq_cst, align 4
+  %4 = load atomic i32, ptr %3 seq_cst, align 4
+  %5 = add i32 %4, 1
+  %6 = atomicrmw xchg ptr %3, i32 %5 seq_cst, align 4
+  ret i32 0
+}
+
+declare void @"sync/atomic.init"()
@@ -0,0 +1,16 @@
+package main
+
+type divT struct {
+	Quot, Rem int32
+}
+
+//llgo:link C.div
+func div(num, denom int32) divT
+
+func quot(a, b int32) int32 {
+	return div(a, b).Quot
+}
+
+func main() {
+	quot(7, 2)
+}
@@ -0,0 +1,38 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%divT = type { i32, i32 }
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+declare i64 @div(i32, i32)
+
+define fastcc i32 @main.quot(i32 %0, i32 %1) {
+_llgo_0:
+  %2 = alloca %divT, align 8
+  %3 = call i64 @div(i32 %0, i32 %1)
+  store i64 %3, ptr %2, align 4
+  %4 = load %divT, ptr %2, align 4
+  %5 = extractvalue %divT %4, 0
+  ret i32 %5
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i32 @main.quot(i32 7, i32 2)
+  ret i32 0
+}
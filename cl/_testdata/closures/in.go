@@ -0,0 +1,12 @@
+package foo
+
+var f = func() int { return 1 }
+
+func g(n int) func(int) int {
+	return func(x int) int { return n + x }
+}
+
+func h(n int) int {
+	k := func() int { return n + 1 }
+	return k()
+}
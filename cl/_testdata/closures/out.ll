@@ -0,0 +1,73 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@foo.f = global { ptr, ptr } zeroinitializer
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  store { ptr, ptr } { ptr @"foo.init$1$stub", ptr null }, ptr @foo.f, align 8
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @"foo.init$1"() {
+_llgo_0:
+  ret i64 1
+}
+
+define { ptr, ptr } @foo.g(i64 %0) {
+_llgo_0:
+  %1 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 %0, ptr %1, align 4
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %3 = getelementptr inbounds { ptr }, ptr %2, i32 0, i32 0
+  store ptr %1, ptr %3, align 8
+  %4 = insertvalue { ptr, ptr } { ptr @"foo.g$1$stub", ptr undef }, ptr %2, 1
+  ret { ptr, ptr } %4
+}
+
+define i64 @"foo.g$1"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = load i64, ptr %0, align 4
+  %3 = add i64 %2, %1
+  ret i64 %3
+}
+
+define i64 @foo.h(i64 %0) {
+_llgo_0:
+  %1 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 %0, ptr %1, align 4
+  %2 = tail call i64 @"foo.h$1"(ptr %1)
+  ret i64 %2
+}
+
+define i64 @"foo.h$1"(ptr %0) {
+_llgo_0:
+  %1 = load i64, ptr %0, align 4
+  %2 = add i64 %1, 1
+  ret i64 %2
+}
+
+define private i64 @"foo.init$1$stub"(ptr %0) {
+_llgo_0:
+  %1 = tail call i64 @"foo.init$1"()
+  ret i64 %1
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+define private i64 @"foo.g$1$stub"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = getelementptr inbounds { ptr }, ptr %0, i32 0, i32 0
+  %3 = load ptr, ptr %2, align 8
+  %4 = tail call i64 @"foo.g$1"(ptr %3, i64 %1)
+  ret i64 %4
+}
@@ -0,0 +1,22 @@
+package main
+
+func mul(a, b complex128) complex128 {
+	return a * b
+}
+
+func quo(a, b complex64) complex64 {
+	return a / b
+}
+
+func neg(c complex128) complex128 {
+	return -c
+}
+
+func eq(a, b complex128) bool {
+	return a == b
+}
+
+func main() {
+	c := mul(complex(1, 2), 3i)
+	_ = real(c) + imag(c)
+}
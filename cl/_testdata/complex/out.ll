@@ -0,0 +1,95 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc { double, double } @main.mul({ double, double } %0, { double, double } %1) {
+_llgo_0:
+  %2 = extractvalue { double, double } %0, 0
+  %3 = extractvalue { double, double } %0, 1
+  %4 = extractvalue { double, double } %1, 0
+  %5 = extractvalue { double, double } %1, 1
+  %6 = fmul double %2, %4
+  %7 = fmul double %3, %5
+  %8 = fsub double %6, %7
+  %9 = fmul double %2, %5
+  %10 = fmul double %3, %4
+  %11 = fadd double %9, %10
+  %12 = insertvalue { double, double } undef, double %8, 0
+  %13 = insertvalue { double, double } %12, double %11, 1
+  ret { double, double } %13
+}
+
+define { float, float } @main.quo({ float, float } %0, { float, float } %1) {
+_llgo_0:
+  %2 = extractvalue { float, float } %0, 0
+  %3 = extractvalue { float, float } %0, 1
+  %4 = fpext float %2 to double
+  %5 = fpext float %3 to double
+  %6 = insertvalue { double, double } undef, double %4, 0
+  %7 = insertvalue { double, double } %6, double %5, 1
+  %8 = extractvalue { float, float } %1, 0
+  %9 = extractvalue { float, float } %1, 1
+  %10 = fpext float %8 to double
+  %11 = fpext float %9 to double
+  %12 = insertvalue { double, double } undef, double %10, 0
+  %13 = insertvalue { double, double } %12, double %11, 1
+  %14 = call { double, double } @"github.com/goplus/llgo/internal/runtime.Complex128Div"({ double, double } %7, { double, double } %13)
+  %15 = extractvalue { double, double } %14, 0
+  %16 = extractvalue { double, double } %14, 1
+  %17 = fptrunc double %15 to float
+  %18 = fptrunc double %16 to float
+  %19 = insertvalue { float, float } undef, float %17, 0
+  %20 = insertvalue { float, float } %19, float %18, 1
+  ret { float, float } %20
+}
+
+define { double, double } @main.neg({ double, double } %0) {
+_llgo_0:
+  %1 = extractvalue { double, double } %0, 0
+  %2 = extractvalue { double, double } %0, 1
+  %3 = fneg double %1
+  %4 = fneg double %2
+  %5 = insertvalue { double, double } undef, double %3, 0
+  %6 = insertvalue { double, double } %5, double %4, 1
+  ret { double, double } %6
+}
+
+define i1 @main.eq({ double, double } %0, { double, double } %1) {
+_llgo_0:
+  %2 = extractvalue { double, double } %0, 0
+  %3 = extractvalue { double, double } %0, 1
+  %4 = extractvalue { double, double } %1, 0
+  %5 = extractvalue { double, double } %1, 1
+  %6 = fcmp oeq double %2, %4
+  %7 = fcmp oeq double %3, %5
+  %8 = and i1 %6, %7
+  ret i1 %8
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc { double, double } @main.mul({ double, double } { double 1.000000e+00, double 2.000000e+00 }, { double, double } { double 0.000000e+00, double 3.000000e+00 })
+  %4 = extractvalue { double, double } %3, 0
+  %5 = extractvalue { double, double } %3, 1
+  %6 = extractvalue { double, double } %3, 0
+  %7 = extractvalue { double, double } %3, 1
+  %8 = fadd double %4, %7
+  ret i32 0
+}
+
+declare { double, double } @"github.com/goplus/llgo/internal/runtime.Complex128Div"({ double, double }, { double, double })
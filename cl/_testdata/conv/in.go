@@ -0,0 +1,34 @@
+package main
+
+func toBytes(s string) []byte {
+	return []byte(s)
+}
+
+func toString(b []byte) string {
+	return string(b)
+}
+
+func isFoo(b []byte) bool {
+	return string(b) == "foo"
+}
+
+func count(m map[string]int, b []byte) int {
+	return m[string(b)]
+}
+
+func first(s string) byte {
+	return []byte(s)[0]
+}
+
+func size(s string) int {
+	return len([]byte(s))
+}
+
+func main() {
+	b := toBytes("foo")
+	_ = toString(b)
+	_ = isFoo(b)
+	_ = count(make(map[string]int), b)
+	_ = first("bar")
+	_ = size("bar")
+}
@@ -0,0 +1,142 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [3 x i8] c"foo"
+@1 = private unnamed_addr constant [3 x i8] c"bar"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc { ptr, i64, i64 } @main.toBytes({ ptr, i64 } %0) {
+_llgo_0:
+  %1 = call { ptr, i64, i64 } @"github.com/goplus/llgo/internal/runtime.StringToBytes"({ ptr, i64 } %0)
+  ret { ptr, i64, i64 } %1
+}
+
+define fastcc { ptr, i64 } @main.toString({ ptr, i64, i64 } %0) {
+_llgo_0:
+  %1 = call { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.BytesToString"({ ptr, i64, i64 } %0)
+  ret { ptr, i64 } %1
+}
+
+define fastcc i1 @main.isFoo({ ptr, i64, i64 } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, i64, i64 } %0, 1
+  %2 = extractvalue { ptr, i64, i64 } %0, 0
+  %3 = insertvalue { ptr, i64 } undef, ptr %2, 0
+  %4 = insertvalue { ptr, i64 } %3, i64 %1, 1
+  %5 = call i1 @"github.com/goplus/llgo/internal/runtime.StringEqual"({ ptr, i64 } %4, { ptr, i64 } { ptr @0, i64 3 })
+  ret i1 %5
+}
+
+define fastcc i64 @main.count(ptr %0, { ptr, i64, i64 } %1) {
+_llgo_0:
+  %2 = alloca { ptr, i64 }, align 8
+  %3 = alloca i64, align 8
+  %4 = extractvalue { ptr, i64, i64 } %1, 1
+  %5 = extractvalue { ptr, i64, i64 } %1, 0
+  %6 = insertvalue { ptr, i64 } undef, ptr %5, 0
+  %7 = insertvalue { ptr, i64 } %6, i64 %4, 1
+  store i64 0, ptr %3, align 4
+  store { ptr, i64 } %7, ptr %2, align 8
+  %8 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %0, ptr %2, ptr %3)
+  %9 = load i64, ptr %3, align 4
+  ret i64 %9
+}
+
+define fastcc i8 @main.first({ ptr, i64 } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, i64 } %0, 1
+  %2 = extractvalue { ptr, i64 } %0, 0
+  %3 = insertvalue { ptr, i64, i64 } undef, ptr %2, 0
+  %4 = insertvalue { ptr, i64, i64 } %3, i64 %1, 1
+  %5 = insertvalue { ptr, i64, i64 } %4, i64 %1, 2
+  %6 = extractvalue { ptr, i64, i64 } %5, 1
+  %7 = icmp uge i64 0, %6
+  br i1 %7, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64 0, i64 %6)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %8 = extractvalue { ptr, i64, i64 } %5, 0
+  %9 = getelementptr inbounds i8, ptr %8, i64 0
+  %10 = load i8, ptr %9, align 1
+  ret i8 %10
+}
+
+define fastcc i64 @main.size({ ptr, i64 } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, i64 } %0, 1
+  %2 = extractvalue { ptr, i64 } %0, 0
+  %3 = insertvalue { ptr, i64, i64 } undef, ptr %2, 0
+  %4 = insertvalue { ptr, i64, i64 } %3, i64 %1, 1
+  %5 = insertvalue { ptr, i64, i64 } %4, i64 %1, 2
+  %6 = extractvalue { ptr, i64, i64 } %5, 1
+  ret i64 %6
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc { ptr, i64, i64 } @main.toBytes({ ptr, i64 } { ptr @0, i64 3 })
+  %4 = call fastcc { ptr, i64 } @main.toString({ ptr, i64, i64 } %3)
+  %5 = call fastcc i1 @main.isFoo({ ptr, i64, i64 } %3)
+  %6 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 16, i64 8, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null }, i64 0)
+  %7 = call fastcc i64 @main.count(ptr %6, { ptr, i64, i64 } %3)
+  %8 = call fastcc i8 @main.first({ ptr, i64 } { ptr @1, i64 3 })
+  %9 = call fastcc i64 @main.size({ ptr, i64 } { ptr @1, i64 3 })
+  ret i32 0
+}
+
+declare { ptr, i64, i64 } @"github.com/goplus/llgo/internal/runtime.StringToBytes"({ ptr, i64 })
+
+declare { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.BytesToString"({ ptr, i64, i64 })
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.StringEqual"({ ptr, i64 }, { ptr, i64 })
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr, ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64, i64)
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.string(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64, i64, { ptr, ptr }, { ptr, ptr }, i64)
@@ -0,0 +1,13 @@
+package foo
+
+import "unsafe"
+
+func addr(p *int) uintptr { return uintptr(unsafe.Pointer(p)) }
+
+func ptr(u uintptr) *int { return (*int)(unsafe.Pointer(u)) }
+
+func float(i int8) float64 { return float64(i) }
+
+func trunc(f float32) uint16 { return uint16(f) }
+
+func widen(i int32) (int64, uint8) { return int64(i), uint8(i) }
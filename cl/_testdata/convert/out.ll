@@ -0,0 +1,50 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.addr(ptr %0) {
+_llgo_0:
+  %1 = ptrtoint ptr %0 to i64
+  ret i64 %1
+}
+
+define ptr @foo.ptr(i64 %0) {
+_llgo_0:
+  %1 = inttoptr i64 %0 to ptr
+  ret ptr %1
+}
+
+define double @foo.float(i8 %0) {
+_llgo_0:
+  %1 = sitofp i8 %0 to double
+  ret double %1
+}
+
+define i16 @foo.trunc(float %0) {
+_llgo_0:
+  %1 = fptoui float %0 to i16
+  ret i16 %1
+}
+
+define { i64, i8 } @foo.widen(i32 %0) {
+_llgo_0:
+  %1 = sext i32 %0 to i64
+  %2 = trunc i32 %0 to i8
+  %mrv = insertvalue { i64, i8 } undef, i64 %1, 0
+  %mrv1 = insertvalue { i64, i8 } %mrv, i8 %2, 1
+  ret { i64, i8 } %mrv1
+}
@@ -0,0 +1,25 @@
+package main
+
+type small struct {
+	x, y int
+}
+
+type large struct {
+	buf [16]int64
+	n   int
+}
+
+func copySmall(dst, src *small) {
+	*dst = *src
+}
+
+func copyLarge(dst, src *large) {
+	*dst = *src
+}
+
+func copyBuf(dst *[16]int64, src *large) {
+	*dst = src.buf
+}
+
+func main() {
+}
@@ -0,0 +1,51 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%small = type { i64, i64 }
+%large = type { [16 x i64], i64 }
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @main.copySmall(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = load %small, ptr %1, align 4
+  store %small %2, ptr %0, align 4
+  ret void
+}
+
+define void @main.copyLarge(ptr %0, ptr %1) {
+_llgo_0:
+  call void @llvm.memcpy.p0.p0.i64(ptr align 4 %0, ptr align 4 %1, i64 136, i1 false)
+  ret void
+}
+
+define void @main.copyBuf(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %large, ptr %1, i32 0, i32 0
+  call void @llvm.memcpy.p0.p0.i64(ptr align 4 %0, ptr align 4 %2, i64 128, i1 false)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  ret i32 0
+}
+
+; Function Attrs: argmemonly nofree nounwind willreturn
+declare void @llvm.memcpy.p0.p0.i64(ptr noalias nocapture writeonly, ptr noalias nocapture readonly, i64, i1 immarg) #0
+
+attributes #0 = { argmemonly nofree nounwind willreturn }
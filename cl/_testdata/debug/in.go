@@ -0,0 +1,13 @@
+package main
+
+import "runtime/debug"
+
+func main() {
+	old := debug.SetGCPercent(50)
+	debug.FreeOSMemory()
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	if stats.NumGC > 0 {
+		debug.SetGCPercent(old)
+	}
+}
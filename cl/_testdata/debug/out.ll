@@ -0,0 +1,54 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%GCStats = type { %Time, i64, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 } }
+%Time = type { i64, i64, ptr }
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/internal/runtime.DebugInit"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  %3 = call i64 @"github.com/goplus/llgo/internal/runtime.DebugSetGCPercent"(i64 50)
+  call void @"github.com/goplus/llgo/internal/runtime.DebugFreeOSMemory"()
+  %4 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 112)
+  call void @"github.com/goplus/llgo/internal/runtime.DebugReadGCStats"(ptr %4)
+  %5 = getelementptr inbounds %GCStats, ptr %4, i32 0, i32 1
+  %6 = load i64, ptr %5, align 4
+  %7 = icmp sgt i64 %6, 0
+  br i1 %7, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %8 = call i64 @"github.com/goplus/llgo/internal/runtime.DebugSetGCPercent"(i64 %3)
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.DebugInit"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.DebugSetGCPercent"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.DebugFreeOSMemory"()
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.DebugReadGCStats"(ptr)
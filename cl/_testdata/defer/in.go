@@ -0,0 +1,23 @@
+package main
+
+var n int
+
+func f() {
+	n++
+}
+
+func h(d int) {
+	n += d
+}
+
+func g(x int) int {
+	defer f()
+	if x > 0 {
+		defer h(x * 2)
+	}
+	return x
+}
+
+func main() {
+	g(1)
+}
@@ -0,0 +1,149 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.n = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.f() {
+_llgo_0:
+  %0 = load i64, ptr @main.n, align 4
+  %1 = add i64 %0, 1
+  store i64 %1, ptr @main.n, align 4
+  ret void
+}
+
+define fastcc void @main.h(i64 %0) {
+_llgo_0:
+  %1 = load i64, ptr @main.n, align 4
+  %2 = add i64 %1, %0
+  store i64 %2, ptr @main.n, align 4
+  ret void
+}
+
+define fastcc i64 @main.g(i64 %0) personality ptr @__gcc_personality_v0 {
+_llgo_0:
+  %1 = alloca i64, align 8
+  %2 = alloca i8, align 1
+  store i8 0, ptr %2, align 1
+  store i64 0, ptr %1, align 4
+  %3 = load i8, ptr %2, align 1
+  %4 = or i8 %3, 1
+  store i8 %4, ptr %2, align 1
+  %5 = icmp sgt i64 %0, 0
+  br i1 %5, label %_llgo_2, label %_llgo_3
+
+_llgo_1:                                          ; preds = %_llgo_15
+  ret i64 0
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %6 = mul i64 %0, 2
+  store i64 %6, ptr %1, align 4
+  %7 = load i8, ptr %2, align 1
+  %8 = or i8 %7, 2
+  store i8 %8, ptr %2, align 1
+  br label %_llgo_3
+
+_llgo_3:                                          ; preds = %_llgo_2, %_llgo_0
+  %9 = load i8, ptr %2, align 1
+  %10 = and i8 %9, 2
+  %11 = icmp ne i8 %10, 0
+  br i1 %11, label %_llgo_5, label %_llgo_6
+
+_llgo_4:                                          ; preds = %_llgo_14, %_llgo_11, %_llgo_8, %_llgo_5
+  %12 = landingpad { ptr, i32 }
+          cleanup
+  %13 = extractvalue { ptr, i32 } %12, 0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr %13, ptr %2)
+  %14 = load i8, ptr %2, align 1
+  %15 = and i8 %14, 2
+  %16 = icmp ne i8 %15, 0
+  br i1 %16, label %_llgo_11, label %_llgo_12
+
+_llgo_5:                                          ; preds = %_llgo_3
+  %17 = and i8 %9, -3
+  store i8 %17, ptr %2, align 1
+  %18 = load i64, ptr %1, align 4
+  invoke fastcc void @main.h(i64 %18)
+          to label %_llgo_7 unwind label %_llgo_4
+
+_llgo_6:                                          ; preds = %_llgo_7, %_llgo_3
+  %19 = load i8, ptr %2, align 1
+  %20 = and i8 %19, 1
+  %21 = icmp ne i8 %20, 0
+  br i1 %21, label %_llgo_8, label %_llgo_9
+
+_llgo_7:                                          ; preds = %_llgo_5
+  br label %_llgo_6
+
+_llgo_8:                                          ; preds = %_llgo_6
+  %22 = and i8 %19, -2
+  store i8 %22, ptr %2, align 1
+  invoke fastcc void @main.f()
+          to label %_llgo_10 unwind label %_llgo_4
+
+_llgo_9:                                          ; preds = %_llgo_10, %_llgo_6
+  ret i64 %0
+
+_llgo_10:                                         ; preds = %_llgo_8
+  br label %_llgo_9
+
+_llgo_11:                                         ; preds = %_llgo_4
+  %23 = and i8 %14, -3
+  store i8 %23, ptr %2, align 1
+  %24 = load i64, ptr %1, align 4
+  invoke fastcc void @main.h(i64 %24)
+          to label %_llgo_13 unwind label %_llgo_4
+
+_llgo_12:                                         ; preds = %_llgo_13, %_llgo_4
+  %25 = load i8, ptr %2, align 1
+  %26 = and i8 %25, 1
+  %27 = icmp ne i8 %26, 0
+  br i1 %27, label %_llgo_14, label %_llgo_15
+
+_llgo_13:                                         ; preds = %_llgo_11
+  br label %_llgo_12
+
+_llgo_14:                                         ; preds = %_llgo_12
+  %28 = and i8 %25, -2
+  store i8 %28, ptr %2, align 1
+  invoke fastcc void @main.f()
+          to label %_llgo_16 unwind label %_llgo_4
+
+_llgo_15:                                         ; preds = %_llgo_16, %_llgo_12
+  %29 = call i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr %13)
+  br i1 %29, label %_llgo_1, label %_llgo_17
+
+_llgo_16:                                         ; preds = %_llgo_14
+  br label %_llgo_15
+
+_llgo_17:                                         ; preds = %_llgo_15
+  %30 = insertvalue { ptr, i32 } undef, ptr %13, 0
+  %31 = insertvalue { ptr, i32 } %30, i32 0, 1
+  resume { ptr, i32 } %31
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i64 @main.g(i64 1)
+  ret i32 0
+}
+
+declare i32 @__gcc_personality_v0()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr)
@@ -0,0 +1,18 @@
+package main
+
+var n int
+
+func add(d int) {
+	n += d
+}
+
+func count(k int) int {
+	for i := 0; i < k; i++ {
+		defer add(i)
+	}
+	return n
+}
+
+func main() {
+	count(3)
+}
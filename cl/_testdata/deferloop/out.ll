@@ -0,0 +1,136 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.n = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.add(i64 %0) {
+_llgo_0:
+  %1 = load i64, ptr @main.n, align 4
+  %2 = add i64 %1, %0
+  store i64 %2, ptr @main.n, align 4
+  ret void
+}
+
+define fastcc i64 @main.count(i64 %0) personality ptr @__gcc_personality_v0 {
+_llgo_0:
+  %1 = alloca ptr, align 8
+  store ptr null, ptr %1, align 8
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  %2 = phi i64 [ 0, %_llgo_0 ], [ %9, %_llgo_2 ]
+  %3 = icmp slt i64 %2, %0
+  br i1 %3, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  %4 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  %5 = getelementptr inbounds { ptr, i64, i64 }, ptr %4, i32 0, i32 0
+  %6 = load ptr, ptr %1, align 8
+  store ptr %6, ptr %5, align 8
+  %7 = getelementptr inbounds { ptr, i64, i64 }, ptr %4, i32 0, i32 1
+  store i64 0, ptr %7, align 4
+  %8 = getelementptr inbounds { ptr, i64, i64 }, ptr %4, i32 0, i32 2
+  store i64 %2, ptr %8, align 4
+  store ptr %4, ptr %1, align 8
+  %9 = add i64 %2, 1
+  br label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_1
+  %10 = load i64, ptr @main.n, align 4
+  br label %_llgo_6
+
+_llgo_4:                                          ; preds = %_llgo_13
+  ret i64 0
+
+_llgo_5:                                          ; preds = %_llgo_14, %_llgo_9
+  %11 = landingpad { ptr, i32 }
+          cleanup
+  %12 = extractvalue { ptr, i32 } %11, 0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr %12, ptr %1)
+  br label %_llgo_11
+
+_llgo_6:                                          ; preds = %_llgo_10, %_llgo_3
+  %13 = load ptr, ptr %1, align 8
+  %14 = icmp eq ptr %13, null
+  br i1 %14, label %_llgo_8, label %_llgo_7
+
+_llgo_7:                                          ; preds = %_llgo_6
+  %15 = getelementptr inbounds { ptr, i64 }, ptr %13, i32 0, i32 0
+  %16 = load ptr, ptr %15, align 8
+  store ptr %16, ptr %1, align 8
+  %17 = getelementptr inbounds { ptr, i64 }, ptr %13, i32 0, i32 1
+  %18 = load i64, ptr %17, align 4
+  br label %_llgo_9
+
+_llgo_8:                                          ; preds = %_llgo_6
+  ret i64 %10
+
+_llgo_9:                                          ; preds = %_llgo_7
+  %19 = getelementptr inbounds { ptr, i64, i64 }, ptr %13, i32 0, i32 2
+  %20 = load i64, ptr %19, align 4
+  invoke fastcc void @main.add(i64 %20)
+          to label %_llgo_10 unwind label %_llgo_5
+
+_llgo_10:                                         ; preds = %_llgo_9
+  br label %_llgo_6
+
+_llgo_11:                                         ; preds = %_llgo_15, %_llgo_5
+  %21 = load ptr, ptr %1, align 8
+  %22 = icmp eq ptr %21, null
+  br i1 %22, label %_llgo_13, label %_llgo_12
+
+_llgo_12:                                         ; preds = %_llgo_11
+  %23 = getelementptr inbounds { ptr, i64 }, ptr %21, i32 0, i32 0
+  %24 = load ptr, ptr %23, align 8
+  store ptr %24, ptr %1, align 8
+  %25 = getelementptr inbounds { ptr, i64 }, ptr %21, i32 0, i32 1
+  %26 = load i64, ptr %25, align 4
+  br label %_llgo_14
+
+_llgo_13:                                         ; preds = %_llgo_11
+  %27 = call i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr %12)
+  br i1 %27, label %_llgo_4, label %_llgo_16
+
+_llgo_14:                                         ; preds = %_llgo_12
+  %28 = getelementptr inbounds { ptr, i64, i64 }, ptr %21, i32 0, i32 2
+  %29 = load i64, ptr %28, align 4
+  invoke fastcc void @main.add(i64 %29)
+          to label %_llgo_15 unwind label %_llgo_5
+
+_llgo_15:                                         ; preds = %_llgo_14
+  br label %_llgo_11
+
+_llgo_16:                                         ; preds = %_llgo_13
+  %30 = insertvalue { ptr, i32 } undef, ptr %12, 0
+  %31 = insertvalue { ptr, i32 } %30, i32 0, 1
+  resume { ptr, i32 } %31
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i64 @main.count(i64 3)
+  ret i32 0
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare i32 @__gcc_personality_v0()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr)
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+type stringer interface {
+	String() string
+}
+
+func name() string {
+	var s stringer = syscall.SIGTERM
+	return s.String()
+}
+
+func signalName() string {
+	var sig os.Signal = syscall.SIGINT
+	var s stringer = sig
+	return s.String()
+}
+
+func main() {
+	name()
+	signalName()
+}
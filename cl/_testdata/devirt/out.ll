@@ -0,0 +1,45 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc { ptr, i64 } @main.name() {
+_llgo_0:
+  %0 = call { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SyscallSignalString"(i64 15)
+  ret { ptr, i64 } %0
+}
+
+define fastcc { ptr, i64 } @main.signalName() {
+_llgo_0:
+  %0 = call { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SyscallSignalString"(i64 2)
+  ret { ptr, i64 } %0
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  %3 = call fastcc { ptr, i64 } @main.name()
+  %4 = call fastcc { ptr, i64 } @main.signalName()
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+
+declare { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SyscallSignalString"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
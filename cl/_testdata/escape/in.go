@@ -0,0 +1,20 @@
+package main
+
+var g *int
+
+func sum() int {
+	p := new([2]int)
+	p[0] = 1
+	p[1] = 2
+	return p[0] + p[1]
+}
+
+func leak() *int {
+	return new(int)
+}
+
+func main() {
+	x := sum()
+	g = &x
+	g = leak()
+}
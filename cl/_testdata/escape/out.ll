@@ -0,0 +1,54 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.g = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.sum() {
+_llgo_0:
+  %0 = alloca [2 x i64], align 8
+  store [2 x i64] zeroinitializer, ptr %0, align 4
+  %1 = getelementptr inbounds i64, ptr %0, i64 0
+  store i64 1, ptr %1, align 4
+  %2 = getelementptr inbounds i64, ptr %0, i64 1
+  store i64 2, ptr %2, align 4
+  %3 = getelementptr inbounds i64, ptr %0, i64 0
+  %4 = load i64, ptr %3, align 4
+  %5 = getelementptr inbounds i64, ptr %0, i64 1
+  %6 = load i64, ptr %5, align 4
+  %7 = add i64 %4, %6
+  ret i64 %7
+}
+
+define fastcc ptr @main.leak() {
+_llgo_0:
+  %0 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  ret ptr %0
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %4 = call fastcc i64 @main.sum()
+  store i64 %4, ptr %3, align 4
+  store ptr %3, ptr @main.g, align 8
+  %5 = call fastcc ptr @main.leak()
+  store ptr %5, ptr @main.g, align 8
+  ret i32 0
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
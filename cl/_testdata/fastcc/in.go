@@ -0,0 +1,17 @@
+package foo
+
+func f(a, b int) (int, int) { return b, a }
+
+func g() {}
+
+func h() {}
+
+func k() {}
+
+func l() {}
+
+func F() { f(1, 2); g() }
+
+func G() func() any { return func() any { l(); return l } }
+
+var fn = h
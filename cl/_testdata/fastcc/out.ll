@@ -0,0 +1,89 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@foo.fn = global { ptr, ptr } zeroinitializer
+@"__llgo_type.func()" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 19, i32 -2121285444, { ptr, i64 } { ptr @0, i64 6 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer }
+@0 = private unnamed_addr constant [6 x i8] c"func()"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  store { ptr, ptr } { ptr @"foo.h$stub", ptr null }, ptr @foo.fn, align 8
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc { i64, i64 } @foo.f(i64 %0, i64 %1) {
+_llgo_0:
+  %mrv = insertvalue { i64, i64 } undef, i64 %1, 0
+  %mrv1 = insertvalue { i64, i64 } %mrv, i64 %0, 1
+  ret { i64, i64 } %mrv1
+}
+
+define fastcc void @foo.g() {
+_llgo_0:
+  ret void
+}
+
+define void @foo.h() {
+_llgo_0:
+  ret void
+}
+
+define void @foo.k() {
+_llgo_0:
+  ret void
+}
+
+define void @foo.l() {
+_llgo_0:
+  ret void
+}
+
+define void @foo.F() {
+_llgo_0:
+  %0 = call fastcc { i64, i64 } @foo.f(i64 1, i64 2)
+  tail call fastcc void @foo.g()
+  ret void
+}
+
+define { ptr, ptr } @foo.G() {
+_llgo_0:
+  ret { ptr, ptr } { ptr @"foo.G$1$stub", ptr null }
+}
+
+define { ptr, ptr } @"foo.G$1"() {
+_llgo_0:
+  call void @foo.l()
+  %0 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, ptr } { ptr @"foo.l$stub", ptr null }, ptr %0, align 8
+  %1 = insertvalue { ptr, ptr } { ptr @"__llgo_type.func()", ptr undef }, ptr %0, 1
+  ret { ptr, ptr } %1
+}
+
+define private void @"foo.h$stub"(ptr %0) {
+_llgo_0:
+  tail call void @foo.h()
+  ret void
+}
+
+define private void @"foo.l$stub"(ptr %0) {
+_llgo_0:
+  tail call void @foo.l()
+  ret void
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+define private { ptr, ptr } @"foo.G$1$stub"(ptr %0) {
+_llgo_0:
+  %1 = tail call { ptr, ptr } @"foo.G$1"()
+  ret { ptr, ptr } %1
+}
@@ -0,0 +1,20 @@
+package main
+
+type point struct {
+	x, y int
+}
+
+func getY(p *point) int {
+	return p.y
+}
+
+func setX(p *point, x int) {
+	p.x = x
+}
+
+func sum(p point) int {
+	return p.x + p.y
+}
+
+func main() {
+}
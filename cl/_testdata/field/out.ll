@@ -0,0 +1,52 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%point = type { i64, i64 }
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @main.getY(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds %point, ptr %0, i32 0, i32 1
+  %2 = load i64, ptr %1, align 4
+  ret i64 %2
+}
+
+define void @main.setX(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %point, ptr %0, i32 0, i32 0
+  store i64 %1, ptr %2, align 4
+  ret void
+}
+
+define i64 @main.sum(%point %0) {
+_llgo_0:
+  %1 = alloca %point, align 8
+  store %point zeroinitializer, ptr %1, align 4
+  store %point %0, ptr %1, align 4
+  %2 = getelementptr inbounds %point, ptr %1, i32 0, i32 0
+  %3 = load i64, ptr %2, align 4
+  %4 = getelementptr inbounds %point, ptr %1, i32 0, i32 1
+  %5 = load i64, ptr %4, align 4
+  %6 = add i64 %3, %5
+  ret i64 %6
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  ret i32 0
+}
@@ -0,0 +1,12 @@
+package foo
+
+import "runtime"
+
+type T struct{ fd int }
+
+func closeT(t *T) {}
+
+func use(t *T) {
+	runtime.SetFinalizer(t, closeT)
+	runtime.KeepAlive(t)
+}
@@ -0,0 +1,77 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@"__llgo_type.*foo.T" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 22, i32 -385119595, { ptr, i64 } { ptr @0, i64 6 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.*foo.T$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.*foo.T$stub", ptr null } }
+@0 = private unnamed_addr constant [6 x i8] c"*foo.T"
+@"__llgo_type.func(*foo.T)" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 19, i32 -911197556, { ptr, i64 } { ptr @1, i64 14 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer }
+@1 = private unnamed_addr constant [14 x i8] c"func(t *foo.T)"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @foo.closeT(ptr %0) {
+_llgo_0:
+  ret void
+}
+
+define void @foo.use(ptr %0) {
+_llgo_0:
+  %1 = insertvalue { ptr, ptr } { ptr @"__llgo_type.*foo.T", ptr undef }, ptr %0, 1
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, ptr } { ptr @"foo.closeT$stub", ptr null }, ptr %2, align 8
+  %3 = insertvalue { ptr, ptr } { ptr @"__llgo_type.func(*foo.T)", ptr undef }, ptr %2, 1
+  call void @"github.com/goplus/llgo/internal/runtime.SetFinalizer"({ ptr, ptr } %1, { ptr, ptr } %3)
+  %4 = insertvalue { ptr, ptr } { ptr @"__llgo_type.*foo.T", ptr undef }, ptr %0, 1
+  %5 = extractvalue { ptr, ptr } %4, 1
+  call void asm sideeffect "", "r"(ptr %5)
+  ret void
+}
+
+define linkonce_odr i64 @"__llgo_hash.*foo.T"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.*foo.T$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @"__llgo_hash.*foo.T"(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.*foo.T"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.*foo.T$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @"__llgo_equal.*foo.T"(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define private void @"foo.closeT$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @foo.closeT(ptr %1)
+  ret void
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.SetFinalizer"({ ptr, ptr }, { ptr, ptr })
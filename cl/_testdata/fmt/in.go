@@ -0,0 +1,12 @@
+package main
+
+import "fmt"
+
+func show(name string, n int, f float32, ok bool) {
+	fmt.Println(name, n)
+	fmt.Printf("%s: %-5d|%.2f %t%%\n", name, n, f, ok)
+}
+
+func main() {
+	show("llgo", 100, 3.14, true)
+}
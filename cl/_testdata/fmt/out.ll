@@ -0,0 +1,61 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [1 x i8] c" "
+@1 = private unnamed_addr constant [1 x i8] c"\0A"
+@2 = private unnamed_addr constant [2 x i8] c": "
+@3 = private unnamed_addr constant [1 x i8] c"|"
+@4 = private unnamed_addr constant [2 x i8] c"%\0A"
+@5 = private unnamed_addr constant [4 x i8] c"llgo"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @fmt.init()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.show({ ptr, i64 } %0, i64 %1, float %2, i1 %3) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } %0, i32 118, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } { ptr @0, i64 1 }, i32 115, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtInt"(i64 %1, i32 118, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } { ptr @1, i64 1 }, i32 115, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } %0, i32 115, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } { ptr @2, i64 2 }, i32 115, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtInt"(i64 %1, i32 100, i64 5, i64 -1, i64 1)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } { ptr @3, i64 1 }, i32 115, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtFloat32"(float %2, i32 102, i64 -1, i64 2, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } { ptr @0, i64 1 }, i32 115, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtBool"(i1 %3, i32 116, i64 -1, i64 -1, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 } { ptr @4, i64 2 }, i32 115, i64 -1, i64 -1, i64 0)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  call fastcc void @main.show({ ptr, i64 } { ptr @5, i64 4 }, i64 100, float 0x40091EB860000000, i1 true)
+  ret i32 0
+}
+
+declare void @fmt.init()
+
+declare void @"github.com/goplus/llgo/internal/runtime.FmtString"({ ptr, i64 }, i32, i64, i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.FmtInt"(i64, i32, i64, i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.FmtFloat32"(float, i32, i64, i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.FmtBool"(i1, i32, i64, i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
@@ -16,7 +16,7 @@ _llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
   ret void
 }
 
-define i64 @main.max(i64 %0, i64 %1) {
+define fastcc i64 @main.max(i64 %0, i64 %1) {
 _llgo_0:
   %2 = icmp sgt i64 %0, %1
   br i1 %2, label %_llgo_1, label %_llgo_2
@@ -28,9 +28,9 @@ _llgo_2:                                          ; preds = %_llgo_0
   ret i64 %1
 }
 
-define void @main() {
+define i32 @main(i32 %0, ptr %1, ptr %2) {
 _llgo_0:
   call void @main.init()
-  %0 = call i64 @main.max(i64 1, i64 2)
-  ret void
+  %3 = call fastcc i64 @main.max(i64 1, i64 2)
+  ret i32 0
 }
@@ -0,0 +1,29 @@
+package main
+
+const debug = false
+
+const level = 2
+
+var n int
+
+func count() {
+	if debug {
+		n += 10
+	}
+	n++
+}
+
+func limit() int {
+	switch level {
+	case 1:
+		return 10
+	case 2:
+		return 20
+	}
+	return 0
+}
+
+func main() {
+	count()
+	n = limit()
+}
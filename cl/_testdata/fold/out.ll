@@ -0,0 +1,49 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.n = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.count() {
+_llgo_0:
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %0 = load i64, ptr @main.n, align 4
+  %1 = add i64 %0, 1
+  store i64 %1, ptr @main.n, align 4
+  ret void
+}
+
+define fastcc i64 @main.limit() {
+_llgo_0:
+  br label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_3
+  ret i64 20
+
+_llgo_3:                                          ; preds = %_llgo_0
+  br label %_llgo_2
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  call fastcc void @main.count()
+  %3 = call fastcc i64 @main.limit()
+  store i64 %3, ptr @main.n, align 4
+  ret i32 0
+}
@@ -0,0 +1,34 @@
+package main
+
+var sum int
+
+func add(a, b int) {
+	sum = a + b
+}
+
+func hello() {
+}
+
+type counter struct {
+	n int
+}
+
+func (c *counter) inc() {
+	c.n++
+}
+
+func run(f func(int, int), a, b int) {
+	go f(a, b)
+}
+
+func main() {
+	go add(1, 2)
+	go hello()
+	x := 3
+	go func() {
+		sum = x
+	}()
+	c := &counter{}
+	go c.inc()
+	run(add, 4, 5)
+}
@@ -0,0 +1,173 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%counter = type { i64 }
+
+@"main.init$guard" = global ptr null
+@main.sum = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @main.add(i64 %0, i64 %1) {
+_llgo_0:
+  %2 = add i64 %0, %1
+  store i64 %2, ptr @main.sum, align 4
+  ret void
+}
+
+define fastcc void @main.hello() {
+_llgo_0:
+  ret void
+}
+
+define fastcc void @"main.(*counter).inc"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds %counter, ptr %0, i32 0, i32 0
+  %2 = load i64, ptr %1, align 4
+  %3 = add i64 %2, 1
+  %4 = getelementptr inbounds %counter, ptr %0, i32 0, i32 0
+  store i64 %3, ptr %4, align 4
+  ret void
+}
+
+define fastcc void @main.run({ ptr, ptr } %0, i64 %1, i64 %2) {
+_llgo_0:
+  %3 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 32)
+  %4 = getelementptr inbounds { { ptr, ptr }, i64, i64 }, ptr %3, i32 0, i32 0
+  store { ptr, ptr } %0, ptr %4, align 8
+  %5 = getelementptr inbounds { { ptr, ptr }, i64, i64 }, ptr %3, i32 0, i32 1
+  store i64 %1, ptr %5, align 4
+  %6 = getelementptr inbounds { { ptr, ptr }, i64, i64 }, ptr %3, i32 0, i32 2
+  store i64 %2, ptr %6, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"__llgo_go.struct{_ func(int, int); _ int; _ int}$stub", ptr null }, ptr %3)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  %4 = getelementptr inbounds { i64, i64 }, ptr %3, i32 0, i32 0
+  store i64 1, ptr %4, align 4
+  %5 = getelementptr inbounds { i64, i64 }, ptr %3, i32 0, i32 1
+  store i64 2, ptr %5, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"main.add$go$stub", ptr null }, ptr %3)
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"main.hello$go$stub", ptr null }, ptr null)
+  %6 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 3, ptr %6, align 4
+  %7 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %8 = getelementptr inbounds { ptr }, ptr %7, i32 0, i32 0
+  store ptr %6, ptr %8, align 8
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"main.main$1$go$stub", ptr null }, ptr %7)
+  %9 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %10 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %11 = getelementptr inbounds { ptr }, ptr %10, i32 0, i32 0
+  store ptr %9, ptr %11, align 8
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"main.(*counter).inc$go$stub", ptr null }, ptr %10)
+  call fastcc void @main.run({ ptr, ptr } { ptr @"main.add$stub", ptr null }, i64 4, i64 5)
+  ret i32 0
+}
+
+define void @"main.main$1"(ptr %0) {
+_llgo_0:
+  %1 = load i64, ptr %0, align 4
+  store i64 %1, ptr @main.sum, align 4
+  ret void
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+define linkonce_odr void @"__llgo_go.struct{_ func(int, int); _ int; _ int}"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds { { ptr, ptr }, i64, i64 }, ptr %0, i32 0, i32 0
+  %2 = load { ptr, ptr }, ptr %1, align 8
+  %3 = getelementptr inbounds { { ptr, ptr }, i64, i64 }, ptr %0, i32 0, i32 1
+  %4 = load i64, ptr %3, align 4
+  %5 = getelementptr inbounds { { ptr, ptr }, i64, i64 }, ptr %0, i32 0, i32 2
+  %6 = load i64, ptr %5, align 4
+  %7 = extractvalue { ptr, ptr } %2, 1
+  %8 = extractvalue { ptr, ptr } %2, 0
+  call void %8(ptr %7, i64 %4, i64 %6)
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr }, ptr)
+
+define private void @"__llgo_go.struct{_ func(int, int); _ int; _ int}$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @"__llgo_go.struct{_ func(int, int); _ int; _ int}"(ptr %1)
+  ret void
+}
+
+define private void @"main.add$go"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds { i64, i64 }, ptr %0, i32 0, i32 0
+  %2 = load i64, ptr %1, align 4
+  %3 = getelementptr inbounds { i64, i64 }, ptr %0, i32 0, i32 1
+  %4 = load i64, ptr %3, align 4
+  call void @main.add(i64 %2, i64 %4)
+  ret void
+}
+
+define private void @"main.add$go$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @"main.add$go"(ptr %1)
+  ret void
+}
+
+define private void @"main.hello$go"(ptr %0) {
+_llgo_0:
+  call fastcc void @main.hello()
+  ret void
+}
+
+define private void @"main.hello$go$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @"main.hello$go"(ptr %1)
+  ret void
+}
+
+define private void @"main.main$1$go"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds { ptr }, ptr %0, i32 0, i32 0
+  %2 = load ptr, ptr %1, align 8
+  call void @"main.main$1"(ptr %2)
+  ret void
+}
+
+define private void @"main.main$1$go$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @"main.main$1$go"(ptr %1)
+  ret void
+}
+
+define private void @"main.(*counter).inc$go"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds { ptr }, ptr %0, i32 0, i32 0
+  %2 = load ptr, ptr %1, align 8
+  call fastcc void @"main.(*counter).inc"(ptr %2)
+  ret void
+}
+
+define private void @"main.(*counter).inc$go$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @"main.(*counter).inc$go"(ptr %1)
+  ret void
+}
+
+define private void @"main.add$stub"(ptr %0, i64 %1, i64 %2) {
+_llgo_0:
+  tail call void @main.add(i64 %1, i64 %2)
+  ret void
+}
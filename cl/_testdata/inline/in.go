@@ -0,0 +1,8 @@
+package foo
+
+// f is inlined.
+//
+//llgo:inline
+func f() {}
+
+func g() {}
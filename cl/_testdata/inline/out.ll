@@ -0,0 +1,30 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+; Function Attrs: alwaysinline
+define void @foo.f() #0 {
+_llgo_0:
+  ret void
+}
+
+define void @foo.g() {
+_llgo_0:
+  ret void
+}
+
+attributes #0 = { alwaysinline }
@@ -0,0 +1,27 @@
+package main
+
+import "github.com/goplus/llgo/gpu"
+
+//llgo:kernel
+func scale(a float32, x []float32) {
+	i := int(gpu.BlockIdx(gpu.X)*gpu.BlockDim(gpu.X) + gpu.ThreadIdx(gpu.X))
+	if i < len(x) {
+		x[i] *= a
+	}
+}
+
+//llgo:kernel
+func sum(x []float32, n int, out *float32) {
+	var s float32
+	for i := 0; i < n; i++ {
+		s += x[i]
+	}
+	*out = s
+}
+
+func main() {
+	x := []float32{1, 2, 3}
+	var s float32
+	scale(2, x)
+	sum(x, len(x), &s)
+}
@@ -0,0 +1,72 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [6 x i8] c"nvptx\00", align 1
+@1 = private unnamed_addr constant [1506 x i8] c"//\0A// Generated by LLVM NVPTX Back-End\0A//\0A\0A.version 4.1\0A.target sm_52\0A.address_size 64\0A\0A\09// .globl\09scale\0A\0A.visible .entry scale(\0A\09.param .f32 scale_param_0,\0A\09.param .align 8 .b8 scale_param_1[24]\0A)\0A{\0A\09.reg .pred \09%p<3>;\0A\09.reg .b32 \09%r<5>;\0A\09.reg .f32 \09%f<4>;\0A\09.reg .b64 \09%rd<8>;\0A\0A\09ld.param.u64 \09%rd4, [scale_param_1+8];\0A\09mov.u32 \09%r1, %ctaid.x;\0A\09mov.u32 \09%r2, %ntid.x;\0A\09mov.u32 \09%r3, %tid.x;\0A\09mad.lo.s32 \09%r4, %r1, %r2, %r3;\0A\09cvt.u64.u32 \09%rd1, %r4;\0A\09setp.ge.s64 \09%p1, %rd1, %rd4;\0A\09@%p1 bra \09LBB0_4;\0A\09setp.ge.u64 \09%p2, %rd1, %rd4;\0A\09@%p2 bra \09LBB0_2;\0A\09ld.param.u64 \09%rd3, [scale_param_1];\0A\09ld.param.f32 \09%f1, [scale_param_0];\0A\09shl.b64 \09%rd6, %rd1, 2;\0A\09add.s64 \09%rd7, %rd3, %rd6;\0A\09ld.f32 \09%f2, [%rd7];\0A\09mul.rn.f32 \09%f3, %f2, %f1;\0A\09st.f32 \09[%rd7], %f3;\0ALBB0_4:\0A\09ret;\0ALBB0_2:\0A\09trap;\0A\0A}\0A\09// .globl\09sum\0A.visible .entry sum(\0A\09.param .align 8 .b8 sum_param_0[24],\0A\09.param .u64 sum_param_1,\0A\09.param .u64 sum_param_2\0A)\0A{\0A\09.reg .pred \09%p<3>;\0A\09.reg .f32 \09%f<6>;\0A\09.reg .b64 \09%rd<14>;\0A\0A\09ld.param.u64 \09%rd6, [sum_param_0+8];\0A\09ld.param.u64 \09%rd5, [sum_param_0];\0A\09mov.f32 \09%f5, 0f00000000;\0A\09mov.u64 \09%rd12, 0;\0A\09ld.param.u64 \09%rd9, [sum_param_2];\0A\09ld.param.u64 \09%rd8, [sum_param_1];\0A\09mov.u64 \09%rd13, %rd12;\0ALBB1_1:\0A\09setp.ge.s64 \09%p1, %rd13, %rd8;\0A\09@%p1 bra \09LBB1_5;\0A\09setp.ge.u64 \09%p2, %rd13, %rd6;\0A\09@%p2 bra \09LBB1_3;\0A\09add.s64 \09%rd11, %rd5, %rd12;\0A\09ld.f32 \09%f4, [%rd11];\0A\09add.rn.f32 \09%f5, %f5, %f4;\0A\09add.s64 \09%rd13, %rd13, 1;\0A\09add.s64 \09%rd12, %rd12, 4;\0A\09bra.uni \09LBB1_1;\0ALBB1_5:\0A\09st.f32 \09[%rd9], %f5;\0A\09ret;\0ALBB1_3:\0A\09trap;\0A\0A}\0A\00", align 1
+@2 = private unnamed_addr constant [6 x i8] c"scale\00", align 1
+@3 = private unnamed_addr constant [4 x i8] c"sum\00", align 1
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/gpu.init"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.scale(float %0, { ptr, i64, i64 } %1) {
+_llgo_0:
+  %2 = alloca { float, { ptr, i64, i64 } }, align 8
+  %3 = getelementptr inbounds { float, { ptr, i64, i64 } }, ptr %2, i32 0, i32 0
+  store float %0, ptr %3, align 4
+  %4 = getelementptr inbounds { float, { ptr, i64, i64 } }, ptr %2, i32 0, i32 1
+  store { ptr, i64, i64 } %1, ptr %4, align 8
+  call void @llgo_gpu_launch(ptr @0, ptr @1, i64 1505, ptr @2, ptr %2, i64 32)
+  ret void
+}
+
+define fastcc void @main.sum({ ptr, i64, i64 } %0, i64 %1, ptr %2) {
+_llgo_0:
+  %3 = alloca { { ptr, i64, i64 }, i64, ptr }, align 8
+  %4 = getelementptr inbounds { { ptr, i64, i64 }, i64, ptr }, ptr %3, i32 0, i32 0
+  store { ptr, i64, i64 } %0, ptr %4, align 8
+  %5 = getelementptr inbounds { { ptr, i64, i64 }, i64, ptr }, ptr %3, i32 0, i32 1
+  store i64 %1, ptr %5, align 4
+  %6 = getelementptr inbounds { { ptr, i64, i64 }, i64, ptr }, ptr %3, i32 0, i32 2
+  store ptr %2, ptr %6, align 8
+  call void @llgo_gpu_launch(ptr @0, ptr @1, i64 1505, ptr @3, ptr %3, i64 40)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 12)
+  %4 = getelementptr inbounds float, ptr %3, i64 0
+  store float 1.000000e+00, ptr %4, align 4
+  %5 = getelementptr inbounds float, ptr %3, i64 1
+  store float 2.000000e+00, ptr %5, align 4
+  %6 = getelementptr inbounds float, ptr %3, i64 2
+  store float 3.000000e+00, ptr %6, align 4
+  %7 = insertvalue { ptr, i64, i64 } undef, ptr %3, 0
+  %8 = insertvalue { ptr, i64, i64 } %7, i64 3, 1
+  %9 = insertvalue { ptr, i64, i64 } %8, i64 3, 2
+  %10 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 4)
+  call fastcc void @main.scale(float 2.000000e+00, { ptr, i64, i64 } %9)
+  %11 = extractvalue { ptr, i64, i64 } %9, 1
+  call fastcc void @main.sum({ ptr, i64, i64 } %9, i64 %11, ptr %10)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/gpu.init"()
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @llgo_gpu_launch(ptr, ptr, i64, ptr, ptr, i64)
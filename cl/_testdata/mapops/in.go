@@ -0,0 +1,129 @@
+package main
+
+type point struct{ x, y int }
+
+type named struct {
+	name string
+	f    float64
+}
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+// grow fills a map past several growths of its buckets, and reads it back.
+func grow(n int) {
+	m := make(map[int]int)
+	for i := 0; i < n; i++ {
+		m[i] = i * i
+	}
+	check(len(m) == n, "grow: len")
+	for i := 0; i < n; i++ {
+		check(m[i] == i*i, "grow: value")
+	}
+}
+
+// del deletes the even keys, and reinserts some of them.
+func del(n int) {
+	m := make(map[int]int)
+	for i := 0; i < n; i++ {
+		m[i] = i
+	}
+	for i := 0; i < n; i += 2 {
+		delete(m, i)
+	}
+	check(len(m) == n/2, "del: len")
+	for i := 0; i < n; i++ {
+		_, ok := m[i]
+		check(ok == (i%2 == 1), "del: lookup")
+	}
+	m[0] = 1
+	check(len(m) == n/2+1 && m[0] == 1, "del: reinsert")
+}
+
+// mutate deletes the entries not visited yet and adds new ones while ranging
+// over a map, which grows: the deleted entries must not be visited, and the
+// ones that are visited are visited once.
+func mutate(n int) {
+	m := make(map[int]int)
+	for i := 0; i < n; i++ {
+		m[i] = i
+	}
+	seen := make(map[int]int)
+	for k := range m {
+		seen[k]++
+		delete(m, n-1-k)
+		m[n+k] = k
+	}
+	for k, c := range seen {
+		check(c == 1, "mutate: visited twice")
+		if k < n {
+			check(seen[n-1-k] == 0, "mutate: visited a deleted key")
+		}
+	}
+}
+
+// ifaceKeys uses interfaces of different dynamic types as keys: equal values
+// of the same type are the same key, and values of different types aren't.
+func ifaceKeys() {
+	m := make(map[any]int)
+	m[1] = 1
+	m[int64(1)] = 2
+	m["1"] = 3
+	m[point{1, 2}] = 4
+	m[1.5] = 5
+	m[nil] = 6
+	m[point{1, 2}]++
+	check(len(m) == 6, "iface: len")
+	check(m[1] == 1 && m[int64(1)] == 2 && m["1"] == 3, "iface: basic keys")
+	check(m[point{1, 2}] == 5 && m[1.5] == 5 && m[nil] == 6, "iface: other keys")
+	delete(m, any(1))
+	_, ok := m[1]
+	check(!ok && len(m) == 5, "iface: delete")
+}
+
+// structKeys uses interface keys of dynamic struct and array types whose
+// equal values may have different bytes: strings at different addresses, and
+// floats +0 and -0.
+func structKeys() {
+	m := make(map[any]int)
+	a, b := "ab", string([]byte{'a', 'b'})
+	m[named{a, 0}] = 1
+	m[named{b, negZero()}]++
+	m[[2]string{a, b}] = 3
+	m[[2]string{b, a}]++
+	check(len(m) == 2, "struct: len")
+	check(m[named{"ab", 0}] == 2 && m[[2]string{"ab", "ab"}] == 4, "struct: values")
+	check(any(named{a, 0}) == any(named{b, negZero()}), "struct: ==")
+}
+
+func negZero() float64 {
+	z := 0.0
+	return -z
+}
+
+// unhashable assigns a key of an unhashable dynamic type, which panics, and
+// checks that the map can still be written.
+func unhashable() {
+	m := make(map[any]int)
+	func() {
+		defer func() {
+			check(recover() != nil, "unhashable: no panic")
+		}()
+		m[[]int{1}] = 1
+	}()
+	m[1] = 1
+	check(len(m) == 1, "unhashable: len")
+}
+
+func main() {
+	grow(1000)
+	del(100)
+	mutate(200)
+	ifaceKeys()
+	structKeys()
+	unhashable()
+	println("ok")
+}
@@ -0,0 +1,1069 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%point = type { i64, i64 }
+%named = type { { ptr, i64 }, double }
+
+@"main.init$guard" = global ptr null
+@__llgo_type.string = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 24, i32 398550328, { ptr, i64 } { ptr @0, i64 6 }, { ptr, i64 } { ptr @0, i64 6 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null } }
+@0 = private unnamed_addr constant [6 x i8] c"string"
+@1 = private unnamed_addr constant [9 x i8] c"grow: len"
+@2 = private unnamed_addr constant [11 x i8] c"grow: value"
+@3 = private unnamed_addr constant [8 x i8] c"del: len"
+@4 = private unnamed_addr constant [11 x i8] c"del: lookup"
+@5 = private unnamed_addr constant [13 x i8] c"del: reinsert"
+@6 = private unnamed_addr constant [21 x i8] c"mutate: visited twice"
+@7 = private unnamed_addr constant [29 x i8] c"mutate: visited a deleted key"
+@__llgo_type.int = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 2, i32 -1779859874, { ptr, i64 } { ptr @8, i64 3 }, { ptr, i64 } { ptr @8, i64 3 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null } }
+@8 = private unnamed_addr constant [3 x i8] c"int"
+@__llgo_type.int64 = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 6, i32 64103268, { ptr, i64 } { ptr @9, i64 5 }, { ptr, i64 } { ptr @9, i64 5 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.int64$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int64$stub", ptr null } }
+@9 = private unnamed_addr constant [5 x i8] c"int64"
+@10 = private unnamed_addr constant [1 x i8] c"1"
+@__llgo_type.main.point = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 25, i32 -1082403676, { ptr, i64 } { ptr @11, i64 10 }, { ptr, i64 } { ptr @12, i64 5 }, { ptr, i64 } { ptr @13, i64 4 }, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.main.point$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.main.point$stub", ptr null } }
+@11 = private unnamed_addr constant [10 x i8] c"main.point"
+@12 = private unnamed_addr constant [5 x i8] c"point"
+@13 = private unnamed_addr constant [4 x i8] c"main"
+@__llgo_type.float64 = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 14, i32 2090339911, { ptr, i64 } { ptr @14, i64 7 }, { ptr, i64 } { ptr @14, i64 7 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.float64$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.float64$stub", ptr null } }
+@14 = private unnamed_addr constant [7 x i8] c"float64"
+@15 = private unnamed_addr constant [10 x i8] c"iface: len"
+@16 = private unnamed_addr constant [17 x i8] c"iface: basic keys"
+@17 = private unnamed_addr constant [17 x i8] c"iface: other keys"
+@18 = private unnamed_addr constant [13 x i8] c"iface: delete"
+@19 = private unnamed_addr constant [2 x i8] c"ab"
+@__llgo_type.main.named = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 24, i64 25, i32 862392647, { ptr, i64 } { ptr @20, i64 10 }, { ptr, i64 } { ptr @21, i64 5 }, { ptr, i64 } { ptr @13, i64 4 }, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.main.named$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.main.named$stub", ptr null } }
+@20 = private unnamed_addr constant [10 x i8] c"main.named"
+@21 = private unnamed_addr constant [5 x i8] c"named"
+@"__llgo_type.[2]string" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 32, i64 17, i32 790474954, { ptr, i64 } { ptr @22, i64 9 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.[2]string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.[2]string$stub", ptr null } }
+@22 = private unnamed_addr constant [9 x i8] c"[2]string"
+@23 = private unnamed_addr constant [11 x i8] c"struct: len"
+@24 = private unnamed_addr constant [14 x i8] c"struct: values"
+@25 = private unnamed_addr constant [10 x i8] c"struct: =="
+@26 = private unnamed_addr constant [20 x i8] c"unhashable: no panic"
+@"__llgo_type.[]int" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 24, i64 23, i32 736017598, { ptr, i64 } { ptr @27, i64 5 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer }
+@27 = private unnamed_addr constant [5 x i8] c"[]int"
+@28 = private unnamed_addr constant [15 x i8] c"unhashable: len"
+@29 = private unnamed_addr constant [2 x i8] c"ok"
+@30 = private unnamed_addr constant [1 x i8] c"\0A"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.check(i1 %0, { ptr, i64 } %1) {
+_llgo_0:
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } %1, ptr %2, align 8
+  %3 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %2, 1
+  call void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr } %3)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  ret void
+}
+
+define fastcc void @main.grow(i64 %0) {
+_llgo_0:
+  %1 = alloca i64, align 8
+  %2 = alloca i64, align 8
+  %3 = alloca i64, align 8
+  %4 = alloca i64, align 8
+  %5 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 8, i64 8, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null }, i64 0)
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  %6 = phi i64 [ 0, %_llgo_0 ], [ %9, %_llgo_2 ]
+  %7 = icmp slt i64 %6, %0
+  br i1 %7, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  %8 = mul i64 %6, %6
+  store i64 %6, ptr %4, align 4
+  store i64 %8, ptr %3, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %5, ptr %4, ptr %3)
+  %9 = add i64 %6, 1
+  br label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_1
+  %10 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %5)
+  %11 = icmp eq i64 %10, %0
+  call fastcc void @main.check(i1 %11, { ptr, i64 } { ptr @1, i64 9 })
+  br label %_llgo_4
+
+_llgo_4:                                          ; preds = %_llgo_5, %_llgo_3
+  %12 = phi i64 [ 0, %_llgo_3 ], [ %18, %_llgo_5 ]
+  %13 = icmp slt i64 %12, %0
+  br i1 %13, label %_llgo_5, label %_llgo_6
+
+_llgo_5:                                          ; preds = %_llgo_4
+  store i64 0, ptr %2, align 4
+  store i64 %12, ptr %1, align 4
+  %14 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %5, ptr %1, ptr %2)
+  %15 = load i64, ptr %2, align 4
+  %16 = mul i64 %12, %12
+  %17 = icmp eq i64 %15, %16
+  call fastcc void @main.check(i1 %17, { ptr, i64 } { ptr @2, i64 11 })
+  %18 = add i64 %12, 1
+  br label %_llgo_4
+
+_llgo_6:                                          ; preds = %_llgo_4
+  ret void
+}
+
+define fastcc void @main.del(i64 %0) {
+_llgo_0:
+  %1 = alloca i64, align 8
+  %2 = alloca i64, align 8
+  %3 = alloca i64, align 8
+  %4 = alloca i64, align 8
+  %5 = alloca i64, align 8
+  %6 = alloca i64, align 8
+  %7 = alloca i64, align 8
+  %8 = alloca i64, align 8
+  %9 = alloca i64, align 8
+  %10 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 8, i64 8, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null }, i64 0)
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  %11 = phi i64 [ 0, %_llgo_0 ], [ %13, %_llgo_2 ]
+  %12 = icmp slt i64 %11, %0
+  br i1 %12, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  store i64 %11, ptr %9, align 4
+  store i64 %11, ptr %8, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %10, ptr %9, ptr %8)
+  %13 = add i64 %11, 1
+  br label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_1
+  br label %_llgo_4
+
+_llgo_4:                                          ; preds = %_llgo_5, %_llgo_3
+  %14 = phi i64 [ 0, %_llgo_3 ], [ %16, %_llgo_5 ]
+  %15 = icmp slt i64 %14, %0
+  br i1 %15, label %_llgo_5, label %_llgo_6
+
+_llgo_5:                                          ; preds = %_llgo_4
+  store i64 %14, ptr %7, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapDelete"(ptr %10, ptr %7)
+  %16 = add i64 %14, 2
+  br label %_llgo_4
+
+_llgo_6:                                          ; preds = %_llgo_4
+  %17 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %10)
+  %18 = sdiv i64 %0, 2
+  %19 = icmp eq i64 %17, %18
+  call fastcc void @main.check(i1 %19, { ptr, i64 } { ptr @3, i64 8 })
+  br label %_llgo_7
+
+_llgo_7:                                          ; preds = %_llgo_8, %_llgo_6
+  %20 = phi i64 [ 0, %_llgo_6 ], [ %31, %_llgo_8 ]
+  %21 = icmp slt i64 %20, %0
+  br i1 %21, label %_llgo_8, label %_llgo_9
+
+_llgo_8:                                          ; preds = %_llgo_7
+  store i64 0, ptr %6, align 4
+  store i64 %20, ptr %5, align 4
+  %22 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %10, ptr %5, ptr %6)
+  %23 = load i64, ptr %6, align 4
+  %24 = insertvalue { i64, i1 } undef, i64 %23, 0
+  %25 = insertvalue { i64, i1 } %24, i1 %22, 1
+  %26 = extractvalue { i64, i1 } %25, 0
+  %27 = extractvalue { i64, i1 } %25, 1
+  %28 = srem i64 %20, 2
+  %29 = icmp eq i64 %28, 1
+  %30 = icmp eq i1 %27, %29
+  call fastcc void @main.check(i1 %30, { ptr, i64 } { ptr @4, i64 11 })
+  %31 = add i64 %20, 1
+  br label %_llgo_7
+
+_llgo_9:                                          ; preds = %_llgo_7
+  store i64 0, ptr %4, align 4
+  store i64 1, ptr %3, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %10, ptr %4, ptr %3)
+  %32 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %10)
+  %33 = sdiv i64 %0, 2
+  %34 = add i64 %33, 1
+  %35 = icmp eq i64 %32, %34
+  br i1 %35, label %_llgo_10, label %_llgo_11
+
+_llgo_10:                                         ; preds = %_llgo_9
+  store i64 0, ptr %2, align 4
+  store i64 0, ptr %1, align 4
+  %36 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %10, ptr %1, ptr %2)
+  %37 = load i64, ptr %2, align 4
+  %38 = icmp eq i64 %37, 1
+  br label %_llgo_11
+
+_llgo_11:                                         ; preds = %_llgo_10, %_llgo_9
+  %39 = phi i1 [ false, %_llgo_9 ], [ %38, %_llgo_10 ]
+  tail call fastcc void @main.check(i1 %39, { ptr, i64 } { ptr @5, i64 13 })
+  ret void
+}
+
+define fastcc void @main.mutate(i64 %0) {
+_llgo_0:
+  %1 = alloca i64, align 8
+  %2 = alloca i64, align 8
+  %3 = alloca i64, align 8
+  %4 = alloca i64, align 8
+  %5 = alloca { ptr, ptr, i8, i8, i64, i64, ptr, i64 }, align 8
+  %6 = alloca i64, align 8
+  %7 = alloca i64, align 8
+  %8 = alloca i64, align 8
+  %9 = alloca i64, align 8
+  %10 = alloca i64, align 8
+  %11 = alloca i64, align 8
+  %12 = alloca i64, align 8
+  %13 = alloca i64, align 8
+  %14 = alloca i64, align 8
+  %15 = alloca { ptr, ptr, i8, i8, i64, i64, ptr, i64 }, align 8
+  %16 = alloca i64, align 8
+  %17 = alloca i64, align 8
+  %18 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 8, i64 8, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null }, i64 0)
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  %19 = phi i64 [ 0, %_llgo_0 ], [ %21, %_llgo_2 ]
+  %20 = icmp slt i64 %19, %0
+  br i1 %20, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  store i64 %19, ptr %17, align 4
+  store i64 %19, ptr %16, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %18, ptr %17, ptr %16)
+  %21 = add i64 %19, 1
+  br label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_1
+  %22 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 8, i64 8, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null }, i64 0)
+  call void @"github.com/goplus/llgo/internal/runtime.MapIterInit"(ptr %18, ptr %15)
+  br label %_llgo_4
+
+_llgo_4:                                          ; preds = %_llgo_5, %_llgo_3
+  %23 = call i1 @"github.com/goplus/llgo/internal/runtime.MapIterNext"(ptr %15, ptr %14, ptr %13)
+  %24 = load i64, ptr %14, align 4
+  %25 = load i64, ptr %13, align 4
+  %26 = insertvalue { i1, i64, i64 } undef, i1 %23, 0
+  %27 = insertvalue { i1, i64, i64 } %26, i64 %24, 1
+  %28 = insertvalue { i1, i64, i64 } %27, i64 %25, 2
+  %29 = extractvalue { i1, i64, i64 } %28, 0
+  br i1 %29, label %_llgo_5, label %_llgo_6
+
+_llgo_5:                                          ; preds = %_llgo_4
+  %30 = extractvalue { i1, i64, i64 } %28, 1
+  store i64 0, ptr %12, align 4
+  store i64 %30, ptr %11, align 4
+  %31 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %22, ptr %11, ptr %12)
+  %32 = load i64, ptr %12, align 4
+  %33 = add i64 %32, 1
+  store i64 %30, ptr %10, align 4
+  store i64 %33, ptr %9, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %22, ptr %10, ptr %9)
+  %34 = sub i64 %0, 1
+  %35 = sub i64 %34, %30
+  store i64 %35, ptr %8, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapDelete"(ptr %18, ptr %8)
+  %36 = add i64 %0, %30
+  store i64 %36, ptr %7, align 4
+  store i64 %30, ptr %6, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %18, ptr %7, ptr %6)
+  br label %_llgo_4
+
+_llgo_6:                                          ; preds = %_llgo_4
+  call void @"github.com/goplus/llgo/internal/runtime.MapIterInit"(ptr %22, ptr %5)
+  br label %_llgo_7
+
+_llgo_7:                                          ; preds = %_llgo_10, %_llgo_8, %_llgo_6
+  %37 = call i1 @"github.com/goplus/llgo/internal/runtime.MapIterNext"(ptr %5, ptr %4, ptr %3)
+  %38 = load i64, ptr %4, align 4
+  %39 = load i64, ptr %3, align 4
+  %40 = insertvalue { i1, i64, i64 } undef, i1 %37, 0
+  %41 = insertvalue { i1, i64, i64 } %40, i64 %38, 1
+  %42 = insertvalue { i1, i64, i64 } %41, i64 %39, 2
+  %43 = extractvalue { i1, i64, i64 } %42, 0
+  br i1 %43, label %_llgo_8, label %_llgo_9
+
+_llgo_8:                                          ; preds = %_llgo_7
+  %44 = extractvalue { i1, i64, i64 } %42, 1
+  %45 = extractvalue { i1, i64, i64 } %42, 2
+  %46 = icmp eq i64 %45, 1
+  call fastcc void @main.check(i1 %46, { ptr, i64 } { ptr @6, i64 21 })
+  %47 = icmp slt i64 %44, %0
+  br i1 %47, label %_llgo_10, label %_llgo_7
+
+_llgo_9:                                          ; preds = %_llgo_7
+  ret void
+
+_llgo_10:                                         ; preds = %_llgo_8
+  %48 = sub i64 %0, 1
+  %49 = sub i64 %48, %44
+  store i64 0, ptr %2, align 4
+  store i64 %49, ptr %1, align 4
+  %50 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %22, ptr %1, ptr %2)
+  %51 = load i64, ptr %2, align 4
+  %52 = icmp eq i64 %51, 0
+  call fastcc void @main.check(i1 %52, { ptr, i64 } { ptr @7, i64 29 })
+  br label %_llgo_7
+}
+
+define fastcc void @main.ifaceKeys() {
+_llgo_0:
+  %0 = alloca { ptr, ptr }, align 8
+  %1 = alloca i64, align 8
+  %2 = alloca { ptr, ptr }, align 8
+  %3 = alloca { ptr, ptr }, align 8
+  %4 = alloca i64, align 8
+  %5 = alloca { ptr, ptr }, align 8
+  %6 = alloca i64, align 8
+  %7 = alloca { ptr, ptr }, align 8
+  %8 = alloca i64, align 8
+  %9 = alloca %point, align 8
+  %10 = alloca { ptr, ptr }, align 8
+  %11 = alloca i64, align 8
+  %12 = alloca { ptr, ptr }, align 8
+  %13 = alloca i64, align 8
+  %14 = alloca { ptr, ptr }, align 8
+  %15 = alloca i64, align 8
+  %16 = alloca i64, align 8
+  %17 = alloca { ptr, ptr }, align 8
+  %18 = alloca { ptr, ptr }, align 8
+  %19 = alloca i64, align 8
+  %20 = alloca %point, align 8
+  %21 = alloca i64, align 8
+  %22 = alloca { ptr, ptr }, align 8
+  %23 = alloca i64, align 8
+  %24 = alloca { ptr, ptr }, align 8
+  %25 = alloca i64, align 8
+  %26 = alloca { ptr, ptr }, align 8
+  %27 = alloca %point, align 8
+  %28 = alloca i64, align 8
+  %29 = alloca { ptr, ptr }, align 8
+  %30 = alloca i64, align 8
+  %31 = alloca { ptr, ptr }, align 8
+  %32 = alloca i64, align 8
+  %33 = alloca { ptr, ptr }, align 8
+  %34 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 16, i64 8, { ptr, ptr } { ptr @"__llgo_hash.any$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.any$stub", ptr null }, i64 0)
+  %35 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %35, align 4
+  %36 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %35, 1
+  store { ptr, ptr } %36, ptr %33, align 8
+  store i64 1, ptr %32, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %33, ptr %32)
+  %37 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %37, align 4
+  %38 = insertvalue { ptr, ptr } { ptr @__llgo_type.int64, ptr undef }, ptr %37, 1
+  store { ptr, ptr } %38, ptr %31, align 8
+  store i64 2, ptr %30, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %31, ptr %30)
+  %39 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } { ptr @10, i64 1 }, ptr %39, align 8
+  %40 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %39, 1
+  store { ptr, ptr } %40, ptr %29, align 8
+  store i64 3, ptr %28, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %29, ptr %28)
+  store %point zeroinitializer, ptr %27, align 4
+  %41 = getelementptr inbounds %point, ptr %27, i32 0, i32 0
+  %42 = getelementptr inbounds %point, ptr %27, i32 0, i32 1
+  store i64 1, ptr %41, align 4
+  store i64 2, ptr %42, align 4
+  %43 = load %point, ptr %27, align 4
+  %44 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store %point %43, ptr %44, align 4
+  %45 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.point, ptr undef }, ptr %44, 1
+  store { ptr, ptr } %45, ptr %26, align 8
+  store i64 4, ptr %25, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %26, ptr %25)
+  %46 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store double 1.500000e+00, ptr %46, align 8
+  %47 = insertvalue { ptr, ptr } { ptr @__llgo_type.float64, ptr undef }, ptr %46, 1
+  store { ptr, ptr } %47, ptr %24, align 8
+  store i64 5, ptr %23, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %24, ptr %23)
+  store { ptr, ptr } zeroinitializer, ptr %22, align 8
+  store i64 6, ptr %21, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %22, ptr %21)
+  store %point zeroinitializer, ptr %20, align 4
+  %48 = getelementptr inbounds %point, ptr %20, i32 0, i32 0
+  %49 = getelementptr inbounds %point, ptr %20, i32 0, i32 1
+  store i64 1, ptr %48, align 4
+  store i64 2, ptr %49, align 4
+  %50 = load %point, ptr %20, align 4
+  %51 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store %point %50, ptr %51, align 4
+  %52 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.point, ptr undef }, ptr %51, 1
+  store i64 0, ptr %19, align 4
+  store { ptr, ptr } %52, ptr %18, align 8
+  %53 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %18, ptr %19)
+  %54 = load i64, ptr %19, align 4
+  %55 = add i64 %54, 1
+  store { ptr, ptr } %52, ptr %17, align 8
+  store i64 %55, ptr %16, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %34, ptr %17, ptr %16)
+  %56 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %34)
+  %57 = icmp eq i64 %56, 6
+  call fastcc void @main.check(i1 %57, { ptr, i64 } { ptr @15, i64 10 })
+  %58 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %58, align 4
+  %59 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %58, 1
+  store i64 0, ptr %15, align 4
+  store { ptr, ptr } %59, ptr %14, align 8
+  %60 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %14, ptr %15)
+  %61 = load i64, ptr %15, align 4
+  %62 = icmp eq i64 %61, 1
+  br i1 %62, label %_llgo_3, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_3
+  %63 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } { ptr @10, i64 1 }, ptr %63, align 8
+  %64 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %63, 1
+  store i64 0, ptr %11, align 4
+  store { ptr, ptr } %64, ptr %10, align 8
+  %65 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %10, ptr %11)
+  %66 = load i64, ptr %11, align 4
+  %67 = icmp eq i64 %66, 3
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_3, %_llgo_0
+  %68 = phi i1 [ false, %_llgo_0 ], [ false, %_llgo_3 ], [ %67, %_llgo_1 ]
+  call fastcc void @main.check(i1 %68, { ptr, i64 } { ptr @16, i64 17 })
+  store %point zeroinitializer, ptr %9, align 4
+  %69 = getelementptr inbounds %point, ptr %9, i32 0, i32 0
+  %70 = getelementptr inbounds %point, ptr %9, i32 0, i32 1
+  store i64 1, ptr %69, align 4
+  store i64 2, ptr %70, align 4
+  %71 = load %point, ptr %9, align 4
+  %72 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store %point %71, ptr %72, align 4
+  %73 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.point, ptr undef }, ptr %72, 1
+  store i64 0, ptr %8, align 4
+  store { ptr, ptr } %73, ptr %7, align 8
+  %74 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %7, ptr %8)
+  %75 = load i64, ptr %8, align 4
+  %76 = icmp eq i64 %75, 5
+  br i1 %76, label %_llgo_6, label %_llgo_5
+
+_llgo_3:                                          ; preds = %_llgo_0
+  %77 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %77, align 4
+  %78 = insertvalue { ptr, ptr } { ptr @__llgo_type.int64, ptr undef }, ptr %77, 1
+  store i64 0, ptr %13, align 4
+  store { ptr, ptr } %78, ptr %12, align 8
+  %79 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %12, ptr %13)
+  %80 = load i64, ptr %13, align 4
+  %81 = icmp eq i64 %80, 2
+  br i1 %81, label %_llgo_1, label %_llgo_2
+
+_llgo_4:                                          ; preds = %_llgo_6
+  store i64 0, ptr %4, align 4
+  store { ptr, ptr } zeroinitializer, ptr %3, align 8
+  %82 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %3, ptr %4)
+  %83 = load i64, ptr %4, align 4
+  %84 = icmp eq i64 %83, 6
+  br label %_llgo_5
+
+_llgo_5:                                          ; preds = %_llgo_4, %_llgo_6, %_llgo_2
+  %85 = phi i1 [ false, %_llgo_2 ], [ false, %_llgo_6 ], [ %84, %_llgo_4 ]
+  call fastcc void @main.check(i1 %85, { ptr, i64 } { ptr @17, i64 17 })
+  %86 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %86, align 4
+  %87 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %86, 1
+  store { ptr, ptr } %87, ptr %2, align 8
+  call void @"github.com/goplus/llgo/internal/runtime.MapDelete"(ptr %34, ptr %2)
+  %88 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %88, align 4
+  %89 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %88, 1
+  store i64 0, ptr %1, align 4
+  store { ptr, ptr } %89, ptr %0, align 8
+  %90 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %0, ptr %1)
+  %91 = load i64, ptr %1, align 4
+  %92 = insertvalue { i64, i1 } undef, i64 %91, 0
+  %93 = insertvalue { i64, i1 } %92, i1 %90, 1
+  %94 = extractvalue { i64, i1 } %93, 0
+  %95 = extractvalue { i64, i1 } %93, 1
+  br i1 %95, label %_llgo_8, label %_llgo_7
+
+_llgo_6:                                          ; preds = %_llgo_2
+  %96 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store double 1.500000e+00, ptr %96, align 8
+  %97 = insertvalue { ptr, ptr } { ptr @__llgo_type.float64, ptr undef }, ptr %96, 1
+  store i64 0, ptr %6, align 4
+  store { ptr, ptr } %97, ptr %5, align 8
+  %98 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %34, ptr %5, ptr %6)
+  %99 = load i64, ptr %6, align 4
+  %100 = icmp eq i64 %99, 5
+  br i1 %100, label %_llgo_4, label %_llgo_5
+
+_llgo_7:                                          ; preds = %_llgo_5
+  %101 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %34)
+  %102 = icmp eq i64 %101, 5
+  br label %_llgo_8
+
+_llgo_8:                                          ; preds = %_llgo_7, %_llgo_5
+  %103 = phi i1 [ false, %_llgo_5 ], [ %102, %_llgo_7 ]
+  call fastcc void @main.check(i1 %103, { ptr, i64 } { ptr @18, i64 13 })
+  ret void
+}
+
+define fastcc void @main.structKeys() {
+_llgo_0:
+  %0 = alloca { ptr, ptr }, align 8
+  %1 = alloca { ptr, ptr }, align 8
+  %2 = alloca %named, align 8
+  %3 = alloca %named, align 8
+  %4 = alloca { ptr, ptr }, align 8
+  %5 = alloca i64, align 8
+  %6 = alloca [2 x { ptr, i64 }], align 8
+  %7 = alloca { ptr, ptr }, align 8
+  %8 = alloca i64, align 8
+  %9 = alloca %named, align 8
+  %10 = alloca i64, align 8
+  %11 = alloca { ptr, ptr }, align 8
+  %12 = alloca { ptr, ptr }, align 8
+  %13 = alloca i64, align 8
+  %14 = alloca [2 x { ptr, i64 }], align 8
+  %15 = alloca i64, align 8
+  %16 = alloca { ptr, ptr }, align 8
+  %17 = alloca [2 x { ptr, i64 }], align 8
+  %18 = alloca i64, align 8
+  %19 = alloca { ptr, ptr }, align 8
+  %20 = alloca { ptr, ptr }, align 8
+  %21 = alloca i64, align 8
+  %22 = alloca %named, align 8
+  %23 = alloca i64, align 8
+  %24 = alloca { ptr, ptr }, align 8
+  %25 = alloca %named, align 8
+  %26 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 16, i64 8, { ptr, ptr } { ptr @"__llgo_hash.any$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.any$stub", ptr null }, i64 0)
+  %27 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 2)
+  %28 = getelementptr inbounds i8, ptr %27, i64 0
+  store i8 97, ptr %28, align 1
+  %29 = getelementptr inbounds i8, ptr %27, i64 1
+  store i8 98, ptr %29, align 1
+  %30 = insertvalue { ptr, i64, i64 } undef, ptr %27, 0
+  %31 = insertvalue { ptr, i64, i64 } %30, i64 2, 1
+  %32 = insertvalue { ptr, i64, i64 } %31, i64 2, 2
+  %33 = call { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.BytesToString"({ ptr, i64, i64 } %32)
+  store %named zeroinitializer, ptr %25, align 8
+  %34 = getelementptr inbounds %named, ptr %25, i32 0, i32 0
+  %35 = getelementptr inbounds %named, ptr %25, i32 0, i32 1
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %34, align 8
+  store double 0.000000e+00, ptr %35, align 8
+  %36 = load %named, ptr %25, align 8
+  %37 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  store %named %36, ptr %37, align 8
+  %38 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.named, ptr undef }, ptr %37, 1
+  store { ptr, ptr } %38, ptr %24, align 8
+  store i64 1, ptr %23, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %26, ptr %24, ptr %23)
+  store %named zeroinitializer, ptr %22, align 8
+  %39 = getelementptr inbounds %named, ptr %22, i32 0, i32 0
+  %40 = getelementptr inbounds %named, ptr %22, i32 0, i32 1
+  %41 = call fastcc double @main.negZero()
+  store { ptr, i64 } %33, ptr %39, align 8
+  store double %41, ptr %40, align 8
+  %42 = load %named, ptr %22, align 8
+  %43 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  store %named %42, ptr %43, align 8
+  %44 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.named, ptr undef }, ptr %43, 1
+  store i64 0, ptr %21, align 4
+  store { ptr, ptr } %44, ptr %20, align 8
+  %45 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %26, ptr %20, ptr %21)
+  %46 = load i64, ptr %21, align 4
+  %47 = add i64 %46, 1
+  store { ptr, ptr } %44, ptr %19, align 8
+  store i64 %47, ptr %18, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %26, ptr %19, ptr %18)
+  store [2 x { ptr, i64 }] zeroinitializer, ptr %17, align 8
+  %48 = getelementptr inbounds { ptr, i64 }, ptr %17, i64 0
+  %49 = getelementptr inbounds { ptr, i64 }, ptr %17, i64 1
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %48, align 8
+  store { ptr, i64 } %33, ptr %49, align 8
+  %50 = load [2 x { ptr, i64 }], ptr %17, align 8
+  %51 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 32)
+  store [2 x { ptr, i64 }] %50, ptr %51, align 8
+  %52 = insertvalue { ptr, ptr } { ptr @"__llgo_type.[2]string", ptr undef }, ptr %51, 1
+  store { ptr, ptr } %52, ptr %16, align 8
+  store i64 3, ptr %15, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %26, ptr %16, ptr %15)
+  store [2 x { ptr, i64 }] zeroinitializer, ptr %14, align 8
+  %53 = getelementptr inbounds { ptr, i64 }, ptr %14, i64 0
+  %54 = getelementptr inbounds { ptr, i64 }, ptr %14, i64 1
+  store { ptr, i64 } %33, ptr %53, align 8
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %54, align 8
+  %55 = load [2 x { ptr, i64 }], ptr %14, align 8
+  %56 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 32)
+  store [2 x { ptr, i64 }] %55, ptr %56, align 8
+  %57 = insertvalue { ptr, ptr } { ptr @"__llgo_type.[2]string", ptr undef }, ptr %56, 1
+  store i64 0, ptr %13, align 4
+  store { ptr, ptr } %57, ptr %12, align 8
+  %58 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %26, ptr %12, ptr %13)
+  %59 = load i64, ptr %13, align 4
+  %60 = add i64 %59, 1
+  store { ptr, ptr } %57, ptr %11, align 8
+  store i64 %60, ptr %10, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %26, ptr %11, ptr %10)
+  %61 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %26)
+  %62 = icmp eq i64 %61, 2
+  call fastcc void @main.check(i1 %62, { ptr, i64 } { ptr @23, i64 11 })
+  store %named zeroinitializer, ptr %9, align 8
+  %63 = getelementptr inbounds %named, ptr %9, i32 0, i32 0
+  %64 = getelementptr inbounds %named, ptr %9, i32 0, i32 1
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %63, align 8
+  store double 0.000000e+00, ptr %64, align 8
+  %65 = load %named, ptr %9, align 8
+  %66 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  store %named %65, ptr %66, align 8
+  %67 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.named, ptr undef }, ptr %66, 1
+  store i64 0, ptr %8, align 4
+  store { ptr, ptr } %67, ptr %7, align 8
+  %68 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %26, ptr %7, ptr %8)
+  %69 = load i64, ptr %8, align 4
+  %70 = icmp eq i64 %69, 2
+  br i1 %70, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store [2 x { ptr, i64 }] zeroinitializer, ptr %6, align 8
+  %71 = getelementptr inbounds { ptr, i64 }, ptr %6, i64 0
+  %72 = getelementptr inbounds { ptr, i64 }, ptr %6, i64 1
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %71, align 8
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %72, align 8
+  %73 = load [2 x { ptr, i64 }], ptr %6, align 8
+  %74 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 32)
+  store [2 x { ptr, i64 }] %73, ptr %74, align 8
+  %75 = insertvalue { ptr, ptr } { ptr @"__llgo_type.[2]string", ptr undef }, ptr %74, 1
+  store i64 0, ptr %5, align 4
+  store { ptr, ptr } %75, ptr %4, align 8
+  %76 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %26, ptr %4, ptr %5)
+  %77 = load i64, ptr %5, align 4
+  %78 = icmp eq i64 %77, 4
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  %79 = phi i1 [ false, %_llgo_0 ], [ %78, %_llgo_1 ]
+  call fastcc void @main.check(i1 %79, { ptr, i64 } { ptr @24, i64 14 })
+  store %named zeroinitializer, ptr %3, align 8
+  %80 = getelementptr inbounds %named, ptr %3, i32 0, i32 0
+  %81 = getelementptr inbounds %named, ptr %3, i32 0, i32 1
+  store { ptr, i64 } { ptr @19, i64 2 }, ptr %80, align 8
+  store double 0.000000e+00, ptr %81, align 8
+  %82 = load %named, ptr %3, align 8
+  %83 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  store %named %82, ptr %83, align 8
+  %84 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.named, ptr undef }, ptr %83, 1
+  store %named zeroinitializer, ptr %2, align 8
+  %85 = getelementptr inbounds %named, ptr %2, i32 0, i32 0
+  %86 = getelementptr inbounds %named, ptr %2, i32 0, i32 1
+  %87 = call fastcc double @main.negZero()
+  store { ptr, i64 } %33, ptr %85, align 8
+  store double %87, ptr %86, align 8
+  %88 = load %named, ptr %2, align 8
+  %89 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  store %named %88, ptr %89, align 8
+  %90 = insertvalue { ptr, ptr } { ptr @__llgo_type.main.named, ptr undef }, ptr %89, 1
+  store { ptr, ptr } %84, ptr %1, align 8
+  store { ptr, ptr } %90, ptr %0, align 8
+  %91 = call i1 @"__llgo_equal.interface{}"(ptr %1, ptr %0)
+  call fastcc void @main.check(i1 %91, { ptr, i64 } { ptr @25, i64 10 })
+  ret void
+}
+
+define fastcc double @main.negZero() {
+_llgo_0:
+  ret double -0.000000e+00
+}
+
+define fastcc void @main.unhashable() {
+_llgo_0:
+  %0 = alloca i64, align 8
+  %1 = alloca { ptr, ptr }, align 8
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %3 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 16, i64 8, { ptr, ptr } { ptr @"__llgo_hash.any$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.any$stub", ptr null }, i64 0)
+  store ptr %3, ptr %2, align 8
+  call void @"main.unhashable$1"(ptr %2)
+  %4 = load ptr, ptr %2, align 8
+  %5 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %5, align 4
+  %6 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %5, 1
+  store { ptr, ptr } %6, ptr %1, align 8
+  store i64 1, ptr %0, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %4, ptr %1, ptr %0)
+  %7 = load ptr, ptr %2, align 8
+  %8 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %7)
+  %9 = icmp eq i64 %8, 1
+  tail call fastcc void @main.check(i1 %9, { ptr, i64 } { ptr @28, i64 15 })
+  ret void
+}
+
+define void @"main.unhashable$1"(ptr %0) personality ptr @__gcc_personality_v0 {
+_llgo_0:
+  %1 = alloca i64, align 8
+  %2 = alloca { ptr, ptr }, align 8
+  %3 = alloca i8, align 1
+  store i8 0, ptr %3, align 1
+  %4 = load i8, ptr %3, align 1
+  %5 = or i8 %4, 1
+  store i8 %5, ptr %3, align 1
+  %6 = load ptr, ptr %0, align 8
+  %7 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %8 = getelementptr inbounds i64, ptr %7, i64 0
+  store i64 1, ptr %8, align 4
+  %9 = insertvalue { ptr, i64, i64 } undef, ptr %7, 0
+  %10 = insertvalue { ptr, i64, i64 } %9, i64 1, 1
+  %11 = insertvalue { ptr, i64, i64 } %10, i64 1, 2
+  %12 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 24)
+  store { ptr, i64, i64 } %11, ptr %12, align 8
+  %13 = insertvalue { ptr, ptr } { ptr @"__llgo_type.[]int", ptr undef }, ptr %12, 1
+  store { ptr, ptr } %13, ptr %2, align 8
+  store i64 1, ptr %1, align 4
+  invoke void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %6, ptr %2, ptr %1)
+          to label %_llgo_3 unwind label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_8
+  ret void
+
+_llgo_2:                                          ; preds = %_llgo_7, %_llgo_4, %_llgo_0
+  %14 = landingpad { ptr, i32 }
+          cleanup
+  %15 = extractvalue { ptr, i32 } %14, 0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr %15, ptr %3)
+  %16 = load i8, ptr %3, align 1
+  %17 = and i8 %16, 1
+  %18 = icmp ne i8 %17, 0
+  br i1 %18, label %_llgo_7, label %_llgo_8
+
+_llgo_3:                                          ; preds = %_llgo_0
+  %19 = load i8, ptr %3, align 1
+  %20 = and i8 %19, 1
+  %21 = icmp ne i8 %20, 0
+  br i1 %21, label %_llgo_4, label %_llgo_5
+
+_llgo_4:                                          ; preds = %_llgo_3
+  %22 = and i8 %19, -2
+  store i8 %22, ptr %3, align 1
+  invoke void @"main.unhashable$1$1"()
+          to label %_llgo_6 unwind label %_llgo_2
+
+_llgo_5:                                          ; preds = %_llgo_6, %_llgo_3
+  ret void
+
+_llgo_6:                                          ; preds = %_llgo_4
+  br label %_llgo_5
+
+_llgo_7:                                          ; preds = %_llgo_2
+  %23 = and i8 %16, -2
+  store i8 %23, ptr %3, align 1
+  invoke void @"main.unhashable$1$1"()
+          to label %_llgo_9 unwind label %_llgo_2
+
+_llgo_8:                                          ; preds = %_llgo_9, %_llgo_2
+  %24 = call i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr %15)
+  br i1 %24, label %_llgo_1, label %_llgo_10
+
+_llgo_9:                                          ; preds = %_llgo_7
+  br label %_llgo_8
+
+_llgo_10:                                         ; preds = %_llgo_8
+  %25 = insertvalue { ptr, i32 } undef, ptr %15, 0
+  %26 = insertvalue { ptr, i32 } %25, i32 0, 1
+  resume { ptr, i32 } %26
+}
+
+define void @"main.unhashable$1$1"() {
+_llgo_0:
+  %0 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.Recover"()
+  %1 = extractvalue { ptr, ptr } %0, 0
+  %2 = icmp ne ptr %1, null
+  tail call fastcc void @main.check(i1 %2, { ptr, i64 } { ptr @26, i64 20 })
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  call fastcc void @main.grow(i64 1000)
+  call fastcc void @main.del(i64 100)
+  call fastcc void @main.mutate(i64 200)
+  call fastcc void @main.ifaceKeys()
+  call fastcc void @main.structKeys()
+  call fastcc void @main.unhashable()
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @29, i64 2 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @30, i64 1 })
+  ret i32 0
+}
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.string(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr })
+
+define linkonce_odr i64 @__llgo_hash.int(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.int$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.int(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.int(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.int$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.int(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64, i64, { ptr, ptr }, { ptr, ptr }, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr, ptr, ptr)
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr, ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapDelete"(ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapIterInit"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.MapIterNext"(ptr, ptr, ptr)
+
+define linkonce_odr i64 @__llgo_hash.any(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Interhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Interhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.any$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.any(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.any(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.any$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.any(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.int64(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.int64$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.int64(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.int64(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.int64$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.int64(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.main.point(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 16, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.main.point$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.main.point(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.main.point(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 16)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.main.point$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.main.point(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.float64(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.F64hash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.F64hash"(ptr, i64)
+
+define private i64 @"__llgo_hash.float64$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.float64(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.float64(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.F64equal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.F64equal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.float64$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.float64(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.BytesToString"({ ptr, i64, i64 })
+
+define linkonce_odr i64 @__llgo_hash.main.named(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %named, ptr %0, i32 0, i32 0
+  %3 = call i64 @__llgo_hash.string(ptr %2, i64 %1)
+  %4 = getelementptr inbounds %named, ptr %0, i32 0, i32 1
+  %5 = call i64 @__llgo_hash.float64(ptr %4, i64 %3)
+  ret i64 %5
+}
+
+define private i64 @"__llgo_hash.main.named$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.main.named(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.main.named(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %named, ptr %0, i32 0, i32 0
+  %3 = getelementptr inbounds %named, ptr %1, i32 0, i32 0
+  %4 = call i1 @__llgo_equal.string(ptr %2, ptr %3)
+  br i1 %4, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  ret i1 false
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %5 = getelementptr inbounds %named, ptr %0, i32 0, i32 1
+  %6 = getelementptr inbounds %named, ptr %1, i32 0, i32 1
+  %7 = call i1 @__llgo_equal.float64(ptr %5, ptr %6)
+  br i1 %7, label %_llgo_3, label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_2
+  ret i1 true
+}
+
+define private i1 @"__llgo_equal.main.named$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.main.named(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @"__llgo_hash.[2]string"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.ArrayHash"(ptr %0, i64 2, i64 16, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.ArrayHash"(ptr, i64, i64, { ptr, ptr }, i64)
+
+define private i64 @"__llgo_hash.[2]string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @"__llgo_hash.[2]string"(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.[2]string"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.ArrayEqual"(ptr %0, ptr %1, i64 2, i64 16, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null })
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.ArrayEqual"(ptr, ptr, i64, i64, { ptr, ptr })
+
+define private i1 @"__llgo_equal.[2]string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @"__llgo_equal.[2]string"(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.interface{}"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.Recover"()
+
+declare i32 @__gcc_personality_v0()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 })
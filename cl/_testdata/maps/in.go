@@ -0,0 +1,15 @@
+package main
+
+func main() {
+	m := make(map[string]int)
+	m["a"] = 1
+	m["b"] = 2
+	delete(m, "a")
+	_, ok := m["b"]
+	_ = ok
+	for k, v := range m {
+		m[k] = v + 1
+	}
+	clear(m)
+	_ = len(m)
+}
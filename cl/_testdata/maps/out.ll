@@ -0,0 +1,123 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [1 x i8] c"a"
+@1 = private unnamed_addr constant [1 x i8] c"b"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = alloca i64, align 8
+  %4 = alloca { ptr, i64 }, align 8
+  %5 = alloca i64, align 8
+  %6 = alloca { ptr, i64 }, align 8
+  %7 = alloca { ptr, ptr, i8, i8, i64, i64, ptr, i64 }, align 8
+  %8 = alloca { ptr, i64 }, align 8
+  %9 = alloca i64, align 8
+  %10 = alloca { ptr, i64 }, align 8
+  %11 = alloca i64, align 8
+  %12 = alloca { ptr, i64 }, align 8
+  %13 = alloca i64, align 8
+  %14 = alloca { ptr, i64 }, align 8
+  call void @main.init()
+  %15 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 16, i64 8, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null }, i64 0)
+  store { ptr, i64 } { ptr @0, i64 1 }, ptr %14, align 8
+  store i64 1, ptr %13, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %15, ptr %14, ptr %13)
+  store { ptr, i64 } { ptr @1, i64 1 }, ptr %12, align 8
+  store i64 2, ptr %11, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %15, ptr %12, ptr %11)
+  store { ptr, i64 } { ptr @0, i64 1 }, ptr %10, align 8
+  call void @"github.com/goplus/llgo/internal/runtime.MapDelete"(ptr %15, ptr %10)
+  store i64 0, ptr %9, align 4
+  store { ptr, i64 } { ptr @1, i64 1 }, ptr %8, align 8
+  %16 = call i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr %15, ptr %8, ptr %9)
+  %17 = load i64, ptr %9, align 4
+  %18 = insertvalue { i64, i1 } undef, i64 %17, 0
+  %19 = insertvalue { i64, i1 } %18, i1 %16, 1
+  %20 = extractvalue { i64, i1 } %19, 0
+  %21 = extractvalue { i64, i1 } %19, 1
+  call void @"github.com/goplus/llgo/internal/runtime.MapIterInit"(ptr %15, ptr %7)
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  %22 = call i1 @"github.com/goplus/llgo/internal/runtime.MapIterNext"(ptr %7, ptr %6, ptr %5)
+  %23 = load { ptr, i64 }, ptr %6, align 8
+  %24 = load i64, ptr %5, align 4
+  %25 = insertvalue { i1, { ptr, i64 }, i64 } undef, i1 %22, 0
+  %26 = insertvalue { i1, { ptr, i64 }, i64 } %25, { ptr, i64 } %23, 1
+  %27 = insertvalue { i1, { ptr, i64 }, i64 } %26, i64 %24, 2
+  %28 = extractvalue { i1, { ptr, i64 }, i64 } %27, 0
+  br i1 %28, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  %29 = extractvalue { i1, { ptr, i64 }, i64 } %27, 1
+  %30 = extractvalue { i1, { ptr, i64 }, i64 } %27, 2
+  %31 = add i64 %30, 1
+  store { ptr, i64 } %29, ptr %4, align 8
+  store i64 %31, ptr %3, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %15, ptr %4, ptr %3)
+  br label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_1
+  call void @"github.com/goplus/llgo/internal/runtime.MapClear"(ptr %15)
+  %32 = call i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr %15)
+  ret i32 0
+}
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.string(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64, i64, { ptr, ptr }, { ptr, ptr }, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr, ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapDelete"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.MapAccess"(ptr, ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapIterInit"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.MapIterNext"(ptr, ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapClear"(ptr)
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.MapLen"(ptr)
@@ -0,0 +1,21 @@
+package foo
+
+type T struct{ a, b int }
+
+func (t T) Sum() int { return t.a + t.b }
+
+func (t *T) Get() int { return t.a }
+
+type E struct{ T }
+
+func expr(t *T) int { return (*T).Sum(t) }
+
+func value(t *T) int {
+	get := t.Get
+	return get()
+}
+
+func promoted(e *E) int {
+	var s interface{ Sum() int } = e
+	return s.Sum()
+}
@@ -0,0 +1,91 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+%T = type { i64, i64 }
+%E = type { %T }
+
+@"foo.init$guard" = global ptr null
+@0 = private unnamed_addr constant [5 x i8] c"foo.T"
+@1 = private unnamed_addr constant [3 x i8] c"Sum"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.T.Sum(%T %0) {
+_llgo_0:
+  %1 = alloca %T, align 8
+  store %T zeroinitializer, ptr %1, align 4
+  store %T %0, ptr %1, align 4
+  %2 = getelementptr inbounds %T, ptr %1, i32 0, i32 0
+  %3 = load i64, ptr %2, align 4
+  %4 = getelementptr inbounds %T, ptr %1, i32 0, i32 1
+  %5 = load i64, ptr %4, align 4
+  %6 = add i64 %3, %5
+  ret i64 %6
+}
+
+define i64 @"foo.(*T).Get"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds %T, ptr %0, i32 0, i32 0
+  %2 = load i64, ptr %1, align 4
+  ret i64 %2
+}
+
+define i64 @foo.expr(ptr %0) {
+_llgo_0:
+  %1 = tail call i64 @"foo.(*T).Sum$thunk"(ptr %0)
+  ret i64 %1
+}
+
+define i64 @foo.value(ptr %0) {
+_llgo_0:
+  %1 = tail call i64 @"foo.(*T).Get$bound"(ptr %0)
+  ret i64 %1
+}
+
+define i64 @foo.promoted(ptr %0) {
+_llgo_0:
+  %1 = tail call i64 @"foo.(*E).Sum"(ptr %0)
+  ret i64 %1
+}
+
+define linkonce_odr i64 @"foo.(*T).Sum$thunk"(ptr %0) {
+_llgo_0:
+  %1 = icmp eq ptr %0, null
+  br i1 %1, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.WrapNilFailed"({ ptr, i64 } { ptr @0, i64 5 }, { ptr, i64 } { ptr @1, i64 3 })
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %2 = load %T, ptr %0, align 4
+  %3 = tail call i64 @foo.T.Sum(%T %2)
+  ret i64 %3
+}
+
+define linkonce_odr i64 @"foo.(*T).Get$bound"(ptr %0) {
+_llgo_0:
+  %1 = tail call i64 @"foo.(*T).Get"(ptr %0)
+  ret i64 %1
+}
+
+define linkonce_odr i64 @"foo.(*E).Sum"(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds %E, ptr %0, i32 0, i32 0
+  %2 = load %T, ptr %1, align 4
+  %3 = tail call i64 @foo.T.Sum(%T %2)
+  ret i64 %3
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.WrapNilFailed"({ ptr, i64 }, { ptr, i64 })
@@ -0,0 +1,23 @@
+package main
+
+import "net"
+
+func dial(network, address string) {
+	net.Dial(network, address)
+}
+
+func listen(network, address string) {
+	net.Listen(network, address)
+}
+
+func serve(l *net.TCPListener, buf []byte) {
+	c, _ := l.AcceptTCP()
+	n, _ := c.Read(buf)
+	c.Write(buf)
+	c.Close()
+	l.Close()
+	_ = n
+}
+
+func main() {
+}
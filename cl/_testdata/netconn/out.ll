@@ -0,0 +1,72 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%TCPConn = type { %conn }
+%conn = type { ptr }
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @main.dial({ ptr, i64 } %0, { ptr, i64 } %1) {
+_llgo_0:
+  %2 = call { { ptr, ptr }, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetDial"({ ptr, i64 } %0, { ptr, i64 } %1)
+  ret void
+}
+
+define void @main.listen({ ptr, i64 } %0, { ptr, i64 } %1) {
+_llgo_0:
+  %2 = call { { ptr, ptr }, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetListen"({ ptr, i64 } %0, { ptr, i64 } %1)
+  ret void
+}
+
+define void @main.serve(ptr %0, { ptr, i64, i64 } %1) {
+_llgo_0:
+  %2 = call { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.TCPListenerAcceptTCP"(ptr %0)
+  %3 = extractvalue { ptr, { ptr, ptr } } %2, 0
+  %4 = extractvalue { ptr, { ptr, ptr } } %2, 1
+  %5 = getelementptr inbounds %TCPConn, ptr %3, i32 0, i32 0
+  %6 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetConnRead"(ptr %5, { ptr, i64, i64 } %1)
+  %7 = extractvalue { i64, { ptr, ptr } } %6, 0
+  %8 = extractvalue { i64, { ptr, ptr } } %6, 1
+  %9 = getelementptr inbounds %TCPConn, ptr %3, i32 0, i32 0
+  %10 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetConnWrite"(ptr %9, { ptr, i64, i64 } %1)
+  %11 = getelementptr inbounds %TCPConn, ptr %3, i32 0, i32 0
+  %12 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.NetConnClose"(ptr %11)
+  %13 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.TCPListenerClose"(ptr %0)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  ret i32 0
+}
+
+declare { { ptr, ptr }, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetDial"({ ptr, i64 }, { ptr, i64 })
+
+declare { { ptr, ptr }, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetListen"({ ptr, i64 }, { ptr, i64 })
+
+declare { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.TCPListenerAcceptTCP"(ptr)
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetConnRead"(ptr, { ptr, i64, i64 })
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.NetConnWrite"(ptr, { ptr, i64, i64 })
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.NetConnClose"(ptr)
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.TCPListenerClose"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
@@ -0,0 +1,83 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@"github.com/goplus/llgo/internal/runtime.Stdout" = external global ptr
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @main.cat({ ptr, i64 } %0, { ptr, i64, i64 } %1) {
+_llgo_0:
+  %2 = call { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsOpen"({ ptr, i64 } %0)
+  %3 = extractvalue { ptr, { ptr, ptr } } %2, 0
+  %4 = extractvalue { ptr, { ptr, ptr } } %2, 1
+  %5 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.FileRead"(ptr %3, { ptr, i64, i64 } %1)
+  %6 = extractvalue { i64, { ptr, ptr } } %5, 0
+  %7 = extractvalue { i64, { ptr, ptr } } %5, 1
+  %8 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.FileClose"(ptr %3)
+  %9 = load ptr, ptr @"github.com/goplus/llgo/internal/runtime.Stdout", align 8
+  %10 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.FileWrite"(ptr %9, { ptr, i64, i64 } %1)
+  ret void
+}
+
+define void @main.create({ ptr, i64 } %0, { ptr, i64 } %1) {
+_llgo_0:
+  %2 = call { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsCreate"({ ptr, i64 } %0)
+  %3 = extractvalue { ptr, { ptr, ptr } } %2, 0
+  %4 = extractvalue { ptr, { ptr, ptr } } %2, 1
+  %5 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.FileWriteString"(ptr %3, { ptr, i64 } %1)
+  %6 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.FileClose"(ptr %3)
+  ret void
+}
+
+define void @main.echo({ ptr, i64, i64 } %0) {
+_llgo_0:
+  %1 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.SyscallRead"(i64 0, { ptr, i64, i64 } %0)
+  %2 = call { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.SyscallWrite"(i64 1, { ptr, i64, i64 } %0)
+  %3 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.SyscallClose"(i64 0)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  call void @"github.com/goplus/llgo/internal/runtime.OsExit"(i64 0)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+
+declare { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsOpen"({ ptr, i64 })
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.FileRead"(ptr, { ptr, i64, i64 })
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.FileClose"(ptr)
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.FileWrite"(ptr, { ptr, i64, i64 })
+
+declare { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsCreate"({ ptr, i64 })
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.FileWriteString"(ptr, { ptr, i64 })
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.SyscallRead"(i64, { ptr, i64, i64 })
+
+declare { i64, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.SyscallWrite"(i64, { ptr, i64, i64 })
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.SyscallClose"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.OsExit"(i64)
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"runtime/pprof"
+)
+
+func main() {
+	f, _ := os.Create("cpu.prof")
+	pprof.StartCPUProfile(f)
+	pprof.StopCPUProfile()
+	f.Close()
+	h, _ := os.Create("heap.prof")
+	pprof.WriteHeapProfile(h)
+	h.Close()
+}
@@ -0,0 +1,87 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [8 x i8] c"cpu.prof"
+@"__llgo_type.*os.File" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 22, i32 502357917, { ptr, i64 } { ptr @1, i64 8 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.*os.File$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.*os.File$stub", ptr null } }
+@1 = private unnamed_addr constant [8 x i8] c"*os.File"
+@2 = private unnamed_addr constant [9 x i8] c"heap.prof"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+  call void @"github.com/goplus/llgo/internal/runtime.PprofInit"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  %3 = call { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsCreate"({ ptr, i64 } { ptr @0, i64 8 })
+  %4 = extractvalue { ptr, { ptr, ptr } } %3, 0
+  %5 = extractvalue { ptr, { ptr, ptr } } %3, 1
+  %6 = insertvalue { ptr, ptr } { ptr @"__llgo_type.*os.File", ptr undef }, ptr %4, 1
+  %7 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.PprofStartCPUProfile"({ ptr, ptr } %6)
+  call void @"github.com/goplus/llgo/internal/runtime.PprofStopCPUProfile"()
+  %8 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.FileClose"(ptr %4)
+  %9 = call { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsCreate"({ ptr, i64 } { ptr @2, i64 9 })
+  %10 = extractvalue { ptr, { ptr, ptr } } %9, 0
+  %11 = extractvalue { ptr, { ptr, ptr } } %9, 1
+  %12 = insertvalue { ptr, ptr } { ptr @"__llgo_type.*os.File", ptr undef }, ptr %10, 1
+  %13 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.PprofWriteHeapProfile"({ ptr, ptr } %12)
+  %14 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.FileClose"(ptr %10)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PprofInit"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
+
+declare { ptr, { ptr, ptr } } @"github.com/goplus/llgo/internal/runtime.OsCreate"({ ptr, i64 })
+
+define linkonce_odr i64 @"__llgo_hash.*os.File"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.*os.File$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @"__llgo_hash.*os.File"(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.*os.File"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.*os.File$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @"__llgo_equal.*os.File"(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.PprofStartCPUProfile"({ ptr, ptr })
+
+declare void @"github.com/goplus/llgo/internal/runtime.PprofStopCPUProfile"()
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.FileClose"(ptr)
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.PprofWriteHeapProfile"({ ptr, ptr })
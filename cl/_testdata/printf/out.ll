@@ -26,9 +26,9 @@ _llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
 
 declare void @printf(ptr, ...)
 
-define void @main() {
+define i32 @main(i32 %0, ptr %1, ptr %2) {
 _llgo_0:
   call void @main.init()
   call void (ptr, ...) @printf(ptr @main.hello)
-  ret void
+  ret i32 0
 }
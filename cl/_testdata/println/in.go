@@ -0,0 +1,6 @@
+package foo
+
+func show(n int8, f float32, c complex64, p *int, s string) {
+	println(n, f, c, p, s, true)
+	print(3.14)
+}
@@ -0,0 +1,52 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@0 = private unnamed_addr constant [1 x i8] c" "
+@1 = private unnamed_addr constant [1 x i8] c"\0A"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @foo.show(i8 %0, float %1, { float, float } %2, ptr %3, { ptr, i64 } %4) {
+_llgo_0:
+  %5 = sext i8 %0 to i64
+  call void @"github.com/goplus/llgo/internal/runtime.PrintInt"(i64 %5)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintFloat32"(float %1)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintComplex64"({ float, float } %2)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintPointer"(ptr %3)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } %4)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintBool"(i1 true)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @1, i64 1 })
+  call void @"github.com/goplus/llgo/internal/runtime.PrintFloat"(double 3.140000e+00)
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintInt"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 })
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintFloat32"(float)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintComplex64"({ float, float })
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintPointer"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintBool"(i1)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintFloat"(double)
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/goplus/llgo/py"
+	"github.com/goplus/llgo/py/math"
+)
+
+func main() {
+	x := py.Float(2)
+	y := math.Sqrt(x)
+	py.DecRef(x)
+	py.DecRef(y)
+}
@@ -0,0 +1,54 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%Object = type { [8 x i8] }
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [5 x i8] c"math\00", align 1
+@1 = private unnamed_addr constant [5 x i8] c"sqrt\00", align 1
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/py.init"()
+  call void @"github.com/goplus/llgo/py/math.init"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call ptr @PyFloat_FromDouble(double 2.000000e+00)
+  call void @Py_Initialize()
+  %4 = call ptr @PyImport_ImportModule(ptr @0)
+  %5 = call ptr @PyObject_GetAttrString(ptr %4, ptr @1)
+  call void @Py_DecRef(ptr %4)
+  %6 = call ptr (ptr, ...) @PyObject_CallFunctionObjArgs(ptr %5, ptr %3, ptr null)
+  call void @Py_DecRef(ptr %5)
+  call void @Py_DecRef(ptr %3)
+  call void @Py_DecRef(ptr %6)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/py.init"()
+
+declare void @"github.com/goplus/llgo/py/math.init"()
+
+declare ptr @PyFloat_FromDouble(double)
+
+declare void @Py_Initialize()
+
+declare ptr @PyImport_ImportModule(ptr)
+
+declare ptr @PyObject_GetAttrString(ptr, ptr)
+
+declare void @Py_DecRef(ptr)
+
+declare ptr @PyObject_CallFunctionObjArgs(ptr, ...)
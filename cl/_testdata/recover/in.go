@@ -0,0 +1,35 @@
+package main
+
+var recovered any
+
+func catch() {
+	recovered = recover()
+}
+
+func safe() (ok bool) {
+	defer catch()
+	fail()
+	return true
+}
+
+func fail() {
+	panic("boom")
+}
+
+// assert fails the type assertion of x, unless x is a string: the runtime
+// error of the failure is recovered too.
+func assert(x any) (ok bool) {
+	defer catch()
+	_ = x.(string)
+	return true
+}
+
+func main() {
+	if safe() || recovered == nil {
+		panic("not recovered")
+	}
+	recovered = nil
+	if assert(1) || recovered == nil {
+		panic("type assertion not recovered")
+	}
+}
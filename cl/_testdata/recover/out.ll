@@ -0,0 +1,326 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.recovered = global { ptr, ptr } zeroinitializer
+@0 = private unnamed_addr constant [4 x i8] c"boom"
+@__llgo_type.string = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 24, i32 398550328, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null } }
+@1 = private unnamed_addr constant [6 x i8] c"string"
+@__llgo_type.any = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 20, i32 740945997, { ptr, i64 } { ptr @2, i64 3 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.any$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.any$stub", ptr null } }
+@2 = private unnamed_addr constant [3 x i8] c"any"
+@3 = private unnamed_addr constant [13 x i8] c"not recovered"
+@__llgo_type.int = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 2, i32 -1779859874, { ptr, i64 } { ptr @4, i64 3 }, { ptr, i64 } { ptr @4, i64 3 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null } }
+@4 = private unnamed_addr constant [3 x i8] c"int"
+@5 = private unnamed_addr constant [28 x i8] c"type assertion not recovered"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc void @main.catch() {
+_llgo_0:
+  %0 = call { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.Recover"()
+  store { ptr, ptr } %0, ptr @main.recovered, align 8
+  ret void
+}
+
+define fastcc i1 @main.safe() personality ptr @__gcc_personality_v0 {
+_llgo_0:
+  %0 = alloca i1, align 1
+  %1 = alloca i8, align 1
+  store i8 0, ptr %1, align 1
+  store i1 false, ptr %0, align 1
+  %2 = load i8, ptr %1, align 1
+  %3 = or i8 %2, 1
+  store i8 %3, ptr %1, align 1
+  invoke fastcc void @main.fail()
+          to label %_llgo_3 unwind label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_8
+  %4 = load i1, ptr %0, align 1
+  ret i1 %4
+
+_llgo_2:                                          ; preds = %_llgo_7, %_llgo_4, %_llgo_0
+  %5 = landingpad { ptr, i32 }
+          cleanup
+  %6 = extractvalue { ptr, i32 } %5, 0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr %6, ptr %1)
+  %7 = load i8, ptr %1, align 1
+  %8 = and i8 %7, 1
+  %9 = icmp ne i8 %8, 0
+  br i1 %9, label %_llgo_7, label %_llgo_8
+
+_llgo_3:                                          ; preds = %_llgo_0
+  store i1 true, ptr %0, align 1
+  %10 = load i8, ptr %1, align 1
+  %11 = and i8 %10, 1
+  %12 = icmp ne i8 %11, 0
+  br i1 %12, label %_llgo_4, label %_llgo_5
+
+_llgo_4:                                          ; preds = %_llgo_3
+  %13 = and i8 %10, -2
+  store i8 %13, ptr %1, align 1
+  invoke fastcc void @main.catch()
+          to label %_llgo_6 unwind label %_llgo_2
+
+_llgo_5:                                          ; preds = %_llgo_6, %_llgo_3
+  %14 = load i1, ptr %0, align 1
+  ret i1 %14
+
+_llgo_6:                                          ; preds = %_llgo_4
+  br label %_llgo_5
+
+_llgo_7:                                          ; preds = %_llgo_2
+  %15 = and i8 %7, -2
+  store i8 %15, ptr %1, align 1
+  invoke fastcc void @main.catch()
+          to label %_llgo_9 unwind label %_llgo_2
+
+_llgo_8:                                          ; preds = %_llgo_9, %_llgo_2
+  %16 = call i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr %6)
+  br i1 %16, label %_llgo_1, label %_llgo_10
+
+_llgo_9:                                          ; preds = %_llgo_7
+  br label %_llgo_8
+
+_llgo_10:                                         ; preds = %_llgo_8
+  %17 = insertvalue { ptr, i32 } undef, ptr %6, 0
+  %18 = insertvalue { ptr, i32 } %17, i32 0, 1
+  resume { ptr, i32 } %18
+}
+
+define fastcc void @main.fail() {
+_llgo_0:
+  %0 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } { ptr @0, i64 4 }, ptr %0, align 8
+  %1 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %0, 1
+  call void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr } %1)
+  unreachable
+}
+
+define fastcc i1 @main.assert({ ptr, ptr } %0) personality ptr @__gcc_personality_v0 {
+_llgo_0:
+  %1 = alloca i1, align 1
+  %2 = alloca i8, align 1
+  store i8 0, ptr %2, align 1
+  store i1 false, ptr %1, align 1
+  %3 = load i8, ptr %2, align 1
+  %4 = or i8 %3, 1
+  store i8 %4, ptr %2, align 1
+  %5 = extractvalue { ptr, ptr } %0, 0
+  %6 = icmp eq ptr %5, @__llgo_type.string
+  br i1 %6, label %_llgo_4, label %_llgo_3
+
+_llgo_1:                                          ; preds = %_llgo_10
+  %7 = load i1, ptr %1, align 1
+  ret i1 %7
+
+_llgo_2:                                          ; preds = %_llgo_9, %_llgo_6, %_llgo_3
+  %8 = landingpad { ptr, i32 }
+          cleanup
+  %9 = extractvalue { ptr, i32 } %8, 0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr %9, ptr %2)
+  %10 = load i8, ptr %2, align 1
+  %11 = and i8 %10, 1
+  %12 = icmp ne i8 %11, 0
+  br i1 %12, label %_llgo_9, label %_llgo_10
+
+_llgo_3:                                          ; preds = %_llgo_0
+  %13 = extractvalue { ptr, ptr } %0, 0
+  invoke void @"github.com/goplus/llgo/internal/runtime.TypeAssertFailed"(ptr @__llgo_type.any, ptr %13, ptr @__llgo_type.string)
+          to label %_llgo_5 unwind label %_llgo_2
+
+_llgo_4:                                          ; preds = %_llgo_0
+  %14 = extractvalue { ptr, ptr } %0, 1
+  %15 = load { ptr, i64 }, ptr %14, align 8
+  store i1 true, ptr %1, align 1
+  %16 = load i8, ptr %2, align 1
+  %17 = and i8 %16, 1
+  %18 = icmp ne i8 %17, 0
+  br i1 %18, label %_llgo_6, label %_llgo_7
+
+_llgo_5:                                          ; preds = %_llgo_3
+  unreachable
+
+_llgo_6:                                          ; preds = %_llgo_4
+  %19 = and i8 %16, -2
+  store i8 %19, ptr %2, align 1
+  invoke fastcc void @main.catch()
+          to label %_llgo_8 unwind label %_llgo_2
+
+_llgo_7:                                          ; preds = %_llgo_8, %_llgo_4
+  %20 = load i1, ptr %1, align 1
+  ret i1 %20
+
+_llgo_8:                                          ; preds = %_llgo_6
+  br label %_llgo_7
+
+_llgo_9:                                          ; preds = %_llgo_2
+  %21 = and i8 %10, -2
+  store i8 %21, ptr %2, align 1
+  invoke fastcc void @main.catch()
+          to label %_llgo_11 unwind label %_llgo_2
+
+_llgo_10:                                         ; preds = %_llgo_11, %_llgo_2
+  %22 = call i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr %9)
+  br i1 %22, label %_llgo_1, label %_llgo_12
+
+_llgo_11:                                         ; preds = %_llgo_9
+  br label %_llgo_10
+
+_llgo_12:                                         ; preds = %_llgo_10
+  %23 = insertvalue { ptr, i32 } undef, ptr %9, 0
+  %24 = insertvalue { ptr, i32 } %23, i32 0, 1
+  resume { ptr, i32 } %24
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i1 @main.safe()
+  br i1 %3, label %_llgo_1, label %_llgo_3
+
+_llgo_1:                                          ; preds = %_llgo_3, %_llgo_0
+  %4 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } { ptr @3, i64 13 }, ptr %4, align 8
+  %5 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %4, 1
+  call void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr } %5)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_3
+  store { ptr, ptr } zeroinitializer, ptr @main.recovered, align 8
+  %6 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 1, ptr %6, align 4
+  %7 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %6, 1
+  %8 = call fastcc i1 @main.assert({ ptr, ptr } %7)
+  br i1 %8, label %_llgo_4, label %_llgo_6
+
+_llgo_3:                                          ; preds = %_llgo_0
+  %9 = load { ptr, ptr }, ptr @main.recovered, align 8
+  %10 = extractvalue { ptr, ptr } %9, 0
+  %11 = icmp eq ptr %10, null
+  br i1 %11, label %_llgo_1, label %_llgo_2
+
+_llgo_4:                                          ; preds = %_llgo_6, %_llgo_2
+  %12 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } { ptr @5, i64 28 }, ptr %12, align 8
+  %13 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %12, 1
+  call void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr } %13)
+  unreachable
+
+_llgo_5:                                          ; preds = %_llgo_6
+  ret i32 0
+
+_llgo_6:                                          ; preds = %_llgo_2
+  %14 = load { ptr, ptr }, ptr @main.recovered, align 8
+  %15 = extractvalue { ptr, ptr } %14, 0
+  %16 = icmp eq ptr %15, null
+  br i1 %16, label %_llgo_4, label %_llgo_5
+}
+
+declare { ptr, ptr } @"github.com/goplus/llgo/internal/runtime.Recover"()
+
+declare i32 @__gcc_personality_v0()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicDefers"(ptr, ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.PanicRecovered"(ptr)
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.string(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr })
+
+declare void @"github.com/goplus/llgo/internal/runtime.TypeAssertFailed"(ptr, ptr, ptr)
+
+define linkonce_odr i64 @__llgo_hash.any(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Interhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Interhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.any$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.any(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.any(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.any$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.any(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.int(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.int$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.int(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.int(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.int$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.int(ptr %1, ptr %2)
+  ret i1 %3
+}
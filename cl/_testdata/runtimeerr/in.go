@@ -0,0 +1,21 @@
+package foo
+
+func div(x, y int) (int, int) { return x / y, x % y }
+
+func udiv(x, y uint8) uint8 { return x / y }
+
+func half(x int) int { return x / 2 }
+
+func at(s []int, i uint) int { return s[i] }
+
+func set(a *[4]int, i int) { a[i], a[3] = i, i }
+
+func sub(s []int, i, j int) []int { return s[i:j] }
+
+func tail(s string, i uint) string { return s[i:] }
+
+func sub3(a *[8]int, i, j, k int) []int { return a[i:j:k] }
+
+func head(a *[8]int) []int { return a[2:4] }
+
+func arr(s []byte) *[4]byte { return (*[4]byte)(s) }
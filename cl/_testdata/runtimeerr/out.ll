@@ -0,0 +1,231 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define { i64, i64 } @foo.div(i64 %0, i64 %1) {
+_llgo_0:
+  %2 = icmp eq i64 %1, 0
+  br i1 %2, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDivide"()
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %3 = icmp eq i64 %1, -1
+  %4 = select i1 %3, i64 1, i64 %1
+  %5 = sdiv i64 %0, %4
+  %6 = sub i64 0, %0
+  %7 = select i1 %3, i64 %6, i64 %5
+  %8 = icmp eq i64 %1, 0
+  br i1 %8, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDivide"()
+  unreachable
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %9 = icmp eq i64 %1, -1
+  %10 = select i1 %9, i64 1, i64 %1
+  %11 = srem i64 %0, %10
+  %12 = select i1 %9, i64 0, i64 %11
+  %mrv = insertvalue { i64, i64 } undef, i64 %7, 0
+  %mrv1 = insertvalue { i64, i64 } %mrv, i64 %12, 1
+  ret { i64, i64 } %mrv1
+}
+
+define i8 @foo.udiv(i8 %0, i8 %1) {
+_llgo_0:
+  %2 = icmp eq i8 %1, 0
+  br i1 %2, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicDivide"()
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %3 = udiv i8 %0, %1
+  ret i8 %3
+}
+
+define i64 @foo.half(i64 %0) {
+_llgo_0:
+  %1 = sdiv i64 %0, 2
+  ret i64 %1
+}
+
+define i64 @foo.at({ ptr, i64, i64 } %0, i64 %1) {
+_llgo_0:
+  %2 = extractvalue { ptr, i64, i64 } %0, 1
+  %3 = icmp uge i64 %1, %2
+  br i1 %3, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicIndexU"(i64 %1, i64 %2)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %4 = extractvalue { ptr, i64, i64 } %0, 0
+  %5 = getelementptr inbounds i64, ptr %4, i64 %1
+  %6 = load i64, ptr %5, align 4
+  ret i64 %6
+}
+
+define void @foo.set(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = icmp uge i64 %1, 4
+  br i1 %2, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64 %1, i64 4)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %3 = getelementptr inbounds i64, ptr %0, i64 %1
+  store i64 %1, ptr %3, align 4
+  %4 = getelementptr inbounds i64, ptr %0, i64 3
+  store i64 %1, ptr %4, align 4
+  ret void
+}
+
+define { ptr, i64, i64 } @foo.sub({ ptr, i64, i64 } %0, i64 %1, i64 %2) {
+_llgo_0:
+  %3 = extractvalue { ptr, i64, i64 } %0, 0
+  %4 = extractvalue { ptr, i64, i64 } %0, 1
+  %5 = extractvalue { ptr, i64, i64 } %0, 2
+  %6 = icmp ugt i64 %2, %5
+  br i1 %6, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSliceAcap"(i64 %2, i64 %5)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %7 = icmp ugt i64 %1, %2
+  br i1 %7, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSliceB"(i64 %1, i64 %2)
+  unreachable
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %8 = getelementptr inbounds i64, ptr %3, i64 %1
+  %9 = sub i64 %2, %1
+  %10 = sub i64 %5, %1
+  %11 = insertvalue { ptr, i64, i64 } undef, ptr %8, 0
+  %12 = insertvalue { ptr, i64, i64 } %11, i64 %9, 1
+  %13 = insertvalue { ptr, i64, i64 } %12, i64 %10, 2
+  ret { ptr, i64, i64 } %13
+}
+
+define { ptr, i64 } @foo.tail({ ptr, i64 } %0, i64 %1) {
+_llgo_0:
+  %2 = extractvalue { ptr, i64 } %0, 0
+  %3 = extractvalue { ptr, i64 } %0, 1
+  %4 = icmp ugt i64 %1, %3
+  br i1 %4, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSliceBU"(i64 %1, i64 %3)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %5 = getelementptr inbounds i8, ptr %2, i64 %1
+  %6 = sub i64 %3, %1
+  %7 = insertvalue { ptr, i64 } undef, ptr %5, 0
+  %8 = insertvalue { ptr, i64 } %7, i64 %6, 1
+  ret { ptr, i64 } %8
+}
+
+define { ptr, i64, i64 } @foo.sub3(ptr %0, i64 %1, i64 %2, i64 %3) {
+_llgo_0:
+  %4 = icmp ugt i64 %3, 8
+  br i1 %4, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSlice3Alen"(i64 %3, i64 8)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %5 = icmp ugt i64 %2, %3
+  br i1 %5, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSlice3B"(i64 %2, i64 %3)
+  unreachable
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %6 = icmp ugt i64 %1, %2
+  br i1 %6, label %_llgo_5, label %_llgo_6
+
+_llgo_5:                                          ; preds = %_llgo_4
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSlice3C"(i64 %1, i64 %2)
+  unreachable
+
+_llgo_6:                                          ; preds = %_llgo_4
+  %7 = getelementptr inbounds i64, ptr %0, i64 %1
+  %8 = sub i64 %2, %1
+  %9 = sub i64 %3, %1
+  %10 = insertvalue { ptr, i64, i64 } undef, ptr %7, 0
+  %11 = insertvalue { ptr, i64, i64 } %10, i64 %8, 1
+  %12 = insertvalue { ptr, i64, i64 } %11, i64 %9, 2
+  ret { ptr, i64, i64 } %12
+}
+
+define { ptr, i64, i64 } @foo.head(ptr %0) {
+_llgo_0:
+  %1 = getelementptr inbounds i64, ptr %0, i64 2
+  %2 = insertvalue { ptr, i64, i64 } undef, ptr %1, 0
+  %3 = insertvalue { ptr, i64, i64 } %2, i64 2, 1
+  %4 = insertvalue { ptr, i64, i64 } %3, i64 6, 2
+  ret { ptr, i64, i64 } %4
+}
+
+define ptr @foo.arr({ ptr, i64, i64 } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, i64, i64 } %0, 1
+  %2 = icmp ult i64 %1, 4
+  br i1 %2, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSliceConvert"(i64 %1, i64 4)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %3 = extractvalue { ptr, i64, i64 } %0, 0
+  ret ptr %3
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicDivide"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicIndexU"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSliceAcap"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSliceB"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSliceBU"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSlice3Alen"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSlice3B"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSlice3C"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSliceConvert"(i64, i64)
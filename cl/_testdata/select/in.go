@@ -0,0 +1,30 @@
+package main
+
+func poll(a, b chan int, c chan string) int {
+	select {
+	case v := <-a:
+		return v
+	case b <- 1:
+		return 1
+	case _, ok := <-c:
+		if ok {
+			return 3
+		}
+		return 4
+	default:
+		return 2
+	}
+}
+
+func wait(a chan int, b chan string, v string) int {
+	select {
+	case x := <-a:
+		return x
+	case b <- v:
+		return -1
+	}
+}
+
+func main() {
+	poll(nil, nil, nil)
+}
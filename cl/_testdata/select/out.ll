@@ -0,0 +1,182 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [31 x i8] c"blocking select matched no case"
+@__llgo_type.string = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 24, i32 398550328, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null } }
+@1 = private unnamed_addr constant [6 x i8] c"string"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.poll(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = alloca { ptr, i64 }, align 8
+  %4 = alloca i64, align 8
+  %5 = alloca i64, align 8
+  %6 = alloca [3 x { ptr, ptr, i64, i1 }], align 8
+  store i64 0, ptr %5, align 4
+  %7 = insertvalue { ptr, ptr, i64, i1 } undef, ptr %0, 0
+  %8 = insertvalue { ptr, ptr, i64, i1 } %7, ptr %5, 1
+  %9 = insertvalue { ptr, ptr, i64, i1 } %8, i64 8, 2
+  %10 = insertvalue { ptr, ptr, i64, i1 } %9, i1 false, 3
+  %11 = getelementptr inbounds { ptr, ptr, i64, i1 }, ptr %6, i64 0
+  store { ptr, ptr, i64, i1 } %10, ptr %11, align 8
+  store i64 1, ptr %4, align 4
+  %12 = insertvalue { ptr, ptr, i64, i1 } undef, ptr %1, 0
+  %13 = insertvalue { ptr, ptr, i64, i1 } %12, ptr %4, 1
+  %14 = insertvalue { ptr, ptr, i64, i1 } %13, i64 8, 2
+  %15 = insertvalue { ptr, ptr, i64, i1 } %14, i1 true, 3
+  %16 = getelementptr inbounds { ptr, ptr, i64, i1 }, ptr %6, i64 1
+  store { ptr, ptr, i64, i1 } %15, ptr %16, align 8
+  store { ptr, i64 } zeroinitializer, ptr %3, align 8
+  %17 = insertvalue { ptr, ptr, i64, i1 } undef, ptr %2, 0
+  %18 = insertvalue { ptr, ptr, i64, i1 } %17, ptr %3, 1
+  %19 = insertvalue { ptr, ptr, i64, i1 } %18, i64 16, 2
+  %20 = insertvalue { ptr, ptr, i64, i1 } %19, i1 false, 3
+  %21 = getelementptr inbounds { ptr, ptr, i64, i1 }, ptr %6, i64 2
+  store { ptr, ptr, i64, i1 } %20, ptr %21, align 8
+  %22 = call { i64, i1 } @"github.com/goplus/llgo/internal/runtime.Select"(ptr %6, i64 3)
+  %23 = extractvalue { i64, i1 } %22, 0
+  %24 = extractvalue { i64, i1 } %22, 1
+  %25 = load i64, ptr %5, align 4
+  %26 = load { ptr, i64 }, ptr %3, align 8
+  %27 = insertvalue { i64, i1, i64, { ptr, i64 } } undef, i64 %23, 0
+  %28 = insertvalue { i64, i1, i64, { ptr, i64 } } %27, i1 %24, 1
+  %29 = insertvalue { i64, i1, i64, { ptr, i64 } } %28, i64 %25, 2
+  %30 = insertvalue { i64, i1, i64, { ptr, i64 } } %29, { ptr, i64 } %26, 3
+  %31 = extractvalue { i64, i1, i64, { ptr, i64 } } %30, 0
+  %32 = icmp eq i64 %31, 0
+  br i1 %32, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %33 = extractvalue { i64, i1, i64, { ptr, i64 } } %30, 2
+  ret i64 %33
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %34 = icmp eq i64 %31, 1
+  br i1 %34, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  ret i64 1
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %35 = icmp eq i64 %31, 2
+  br i1 %35, label %_llgo_5, label %_llgo_6
+
+_llgo_5:                                          ; preds = %_llgo_4
+  %36 = extractvalue { i64, i1, i64, { ptr, i64 } } %30, 3
+  %37 = extractvalue { i64, i1, i64, { ptr, i64 } } %30, 1
+  br i1 %37, label %_llgo_7, label %_llgo_8
+
+_llgo_6:                                          ; preds = %_llgo_4
+  ret i64 2
+
+_llgo_7:                                          ; preds = %_llgo_5
+  ret i64 3
+
+_llgo_8:                                          ; preds = %_llgo_5
+  ret i64 4
+}
+
+define i64 @main.wait(ptr %0, ptr %1, { ptr, i64 } %2) {
+_llgo_0:
+  %3 = alloca { ptr, i64 }, align 8
+  %4 = alloca i64, align 8
+  %5 = alloca [2 x { ptr, ptr, i64, i1 }], align 8
+  store i64 0, ptr %4, align 4
+  %6 = insertvalue { ptr, ptr, i64, i1 } undef, ptr %0, 0
+  %7 = insertvalue { ptr, ptr, i64, i1 } %6, ptr %4, 1
+  %8 = insertvalue { ptr, ptr, i64, i1 } %7, i64 8, 2
+  %9 = insertvalue { ptr, ptr, i64, i1 } %8, i1 false, 3
+  %10 = getelementptr inbounds { ptr, ptr, i64, i1 }, ptr %5, i64 0
+  store { ptr, ptr, i64, i1 } %9, ptr %10, align 8
+  store { ptr, i64 } %2, ptr %3, align 8
+  %11 = insertvalue { ptr, ptr, i64, i1 } undef, ptr %1, 0
+  %12 = insertvalue { ptr, ptr, i64, i1 } %11, ptr %3, 1
+  %13 = insertvalue { ptr, ptr, i64, i1 } %12, i64 16, 2
+  %14 = insertvalue { ptr, ptr, i64, i1 } %13, i1 true, 3
+  %15 = getelementptr inbounds { ptr, ptr, i64, i1 }, ptr %5, i64 1
+  store { ptr, ptr, i64, i1 } %14, ptr %15, align 8
+  %16 = call { i64, i1 } @"github.com/goplus/llgo/internal/runtime.SelectBlock"(ptr %5, i64 2)
+  %17 = extractvalue { i64, i1 } %16, 0
+  %18 = extractvalue { i64, i1 } %16, 1
+  %19 = load i64, ptr %4, align 4
+  %20 = insertvalue { i64, i1, i64 } undef, i64 %17, 0
+  %21 = insertvalue { i64, i1, i64 } %20, i1 %18, 1
+  %22 = insertvalue { i64, i1, i64 } %21, i64 %19, 2
+  %23 = extractvalue { i64, i1, i64 } %22, 0
+  %24 = icmp eq i64 %23, 0
+  br i1 %24, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %25 = extractvalue { i64, i1, i64 } %22, 2
+  ret i64 %25
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %26 = icmp eq i64 %23, 1
+  br i1 %26, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  ret i64 -1
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %27 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store { ptr, i64 } { ptr @0, i64 31 }, ptr %27, align 8
+  %28 = insertvalue { ptr, ptr } { ptr @__llgo_type.string, ptr undef }, ptr %27, 1
+  call void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr } %28)
+  unreachable
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i64 @main.poll(ptr null, ptr null, ptr null)
+  ret i32 0
+}
+
+declare { i64, i1 } @"github.com/goplus/llgo/internal/runtime.Select"(ptr, i64)
+
+declare { i64, i1 } @"github.com/goplus/llgo/internal/runtime.SelectBlock"(ptr, i64)
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.string(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.Panic"({ ptr, ptr })
@@ -0,0 +1,17 @@
+package main
+
+func shl(x int, n uint8) int {
+	return x << n
+}
+
+func shr(x int8, n uint) int8 {
+	return x >> n
+}
+
+func ushr(x uint32, n int) uint32 {
+	return x >> n
+}
+
+func main() {
+	println(shl(1, 3), shr(-8, 70), ushr(8, 2))
+}
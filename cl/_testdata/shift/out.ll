@@ -0,0 +1,69 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [1 x i8] c" "
+@1 = private unnamed_addr constant [1 x i8] c"\0A"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.shl(i64 %0, i8 %1) {
+_llgo_0:
+  %2 = icmp uge i8 %1, 64
+  %3 = zext i8 %1 to i64
+  %4 = shl i64 %0, %3
+  %5 = select i1 %2, i64 0, i64 %4
+  ret i64 %5
+}
+
+define fastcc i8 @main.shr(i8 %0, i64 %1) {
+_llgo_0:
+  %2 = icmp uge i64 %1, 8
+  %3 = trunc i64 %1 to i8
+  %4 = select i1 %2, i8 7, i8 %3
+  %5 = ashr i8 %0, %4
+  ret i8 %5
+}
+
+define fastcc i32 @main.ushr(i32 %0, i64 %1) {
+_llgo_0:
+  %2 = icmp uge i64 %1, 32
+  %3 = trunc i64 %1 to i32
+  %4 = lshr i32 %0, %3
+  %5 = select i1 %2, i32 0, i32 %4
+  ret i32 %5
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i64 @main.shl(i64 1, i8 3)
+  %4 = call fastcc i8 @main.shr(i8 -8, i64 70)
+  %5 = call fastcc i32 @main.ushr(i32 8, i64 2)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintInt"(i64 %3)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  %6 = sext i8 %4 to i64
+  call void @"github.com/goplus/llgo/internal/runtime.PrintInt"(i64 %6)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @0, i64 1 })
+  %7 = zext i32 %5 to i64
+  call void @"github.com/goplus/llgo/internal/runtime.PrintUint"(i64 %7)
+  call void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 } { ptr @1, i64 1 })
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintInt"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintString"({ ptr, i64 })
+
+declare void @"github.com/goplus/llgo/internal/runtime.PrintUint"(i64)
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func watch(c chan<- os.Signal) {
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	signal.Ignore(syscall.SIGINT)
+	signal.Reset()
+	signal.Stop(c)
+}
+
+func main() {
+	_ = os.Interrupt.String()
+	_ = syscall.SIGTERM.String()
+}
@@ -0,0 +1,109 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@"github.com/goplus/llgo/internal/runtime.Interrupt" = external global ptr
+@__llgo_type.syscall.Signal = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 2, i32 -1601196216, { ptr, i64 } { ptr @0, i64 14 }, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } { ptr @2, i64 7 }, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.syscall.Signal$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.syscall.Signal$stub", ptr null } }
+@0 = private unnamed_addr constant [14 x i8] c"syscall.Signal"
+@1 = private unnamed_addr constant [6 x i8] c"Signal"
+@2 = private unnamed_addr constant [7 x i8] c"syscall"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @main.watch(ptr %0) {
+_llgo_0:
+  %1 = load { ptr, ptr }, ptr @"github.com/goplus/llgo/internal/runtime.Interrupt", align 8
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 32)
+  %3 = getelementptr inbounds { ptr, ptr }, ptr %2, i64 0
+  store { ptr, ptr } %1, ptr %3, align 8
+  %4 = getelementptr inbounds { ptr, ptr }, ptr %2, i64 1
+  %5 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 15, ptr %5, align 4
+  %6 = insertvalue { ptr, ptr } { ptr @__llgo_type.syscall.Signal, ptr undef }, ptr %5, 1
+  store { ptr, ptr } %6, ptr %4, align 8
+  %7 = insertvalue { ptr, i64, i64 } undef, ptr %2, 0
+  %8 = insertvalue { ptr, i64, i64 } %7, i64 2, 1
+  %9 = insertvalue { ptr, i64, i64 } %8, i64 2, 2
+  call void @"github.com/goplus/llgo/internal/runtime.SignalNotify"(ptr %0, { ptr, i64, i64 } %9)
+  %10 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  %11 = getelementptr inbounds { ptr, ptr }, ptr %10, i64 0
+  %12 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 2, ptr %12, align 4
+  %13 = insertvalue { ptr, ptr } { ptr @__llgo_type.syscall.Signal, ptr undef }, ptr %12, 1
+  store { ptr, ptr } %13, ptr %11, align 8
+  %14 = insertvalue { ptr, i64, i64 } undef, ptr %10, 0
+  %15 = insertvalue { ptr, i64, i64 } %14, i64 1, 1
+  %16 = insertvalue { ptr, i64, i64 } %15, i64 1, 2
+  call void @"github.com/goplus/llgo/internal/runtime.SignalIgnore"({ ptr, i64, i64 } %16)
+  call void @"github.com/goplus/llgo/internal/runtime.SignalReset"({ ptr, i64, i64 } zeroinitializer)
+  call void @"github.com/goplus/llgo/internal/runtime.SignalStop"(ptr %0)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32 %0, ptr %1, ptr %2)
+  call void @main.init()
+  %3 = load { ptr, ptr }, ptr @"github.com/goplus/llgo/internal/runtime.Interrupt", align 8
+  %4 = call { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SignalString"({ ptr, ptr } %3)
+  %5 = call { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SyscallSignalString"(i64 15)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.OsInit"()
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+define linkonce_odr i64 @__llgo_hash.syscall.Signal(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.syscall.Signal$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.syscall.Signal(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.syscall.Signal(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.syscall.Signal$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.syscall.Signal(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.SignalNotify"(ptr, { ptr, i64, i64 })
+
+declare void @"github.com/goplus/llgo/internal/runtime.SignalIgnore"({ ptr, i64, i64 })
+
+declare void @"github.com/goplus/llgo/internal/runtime.SignalReset"({ ptr, i64, i64 })
+
+declare void @"github.com/goplus/llgo/internal/runtime.SignalStop"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.ArgsInit"(i32, ptr, ptr)
+
+declare { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SignalString"({ ptr, ptr })
+
+declare { ptr, i64 } @"github.com/goplus/llgo/internal/runtime.SyscallSignalString"(i64)
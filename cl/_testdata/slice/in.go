@@ -0,0 +1,33 @@
+package main
+
+func sum(s []int) int {
+	n := 0
+	for _, v := range s {
+		n += v
+	}
+	return n
+}
+
+func tail(s string, i int) string {
+	return s[i:]
+}
+
+func window(a *[8]int, i, j int) []int {
+	return a[i:j:8]
+}
+
+func grow(s []byte, x string) []byte {
+	return append(s, x...)
+}
+
+func move(dst, src []int) int {
+	return copy(dst, src)
+}
+
+func main() {
+	a := [8]int{1, 2, 3, 4}
+	_ = sum(window(&a, 1, 3))
+	_ = tail("hello", 2)
+	_ = grow(nil, "hi")
+	_ = move(a[:], a[2:])
+}
@@ -0,0 +1,156 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [5 x i8] c"hello"
+@1 = private unnamed_addr constant [2 x i8] c"hi"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.sum({ ptr, i64, i64 } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, i64, i64 } %0, 1
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_5, %_llgo_0
+  %2 = phi i64 [ 0, %_llgo_0 ], [ %11, %_llgo_5 ]
+  %3 = phi i64 [ -1, %_llgo_0 ], [ %4, %_llgo_5 ]
+  %4 = add i64 %3, 1
+  %5 = icmp slt i64 %4, %1
+  br i1 %5, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  %6 = extractvalue { ptr, i64, i64 } %0, 1
+  %7 = icmp uge i64 %4, %6
+  br i1 %7, label %_llgo_4, label %_llgo_5
+
+_llgo_3:                                          ; preds = %_llgo_1
+  ret i64 %2
+
+_llgo_4:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64 %4, i64 %6)
+  unreachable
+
+_llgo_5:                                          ; preds = %_llgo_2
+  %8 = extractvalue { ptr, i64, i64 } %0, 0
+  %9 = getelementptr inbounds i64, ptr %8, i64 %4
+  %10 = load i64, ptr %9, align 4
+  %11 = add i64 %2, %10
+  br label %_llgo_1
+}
+
+define fastcc { ptr, i64 } @main.tail({ ptr, i64 } %0, i64 %1) {
+_llgo_0:
+  %2 = extractvalue { ptr, i64 } %0, 0
+  %3 = extractvalue { ptr, i64 } %0, 1
+  %4 = icmp ugt i64 %1, %3
+  br i1 %4, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSliceB"(i64 %1, i64 %3)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %5 = getelementptr inbounds i8, ptr %2, i64 %1
+  %6 = sub i64 %3, %1
+  %7 = insertvalue { ptr, i64 } undef, ptr %5, 0
+  %8 = insertvalue { ptr, i64 } %7, i64 %6, 1
+  ret { ptr, i64 } %8
+}
+
+define fastcc { ptr, i64, i64 } @main.window(ptr %0, i64 %1, i64 %2) {
+_llgo_0:
+  %3 = icmp ugt i64 %2, 8
+  br i1 %3, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSlice3B"(i64 %2, i64 8)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %4 = icmp ugt i64 %1, %2
+  br i1 %4, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.PanicSlice3C"(i64 %1, i64 %2)
+  unreachable
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %5 = getelementptr inbounds i64, ptr %0, i64 %1
+  %6 = sub i64 %2, %1
+  %7 = sub i64 8, %1
+  %8 = insertvalue { ptr, i64, i64 } undef, ptr %5, 0
+  %9 = insertvalue { ptr, i64, i64 } %8, i64 %6, 1
+  %10 = insertvalue { ptr, i64, i64 } %9, i64 %7, 2
+  ret { ptr, i64, i64 } %10
+}
+
+define fastcc { ptr, i64, i64 } @main.grow({ ptr, i64, i64 } %0, { ptr, i64 } %1) {
+_llgo_0:
+  %2 = extractvalue { ptr, i64 } %1, 0
+  %3 = extractvalue { ptr, i64 } %1, 1
+  %4 = call { ptr, i64, i64 } @"github.com/goplus/llgo/internal/runtime.SliceAppend"({ ptr, i64, i64 } %0, ptr %2, i64 %3, i64 1)
+  ret { ptr, i64, i64 } %4
+}
+
+define fastcc i64 @main.move({ ptr, i64, i64 } %0, { ptr, i64, i64 } %1) {
+_llgo_0:
+  %2 = extractvalue { ptr, i64, i64 } %0, 1
+  %3 = extractvalue { ptr, i64, i64 } %1, 1
+  %4 = extractvalue { ptr, i64, i64 } %0, 0
+  %5 = extractvalue { ptr, i64, i64 } %1, 0
+  %6 = call i64 @"github.com/goplus/llgo/internal/runtime.SliceCopy"(ptr %4, i64 %2, ptr %5, i64 %3, i64 8)
+  ret i64 %6
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 64)
+  %4 = getelementptr inbounds i64, ptr %3, i64 0
+  %5 = getelementptr inbounds i64, ptr %3, i64 1
+  %6 = getelementptr inbounds i64, ptr %3, i64 2
+  %7 = getelementptr inbounds i64, ptr %3, i64 3
+  store i64 1, ptr %4, align 4
+  store i64 2, ptr %5, align 4
+  store i64 3, ptr %6, align 4
+  store i64 4, ptr %7, align 4
+  %8 = call fastcc { ptr, i64, i64 } @main.window(ptr %3, i64 1, i64 3)
+  %9 = call fastcc i64 @main.sum({ ptr, i64, i64 } %8)
+  %10 = call fastcc { ptr, i64 } @main.tail({ ptr, i64 } { ptr @0, i64 5 }, i64 2)
+  %11 = call fastcc { ptr, i64, i64 } @main.grow({ ptr, i64, i64 } zeroinitializer, { ptr, i64 } { ptr @1, i64 2 })
+  %12 = insertvalue { ptr, i64, i64 } undef, ptr %3, 0
+  %13 = insertvalue { ptr, i64, i64 } %12, i64 8, 1
+  %14 = insertvalue { ptr, i64, i64 } %13, i64 8, 2
+  %15 = getelementptr inbounds i64, ptr %3, i64 2
+  %16 = insertvalue { ptr, i64, i64 } undef, ptr %15, 0
+  %17 = insertvalue { ptr, i64, i64 } %16, i64 6, 1
+  %18 = insertvalue { ptr, i64, i64 } %17, i64 6, 2
+  %19 = call fastcc i64 @main.move({ ptr, i64, i64 } %14, { ptr, i64, i64 } %18)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSliceB"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSlice3B"(i64, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicSlice3C"(i64, i64)
+
+declare { ptr, i64, i64 } @"github.com/goplus/llgo/internal/runtime.SliceAppend"({ ptr, i64, i64 }, ptr, i64, i64)
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.SliceCopy"(ptr, i64, ptr, i64, i64)
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
@@ -0,0 +1,28 @@
+package main
+
+type point struct {
+	x, y int
+}
+
+type entry struct {
+	name string
+	_    int32
+	n    int32
+}
+
+func samePoint(a, b point) bool {
+	return a == b
+}
+
+func otherEntry(a, b entry) bool {
+	return a != b
+}
+
+func sameNames(a, b [2]string) bool {
+	return a == b
+}
+
+func main() {
+	m := make(map[entry]point)
+	m[entry{name: "a", n: 1}] = point{1, 2}
+}
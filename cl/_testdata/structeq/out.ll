@@ -0,0 +1,175 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%point = type { i64, i64 }
+%entry = type { { ptr, i64 }, i32, i32 }
+
+@"main.init$guard" = global ptr null
+@0 = private unnamed_addr constant [1 x i8] c"a"
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i1 @main.samePoint(%point %0, %point %1) {
+_llgo_0:
+  %2 = alloca %point, align 8
+  %3 = alloca %point, align 8
+  store %point %0, ptr %3, align 4
+  store %point %1, ptr %2, align 4
+  %4 = call i1 @__llgo_equal.main.point(ptr %3, ptr %2)
+  ret i1 %4
+}
+
+define i1 @main.otherEntry(%entry %0, %entry %1) {
+_llgo_0:
+  %2 = alloca %entry, align 8
+  %3 = alloca %entry, align 8
+  store %entry %0, ptr %3, align 8
+  store %entry %1, ptr %2, align 8
+  %4 = call i1 @__llgo_equal.main.entry(ptr %3, ptr %2)
+  %5 = xor i1 %4, true
+  ret i1 %5
+}
+
+define i1 @main.sameNames([2 x { ptr, i64 }] %0, [2 x { ptr, i64 }] %1) {
+_llgo_0:
+  %2 = alloca [2 x { ptr, i64 }], align 8
+  %3 = alloca [2 x { ptr, i64 }], align 8
+  store [2 x { ptr, i64 }] %0, ptr %3, align 8
+  store [2 x { ptr, i64 }] %1, ptr %2, align 8
+  %4 = call i1 @"__llgo_equal.[2]string"(ptr %3, ptr %2)
+  ret i1 %4
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = alloca %point, align 8
+  %4 = alloca %entry, align 8
+  %5 = alloca %point, align 8
+  %6 = alloca %entry, align 8
+  call void @main.init()
+  %7 = call ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64 24, i64 16, { ptr, ptr } { ptr @"__llgo_hash.main.entry$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.main.entry$stub", ptr null }, i64 0)
+  store %entry zeroinitializer, ptr %6, align 8
+  %8 = getelementptr inbounds %entry, ptr %6, i32 0, i32 0
+  %9 = getelementptr inbounds %entry, ptr %6, i32 0, i32 2
+  store { ptr, i64 } { ptr @0, i64 1 }, ptr %8, align 8
+  store i32 1, ptr %9, align 4
+  %10 = load %entry, ptr %6, align 8
+  store %point zeroinitializer, ptr %5, align 4
+  %11 = getelementptr inbounds %point, ptr %5, i32 0, i32 0
+  %12 = getelementptr inbounds %point, ptr %5, i32 0, i32 1
+  store i64 1, ptr %11, align 4
+  store i64 2, ptr %12, align 4
+  %13 = load %point, ptr %5, align 4
+  store %entry %10, ptr %4, align 8
+  store %point %13, ptr %3, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr %7, ptr %4, ptr %3)
+  ret i32 0
+}
+
+define linkonce_odr i1 @__llgo_equal.main.point(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 16)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define linkonce_odr i1 @__llgo_equal.main.entry(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %entry, ptr %0, i32 0, i32 0
+  %3 = getelementptr inbounds %entry, ptr %1, i32 0, i32 0
+  %4 = call i1 @__llgo_equal.string(ptr %2, ptr %3)
+  br i1 %4, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_2, %_llgo_0
+  ret i1 false
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %5 = getelementptr inbounds %entry, ptr %0, i32 0, i32 2
+  %6 = getelementptr inbounds %entry, ptr %1, i32 0, i32 2
+  %7 = call i1 @__llgo_equal.int32(ptr %5, ptr %6)
+  br i1 %7, label %_llgo_3, label %_llgo_1
+
+_llgo_3:                                          ; preds = %_llgo_2
+  ret i1 true
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define linkonce_odr i1 @__llgo_equal.int32(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 4)
+  ret i1 %2
+}
+
+define linkonce_odr i1 @"__llgo_equal.[2]string"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.ArrayEqual"(ptr %0, ptr %1, i64 2, i64 16, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null })
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.ArrayEqual"(ptr, ptr, i64, i64, { ptr, ptr })
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.main.entry(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %entry, ptr %0, i32 0, i32 0
+  %3 = call i64 @__llgo_hash.string(ptr %2, i64 %1)
+  %4 = getelementptr inbounds %entry, ptr %0, i32 0, i32 2
+  %5 = call i64 @__llgo_hash.int32(ptr %4, i64 %3)
+  ret i64 %5
+}
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define linkonce_odr i64 @__llgo_hash.int32(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 4, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.main.entry$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.main.entry(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define private i1 @"__llgo_equal.main.entry$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.main.entry(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.NewMap"(i64, i64, { ptr, ptr }, { ptr, ptr }, i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MapAssign"(ptr, ptr, ptr)
@@ -0,0 +1,19 @@
+package main
+
+func f(x int) int {
+	switch x {
+	case 1:
+		return 10
+	case 2, 3:
+		return 20
+	case 5:
+		return 50
+	case 8:
+		return 80
+	}
+	return 0
+}
+
+func main() {
+	f(2)
+}
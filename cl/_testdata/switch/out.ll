@@ -0,0 +1,50 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.f(i64 %0) {
+_llgo_0:
+  switch i64 %0, label %_llgo_9 [
+    i64 1, label %_llgo_1
+    i64 2, label %_llgo_2
+    i64 3, label %_llgo_2
+    i64 5, label %_llgo_4
+    i64 8, label %_llgo_7
+  ]
+
+_llgo_1:                                          ; preds = %_llgo_0
+  ret i64 10
+
+_llgo_2:                                          ; preds = %_llgo_0, %_llgo_0
+  ret i64 20
+
+_llgo_4:                                          ; preds = %_llgo_0
+  ret i64 50
+
+_llgo_7:                                          ; preds = %_llgo_0
+  ret i64 80
+
+_llgo_9:                                          ; preds = %_llgo_0
+  ret i64 0
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i64 @main.f(i64 2)
+  ret i32 0
+}
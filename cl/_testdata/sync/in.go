@@ -0,0 +1,44 @@
+package main
+
+import "sync"
+
+var (
+	mu    sync.Mutex
+	rw    sync.RWMutex
+	wg    sync.WaitGroup
+	once  sync.Once
+	count int
+)
+
+func setup() {
+	count = 1
+}
+
+func incr() {
+	mu.Lock()
+	count++
+	mu.Unlock()
+	wg.Done()
+}
+
+func get() int {
+	rw.RLock()
+	n := count
+	rw.RUnlock()
+	return n
+}
+
+func main() {
+	once.Do(setup)
+	wg.Add(2)
+	go incr()
+	go incr()
+	wg.Wait()
+	if mu.TryLock() {
+		mu.Unlock()
+	}
+	rw.Lock()
+	count = 0
+	rw.Unlock()
+	_ = get()
+}
@@ -0,0 +1,124 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%Mutex = type { %noCopy, %Mutex.0 }
+%noCopy = type {}
+%Mutex.0 = type { i32, i32 }
+%RWMutex = type { %Mutex, i32, i32, %Int32, %Int32 }
+%Int32 = type { %noCopy.1, i32 }
+%noCopy.1 = type {}
+%WaitGroup = type { %noCopy, %Uint64, i32 }
+%Uint64 = type { %noCopy.1, %align64, i64 }
+%align64 = type {}
+%Once = type { %noCopy, %Bool, %Mutex }
+%Bool = type { %noCopy.1, i32 }
+
+@"main.init$guard" = global ptr null
+@main.mu = global ptr null
+@main.rw = global %RWMutex zeroinitializer
+@main.wg = global %WaitGroup zeroinitializer
+@main.once = global %Once zeroinitializer
+@main.count = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @main.setup() {
+_llgo_0:
+  store i64 1, ptr @main.count, align 4
+  ret void
+}
+
+define fastcc void @main.incr() {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.MutexLock"(ptr @main.mu)
+  %0 = load i64, ptr @main.count, align 4
+  %1 = add i64 %0, 1
+  store i64 %1, ptr @main.count, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.MutexUnlock"(ptr @main.mu)
+  call void @"github.com/goplus/llgo/internal/runtime.WaitGroupDone"(ptr @main.wg)
+  ret void
+}
+
+define fastcc i64 @main.get() {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.RWMutexRLock"(ptr @main.rw)
+  %0 = load i64, ptr @main.count, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.RWMutexRUnlock"(ptr @main.rw)
+  ret i64 %0
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  call void @"github.com/goplus/llgo/internal/runtime.OnceDo"(ptr @main.once, { ptr, ptr } { ptr @"main.setup$stub", ptr null })
+  call void @"github.com/goplus/llgo/internal/runtime.WaitGroupAdd"(ptr @main.wg, i64 2)
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"main.incr$go$stub", ptr null }, ptr null)
+  call void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr } { ptr @"main.incr$go$stub", ptr null }, ptr null)
+  call void @"github.com/goplus/llgo/internal/runtime.WaitGroupWait"(ptr @main.wg)
+  %3 = call i1 @"github.com/goplus/llgo/internal/runtime.MutexTryLock"(ptr @main.mu)
+  br i1 %3, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.MutexUnlock"(ptr @main.mu)
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.RWMutexLock"(ptr @main.rw)
+  store i64 0, ptr @main.count, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.RWMutexUnlock"(ptr @main.rw)
+  %4 = call fastcc i64 @main.get()
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.MutexLock"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.MutexUnlock"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.WaitGroupDone"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.RWMutexRLock"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.RWMutexRUnlock"(ptr)
+
+define private void @"main.setup$stub"(ptr %0) {
+_llgo_0:
+  tail call void @main.setup()
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.OnceDo"(ptr, { ptr, ptr })
+
+declare void @"github.com/goplus/llgo/internal/runtime.WaitGroupAdd"(ptr, i64)
+
+define private void @"main.incr$go"(ptr %0) {
+_llgo_0:
+  call fastcc void @main.incr()
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.Go"({ ptr, ptr }, ptr)
+
+define private void @"main.incr$go$stub"(ptr %0, ptr %1) {
+_llgo_0:
+  tail call void @"main.incr$go"(ptr %1)
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.WaitGroupWait"(ptr)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.MutexTryLock"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.RWMutexLock"(ptr)
+
+declare void @"github.com/goplus/llgo/internal/runtime.RWMutexUnlock"(ptr)
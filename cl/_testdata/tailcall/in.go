@@ -0,0 +1,20 @@
+package foo
+
+func f(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return f(n - 1)
+}
+
+func g(n int) int {
+	var a [2]int
+	a[n&1] = n
+	return f(a[0]) + f(a[1])
+}
+
+func h(n int) int {
+	var a [2]int
+	a[n&1] = n
+	return f(a[0])
+}
@@ -0,0 +1,79 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @foo.f(i64 %0) {
+_llgo_0:
+  %1 = icmp eq i64 %0, 0
+  br i1 %1, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  ret i64 0
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %2 = sub i64 %0, 1
+  %3 = tail call fastcc i64 @foo.f(i64 %2)
+  ret i64 %3
+}
+
+define i64 @foo.g(i64 %0) {
+_llgo_0:
+  %1 = alloca [2 x i64], align 8
+  store [2 x i64] zeroinitializer, ptr %1, align 4
+  %2 = and i64 %0, 1
+  %3 = icmp uge i64 %2, 2
+  br i1 %3, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64 %2, i64 2)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %4 = getelementptr inbounds i64, ptr %1, i64 %2
+  store i64 %0, ptr %4, align 4
+  %5 = getelementptr inbounds i64, ptr %1, i64 0
+  %6 = load i64, ptr %5, align 4
+  %7 = call fastcc i64 @foo.f(i64 %6)
+  %8 = getelementptr inbounds i64, ptr %1, i64 1
+  %9 = load i64, ptr %8, align 4
+  %10 = call fastcc i64 @foo.f(i64 %9)
+  %11 = add i64 %7, %10
+  ret i64 %11
+}
+
+define i64 @foo.h(i64 %0) {
+_llgo_0:
+  %1 = alloca [2 x i64], align 8
+  store [2 x i64] zeroinitializer, ptr %1, align 4
+  %2 = and i64 %0, 1
+  %3 = icmp uge i64 %2, 2
+  br i1 %3, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64 %2, i64 2)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %4 = getelementptr inbounds i64, ptr %1, i64 %2
+  store i64 %0, ptr %4, align 4
+  %5 = getelementptr inbounds i64, ptr %1, i64 0
+  %6 = load i64, ptr %5, align 4
+  %7 = call fastcc i64 @foo.f(i64 %6)
+  ret i64 %7
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicIndex"(i64, i64)
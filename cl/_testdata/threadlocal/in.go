@@ -0,0 +1,15 @@
+package foo
+
+//llgo:threadlocal
+var cur *int
+
+//llgo:threadlocal
+var Exported *int
+
+var (
+	//llgo:threadlocal
+	buf [4]int
+	n   int
+)
+
+func F() *int { return cur }
@@ -0,0 +1,27 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@foo.cur = thread_local global ptr null
+@foo.Exported = global ptr null
+@foo.buf = thread_local global [4 x i64] zeroinitializer
+@foo.n = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define ptr @foo.F() {
+_llgo_0:
+  %0 = load ptr, ptr @foo.cur, align 8
+  ret ptr %0
+}
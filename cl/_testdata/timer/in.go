@@ -0,0 +1,14 @@
+package main
+
+import "time"
+
+func main() {
+	start := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	t := time.NewTimer(time.Second)
+	if !t.Stop() {
+		t.Reset(time.Millisecond)
+	}
+	_ = time.After(time.Millisecond)
+	_ = time.Since(start)
+}
@@ -0,0 +1,52 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%Time = type { i64, i64, ptr }
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call %Time @"github.com/goplus/llgo/internal/runtime.TimeNow"()
+  call void @"github.com/goplus/llgo/internal/runtime.TimeSleep"(i64 10000000)
+  %4 = call ptr @"github.com/goplus/llgo/internal/runtime.TimeNewTimer"(i64 1000000000)
+  %5 = call i1 @"github.com/goplus/llgo/internal/runtime.TimerStop"(ptr %4)
+  br i1 %5, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %6 = call i1 @"github.com/goplus/llgo/internal/runtime.TimerReset"(ptr %4, i64 1000000)
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  %7 = call ptr @"github.com/goplus/llgo/internal/runtime.TimeAfter"(i64 1000000)
+  %8 = call i64 @"github.com/goplus/llgo/internal/runtime.TimeSince"(%Time %3)
+  ret i32 0
+}
+
+declare %Time @"github.com/goplus/llgo/internal/runtime.TimeNow"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.TimeSleep"(i64)
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.TimeNewTimer"(i64)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.TimerStop"(ptr)
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.TimeAfter"(i64)
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.TimeSince"(%Time)
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.TimerReset"(ptr, i64)
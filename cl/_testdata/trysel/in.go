@@ -0,0 +1,22 @@
+package main
+
+func trySend(ch chan int, v int) bool {
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func tryRecv(ch chan int) (int, bool) {
+	select {
+	case v, ok := <-ch:
+		return v, ok
+	default:
+		return 0, false
+	}
+}
+
+func main() {
+}
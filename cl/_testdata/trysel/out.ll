@@ -0,0 +1,72 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i1 @main.trySend(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = alloca i64, align 8
+  store i64 %1, ptr %2, align 4
+  %3 = call i1 @"github.com/goplus/llgo/internal/runtime.ChanTrySend"(ptr %0, ptr %2, i64 8)
+  %4 = select i1 %3, i64 0, i64 -1
+  %5 = insertvalue { i64, i1 } undef, i64 %4, 0
+  %6 = insertvalue { i64, i1 } %5, i1 false, 1
+  %7 = extractvalue { i64, i1 } %6, 0
+  %8 = icmp eq i64 %7, 0
+  br i1 %8, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  ret i1 true
+
+_llgo_2:                                          ; preds = %_llgo_0
+  ret i1 false
+}
+
+define { i64, i1 } @main.tryRecv(ptr %0) {
+_llgo_0:
+  %1 = alloca i64, align 8
+  %2 = call { i1, i1 } @"github.com/goplus/llgo/internal/runtime.ChanTryRecv"(ptr %0, ptr %1, i64 8)
+  %3 = extractvalue { i1, i1 } %2, 0
+  %4 = extractvalue { i1, i1 } %2, 1
+  %5 = select i1 %3, i64 0, i64 -1
+  %6 = load i64, ptr %1, align 4
+  %7 = insertvalue { i64, i1, i64 } undef, i64 %5, 0
+  %8 = insertvalue { i64, i1, i64 } %7, i1 %4, 1
+  %9 = insertvalue { i64, i1, i64 } %8, i64 %6, 2
+  %10 = extractvalue { i64, i1, i64 } %9, 0
+  %11 = icmp eq i64 %10, 0
+  br i1 %11, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %12 = extractvalue { i64, i1, i64 } %9, 2
+  %13 = extractvalue { i64, i1, i64 } %9, 1
+  %mrv = insertvalue { i64, i1 } poison, i64 %12, 0
+  %mrv1 = insertvalue { i64, i1 } %mrv, i1 %13, 1
+  ret { i64, i1 } %mrv1
+
+_llgo_2:                                          ; preds = %_llgo_0
+  ret { i64, i1 } zeroinitializer
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  ret i32 0
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.ChanTrySend"(ptr, ptr, i64)
+
+declare { i1, i1 } @"github.com/goplus/llgo/internal/runtime.ChanTryRecv"(ptr, ptr, i64)
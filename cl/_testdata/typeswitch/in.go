@@ -0,0 +1,21 @@
+package foo
+
+type T struct{ a, b int }
+
+func kind(x any) int {
+	switch v := x.(type) {
+	case int:
+		return v
+	case string:
+		return len(v)
+	case *T:
+		return v.a
+	case T:
+		return v.b
+	case bool, float64:
+		return 5
+	}
+	return 0
+}
+
+func must(x any) T { return x.(T) }
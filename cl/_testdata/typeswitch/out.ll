@@ -0,0 +1,433 @@
+; ModuleID = 'foo'
+source_filename = "foo"
+
+%T = type { i64, i64 }
+
+@"foo.init$guard" = global ptr null
+@__llgo_type.int = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 2, i32 -1779859874, { ptr, i64 } { ptr @0, i64 3 }, { ptr, i64 } { ptr @0, i64 3 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null } }
+@0 = private unnamed_addr constant [3 x i8] c"int"
+@__llgo_type.string = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 24, i32 398550328, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } { ptr @1, i64 6 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.string$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.string$stub", ptr null } }
+@1 = private unnamed_addr constant [6 x i8] c"string"
+@"__llgo_type.*foo.T" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 22, i32 -385119595, { ptr, i64 } { ptr @2, i64 6 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.*foo.T$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.*foo.T$stub", ptr null } }
+@2 = private unnamed_addr constant [6 x i8] c"*foo.T"
+@__llgo_type.foo.T = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 25, i32 1733510781, { ptr, i64 } { ptr @3, i64 5 }, { ptr, i64 } { ptr @4, i64 1 }, { ptr, i64 } { ptr @5, i64 3 }, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.foo.T$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.foo.T$stub", ptr null } }
+@3 = private unnamed_addr constant [5 x i8] c"foo.T"
+@4 = private unnamed_addr constant [1 x i8] c"T"
+@5 = private unnamed_addr constant [3 x i8] c"foo"
+@__llgo_type.bool = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 1, i64 1, i32 -929786563, { ptr, i64 } { ptr @6, i64 4 }, { ptr, i64 } { ptr @6, i64 4 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.bool$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.bool$stub", ptr null } }
+@6 = private unnamed_addr constant [4 x i8] c"bool"
+@__llgo_type.float64 = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 14, i32 2090339911, { ptr, i64 } { ptr @7, i64 7 }, { ptr, i64 } { ptr @7, i64 7 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.float64$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.float64$stub", ptr null } }
+@7 = private unnamed_addr constant [7 x i8] c"float64"
+@__llgo_type.any = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 20, i32 740945997, { ptr, i64 } { ptr @8, i64 3 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.any$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.any$stub", ptr null } }
+@8 = private unnamed_addr constant [3 x i8] c"any"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.kind({ ptr, ptr } %0) {
+_llgo_0:
+  %1 = alloca double, align 8
+  %2 = alloca i1, align 1
+  %3 = alloca %T, align 8
+  %4 = alloca %T, align 8
+  %5 = alloca { ptr, i64 }, align 8
+  %6 = alloca i64, align 8
+  %7 = alloca i64, align 8
+  store i64 6, ptr %7, align 4
+  %8 = extractvalue { ptr, ptr } %0, 0
+  %9 = icmp eq ptr %8, null
+  br i1 %9, label %_llgo_13, label %_llgo_12
+
+_llgo_1:                                          ; preds = %_llgo_13
+  ret i64 %63
+
+_llgo_2:                                          ; preds = %_llgo_13
+  %10 = icmp eq i64 %56, 1
+  %11 = extractvalue { ptr, ptr } %0, 1
+  store { ptr, i64 } zeroinitializer, ptr %5, align 8
+  %12 = select i1 %10, ptr %11, ptr %5
+  %13 = load { ptr, i64 }, ptr %12, align 8
+  %14 = insertvalue { { ptr, i64 }, i1 } undef, { ptr, i64 } %13, 0
+  %15 = insertvalue { { ptr, i64 }, i1 } %14, i1 %10, 1
+  %16 = extractvalue { { ptr, i64 }, i1 } %15, 0
+  %17 = extractvalue { { ptr, i64 }, i1 } %15, 1
+  br i1 %17, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  %18 = extractvalue { ptr, i64 } %16, 1
+  ret i64 %18
+
+_llgo_4:                                          ; preds = %_llgo_2, %_llgo_13
+  %19 = icmp eq i64 %56, 2
+  %20 = extractvalue { ptr, ptr } %0, 1
+  %21 = select i1 %19, ptr %20, ptr null
+  %22 = insertvalue { ptr, i1 } undef, ptr %21, 0
+  %23 = insertvalue { ptr, i1 } %22, i1 %19, 1
+  %24 = extractvalue { ptr, i1 } %23, 0
+  %25 = extractvalue { ptr, i1 } %23, 1
+  br i1 %25, label %_llgo_5, label %_llgo_6
+
+_llgo_5:                                          ; preds = %_llgo_4
+  %26 = getelementptr inbounds %T, ptr %24, i32 0, i32 0
+  %27 = load i64, ptr %26, align 4
+  ret i64 %27
+
+_llgo_6:                                          ; preds = %_llgo_4, %_llgo_13
+  %28 = icmp eq i64 %56, 3
+  %29 = extractvalue { ptr, ptr } %0, 1
+  store %T zeroinitializer, ptr %4, align 4
+  %30 = select i1 %28, ptr %29, ptr %4
+  %31 = load %T, ptr %30, align 4
+  %32 = insertvalue { %T, i1 } undef, %T %31, 0
+  %33 = insertvalue { %T, i1 } %32, i1 %28, 1
+  %34 = extractvalue { %T, i1 } %33, 0
+  %35 = extractvalue { %T, i1 } %33, 1
+  br i1 %35, label %_llgo_7, label %_llgo_8
+
+_llgo_7:                                          ; preds = %_llgo_6
+  store %T zeroinitializer, ptr %3, align 4
+  store %T %34, ptr %3, align 4
+  %36 = getelementptr inbounds %T, ptr %3, i32 0, i32 1
+  %37 = load i64, ptr %36, align 4
+  ret i64 %37
+
+_llgo_8:                                          ; preds = %_llgo_6, %_llgo_13
+  %38 = icmp eq i64 %56, 4
+  %39 = extractvalue { ptr, ptr } %0, 1
+  store i1 false, ptr %2, align 1
+  %40 = select i1 %38, ptr %39, ptr %2
+  %41 = load i1, ptr %40, align 1
+  %42 = insertvalue { i1, i1 } undef, i1 %41, 0
+  %43 = insertvalue { i1, i1 } %42, i1 %38, 1
+  %44 = extractvalue { i1, i1 } %43, 0
+  %45 = extractvalue { i1, i1 } %43, 1
+  br i1 %45, label %_llgo_9, label %_llgo_10
+
+_llgo_9:                                          ; preds = %_llgo_10, %_llgo_8
+  ret i64 5
+
+_llgo_10:                                         ; preds = %_llgo_8, %_llgo_13
+  %46 = icmp eq i64 %56, 5
+  %47 = extractvalue { ptr, ptr } %0, 1
+  store double 0.000000e+00, ptr %1, align 8
+  %48 = select i1 %46, ptr %47, ptr %1
+  %49 = load double, ptr %48, align 8
+  %50 = insertvalue { double, i1 } undef, double %49, 0
+  %51 = insertvalue { double, i1 } %50, i1 %46, 1
+  %52 = extractvalue { double, i1 } %51, 0
+  %53 = extractvalue { double, i1 } %51, 1
+  br i1 %53, label %_llgo_9, label %_llgo_11
+
+_llgo_11:                                         ; preds = %_llgo_10
+  ret i64 0
+
+_llgo_12:                                         ; preds = %_llgo_0
+  %54 = getelementptr inbounds { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } }, ptr %8, i32 0, i32 2
+  %55 = load i32, ptr %54, align 4
+  switch i32 %55, label %_llgo_13 [
+    i32 -1779859874, label %_llgo_14
+    i32 398550328, label %_llgo_15
+    i32 -385119595, label %_llgo_16
+    i32 1733510781, label %_llgo_17
+    i32 -929786563, label %_llgo_18
+    i32 2090339911, label %_llgo_19
+  ]
+
+_llgo_13:                                         ; preds = %_llgo_31, %_llgo_30, %_llgo_29, %_llgo_28, %_llgo_27, %_llgo_26, %_llgo_25, %_llgo_24, %_llgo_23, %_llgo_22, %_llgo_21, %_llgo_20, %_llgo_12, %_llgo_0
+  %56 = load i64, ptr %7, align 4
+  %57 = icmp eq i64 %56, 0
+  %58 = extractvalue { ptr, ptr } %0, 1
+  store i64 0, ptr %6, align 4
+  %59 = select i1 %57, ptr %58, ptr %6
+  %60 = load i64, ptr %59, align 4
+  %61 = insertvalue { i64, i1 } undef, i64 %60, 0
+  %62 = insertvalue { i64, i1 } %61, i1 %57, 1
+  %63 = extractvalue { i64, i1 } %62, 0
+  %64 = extractvalue { i64, i1 } %62, 1
+  switch i64 %56, label %_llgo_10 [
+    i64 0, label %_llgo_1
+    i64 1, label %_llgo_2
+    i64 2, label %_llgo_4
+    i64 3, label %_llgo_6
+    i64 4, label %_llgo_8
+  ]
+
+_llgo_14:                                         ; preds = %_llgo_12
+  %65 = icmp eq ptr %8, @__llgo_type.int
+  br i1 %65, label %_llgo_20, label %_llgo_21
+
+_llgo_15:                                         ; preds = %_llgo_12
+  %66 = icmp eq ptr %8, @__llgo_type.string
+  br i1 %66, label %_llgo_22, label %_llgo_23
+
+_llgo_16:                                         ; preds = %_llgo_12
+  %67 = icmp eq ptr %8, @"__llgo_type.*foo.T"
+  br i1 %67, label %_llgo_24, label %_llgo_25
+
+_llgo_17:                                         ; preds = %_llgo_12
+  %68 = icmp eq ptr %8, @__llgo_type.foo.T
+  br i1 %68, label %_llgo_26, label %_llgo_27
+
+_llgo_18:                                         ; preds = %_llgo_12
+  %69 = icmp eq ptr %8, @__llgo_type.bool
+  br i1 %69, label %_llgo_28, label %_llgo_29
+
+_llgo_19:                                         ; preds = %_llgo_12
+  %70 = icmp eq ptr %8, @__llgo_type.float64
+  br i1 %70, label %_llgo_30, label %_llgo_31
+
+_llgo_20:                                         ; preds = %_llgo_14
+  store i64 0, ptr %7, align 4
+  br label %_llgo_13
+
+_llgo_21:                                         ; preds = %_llgo_14
+  br label %_llgo_13
+
+_llgo_22:                                         ; preds = %_llgo_15
+  store i64 1, ptr %7, align 4
+  br label %_llgo_13
+
+_llgo_23:                                         ; preds = %_llgo_15
+  br label %_llgo_13
+
+_llgo_24:                                         ; preds = %_llgo_16
+  store i64 2, ptr %7, align 4
+  br label %_llgo_13
+
+_llgo_25:                                         ; preds = %_llgo_16
+  br label %_llgo_13
+
+_llgo_26:                                         ; preds = %_llgo_17
+  store i64 3, ptr %7, align 4
+  br label %_llgo_13
+
+_llgo_27:                                         ; preds = %_llgo_17
+  br label %_llgo_13
+
+_llgo_28:                                         ; preds = %_llgo_18
+  store i64 4, ptr %7, align 4
+  br label %_llgo_13
+
+_llgo_29:                                         ; preds = %_llgo_18
+  br label %_llgo_13
+
+_llgo_30:                                         ; preds = %_llgo_19
+  store i64 5, ptr %7, align 4
+  br label %_llgo_13
+
+_llgo_31:                                         ; preds = %_llgo_19
+  br label %_llgo_13
+}
+
+define %T @foo.must({ ptr, ptr } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, ptr } %0, 0
+  %2 = icmp eq ptr %1, @__llgo_type.foo.T
+  br i1 %2, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %3 = extractvalue { ptr, ptr } %0, 0
+  call void @"github.com/goplus/llgo/internal/runtime.TypeAssertFailed"(ptr @__llgo_type.any, ptr %3, ptr @__llgo_type.foo.T)
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %4 = extractvalue { ptr, ptr } %0, 1
+  %5 = load %T, ptr %4, align 4
+  ret %T %5
+}
+
+define linkonce_odr i64 @__llgo_hash.int(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.int$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.int(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.int(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.int$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.int(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.string(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Strhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.string$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.string(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.string(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Strequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.string$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.string(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @"__llgo_hash.*foo.T"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.*foo.T$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @"__llgo_hash.*foo.T"(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.*foo.T"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.*foo.T$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @"__llgo_equal.*foo.T"(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.foo.T(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 16, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.foo.T$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.foo.T(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.foo.T(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 16)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.foo.T$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.foo.T(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.bool(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 1, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.bool$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.bool(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.bool(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 1)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.bool$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.bool(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.float64(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.F64hash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.F64hash"(ptr, i64)
+
+define private i64 @"__llgo_hash.float64$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.float64(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.float64(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.F64equal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.F64equal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.float64$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.float64(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.TypeAssertFailed"(ptr, ptr, ptr)
+
+define linkonce_odr i64 @__llgo_hash.any(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Interhash"(ptr %0, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Interhash"(ptr, i64)
+
+define private i64 @"__llgo_hash.any$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.any(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.any(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr %0, ptr %1)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Interequal"(ptr, ptr)
+
+define private i1 @"__llgo_equal.any$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.any(ptr %1, ptr %2)
+  ret i1 %3
+}
@@ -0,0 +1,29 @@
+package main
+
+import "unsafe"
+
+type point struct {
+	x int8
+	y int64
+}
+
+func add(p unsafe.Pointer, n int32) unsafe.Pointer {
+	return unsafe.Add(p, n)
+}
+
+func bytes(p *byte, n int) []byte {
+	return unsafe.Slice(p, n)
+}
+
+func str(p *byte, n uint8) string {
+	return unsafe.String(p, n)
+}
+
+func data(s string) *byte {
+	return unsafe.StringData(s)
+}
+
+var offset = unsafe.Offsetof(point{}.y)
+
+func main() {
+}
@@ -0,0 +1,88 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.offset = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  store i64 8, ptr @main.offset, align 4
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define ptr @main.add(ptr %0, i32 %1) {
+_llgo_0:
+  %2 = sext i32 %1 to i64
+  %3 = getelementptr i8, ptr %0, i64 %2
+  ret ptr %3
+}
+
+define { ptr, i64, i64 } @main.bytes(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = icmp slt i64 %1, 0
+  br i1 %2, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicUnsafeSliceLen"()
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %3 = icmp eq ptr %0, null
+  %4 = icmp ne i64 %1, 0
+  %5 = and i1 %3, %4
+  br i1 %5, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.PanicUnsafeSliceNilPtr"()
+  unreachable
+
+_llgo_4:                                          ; preds = %_llgo_2
+  %6 = insertvalue { ptr, i64, i64 } undef, ptr %0, 0
+  %7 = insertvalue { ptr, i64, i64 } %6, i64 %1, 1
+  %8 = insertvalue { ptr, i64, i64 } %7, i64 %1, 2
+  ret { ptr, i64, i64 } %8
+}
+
+define { ptr, i64 } @main.str(ptr %0, i8 %1) {
+_llgo_0:
+  %2 = zext i8 %1 to i64
+  %3 = icmp eq ptr %0, null
+  %4 = icmp ne i64 %2, 0
+  %5 = and i1 %3, %4
+  br i1 %5, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.PanicUnsafeStringNilPtr"()
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %6 = insertvalue { ptr, i64 } undef, ptr %0, 0
+  %7 = insertvalue { ptr, i64 } %6, i64 %2, 1
+  ret { ptr, i64 } %7
+}
+
+define ptr @main.data({ ptr, i64 } %0) {
+_llgo_0:
+  %1 = extractvalue { ptr, i64 } %0, 0
+  ret ptr %1
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicUnsafeSliceLen"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicUnsafeSliceNilPtr"()
+
+declare void @"github.com/goplus/llgo/internal/runtime.PanicUnsafeStringNilPtr"()
@@ -18,12 +18,12 @@ _llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
   ret void
 }
 
-define void @main() {
+define i32 @main(i32 %0, ptr %1, ptr %2) {
 _llgo_0:
   call void @main.init()
-  %0 = load i64, ptr @main.a, align 4
-  %1 = add i64 %0, 1
-  store i64 %1, ptr @main.a, align 4
-  %2 = load i64, ptr @main.a, align 4
-  ret void
+  %3 = load i64, ptr @main.a, align 4
+  %4 = add i64 %3, 1
+  store i64 %4, ptr @main.a, align 4
+  %5 = load i64, ptr @main.a, align 4
+  ret i32 0
 }
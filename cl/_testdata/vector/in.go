@@ -0,0 +1,14 @@
+package main
+
+import "github.com/goplus/llgo/vector"
+
+func dot(a, b vector.Float32x4) float32 {
+	return a.Mul(b).Sum()
+}
+
+func main() {
+	a := vector.Int32x4{1, 2, 3, 4}
+	b := a.Add(a).Shuffle(a, 0, 4, 1, 5)
+	_ = b.Sum()
+	_ = dot(vector.Float32x4{1, 2, 3, 4}, vector.Float32x4{4, 3, 2, 1})
+}
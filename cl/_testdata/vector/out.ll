@@ -0,0 +1,168 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  call void @"github.com/goplus/llgo/vector.init"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc float @main.dot([4 x float] %0, [4 x float] %1) {
+_llgo_0:
+  %2 = extractvalue [4 x float] %0, 0
+  %3 = insertelement <4 x float> undef, float %2, i32 0
+  %4 = extractvalue [4 x float] %0, 1
+  %5 = insertelement <4 x float> %3, float %4, i32 1
+  %6 = extractvalue [4 x float] %0, 2
+  %7 = insertelement <4 x float> %5, float %6, i32 2
+  %8 = extractvalue [4 x float] %0, 3
+  %9 = insertelement <4 x float> %7, float %8, i32 3
+  %10 = extractvalue [4 x float] %1, 0
+  %11 = insertelement <4 x float> undef, float %10, i32 0
+  %12 = extractvalue [4 x float] %1, 1
+  %13 = insertelement <4 x float> %11, float %12, i32 1
+  %14 = extractvalue [4 x float] %1, 2
+  %15 = insertelement <4 x float> %13, float %14, i32 2
+  %16 = extractvalue [4 x float] %1, 3
+  %17 = insertelement <4 x float> %15, float %16, i32 3
+  %18 = fmul <4 x float> %9, %17
+  %19 = extractelement <4 x float> %18, i32 0
+  %20 = insertvalue [4 x float] undef, float %19, 0
+  %21 = extractelement <4 x float> %18, i32 1
+  %22 = insertvalue [4 x float] %20, float %21, 1
+  %23 = extractelement <4 x float> %18, i32 2
+  %24 = insertvalue [4 x float] %22, float %23, 2
+  %25 = extractelement <4 x float> %18, i32 3
+  %26 = insertvalue [4 x float] %24, float %25, 3
+  %27 = extractvalue [4 x float] %26, 0
+  %28 = insertelement <4 x float> undef, float %27, i32 0
+  %29 = extractvalue [4 x float] %26, 1
+  %30 = insertelement <4 x float> %28, float %29, i32 1
+  %31 = extractvalue [4 x float] %26, 2
+  %32 = insertelement <4 x float> %30, float %31, i32 2
+  %33 = extractvalue [4 x float] %26, 3
+  %34 = insertelement <4 x float> %32, float %33, i32 3
+  %35 = call float @llvm.vector.reduce.fadd.v4f32(float 0.000000e+00, <4 x float> %34)
+  ret float %35
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = alloca [4 x float], align 4
+  %4 = alloca [4 x float], align 4
+  %5 = alloca [4 x i32], align 4
+  call void @main.init()
+  store [4 x i32] zeroinitializer, ptr %5, align 4
+  %6 = getelementptr inbounds i32, ptr %5, i64 0
+  %7 = getelementptr inbounds i32, ptr %5, i64 1
+  %8 = getelementptr inbounds i32, ptr %5, i64 2
+  %9 = getelementptr inbounds i32, ptr %5, i64 3
+  store i32 1, ptr %6, align 4
+  store i32 2, ptr %7, align 4
+  store i32 3, ptr %8, align 4
+  store i32 4, ptr %9, align 4
+  %10 = load [4 x i32], ptr %5, align 4
+  %11 = load [4 x i32], ptr %5, align 4
+  %12 = extractvalue [4 x i32] %10, 0
+  %13 = insertelement <4 x i32> undef, i32 %12, i32 0
+  %14 = extractvalue [4 x i32] %10, 1
+  %15 = insertelement <4 x i32> %13, i32 %14, i32 1
+  %16 = extractvalue [4 x i32] %10, 2
+  %17 = insertelement <4 x i32> %15, i32 %16, i32 2
+  %18 = extractvalue [4 x i32] %10, 3
+  %19 = insertelement <4 x i32> %17, i32 %18, i32 3
+  %20 = extractvalue [4 x i32] %11, 0
+  %21 = insertelement <4 x i32> undef, i32 %20, i32 0
+  %22 = extractvalue [4 x i32] %11, 1
+  %23 = insertelement <4 x i32> %21, i32 %22, i32 1
+  %24 = extractvalue [4 x i32] %11, 2
+  %25 = insertelement <4 x i32> %23, i32 %24, i32 2
+  %26 = extractvalue [4 x i32] %11, 3
+  %27 = insertelement <4 x i32> %25, i32 %26, i32 3
+  %28 = add <4 x i32> %19, %27
+  %29 = extractelement <4 x i32> %28, i32 0
+  %30 = insertvalue [4 x i32] undef, i32 %29, 0
+  %31 = extractelement <4 x i32> %28, i32 1
+  %32 = insertvalue [4 x i32] %30, i32 %31, 1
+  %33 = extractelement <4 x i32> %28, i32 2
+  %34 = insertvalue [4 x i32] %32, i32 %33, 2
+  %35 = extractelement <4 x i32> %28, i32 3
+  %36 = insertvalue [4 x i32] %34, i32 %35, 3
+  %37 = load [4 x i32], ptr %5, align 4
+  %38 = extractvalue [4 x i32] %36, 0
+  %39 = insertelement <4 x i32> undef, i32 %38, i32 0
+  %40 = extractvalue [4 x i32] %36, 1
+  %41 = insertelement <4 x i32> %39, i32 %40, i32 1
+  %42 = extractvalue [4 x i32] %36, 2
+  %43 = insertelement <4 x i32> %41, i32 %42, i32 2
+  %44 = extractvalue [4 x i32] %36, 3
+  %45 = insertelement <4 x i32> %43, i32 %44, i32 3
+  %46 = extractvalue [4 x i32] %37, 0
+  %47 = insertelement <4 x i32> undef, i32 %46, i32 0
+  %48 = extractvalue [4 x i32] %37, 1
+  %49 = insertelement <4 x i32> %47, i32 %48, i32 1
+  %50 = extractvalue [4 x i32] %37, 2
+  %51 = insertelement <4 x i32> %49, i32 %50, i32 2
+  %52 = extractvalue [4 x i32] %37, 3
+  %53 = insertelement <4 x i32> %51, i32 %52, i32 3
+  %54 = shufflevector <4 x i32> %45, <4 x i32> %53, <4 x i32> <i32 0, i32 4, i32 1, i32 5>
+  %55 = extractelement <4 x i32> %54, i32 0
+  %56 = insertvalue [4 x i32] undef, i32 %55, 0
+  %57 = extractelement <4 x i32> %54, i32 1
+  %58 = insertvalue [4 x i32] %56, i32 %57, 1
+  %59 = extractelement <4 x i32> %54, i32 2
+  %60 = insertvalue [4 x i32] %58, i32 %59, 2
+  %61 = extractelement <4 x i32> %54, i32 3
+  %62 = insertvalue [4 x i32] %60, i32 %61, 3
+  %63 = extractvalue [4 x i32] %62, 0
+  %64 = insertelement <4 x i32> undef, i32 %63, i32 0
+  %65 = extractvalue [4 x i32] %62, 1
+  %66 = insertelement <4 x i32> %64, i32 %65, i32 1
+  %67 = extractvalue [4 x i32] %62, 2
+  %68 = insertelement <4 x i32> %66, i32 %67, i32 2
+  %69 = extractvalue [4 x i32] %62, 3
+  %70 = insertelement <4 x i32> %68, i32 %69, i32 3
+  %71 = call i32 @llvm.vector.reduce.add.v4i32(<4 x i32> %70)
+  store [4 x float] zeroinitializer, ptr %4, align 4
+  %72 = getelementptr inbounds float, ptr %4, i64 0
+  %73 = getelementptr inbounds float, ptr %4, i64 1
+  %74 = getelementptr inbounds float, ptr %4, i64 2
+  %75 = getelementptr inbounds float, ptr %4, i64 3
+  store float 1.000000e+00, ptr %72, align 4
+  store float 2.000000e+00, ptr %73, align 4
+  store float 3.000000e+00, ptr %74, align 4
+  store float 4.000000e+00, ptr %75, align 4
+  %76 = load [4 x float], ptr %4, align 4
+  store [4 x float] zeroinitializer, ptr %3, align 4
+  %77 = getelementptr inbounds float, ptr %3, i64 0
+  %78 = getelementptr inbounds float, ptr %3, i64 1
+  %79 = getelementptr inbounds float, ptr %3, i64 2
+  %80 = getelementptr inbounds float, ptr %3, i64 3
+  store float 4.000000e+00, ptr %77, align 4
+  store float 3.000000e+00, ptr %78, align 4
+  store float 2.000000e+00, ptr %79, align 4
+  store float 1.000000e+00, ptr %80, align 4
+  %81 = load [4 x float], ptr %3, align 4
+  %82 = call fastcc float @main.dot([4 x float] %76, [4 x float] %81)
+  ret i32 0
+}
+
+declare void @"github.com/goplus/llgo/vector.init"()
+
+; Function Attrs: nofree nosync nounwind readnone willreturn
+declare float @llvm.vector.reduce.fadd.v4f32(float, <4 x float>) #0
+
+; Function Attrs: nofree nosync nounwind readnone willreturn
+declare i32 @llvm.vector.reduce.add.v4i32(<4 x i32>) #0
+
+attributes #0 = { nofree nosync nounwind readnone willreturn }
@@ -0,0 +1,13 @@
+package main
+
+//go:wasmimport env log_i32
+func logI32(v int32)
+
+//go:wasmexport add
+func add(a, b int32) int32 {
+	return a + b
+}
+
+func main() {
+	logI32(add(1, 2))
+}
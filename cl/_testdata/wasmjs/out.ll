@@ -0,0 +1,36 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+declare void @main.logI32(i32) #0
+
+define i32 @main.add(i32 %0, i32 %1) #1 {
+_llgo_0:
+  %2 = add i32 %0, %1
+  ret i32 %2
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call i32 @main.add(i32 1, i32 2)
+  call void @main.logI32(i32 %3)
+  ret i32 0
+}
+
+attributes #0 = { "wasm-import-module"="env" "wasm-import-name"="log_i32" }
+attributes #1 = { "wasm-export-name"="add" }
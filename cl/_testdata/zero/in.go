@@ -0,0 +1,24 @@
+package main
+
+type big struct {
+	buf [64]int64
+}
+
+var g = big{}
+
+var counters [128]int32
+
+var n int
+
+func sum() int64 {
+	var b big
+	b.buf[1] = 2
+	return b.buf[0] + b.buf[1]
+}
+
+func reset() {
+	g = big{}
+}
+
+func main() {
+}
@@ -0,0 +1,56 @@
+; ModuleID = 'main'
+source_filename = "main"
+
+%big = type { [64 x i64] }
+
+@"main.init$guard" = global ptr null
+@main.g = global %big zeroinitializer
+@main.counters = global [128 x i32] zeroinitializer
+@main.n = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @main.sum() {
+_llgo_0:
+  %0 = alloca %big, align 8
+  call void @llvm.memset.p0.i64(ptr align 4 %0, i8 0, i64 512, i1 false)
+  %1 = getelementptr inbounds %big, ptr %0, i32 0, i32 0
+  %2 = getelementptr inbounds i64, ptr %1, i64 1
+  store i64 2, ptr %2, align 4
+  %3 = getelementptr inbounds %big, ptr %0, i32 0, i32 0
+  %4 = getelementptr inbounds i64, ptr %3, i64 0
+  %5 = load i64, ptr %4, align 4
+  %6 = getelementptr inbounds %big, ptr %0, i32 0, i32 0
+  %7 = getelementptr inbounds i64, ptr %6, i64 1
+  %8 = load i64, ptr %7, align 4
+  %9 = add i64 %5, %8
+  ret i64 %9
+}
+
+define void @main.reset() {
+_llgo_0:
+  call void @llvm.memset.p0.i64(ptr align 4 @main.g, i8 0, i64 512, i1 false)
+  ret void
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  ret i32 0
+}
+
+; Function Attrs: argmemonly nofree nounwind willreturn writeonly
+declare void @llvm.memset.p0.i64(ptr nocapture writeonly, i8, i64, i1 immarg) #0
+
+attributes #0 = { argmemonly nofree nounwind willreturn writeonly }
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/constant"
+	"go/types"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Calls of asm.Inline and asm.Constraint are compiled to LLVM inline assembly
+// (see llssa.Builder.InlineAsm). The operands of Constraint are the variadic
+// arguments of the call, which are passed as the ones of fmt.Println are (see
+// lowerFmtCalls), and the instructions that build them aren't compiled:
+//
+//	t0 = new [2]uintptr (varargs)
+//	t1 = &t0[0:int]
+//	*t1 = 1:uintptr
+//	t2 = &t0[1:int]
+//	*t2 = t3
+//	t4 = slice t0[:]
+//	t5 = asm.Constraint("syscall":string, "={rax},{rax},{rdi}":string, t4...)
+
+// pkgAsm is the package of inline assembly.
+const pkgAsm = "github.com/goplus/llgo/asm"
+
+// asmCall is a call of package asm that is lowered to inline assembly.
+type asmCall struct {
+	code        string
+	constraints string
+	args        []ssa.Value
+}
+
+// lowerAsmCalls finds the calls of package asm in f, and the instructions
+// that build their operands, which mustn't be compiled. It must be called
+// after lowerFmtCalls.
+func (p *context) lowerAsmCalls(f *ssa.Function) {
+	p.asms = make(map[*ssa.Call]*asmCall)
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			if name, ok := asmFuncOf(call); ok {
+				p.asms[call] = p.asmCallOf(call, name)
+			}
+		}
+	}
+}
+
+// asmFuncOf returns the name of the function of package asm that call calls,
+// if it is one.
+func asmFuncOf(call *ssa.Call) (name string, ok bool) {
+	fn, isFn := call.Call.Value.(*ssa.Function)
+	if !isFn || fn.Pkg == nil || fn.Pkg.Pkg.Path() != pkgAsm {
+		return
+	}
+	switch name = fn.Name(); name {
+	case "Inline", "Constraint":
+		return name, true
+	}
+	return "", false
+}
+
+// asmCallOf returns the lowered call of the function name of package asm. It
+// fails if the assembly isn't constant, or if the operands are passed as a
+// slice, as the call can't be compiled otherwise.
+func (p *context) asmCallOf(call *ssa.Call, name string) *asmCall {
+	args := call.Call.Args
+	ret := &asmCall{code: p.asmString(call, args[0])}
+	if name == "Constraint" {
+		ret.constraints = p.asmString(call, args[1])
+		vals, skips, ok := varArgsOf(args[2])
+		if !ok {
+			p.unsupported(call.Pos(), "unsupported operands of inline assembly, which must be separate arguments: %v", call)
+		}
+		ret.args = vals
+		for _, instr := range skips {
+			p.skips[instr] = none{}
+		}
+	}
+	return ret
+}
+
+func (p *context) asmString(call *ssa.Call, v ssa.Value) string {
+	c, ok := v.(*ssa.Const)
+	if !ok || c.Value == nil || c.Value.Kind() != constant.String {
+		p.unsupported(call.Pos(), "unsupported inline assembly, which must be a constant string: %v", call)
+	}
+	return constant.StringVal(c.Value)
+}
+
+// compileAsm compiles the lowered call of package asm, whose result is 0 if
+// the assembly has no output.
+func (p *context) compileAsm(b llssa.Builder, call *asmCall) llssa.Expr {
+	prog := p.prog
+	args := p.compileValues(b, call.args, fnNormal)
+	tuintptr := prog.Type(types.Typ[types.Uintptr])
+	if !strings.HasPrefix(call.constraints, "=") {
+		b.InlineAsm(call.code, call.constraints, prog.Void(), args...)
+		return prog.IntVal(0, tuintptr)
+	}
+	return b.InlineAsm(call.code, call.constraints, tuintptr, args...)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// CheckPackage reports the constructs of the Go package pkg that llgo can't
+// compile yet, without compiling it, so that the portability of code to llgo
+// can be assessed: the ones that NewPackage fails on, each instruction that it
+// would reject rather than the first one of a function or method. A few
+// constructs, that llssa fails on when it generates their code, are only
+// reported by NewPackage. The errors are sorted by position, and the list is
+// empty if the package can be compiled.
+func CheckPackage(pkg *ssa.Package) ErrorList {
+	p := &context{
+		conf:   new(Config),
+		fset:   pkg.Prog.Fset,
+		goTyps: pkg.Pkg,
+		goPkg:  pkg,
+	}
+	names := make([]string, 0, len(pkg.Members))
+	for name := range pkg.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch m := pkg.Members[name].(type) {
+		case *ssa.Function:
+			if m.TypeParams() == nil && len(m.Blocks) > 0 {
+				p.checkFunc(m)
+			}
+		case *ssa.Type:
+			for _, fn := range typeMethods(pkg, m) {
+				if len(fn.Blocks) > 0 {
+					p.checkFunc(fn)
+				}
+			}
+		}
+	}
+	p.errs.Sort()
+	return p.errs
+}
+
+// checkFunc reports the instructions of f that compileFunc would reject.
+func (p *context) checkFunc(f *ssa.Function) {
+	p.pos = token.NoPos
+	p.checkFn(f, func() {
+		p.lowerFmtCalls(f)
+		p.lowerAsmCalls(f)
+		p.lowerVArgs(f)
+		p.devirtualize(f)
+	})
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			if _, ok := p.skips[instr]; ok {
+				continue
+			}
+			p.pos = instr.Pos()
+			p.checkFn(f, func() { p.checkInstr(instr) })
+		}
+	}
+	for _, anon := range f.AnonFuncs {
+		p.checkFunc(anon)
+	}
+}
+
+// checkFn runs do, whose failure is recorded as the one of compiling f (see
+// recoverFunc).
+func (p *context) checkFn(f *ssa.Function, do func()) {
+	defer p.recoverFunc(f)
+	do()
+}
+
+// checkInstr fails as compileInstr would on instr, without compiling it.
+func (p *context) checkInstr(instr ssa.Instruction) {
+	for _, op := range instr.Operands(nil) {
+		if op != nil && *op != nil {
+			p.checkValue(*op)
+		}
+	}
+	switch v := instr.(type) {
+	case *ssa.Call:
+		if _, ok := p.fmts[v]; ok {
+			break
+		}
+		if _, ok := p.asms[v]; ok {
+			break
+		}
+		p.checkCall(&v.Call, v)
+	case *ssa.Defer:
+		p.checkCall(&v.Call, v)
+	case *ssa.Go:
+		call := v.Call
+		if dc, ok := p.devirt[&v.Call]; ok {
+			call = *dc
+		}
+		if !goSupported(&call) {
+			p.unsupported(v.Pos(), "unsupported go statement: %v", v)
+		}
+	case *ssa.Convert:
+		if !convertible(v) {
+			p.unsupported(v.Pos(), "unsupported conversion: %v", v)
+		}
+	case *ssa.ChangeType:
+		if !p.changeable(v) {
+			p.unsupported(v.Pos(), "unsupported conversion: %v", v)
+		}
+	case *ssa.Range:
+		if _, ok := v.X.Type().Underlying().(*types.Map); !ok {
+			p.unsupported(v.Pos(), "unsupported range over string: %v", v)
+		}
+	case *ssa.Next:
+		if v.IsString {
+			p.unsupported(v.Pos(), "unsupported range over string: %v", v)
+		}
+	case *ssa.TypeAssert:
+		if u, ok := v.AssertedType.Underlying().(*types.Interface); ok && !u.Empty() {
+			p.unsupported(v.Pos(), "unsupported type assertion to a non-empty interface: %v", v)
+		}
+	case *ssa.BinOp, *ssa.UnOp, *ssa.IndexAddr, *ssa.FieldAddr, *ssa.Field, *ssa.Alloc,
+		*ssa.Extract, *ssa.MakeInterface, *ssa.MakeClosure, *ssa.MakeMap, *ssa.Select,
+		*ssa.Slice, *ssa.SliceToArrayPointer, *ssa.Phi, *ssa.Index, *ssa.Lookup,
+		*ssa.Store, *ssa.MapUpdate, *ssa.Jump, *ssa.Return, *ssa.RunDefers, *ssa.If, *ssa.Panic:
+	default:
+		p.unsupported(instr.Pos(), "unsupported instruction %T: %v", instr, instr)
+	}
+}
+
+// checkValue fails as compileValue would on the operand v.
+func (p *context) checkValue(v ssa.Value) {
+	switch v.(type) {
+	case instrAndValue, *ssa.Parameter, *ssa.FreeVar, *ssa.Function, *ssa.Global, *ssa.Const, *ssa.Builtin:
+		return
+	}
+	p.unsupported(v.Pos(), "unsupported value %T: %v", v, v)
+}
+
+// checkCall fails as compileCall would on the call of instr.
+func (p *context) checkCall(call *ssa.CallCommon, instr ssa.Instruction) {
+	if dc, ok := p.devirt[call]; ok {
+		call = dc
+	}
+	if fn, ok := call.Value.(*ssa.Builtin); ok {
+		if !builtinSupported(fn.Name(), call.Args) {
+			p.unsupported(instr.Pos(), "unsupported builtin call: %v", instr)
+		}
+		return
+	}
+	if call.IsInvoke() {
+		if _, _, ok := rtInvokeIntrinsicOf(call); !ok {
+			p.unsupported(instr.Pos(), "unsupported interface method call: %v", instr)
+		}
+		return
+	}
+	if funcKind(call.Value) == fnHasVArg {
+		varg := call.Args[len(call.Args)-1]
+		if _, ok := p.vargs[varg]; ok {
+			return
+		}
+		if c, ok := varg.(*ssa.Const); !ok || c.Value != nil {
+			p.unsupported(instr.Pos(), "unsupported variadic arguments: %v", instr)
+		}
+	}
+}
+
+// goSupported reports whether the go statement of call, devirtualized, is
+// compiled by llssa.Builder.Go: its callee is a function, static or a func
+// value, rather than a builtin or an interface method.
+func goSupported(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return false
+	}
+	_, ok := call.Value.(*ssa.Builtin)
+	return !ok
+}
+
+// builtinSupported reports whether the call of the builtin function name with
+// args is compiled by llssa.Builder.BuiltinCall.
+func builtinSupported(name string, args []ssa.Value) bool {
+	switch name {
+	case "real", "imag", "complex", "Add", "Slice", "String", "SliceData", "StringData", "append", "copy", "delete", "recover", "ssa:wrapnilchk":
+		return true
+	case "len", "cap":
+		switch t := args[0].Type().Underlying().(type) {
+		case *types.Basic:
+			return t.Info()&types.IsString != 0
+		case *types.Slice:
+			return true
+		case *types.Map:
+			return name == "len"
+		}
+	case "clear":
+		_, ok := args[0].Type().Underlying().(*types.Map)
+		return ok
+	case "print", "println":
+		for _, arg := range args {
+			if !printable(arg.Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// printable reports whether values of the type t can be printed by the builtin
+// print (see llssa.Builder.Print): booleans, numbers, strings and pointers.
+func printable(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Info()&(types.IsBoolean|types.IsNumeric|types.IsString) != 0 || t.Kind() == types.UnsafePointer
+	case *types.Pointer, *types.Map, *types.Chan, *types.Signature:
+		return true
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
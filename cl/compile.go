@@ -17,16 +17,19 @@
 package cl
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	llssa "github.com/goplus/llgo/ssa"
 	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
 )
 
 // -----------------------------------------------------------------------------
@@ -56,7 +59,7 @@ func SetDebug(dbgFlags dbgFlags) {
 const (
 	fnNormal = iota
 	fnHasVArg
-	fnUnsafeInit
+	fnNoInit // init of a package that isn't compiled
 )
 
 func funcKind(vfn ssa.Value) int {
@@ -64,8 +67,10 @@ func funcKind(vfn ssa.Value) int {
 		params := fn.Signature.Params()
 		n := params.Len()
 		if n == 0 {
-			if fn.Name() == "init" && fn.Pkg.Pkg.Path() == "unsafe" {
-				return fnUnsafeInit
+			if fn.Name() == "init" && fn.Pkg != nil {
+				if path := fn.Pkg.Pkg.Path(); path == "unsafe" || ImplementedByRuntime(path) {
+					return fnNoInit
+				}
 			}
 		} else {
 			last := params.At(n - 1)
@@ -77,6 +82,28 @@ func funcKind(vfn ssa.Value) int {
 	return fnNormal
 }
 
+// localVarName returns the name of the Go variable allocated by v. go/ssa
+// stores it in Comment, which describes the allocation instead if it isn't a
+// named variable.
+func localVarName(v *ssa.Alloc) (string, bool) {
+	switch name := v.Comment; name {
+	case "", "_", "new", "makeslice", "complit", "slicelit", "varargs", "rangeindex", "rangeint.iter":
+		return "", false
+	default:
+		return name, true
+	}
+}
+
+// position returns the position of pos that the package emits, whose file
+// name is trimmed with Config.TrimPath.
+func (p *context) position(pos token.Pos) token.Position {
+	ret := p.fset.Position(pos)
+	if p.conf.TrimPath && ret.Filename != "" {
+		ret.Filename = path.Join(p.goTyps.Path(), filepath.Base(ret.Filename))
+	}
+	return ret
+}
+
 // -----------------------------------------------------------------------------
 
 type none = struct{}
@@ -87,20 +114,61 @@ type instrAndValue interface {
 }
 
 type context struct {
+	conf   *Config
 	prog   llssa.Program
 	pkg    llssa.Package
 	fn     llssa.Function
 	fset   *token.FileSet
 	goTyps *types.Package
 	goPkg  *ssa.Package
-	link   map[string]string        // pkgPath.nameInPkg => linkname
-	loaded map[*types.Package]none  // loaded packages
-	bvals  map[ssa.Value]llssa.Expr // block values
+	link   map[string]string         // pkgPath.nameInPkg => linkname
+	cfns   map[string]none           // pkgPath.nameInPkg of C functions
+	pyfns  map[string]pyFunc         // pkgPath.nameInPkg of Python functions
+	wasmIn map[string]wasmImport     // pkgPath.nameInPkg of functions imported from the WebAssembly host
+	wasmEx map[string]string         // pkgPath.nameInPkg => name exported to the WebAssembly host
+	prags  map[ast.Node]funcPragmas  // pragmas of functions, see initPragmas
+	tls    map[string]none           // pkgPath.nameInPkg of thread-local variables, see initPragmas
+	fastcc map[*ssa.Function]none    // functions of the fast calling convention, see fastFuncs
+	loaded map[*types.Package]none   // loaded packages
+	bvals  map[ssa.Value]llssa.Expr  // function values
+	ends   []llssa.BasicBlock        // blocks that end the blocks of the function, see compilePhis
+	phis   []*ssa.Phi                // phis of the function, see compilePhis
+	skips  map[ssa.Instruction]none  // instructions not compiled, see lowerFmtCalls
+	vargs  map[ssa.Value][]ssa.Value // variadic arguments of C functions, see lowerVArgs
+	fmts   map[*ssa.Call][]fmtOp     // calls of fmt that are lowered
+	asms   map[*ssa.Call]*asmCall    // calls of package asm, see lowerAsmCalls
+	copies map[*ssa.Store]ssa.Value  // sources of the stores compiled to copies, see lowerCopies
+	shares map[*ssa.Convert]none     // conversions that share the bytes of their operand, see lowerConversions
+	devirt map[*ssa.CallCommon]*ssa.CallCommon
+	swks   map[*ssautil.Switch]llssa.Expr // indexes of the cases of type switches, see typeSwitchIndex
 	inits  []func()
+	cover  []coverFunc       // functions whose coverage is measured, see Config.Cover
+	ctrs   llssa.Expr        // coverage counters of the function being compiled, if any
+	defers []*deferSite      // defer statements of the function being compiled
+	dbits  llssa.Expr        // mask of the executed defer statements, see openDefers
+	dhead  llssa.Expr        // head of the list of defer records, if not open-coded, see openDefers
+	lpad   llssa.BasicBlock  // landing pad of the function, see compileLandingPad
+	sws    []*ssautil.Switch // switches compiled to switch instructions, see lowerSwitches
+	tail   bool              // calls in tail position are tail calls, see tailCalls
+	safept bool              // back edges of loops check for preemption, see Config.PreemptLoops
+	pos    token.Pos         // position of the instruction being compiled
+	errs   ErrorList
+	failed []string // functions that failed to compile
+	nfunc  int      // number of functions compiled
+
+	device  string              // GPU of the kernels that the context compiles, see compileKernel
+	devices map[string]*context // contexts of the device packages of kernels by GPU and processor
+	kernels []*kernel           // kernels of the package
 }
 
 func (p *context) compileType(pkg llssa.Package, member *ssa.Type) {
-	panic("todo")
+	// LLVM types are created lazily when they are used, so there is nothing
+	// to do for a type declaration itself but to compile its methods.
+	for _, fn := range typeMethods(p.goPkg, member) {
+		if p.conf.Reachable == nil || p.conf.Reachable.Has(fn) {
+			p.compileFunc(pkg, fn)
+		}
+	}
 }
 
 // Global variable.
@@ -110,21 +178,62 @@ func (p *context) compileGlobal(pkg llssa.Package, gbl *ssa.Global) {
 	if debugInstr {
 		log.Println("==> NewVar", name, typ)
 	}
-	g := pkg.NewVar(name, typ)
-	g.Init(p.prog.Null(g.Type))
+	// a variable larger than a pointer is defined with its own type, in the
+	// .bss section, rather than zeroed by stores
+	var g llssa.Global
+	sizes := p.prog.TypeSizes()
+	if sizes.Sizeof(typ.(*types.Pointer).Elem()) > sizes.Sizeof(typ) {
+		g = pkg.NewZeroVar(name, typ)
+	} else {
+		g = pkg.NewVar(name, typ)
+		g.Init(p.prog.Null(g.Type))
+	}
+	if _, ok := p.tls[name]; ok {
+		g.SetThreadLocal()
+	}
+	if vis := p.conf.Visibility; vis != llssa.VisibilityDefault {
+		g.SetVisibility(vis)
+	}
 }
 
 func (p *context) compileFunc(pkg llssa.Package, f *ssa.Function) {
-	name := p.funcName(f.Pkg.Pkg, f)
+	name := p.funcName(p.pkgOf(f), f)
 	if debugInstr {
 		log.Println("==> NewFunc", name)
 	}
-	fn := pkg.NewFunc(name, f.Signature)
+	p.nfunc++
+	p.pos = f.Pos()
+	defer p.recoverFunc(f)
+	sig := funcSig(f)
+	if p.isCMain(f) {
+		sig = cMainSig
+	}
+	fn := p.newFunc(pkg, p.goFuncName(p.pkgOf(f), f), name, sig)
+	if isWrapper(f) {
+		fn.SetLinkOnce()
+	}
+	prags := p.prags[f.Syntax()]
+	if len(f.Blocks) > 0 {
+		p.setPragmas(fn, prags)
+	}
+	if _, ok := p.fastcc[f]; ok {
+		fn.SetFastCC()
+	}
+	if prags.kernel != "" && p.device == "" {
+		p.compileKernel(f, fn, prags)
+		return
+	}
+	for _, anon := range f.AnonFuncs {
+		if p.conf.Reachable == nil || p.conf.Reachable.Has(anon) {
+			p.compileFunc(pkg, anon)
+		}
+	}
 	p.inits = append(p.inits, func() {
-		p.fn = fn
+		p.fn, p.pos = fn, f.Pos()
 		defer func() {
 			p.fn = nil
 		}()
+		defer p.recoverFunc(f)
 		nblk := len(f.Blocks)
 		if nblk == 0 { // external function
 			return
@@ -135,10 +244,47 @@ func (p *context) compileFunc(pkg llssa.Package, f *ssa.Function) {
 		if debugInstr {
 			log.Println("==> FuncBody", name)
 		}
+		fn.SetPos(p.position(f.Pos()))
 		fn.MakeBlocks(nblk)
+		if p.isTraced() {
+			pkg.AddFuncInfo(fn, p.funcInfoName(f), p.funcInfoPos(f))
+		}
 		b := fn.NewBuilder()
-		for i, block := range f.Blocks {
-			p.compileBlock(b, block, i == 0 && name == "main")
+		b.SetBlock(fn.Block(0))
+		b.SetPos(p.position(f.Pos()))
+		for i, param := range f.Params {
+			b.DebugParam(len(f.FreeVars)+i, param.Name(), p.position(param.Pos()))
+		}
+		preempt := p.conf.Preempt && !prags.nosplit && !strings.HasPrefix(p.goTyps.Path(), llssa.PkgRuntime)
+		if preempt {
+			b.PreemptCheck()
+		}
+		p.safept = preempt && p.conf.PreemptLoops
+		p.bvals = make(map[ssa.Value]llssa.Expr)
+		p.ends, p.phis = make([]llssa.BasicBlock, nblk), nil
+		p.ctrs = llssa.Expr{}
+		if p.isCovered(f) {
+			p.ctrs = p.coverCounters(f, name)
+		}
+		p.lowerFmtCalls(f)
+		p.lowerVArgs(f)
+		p.lowerAsmCalls(f)
+		p.lowerCopies(f)
+		p.lowerConversions(f)
+		p.devirtualize(f)
+		p.lowerSwitches(f)
+		p.openDefers(b, f)
+		p.tail = p.tailCalls(f)
+		for i, block := range f.DomPreorder() { // values are defined before they are used
+			p.compileBlock(b, block, i == 0 && p.isCMain(f))
+			p.ends[block.Index] = b.Block()
+		}
+		p.compileLandingPad(b, f)
+		p.compilePhis(b)
+		if p.conf.Verify {
+			if err := fn.Verify(); err != nil {
+				panic(&compileError{f.Pos(), "invalid LLVM IR: " + err.Error()})
+			}
 		}
 	})
 }
@@ -146,55 +292,225 @@ func (p *context) compileFunc(pkg llssa.Package, f *ssa.Function) {
 func (p *context) compileBlock(b llssa.Builder, block *ssa.BasicBlock, doInit bool) llssa.BasicBlock {
 	ret := p.fn.Block(block.Index)
 	b.SetBlock(ret)
-	p.bvals = make(map[ssa.Value]llssa.Expr)
 	if doInit {
-		fn := p.pkg.FuncOf("main.init")
+		if usesPkg(p.goTyps, "os") {
+			b.RuntimeCall("ArgsInit", argsInitSig, p.fn.Param(0), p.fn.Param(1), p.fn.Param(2))
+		}
+		fn := p.pkg.FuncOf(fullName(p.goTyps, "init"))
 		b.Call(fn.Expr)
 	}
+	if block.Comment == "init.start" && p.isTraced() {
+		b.Call(p.funcTabInit().Expr)
+	}
+	if block.Comment == "init.start" && p.isCoveredPkg() {
+		b.Call(p.coverInit().Expr)
+	}
+	inc := p.ctrs.Type != nil
 	for _, instr := range block.Instrs {
+		if _, ok := instr.(*ssa.Phi); !ok && inc { // after the phis of block
+			p.coverInc(b, p.ctrs, block)
+			inc = false
+		}
+		if _, ok := p.skips[instr]; ok {
+			continue
+		}
+		if pos := instr.Pos(); pos.IsValid() {
+			p.pos = pos
+			b.SetPos(p.position(pos))
+		}
 		p.compileInstr(b, instr)
 	}
 	return ret
 }
 
+// compilePhis adds the incoming values of the phis of the function, once its
+// blocks are compiled: the values of the back edges of loops are defined after
+// the phis of their headers. The edges from the blocks that aren't compiled,
+// as they are unreachable, are ignored (see llssa.Phi.AddIncoming).
+func (p *context) compilePhis(b llssa.Builder) {
+	for _, v := range p.phis {
+		preds := v.Block().Preds
+		blks := make([]llssa.BasicBlock, 0, len(preds))
+		vals := make([]llssa.Expr, 0, len(preds))
+		for i, pred := range preds {
+			if end := p.ends[pred.Index]; end != nil {
+				blks = append(blks, end)
+				vals = append(vals, p.compileValue(b, v.Edges[i]))
+			}
+		}
+		llssa.Phi{Expr: p.bvals[v]}.AddIncoming(blks, vals)
+	}
+}
+
+// isBackEdge reports whether block ends a loop, ie. whether it jumps to a
+// block that dominates it: the header of the loop.
+func isBackEdge(block *ssa.BasicBlock) bool {
+	for _, succ := range block.Succs {
+		if succ.Dominates(block) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *context) compileInstrAndValue(b llssa.Builder, iv instrAndValue) (ret llssa.Expr) {
 	if v, ok := p.bvals[iv]; ok {
 		return v
 	}
 	switch v := iv.(type) {
 	case *ssa.Call:
-		call := v.Call
-		kind := funcKind(call.Value)
-		if kind == fnUnsafeInit {
-			return
+		if ops, ok := p.fmts[v]; ok {
+			p.compileFmt(b, ops)
+			break
 		}
-		if debugGoSSA {
-			log.Println(">>> Call", call.Value, call.Args)
+		if call, ok := p.asms[v]; ok {
+			ret = p.compileAsm(b, call)
+			break
 		}
-		fn := p.compileValue(b, call.Value)
-		args := p.compileValues(b, call.Args, kind)
-		ret = b.Call(fn, args...)
+		ret = p.compileCall(b, &v.Call, v)
 	case *ssa.BinOp:
 		x := p.compileValue(b, v.X)
 		y := p.compileValue(b, v.Y)
 		ret = b.BinOp(v.Op, x, y)
 	case *ssa.UnOp:
 		x := p.compileValue(b, v.X)
+		if v.Op == token.MUL && p.race(v.X) {
+			b.RaceRead(x)
+		}
 		ret = b.UnOp(v.Op, x)
 	case *ssa.IndexAddr:
 		x := p.compileValue(b, v.X)
 		idx := p.compileValue(b, v.Index)
 		ret = b.IndexAddr(x, idx)
+	case *ssa.FieldAddr:
+		x := p.compileValue(b, v.X)
+		ret = b.FieldAddr(x, v.Field)
+	case *ssa.Slice:
+		x := p.compileValue(b, v.X)
+		var low, high, max llssa.Expr
+		if v.Low != nil {
+			low = p.compileValue(b, v.Low)
+		}
+		if v.High != nil {
+			high = p.compileValue(b, v.High)
+		}
+		if v.Max != nil {
+			max = p.compileValue(b, v.Max)
+		}
+		ret = b.Slice(x, low, high, max)
+	case *ssa.SliceToArrayPointer:
+		ret = b.SliceToArrayPointer(p.prog.Type(v.Type()), p.compileValue(b, v.X))
+	case *ssa.Field:
+		x := p.compileValue(b, v.X)
+		ret = b.Field(x, v.Field)
 	case *ssa.Alloc:
 		t := v.Type()
-		ret = b.Alloc(p.prog.Type(t), v.Heap)
+		heap := isHeapAlloc(v)
+		ret = b.Alloc(p.prog.Type(t), heap)
+		if name, ok := localVarName(v); ok && !heap {
+			b.DebugVar(ret, name, p.position(v.Pos()))
+		}
+	case *ssa.Extract:
+		x := p.compileValue(b, v.Tuple)
+		ret = b.Extract(x, v.Index)
+	case *ssa.Convert:
+		if !convertible(v) {
+			p.unsupported(v.Pos(), "unsupported conversion: %v", v)
+		}
+		_, share := p.shares[v]
+		ret = b.Convert(p.prog.Type(v.Type()), p.compileValue(b, v.X), share)
+	case *ssa.ChangeType:
+		if !p.changeable(v) {
+			p.unsupported(v.Pos(), "unsupported conversion: %v", v)
+		}
+		if cFuncConv(v) {
+			ret = p.compileCFunc(b, v)
+			break
+		}
+		ret = b.ChangeType(p.prog.Type(v.Type()), p.compileValue(b, v.X))
+	case *ssa.Phi:
+		ret = b.Phi(p.prog.Type(v.Type())).Expr
+		p.phis = append(p.phis, v)
+	case *ssa.MakeInterface:
+		x := p.compileValue(b, v.X)
+		ret = b.MakeInterface(p.prog.Type(v.Type()), x)
+	case *ssa.MakeClosure:
+		fn := p.funcOf(v.Fn.(*ssa.Function))
+		bindings := p.compileValues(b, v.Bindings, fnNormal)
+		ret = b.MakeClosure(fn.Expr, p.prog.Type(v.Type()), bindings...)
+	case *ssa.TypeAssert:
+		if u, ok := v.AssertedType.Underlying().(*types.Interface); ok && !u.Empty() {
+			p.unsupported(v.Pos(), "unsupported type assertion to a non-empty interface: %v", v)
+		}
+		t := p.prog.Type(v.AssertedType)
+		if sw, i := p.typeCaseOf(v); sw != nil {
+			k := p.typeSwitchIndex(b, sw)
+			ret = b.TypeSwitchCase(p.compileValue(b, v.X), t, k, i)
+			break
+		}
+		ret = b.TypeAssert(p.compileValue(b, v.X), t, v.CommaOk)
+	case *ssa.MakeMap:
+		var hint llssa.Expr
+		if v.Reserve != nil {
+			hint = p.compileValue(b, v.Reserve)
+		}
+		ret = b.MakeMap(p.prog.Type(v.Type()), hint)
+	case *ssa.Lookup:
+		x := p.compileValue(b, v.X)
+		idx := p.compileValue(b, v.Index)
+		if _, ok := v.X.Type().Underlying().(*types.Map); !ok { // string index
+			ret = b.Index(x, idx)
+			break
+		}
+		ret = b.MapLookup(x, idx, v.CommaOk)
+	case *ssa.Index:
+		x := p.compileValue(b, v.X)
+		idx := p.compileValue(b, v.Index)
+		ret = b.Index(x, idx)
+	case *ssa.Range:
+		if _, ok := v.X.Type().Underlying().(*types.Map); !ok {
+			p.unsupported(v.Pos(), "unsupported range over string: %v", v)
+		}
+		ret = b.MapRange(p.compileValue(b, v.X))
+	case *ssa.Next:
+		if v.IsString {
+			p.unsupported(v.Pos(), "unsupported range over string: %v", v)
+		}
+		it := p.compileValue(b, v.Iter)
+		ret = b.MapNext(it, p.prog.Type(v.Iter.(*ssa.Range).X.Type()))
+	case *ssa.Select:
+		states := make([]*llssa.SelectState, len(v.States))
+		for i, s := range v.States {
+			state := &llssa.SelectState{
+				Chan: p.compileValue(b, s.Chan),
+				Send: s.Dir == types.SendOnly,
+			}
+			if state.Send {
+				state.Value = p.compileValue(b, s.Send)
+			}
+			states[i] = state
+		}
+		ret = b.Select(states, v.Blocking)
 	default:
-		panic(fmt.Sprintf("compileInstrAndValue: unknown instr - %T\n", iv))
+		p.unsupported(iv.Pos(), "unsupported instruction %T: %v", iv, iv)
 	}
 	p.bvals[iv] = ret
 	return ret
 }
 
+// race reports whether the accesses of the package to the memory at addr are
+// reported to ThreadSanitizer (see Config.Race). The ones to variables on the
+// stack, which goroutines don't share, aren't.
+func (p *context) race(addr ssa.Value) bool {
+	if !p.conf.Race || strings.HasPrefix(p.goTyps.Path(), llssa.PkgRuntime) {
+		return false
+	}
+	if alloc, ok := addr.(*ssa.Alloc); ok && !isHeapAlloc(alloc) {
+		return false
+	}
+	return true
+}
+
 func (p *context) compileInstr(b llssa.Builder, instr ssa.Instruction) {
 	if iv, ok := instr.(instrAndValue); ok {
 		p.compileInstrAndValue(b, iv)
@@ -202,33 +518,159 @@ func (p *context) compileInstr(b llssa.Builder, instr ssa.Instruction) {
 	}
 	switch v := instr.(type) {
 	case *ssa.Store:
+		if src, ok := p.copies[v]; ok {
+			p.compileCopy(b, v, src)
+			break
+		}
 		ptr := p.compileValue(b, v.Addr)
 		val := p.compileValue(b, v.Val)
+		if p.race(v.Addr) {
+			b.RaceWrite(ptr)
+		}
 		b.Store(ptr, val)
+	case *ssa.MapUpdate:
+		m := p.compileValue(b, v.Map)
+		key := p.compileValue(b, v.Key)
+		val := p.compileValue(b, v.Value)
+		b.MapUpdate(m, key, val)
 	case *ssa.Jump:
+		if p.safept && isBackEdge(v.Block()) {
+			b.Safepoint()
+		}
 		fn := p.fn
 		succs := v.Block().Succs
 		jmpb := fn.Block(succs[0].Index)
 		b.Jump(jmpb)
 	case *ssa.Return:
 		var results []llssa.Expr
-		if n := len(v.Results); n > 0 {
+		if p.isCMain(v.Parent()) {
+			results = []llssa.Expr{p.prog.IntVal(0, p.prog.Type(types.Typ[types.Int32]))}
+		} else if n := len(v.Results); n > 0 {
 			results = make([]llssa.Expr, n)
 			for i, r := range v.Results {
 				results[i] = p.compileValue(b, r)
 			}
 		}
 		b.Return(results...)
+	case *ssa.Defer:
+		p.compileDefer(b, v)
+	case *ssa.RunDefers:
+		p.compileRunDefers(b)
+	case *ssa.Panic:
+		b.Panic(p.compileValue(b, v.X))
+	case *ssa.Go:
+		call := v.Call
+		if dc, ok := p.devirt[&v.Call]; ok {
+			call = *dc
+		}
+		if !goSupported(&call) {
+			p.unsupported(v.Pos(), "unsupported go statement: %v", v)
+		}
+		var fn llssa.Expr
+		if f, ok := call.Value.(*ssa.Function); ok {
+			fn = p.funcOf(f).Expr
+		} else { // a func value, eg. a parameter or a closure stored in a variable
+			fn = p.compileValue(b, call.Value)
+		}
+		args := p.compileValues(b, call.Args, fnNormal)
+		b.Go(fn, args...)
 	case *ssa.If:
+		if sw := p.switchOf(v.Block()); sw != nil {
+			p.compileSwitch(b, sw)
+			break
+		}
 		fn := p.fn
 		cond := p.compileValue(b, v.Cond)
+		if p.safept && isBackEdge(v.Block()) {
+			b.Safepoint()
+		}
 		succs := v.Block().Succs
 		thenb := fn.Block(succs[0].Index)
 		elseb := fn.Block(succs[1].Index)
 		b.If(cond, thenb, elseb)
 	default:
-		panic(fmt.Sprintf("compileInstr: unknown instr - %T\n", instr))
+		p.unsupported(instr.Pos(), "unsupported instruction %T: %v", instr, instr)
+	}
+}
+
+// compileCall compiles the call of instr, which is either a call or a deferred
+// call (see compileRunDefers).
+func (p *context) compileCall(b llssa.Builder, call *ssa.CallCommon, instr ssa.Instruction) llssa.Expr {
+	if dc, ok := p.devirt[call]; ok { // see devirtualize
+		call = dc
+	}
+	if fn, ok := call.Value.(*ssa.Builtin); ok {
+		args := p.compileValues(b, call.Args, fnNormal)
+		return b.BuiltinCall(fn.Name(), args...)
+	}
+	if fn, ok := call.Value.(*ssa.Function); ok {
+		if in, ok := atomicIntrinsicOf(fn); ok {
+			args := p.compileValues(b, call.Args, fnNormal)
+			return p.compileAtomic(b, in, args)
+		}
+		if isKeepAlive(fn) {
+			b.KeepAlive(p.compileValue(b, call.Args[0]))
+			return llssa.Expr{}
+		}
+		if name, sig, ok := rtIntrinsicOf(fn); ok {
+			args := p.compileValues(b, call.Args, fnNormal)
+			return b.RuntimeCall(name, sig, args...)
+		}
+		if name, ok := gpuIntrinsicOf(fn); ok && p.device != "" {
+			return p.compileGPU(b, name, call)
+		}
+		if name, ok := vectorIntrinsicOf(fn); ok {
+			if ret, ok := p.compileVector(b, name, call); ok {
+				return ret
+			}
+		}
+		if fn, ok := p.mathIntrinsicOf(fn); ok {
+			args := p.compileValues(b, call.Args, fnNormal)
+			return b.Call(fn.Expr, args...)
+		}
+		if ret, ok := p.compileBitsIntrinsic(b, fn, call); ok {
+			return ret
+		}
+		if py, ok := p.pyfns[fullName(p.pkgOf(fn), fn.Name())]; ok {
+			args := p.compileValues(b, call.Args, fnNormal)
+			return b.PyCall(py.mod, py.name, p.prog.Type(resultType(call.Signature())), args...)
+		}
+	}
+	if call.IsInvoke() {
+		name, sig, ok := rtInvokeIntrinsicOf(call)
+		if !ok {
+			p.unsupported(instr.Pos(), "unsupported interface method call: %v", instr)
+		}
+		args := p.compileValues(b, append([]ssa.Value{call.Value}, call.Args...), fnNormal)
+		return b.RuntimeCall(name, sig, args...)
+	}
+	kind := funcKind(call.Value)
+	if kind == fnNoInit {
+		return llssa.Expr{}
+	}
+	if debugGoSSA {
+		log.Println(">>> Call", call.Value, call.Args)
 	}
+	var fn llssa.Expr
+	if f, ok := call.Value.(*ssa.Function); ok { // a static call, rather than of a func value
+		fn = p.funcOf(f).Expr
+	} else {
+		fn = p.compileValue(b, call.Value)
+	}
+	args := p.compileValues(b, call.Args, kind)
+	if v, ok := instr.(*ssa.Call); ok && kind == fnNormal && p.isTailCall(v) {
+		return b.TailCall(fn, args...)
+	}
+	return b.Call(fn, args...)
+}
+
+// resultType returns the type of the result of a call of sig, as in the Type
+// of ssa.Call.
+func resultType(sig *types.Signature) types.Type {
+	if ret := sig.Results(); ret.Len() == 1 {
+		return ret.At(0).Type()
+	}
+	return sig.Results()
 }
 
 func (p *context) compileValue(b llssa.Builder, v ssa.Value) llssa.Expr {
@@ -240,12 +682,19 @@ func (p *context) compileValue(b llssa.Builder, v ssa.Value) llssa.Expr {
 		fn := v.Parent()
 		for idx, param := range fn.Params {
 			if param == v {
+				return p.fn.Param(len(fn.FreeVars) + idx)
+			}
+		}
+	case *ssa.FreeVar:
+		fn := v.Parent()
+		for idx, fv := range fn.FreeVars {
+			if fv == v {
 				return p.fn.Param(idx)
 			}
 		}
-	case *ssa.Function:
+	case *ssa.Function: // a func value, see llssa.Builder.MakeClosure
 		fn := p.funcOf(v)
-		return fn.Expr
+		return b.MakeClosure(fn.Expr, p.prog.Type(v.Type()))
 	case *ssa.Global:
 		g := p.varOf(v)
 		return g.Expr
@@ -253,18 +702,8 @@ func (p *context) compileValue(b llssa.Builder, v ssa.Value) llssa.Expr {
 		t := v.Type()
 		return b.Const(v.Value, p.prog.Type(t))
 	}
-	panic(fmt.Sprintf("compileValue: unknown value - %T\n", v))
-}
-
-func (p *context) compileVArg(ret []llssa.Expr, b llssa.Builder, v ssa.Value) []llssa.Expr {
-	_ = b
-	switch v := v.(type) {
-	case *ssa.Const:
-		if v.Value == nil {
-			return ret
-		}
-	}
-	panic("todo")
+	p.unsupported(v.Pos(), "unsupported value %T: %v", v, v)
+	return llssa.Expr{}
 }
 
 func (p *context) compileValues(b llssa.Builder, vals []ssa.Value, hasVArg int) []llssa.Expr {
@@ -281,8 +720,104 @@ func (p *context) compileValues(b llssa.Builder, vals []ssa.Value, hasVArg int)
 
 // -----------------------------------------------------------------------------
 
+// Config represents the configuration of compiling a Go package.
+type Config struct {
+	// MathIntrinsics lowers functions of the math and math/bits packages (eg.
+	// math.Sqrt or bits.OnesCount) to LLVM intrinsics instead of calling their
+	// Go implementations.
+	MathIntrinsics bool
+
+	// DebugInfo specifies how much DWARF debug information is generated.
+	DebugInfo llssa.DebugInfoLevel
+
+	// ContinueOnError compiles functions that fail to compile to stubs that
+	// trap when called, so that the rest of the package is still usable.
+	// NewPackageEx returns the package with a *PartialError in this case.
+	ContinueOnError bool
+
+	// Reachable, if not nil, is the set of functions and global variables to
+	// be compiled: the other ones are eliminated as dead code.
+	Reachable *Reachable
+
+	// Preempt inserts a preemption check in the prologue of every function
+	// (see llssa.Builder.PreemptCheck), so that the goroutine scheduler of
+	// the runtime can preempt goroutines that run for too long. Functions of
+	// the runtime itself aren't checked.
+	Preempt bool
+
+	// PreemptLoops also inserts a preemption check on the back edges of loops
+	// (see llssa.Builder.Safepoint), so that a loop that calls no function,
+	// eg. one that spins until a variable is set, can be preempted too, at
+	// the cost of a load per iteration. It has no effect without Preempt.
+	PreemptLoops bool
+
+	// NoMain compiles the main function of a main package as a Go function,
+	// rather than as the C entry point main that initializes the packages,
+	// eg. when the package is built as a C library, whose initialization
+	// calls the init function of the package instead.
+	NoMain bool
+
+	// Reflect emits type descriptors rich enough for the functions of the
+	// reflect package that the runtime implements (see
+	// llssa.Package.SetReflect), which increases the size of binaries.
+	Reflect bool
+
+	// Race reports the loads and stores of Go code to the runtime of
+	// ThreadSanitizer (see llssa.Builder.RaceRead), which detects data races
+	// when it is linked to the program. The runtime annotates the
+	// synchronization of goroutines, and the accesses of its own functions
+	// aren't reported.
+	Race bool
+
+	// Cover measures the statement coverage of the functions of the package,
+	// counting how many times their basic blocks run, for llgo test -cover.
+	// The runtime writes the counters in a coverage profile of the Go
+	// toolchain (see runtime.CoverReport), whose blocks approximate the ones
+	// of cmd/cover (see cover.go).
+	Cover bool
+
+	// Traceback adds the functions of the package to a table that the runtime
+	// uses to print the names and positions of the functions of the stack
+	// traces of fatal errors, eg. segmentation faults (see
+	// runtime.FuncTabRegister).
+	Traceback bool
+
+	// Visibility is the visibility of the symbols of the functions and the
+	// variables that the package defines, unless the pragma
+	// //llgo:visibility of a function overrides it. Hidden symbols aren't
+	// exported by the shared library that the package is linked in, which
+	// then only exports the functions that are exported to C (see CExportOf)
+	// and the ones whose visibility is default.
+	Visibility llssa.Visibility
+
+	// TrimPath rewrites the names of the source files in the debug
+	// information and the tables of tracebacks of the package to the import
+	// path of the package joined with their base name, eg. fmt/print.go, as
+	// go build -trimpath does, so that they don't depend on the directory
+	// where the package is built.
+	TrimPath bool
+
+	// Verify checks the LLVM IR of every function that is compiled by the LLVM
+	// verifier (see llssa.Function.Verify), and reports a function whose IR is
+	// invalid as an error at its source position, rather than letting LLVM
+	// abort later. It is slow, and meant to debug the compiler.
+	Verify bool
+}
+
 // NewPackage compiles a Go package to LLVM IR package.
 func NewPackage(prog llssa.Program, pkg *ssa.Package, files []*ast.File) (ret llssa.Package, err error) {
+	return NewPackageEx(prog, pkg, files, nil)
+}
+
+// NewPackageEx compiles a Go package to LLVM IR package with the specified
+// configuration. A nil conf means the default configuration.
+//
+// If some functions of the package can't be compiled, err is an ErrorList
+// describing the offending Go source positions.
+func NewPackageEx(prog llssa.Program, pkg *ssa.Package, files []*ast.File, conf *Config) (ret llssa.Package, err error) {
+	if conf == nil {
+		conf = new(Config)
+	}
 	type namedMember struct {
 		name string
 		val  ssa.Member
@@ -290,7 +825,9 @@ func NewPackage(prog llssa.Program, pkg *ssa.Package, files []*ast.File) (ret ll
 
 	// Sort by position, so that the order of the functions in the IR matches
 	// the order of functions in the source file. This is useful for testing,
-	// for example.
+	// for example. Members at the same position, eg. the synthetic ones,
+	// which have none, are sorted by name, so that the IR doesn't depend on
+	// the order of the map of members.
 	var members []*namedMember
 	for name, v := range pkg.Members {
 		members = append(members, &namedMember{name, v})
@@ -298,24 +835,43 @@ func NewPackage(prog llssa.Program, pkg *ssa.Package, files []*ast.File) (ret ll
 	sort.Slice(members, func(i, j int) bool {
 		iPos := members[i].val.Pos()
 		jPos := members[j].val.Pos()
-		return iPos < jPos
+		if iPos != jPos {
+			return iPos < jPos
+		}
+		return members[i].name < members[j].name
 	})
 
 	pkgTypes := pkg.Pkg
 	ret = prog.NewPackage(pkgTypes.Name(), pkgTypes.Path())
 
 	ctx := &context{
+		conf:   conf,
 		prog:   prog,
 		pkg:    ret,
 		fset:   pkg.Prog.Fset,
 		goTyps: pkgTypes,
 		goPkg:  pkg,
 		link:   make(map[string]string),
+		cfns:   make(map[string]none),
+		pyfns:  make(map[string]pyFunc),
+		wasmIn: make(map[string]wasmImport),
+		wasmEx: make(map[string]string),
+		prags:  make(map[ast.Node]funcPragmas),
+		tls:    make(map[string]none),
 		loaded: make(map[*types.Package]none),
 	}
+	ret.SetReflect(conf.Reflect)
+	ret.SetMethodFunc(ctx.methodFunc)
+	if len(files) > 0 {
+		ret.InitDebugInfo(conf.DebugInfo, ctx.position(files[0].Pos()).Filename)
+	}
 	ctx.initFiles(pkgTypes.Path(), files)
+	ctx.fastcc = ctx.fastFuncs(pkg)
 	for _, m := range members {
 		member := m.val
+		if conf.Reachable != nil && !conf.Reachable.Has(member) {
+			continue
+		}
 		switch member := member.(type) {
 		case *ssa.Function:
 			if member.TypeParams() != nil {
@@ -329,8 +885,23 @@ func NewPackage(prog llssa.Program, pkg *ssa.Package, files []*ast.File) (ret ll
 			ctx.compileGlobal(ret, member)
 		}
 	}
-	for _, ini := range ctx.inits {
-		ini()
+	for i := 0; i < len(ctx.inits); i++ { // compiling a function may add wrappers
+		ctx.inits[i]()
+	}
+	ctx.compileKernelStubs()
+	if ctx.isCoveredPkg() {
+		ctx.compileCoverInit()
+	}
+	if ctx.isTraced() {
+		ctx.compileFuncTabInit()
+	}
+	ret.Prune()
+	ret.FinishDebugInfo()
+	ctx.errs.Sort()
+	if len(ctx.failed) > 0 {
+		err = &PartialError{Errs: ctx.errs, Failed: ctx.failed, Total: ctx.nfunc}
+	} else {
+		err = ctx.errs.Err()
 	}
 	return
 }
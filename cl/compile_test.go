@@ -18,12 +18,17 @@ package cl
 
 import (
 	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -76,10 +81,33 @@ func testFrom(t *testing.T, pkgDir, sel string) {
 	if err != nil {
 		t.Fatal("ReadFile failed:", err)
 	}
-	testCompileEx(t, nil, in, string(expected))
+	testCompileEx(t, nil, nil, in, string(expected))
 }
 
-func testCompileEx(t *testing.T, src any, fname, expected string) {
+func testCompileEx(t *testing.T, conf *Config, src any, fname, expected string) {
+	t.Helper()
+	ret := compileWith(t, conf, src, fname)
+	if v := ret.String(); v != expected {
+		t.Fatalf("\n==> got:\n%s\n==> expected:\n%s\n", v, expected)
+	}
+}
+
+func compileWith(t *testing.T, conf *Config, src any, fname string) llssa.Package {
+	t.Helper()
+	ret, err := compileEx(t, conf, src, fname)
+	if err != nil {
+		t.Fatal("cl.NewPackage failed:", err)
+	}
+	return ret
+}
+
+func compileEx(t *testing.T, conf *Config, src any, fname string) (llssa.Package, error) {
+	t.Helper()
+	prog, foo, files := buildSSA(t, src, fname)
+	return NewPackageEx(prog, foo, files, conf)
+}
+
+func buildSSA(t *testing.T, src any, fname string) (llssa.Program, *ssa.Package, []*ast.File) {
 	t.Helper()
 	fset := token.NewFileSet()
 	f, err := parser.ParseFile(fset, fname, src, parser.ParseComments)
@@ -90,25 +118,19 @@ func testCompileEx(t *testing.T, src any, fname, expected string) {
 	name := f.Name.Name
 	pkg := types.NewPackage(name, name)
 	imp := packages.NewImporter(fset)
+	prog := llssa.NewProgram(nil)
 	foo, _, err := ssautil.BuildPackage(
-		&types.Config{Importer: imp}, fset, pkg, files, ssa.SanityCheckFunctions)
+		&types.Config{Importer: imp, Sizes: prog.TypeSizes()}, fset, pkg, files, ssa.SanityCheckFunctions)
 	if err != nil {
 		t.Fatal("BuildPackage failed:", err)
 	}
 	foo.WriteTo(os.Stderr)
-	prog := llssa.NewProgram(nil)
-	ret, err := NewPackage(prog, foo, files)
-	if err != nil {
-		t.Fatal("cl.NewPackage failed:", err)
-	}
-	if v := ret.String(); v != expected {
-		t.Fatalf("\n==> got:\n%s\n==> expected:\n%s\n", v, expected)
-	}
+	return prog, foo, files
 }
 
 func testCompile(t *testing.T, src, expected string) {
 	t.Helper()
-	testCompileEx(t, src, "foo.go", expected)
+	testCompileEx(t, nil, src, "foo.go", expected)
 }
 
 func TestVar(t *testing.T) {
@@ -166,3 +188,1469 @@ _llgo_0:
 }
 `)
 }
+
+func TestMathIntrinsics(t *testing.T) {
+	testCompileEx(t, &Config{MathIntrinsics: true}, `package foo
+
+import "math"
+
+func hypot(x, y float64) float64 {
+	return math.Sqrt(math.FMA(x, x, y*y))
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  call void @math.init()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define double @foo.hypot(double %0, double %1) {
+_llgo_0:
+  %2 = fmul double %1, %1
+  %3 = call double @llvm.fma.f64(double %0, double %0, double %2)
+  %4 = call double @llvm.sqrt.f64(double %3)
+  ret double %4
+}
+
+declare void @math.init()
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare double @llvm.fma.f64(double, double, double) #0
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare double @llvm.sqrt.f64(double) #0
+
+attributes #0 = { nofree nosync nounwind readnone speculatable willreturn }
+`)
+	testCompileEx(t, &Config{MathIntrinsics: true}, `package foo
+
+import "math/bits"
+
+func log2(x uint32) int {
+	return bits.Len32(x) - 1
+}
+
+func count(x uint64) int {
+	return bits.OnesCount64(x) + bits.TrailingZeros64(x)
+}
+
+func swap(x uint16, k int) uint16 {
+	return bits.RotateLeft16(bits.ReverseBytes16(x), k)
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  call void @"math/bits.init"()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.log2(i32 %0) {
+_llgo_0:
+  %1 = call i32 @llvm.ctlz.i32(i32 %0, i1 false)
+  %2 = sub i32 32, %1
+  %3 = zext i32 %2 to i64
+  %4 = sub i64 %3, 1
+  ret i64 %4
+}
+
+define i64 @foo.count(i64 %0) {
+_llgo_0:
+  %1 = call i64 @llvm.ctpop.i64(i64 %0)
+  %2 = call i64 @llvm.cttz.i64(i64 %0, i1 false)
+  %3 = add i64 %1, %2
+  ret i64 %3
+}
+
+define i16 @foo.swap(i16 %0, i64 %1) {
+_llgo_0:
+  %2 = call i16 @llvm.bswap.i16(i16 %0)
+  %3 = trunc i64 %1 to i16
+  %4 = call i16 @llvm.fshl.i16(i16 %2, i16 %2, i16 %3)
+  ret i16 %4
+}
+
+declare void @"math/bits.init"()
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare i32 @llvm.ctlz.i32(i32, i1 immarg) #0
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare i64 @llvm.ctpop.i64(i64) #0
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare i64 @llvm.cttz.i64(i64, i1 immarg) #0
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare i16 @llvm.bswap.i16(i16) #0
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare i16 @llvm.fshl.i16(i16, i16, i16) #0
+
+attributes #0 = { nofree nosync nounwind readnone speculatable willreturn }
+`)
+}
+
+func TestPreempt(t *testing.T) {
+	testCompileEx(t, &Config{Preempt: true}, `package foo
+
+func add(a, b int) int {
+	return a + b
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@"github.com/goplus/llgo/internal/runtime.preemptFlag" = external global ptr
+
+define void @foo.init() {
+_llgo_prologue:
+  %0 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %1 = icmp ne i32 %0, 0
+  br i1 %1, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  %2 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %2, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.add(i64 %0, i64 %1) {
+_llgo_prologue:
+  %2 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %3 = icmp ne i32 %2, 0
+  br i1 %3, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  %4 = add i64 %0, %1
+  ret i64 %4
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+`)
+}
+
+func TestPreemptLoops(t *testing.T) {
+	testCompileEx(t, &Config{Preempt: true, PreemptLoops: true}, `package foo
+
+var stop int32
+
+func spin() {
+	for stop == 0 {
+	}
+}
+
+func sum(n int) (s int) {
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	return
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@foo.stop = global ptr null
+@"github.com/goplus/llgo/internal/runtime.preemptFlag" = external global ptr
+
+define void @foo.init() {
+_llgo_prologue:
+  %0 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %1 = icmp ne i32 %0, 0
+  br i1 %1, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  %2 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %2, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @foo.spin() {
+_llgo_prologue:
+  %0 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %1 = icmp ne i32 %0, 0
+  br i1 %1, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  br label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_4
+  ret void
+
+_llgo_2:                                          ; preds = %_llgo_4, %_llgo_0
+  %2 = load i32, ptr @foo.stop, align 4
+  %3 = icmp eq i32 %2, 0
+  %4 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %5 = icmp ne i32 %4, 0
+  br i1 %5, label %_llgo_3, label %_llgo_4
+
+_llgo_3:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_4
+
+_llgo_4:                                          ; preds = %_llgo_3, %_llgo_2
+  br i1 %3, label %_llgo_2, label %_llgo_1
+}
+
+define i64 @foo.sum(i64 %0) {
+_llgo_prologue:
+  %1 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %2 = icmp ne i32 %1, 0
+  br i1 %2, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  br label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_5, %_llgo_0
+  %3 = phi i64 [ 0, %_llgo_0 ], [ %6, %_llgo_5 ]
+  %4 = phi i64 [ 0, %_llgo_0 ], [ %7, %_llgo_5 ]
+  %5 = icmp slt i64 %4, %0
+  br i1 %5, label %_llgo_2, label %_llgo_3
+
+_llgo_2:                                          ; preds = %_llgo_1
+  %6 = add i64 %3, %4
+  %7 = add i64 %4, 1
+  %8 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %9 = icmp ne i32 %8, 0
+  br i1 %9, label %_llgo_4, label %_llgo_5
+
+_llgo_3:                                          ; preds = %_llgo_1
+  ret i64 %3
+
+_llgo_4:                                          ; preds = %_llgo_2
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_5
+
+_llgo_5:                                          ; preds = %_llgo_4, %_llgo_2
+  br label %_llgo_1
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+`)
+}
+
+func TestCallbacks(t *testing.T) {
+	prog, foo, files := buildSSA(t, `package foo
+
+import _ "unsafe"
+
+const LLGoPackage = true
+
+type Handler func(x int32) int32
+
+//go:linkname register register
+func register(h Handler)
+
+func f(x int32) int32 { return x }
+
+func use(n int32) {
+	register(f)
+	register(func(x int32) int32 { return x + n })
+}
+`, "foo.go")
+	pkg, err := NewPackage(prog, foo, files)
+	if !prog.HasTrampolines() {
+		if err == nil || !strings.Contains(err.Error(), "unsupported conversion") {
+			t.Fatal("TestCallbacks: closure converted to a C function pointer without trampolines:", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal("cl.NewPackage failed:", err)
+	}
+	expected := `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+declare void @register(ptr)
+
+define i32 @foo.f(i32 %0) {
+_llgo_0:
+  ret i32 %0
+}
+
+define void @foo.use(i32 %0) {
+_llgo_0:
+  %1 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 4)
+  store i32 %0, ptr %1, align 4
+  call void @"github.com/goplus/llgo/internal/runtime.EnableCallbacks"()
+  call void @register(ptr @"foo.f$callback")
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  %3 = getelementptr inbounds { ptr }, ptr %2, i32 0, i32 0
+  store ptr %1, ptr %3, align 8
+  %4 = insertvalue { ptr, ptr } { ptr @"foo.use$1$stub", ptr undef }, ptr %2, 1
+  %5 = extractvalue { ptr, ptr } %4, 0
+  %6 = extractvalue { ptr, ptr } %4, 1
+  %7 = call ptr @"github.com/goplus/llgo/internal/runtime.NewCallback"(ptr %5, ptr %6, ptr @"__llgo_callback.func(int32) (int32)")
+  tail call void @register(ptr %7)
+  ret void
+}
+
+define i32 @"foo.use$1"(ptr %0, i32 %1) {
+_llgo_0:
+  %2 = load i32, ptr %0, align 4
+  %3 = add i32 %1, %2
+  ret i32 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+declare void @"github.com/goplus/llgo/internal/runtime.EnableCallbacks"()
+
+define private i32 @"foo.f$callback"(i32 %0) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.EnterCallback"()
+  %1 = call i32 @foo.f(i32 %0)
+  ret i32 %1
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.EnterCallback"()
+
+define private i32 @"foo.use$1$stub"(ptr %0, i32 %1) {
+_llgo_0:
+  %2 = getelementptr inbounds { ptr }, ptr %0, i32 0, i32 0
+  %3 = load ptr, ptr %2, align 8
+  %4 = tail call i32 @"foo.use$1"(ptr %3, i32 %1)
+  ret i32 %4
+}
+
+define linkonce_odr i32 @"__llgo_callback.func(int32) (int32)"(ptr nest %0, i32 %1) {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.EnterCallback"()
+  %2 = load { ptr, ptr }, ptr %0, align 8
+  %3 = extractvalue { ptr, ptr } %2, 1
+  %4 = extractvalue { ptr, ptr } %2, 0
+  %5 = call i32 %4(ptr %3, i32 %1)
+  ret i32 %5
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.NewCallback"(ptr, ptr, ptr)
+`
+	if v := pkg.String(); v != expected {
+		t.Fatalf("\n==> got:\n%s\n==> expected:\n%s\n", v, expected)
+	}
+}
+
+func TestNoMain(t *testing.T) {
+	testCompileEx(t, &Config{NoMain: true}, `package main
+
+//export Add
+func add(a, b int32) int32 {
+	return a + b
+}
+
+func main() {
+}
+`, "foo.go", `; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i32 @main.add(i32 %0, i32 %1) {
+_llgo_0:
+  %2 = add i32 %0, %1
+  ret i32 %2
+}
+
+define void @main.main() {
+_llgo_0:
+  ret void
+}
+`)
+}
+
+func TestRace(t *testing.T) {
+	testCompileEx(t, &Config{Race: true}, `package foo
+
+var n int
+var a [3]int
+
+func incr() {
+	n++
+}
+
+func get() [3]int {
+	return a
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@foo.n = global ptr null
+@foo.a = global [3 x i64] zeroinitializer
+
+define void @foo.init() {
+_llgo_0:
+  call void @__tsan_read1(ptr @"foo.init$guard")
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @__tsan_write1(ptr @"foo.init$guard")
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @foo.incr() {
+_llgo_0:
+  call void @__tsan_read8(ptr @foo.n)
+  %0 = load i64, ptr @foo.n, align 4
+  %1 = add i64 %0, 1
+  call void @__tsan_write8(ptr @foo.n)
+  store i64 %1, ptr @foo.n, align 4
+  ret void
+}
+
+define [3 x i64] @foo.get() {
+_llgo_0:
+  call void @__tsan_read_range(ptr @foo.a, i64 24)
+  %0 = load [3 x i64], ptr @foo.a, align 4
+  ret [3 x i64] %0
+}
+
+declare void @__tsan_read1(ptr)
+
+declare void @__tsan_write1(ptr)
+
+declare void @__tsan_read8(ptr)
+
+declare void @__tsan_write8(ptr)
+
+declare void @__tsan_read_range(ptr, i64)
+`)
+}
+
+func TestCover(t *testing.T) {
+	testCompileEx(t, &Config{Cover: true}, `package foo
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@"foo.abs$cover" = global [3 x i32] zeroinitializer
+@0 = private unnamed_addr constant [64 x i8] c"foo/foo.go:4.7,4.8 1\0Afoo/foo.go:5.3,5.11 1\0Afoo/foo.go:7.2,7.3 1\0A"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"foo.init$cover"()
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.abs(i64 %0) {
+_llgo_0:
+  %1 = load i32, ptr @"foo.abs$cover", align 4
+  %2 = add i32 %1, 1
+  store i32 %2, ptr @"foo.abs$cover", align 4
+  %3 = icmp slt i64 %0, 0
+  br i1 %3, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  %4 = load i32, ptr getelementptr inbounds (i32, ptr @"foo.abs$cover", i64 1), align 4
+  %5 = add i32 %4, 1
+  store i32 %5, ptr getelementptr inbounds (i32, ptr @"foo.abs$cover", i64 1), align 4
+  %6 = sub i64 0, %0
+  ret i64 %6
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %7 = load i32, ptr getelementptr inbounds (i32, ptr @"foo.abs$cover", i64 2), align 4
+  %8 = add i32 %7, 1
+  store i32 %8, ptr getelementptr inbounds (i32, ptr @"foo.abs$cover", i64 2), align 4
+  ret i64 %0
+}
+
+define void @"foo.init$cover"() {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.CoverRegister"(ptr @"foo.abs$cover", i64 3, { ptr, i64 } { ptr @0, i64 64 })
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.CoverRegister"(ptr, i64, { ptr, i64 })
+`)
+}
+
+func TestTraceback(t *testing.T) {
+	testCompileEx(t, &Config{Traceback: true}, `package foo
+
+func f() {}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@0 = private unnamed_addr constant [8 x i8] c"foo.init"
+@1 = private unnamed_addr constant [15 x i8] c"<autogenerated>"
+@2 = private unnamed_addr constant [5 x i8] c"foo.f"
+@3 = private unnamed_addr constant [6 x i8] c"foo.go"
+@4 = private constant [3 x { ptr, { ptr, i64 }, { ptr, i64 }, i64 }] [{ ptr, { ptr, i64 }, { ptr, i64 }, i64 } { ptr @foo.init, { ptr, i64 } { ptr @0, i64 8 }, { ptr, i64 } { ptr @1, i64 15 }, i64 1 }, { ptr, { ptr, i64 }, { ptr, i64 }, i64 } { ptr @foo.f, { ptr, i64 } { ptr @2, i64 5 }, { ptr, i64 } { ptr @3, i64 6 }, i64 3 }, { ptr, { ptr, i64 }, { ptr, i64 }, i64 } { ptr @5, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, i64 0 }]
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"foo.init$functab"()
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @foo.f() {
+_llgo_0:
+  ret void
+}
+
+define void @"foo.init$functab"() {
+_llgo_0:
+  call void @"github.com/goplus/llgo/internal/runtime.FuncTabRegister"(ptr @4, i64 3)
+  ret void
+}
+
+define private void @5() {
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.FuncTabRegister"(ptr, i64)
+`)
+}
+
+func TestPragmas(t *testing.T) {
+	testCompileEx(t, &Config{Preempt: true}, `//llgo:section .text.boot
+
+package foo
+
+//go:noinline
+//llgo:align 16
+func f() {}
+
+//go:nosplit
+//llgo:section .text.isr
+func g() {}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@"github.com/goplus/llgo/internal/runtime.preemptFlag" = external global ptr
+
+define void @foo.init() {
+_llgo_prologue:
+  %0 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %1 = icmp ne i32 %0, 0
+  br i1 %1, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  %2 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %2, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+; Function Attrs: noinline
+define void @foo.f() #0 section ".text.boot" align 16 {
+_llgo_prologue:
+  %0 = load atomic i32, ptr @"github.com/goplus/llgo/internal/runtime.preemptFlag" seq_cst, align 4
+  %1 = icmp ne i32 %0, 0
+  br i1 %1, label %_llgo_preempt, label %_llgo_0
+
+_llgo_preempt:                                    ; preds = %_llgo_prologue
+  call void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+  br label %_llgo_0
+
+_llgo_0:                                          ; preds = %_llgo_preempt, %_llgo_prologue
+  ret void
+}
+
+define void @foo.g() section ".text.isr" {
+_llgo_0:
+  ret void
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.Preempt"()
+
+attributes #0 = { noinline }
+`)
+}
+
+func TestVisibility(t *testing.T) {
+	testCompileEx(t, &Config{Visibility: llssa.VisibilityHidden}, `package foo
+
+var a int
+
+//llgo:weak
+func f() {}
+
+//llgo:visibility default
+func g() {}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = hidden global ptr null
+@foo.a = hidden global ptr null
+
+define hidden void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define weak hidden void @foo.f() {
+_llgo_0:
+  ret void
+}
+
+define void @foo.g() {
+_llgo_0:
+  ret void
+}
+`)
+}
+
+func TestDebugInfo(t *testing.T) {
+	testCompileEx(t, &Config{DebugInfo: llssa.DebugInfoFull}, `package foo
+
+type node struct {
+	next *node
+	val  int
+}
+
+func add(a int, n *node) int {
+	var arr [2]int
+	arr[0] = a
+	return arr[0] + arr[1]
+}
+
+func use(c chan int, m map[string]int, e error, f func(int) bool) {
+}
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define i64 @foo.add(i64 %0, ptr %1) !dbg !4 {
+_llgo_0:
+  %2 = alloca [2 x i64], align 8, !dbg !16
+  call void @llvm.dbg.value(metadata i64 %0, metadata !14, metadata !DIExpression()), !dbg !16
+  call void @llvm.dbg.value(metadata ptr %1, metadata !15, metadata !DIExpression()), !dbg !17
+  store [2 x i64] zeroinitializer, ptr %2, align 4, !dbg !18
+  call void @llvm.dbg.declare(metadata ptr %2, metadata !19, metadata !DIExpression()), !dbg !18
+  %3 = getelementptr inbounds i64, ptr %2, i64 0, !dbg !23
+  store i64 %0, ptr %3, align 4, !dbg !23
+  %4 = getelementptr inbounds i64, ptr %2, i64 0, !dbg !24
+  %5 = load i64, ptr %4, align 4, !dbg !24
+  %6 = getelementptr inbounds i64, ptr %2, i64 1, !dbg !25
+  %7 = load i64, ptr %6, align 4, !dbg !25
+  %8 = add i64 %5, %7, !dbg !26
+  ret i64 %8, !dbg !27
+}
+
+define void @foo.use(ptr %0, ptr %1, { ptr, ptr } %2, { ptr, ptr } %3) !dbg !28 {
+_llgo_0:
+  call void @llvm.dbg.value(metadata ptr %0, metadata !49, metadata !DIExpression()), !dbg !53
+  call void @llvm.dbg.value(metadata ptr %1, metadata !50, metadata !DIExpression()), !dbg !54
+  call void @llvm.dbg.value(metadata { ptr, ptr } %2, metadata !51, metadata !DIExpression()), !dbg !55
+  call void @llvm.dbg.value(metadata { ptr, ptr } %3, metadata !52, metadata !DIExpression()), !dbg !56
+  ret void, !dbg !57
+}
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare void @llvm.dbg.value(metadata, metadata, metadata) #0
+
+; Function Attrs: nofree nosync nounwind readnone speculatable willreturn
+declare void @llvm.dbg.declare(metadata, metadata, metadata) #0
+
+attributes #0 = { nofree nosync nounwind readnone speculatable willreturn }
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!2, !3}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_Go, file: !1, producer: "llgo", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug)
+!1 = !DIFile(filename: "foo.go", directory: "")
+!2 = !{i32 2, !"Debug Info Version", i32 3}
+!3 = !{i32 2, !"Dwarf Version", i32 4}
+!4 = distinct !DISubprogram(name: "foo.add", linkageName: "foo.add", scope: !1, file: !1, line: 8, type: !5, scopeLine: 8, flags: DIFlagPrototyped, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !13)
+!5 = !DISubroutineType(types: !6)
+!6 = !{null, !7, !8}
+!7 = !DIBasicType(name: "int", size: 64, encoding: DW_ATE_signed)
+!8 = !DIDerivedType(tag: DW_TAG_pointer_type, name: "*foo.node", baseType: !9, size: 64, align: 64, dwarfAddressSpace: 0)
+!9 = !DICompositeType(tag: DW_TAG_structure_type, name: "foo.node", size: 128, align: 64, elements: !10)
+!10 = !{!11, !12}
+!11 = !DIDerivedType(tag: DW_TAG_member, name: "next", baseType: !8, size: 64, align: 64)
+!12 = !DIDerivedType(tag: DW_TAG_member, name: "val", baseType: !7, size: 64, align: 32, offset: 64)
+!13 = !{!14, !15}
+!14 = !DILocalVariable(name: "a", arg: 1, scope: !4, file: !1, line: 8, type: !7)
+!15 = !DILocalVariable(name: "n", arg: 2, scope: !4, file: !1, line: 8, type: !8)
+!16 = !DILocation(line: 8, column: 10, scope: !4)
+!17 = !DILocation(line: 8, column: 17, scope: !4)
+!18 = !DILocation(line: 9, column: 6, scope: !4)
+!19 = !DILocalVariable(name: "arr", scope: !4, file: !1, line: 9, type: !20)
+!20 = !DICompositeType(tag: DW_TAG_array_type, baseType: !7, size: 128, align: 32, elements: !21)
+!21 = !{!22}
+!22 = !DISubrange(count: 2, lowerBound: 0)
+!23 = !DILocation(line: 10, column: 5, scope: !4)
+!24 = !DILocation(line: 11, column: 12, scope: !4)
+!25 = !DILocation(line: 11, column: 21, scope: !4)
+!26 = !DILocation(line: 11, column: 16, scope: !4)
+!27 = !DILocation(line: 11, column: 2, scope: !4)
+!28 = distinct !DISubprogram(name: "foo.use", linkageName: "foo.use", scope: !1, file: !1, line: 14, type: !29, scopeLine: 14, flags: DIFlagPrototyped, spFlags: DISPFlagDefinition, unit: !0, retainedNodes: !48)
+!29 = !DISubroutineType(types: !30)
+!30 = !{null, !31, !34, !36, !41}
+!31 = !DIDerivedType(tag: DW_TAG_pointer_type, name: "chan int", baseType: !32, size: 64, align: 64, dwarfAddressSpace: 0)
+!32 = !DICompositeType(tag: DW_TAG_structure_type, name: "github.com/goplus/llgo/internal/runtime.Chan", flags: DIFlagFwdDecl, elements: !33)
+!33 = !{}
+!34 = !DIDerivedType(tag: DW_TAG_pointer_type, name: "map[string]int", baseType: !35, size: 64, align: 64, dwarfAddressSpace: 0)
+!35 = !DICompositeType(tag: DW_TAG_structure_type, name: "github.com/goplus/llgo/internal/runtime.Map", flags: DIFlagFwdDecl, elements: !33)
+!36 = !DICompositeType(tag: DW_TAG_structure_type, name: "error", size: 128, align: 64, elements: !37)
+!37 = !{!38, !40}
+!38 = !DIDerivedType(tag: DW_TAG_member, name: "typ", baseType: !39, size: 64, align: 64)
+!39 = !DIBasicType(name: "unsafe.Pointer", size: 64, encoding: DW_ATE_address)
+!40 = !DIDerivedType(tag: DW_TAG_member, name: "data", baseType: !39, size: 64, align: 64, offset: 64)
+!41 = !DICompositeType(tag: DW_TAG_structure_type, name: "func(int) bool", size: 128, align: 64, elements: !42)
+!42 = !{!43, !47}
+!43 = !DIDerivedType(tag: DW_TAG_member, name: "fn", baseType: !44, size: 64, align: 64)
+!44 = !DIDerivedType(tag: DW_TAG_pointer_type, baseType: !45, size: 64, align: 64, dwarfAddressSpace: 0)
+!45 = !DISubroutineType(flags: DIFlagPrototyped, types: !46)
+!46 = !{null, !39, !7}
+!47 = !DIDerivedType(tag: DW_TAG_member, name: "ctx", baseType: !39, size: 64, align: 64, offset: 64)
+!48 = !{!49, !50, !51, !52}
+!49 = !DILocalVariable(name: "c", arg: 1, scope: !28, file: !1, line: 14, type: !31)
+!50 = !DILocalVariable(name: "m", arg: 2, scope: !28, file: !1, line: 14, type: !34)
+!51 = !DILocalVariable(name: "e", arg: 3, scope: !28, file: !1, line: 14, type: !36)
+!52 = !DILocalVariable(name: "f", arg: 4, scope: !28, file: !1, line: 14, type: !41)
+!53 = !DILocation(line: 14, column: 10, scope: !28)
+!54 = !DILocation(line: 14, column: 22, scope: !28)
+!55 = !DILocation(line: 14, column: 40, scope: !28)
+!56 = !DILocation(line: 14, column: 49, scope: !28)
+!57 = !DILocation(line: 14, column: 6, scope: !28)
+`)
+}
+
+func TestUnsupported(t *testing.T) {
+	_, err := compileEx(t, nil, `package foo
+
+func f() {}
+
+func fn() {
+	for {
+		go println()
+	}
+}
+
+var g = f
+`, "foo.go")
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 1 {
+		t.Fatal("TestUnsupported: unexpected error -", err)
+	}
+	if e := errs[0]; e.Pos.Line != 7 || !strings.Contains(e.Msg, "go statement") {
+		t.Fatal("TestUnsupported: unexpected error -", e)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	ret, err := compileEx(t, &Config{ContinueOnError: true}, `package foo
+
+func f() {}
+
+func fn() {
+	for {
+		go println()
+	}
+}
+
+var g = f
+
+func main() {
+	fn()
+}
+`, "foo.go")
+	e, ok := err.(*PartialError)
+	if !ok || len(e.Failed) != 1 || e.Failed[0] != "foo.fn" || e.Total != 4 { // with foo.init
+		t.Fatal("TestContinueOnError: unexpected error -", err)
+	}
+	expected := `; ModuleID = 'foo'
+source_filename = "foo"
+
+@"foo.init$guard" = global ptr null
+@foo.g = global { ptr, ptr } zeroinitializer
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  store { ptr, ptr } { ptr @"foo.f$stub", ptr null }, ptr @foo.g, align 8
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @foo.f() {
+_llgo_0:
+  ret void
+}
+
+define void @foo.main() {
+_llgo_0:
+  tail call fastcc void @foo.fn()
+  ret void
+}
+
+define private void @"foo.f$stub"(ptr %0) {
+_llgo_0:
+  tail call void @foo.f()
+  ret void
+}
+
+define fastcc void @foo.fn() {
+_llgo_0:
+  call void @llvm.trap()
+  unreachable
+}
+
+; Function Attrs: cold noreturn nounwind
+declare void @llvm.trap() #0
+
+attributes #0 = { cold noreturn nounwind }
+`
+	if v := ret.String(); v != expected {
+		t.Fatalf("\n==> got:\n%s\n==> expected:\n%s\n", v, expected)
+	}
+}
+
+func TestLinkFlags(t *testing.T) {
+	ret := compileWith(t, nil, `// Package foo binds libfoo.
+//
+//llgo:cflags -I${SRCDIR}/include -DFOO
+//llgo:ldflags -lfoo
+package foo
+
+//llgo:ldflags -lm
+//llgo:pkg-config sqlite3 zlib
+`, "/src/foo/foo.go")
+	if got := strings.Join(ret.CFlags(), " "); got != "-I/src/foo/include -DFOO" {
+		t.Fatal("TestLinkFlags: unexpected cflags -", got)
+	}
+	if got := strings.Join(ret.LDFlags(), " "); got != "-lfoo -lm" {
+		t.Fatal("TestLinkFlags: unexpected ldflags -", got)
+	}
+	if got := strings.Join(ret.PkgConfig(), " "); got != "sqlite3 zlib" {
+		t.Fatal("TestLinkFlags: unexpected pkg-config packages -", got)
+	}
+}
+
+func TestMethodTable(t *testing.T) {
+	testCompileEx(t, &Config{Reflect: true}, `package foo
+
+type T struct{ a, b int }
+
+func (t T) Get(k int) (int, bool) { return t.a + k, true }
+
+func (t *T) Set(v int) { t.a = v }
+
+func use() (any, any) { return T{}, &T{} }
+`, "foo.go", `; ModuleID = 'foo'
+source_filename = "foo"
+
+%T = type { i64, i64 }
+
+@"foo.init$guard" = global ptr null
+@__llgo_type.foo.T = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 25, i32 1733510781, { ptr, i64 } { ptr @10, i64 5 }, { ptr, i64 } { ptr @11, i64 1 }, { ptr, i64 } { ptr @12, i64 3 }, ptr null, ptr null, i64 0, { ptr, i64, i64 } { ptr @3, i64 2, i64 2 }, { ptr, i64, i64 } { ptr @9, i64 1, i64 1 }, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.foo.T$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.foo.T$stub", ptr null } }
+@0 = private unnamed_addr constant [1 x i8] c"a"
+@__llgo_type.int = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 2, i32 -1779859874, { ptr, i64 } { ptr @1, i64 3 }, { ptr, i64 } { ptr @1, i64 3 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null } }
+@1 = private unnamed_addr constant [3 x i8] c"int"
+@2 = private unnamed_addr constant [1 x i8] c"b"
+@3 = private unnamed_addr constant [2 x { { ptr, i64 }, ptr, i64, i1 }] [{ { ptr, i64 }, ptr, i64, i1 } { { ptr, i64 } { ptr @0, i64 1 }, ptr @__llgo_type.int, i64 0, i1 false }, { { ptr, i64 }, ptr, i64, i1 } { { ptr, i64 } { ptr @2, i64 1 }, ptr @__llgo_type.int, i64 8, i1 false }]
+@4 = private unnamed_addr constant [3 x i8] c"Get"
+@"__llgo_type.func(int) (int, bool)" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 19, i32 -1572382547, { ptr, i64 } { ptr @8, i64 23 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } { ptr @5, i64 1, i64 1 }, { ptr, i64, i64 } { ptr @7, i64 2, i64 2 }, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer }
+@5 = private unnamed_addr constant [1 x ptr] [ptr @__llgo_type.int]
+@__llgo_type.bool = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 1, i64 1, i32 -929786563, { ptr, i64 } { ptr @6, i64 4 }, { ptr, i64 } { ptr @6, i64 4 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.bool$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.bool$stub", ptr null } }
+@6 = private unnamed_addr constant [4 x i8] c"bool"
+@7 = private unnamed_addr constant [2 x ptr] [ptr @__llgo_type.int, ptr @__llgo_type.bool]
+@8 = private unnamed_addr constant [23 x i8] c"func(k int) (int, bool)"
+@9 = private unnamed_addr constant [1 x { { ptr, i64 }, ptr, ptr, ptr, ptr }] [{ { ptr, i64 }, ptr, ptr, ptr, ptr } { { ptr, i64 } { ptr @4, i64 3 }, ptr @"__llgo_type.func(int) (int, bool)", ptr @"foo.(*T).Get", ptr @foo.T.Get, ptr @"__llgo_rcall.func(int) (int, bool)" }]
+@10 = private unnamed_addr constant [5 x i8] c"foo.T"
+@11 = private unnamed_addr constant [1 x i8] c"T"
+@12 = private unnamed_addr constant [3 x i8] c"foo"
+@"__llgo_type.*foo.T" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 22, i32 -385119595, { ptr, i64 } { ptr @16, i64 6 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr @__llgo_type.foo.T, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } { ptr @15, i64 2, i64 2 }, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.*foo.T$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.*foo.T$stub", ptr null } }
+@13 = private unnamed_addr constant [3 x i8] c"Set"
+@"__llgo_type.func(int)" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 16, i64 19, i32 -2085466883, { ptr, i64 } { ptr @14, i64 11 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } { ptr @5, i64 1, i64 1 }, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer }
+@14 = private unnamed_addr constant [11 x i8] c"func(v int)"
+@15 = private unnamed_addr constant [2 x { { ptr, i64 }, ptr, ptr, ptr, ptr }] [{ { ptr, i64 }, ptr, ptr, ptr, ptr } { { ptr, i64 } { ptr @4, i64 3 }, ptr @"__llgo_type.func(int) (int, bool)", ptr @"foo.(*T).Get", ptr @"foo.(*T).Get", ptr @"__llgo_rcall.func(int) (int, bool)" }, { { ptr, i64 }, ptr, ptr, ptr, ptr } { { ptr, i64 } { ptr @13, i64 3 }, ptr @"__llgo_type.func(int)", ptr @"foo.(*T).Set", ptr @"foo.(*T).Set", ptr @"__llgo_rcall.func(int)" }]
+@16 = private unnamed_addr constant [6 x i8] c"*foo.T"
+
+define void @foo.init() {
+_llgo_0:
+  %0 = load i1, ptr @"foo.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"foo.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define { i64, i1 } @foo.T.Get(%T %0, i64 %1) {
+_llgo_0:
+  %2 = alloca %T, align 8
+  store %T zeroinitializer, ptr %2, align 4
+  store %T %0, ptr %2, align 4
+  %3 = getelementptr inbounds %T, ptr %2, i32 0, i32 0
+  %4 = load i64, ptr %3, align 4
+  %5 = add i64 %4, %1
+  %mrv = insertvalue { i64, i1 } undef, i64 %5, 0
+  %mrv1 = insertvalue { i64, i1 } %mrv, i1 true, 1
+  ret { i64, i1 } %mrv1
+}
+
+define void @"foo.(*T).Set"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = getelementptr inbounds %T, ptr %0, i32 0, i32 0
+  store i64 %1, ptr %2, align 4
+  ret void
+}
+
+define { { ptr, ptr }, { ptr, ptr } } @foo.use() {
+_llgo_0:
+  %0 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  store %T zeroinitializer, ptr %0, align 4
+  %1 = insertvalue { ptr, ptr } { ptr @__llgo_type.foo.T, ptr undef }, ptr %0, 1
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 16)
+  %3 = insertvalue { ptr, ptr } { ptr @"__llgo_type.*foo.T", ptr undef }, ptr %2, 1
+  %mrv = insertvalue { { ptr, ptr }, { ptr, ptr } } undef, { ptr, ptr } %1, 0
+  %mrv1 = insertvalue { { ptr, ptr }, { ptr, ptr } } %mrv, { ptr, ptr } %3, 1
+  ret { { ptr, ptr }, { ptr, ptr } } %mrv1
+}
+
+define linkonce_odr i64 @__llgo_hash.int(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.int$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.int(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.int(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.int$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.int(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr { i64, i1 } @"foo.(*T).Get"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = icmp eq ptr %0, null
+  br i1 %2, label %_llgo_1, label %_llgo_2
+
+_llgo_1:                                          ; preds = %_llgo_0
+  call void @"github.com/goplus/llgo/internal/runtime.WrapNilFailed"({ ptr, i64 } { ptr @10, i64 5 }, { ptr, i64 } { ptr @4, i64 3 })
+  unreachable
+
+_llgo_2:                                          ; preds = %_llgo_0
+  %3 = load %T, ptr %0, align 4
+  %4 = call { i64, i1 } @foo.T.Get(%T %3, i64 %1)
+  %5 = extractvalue { i64, i1 } %4, 0
+  %6 = extractvalue { i64, i1 } %4, 1
+  %mrv = insertvalue { i64, i1 } undef, i64 %5, 0
+  %mrv1 = insertvalue { i64, i1 } %mrv, i1 %6, 1
+  ret { i64, i1 } %mrv1
+}
+
+define linkonce_odr void @"__llgo_rcall.func(int) (int, bool)"(ptr %0, ptr %1, ptr %2, ptr %3, ptr %4) {
+_llgo_0:
+  %5 = getelementptr inbounds ptr, ptr %3, i64 0
+  %6 = load ptr, ptr %5, align 8
+  %7 = load i64, ptr %6, align 4
+  %8 = call { i64, i1 } %1(ptr %2, i64 %7)
+  %9 = extractvalue { i64, i1 } %8, 0
+  %10 = getelementptr inbounds ptr, ptr %4, i64 0
+  %11 = load ptr, ptr %10, align 8
+  store i64 %9, ptr %11, align 4
+  %12 = extractvalue { i64, i1 } %8, 1
+  %13 = getelementptr inbounds ptr, ptr %4, i64 1
+  %14 = load ptr, ptr %13, align 8
+  store i1 %12, ptr %14, align 1
+  ret void
+}
+
+define linkonce_odr i64 @__llgo_hash.bool(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 1, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.bool$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.bool(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.bool(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 1)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.bool$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.bool(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+define linkonce_odr i64 @__llgo_hash.foo.T(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 16, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.foo.T$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.foo.T(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.foo.T(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 16)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.foo.T$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.foo.T(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+define linkonce_odr void @"__llgo_rcall.func(int)"(ptr %0, ptr %1, ptr %2, ptr %3, ptr %4) {
+_llgo_0:
+  %5 = getelementptr inbounds ptr, ptr %3, i64 0
+  %6 = load ptr, ptr %5, align 8
+  %7 = load i64, ptr %6, align 4
+  call void %1(ptr %2, i64 %7)
+  ret void
+}
+
+define linkonce_odr i64 @"__llgo_hash.*foo.T"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.*foo.T$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @"__llgo_hash.*foo.T"(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.*foo.T"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.*foo.T$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @"__llgo_equal.*foo.T"(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare void @"github.com/goplus/llgo/internal/runtime.WrapNilFailed"({ ptr, i64 }, { ptr, i64 })
+`)
+}
+
+func TestCheckPackage(t *testing.T) {
+	_, foo, _ := buildSSA(t, `package foo
+
+type T int
+
+func (T) M() {}
+
+func f() {}
+
+func fn(s string) {
+	for range s {
+	}
+	for {
+		go println()
+	}
+}
+
+var g = f
+
+func ok(a, b int, x any) int {
+	return a + b + x.(int)
+}
+
+func assert(x any) {
+	_ = x.(interface{ M() })
+}
+`, "foo.go")
+	errs := CheckPackage(foo)
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	want := []string{
+		"foo.go:9:6: foo.fn: unsupported range over string: next t0",
+		"foo.go:10:2: foo.fn: unsupported range over string: range s",
+		"foo.go:13:3: foo.fn: unsupported go statement: go println()",
+		"foo.go:24:8: foo.assert: unsupported type assertion to a non-empty interface: typeassert x.(interface{M()})",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("TestCheckPackage: got\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestReachable(t *testing.T) {
+	prog, foo, files := buildSSA(t, `package main
+
+var g, dead int
+
+func used() int {
+	return g
+}
+
+func unused() int {
+	return dead
+}
+
+func main() {
+	used()
+}
+`, "foo.go")
+	r := NewReachable(foo.Func("main"), foo.Func("init"))
+	ret, err := NewPackageEx(prog, foo, files, &Config{Reachable: r})
+	if err != nil {
+		t.Fatal("NewPackageEx failed:", err)
+	}
+	// main.unused and main.dead are unreachable, so they aren't compiled.
+	expected := `; ModuleID = 'main'
+source_filename = "main"
+
+@"main.init$guard" = global ptr null
+@main.g = global ptr null
+
+define void @main.init() {
+_llgo_0:
+  %0 = load i1, ptr @"main.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"main.init$guard", align 1
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define fastcc i64 @main.used() {
+_llgo_0:
+  %0 = load i64, ptr @main.g, align 4
+  ret i64 %0
+}
+
+define i32 @main(i32 %0, ptr %1, ptr %2) {
+_llgo_0:
+  call void @main.init()
+  %3 = call fastcc i64 @main.used()
+  ret i32 0
+}
+`
+	if v := ret.String(); v != expected {
+		t.Fatalf("\n==> got:\n%s\n==> expected:\n%s\n", v, expected)
+	}
+}
+
+func TestKernel(t *testing.T) {
+	compileWith(t, nil, `package foo
+
+import "github.com/goplus/llgo/gpu"
+
+//llgo:kernel amdgpu
+func scale(a float32, x []float32) {
+	i := int(gpu.BlockIdx(gpu.X)*gpu.BlockDim(gpu.X) + gpu.ThreadIdx(gpu.X))
+	for ; i < len(x); i += int(gpu.GridDim(gpu.X) * gpu.BlockDim(gpu.X)) {
+		x[i] *= a
+	}
+}
+`, "foo.go")
+	_, err := compileEx(t, nil, `package foo
+
+//llgo:kernel
+func hello(s string) {
+}
+`, "foo.go")
+	if err == nil || !strings.Contains(err.Error(), "unsupported type of kernel parameter s") {
+		t.Fatal("TestKernel: unexpected error -", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	_, err := compileEx(t, nil, `package foo
+
+func show(s []int) {
+	println(s)
+}
+`, "foo.go")
+	if err == nil {
+		t.Fatal("TestPrint: println of a slice is compiled")
+	}
+}
+
+func TestPrintfOps(t *testing.T) {
+	n := ssa.NewConst(constant.MakeInt64(1), types.Typ[types.Int])
+	f := ssa.NewConst(constant.MakeFloat64(1), types.Typ[types.Float64])
+	for _, tt := range []struct {
+		format string
+		val    ssa.Value
+		ok     bool
+	}{
+		{"%#b", n, true},
+		{"%.3d", n, true},
+		{"%#v", n, false},
+		{"%#g", f, false},
+		{"%q", n, false},
+	} {
+		if _, ok := fmtPrintfOps(tt.format, []ssa.Value{tt.val}); ok != tt.ok {
+			t.Errorf("TestPrintfOps: %q is lowered: %v, want %v", tt.format, ok, tt.ok)
+		}
+	}
+}
+
+// TestRuntime compiles the runtime with each collector, as the build driver
+// does: all its functions must be supported.
+func TestRuntime(t *testing.T) {
+	for _, gc := range []string{"gc.boehm", "gc.precise", "gc.none"} {
+		t.Run(gc, func(t *testing.T) {
+			ctx := build.Default
+			ctx.BuildTags = []string{gc}
+			ctx.CgoEnabled = false
+			fset := token.NewFileSet()
+			imp := &srcImporter{&ctx, fset, make(map[string]*types.Package), importer.ForCompiler(fset, "source", nil)}
+			files, err := imp.parseDir(llssa.PkgRuntime)
+			if err != nil {
+				t.Fatal("parseDir failed:", err)
+			}
+			prog := llssa.NewProgram(nil)
+			if gc == "gc.precise" {
+				prog.SetGC("statepoint-example")
+			}
+			pkg := types.NewPackage(llssa.PkgRuntime, "runtime")
+			rt, _, err := ssautil.BuildPackage(
+				&types.Config{Importer: imp, Sizes: prog.TypeSizes()}, fset, pkg, files, ssa.SanityCheckFunctions)
+			if err != nil {
+				t.Fatal("BuildPackage failed:", err)
+			}
+			ret, err := NewPackageEx(prog, rt, files, nil)
+			if err != nil {
+				t.Fatal("NewPackageEx failed:", err)
+			}
+			// the loops of the scheduler
+			ir := ret.String()
+			for _, fn := range []string{"procresize", "schedule", "runqput", "runqget", "runqsteal"} {
+				def := regexp.MustCompile(`(?m)^define .* @"` + regexp.QuoteMeta(llssa.PkgRuntime+"."+fn) + `"\(`)
+				if !def.MatchString(ir) {
+					t.Fatalf("TestRuntime: %s not compiled", fn)
+				}
+			}
+		})
+	}
+}
+
+// srcImporter imports the packages of the llgo module from their source, with
+// the build tags of ctx, and the other ones by std.
+type srcImporter struct {
+	ctx  *build.Context
+	fset *token.FileSet
+	pkgs map[string]*types.Package
+	std  types.Importer
+}
+
+func (p *srcImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := p.pkgs[path]; ok {
+		return pkg, nil
+	}
+	if !strings.HasPrefix(path, llgoModule+"/") {
+		return p.std.Import(path)
+	}
+	files, err := p.parseDir(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := &types.Config{Importer: p, IgnoreFuncBodies: true}
+	pkg, err := conf.Check(path, p.fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// parseDir parses the Go files of the package path of the llgo module that
+// ctx selects.
+func (p *srcImporter) parseDir(path string) ([]*ast.File, error) {
+	dir := filepath.Join("..", strings.TrimPrefix(path, llgoModule+"/"))
+	bp, err := p.ctx.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(p.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+const llgoModule = "github.com/goplus/llgo"
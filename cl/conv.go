@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// A conversion of a string to a []byte, or of a []byte to a string, copies
+// the bytes, which are immutable in a string. As gc does, the bytes are
+// shared instead (see llssa.Builder.Convert) when the result can't observe
+// it, ie.
+//
+//   - a []byte converted from a string is only read, eg. ranged over:
+//
+//     for i, c := range []byte(s)
+//
+//   - a string converted from a []byte is only compared, looked up in a map,
+//     or indexed, before anything is written to memory:
+//
+//     string(b) == "foo"
+//     m[string(b)]
+
+// lowerConversions finds the conversions of f that share the bytes of their
+// operand.
+func (p *context) lowerConversions(f *ssa.Function) {
+	p.shares = make(map[*ssa.Convert]none)
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			conv, ok := instr.(*ssa.Convert)
+			if !ok {
+				continue
+			}
+			switch {
+			case isString(conv.X.Type()) && isBytes(conv.Type()):
+				if bytesOnlyRead(conv) {
+					p.shares[conv] = none{}
+				}
+			case isBytes(conv.X.Type()) && isString(conv.Type()):
+				if stringUsedBeforeWrite(conv) {
+					p.shares[conv] = none{}
+				}
+			}
+		}
+	}
+}
+
+// bytesOnlyRead reports whether the elements of the []byte v are only read,
+// and whether v doesn't escape.
+func bytesOnlyRead(v ssa.Value) bool {
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		switch r := ref.(type) {
+		case *ssa.Call:
+			fn, ok := r.Call.Value.(*ssa.Builtin)
+			if !ok {
+				return false
+			}
+			switch fn.Name() {
+			case "len", "cap":
+			case "copy":
+				if r.Call.Args[0] == v {
+					return false
+				}
+			default:
+				return false
+			}
+		case *ssa.IndexAddr:
+			for _, ref := range *r.Referrers() {
+				switch load := ref.(type) {
+				case *ssa.UnOp:
+					if load.Op != token.MUL {
+						return false
+					}
+				case *ssa.DebugRef:
+				default:
+					return false
+				}
+			}
+		case *ssa.Slice:
+			if !bytesOnlyRead(r) {
+				return false
+			}
+		case *ssa.DebugRef:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// stringUsedBeforeWrite reports whether the string v, converted from a
+// []byte, is only compared, looked up in a map, indexed or measured, in its
+// block before anything is written to memory, so that the bytes it shares
+// can't change while it is used.
+func stringUsedBeforeWrite(v *ssa.Convert) bool {
+	for _, ref := range *v.Referrers() {
+		switch r := ref.(type) {
+		case *ssa.BinOp:
+			switch r.Op {
+			case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			default:
+				return false
+			}
+		case *ssa.Lookup:
+		case *ssa.Call:
+			fn, ok := r.Call.Value.(*ssa.Builtin)
+			if !ok || fn.Name() != "len" {
+				return false
+			}
+		case *ssa.DebugRef:
+			continue
+		default:
+			return false
+		}
+		if !noWriteBetween(v, ref) {
+			return false
+		}
+	}
+	return true
+}
+
+// noWriteBetween reports whether the instruction use follows def in its
+// block, and no instruction in between may write to memory.
+func noWriteBetween(def, use ssa.Instruction) bool {
+	block := def.Block()
+	if use.Block() != block {
+		return false
+	}
+	in := false
+	for _, instr := range block.Instrs {
+		switch instr {
+		case def:
+			in = true
+			continue
+		case use:
+			return in
+		}
+		if !in {
+			continue
+		}
+		switch instr := instr.(type) {
+		case *ssa.Store, *ssa.MapUpdate, *ssa.Call, *ssa.Go, *ssa.Defer, *ssa.RunDefers, *ssa.Send, *ssa.Select:
+			return false
+		case *ssa.UnOp:
+			if instr.Op == token.ARROW {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// convertible reports whether the conversion conv is compiled by
+// llssa.Builder.Convert: the one of a string to a []byte or back, or the ones
+// of numbers and pointers, including unsafe.Pointer and uintptr.
+func convertible(conv *ssa.Convert) bool {
+	from, to := conv.X.Type(), conv.Type()
+	if isString(from) && isBytes(to) || isBytes(from) && isString(to) {
+		return true
+	}
+	return isNumOrPtr(from) && isNumOrPtr(to)
+}
+
+// cFuncConv reports whether the conversion conv is the one of a func value
+// to a C function pointer type (see llssa.IsCFuncType), eg. of a callback
+// passed to C, which compileCFunc compiles.
+func cFuncConv(conv *ssa.ChangeType) bool {
+	return llssa.IsCFuncType(conv.Type()) && !llssa.IsCFuncType(conv.X.Type())
+}
+
+// changeable reports whether the conversion conv is compiled: not the one of
+// a C function pointer to a func value, and, if it is the one of a func value
+// to a C function pointer, of a signature that C passes as Go does (see
+// llssa.Program.CDirect), and of a function of a package unless the target
+// has trampolines.
+func (p *context) changeable(conv *ssa.ChangeType) bool {
+	if llssa.IsCFuncType(conv.X.Type()) {
+		return llssa.IsCFuncType(conv.Type())
+	}
+	if !cFuncConv(conv) {
+		return true
+	}
+	if !p.prog.CDirect(conv.Type().Underlying().(*types.Signature)) {
+		return false
+	}
+	_, ok := conv.X.(*ssa.Function)
+	return ok || p.prog.HasTrampolines()
+}
+
+// compileCFunc compiles the conversion v of a func value to a C function
+// pointer (see cFuncConv). C calls a function of a package through a wrapper
+// that enters the runtime first, or directly if it is a function of the
+// runtime, as C calls them on the threads that the runtime creates and in
+// signal handlers, and other func values through trampolines (see
+// llssa.Builder.CFunc and Callback).
+func (p *context) compileCFunc(b llssa.Builder, v *ssa.ChangeType) llssa.Expr {
+	t := p.prog.Type(v.Type())
+	if fn, ok := v.X.(*ssa.Function); ok {
+		raw := strings.HasPrefix(p.goTyps.Path(), llssa.PkgRuntime)
+		return b.CFunc(t, p.funcOf(fn).Expr, raw)
+	}
+	return b.Callback(t, p.compileValue(b, v.X))
+}
+
+// isNumOrPtr reports whether t is a numeric, unsafe.Pointer or pointer type.
+func isNumOrPtr(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Info()&types.IsNumeric != 0 || t.Kind() == types.UnsafePointer
+	case *types.Pointer:
+		return true
+	}
+	return false
+}
+
+// isString reports whether t is a string type.
+func isString(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+// isBytes reports whether t is a slice of bytes.
+func isBytes(t types.Type) bool {
+	s, ok := t.Underlying().(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cl
+
+import (
+	"go/token"
+	"go/types"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// An assignment of a struct or an array that is loaded from memory, eg.
+//
+//	t1 = *t0
+//	*t2 = t1
+//
+// is compiled to a copy from t0 to t2 (see llssa.Builder.Copy), which copies
+// large values by llvm.memcpy, rather than to a load of the value and a store
+// of it. The load isn't compiled.
+
+// lowerCopies finds the stores of f that are compiled to copies, and the
+// loads of their values, which mustn't be compiled.
+func (p *context) lowerCopies(f *ssa.Function) {
+	p.copies = make(map[*ssa.Store]ssa.Value)
+	for _, block := range f.Blocks {
+		for i, instr := range block.Instrs {
+			store, ok := instr.(*ssa.Store)
+			if !ok || i == 0 {
+				continue
+			}
+			// the value is loaded just before it is stored, so that the
+			// memory it is loaded from can't be written in between
+			load, ok := store.Val.(*ssa.UnOp)
+			if !ok || load.Op != token.MUL || block.Instrs[i-1] != load || len(*load.Referrers()) != 1 {
+				continue
+			}
+			switch load.Type().Underlying().(type) {
+			case *types.Struct, *types.Array:
+				p.copies[store] = load.X
+				p.skips[load] = none{}
+			}
+		}
+	}
+}
+
+// compileCopy compiles the store, whose value is loaded from src, to a copy.
+func (p *context) compileCopy(b llssa.Builder, store *ssa.Store, src ssa.Value) {
+	from := p.compileValue(b, src)
+	if p.race(src) {
+		b.RaceRead(from)
+	}
+	to := p.compileValue(b, store.Addr)
+	if p.race(store.Addr) {
+		b.RaceWrite(to)
+	}
+	b.Copy(to, from)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// With Config.Cover, each function of the package counts how many times its
+// basic blocks run in a global array of uint32, named <fn>$cover, which the
+// function init$cover of the package registers with the runtime (see
+// runtime.CoverRegister), along with the description of the blocks in the
+// format of the coverage profiles of the Go toolchain, one line per block:
+//
+//	example.com/foo/foo.go:3.2,5.10 2
+//
+// is a block of foo.go from line 3, column 2, to line 5, column 10, which
+// has two statements. Statements are counted by source lines, and blocks
+// without statements have an empty line. The init function of the package
+// calls init$cover first.
+//
+// The output is an approximation of the one of go test -cover: unlike
+// cmd/cover, which splits the statements of the AST in blocks, the blocks are
+// the basic blocks of go/ssa, whose extent is the one of the positions of
+// their instructions (see coverBlocks). A block of the source may thus be
+// split in several blocks, blocks may overlap, eg. the condition of a for
+// loop and its post statement, and the statements that have no instructions,
+// eg. the declarations of constants, aren't counted. The percentages of
+// covered statements differ a little from the ones of the Go toolchain, but
+// the profiles are valid input of go tool cover.
+
+// coverFunc is the coverage of a function.
+type coverFunc struct {
+	ctrs   llssa.Global // counters of its blocks
+	n      int          // number of its blocks
+	blocks string       // description of its blocks
+}
+
+// isCoveredPkg reports whether the coverage of the package is measured, which
+// the one of the runtime isn't.
+func (p *context) isCoveredPkg() bool {
+	return p.conf.Cover && !strings.HasPrefix(p.goTyps.Path(), llssa.PkgRuntime)
+}
+
+// isCovered reports whether the coverage of function f is measured: it must
+// be declared in the source of the package, but not in a test file.
+func (p *context) isCovered(f *ssa.Function) bool {
+	if !p.isCoveredPkg() || f.Synthetic != "" || !f.Pos().IsValid() {
+		return false
+	}
+	return !strings.HasSuffix(p.fset.Position(f.Pos()).Filename, "_test.go")
+}
+
+// coverCounters creates the counters of the blocks of function f, named name,
+// and returns them.
+func (p *context) coverCounters(f *ssa.Function, name string) llssa.Expr {
+	n := len(f.Blocks)
+	typ := types.NewPointer(types.NewArray(types.Typ[types.Uint32], int64(n)))
+	ctrs := p.pkg.NewZeroVar(name+"$cover", typ)
+	p.cover = append(p.cover, coverFunc{ctrs, n, p.coverBlocks(f)})
+	return ctrs.Expr
+}
+
+// coverBlocks returns the description of the blocks of function f: a block
+// spans from the first to the last position of its instructions in the file
+// of its first one, and has a statement per line of them.
+func (p *context) coverBlocks(f *ssa.Function) string {
+	var b strings.Builder
+	for _, block := range f.Blocks {
+		var start, end token.Position
+		lines := make(map[int]none)
+		for _, instr := range block.Instrs {
+			pos := instr.Pos()
+			if !pos.IsValid() {
+				continue
+			}
+			at := p.fset.Position(pos)
+			if start.IsValid() && at.Filename != start.Filename {
+				continue
+			}
+			if !start.IsValid() || before(at, start) {
+				start = at
+			}
+			if !end.IsValid() || before(end, at) {
+				end = at
+			}
+			lines[at.Line] = none{}
+		}
+		if start.IsValid() {
+			file := path.Join(p.goTyps.Path(), filepath.Base(start.Filename))
+			fmt.Fprintf(&b, "%s:%d.%d,%d.%d %d", file, start.Line, start.Column, end.Line, end.Column+1, len(lines))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// before reports whether position a is before position b of the same file.
+func before(a, b token.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// coverInc increments the counter of block of the function being compiled,
+// whose counters are ctrs.
+func (p *context) coverInc(b llssa.Builder, ctrs llssa.Expr, block *ssa.BasicBlock) {
+	prog := p.prog
+	ptr := b.IndexAddr(ctrs, prog.Val(block.Index))
+	one := prog.IntVal(1, prog.Type(types.Typ[types.Uint32]))
+	b.Store(ptr, b.BinOp(token.ADD, b.Load(ptr), one))
+}
+
+// coverInit returns the function init$cover of the package.
+func (p *context) coverInit() llssa.Function {
+	name := fullName(p.goTyps, "init$cover")
+	if fn := p.pkg.FuncOf(name); fn != nil {
+		return fn
+	}
+	return p.pkg.NewFunc(name, types.NewSignatureType(nil, nil, nil, nil, nil, false))
+}
+
+// compileCoverInit defines the function init$cover of the package, which
+// registers the counters of its functions with the runtime.
+func (p *context) compileCoverInit() {
+	b := p.coverInit().MakeBody(1)
+	params := types.NewTuple(
+		types.NewParam(0, nil, "ctrs", types.NewPointer(types.Typ[types.Uint32])),
+		types.NewParam(0, nil, "n", types.Typ[types.Int]),
+		types.NewParam(0, nil, "blocks", types.Typ[types.String]))
+	sig := types.NewSignatureType(nil, nil, nil, params, nil, false)
+	tyString := p.prog.Type(types.Typ[types.String])
+	for _, f := range p.cover {
+		blocks := b.Const(constant.MakeString(f.blocks), tyString)
+		ctrs := b.IndexAddr(f.ctrs.Expr, p.prog.Val(0))
+		b.RuntimeCall("CoverRegister", sig, ctrs, p.prog.Val(f.n), blocks)
+	}
+	b.Return()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,333 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+	"strconv"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Defer statements are open-coded: a deferred call doesn't allocate a record,
+// but saves the values it evaluates to stack slots of the function and sets a
+// bit of a mask. Before returning, the function tests the bits in reverse
+// order and makes the calls whose bits are set, clearing them first.
+//
+// A function with more than maxOpenDefers defer statements, or with one in a
+// loop, which may be executed any number of times, allocates a record on the
+// heap instead each time a defer statement is executed: it saves the values of
+// the statement, and is pushed to a list of the frame. Before returning, the
+// function pops the records and makes their calls, newest first.
+//
+// On the targets where panics unwind the stack (see llssa.Program.HasUnwinding),
+// the calls of a function with defer statements are invokes, which unwind to
+// a landing pad of the function (see compileLandingPad): it makes the deferred
+// calls whose bits are still set, as the exit paths do, and then returns by
+// the Recover block of go/ssa if one of them recovered the panic, or resumes
+// unwinding. The recovery path is out of line, while the paths that don't
+// panic are the ones without unwinding, so that LLVM inlines the functions
+// with defer statements as other functions. Elsewhere, a panic aborts the
+// program, and the deferred calls are made on the normal exit paths only.
+
+// maxOpenDefers is the maximum number of defer statements of a function, the
+// number of bits of the mask.
+const maxOpenDefers = 8
+
+// deferSites returns the defer statements of f, and whether they can be
+// open-coded: if none is in a loop, and if there are at most maxOpenDefers.
+func deferSites(f *ssa.Function) (ret []*deferSite, open bool) {
+	open = true
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			if v, ok := instr.(*ssa.Defer); ok {
+				if inLoop(block) {
+					open = false
+				}
+				ret = append(ret, &deferSite{instr: v})
+			}
+		}
+	}
+	if len(ret) > maxOpenDefers {
+		open = false
+	}
+	return
+}
+
+type deferSite struct {
+	instr *ssa.Defer
+	vals  []ssa.Value  // values evaluated by the defer statement
+	slots []llssa.Expr // stack slots of vals, if open-coded
+	rec   llssa.Type   // pointer to the records of the statement otherwise, see deferRecord
+}
+
+// openDefers allocates the mask and the stack slots of the defer statements
+// of f, or the head of its list of defer records if they can't be open-coded,
+// in the entry block that b is positioned at, and makes the calls that b
+// emits then unwind to the landing pad of f, if panics unwind the stack.
+func (p *context) openDefers(b llssa.Builder, f *ssa.Function) {
+	var open bool
+	p.defers, open = deferSites(f)
+	p.lpad, p.dhead = nil, llssa.Expr{}
+	if len(p.defers) == 0 {
+		return
+	}
+	prog := p.prog
+	tbits := prog.Pointer(prog.Type(types.Typ[types.Uint8]))
+	if open {
+		p.dbits = b.Alloc(tbits, false)
+	} else {
+		p.dhead = b.Alloc(prog.Pointer(prog.Type(types.Typ[types.UnsafePointer])), false)
+		p.dbits = b.ChangeType(tbits, p.dhead) // the mask is unused, but identifies the frame
+	}
+	if prog.HasUnwinding() {
+		p.lpad = p.fn.MakeBlocks(1)[0]
+		b.SetUnwind(p.lpad)
+	}
+	for _, site := range p.defers {
+		call := &site.instr.Call
+		if dc, ok := p.devirt[call]; ok { // the bindings of a closure are its arguments
+			call = dc
+		}
+		for _, v := range append([]ssa.Value{call.Value}, call.Args...) {
+			if _, ok := v.(instrAndValue); !ok { // constants, functions, globals and parameters can be evaluated again
+				continue
+			}
+			site.vals = append(site.vals, v)
+			if open {
+				site.slots = append(site.slots, b.Alloc(prog.Pointer(prog.Type(v.Type())), false))
+			}
+		}
+		if !open {
+			site.rec = prog.Type(types.NewPointer(deferRecord(site.vals)))
+		}
+	}
+}
+
+// deferRecord returns the type of the records of a defer statement that saves
+// the values vals: the next record of the list, the index of the statement in
+// the defer statements of the function, and vals.
+func deferRecord(vals []ssa.Value) *types.Struct {
+	fields := []*types.Var{
+		types.NewField(token.NoPos, nil, "link", types.Typ[types.UnsafePointer], false),
+		types.NewField(token.NoPos, nil, "site", types.Typ[types.Int], false),
+	}
+	for i, v := range vals {
+		fields = append(fields, types.NewField(token.NoPos, nil, "v"+strconv.Itoa(i), v.Type(), false))
+	}
+	return types.NewStruct(fields, nil)
+}
+
+// deferHeader is the type of the fields that the records of all the defer
+// statements begin with.
+var deferHeader = types.NewPointer(deferRecord(nil))
+
+// inLoop reports whether block is in a cycle of the control flow graph.
+func inLoop(block *ssa.BasicBlock) bool {
+	seen := make(map[*ssa.BasicBlock]bool)
+	todo := append([]*ssa.BasicBlock(nil), block.Succs...)
+	for len(todo) > 0 {
+		blk := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if blk == block {
+			return true
+		}
+		if !seen[blk] {
+			seen[blk] = true
+			todo = append(todo, blk.Succs...)
+		}
+	}
+	return false
+}
+
+func (p *context) deferBit(i int) llssa.Expr {
+	return p.prog.IntVal(1<<i, p.prog.Type(types.Typ[types.Uint8]))
+}
+
+// compileDefer saves the values of the defer statement v and sets its bit, or
+// pushes a record of them to the list of the frame.
+func (p *context) compileDefer(b llssa.Builder, v *ssa.Defer) {
+	for i, site := range p.defers {
+		if site.instr != v {
+			continue
+		}
+		if p.dhead.Type != nil {
+			prog := p.prog
+			rec := b.Alloc(site.rec, true)
+			b.Store(b.FieldAddr(rec, 0), b.Load(p.dhead))
+			b.Store(b.FieldAddr(rec, 1), prog.Val(i))
+			for j, val := range site.vals {
+				b.Store(b.FieldAddr(rec, 2+j), p.compileValue(b, val))
+			}
+			b.Store(p.dhead, b.ChangeType(prog.Type(types.Typ[types.UnsafePointer]), rec))
+			return
+		}
+		for j, val := range site.vals {
+			b.Store(site.slots[j], p.compileValue(b, val))
+		}
+		bits := b.Load(p.dbits)
+		b.Store(p.dbits, b.BinOp(token.OR, bits, p.deferBit(i)))
+		return
+	}
+}
+
+// compileRunDefers makes the deferred calls whose bits are set, in reverse
+// order, with the values saved by their defer statements. A bit is cleared
+// before its call, which the landing pad then doesn't make again if the call
+// panics. It leaves b at a new block where the function continues.
+func (p *context) compileRunDefers(b llssa.Builder) {
+	if p.dhead.Type != nil {
+		p.compileRunRecords(b)
+		return
+	}
+	for i := len(p.defers) - 1; i >= 0; i-- {
+		site := p.defers[i]
+		blks := p.fn.MakeBlocks(2)
+		call, next := blks[0], blks[1]
+		bits := b.Load(p.dbits)
+		set := b.BinOp(token.AND, bits, p.deferBit(i))
+		b.If(b.BinOp(token.NEQ, set, p.prog.IntVal(0, set.Type)), call, next)
+		b.SetBlock(call)
+		b.Store(p.dbits, b.BinOp(token.AND_NOT, bits, p.deferBit(i)))
+		vals := make([]llssa.Expr, len(site.vals))
+		for j := range site.vals {
+			vals[j] = b.Load(site.slots[j])
+		}
+		p.compileDeferCall(b, site, vals)
+		b.Jump(next)
+		b.SetBlock(next)
+	}
+}
+
+// compileRunRecords pops the records of the list of the frame, newest first,
+// and makes their calls, as compileRunDefers does with the bits of the mask:
+//
+//	loop:
+//		rec := *head
+//		if rec == nil { goto next }
+//		*head = rec.link
+//		switch rec.site { case i: ... call of defer statement i ...; goto loop }
+//	next:
+func (p *context) compileRunRecords(b llssa.Builder) {
+	prog := p.prog
+	blks := p.fn.MakeBlocks(3)
+	loop, pop, next := blks[0], blks[1], blks[2]
+	b.Jump(loop)
+	b.SetBlock(loop)
+	rec := b.Load(p.dhead)
+	b.If(b.BinOp(token.EQL, rec, prog.Null(rec.Type)), next, pop)
+	b.SetBlock(pop)
+	hdr := b.ChangeType(prog.Type(deferHeader), rec)
+	b.Store(p.dhead, b.Load(b.FieldAddr(hdr, 0)))
+	idx := b.Load(b.FieldAddr(hdr, 1))
+	for i, site := range p.defers {
+		call := p.fn.MakeBlocks(1)[0]
+		if i < len(p.defers)-1 {
+			other := p.fn.MakeBlocks(1)[0]
+			b.If(b.BinOp(token.EQL, idx, prog.Val(i)), call, other)
+			b.SetBlock(call)
+			p.compileRecordCall(b, site, rec, loop)
+			b.SetBlock(other)
+			continue
+		}
+		b.Jump(call)
+		b.SetBlock(call)
+		p.compileRecordCall(b, site, rec, loop)
+	}
+	b.SetBlock(next)
+}
+
+// compileRecordCall makes the deferred call of site with the values saved by
+// its record rec, and continues at the block loop of compileRunRecords.
+func (p *context) compileRecordCall(b llssa.Builder, site *deferSite, rec llssa.Expr, loop llssa.BasicBlock) {
+	rec = b.ChangeType(site.rec, rec)
+	vals := make([]llssa.Expr, len(site.vals))
+	for j := range site.vals {
+		vals[j] = b.Load(b.FieldAddr(rec, 2+j))
+	}
+	p.compileDeferCall(b, site, vals)
+	b.Jump(loop)
+}
+
+// compileDeferCall makes the deferred call of site, with the values vals
+// saved by its defer statement in place of the ones it evaluated.
+func (p *context) compileDeferCall(b llssa.Builder, site *deferSite, vals []llssa.Expr) {
+	old := make([]llssa.Expr, len(site.vals))
+	for j, val := range site.vals {
+		old[j] = p.bvals[val]
+		p.bvals[val] = vals[j]
+	}
+	p.compileCall(b, &site.instr.Call, site.instr)
+	for j, val := range site.vals {
+		if old[j].Type != nil {
+			p.bvals[val] = old[j]
+		} else { // not compiled yet, eg. at a return that the defer statement doesn't dominate
+			delete(p.bvals, val)
+		}
+	}
+}
+
+// compileLandingPad compiles the landing pad of f, which the calls of f unwind
+// to (see openDefers), if it has one. The runtime tells a panic that unwinds
+// the frame the frame it is in, by the address of the mask, before the
+// deferred calls are made, and whether one of them recovered it after:
+//
+//	exc := landingpad
+//	runtime.PanicDefers(exc, &bits)
+//	... deferred calls, which unwind to the landing pad again ...
+//	if runtime.PanicRecovered(exc) { goto recover }
+//	resume exc
+func (p *context) compileLandingPad(b llssa.Builder, f *ssa.Function) {
+	if p.lpad == nil {
+		return
+	}
+	b.SetBlock(p.lpad)
+	exc := b.LandingPad()
+	b.SetUnwind(nil)
+	b.RuntimeCall("PanicDefers", panicDefersSig, exc, p.dbits)
+	b.SetUnwind(p.lpad)
+	p.compileRunDefers(b)
+	b.SetUnwind(nil)
+	recovered := b.RuntimeCall("PanicRecovered", panicRecoveredSig, exc)
+	resume := p.fn.MakeBlocks(1)[0]
+	b.If(recovered, p.fn.Block(f.Recover.Index), resume)
+	b.SetBlock(resume)
+	b.Resume(exc)
+}
+
+var (
+	// panicDefersSig is the signature of runtime.PanicDefers, which is passed
+	// the exception of a landing pad and the mask of its frame.
+	panicDefersSig = types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(
+			types.NewParam(token.NoPos, nil, "exc", types.Typ[types.UnsafePointer]),
+			types.NewParam(token.NoPos, nil, "frame", types.NewPointer(types.Typ[types.Uint8]))),
+		nil, false)
+
+	// panicRecoveredSig is the signature of runtime.PanicRecovered, which
+	// reports whether the panic of the exception of a landing pad is
+	// recovered.
+	panicRecoveredSig = types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(types.NewParam(token.NoPos, nil, "exc", types.Typ[types.UnsafePointer])),
+		types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.Bool])), false)
+)
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Calls of interface methods are devirtualized when the dynamic type of the
+// interface is known statically, ie. when the interface is made by a single
+// MakeInterface, possibly converted to other interfaces by ChangeInterface:
+//
+//	t1 = make Stringer <- T (t0)
+//	t2 = invoke t1.String()
+//
+// is compiled as the static method call T.String(t0), which is dispatched
+// without the type descriptor of T, and is a runtime call if the method is
+// implemented by the runtime (see rtIntrinsicOf). The MakeInterface and the
+// ChangeInterface instructions aren't compiled if they are only used by
+// devirtualized calls.
+//
+// Likewise, the calls of closures, eg. of method values, which are closures of
+// the bound wrappers of methods (see wrapperOf), are compiled as static calls:
+//
+//	t1 = make closure (*T).Get$bound [t0]
+//	t2 = t1()
+//
+// is compiled as the call (*T).Get$bound(t0), whose receiver is passed as the
+// free variable of the wrapper. A MakeClosure isn't compiled if it is only
+// used by such calls: otherwise, its free variables are copied to the heap,
+// as the context of a func value (see llssa.Builder.MakeClosure).
+
+// devirtualize finds the calls of interface methods of f that can be
+// devirtualized, which it maps to their static calls in p.devirt, and the
+// interface conversions that mustn't be compiled then. It must be called after
+// lowerFmtCalls.
+func (p *context) devirtualize(f *ssa.Function) {
+	p.devirt = make(map[*ssa.CallCommon]*ssa.CallCommon)
+	var convs []instrAndValue
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			switch instr := instr.(type) {
+			case ssa.CallInstruction:
+				if call, ok := devirtCallOf(f.Prog, instr.Common()); ok {
+					p.devirt[instr.Common()] = call
+				} else if call, ok := boundCallOf(instr.Common()); ok {
+					p.devirt[instr.Common()] = call
+				}
+			case *ssa.MakeInterface, *ssa.ChangeInterface, *ssa.MakeClosure:
+				convs = append(convs, instr.(instrAndValue))
+			}
+		}
+	}
+	for _, conv := range convs {
+		if p.devirtOnly(conv) {
+			p.skips[conv] = none{}
+		}
+	}
+}
+
+// devirtCallOf returns the static method call that call is devirtualized to,
+// if call is a call of an interface method whose receiver is made by a single
+// MakeInterface. The static callee may be a wrapper, eg. of a method of T
+// through a *T, or of a promoted method (see wrapperOf).
+func devirtCallOf(prog *ssa.Program, call *ssa.CallCommon) (*ssa.CallCommon, bool) {
+	if !call.IsInvoke() {
+		return nil, false
+	}
+	v := call.Value
+	for {
+		ci, ok := v.(*ssa.ChangeInterface)
+		if !ok {
+			break
+		}
+		v = ci.X
+	}
+	mi, ok := v.(*ssa.MakeInterface)
+	if !ok {
+		return nil, false
+	}
+	sel := prog.MethodSets.MethodSet(mi.X.Type()).Lookup(call.Method.Pkg(), call.Method.Name())
+	if sel == nil {
+		return nil, false
+	}
+	fn := prog.MethodValue(sel)
+	if fn == nil {
+		return nil, false
+	}
+	ret := *call
+	ret.Value, ret.Method = fn, nil
+	ret.Args = append([]ssa.Value{mi.X}, call.Args...)
+	return &ret, true
+}
+
+// boundCallOf returns the static call that call is compiled to, if call is a
+// call of a closure made by a MakeClosure, eg. of a method value: its function
+// is called with its free variables, eg. the receiver of the bound wrapper of
+// the method, as first arguments.
+func boundCallOf(call *ssa.CallCommon) (*ssa.CallCommon, bool) {
+	mc, ok := call.Value.(*ssa.MakeClosure)
+	if !ok || call.IsInvoke() {
+		return nil, false
+	}
+	fn := mc.Fn.(*ssa.Function)
+	ret := *call
+	ret.Value = fn
+	ret.Args = append(append([]ssa.Value(nil), mc.Bindings...), call.Args...)
+	return &ret, true
+}
+
+// devirtOnly reports whether the interface conversion v, or the closure v, is
+// only used as the callee or the receiver of devirtualized calls, directly or
+// converted again.
+func (p *context) devirtOnly(v instrAndValue) bool {
+	refs := v.Referrers()
+	if refs == nil || len(*refs) == 0 {
+		return false
+	}
+	for _, ref := range *refs {
+		if ci, ok := ref.(*ssa.ChangeInterface); ok && p.devirtOnly(ci) {
+			continue
+		}
+		call, ok := ref.(ssa.CallInstruction)
+		if !ok || call.Common().Value != v {
+			return false
+		}
+		if _, ok := p.devirt[call.Common()]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
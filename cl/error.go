@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// An Error describes a Go construct that can't be compiled, and the source
+// position of it.
+type Error = scanner.Error
+
+// ErrorList is a list of *Error, which NewPackage returns if any function of
+// the package fails to compile.
+type ErrorList = scanner.ErrorList
+
+// compileError is raised (by panic) when the compiler meets a construct that
+// it can't compile. It is recovered when compiling the function is aborted.
+type compileError struct {
+	pos token.Pos
+	msg string
+}
+
+func (p *context) unsupported(pos token.Pos, format string, args ...any) {
+	panic(&compileError{pos, fmt.Sprintf(format, args...)})
+}
+
+// recoverFunc recovers a failure of compiling function f and records it as
+// an error. The failures are either raised by unsupported, or string panics
+// of llssa (eg. "todo"). Other panics are bugs of the compiler and are not
+// recovered.
+func (p *context) recoverFunc(f *ssa.Function) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	pos := p.pos
+	var msg string
+	switch e := r.(type) {
+	case *compileError:
+		if e.pos.IsValid() {
+			pos = e.pos
+		}
+		msg = e.msg
+	case string:
+		msg = e
+	default:
+		panic(r)
+	}
+	if !pos.IsValid() {
+		pos = f.Pos()
+	}
+	p.errs.Add(p.fset.Position(pos), fmt.Sprintf("%v: %s", f, msg))
+	if p.conf.ContinueOnError {
+		p.failed = append(p.failed, f.String())
+		if p.fn != nil {
+			p.fn.MakeTrap()
+		}
+	}
+}
+
+// PartialError is returned by NewPackageEx in ContinueOnError mode if some
+// functions of the package fail to compile. The returned package is still
+// valid: the failed functions are compiled to stubs that trap when called.
+type PartialError struct {
+	Errs   ErrorList // why the functions failed
+	Failed []string  // names of the failed functions
+	Total  int       // number of functions of the package
+}
+
+func (e *PartialError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d functions failed to compile:", len(e.Failed), e.Total)
+	for _, err := range e.Errs {
+		b.WriteString("\n\t")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Errs
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// go/ssa marks an Alloc as Heap whenever the address of the variable is
+// taken, which includes many variables whose address never outlives the
+// function call, eg. new(T) used only for its fields, or a variable passed
+// to a sync/atomic intrinsic. Such variables can be allocated on the stack.
+
+// isHeapAlloc reports whether v must be allocated on the heap.
+func isHeapAlloc(v *ssa.Alloc) bool {
+	return v.Heap && addrEscapes(v)
+}
+
+// addrEscapes reports whether the address v may outlive the current call of
+// the function it belongs to. It is conservative: any use it doesn't know
+// about is treated as an escape.
+func addrEscapes(v ssa.Value) bool {
+	refs := v.Referrers()
+	if refs == nil {
+		return true
+	}
+	for _, ref := range *refs {
+		switch r := ref.(type) {
+		case *ssa.UnOp:
+			if r.Op != token.MUL {
+				return true
+			}
+		case *ssa.Store:
+			if r.Val == v {
+				return true
+			}
+		case *ssa.FieldAddr:
+			if addrEscapes(r) {
+				return true
+			}
+		case *ssa.IndexAddr:
+			if r.Index == v || addrEscapes(r) {
+				return true
+			}
+		case *ssa.Call:
+			if !isAtomicAddrArg(&r.Call, v) {
+				return true
+			}
+		case *ssa.DebugRef:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// isAtomicAddrArg reports whether call is a sync/atomic intrinsic that uses
+// v only as the address it operates on.
+func isAtomicAddrArg(call *ssa.CallCommon, v ssa.Value) bool {
+	fn, ok := call.Value.(*ssa.Function)
+	if !ok {
+		return false
+	}
+	if _, ok = atomicIntrinsicOf(fn); !ok {
+		return false
+	}
+	for _, arg := range call.Args[1:] {
+		if arg == v {
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// fastFuncs returns the functions of pkg that are compiled with the fast
+// calling convention of LLVM (see llssa.Function.SetFastCC). They must only be
+// called directly by the functions of pkg, which know their convention: they
+// are unexported, aren't used as values, eg. assigned to variables, and
+// aren't called by C, eg. as they are exported to C, linked to another
+// symbol, or weak. The functions that pkg doesn't call keep the C convention,
+// as they gain nothing from the fast one. The functions of the runtime are
+// called by the code that the compiler generates in all packages, so they use
+// the C convention.
+func (p *context) fastFuncs(pkg *ssa.Package) map[*ssa.Function]none {
+	ret := make(map[*ssa.Function]none)
+	if strings.HasPrefix(pkg.Pkg.Path(), llssa.PkgRuntime) {
+		return ret
+	}
+	var funcs []*ssa.Function
+	for _, m := range pkg.Members {
+		if f, ok := m.(*ssa.Function); ok && f.TypeParams() == nil {
+			funcs = append(funcs, f)
+		}
+	}
+	funcs = append(funcs, Methods(pkg)...)
+	for _, f := range funcs {
+		if p.canFastCC(f) {
+			ret[f] = none{}
+		}
+	}
+	called := make(map[*ssa.Function]none)
+	for len(funcs) > 0 {
+		f := funcs[len(funcs)-1]
+		funcs = append(funcs[:len(funcs)-1], f.AnonFuncs...)
+		for _, block := range f.Blocks {
+			for _, instr := range block.Instrs {
+				var callee *ssa.Value
+				if call, ok := instr.(ssa.CallInstruction); ok && !call.Common().IsInvoke() {
+					callee = &call.Common().Value
+					if fn, ok := (*callee).(*ssa.Function); ok {
+						called[fn] = none{}
+					}
+				}
+				for _, op := range instr.Operands(nil) {
+					if fn, ok := (*op).(*ssa.Function); ok && op != callee {
+						delete(ret, fn) // used as a value
+					}
+				}
+			}
+		}
+	}
+	for f := range ret {
+		if _, ok := called[f]; !ok {
+			delete(ret, f)
+		}
+	}
+	return ret
+}
+
+// canFastCC reports whether the function f, if it isn't used as a value, can
+// be compiled with the fast calling convention (see fastFuncs).
+func (p *context) canFastCC(f *ssa.Function) bool {
+	name := f.Name()
+	if len(f.Blocks) == 0 || token.IsExported(name) || name == "init" || name == "main" || IsExport(f) {
+		return false
+	}
+	if _, ok := p.link[fullName(f.Pkg.Pkg, memberName(f))]; ok {
+		return false
+	}
+	prags := p.prags[f.Syntax()]
+	return !prags.weak && prags.vis == ""
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,344 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/constant"
+	"go/types"
+	"strings"
+	"unicode/utf8"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Calls of fmt.Print, Println and Printf whose arguments have statically
+// known types, and whose results aren't used, are lowered to calls of the
+// lightweight formatter of the runtime (see llssa.Builder.Fmt), so that the
+// reflect based implementation of fmt isn't needed to print values:
+//
+//	t0 = new [2]any (varargs)
+//	t1 = &t0[0:int]
+//	t2 = make any <- string ("n =":string)
+//	*t1 = t2
+//	t3 = &t0[1:int]
+//	t4 = make any <- int (n)
+//	*t3 = t4
+//	t5 = slice t0[:]
+//	t6 = fmt.Println(t5...)
+//
+// The arguments are formatted in place of the call t6, and the instructions
+// that build the variadic arguments, t0 to t5, aren't compiled. The other
+// calls are compiled as usual.
+
+// fmtOp is an operation of a lowered call of fmt: it writes the literal text
+// lit if arg is nil, or else formats arg according to verb.
+type fmtOp struct {
+	lit  string
+	arg  ssa.Value
+	verb llssa.FmtVerb
+}
+
+// fmtKind is the kind of the type of an argument of fmt, which specifies the
+// verbs that are supported for it.
+type fmtKind int
+
+const (
+	fmtUnsupported fmtKind = iota
+	fmtBool
+	fmtInt
+	fmtFloat
+	fmtString
+	fmtPointer
+)
+
+var fmtVerbs = [...]string{
+	fmtBool:    "vt",
+	fmtInt:     "vdboxXc",
+	fmtFloat:   "vgef",
+	fmtString:  "vs",
+	fmtPointer: "vp",
+}
+
+// lowerFmtCalls finds the calls of fmt in f that can be lowered, and the
+// instructions that build their arguments, which mustn't be compiled.
+func (p *context) lowerFmtCalls(f *ssa.Function) {
+	p.fmts = make(map[*ssa.Call][]fmtOp)
+	p.skips = make(map[ssa.Instruction]none)
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			if ops, skips, ok := fmtCallOf(call); ok {
+				p.fmts[call] = ops
+				for _, instr := range skips {
+					p.skips[instr] = none{}
+				}
+			}
+		}
+	}
+}
+
+// compileFmt compiles the lowered call of fmt whose operations are ops.
+func (p *context) compileFmt(b llssa.Builder, ops []fmtOp) {
+	for _, op := range ops {
+		if op.arg == nil {
+			b.FmtString(op.lit)
+		} else {
+			b.Fmt(p.compileValue(b, op.arg), op.verb)
+		}
+	}
+}
+
+// fmtCallOf returns the operations of call if it is a call of fmt that can
+// be lowered, and the instructions that build its variadic arguments.
+func fmtCallOf(call *ssa.Call) (ops []fmtOp, skips []ssa.Instruction, ok bool) {
+	fn, isFn := call.Call.Value.(*ssa.Function)
+	if !isFn || fn.Pkg == nil || fn.Pkg.Pkg.Path() != "fmt" || fn.Signature.Recv() != nil {
+		return
+	}
+	if refs := call.Referrers(); refs != nil && len(*refs) > 0 {
+		return
+	}
+	args := call.Call.Args
+	switch fn.Name() {
+	case "Print", "Println":
+		vals, skips, ok := fmtArgsOf(args[0])
+		if !ok {
+			return nil, nil, false
+		}
+		return fmtPrintOps(vals, fn.Name() == "Println"), skips, true
+	case "Printf":
+		format, isConst := args[0].(*ssa.Const)
+		if !isConst || format.Value == nil || format.Value.Kind() != constant.String {
+			return
+		}
+		vals, skips, ok := fmtArgsOf(args[1])
+		if !ok {
+			return nil, nil, false
+		}
+		ops, ok = fmtPrintfOps(constant.StringVal(format.Value), vals)
+		return ops, skips, ok
+	}
+	return
+}
+
+// fmtArgsOf returns the values of the variadic arguments v of fmt, and the
+// instructions that build them, if v is a slice literal of values converted
+// to interfaces.
+func fmtArgsOf(v ssa.Value) (vals []ssa.Value, skips []ssa.Instruction, ok bool) {
+	if vals, skips, ok = varArgsOf(v); !ok {
+		return
+	}
+	for i, val := range vals {
+		mi, isMI := val.(*ssa.MakeInterface)
+		if !isMI || len(*mi.Referrers()) != 1 || fmtKindOf(mi.X.Type()) == fmtUnsupported {
+			return nil, nil, false
+		}
+		vals[i] = mi.X
+		skips = append(skips, mi)
+	}
+	return vals, skips, true
+}
+
+// varArgsOf returns the values of the variadic arguments v of a call, and the
+// instructions that build them, if v is a slice literal, as the arguments of
+// a call that aren't passed as a slice are.
+func varArgsOf(v ssa.Value) (vals []ssa.Value, skips []ssa.Instruction, ok bool) {
+	if c, isConst := v.(*ssa.Const); isConst && c.Value == nil {
+		return nil, nil, true
+	}
+	slice, isSlice := v.(*ssa.Slice)
+	if !isSlice || slice.Low != nil || slice.High != nil || slice.Max != nil || len(*slice.Referrers()) != 1 {
+		return
+	}
+	alloc, isAlloc := slice.X.(*ssa.Alloc)
+	if !isAlloc {
+		return
+	}
+	arr := alloc.Type().(*types.Pointer).Elem().Underlying().(*types.Array)
+	vals = make([]ssa.Value, arr.Len())
+	skips = []ssa.Instruction{alloc, slice}
+	for _, ref := range *alloc.Referrers() {
+		if ref == slice {
+			continue
+		}
+		addr, isAddr := ref.(*ssa.IndexAddr)
+		if !isAddr || len(*addr.Referrers()) != 1 {
+			return nil, nil, false
+		}
+		idx, isConst := addr.Index.(*ssa.Const)
+		store, isStore := (*addr.Referrers())[0].(*ssa.Store)
+		if !isConst || !isStore || store.Addr != addr {
+			return nil, nil, false
+		}
+		vals[idx.Int64()] = store.Val
+		skips = append(skips, addr, store)
+	}
+	for _, val := range vals {
+		if val == nil {
+			return nil, nil, false
+		}
+	}
+	return vals, skips, true
+}
+
+// fmtKindOf returns the kind of the type t of an argument of fmt. It is
+// fmtUnsupported if t has a method that fmt calls, eg. String, or if fmt
+// doesn't print values of t as a single value, eg. *struct{...}.
+func fmtKindOf(t types.Type) fmtKind {
+	mset := types.NewMethodSet(t)
+	for _, name := range []string{"Error", "String", "Format", "GoString"} {
+		if mset.Lookup(nil, name) != nil {
+			return fmtUnsupported
+		}
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		info := t.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			return fmtBool
+		case info&types.IsInteger != 0:
+			return fmtInt
+		case info&types.IsFloat != 0:
+			return fmtFloat
+		case info&types.IsString != 0:
+			return fmtString
+		case t.Kind() == types.UnsafePointer:
+			return fmtPointer
+		}
+	case *types.Pointer:
+		switch t.Elem().Underlying().(type) {
+		case *types.Struct, *types.Array, *types.Slice, *types.Map:
+			return fmtUnsupported
+		}
+		return fmtPointer
+	}
+	return fmtUnsupported
+}
+
+// fmtPrintOps returns the operations of fmt.Print, or of fmt.Println if ln.
+// Print adds spaces between the arguments when neither is a string, and
+// Println always adds them, and a newline.
+func fmtPrintOps(vals []ssa.Value, ln bool) (ops []fmtOp) {
+	for i, val := range vals {
+		if i > 0 && (ln || fmtKindOf(vals[i-1].Type()) != fmtString && fmtKindOf(val.Type()) != fmtString) {
+			ops = append(ops, fmtOp{lit: " "})
+		}
+		ops = append(ops, fmtOp{arg: val, verb: llssa.FmtValue})
+	}
+	if ln {
+		ops = append(ops, fmtOp{lit: "\n"})
+	}
+	return ops
+}
+
+// fmtPrintfOps returns the operations of fmt.Printf with the format string
+// format. It fails if a verb isn't supported for its argument, or if the
+// verbs and the arguments don't match, which fmt reports in the output.
+func fmtPrintfOps(format string, vals []ssa.Value) (ops []fmtOp, ok bool) {
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			ops = append(ops, fmtOp{lit: lit.String()})
+			lit.Reset()
+		}
+	}
+	argNum := 0
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			lit.WriteByte(format[i])
+			i++
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			lit.WriteByte('%')
+			i++
+			continue
+		}
+		verb := llssa.FmtVerb{Width: -1, Prec: -1}
+	flags:
+		for ; i < len(format); i++ {
+			switch format[i] {
+			case '-':
+				verb.Flags |= llssa.FmtMinus
+			case '+':
+				verb.Flags |= llssa.FmtPlus
+			case '#':
+				verb.Flags |= llssa.FmtSharp
+			case ' ':
+				verb.Flags |= llssa.FmtSpace
+			case '0':
+				verb.Flags |= llssa.FmtZero
+			default:
+				break flags
+			}
+		}
+		if verb.Flags&llssa.FmtMinus != 0 {
+			verb.Flags &^= llssa.FmtZero // - overrides 0
+		}
+		verb.Width, i = fmtNum(format, i)
+		if i < len(format) && format[i] == '.' {
+			if verb.Prec, i = fmtNum(format, i+1); verb.Prec < 0 {
+				verb.Prec = 0
+			}
+		}
+		if i >= len(format) || argNum >= len(vals) {
+			return nil, false
+		}
+		r, size := utf8.DecodeRuneInString(format[i:])
+		i += size
+		val := vals[argNum]
+		argNum++
+		kind := fmtKindOf(val.Type())
+		if !strings.ContainsRune(fmtVerbs[kind], r) || verb.Flags&llssa.FmtSharp != 0 && (r == 'v' || kind == fmtFloat) {
+			return nil, false // the alternate formats of %#v and of floats aren't supported
+		}
+		if r == 'v' {
+			verb.Flags &^= llssa.FmtPlus // the + of %v adds the names of fields, not signs
+		}
+		verb.Verb = r
+		flush()
+		ops = append(ops, fmtOp{arg: val, verb: verb})
+	}
+	if argNum != len(vals) {
+		return nil, false
+	}
+	flush()
+	return ops, true
+}
+
+// fmtNum parses the decimal number at format[i:], and returns it, or -1 if
+// there is none, and the index of the byte that follows it.
+func fmtNum(format string, i int) (num, next int) {
+	num = -1
+	for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+		if num < 0 {
+			num = 0
+		}
+		num = num*10 + int(format[i]-'0')
+	}
+	return num, i
+}
+
+// -----------------------------------------------------------------------------
@@ -18,7 +18,9 @@ package cl
 
 import (
 	"bytes"
+	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"os"
@@ -45,9 +47,11 @@ func contentOf(m contentMap, file string) (lines contentLines, err error) {
 
 func (p *context) importPkg(pkg *types.Package) {
 	scope := pkg.Scope()
-	if scope.Lookup("LLGoPackage") == nil {
+	llgoPkg := scope.Lookup("LLGoPackage")
+	if llgoPkg == nil {
 		return
 	}
+	mod := pyModuleOf(llgoPkg)
 	fset := p.fset
 	names := scope.Names()
 	contents := make(contentMap)
@@ -66,6 +70,11 @@ func (p *context) importPkg(pkg *types.Package) {
 						if i := fp.Line - 2; i < len(lines) {
 							line := string(lines[i])
 							p.initLinkname(pkgPath, line)
+							p.initCLink(pkgPath, name, line)
+							p.initWasmImport(pkgPath, name, line)
+							if mod != "" {
+								p.initPyFunc(pkgPath, name, mod, line)
+							}
 						}
 					}
 				}
@@ -76,6 +85,8 @@ func (p *context) importPkg(pkg *types.Package) {
 
 func (p *context) initFiles(pkgPath string, files []*ast.File) {
 	for _, file := range files {
+		p.initPragmas(file)
+		p.initFlags(file)
 		for _, decl := range file.Decls {
 			if decl, ok := decl.(*ast.FuncDecl); ok {
 				if decl.Recv == nil {
@@ -83,6 +94,12 @@ func (p *context) initFiles(pkgPath string, files []*ast.File) {
 						if n := len(doc.List); n > 0 {
 							line := doc.List[n-1].Text
 							p.initLinkname(pkgPath, line)
+							if decl.Body == nil {
+								p.initCLink(pkgPath, decl.Name.Name, line)
+								p.initWasmImport(pkgPath, decl.Name.Name, line)
+							} else if name, ok := wasmExportOf(line); ok {
+								p.wasmEx[llssa.Mangle(pkgPath, decl.Name.Name)] = name
+							}
 						}
 					}
 				}
@@ -98,27 +115,271 @@ func (p *context) initLinkname(pkgPath, line string) {
 	if strings.HasPrefix(line, linkname) {
 		text := strings.TrimSpace(line[len(linkname):])
 		if idx := strings.IndexByte(text, ' '); idx > 0 {
-			name := pkgPath + "." + text[:idx]
+			name := llssa.Mangle(pkgPath, text[:idx])
 			link := strings.TrimLeft(text[idx+1:], " ")
 			p.link[name] = link
 		}
 	}
 }
 
+// initCLink declares the function name of the package pkgPath, which has no
+// body, as the C function sym if line is the pragma
+//
+//	//llgo:link C.sym
+//
+// C functions are called by the C ABI of the target, which differs from the
+// one of Go functions for structs and arrays (see llssa.Package.NewCFunc).
+func (p *context) initCLink(pkgPath, name, line string) {
+	const (
+		clink = "//llgo:link C."
+	)
+	if strings.HasPrefix(line, clink) {
+		if sym := strings.TrimSpace(line[len(clink):]); sym != "" {
+			name = llssa.Mangle(pkgPath, name)
+			p.link[name] = sym
+			p.cfns[name] = none{}
+		}
+	}
+}
+
+// wasmImport is the function name of the module mod of the host of a
+// WebAssembly module.
+type wasmImport struct {
+	mod  string
+	name string
+}
+
+// initWasmImport declares the function name of the package pkgPath, which
+// has no body, as the function sym of the module mod of the WebAssembly host
+// if line is the pragma
+//
+//	//go:wasmimport mod sym
+//
+// It is a C function, which the host implements (see
+// llssa.Function.SetWasmImport).
+func (p *context) initWasmImport(pkgPath, name, line string) {
+	const (
+		wasmimport = "//go:wasmimport "
+	)
+	if strings.HasPrefix(line, wasmimport) {
+		if fields := strings.Fields(line[len(wasmimport):]); len(fields) == 2 {
+			name = llssa.Mangle(pkgPath, name)
+			p.wasmIn[name] = wasmImport{fields[0], fields[1]}
+			p.cfns[name] = none{}
+		}
+	}
+}
+
+// wasmExportOf returns the name under which a function is exported to the
+// host of a WebAssembly module if line, the last line of its doc, is the
+// pragma
+//
+//	//go:wasmexport name
+func wasmExportOf(line string) (string, bool) {
+	const (
+		wasmexport = "//go:wasmexport "
+	)
+	if strings.HasPrefix(line, wasmexport) {
+		if name := strings.TrimSpace(line[len(wasmexport):]); name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// CExportOf returns the C name of the function decl if it is exported to C,
+// as cgo does, by the pragma
+//
+//	//export name
+//
+// which is the last line of its doc. It is called by C through a wrapper
+// named name, which is generated with the header of a C library.
+func CExportOf(decl *ast.FuncDecl) (string, bool) {
+	const (
+		export = "//export "
+	)
+	if decl.Body != nil && decl.Doc != nil {
+		if line := decl.Doc.List[len(decl.Doc.List)-1].Text; strings.HasPrefix(line, export) {
+			if name := strings.TrimSpace(line[len(export):]); name != "" {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// IsExport reports whether the function fn is exported to C (see CExportOf)
+// or to the host of a WebAssembly module by the pragma //go:wasmexport, so
+// that it is reachable although no Go function calls it (see Reachable).
+func IsExport(fn *ssa.Function) bool {
+	decl, ok := fn.Syntax().(*ast.FuncDecl)
+	if !ok || decl.Body == nil || decl.Doc == nil {
+		return false
+	}
+	if _, ok = CExportOf(decl); !ok {
+		_, ok = wasmExportOf(decl.Doc.List[len(decl.Doc.List)-1].Text)
+	}
+	return ok
+}
+
+// pyFunc is the function name of the Python module mod.
+type pyFunc struct {
+	mod  string
+	name string
+}
+
+// pyModuleOf returns the Python module that a package declares if its
+// LLGoPackage constant llgoPkg is "py.<module>", or "".
+func pyModuleOf(llgoPkg types.Object) string {
+	if c, ok := llgoPkg.(*types.Const); ok && c.Val().Kind() == constant.String {
+		if v := constant.StringVal(c.Val()); strings.HasPrefix(v, "py.") {
+			return v[len("py."):]
+		}
+	}
+	return ""
+}
+
+// initPyFunc declares the function name of the package pkgPath, which
+// declares the Python module mod, as the Python function sym of mod if line
+// is the pragma
+//
+//	//go:linkname name py.sym
+//
+// Its calls are compiled to calls of the CPython C API (see
+// llssa.Builder.PyCall).
+func (p *context) initPyFunc(pkgPath, name, mod, line string) {
+	const (
+		linkname = "//go:linkname "
+	)
+	if strings.HasPrefix(line, linkname) {
+		fields := strings.Fields(line[len(linkname):])
+		if len(fields) == 2 && fields[0] == name && strings.HasPrefix(fields[1], "py.") {
+			p.pyfns[llssa.Mangle(pkgPath, name)] = pyFunc{mod, fields[1][len("py."):]}
+		}
+	}
+}
+
+// fullName returns the symbol of the member name of pkg (see llssa.Mangle).
 func fullName(pkg *types.Package, name string) string {
-	return pkg.Path() + "." + name
+	return llssa.Mangle(pkg.Path(), name)
+}
+
+// memberName returns the name of fn in its package, eg. "T.M" or "(*T).M"
+// for a method. The synthetic wrapper of a method expression or value is
+// named after the type of its receiver, eg. "(*T).M$thunk" for (*T).M, or
+// "T.M$bound" for x.M where x is a T.
+func memberName(fn *ssa.Function) string {
+	name := fn.Name()
+	if t := recvTypeOf(fn); t != nil {
+		format := "%s.%s"
+		if ptr, ok := t.(*types.Pointer); ok {
+			t, format = ptr.Elem(), "(*%s).%s"
+		}
+		if named, ok := t.(*types.Named); ok {
+			name = fmt.Sprintf(format, named.Obj().Name(), name)
+		}
+	}
+	return name
+}
+
+// recvTypeOf returns the type of the receiver of fn if it is a method, or a
+// synthetic wrapper of a method, whose receiver is its first parameter, or
+// its free variable (see funcSig).
+func recvTypeOf(fn *ssa.Function) types.Type {
+	sig := fn.Signature
+	switch {
+	case sig.Recv() != nil:
+		return sig.Recv().Type()
+	case !isWrapper(fn):
+		return nil
+	case len(fn.FreeVars) > 0:
+		return fn.FreeVars[0].Type()
+	case sig.Params().Len() > 0:
+		return sig.Params().At(0).Type()
+	}
+	return nil
+}
+
+// pkgOf returns the package of fn. The synthetic wrappers of methods, which
+// belong to no package, are the ones of the package of their receiver, eg.
+// the package of T for (*T).M$bound, or the package that is compiled for the
+// ones of the methods of unnamed or predeclared types, eg. error.Error$thunk.
+func (p *context) pkgOf(fn *ssa.Function) *types.Package {
+	if fn.Pkg != nil {
+		return fn.Pkg.Pkg
+	}
+	t := recvTypeOf(fn)
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg()
+	}
+	return p.goTyps
 }
 
-func funcName(pkg *types.Package, fn *ssa.Function) string {
-	ret := fullName(pkg, fn.Name())
-	if ret == "main.main" {
-		ret = "main"
+// goFuncName returns the symbol of fn, which is the C entry point main if
+// isCMain(fn).
+func (p *context) goFuncName(pkg *types.Package, fn *ssa.Function) string {
+	if p.isCMain(fn) {
+		return "main"
 	}
-	return ret
+	return fullName(pkg, memberName(fn))
+}
+
+// cMainSig is the signature of the main function of a main package, which is
+// the C entry point: it is passed the arguments and the environment of the
+// process, and returns its exit status.
+var cMainSig = types.NewSignatureType(nil, nil, nil,
+	types.NewTuple(
+		types.NewParam(token.NoPos, nil, "argc", types.Typ[types.Int32]),
+		types.NewParam(token.NoPos, nil, "argv", types.Typ[types.UnsafePointer]),
+		types.NewParam(token.NoPos, nil, "envp", types.Typ[types.UnsafePointer])),
+	types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.Int32])), false)
+
+// argsInitSig is the signature of runtime.ArgsInit, which the C entry point
+// passes its parameters to before it initializes the packages, if the program
+// uses the os package: the runtime implements os.Args and the functions of os
+// that read the environment with them.
+var argsInitSig = types.NewSignatureType(nil, nil, nil, cMainSig.Params(), nil, false)
+
+// usesPkg reports whether pkg imports the package path, directly or not.
+func usesPkg(pkg *types.Package, path string) bool {
+	seen := make(map[*types.Package]bool)
+	var visit func(pkg *types.Package) bool
+	visit = func(pkg *types.Package) bool {
+		if pkg.Path() == path {
+			return true
+		}
+		if seen[pkg] {
+			return false
+		}
+		seen[pkg] = true
+		for _, imp := range pkg.Imports() {
+			if visit(imp) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(pkg)
+}
+
+// isMainFunc reports whether fn is the main function of a main package,
+// whose import path isn't necessarily "main".
+func isMainFunc(pkg *types.Package, fn *ssa.Function) bool {
+	return pkg.Name() == "main" && fn.Name() == "main" && fn.Signature.Recv() == nil
+}
+
+// isCMain reports whether fn is compiled as the C entry point main, which
+// initializes the packages: it is the main function of a main package, unless
+// the package is compiled with Config.NoMain.
+func (p *context) isCMain(fn *ssa.Function) bool {
+	return !p.conf.NoMain && fn.Pkg != nil && isMainFunc(fn.Pkg.Pkg, fn)
 }
 
 func (p *context) funcName(pkg *types.Package, fn *ssa.Function) string {
-	name := funcName(pkg, fn)
+	name := p.goFuncName(pkg, fn)
 	if v, ok := p.link[name]; ok {
 		return v
 	}
@@ -126,19 +387,44 @@ func (p *context) funcName(pkg *types.Package, fn *ssa.Function) string {
 }
 
 func (p *context) funcOf(fn *ssa.Function) llssa.Function {
+	if isWrapper(fn) {
+		return p.wrapperOf(fn)
+	}
 	pkgTypes := p.ensureLoaded(fn.Pkg.Pkg)
 	pkg := p.pkg
 	name := p.funcName(pkgTypes, fn)
 	if ret := pkg.FuncOf(name); ret != nil {
 		return ret
 	}
-	return pkg.NewFunc(name, fn.Signature)
+	return p.newFunc(pkg, p.goFuncName(pkgTypes, fn), name, funcSig(fn))
+}
+
+// newFunc declares the function name of pkg, whose Go name is goName, as a C
+// function if it is one (see initCLink and initWasmImport). It is exported to
+// the WebAssembly host if it is marked so (see wasmExportOf).
+func (p *context) newFunc(pkg llssa.Package, goName, name string, sig *types.Signature) llssa.Function {
+	if imp, ok := p.wasmIn[goName]; ok {
+		fn := pkg.NewCFunc(name, sig)
+		fn.SetWasmImport(imp.mod, imp.name)
+		return fn
+	}
+	if _, ok := p.cfns[goName]; ok {
+		return pkg.NewCFunc(name, sig)
+	}
+	fn := pkg.NewFunc(name, sig)
+	if ex, ok := p.wasmEx[goName]; ok {
+		fn.SetWasmExport(ex)
+	}
+	return fn
 }
 
 func (p *context) varOf(v *ssa.Global) llssa.Global {
 	pkgTypes := p.ensureLoaded(v.Pkg.Pkg)
 	pkg := p.pkg
 	name := fullName(pkgTypes, v.Name())
+	if rtName, ok := rtVars[name]; ok {
+		return pkg.RuntimeVar(rtName, v.Type())
+	}
 	if ret := pkg.VarOf(name); ret != nil {
 		return ret
 	}
@@ -16,28 +16,350 @@
 
 package cl
 
-/*
-// Define unimplemented intrinsic functions.
-//
-// Some functions are either normally implemented in Go assembly (like
-// sync/atomic functions) or intentionally left undefined to be implemented
-// directly in the compiler (like runtime/volatile functions). Either way, look
-// for these and implement them if this is the case.
-func (b *builder) defineIntrinsicFunction() {
-	panic("todo")
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	atomicLoad = iota + 1
+	atomicStore
+	atomicCmpXchg
+	atomicRMW // atomicRMW returns the old value
+	atomicAdd // atomicAdd returns the new value
+)
+
+type atomicIntrinsic struct {
+	kind int
+	op   llssa.AtomicOp
+}
+
+// atomicIntrinsics maps sync/atomic functions to native atomic instructions,
+// so they don't need a Go (assembly) implementation to link against.
+var atomicIntrinsics = make(map[string]atomicIntrinsic)
+
+func init() {
+	for _, t := range []string{"Int32", "Int64", "Uint32", "Uint64", "Uintptr", "Pointer"} {
+		atomicIntrinsics["sync/atomic.Load"+t] = atomicIntrinsic{kind: atomicLoad}
+		atomicIntrinsics["sync/atomic.Store"+t] = atomicIntrinsic{kind: atomicStore}
+		atomicIntrinsics["sync/atomic.CompareAndSwap"+t] = atomicIntrinsic{kind: atomicCmpXchg}
+		atomicIntrinsics["sync/atomic.Swap"+t] = atomicIntrinsic{atomicRMW, llssa.AtomicXchg}
+		if t != "Pointer" {
+			atomicIntrinsics["sync/atomic.Add"+t] = atomicIntrinsic{atomicAdd, llssa.AtomicAdd}
+			atomicIntrinsics["sync/atomic.And"+t] = atomicIntrinsic{atomicRMW, llssa.AtomicAnd}
+			atomicIntrinsics["sync/atomic.Or"+t] = atomicIntrinsic{atomicRMW, llssa.AtomicOr}
+		}
+	}
+}
+
+func atomicIntrinsicOf(fn *ssa.Function) (ret atomicIntrinsic, ok bool) {
+	if fn.Pkg != nil && fn.Signature.Recv() == nil {
+		ret, ok = atomicIntrinsics[fullName(fn.Pkg.Pkg, fn.Name())]
+	}
+	return
+}
+
+func (p *context) compileAtomic(b llssa.Builder, in atomicIntrinsic, args []llssa.Expr) (ret llssa.Expr) {
+	switch in.kind {
+	case atomicLoad:
+		ret = b.AtomicLoad(args[0])
+	case atomicStore:
+		b.AtomicStore(args[0], args[1])
+	case atomicCmpXchg:
+		ret = b.AtomicCmpXchg(args[0], args[1], args[2])
+	case atomicRMW:
+		ret = b.AtomicRMW(in.op, args[0], args[1])
+	case atomicAdd:
+		old := b.AtomicRMW(in.op, args[0], args[1])
+		ret = b.BinOp(token.ADD, old, args[1])
+	default:
+		panic("unreachable")
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+// rtIntrinsics maps functions and methods of the packages that the runtime
+// implements to the runtime functions that implement them, so these packages,
+// which depend on the Go runtime, aren't compiled (see ImplementedByRuntime).
+// Methods of interfaces, eg. "reflect.Type.Kind", are mapped as the ones of
+// other types: the receiver is the interface value.
+// The formatting of floats of strconv, which is compiled, is mapped too, so
+// that it's shared with the formatter of the runtime (see FmtFloat).
+var rtIntrinsics = map[string]string{
+	"sync.(*Mutex).Lock":       "MutexLock",
+	"sync.(*Mutex).TryLock":    "MutexTryLock",
+	"sync.(*Mutex).Unlock":     "MutexUnlock",
+	"sync.(*RWMutex).Lock":     "RWMutexLock",
+	"sync.(*RWMutex).TryLock":  "RWMutexTryLock",
+	"sync.(*RWMutex).Unlock":   "RWMutexUnlock",
+	"sync.(*RWMutex).RLock":    "RWMutexRLock",
+	"sync.(*RWMutex).TryRLock": "RWMutexTryRLock",
+	"sync.(*RWMutex).RUnlock":  "RWMutexRUnlock",
+	"sync.(*WaitGroup).Add":    "WaitGroupAdd",
+	"sync.(*WaitGroup).Done":   "WaitGroupDone",
+	"sync.(*WaitGroup).Wait":   "WaitGroupWait",
+	"sync.(*Once).Do":          "OnceDo",
+
+	"time.Now":            "TimeNow",
+	"time.Since":          "TimeSince",
+	"time.Sleep":          "TimeSleep",
+	"time.NewTimer":       "TimeNewTimer",
+	"time.After":          "TimeAfter",
+	"time.(*Timer).Stop":  "TimerStop",
+	"time.(*Timer).Reset": "TimerReset",
+
+	"os.init":                "OsInit",
+	"os.Open":                "OsOpen",
+	"os.Create":              "OsCreate",
+	"os.OpenFile":            "OsOpenFile",
+	"os.Exit":                "OsExit",
+	"os.Getenv":              "OsGetenv",
+	"os.LookupEnv":           "OsLookupEnv",
+	"os.Environ":             "OsEnviron",
+	"os.(*File).Read":        "FileRead",
+	"os.(*File).Write":       "FileWrite",
+	"os.(*File).WriteString": "FileWriteString",
+	"os.(*File).Close":       "FileClose",
+	"os.Signal.String":       "SignalString",
+	"syscall.Open":           "SyscallOpen",
+	"syscall.Read":           "SyscallRead",
+	"syscall.Write":          "SyscallWrite",
+	"syscall.Close":          "SyscallClose",
+	"syscall.Exit":           "SyscallExit",
+	"syscall.Syscall":        "SyscallSyscall",
+	"syscall.Syscall6":       "SyscallSyscall6",
+	"syscall.Signal.String":  "SyscallSignalString",
+
+	"os/signal.Notify": "SignalNotify",
+	"os/signal.Stop":   "SignalStop",
+	"os/signal.Ignore": "SignalIgnore",
+	"os/signal.Reset":  "SignalReset",
+
+	"net.Dial":                     "NetDial",
+	"net.Listen":                   "NetListen",
+	"net.(*conn).Read":             "NetConnRead",
+	"net.(*conn).Write":            "NetConnWrite",
+	"net.(*conn).Close":            "NetConnClose",
+	"net.(*TCPListener).Accept":    "TCPListenerAccept",
+	"net.(*TCPListener).AcceptTCP": "TCPListenerAcceptTCP",
+	"net.(*TCPListener).Close":     "TCPListenerClose",
+
+	"reflect.TypeOf":          "ReflectTypeOf",
+	"reflect.ValueOf":         "ReflectValueOf",
+	"reflect.Kind.String":     "ReflectKindString",
+	"reflect.Type.Kind":       "ReflectTypeKind",
+	"reflect.Type.Name":       "ReflectTypeName",
+	"reflect.Type.PkgPath":    "ReflectTypePkgPath",
+	"reflect.Type.String":     "ReflectTypeString",
+	"reflect.Type.Size":       "ReflectTypeSize",
+	"reflect.Type.Elem":       "ReflectTypeElem",
+	"reflect.Type.Key":        "ReflectTypeKey",
+	"reflect.Type.Len":        "ReflectTypeLen",
+	"reflect.Type.NumField":   "ReflectTypeNumField",
+	"reflect.Type.Field":      "ReflectTypeField",
+	"reflect.Type.NumMethod":  "ReflectTypeNumMethod",
+	"reflect.Type.Method":     "ReflectTypeMethod",
+	"reflect.Value.Kind":      "ReflectValueKind",
+	"reflect.Value.Type":      "ReflectValueType",
+	"reflect.Value.Bool":      "ReflectValueBool",
+	"reflect.Value.Int":       "ReflectValueInt",
+	"reflect.Value.Uint":      "ReflectValueUint",
+	"reflect.Value.Float":     "ReflectValueFloat",
+	"reflect.Value.String":    "ReflectValueString",
+	"reflect.Value.Len":       "ReflectValueLen",
+	"reflect.Value.Index":     "ReflectValueIndex",
+	"reflect.Value.NumField":  "ReflectValueNumField",
+	"reflect.Value.Field":     "ReflectValueField",
+	"reflect.Value.Elem":      "ReflectValueElem",
+	"reflect.Value.IsNil":     "ReflectValueIsNil",
+	"reflect.Value.IsValid":   "ReflectValueIsValid",
+	"reflect.Value.Interface": "ReflectValueInterface",
+	"reflect.Value.NumMethod": "ReflectValueNumMethod",
+	"reflect.Value.Method":    "ReflectValueMethod",
+	"reflect.Value.Call":      "ReflectValueCall",
+
+	"runtime.SetFinalizer": "SetFinalizer",
+
+	"runtime/pprof.init":             "PprofInit",
+	"runtime/pprof.StartCPUProfile":  "PprofStartCPUProfile",
+	"runtime/pprof.StopCPUProfile":   "PprofStopCPUProfile",
+	"runtime/pprof.WriteHeapProfile": "PprofWriteHeapProfile",
+
+	"runtime/debug.init":         "DebugInit",
+	"runtime/debug.SetGCPercent": "DebugSetGCPercent",
+	"runtime/debug.FreeOSMemory": "DebugFreeOSMemory",
+	"runtime/debug.ReadGCStats":  "DebugReadGCStats",
+
+	"strconv.FormatFloat":   "StrconvFormatFloat",
+	"strconv.AppendFloat":   "StrconvAppendFloat",
+	"strconv.FormatComplex": "StrconvFormatComplex",
 }
 
+// isKeepAlive reports whether fn is runtime.KeepAlive, which is compiled to an
+// optimization barrier rather than to a call (see llssa.Builder.KeepAlive).
+func isKeepAlive(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Pkg.Pkg.Path() == "runtime" && fn.Name() == "KeepAlive"
+}
+
+// rtVars maps variables of the packages that the runtime implements to the
+// runtime variables that implement them.
+var rtVars = map[string]string{
+	"os.Stdin":  "Stdin",
+	"os.Stdout": "Stdout",
+	"os.Stderr": "Stderr",
+	"os.Args":   "Args",
+
+	"os.Interrupt": "Interrupt",
+	"os.Kill":      "Kill",
+}
+
+// ImplementedByRuntime reports whether the package pkgPath is implemented by
+// the llgo runtime: the functions of the package that are used are compiled
+// to calls to the runtime, so the package itself must not be compiled.
+func ImplementedByRuntime(pkgPath string) bool {
+	switch pkgPath {
+	case "sync", "time", "os", "syscall", "net", "reflect", "runtime", "runtime/pprof", "runtime/debug", "os/signal":
+		return true
+	}
+	return false
+}
+
+// rtIntrinsicOf returns the runtime function that implements a function or
+// method of a package that the runtime implements, and its signature, which
+// has the receiver of a method as its first parameter.
+func rtIntrinsicOf(fn *ssa.Function) (name string, sig *types.Signature, ok bool) {
+	if fn.Pkg == nil {
+		return
+	}
+	recv := fn.Signature.Recv()
+	if recv == nil {
+		name, ok = rtIntrinsics[fullName(fn.Pkg.Pkg, fn.Name())]
+		return name, fn.Signature, ok
+	}
+	t, format := recv.Type(), "%s.%s"
+	if ptr, isPtr := t.(*types.Pointer); isPtr {
+		t, format = ptr.Elem(), "(*%s).%s"
+	}
+	named, isNamed := t.(*types.Named)
+	if !isNamed {
+		return
+	}
+	if name, ok = rtIntrinsics[fullName(fn.Pkg.Pkg, fmt.Sprintf(format, named.Obj().Name(), fn.Name()))]; !ok {
+		return
+	}
+	return name, recvAsParam(recv, fn.Signature), true
+}
+
+// rtInvokeIntrinsicOf returns the runtime function that implements a method
+// of an interface of a package that the runtime implements, called by call
+// (which is in invoke mode), and its signature, which has the interface as
+// its first parameter.
+func rtInvokeIntrinsicOf(call *ssa.CallCommon) (name string, sig *types.Signature, ok bool) {
+	named, isNamed := call.Value.Type().(*types.Named)
+	if !isNamed || named.Obj().Pkg() == nil {
+		return
+	}
+	if name, ok = rtIntrinsics[fullName(named.Obj().Pkg(), named.Obj().Name()+"."+call.Method.Name())]; !ok {
+		return
+	}
+	recv := types.NewParam(token.NoPos, nil, "", named)
+	return name, recvAsParam(recv, call.Signature()), true
+}
+
+// recvAsParam returns the signature of a function whose parameters are recv
+// and the ones of sig.
+func recvAsParam(recv *types.Var, sig *types.Signature) *types.Signature {
+	in := sig.Params()
+	params := make([]*types.Var, 0, in.Len()+1)
+	params = append(params, recv)
+	for i := 0; i < in.Len(); i++ {
+		params = append(params, in.At(i))
+	}
+	return types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), sig.Results(), sig.Variadic())
+}
+
+// -----------------------------------------------------------------------------
+
+// pkgVector is the package of vectors, whose methods are compiled to vector
+// instructions instead of to calls.
+const pkgVector = "github.com/goplus/llgo/vector"
+
+// vectorIntrinsicOf returns the name of the method fn if it is one of a vector
+// type of package vector, eg. "Add" for vector.Float32x4.Add.
+func vectorIntrinsicOf(fn *ssa.Function) (name string, ok bool) {
+	recv := fn.Signature.Recv()
+	if fn.Pkg == nil || recv == nil || fn.Pkg.Pkg.Path() != pkgVector {
+		return
+	}
+	if _, isNamed := recv.Type().(*types.Named); !isNamed { // wrappers of pointer receivers
+		return
+	}
+	return fn.Name(), true
+}
+
+// compileVector compiles the call of the method name of a vector. It reports
+// false if the method isn't lowered, ie. if the indexes of Shuffle aren't
+// valid constants, so that the call is compiled as other calls are.
+func (p *context) compileVector(b llssa.Builder, name string, call *ssa.CallCommon) (llssa.Expr, bool) {
+	var op token.Token
+	switch name {
+	case "Add":
+		op = token.ADD
+	case "Sub":
+		op = token.SUB
+	case "Mul":
+		op = token.MUL
+	case "Shuffle":
+		n := call.Args[0].Type().Underlying().(*types.Array).Len()
+		mask := make([]int, 0, n)
+		for _, arg := range call.Args[2:] {
+			c, ok := arg.(*ssa.Const)
+			if !ok {
+				return llssa.Expr{}, false
+			}
+			idx, exact := constant.Int64Val(c.Value)
+			if !exact || idx < 0 || idx >= 2*n {
+				return llssa.Expr{}, false
+			}
+			mask = append(mask, int(idx))
+		}
+		x, y := p.compileValue(b, call.Args[0]), p.compileValue(b, call.Args[1])
+		return b.VectorShuffle(x, y, mask), true
+	case "Sum":
+		return b.VectorSum(p.compileValue(b, call.Args[0])), true
+	default:
+		return llssa.Expr{}, false
+	}
+	x, y := p.compileValue(b, call.Args[0]), p.compileValue(b, call.Args[1])
+	return b.VectorBinOp(op, x, y), true
+}
+
+// -----------------------------------------------------------------------------
+
 var mathToLLVMMapping = map[string]string{
-	"math.Ceil":  "llvm.ceil.f64",
-	"math.Exp":   "llvm.exp.f64",
-	"math.Exp2":  "llvm.exp2.f64",
-	"math.Floor": "llvm.floor.f64",
-	"math.Log":   "llvm.log.f64",
-	"math.Sqrt":  "llvm.sqrt.f64",
-	"math.Trunc": "llvm.trunc.f64",
+	"math.Abs":      "llvm.fabs.f64",
+	"math.Ceil":     "llvm.ceil.f64",
+	"math.Copysign": "llvm.copysign.f64",
+	"math.Exp":      "llvm.exp.f64",
+	"math.Exp2":     "llvm.exp2.f64",
+	"math.FMA":      "llvm.fma.f64",
+	"math.Floor":    "llvm.floor.f64",
+	"math.Log":      "llvm.log.f64",
+	"math.Sqrt":     "llvm.sqrt.f64",
+	"math.Trunc":    "llvm.trunc.f64",
 }
 
-// defineMathOp defines a math function body as a call to a LLVM intrinsic,
+// mathIntrinsicOf returns the LLVM intrinsic that implements a math function,
 // instead of the regular Go implementation. This allows LLVM to reason about
 // the math operation and (depending on the architecture) allows it to lower the
 // operation to very fast floating point instructions. If this is not possible,
@@ -47,16 +369,54 @@ var mathToLLVMMapping = map[string]string{
 // float32(math.Sqrt(float64(v))) to a 32-bit floating point operation, which is
 // beneficial on architectures where 64-bit floating point operations are (much)
 // more expensive than 32-bit ones.
-func (b *builder) defineMathOp() {
-	panic("todo")
+func (p *context) mathIntrinsicOf(fn *ssa.Function) (ret llssa.Function, ok bool) {
+	if !p.conf.MathIntrinsics || fn.Pkg == nil || fn.Signature.Recv() != nil {
+		return
+	}
+	name, ok := mathToLLVMMapping[fullName(fn.Pkg.Pkg, fn.Name())]
+	if !ok {
+		return
+	}
+	if ret = p.pkg.FuncOf(name); ret == nil {
+		ret = p.pkg.NewFunc(name, fn.Signature)
+	}
+	return
 }
 
-// Implement most math/bits functions.
-//
-// This implements all the functions that operate on bits. It does not yet
-// implement the arithmetic functions (like bits.Add), which also have LLVM
-// intrinsics.
-func (b *builder) defineMathBitsIntrinsic() bool {
-	panic("todo")
+// bitsIntrinsics maps the functions of package math/bits, eg.
+// bits.LeadingZeros32, to the operations on the bits of their arguments that
+// implement them (see llssa.Builder.Bits).
+var bitsIntrinsics = make(map[string]llssa.BitsOp)
+
+func init() {
+	for _, n := range []string{"", "8", "16", "32", "64"} {
+		bitsIntrinsics["math/bits.LeadingZeros"+n] = llssa.BitsLeadingZeros
+		bitsIntrinsics["math/bits.TrailingZeros"+n] = llssa.BitsTrailingZeros
+		bitsIntrinsics["math/bits.OnesCount"+n] = llssa.BitsOnesCount
+		bitsIntrinsics["math/bits.Len"+n] = llssa.BitsLen
+		bitsIntrinsics["math/bits.Reverse"+n] = llssa.BitsReverse
+		if n != "8" {
+			bitsIntrinsics["math/bits.ReverseBytes"+n] = llssa.BitsReverseBytes
+		}
+	}
 }
-*/
+
+// compileBitsIntrinsic compiles the call of the function fn of package
+// math/bits to LLVM intrinsics, as mathIntrinsicOf does for package math. It
+// reports false if fn isn't lowered.
+func (p *context) compileBitsIntrinsic(b llssa.Builder, fn *ssa.Function, call *ssa.CallCommon) (llssa.Expr, bool) {
+	if !p.conf.MathIntrinsics || fn.Pkg == nil || fn.Signature.Recv() != nil || fn.Pkg.Pkg.Path() != "math/bits" {
+		return llssa.Expr{}, false
+	}
+	if strings.HasPrefix(fn.Name(), "RotateLeft") {
+		args := p.compileValues(b, call.Args, fnNormal)
+		return b.RotateLeft(args[0], args[1]), true
+	}
+	op, ok := bitsIntrinsics[fullName(fn.Pkg.Pkg, fn.Name())]
+	if !ok {
+		return llssa.Expr{}, false
+	}
+	return b.Bits(op, p.compileValue(b, call.Args[0])), true
+}
+
+// -----------------------------------------------------------------------------
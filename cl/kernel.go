@@ -0,0 +1,295 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// A function whose doc has the pragma
+//
+//	//llgo:kernel [gpu [cpu]]
+//
+// is a kernel, which is compiled for the processor cpu of gpu (see
+// llssa.KernelTarget) to a device package of its own, rather than to the
+// package. The kernels of the same GPU and processor share a device package,
+// whose code is embedded in the package, and the function of a kernel in the
+// package is a stub that launches it (see llssa.Builder.KernelLaunch).
+//
+// Kernels are restricted Go (see checkKernel): they only call the functions
+// of package gpu that index the threads of the grid, which are compiled to
+// intrinsics of the GPU, and the math functions that are LLVM intrinsics. As
+// device packages have no runtime, the bounds checks and the checks of
+// divisions by zero of kernels trap rather than panic.
+
+// pkgGPU is the package of kernels.
+const pkgGPU = "github.com/goplus/llgo/gpu"
+
+// A kernel is a kernel function of the package, compiled to its device
+// package, whose stub is fn.
+type kernel struct {
+	f   *ssa.Function
+	fn  llssa.Function
+	dev *context
+}
+
+// deviceOf returns the context that compiles the kernels of the processor cpu
+// of gpu to a device package, creating it if it doesn't exist.
+func (p *context) deviceOf(gpu, cpu string) *context {
+	key := gpu + "/" + cpu
+	if dev, ok := p.devices[key]; ok {
+		return dev
+	}
+	target, ok := llssa.KernelTarget(gpu, cpu)
+	if !ok {
+		p.unsupported(p.pos, "unknown GPU %q of //llgo:kernel", gpu)
+	}
+	prog := llssa.NewProgram(target)
+	dev := &context{
+		conf:   &Config{MathIntrinsics: p.conf.MathIntrinsics, Verify: p.conf.Verify},
+		prog:   prog,
+		pkg:    prog.NewPackage(p.goTyps.Name(), p.goTyps.Path()),
+		fset:   p.fset,
+		goTyps: p.goTyps,
+		goPkg:  p.goPkg,
+		device: gpu,
+		link:   make(map[string]string),
+		cfns:   make(map[string]none),
+		pyfns:  make(map[string]pyFunc),
+		wasmIn: make(map[string]wasmImport),
+		wasmEx: make(map[string]string),
+		prags:  p.prags,
+		tls:    p.tls,
+		loaded: make(map[*types.Package]none),
+	}
+	if p.devices == nil {
+		p.devices = make(map[string]*context)
+	}
+	p.devices[key] = dev
+	return dev
+}
+
+// compileKernel compiles the kernel f, whose pragmas are prags, to its device
+// package. Its stub fn is compiled when the device package is complete (see
+// compileKernelStubs).
+func (p *context) compileKernel(f *ssa.Function, fn llssa.Function, prags funcPragmas) {
+	p.inits = append(p.inits, func() {
+		p.fn, p.pos = fn, f.Pos()
+		defer func() {
+			p.fn = nil
+		}()
+		defer p.recoverFunc(f)
+		p.checkKernel(f)
+		dev := p.deviceOf(prags.kernel, prags.kernelCPU)
+		// The symbol of the kernel is its name, which the launcher looks up.
+		dev.link[p.goFuncName(f.Pkg.Pkg, f)] = f.Name()
+		dev.compileFunc(dev.pkg, f)
+		for _, ini := range dev.inits {
+			ini()
+		}
+		dev.inits = nil
+		if len(dev.errs) > 0 {
+			p.errs = append(p.errs, dev.errs...)
+			dev.errs = nil
+			panic(&compileError{f.Pos(), "kernel failed to compile"})
+		}
+		dev.pkg.FuncOf(f.Name()).SetKernel()
+		p.kernels = append(p.kernels, &kernel{f, fn, dev})
+	})
+}
+
+// compileKernelStubs generates the code of the device packages of the
+// kernels of the package, and compiles the stubs of the kernels, which
+// launch them with the code.
+func (p *context) compileKernelStubs() {
+	images := make(map[*context][]byte)
+	for _, k := range p.kernels {
+		image, ok := images[k.dev]
+		if !ok {
+			ft := llssa.AssemblyFile // PTX
+			if k.dev.device == llssa.GPUAMDGPU {
+				ft = llssa.ObjectFile
+			}
+			var err error
+			if image, err = k.dev.pkg.CodeGen(ft); err != nil {
+				p.errs.Add(p.fset.Position(k.f.Pos()), k.f.String()+": "+err.Error())
+				continue
+			}
+			images[k.dev] = image
+		}
+		b := k.fn.MakeBody(1)
+		args := make([]llssa.Expr, len(k.f.Params))
+		for i := range args {
+			args[i] = k.fn.Param(i)
+		}
+		b.KernelLaunch(k.dev.device, image, k.f.Name(), args...)
+		b.Return()
+	}
+}
+
+// checkKernel reports, by unsupported, the first construct of the kernel f
+// that needs the runtime, which kernels have none of, or that isn't
+// supported on the GPU.
+func (p *context) checkKernel(f *ssa.Function) {
+	if len(f.Blocks) == 0 {
+		p.unsupported(f.Pos(), "kernel without body")
+	}
+	if f.Signature.Results().Len() > 0 {
+		p.unsupported(f.Pos(), "kernel with results")
+	}
+	for _, param := range f.Params {
+		if !kernelParamType(param.Type()) {
+			p.unsupported(param.Pos(), "unsupported type of kernel parameter %s: %v", param.Name(), param.Type())
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			switch v := instr.(type) {
+			case *ssa.BinOp, *ssa.IndexAddr, *ssa.FieldAddr, *ssa.Field, *ssa.Store,
+				*ssa.Convert, *ssa.Phi, *ssa.Extract, *ssa.If, *ssa.Jump, *ssa.Return, *ssa.DebugRef:
+			case *ssa.UnOp:
+				if v.Op == token.ARROW {
+					p.unsupported(v.Pos(), "channel receive in kernel: %v", v)
+				}
+			case *ssa.Alloc:
+				if v.Heap {
+					p.unsupported(v.Pos(), "heap allocation in kernel: %v", v)
+				}
+			case *ssa.Call:
+				if !p.kernelCall(&v.Call) {
+					p.unsupported(v.Pos(), "unsupported call in kernel: %v", v)
+				}
+			default:
+				p.unsupported(instr.Pos(), "unsupported instruction in kernel %T: %v", instr, instr)
+			}
+		}
+	}
+}
+
+// kernelCall reports whether a kernel can make the call: of a function of
+// package gpu, of a math function that is an LLVM intrinsic, or of len or
+// cap of a slice.
+func (p *context) kernelCall(call *ssa.CallCommon) bool {
+	switch fn := call.Value.(type) {
+	case *ssa.Builtin:
+		if name := fn.Name(); name == "len" || name == "cap" {
+			_, ok := call.Args[0].Type().Underlying().(*types.Slice)
+			return ok
+		}
+	case *ssa.Function:
+		if _, ok := gpuIntrinsicOf(fn); ok {
+			return true
+		}
+		_, ok := p.mathIntrinsicOf(fn)
+		return ok
+	}
+	return false
+}
+
+// kernelParamType reports whether a kernel can have a parameter of type t: a
+// number, a pointer, a slice, or an array or a struct of them.
+func kernelParamType(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Info()&(types.IsNumeric|types.IsBoolean) != 0 || t.Kind() == types.UnsafePointer
+	case *types.Pointer, *types.Slice:
+		return true
+	case *types.Array:
+		return kernelParamType(t.Elem())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !kernelParamType(t.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// gpuIntrinsicOf returns the name of fn if it is a function of package gpu
+// that kernels call, eg. "ThreadIdx".
+func gpuIntrinsicOf(fn *ssa.Function) (string, bool) {
+	if fn.Pkg == nil || fn.Pkg.Pkg.Path() != pkgGPU || fn.Signature.Recv() != nil {
+		return "", false
+	}
+	switch name := fn.Name(); name {
+	case "ThreadIdx", "BlockIdx", "BlockDim", "GridDim", "Sync":
+		return name, true
+	}
+	return "", false
+}
+
+// gpuIntrinsics maps the functions of package gpu to the intrinsics of the
+// GPUs, whose names end with the axis, for those that have one. AMD GPUs have
+// none for BlockDim and GridDim (see compileGPU).
+var gpuIntrinsics = map[string]map[string]string{
+	llssa.GPUNVPTX: {
+		"ThreadIdx": "llvm.nvvm.read.ptx.sreg.tid.",
+		"BlockIdx":  "llvm.nvvm.read.ptx.sreg.ctaid.",
+		"BlockDim":  "llvm.nvvm.read.ptx.sreg.ntid.",
+		"GridDim":   "llvm.nvvm.read.ptx.sreg.nctaid.",
+		"Sync":      "llvm.nvvm.barrier0",
+	},
+	llssa.GPUAMDGPU: {
+		"ThreadIdx": "llvm.amdgcn.workitem.id.",
+		"BlockIdx":  "llvm.amdgcn.workgroup.id.",
+		"Sync":      "llvm.amdgcn.s.barrier",
+	},
+}
+
+// compileGPU compiles the call of the function name of package gpu by a
+// kernel to the intrinsic of the GPU of the device package, or for BlockDim
+// and GridDim on AMD GPUs, which have none, to llssa.Builder.AMDGPUDim.
+func (p *context) compileGPU(b llssa.Builder, name string, call *ssa.CallCommon) llssa.Expr {
+	var axis int64
+	if name != "Sync" {
+		c, ok := call.Args[0].(*ssa.Const)
+		if !ok || c.Int64() < 0 || c.Int64() > 2 {
+			p.unsupported(p.pos, "the axis of gpu.%s must be a constant", name)
+		}
+		axis = c.Int64()
+	}
+	if p.device == llssa.GPUAMDGPU && (name == "BlockDim" || name == "GridDim") {
+		return b.AMDGPUDim(name == "GridDim", int(axis))
+	}
+	intr, ok := gpuIntrinsics[p.device][name]
+	if !ok {
+		p.unsupported(p.pos, "gpu.%s isn't supported by %s kernels", name, p.device)
+	}
+	sig := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+	if name != "Sync" {
+		intr += "xyz"[axis:][:1]
+		sig = types.NewSignatureType(nil, nil, nil, nil,
+			types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.Uint32])), false)
+	}
+	fn := p.pkg.FuncOf(intr)
+	if fn == nil {
+		fn = p.pkg.NewFunc(intr, sig)
+	}
+	return b.Call(fn.Expr)
+}
+
+// -----------------------------------------------------------------------------
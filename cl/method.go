@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Methods are compiled as functions whose first parameter is the receiver, eg.
+// T.M or (*T).M, with the types they are declared on (see compileType).
+//
+// The synthetic wrappers of methods that go/ssa creates aren't members of
+// packages:
+//
+//   - the thunk of a method expression, eg. T.M$thunk for T.M, which is a
+//     function whose first parameter is the receiver;
+//   - the bound wrapper of a method value, eg. (*T).M$bound for x.M, which is
+//     a closure whose free variable is the receiver (see boundCallOf);
+//   - the wrappers of promoted methods, and of the methods of T called
+//     through a *T, which are methods of the embedding type, or of *T.
+//
+// They are compiled by the packages that use them, with the symbol of the
+// package of their receiver (see memberName and pkgOf), as definitions that
+// the linker merges.
+
+// Methods returns the methods declared in pkg, which NewPackageEx compiles
+// with the types they are declared on, sorted by position. The methods of
+// generic types, and the ones that are compiled as intrinsics, aren't
+// compiled.
+func Methods(pkg *ssa.Package) []*ssa.Function {
+	var ret []*ssa.Function
+	for _, m := range pkg.Members {
+		if t, ok := m.(*ssa.Type); ok {
+			ret = append(ret, typeMethods(pkg, t)...)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Pos() < ret[j].Pos()
+	})
+	return ret
+}
+
+// typeMethods returns the methods declared on the type t of pkg (see Methods).
+func typeMethods(pkg *ssa.Package, t *ssa.Type) []*ssa.Function {
+	named, ok := t.Type().(*types.Named)
+	if !ok || named.TypeParams() != nil || types.IsInterface(named) {
+		return nil
+	}
+	var ret []*ssa.Function
+	prog := pkg.Prog
+	for _, typ := range []types.Type{named, types.NewPointer(named)} {
+		mset := prog.MethodSets.MethodSet(typ)
+		for i := 0; i < mset.Len(); i++ {
+			fn := prog.MethodValue(mset.At(i))
+			if fn == nil || fn.Synthetic != "" || fn.Pkg != pkg {
+				continue
+			}
+			if _, _, ok := rtIntrinsicOf(fn); ok {
+				continue
+			}
+			if _, ok := vectorIntrinsicOf(fn); ok {
+				continue
+			}
+			ret = append(ret, fn)
+		}
+	}
+	return ret
+}
+
+// isWrapper reports whether fn is a synthetic wrapper of a method, which
+// belongs to no package.
+func isWrapper(fn *ssa.Function) bool {
+	return fn.Pkg == nil && fn.Synthetic != ""
+}
+
+// wrapperOf returns the wrapper fn, which it compiles when it is first used.
+func (p *context) wrapperOf(fn *ssa.Function) llssa.Function {
+	name := p.funcName(p.pkgOf(fn), fn)
+	if ret := p.pkg.FuncOf(name); ret != nil {
+		return ret
+	}
+	pos := p.pos
+	p.compileFunc(p.pkg, fn)
+	p.pos = pos
+	return p.pkg.FuncOf(name)
+}
+
+// methodFunc returns the function of the method sel, for the method tables of
+// type descriptors (see llssa.Package.SetMethodFunc), or nil if the method
+// isn't compiled as a function: it is a method of an interface or of a
+// generic type, or an intrinsic.
+func (p *context) methodFunc(sel *types.Selection) llssa.Function {
+	fn := p.goPkg.Prog.MethodValue(sel)
+	if fn == nil || fn.Origin() != nil {
+		return nil
+	}
+	if _, _, ok := rtIntrinsicOf(fn); ok {
+		return nil
+	}
+	if _, ok := vectorIntrinsicOf(fn); ok {
+		return nil
+	}
+	return p.funcOf(fn)
+}
+
+// funcSig returns the signature of the function of fn, whose first parameters
+// are the receiver of a method, or the free variables of a bound wrapper.
+func funcSig(fn *ssa.Function) *types.Signature {
+	sig := fn.Signature
+	if recv := sig.Recv(); recv != nil {
+		return recvAsParam(recv, sig)
+	}
+	for i := len(fn.FreeVars) - 1; i >= 0; i-- {
+		fv := fn.FreeVars[i]
+		sig = recvAsParam(types.NewParam(token.NoPos, nil, fv.Name(), fv.Type()), sig)
+	}
+	return sig
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// The pragmas of a function are the lines of its doc, eg.
+//
+//	// isr handles the interrupts of the timer.
+//	//
+//	//go:nosplit
+//	//llgo:section .text.isr
+//	func isr() {
+//
+// The pragmas that precede the package clause of a file apply to all the
+// functions of the file, unless they override them, eg. to place the code of
+// the file in a section:
+//
+//	//llgo:section .text.boot
+//
+//	package boot
+//
+// The pragmas are:
+//
+//   - //go:noinline: the function is never inlined.
+//   - //go:nosplit: the function doesn't check for preemption in its prologue
+//     (see Config.Preempt), eg. as it runs before the scheduler does.
+//   - //llgo:inline: the function is inlined at all its call sites, as the
+//     small runtime functions that the compiled code calls are, so that LLVM
+//     simplifies them per call site (see llssa.InlineBitcode).
+//   - //llgo:section name: the code of the function is placed in the section
+//     name, eg. a section that the linker script maps to fast memory.
+//   - //llgo:align n: the code of the function is aligned to n bytes, a power
+//     of two.
+//   - //llgo:weak: the function is a weak definition, which a non-weak
+//     definition of its symbol overrides, eg. a hook of the runtime that a
+//     program can replace.
+//   - //llgo:visibility v: the visibility of the symbol of the function is v,
+//     default, hidden or protected, instead of the one of Config.Visibility.
+//   - //llgo:kernel [gpu [cpu]]: the function is a kernel, which is compiled
+//     for a GPU, and which a call launches on it (see compileKernel).
+//
+// The pragmas of the functions that the package doesn't define are ignored.
+//
+// The pragma of a package-level variable is
+//
+//   - //llgo:threadlocal: each thread has its own instance of the variable
+//     (see llssa.Global.SetThreadLocal), eg. the M of the current thread in
+//     the runtime. It applies to the unexported variables of the declaration
+//     it documents, as the packages that import it declare its variables
+//     without their pragmas.
+
+// funcPragmas are the pragmas of a function.
+type funcPragmas struct {
+	noinline bool
+	nosplit  bool
+	inline   bool
+	weak     bool
+	section  string
+	align    int
+	vis      string
+
+	kernel    string // GPU of a kernel, see compileKernel
+	kernelCPU string
+}
+
+// visibilities are the visibilities of the pragma //llgo:visibility.
+var visibilities = map[string]llssa.Visibility{
+	"default":   llssa.VisibilityDefault,
+	"hidden":    llssa.VisibilityHidden,
+	"protected": llssa.VisibilityProtected,
+}
+
+// parse sets the pragma of line, if it is one.
+func (p *funcPragmas) parse(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	switch fields[0] {
+	case "//go:noinline":
+		p.noinline = true
+	case "//go:nosplit":
+		p.nosplit = true
+	case "//llgo:inline":
+		p.inline = true
+	case "//llgo:weak":
+		p.weak = true
+	case "//llgo:section":
+		if len(fields) == 2 {
+			p.section = fields[1]
+		}
+	case "//llgo:align":
+		if len(fields) == 2 {
+			if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 && n&(n-1) == 0 {
+				p.align = n
+			}
+		}
+	case "//llgo:kernel":
+		if len(fields) <= 3 {
+			p.kernel, p.kernelCPU = llssa.GPUNVPTX, ""
+			if len(fields) > 1 {
+				p.kernel = fields[1]
+			}
+			if len(fields) > 2 {
+				p.kernelCPU = fields[2]
+			}
+		}
+	case "//llgo:visibility":
+		if len(fields) == 2 {
+			if _, ok := visibilities[fields[1]]; ok {
+				p.vis = fields[1]
+			}
+		}
+	}
+}
+
+// initPragmas finds the pragmas of the functions and of the variables of
+// file.
+func (p *context) initPragmas(file *ast.File) {
+	var deflt funcPragmas
+	for _, cg := range file.Comments {
+		if cg.Pos() >= file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			deflt.parse(c.Text)
+		}
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			prags := deflt
+			if doc := decl.Doc; doc != nil {
+				for _, c := range doc.List {
+					prags.parse(c.Text)
+				}
+			}
+			if prags != (funcPragmas{}) {
+				p.prags[decl] = prags
+			}
+		case *ast.GenDecl:
+			if decl.Tok != token.VAR {
+				continue
+			}
+			tls := hasPragma(decl.Doc, "//llgo:threadlocal")
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if !tls && !hasPragma(spec.Doc, "//llgo:threadlocal") {
+					continue
+				}
+				for _, name := range spec.Names {
+					if !name.IsExported() && name.Name != "_" {
+						p.tls[llssa.Mangle(p.goTyps.Path(), name.Name)] = none{}
+					}
+				}
+			}
+		}
+	}
+}
+
+// hasPragma reports whether doc has the line pragma.
+func hasPragma(doc *ast.CommentGroup, pragma string) bool {
+	if doc != nil {
+		for _, c := range doc.List {
+			if strings.TrimSpace(c.Text) == pragma {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setPragmas sets the attributes of the function fn, which the package
+// defines, that its pragmas prags specify, and its visibility.
+func (p *context) setPragmas(fn llssa.Function, prags funcPragmas) {
+	if prags.noinline {
+		fn.SetNoInline()
+	}
+	if prags.inline {
+		fn.SetAlwaysInline()
+	}
+	if prags.section != "" {
+		fn.SetSection(prags.section)
+	}
+	if prags.align != 0 {
+		fn.SetAlignment(prags.align)
+	}
+	if prags.weak {
+		fn.SetWeak()
+	}
+	vis := p.conf.Visibility
+	if v, ok := visibilities[prags.vis]; ok {
+		vis = v
+	}
+	if vis != llssa.VisibilityDefault {
+		fn.SetVisibility(vis)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// initFlags adds the flags of the pragmas of file
+//
+//	//llgo:cflags -I${SRCDIR}/include
+//	//llgo:ldflags -L${SRCDIR}/lib -lsqlite3
+//	//llgo:pkg-config sqlite3
+//
+// to the flags that the C files of the package are compiled with and to the
+// ones that the programs which link it are linked with (see
+// llssa.Package.AddCFlags and AddLDFlags), as #cgo CFLAGS, LDFLAGS and
+// pkg-config directives do: the flags of the C libraries of //llgo:pkg-config
+// are the ones that pkg-config reports when the package is built (see
+// llssa.Package.AddPkgConfig). ${SRCDIR} is replaced by the directory of file.
+// The flags are separated by spaces.
+func (p *context) initFlags(file *ast.File) {
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			fields := strings.Fields(c.Text)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "//llgo:cflags", "//llgo:ldflags", "//llgo:pkg-config":
+			default:
+				continue
+			}
+			dir := filepath.Dir(p.fset.Position(file.Package).Filename)
+			flags := fields[1:]
+			for i, flag := range flags {
+				flags[i] = strings.ReplaceAll(flag, "${SRCDIR}", dir)
+			}
+			switch fields[0] {
+			case "//llgo:cflags":
+				p.pkg.AddCFlags(flags...)
+			case "//llgo:ldflags":
+				p.pkg.AddLDFlags(flags...)
+			default:
+				p.pkg.AddPkgConfig(flags...)
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Reachable is a set of package members that are reachable from roots: the
+// roots themselves, the functions they call or refer to and the global
+// variables they use, transitively. If Config.Reachable is set, NewPackageEx
+// only compiles the reachable functions and global variables, so that unused
+// members don't bloat executables.
+//
+// A Reachable must not be modified while packages are compiled with it, but
+// it can be shared by packages compiled concurrently.
+type Reachable struct {
+	members map[ssa.Member]bool
+	visited map[*ssa.Function]bool
+	work    []*ssa.Function
+}
+
+// NewReachable creates a Reachable of the members reachable from roots. The
+// functions that may be reached must have been built (see ssa.Package.Build).
+func NewReachable(roots ...ssa.Member) *Reachable {
+	r := &Reachable{
+		members: make(map[ssa.Member]bool),
+		visited: make(map[*ssa.Function]bool),
+	}
+	r.Add(roots...)
+	return r
+}
+
+// Add adds roots, and the members reachable from them, to r.
+func (r *Reachable) Add(roots ...ssa.Member) {
+	for _, m := range roots {
+		r.mark(m)
+	}
+	for len(r.work) > 0 {
+		fn := r.work[len(r.work)-1]
+		r.work = r.work[:len(r.work)-1]
+		r.visit(fn)
+	}
+}
+
+// Has reports whether member m is reachable. Types and constants, which
+// generate no code, are always reachable.
+func (r *Reachable) Has(m ssa.Member) bool {
+	switch m.Token() {
+	case token.FUNC, token.VAR:
+		return r.members[m]
+	}
+	return true
+}
+
+func (r *Reachable) mark(m ssa.Member) {
+	switch m := m.(type) {
+	case *ssa.Function:
+		if !r.visited[m] {
+			r.visited[m] = true
+			r.members[m] = true
+			r.work = append(r.work, m)
+		}
+	case *ssa.Global:
+		r.members[m] = true
+	}
+}
+
+// visit marks the functions and global variables that fn refers to. The
+// anonymous functions of fn are referred to by its MakeClosure instructions.
+// The methods of the types that fn converts to interfaces are marked too, as
+// calls of interface methods may be devirtualized (see devirtualize).
+func (r *Reachable) visit(fn *ssa.Function) {
+	var ops []*ssa.Value
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if mi, ok := instr.(*ssa.MakeInterface); ok {
+				r.markMethods(fn.Prog, mi.X.Type())
+			}
+			ops = instr.Operands(ops[:0])
+			for _, op := range ops {
+				switch v := (*op).(type) {
+				case *ssa.Function:
+					r.mark(v)
+				case *ssa.Global:
+					r.mark(v)
+				}
+			}
+		}
+	}
+}
+
+// markMethods marks the methods of the method set of type t.
+func (r *Reachable) markMethods(prog *ssa.Program, t types.Type) {
+	mset := prog.MethodSets.MethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		if fn := prog.MethodValue(mset.At(i)); fn != nil {
+			r.mark(fn)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/types"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// -----------------------------------------------------------------------------
+
+// minSwitchCases is the minimum number of constant cases of a switch that is
+// compiled to a switch instruction, which LLVM lowers to a jump table or a
+// binary search, instead of to a chain of comparisons.
+const minSwitchCases = 4
+
+// lowerSwitches finds the chains of comparisons of an integer with constants
+// in f, to which go/ssa lowers switch statements, that are compiled to switch
+// instructions. They are recorded by the block that starts them, whose If
+// instruction is compiled to the switch, and whose comparison isn't compiled
+// unless it has other uses. The other blocks of the chain are compiled as is,
+// but are unreachable then.
+//
+// The blocks of the cases mustn't have phis, whose predecessors would change,
+// and the switches aren't lowered if the coverage of f is measured, as the
+// blocks of the chain have counters.
+//
+// The chains of type assertions of type switches to concrete types are
+// lowered too, but differently, as the blocks of the chain bind the values of
+// the cases: the dynamic type is looked up once, by its hash, when the first
+// assertion is compiled (see llssa.Builder.TypeSwitch), and the block that
+// starts the chain jumps to the one of the case that matches, whose assertion
+// is compiled to a comparison of the index of the case (see typeCaseOf).
+func (p *context) lowerSwitches(f *ssa.Function) {
+	p.sws, p.swks = nil, nil
+	if p.ctrs.Type != nil {
+		return
+	}
+	for _, sw := range ssautil.Switches(f) {
+		if sw.TypeCases != nil {
+			if isTypeSwitch(&sw) {
+				sw := sw
+				p.sws = append(p.sws, &sw)
+			}
+			continue
+		}
+		if len(sw.ConstCases) < minSwitchCases || !isInteger(sw.X.Type()) || hasPhi(sw.Default) {
+			continue
+		}
+		ok := true
+		for _, c := range sw.ConstCases {
+			if hasPhi(c.Body) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			sw := sw
+			p.sws = append(p.sws, &sw)
+			cond := sw.Start.Instrs[len(sw.Start.Instrs)-1].(*ssa.If).Cond
+			if refs := cond.Referrers(); refs != nil && len(*refs) == 1 {
+				p.skips[cond.(ssa.Instruction)] = none{}
+			}
+		}
+	}
+}
+
+// switchOf returns the switch that block starts if it is compiled to a switch
+// instruction, or nil.
+func (p *context) switchOf(block *ssa.BasicBlock) *ssautil.Switch {
+	for _, sw := range p.sws {
+		if sw.Start == block {
+			return sw
+		}
+	}
+	return nil
+}
+
+// isTypeSwitch reports whether the type switch sw is compiled to a switch
+// instruction: it must have enough cases, of concrete types, and the blocks
+// that it jumps to mustn't have phis.
+func isTypeSwitch(sw *ssautil.Switch) bool {
+	if len(sw.TypeCases) < minSwitchCases || hasPhi(sw.TypeCases[0].Body) {
+		return false
+	}
+	for i, c := range sw.TypeCases {
+		if _, ok := c.Type.Underlying().(*types.Interface); ok {
+			return false
+		}
+		if i > 0 && hasPhi(c.Block) {
+			return false
+		}
+	}
+	return true
+}
+
+// typeCaseOf returns the type switch whose ith case asserts the type of ta, if
+// it is compiled to a switch instruction, or nil.
+func (p *context) typeCaseOf(ta *ssa.TypeAssert) (*ssautil.Switch, int) {
+	for _, sw := range p.sws {
+		for i, c := range sw.TypeCases {
+			if c.Block == ta.Block() && c.Binding.(*ssa.Extract).Tuple == ta {
+				return sw, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// typeSwitchIndex returns the index of the case of the type switch sw that
+// matches its dynamic type, looking it up in the block that starts the switch,
+// which dominates the ones of the other cases.
+func (p *context) typeSwitchIndex(b llssa.Builder, sw *ssautil.Switch) llssa.Expr {
+	if k, ok := p.swks[sw]; ok {
+		return k
+	}
+	typs := make([]types.Type, len(sw.TypeCases))
+	for i, c := range sw.TypeCases {
+		typs[i] = c.Type
+	}
+	k := b.TypeSwitch(p.compileValue(b, sw.X), typs)
+	if p.swks == nil {
+		p.swks = make(map[*ssautil.Switch]llssa.Expr)
+	}
+	p.swks[sw] = k
+	return k
+}
+
+func isInteger(t types.Type) bool {
+	if t, ok := t.Underlying().(*types.Basic); ok {
+		return t.Info()&types.IsInteger != 0
+	}
+	return false
+}
+
+func hasPhi(block *ssa.BasicBlock) bool {
+	if len(block.Instrs) > 0 {
+		_, ok := block.Instrs[0].(*ssa.Phi)
+		return ok
+	}
+	return false
+}
+
+// compileSwitch compiles the switch sw. A value that appears in several cases
+// jumps to the first of them, as the chain of comparisons does. A type switch
+// jumps to the block of the case that matches, or to the one of the last case
+// if none does, whose assertion fails then.
+func (p *context) compileSwitch(b llssa.Builder, sw *ssautil.Switch) {
+	fn := p.fn
+	if sw.TypeCases != nil {
+		k := p.typeSwitchIndex(b, sw)
+		n := len(sw.TypeCases)
+		vals := make([]llssa.Expr, n-1)
+		blks := make([]llssa.BasicBlock, n-1)
+		for i, c := range sw.TypeCases[:n-1] {
+			vals[i] = p.prog.IntVal(uint64(i), k.Type)
+			blks[i] = fn.Block(c.Block.Index)
+		}
+		blks[0] = fn.Block(sw.TypeCases[0].Body.Index)
+		b.Switch(k, vals, blks, fn.Block(sw.TypeCases[n-1].Block.Index))
+		return
+	}
+	x := p.compileValue(b, sw.X)
+	vals := make([]llssa.Expr, 0, len(sw.ConstCases))
+	blks := make([]llssa.BasicBlock, 0, len(sw.ConstCases))
+	seen := make(map[string]bool)
+	for _, c := range sw.ConstCases {
+		key := c.Value.Value.ExactString()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		vals = append(vals, p.compileValue(b, c.Value))
+		blks = append(blks, fn.Block(c.Body.Index))
+	}
+	b.Switch(x, vals, blks, fn.Block(sw.Default.Index))
+}
+
+// -----------------------------------------------------------------------------
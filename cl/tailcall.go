@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// tailCalls reports whether the calls in tail position of f are compiled to
+// tail calls (see llssa.Builder.TailCall). f mustn't have variables on the
+// stack, whose addresses the callees may access, nor defer statements, whose
+// calls follow the ones in tail position.
+func (p *context) tailCalls(f *ssa.Function) bool {
+	if len(p.defers) > 0 || p.isCMain(f) {
+		return false
+	}
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			if v, ok := instr.(*ssa.Alloc); ok && !isHeapAlloc(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isTailCall reports whether the call v is compiled to a tail call: it is in
+// tail position, ie. the function returns its result, if any, right after it.
+func (p *context) isTailCall(v *ssa.Call) bool {
+	if !p.tail {
+		return false
+	}
+	instrs := v.Block().Instrs
+	for i, instr := range instrs {
+		if instr != v {
+			continue
+		}
+		for _, next := range instrs[i+1:] {
+			switch next := next.(type) {
+			case *ssa.DebugRef:
+				continue
+			case *ssa.Return:
+				switch len(next.Results) {
+				case 0:
+					return v.Call.Signature().Results().Len() == 0
+				case 1:
+					return next.Results[0] == v
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// With Config.Traceback, the functions of the package are added to its
+// function table (see llssa.Package.FuncTab), which the function
+// init$functab of the package registers with the runtime (see
+// runtime.FuncTabRegister), so that the runtime prints their names and
+// positions in the stack traces of fatal errors. The init function of the
+// package calls init$functab first.
+
+// isTraced reports whether the functions of the package are added to its
+// function table, which the ones of the runtime aren't.
+func (p *context) isTraced() bool {
+	return p.conf.Traceback && !strings.HasPrefix(p.goTyps.Path(), llssa.PkgRuntime)
+}
+
+// funcInfoName returns the name of function f in stack traces, eg.
+// "example.com/foo.(*T).M".
+func (p *context) funcInfoName(f *ssa.Function) string {
+	return p.pkgOf(f).Path() + "." + memberName(f)
+}
+
+// funcInfoPos returns the position of function f in stack traces, which is
+// <autogenerated>:1 if it isn't declared in the source, as in Go.
+func (p *context) funcInfoPos(f *ssa.Function) token.Position {
+	if !f.Pos().IsValid() {
+		return token.Position{Filename: "<autogenerated>", Line: 1}
+	}
+	return p.position(f.Pos())
+}
+
+// funcTabInit returns the function init$functab of the package.
+func (p *context) funcTabInit() llssa.Function {
+	name := fullName(p.goTyps, "init$functab")
+	if fn := p.pkg.FuncOf(name); fn != nil {
+		return fn
+	}
+	return p.pkg.NewFunc(name, types.NewSignatureType(nil, nil, nil, nil, nil, false))
+}
+
+// compileFuncTabInit defines the function init$functab of the package, which
+// registers its function table with the runtime.
+func (p *context) compileFuncTabInit() {
+	b := p.funcTabInit().MakeBody(1)
+	params := types.NewTuple(
+		types.NewParam(0, nil, "tab", types.Typ[types.UnsafePointer]),
+		types.NewParam(0, nil, "n", types.Typ[types.Int]))
+	sig := types.NewSignatureType(nil, nil, nil, params, nil, false)
+	if tab, n := p.pkg.FuncTab(); n > 0 {
+		b.RuntimeCall("FuncTabRegister", sig, tab, p.prog.Val(n))
+	}
+	b.Return()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// The variadic arguments of a C function, eg. c.Printf, are declared as
+// `__llgo_va_list ...any`, and go/ssa passes them as a slice literal of
+// interfaces, while C expects them one by one. lowerVArgs finds the slice
+// literals of the calls of such functions, whose elements are passed instead
+// of them (see compileVArg), and the instructions that build them, which
+// mustn't be compiled.
+func (p *context) lowerVArgs(f *ssa.Function) {
+	p.vargs = nil
+	for _, block := range f.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || funcKind(call.Call.Value) != fnHasVArg {
+				continue
+			}
+			vals, skips, ok := cVArgsOf(call.Call.Args[len(call.Call.Args)-1])
+			if !ok || vals == nil {
+				continue
+			}
+			if p.vargs == nil {
+				p.vargs = make(map[ssa.Value][]ssa.Value)
+			}
+			p.vargs[call.Call.Args[len(call.Call.Args)-1]] = vals
+			for _, instr := range skips {
+				p.skips[instr] = none{}
+			}
+		}
+	}
+}
+
+// cVArgsOf returns the values of the variadic arguments v of a C function, and
+// the instructions that build them, if v is a slice literal of values
+// converted to interfaces.
+func cVArgsOf(v ssa.Value) (vals []ssa.Value, skips []ssa.Instruction, ok bool) {
+	if vals, skips, ok = varArgsOf(v); !ok {
+		return
+	}
+	for i, val := range vals {
+		mi, isMI := val.(*ssa.MakeInterface)
+		if !isMI || len(*mi.Referrers()) != 1 {
+			return nil, nil, false
+		}
+		vals[i] = mi.X
+		skips = append(skips, mi)
+	}
+	return vals, skips, true
+}
+
+// compileVArg appends the variadic arguments v of a C function to ret: none
+// if v is nil, or the elements of the slice literal v (see lowerVArgs).
+func (p *context) compileVArg(ret []llssa.Expr, b llssa.Builder, v ssa.Value) []llssa.Expr {
+	if vals, ok := p.vargs[v]; ok {
+		for _, val := range vals {
+			ret = append(ret, p.compileValue(b, val))
+		}
+		return ret
+	}
+	if c, ok := v.(*ssa.Const); ok && c.Value == nil {
+		return ret
+	}
+	p.unsupported(v.Pos(), "unsupported variadic arguments: %v", v)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
@@ -112,3 +112,62 @@ func Main(c *Command, app string, args []string) {
 	}
 	c.Run(c, args)
 }
+
+// SanitizerFlags are the flags -asan, -tsan and -msan of the commands that
+// build programs, which instrument them with a sanitizer.
+type SanitizerFlags struct {
+	asan, tsan, msan *bool
+}
+
+// AddSanitizerFlags adds the sanitizer flags to flag.
+func AddSanitizerFlags(flag *flag.FlagSet) *SanitizerFlags {
+	return &SanitizerFlags{
+		asan: flag.Bool("asan", false, "instrument the program with AddressSanitizer"),
+		tsan: flag.Bool("tsan", false, "instrument the program with ThreadSanitizer"),
+		msan: flag.Bool("msan", false, "instrument the program with MemorySanitizer"),
+	}
+}
+
+// Sanitizer returns the sanitizer that the flags enable: "address", "thread",
+// "memory", or "" if none. At most one of them can be set.
+func (p *SanitizerFlags) Sanitizer() (string, error) {
+	var ret []string
+	if *p.asan {
+		ret = append(ret, "address")
+	}
+	if *p.tsan {
+		ret = append(ret, "thread")
+	}
+	if *p.msan {
+		ret = append(ret, "memory")
+	}
+	switch len(ret) {
+	case 0:
+		return "", nil
+	case 1:
+		return ret[0], nil
+	}
+	return "", fmt.Errorf("flags -asan, -tsan and -msan are mutually exclusive")
+}
+
+// TagsFlag is the flag -tags of the commands that load packages, the build
+// tags satisfied in addition to the ones of the target.
+type TagsFlag []string
+
+// AddTagsFlag adds the flag -tags to flag.
+func AddTagsFlag(flag *flag.FlagSet) *TagsFlag {
+	ret := new(TagsFlag)
+	flag.Var(ret, "tags", "a comma-separated list of additional build tags")
+	return ret
+}
+
+func (p *TagsFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+// Set sets the tags to the ones of v, separated by commas or, as the go
+// command used to accept, by spaces.
+func (p *TagsFlag) Set(v string) error {
+	*p = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	return nil
+}
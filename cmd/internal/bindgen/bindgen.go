@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bindgen implements the “llgo bindgen” command.
+package bindgen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/internal/cgo"
+	"github.com/goplus/llgo/x/env/llvm"
+)
+
+// llgo bindgen
+var Cmd = &base.Command{
+	UsageLine: "llgo bindgen [flags] headers",
+	Short:     "Generate a Go package of the bindings of C headers",
+}
+
+var (
+	flagOutput  = flag.String("o", "", "output file (default: standard output)")
+	flagPackage = flag.String("pkg", "", "package name (default: the name of the first header)")
+	flagCflags  = flag.String("cflags", "", "flags passed to clang to parse the headers, eg. -I dir")
+	flagVerbose = flag.Bool("v", false, "print the declarations that have no bindings")
+	flag        = &Cmd.Flag
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Panicln("parse input arguments failed:", err)
+	}
+	headers := flag.Args()
+	if len(headers) == 0 {
+		fmt.Fprintln(os.Stderr, "usage:", cmd.UsageLine)
+		os.Exit(2)
+	}
+
+	pkgName := *flagPackage
+	if pkgName == "" {
+		pkgName = packageName(headers[0])
+	}
+	conf := &cgo.Config{
+		GOOS:   orDefault(os.Getenv("GOOS"), runtime.GOOS),
+		GOARCH: orDefault(os.Getenv("GOARCH"), runtime.GOARCH),
+		Clang:  llvm.New().Clang(),
+		Flags:  strings.Fields(*flagCflags),
+	}
+	ret, err := cgo.Bindgen(pkgName, headers, conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *flagVerbose {
+		for _, skipped := range ret.Skipped {
+			fmt.Fprintln(os.Stderr, skipped)
+		}
+	}
+	if *flagOutput == "" {
+		os.Stdout.Write(ret.Src)
+	} else if err = os.WriteFile(*flagOutput, ret.Src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// packageName returns the package name of the bindings of header, eg. zlib
+// for zlib.h.
+func packageName(header string) string {
+	name := strings.TrimSuffix(filepath.Base(header), filepath.Ext(header))
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "c" + name
+	}
+	return name
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// -----------------------------------------------------------------------------
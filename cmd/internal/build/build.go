@@ -37,9 +37,25 @@ var Cmd = &base.Command{
 }
 
 var (
-	flagOutput = flag.String("o", "", "build output file")
-	_          = flag.Bool("v", false, "print verbose information")
-	flag       = &Cmd.Flag
+	flagOutput  = flag.String("o", "", "build output file")
+	flagForce   = flag.Bool("a", false, "force rebuilding of packages that are already up-to-date")
+	flagEmit    = flag.String("emit", "", "kind of output: exe (default), obj, asm, llvm or bc")
+	flagMode    = flag.String("buildmode", "", "build mode: exe (default), c-archive, c-shared or plugin")
+	flagWork    = flag.Bool("work", false, "print the name of the temporary work directory and the commands run in it, and keep it")
+	flagTrim    = flag.Bool("trimpath", false, "remove the directories of the source files from the output")
+	_           = flag.Bool("v", false, "print verbose information")
+	flagRace    = flag.Bool("race", false, "enable data race detection")
+	flagHarden  = flag.Bool("hardening", false, "harden the program with stack protectors, control-flow integrity and checked memory functions")
+	flagStatic  = flag.Bool("static", false, "link the executable statically, with musl if $MUSL_SYSROOT is set")
+	flagCPU     = flag.String("mcpu", "", "CPU to generate code for, eg. skylake, or native for the one of the host")
+	flagAttr    = flag.String("mattr", "", "features of the CPU to add or remove, eg. +avx2,-avx512f")
+	flagFloat   = flag.String("mfloat-abi", "", "float ABI of ARM targets: hard, soft or softfp")
+	flagSan     = base.AddSanitizerFlags(flag)
+	flagTags    = base.AddTagsFlag(flag)
+	flagMod     = flag.String("mod", "", "module download mode to use: readonly, vendor, or mod")
+	flagOverlay = flag.String("overlay", "", "read a JSON config file that provides an overlay for build operations")
+	flagJSON    = flag.Bool("json", false, "write build errors to stdout as JSON records")
+	flag        = &Cmd.Flag
 )
 
 func init() {
@@ -65,8 +81,15 @@ func runCmd(cmd *base.Command, args []string) {
 		log.Panicln("too many arguments:", args)
 	}
 
-	conf := &llgo.Config{}
-	confCmd := &gocmd.BuildConfig{}
+	san, err := flagSan.Sanitizer()
+	if err != nil {
+		log.Panicln(err)
+	}
+	conf := &llgo.Config{Sanitizer: san, Race: *flagRace, Hardening: *flagHarden, Static: *flagStatic, CPUModel: *flagCPU, Features: *flagAttr, FloatABI: *flagFloat, Tags: *flagTags, ModFlag: *flagMod, Overlay: *flagOverlay}
+	if *flagJSON {
+		conf.Diagnostics = os.Stdout
+	}
+	confCmd := &gocmd.BuildConfig{ForceRebuild: *flagForce, Emit: *flagEmit, BuildMode: *flagMode, Work: *flagWork, TrimPath: *flagTrim}
 	if *flagOutput != "" {
 		output, err := filepath.Abs(*flagOutput)
 		if err != nil {
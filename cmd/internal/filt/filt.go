@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package filt implements the “llgo filt” command.
+package filt
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/ssa"
+)
+
+// llgo filt
+var Cmd = &base.Command{
+	UsageLine: "llgo filt [symbols]",
+	Short:     "Demangle the symbols of Go functions and variables",
+}
+
+var flag = &Cmd.Flag
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+// symbol matches the words of a line that may be symbols, eg. of the output
+// of nm or perf.
+var symbol = regexp.MustCompile(`[^\s'"<>;:@]+`)
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Panicln("parse input arguments failed:", err)
+	}
+	if syms := flag.Args(); len(syms) > 0 {
+		for _, sym := range syms {
+			fmt.Println(demangle(sym))
+		}
+		return
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	s := bufio.NewScanner(os.Stdin)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		w.WriteString(symbol.ReplaceAllStringFunc(s.Text(), demangle))
+		w.WriteByte('\n')
+	}
+	if err := s.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "llgo filt:", err)
+		os.Exit(1)
+	}
+}
+
+// demangle returns the readable name of sym, or sym if it isn't the one of a
+// Go symbol.
+func demangle(sym string) string {
+	if pkgPath, name, ok := ssa.Demangle(sym); ok {
+		return pkgPath + "." + name
+	}
+	return sym
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package run implements the “llgo run” command.
+package run
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+
+	"github.com/goplus/llgo"
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/internal/projs"
+	"github.com/goplus/llgo/x/gocmd"
+)
+
+// llgo run
+var Cmd = &base.Command{
+	UsageLine: "llgo run [flags] package|files [arguments...]",
+	Short:     "Compile and run a Go program",
+}
+
+var (
+	flagForce   = flag.Bool("a", false, "force rebuilding of packages that are already up-to-date")
+	_           = flag.Bool("v", false, "print verbose information")
+	flagRace    = flag.Bool("race", false, "enable data race detection")
+	flagSan     = base.AddSanitizerFlags(flag)
+	flagTags    = base.AddTagsFlag(flag)
+	flagMod     = flag.String("mod", "", "module download mode to use: readonly, vendor, or mod")
+	flagOverlay = flag.String("overlay", "", "read a JSON config file that provides an overlay for build operations")
+	flagJSON    = flag.Bool("json", false, "write build errors to stdout as JSON records")
+	flag        = &Cmd.Flag
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Panicln("parse input arguments failed:", err)
+	}
+
+	args = flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "llgo run: no go files listed")
+		os.Exit(2)
+	}
+
+	proj, args, err := projs.ParseOne(args...)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	san, err := flagSan.Sanitizer()
+	if err != nil {
+		log.Panicln(err)
+	}
+	conf := &llgo.Config{Sanitizer: san, Race: *flagRace, Tags: *flagTags, ModFlag: *flagMod, Overlay: *flagOverlay}
+	if *flagJSON {
+		conf.Diagnostics = os.Stdout
+	}
+	confCmd := &gocmd.RunConfig{ForceRebuild: *flagForce}
+	os.Exit(run(proj, args, conf, confCmd))
+}
+
+func run(proj projs.Proj, args []string, conf *llgo.Config, run *gocmd.RunConfig) int {
+	var obj string
+	var code int
+	var err error
+	switch v := proj.(type) {
+	case *projs.DirProj:
+		obj = v.Dir
+		code, err = llgo.RunDir(obj, args, conf, run)
+	case *projs.FilesProj:
+		code, err = llgo.RunFiles(v.Files, args, conf, run)
+	default:
+		log.Panicln("`llgo run` doesn't support", reflect.TypeOf(v))
+	}
+	if llgo.NotFound(err) {
+		fmt.Fprintf(os.Stderr, "llgo run %v: not found\n", obj)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		return code
+	}
+	return 1
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package test implements the “llgo test” command.
+package test
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+
+	"github.com/goplus/llgo"
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/internal/projs"
+	"github.com/goplus/llgo/x/gocmd"
+)
+
+// llgo test
+var Cmd = &base.Command{
+	UsageLine: "llgo test [flags] [package]",
+	Short:     "Test a Go package",
+}
+
+var (
+	flagForce   = flag.Bool("a", false, "force rebuilding of packages that are already up-to-date")
+	flagRun     = flag.String("run", "", "run only the tests matching the regular expression")
+	flagVerbose = flag.Bool("v", false, "print the name and status of all tests")
+	flagCover   = flag.Bool("cover", false, "enable coverage analysis, by basic blocks that approximate the ones of go test")
+	flagProfile = flag.String("coverprofile", "", "write a coverage profile to the file")
+	flagRace    = flag.Bool("race", false, "enable data race detection")
+	flagSan     = base.AddSanitizerFlags(flag)
+	flagTags    = base.AddTagsFlag(flag)
+	flagMod     = flag.String("mod", "", "module download mode to use: readonly, vendor, or mod")
+	flagOverlay = flag.String("overlay", "", "read a JSON config file that provides an overlay for build operations")
+	flag        = &Cmd.Flag
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Panicln("parse input arguments failed:", err)
+	}
+
+	args = flag.Args()
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	proj, args, err := projs.ParseOne(args...)
+	if err != nil {
+		log.Panicln(err)
+	}
+	if len(args) != 0 {
+		log.Panicln("too many arguments:", args)
+	}
+
+	san, err := flagSan.Sanitizer()
+	if err != nil {
+		log.Panicln(err)
+	}
+	conf := &llgo.Config{Sanitizer: san, Race: *flagRace, Tags: *flagTags, ModFlag: *flagMod, Overlay: *flagOverlay}
+	confCmd := &gocmd.TestConfig{
+		RunConfig: gocmd.RunConfig{ForceRebuild: *flagForce},
+		Run:       *flagRun,
+		Verbose:   *flagVerbose,
+
+		Cover:        *flagCover,
+		CoverProfile: *flagProfile,
+	}
+	os.Exit(test(proj, conf, confCmd))
+}
+
+func test(proj projs.Proj, conf *llgo.Config, test *gocmd.TestConfig) int {
+	var obj string
+	var code int
+	var err error
+	switch v := proj.(type) {
+	case *projs.DirProj:
+		obj = v.Dir
+		code, err = llgo.TestDir(obj, conf, test)
+	case *projs.PkgPathProj:
+		obj = v.Path
+		code, err = llgo.TestPkgPath("", obj, conf, test)
+	default:
+		log.Panicln("`llgo test` doesn't support", reflect.TypeOf(v))
+	}
+	if llgo.NotFound(err) {
+		fmt.Fprintf(os.Stderr, "llgo test %v: not found\n", obj)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		return code
+	}
+	return 1
+}
+
+// -----------------------------------------------------------------------------
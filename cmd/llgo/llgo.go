@@ -25,9 +25,14 @@ import (
 	"github.com/qiniu/x/log"
 
 	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/cmd/internal/bindgen"
 	"github.com/goplus/llgo/cmd/internal/build"
+	"github.com/goplus/llgo/cmd/internal/filt"
 	"github.com/goplus/llgo/cmd/internal/gen"
 	"github.com/goplus/llgo/cmd/internal/help"
+	"github.com/goplus/llgo/cmd/internal/install"
+	"github.com/goplus/llgo/cmd/internal/run"
+	"github.com/goplus/llgo/cmd/internal/test"
 )
 
 func mainUsage() {
@@ -39,7 +44,12 @@ func init() {
 	flag.Usage = mainUsage
 	base.Llgo.Commands = []*base.Command{
 		build.Cmd,
+		install.Cmd,
+		run.Cmd,
+		test.Cmd,
 		gen.Cmd,
+		bindgen.Cmd,
+		filt.Cmd,
 	}
 }
 
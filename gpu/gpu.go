@@ -0,0 +1,449 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gpu runs kernels, functions of a program that are compiled for a
+// GPU, as CUDA does for C++. The function whose doc has the pragma
+//
+//	//llgo:kernel [gpu [cpu]]
+//
+// is compiled for gpu, nvptx (the default) for NVIDIA GPUs or amdgpu for AMD
+// ones, and its processor cpu, eg. sm_70 or gfx90a, and a call of it launches
+// it on the GPU, with the grid of the last call of Configure, and waits for
+// it to complete, eg.
+//
+//	//llgo:kernel
+//	func scale(a float32, x []float32) {
+//		i := gpu.BlockIdx(gpu.X)*gpu.BlockDim(gpu.X) + gpu.ThreadIdx(gpu.X)
+//		if i < uint32(len(x)) {
+//			x[i] *= a
+//		}
+//	}
+//
+//	x := unsafe.Slice((*float32)(gpu.Alloc(n*4)), n)
+//	gpu.Configure(gpu.Dim3{X: n / 256}, gpu.Dim3{X: 256})
+//	scale(2, x)
+//
+// Kernels are restricted Go: they have no results, their parameters are
+// numbers, pointers, slices, and arrays and structs of them, they don't
+// allocate memory on the heap, and they only call the functions of this
+// package that index the threads of the grid, and the math functions that
+// are LLVM intrinsics. The memory that they access must be accessible by the
+// GPU, eg. the one that Alloc allocates.
+//
+// This is an experiment. The driver of the GPU, CUDA or HIP, is loaded when
+// a kernel is first launched on it. AMD kernels are then linked by ld.lld of
+// ROCm ($ROCM_PATH/llvm/bin/ld.lld, where $ROCM_PATH defaults to /opt/rocm),
+// as HIP only loads linked code objects.
+package gpu
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// LLGoPackage specifies that programs which import the package are linked
+// with libdl, by which the drivers of GPUs are loaded.
+const LLGoPackage = "link: -ldl"
+
+// -----------------------------------------------------------------------------
+
+// Axis is an axis of the grid of a kernel, X, Y or Z.
+type Axis int
+
+const (
+	X Axis = iota
+	Y
+	Z
+)
+
+// ThreadIdx returns the index of the calling thread of a kernel in its block,
+// along the axis a, which must be a constant.
+func ThreadIdx(a Axis) uint32 {
+	panic("gpu.ThreadIdx is only supported in kernels")
+}
+
+// BlockIdx returns the index of the block of the calling thread of a kernel in
+// the grid, along the axis a, which must be a constant.
+func BlockIdx(a Axis) uint32 {
+	panic("gpu.BlockIdx is only supported in kernels")
+}
+
+// BlockDim returns the number of threads of a block of the grid of a kernel,
+// along the axis a, which must be a constant.
+func BlockDim(a Axis) uint32 {
+	panic("gpu.BlockDim is only supported in kernels")
+}
+
+// GridDim returns the number of blocks of the grid of a kernel, along the axis
+// a, which must be a constant.
+func GridDim(a Axis) uint32 {
+	panic("gpu.GridDim is only supported in kernels")
+}
+
+// Sync waits until all the threads of the block of the calling thread of a
+// kernel have called it.
+func Sync() {
+	panic("gpu.Sync is only supported in kernels")
+}
+
+// -----------------------------------------------------------------------------
+
+// Dim3 is a size of a grid or of its blocks, along each axis.
+type Dim3 struct {
+	X, Y, Z uint32
+}
+
+var grid, block = Dim3{1, 1, 1}, Dim3{1, 1, 1}
+
+// Configure sets the grid of the kernels that are launched next: grid blocks
+// of block threads. A size 0 along an axis is 1.
+func Configure(g, b Dim3) {
+	grid, block = g.norm(), b.norm()
+}
+
+func (d Dim3) norm() Dim3 {
+	if d.X == 0 {
+		d.X = 1
+	}
+	if d.Y == 0 {
+		d.Y = 1
+	}
+	if d.Z == 0 {
+		d.Z = 1
+	}
+	return d
+}
+
+// Alloc allocates n bytes of unified memory, which both the host and the GPU
+// access, by the driver of the first GPU that is found, CUDA's or else HIP's.
+func Alloc(n uintptr) unsafe.Pointer {
+	d := anyDriver()
+	var p c.Pointer
+	d.check("alloc", d.alloc(&p, n, memAttachGlobal))
+	return p
+}
+
+// Free frees the memory p that Alloc allocated.
+func Free(p unsafe.Pointer) {
+	d := anyDriver()
+	d.check("free", d.free(p))
+}
+
+// -----------------------------------------------------------------------------
+
+// A driver is the driver of the GPUs of a vendor: CUDA, whose functions are
+// the ones of its driver API, or HIP, whose functions have the same
+// signatures.
+type driver struct {
+	name    string
+	lib     string // shared library
+	syms    [7]string
+	modules map[c.Pointer]c.Pointer
+
+	init    initFunc
+	load    loadFunc
+	getFunc getFuncFunc
+	launch  launchFunc
+	sync    syncFunc
+	alloc   allocFunc
+	free    freeFunc
+	end     uintptr // marker of the end of the extra parameters of launch
+}
+
+// The types of the functions of drivers, which are C function pointers, as the
+// package is a C package (see LLGoPackage).
+type (
+	initFunc    func(flags uint32) c.Int
+	loadFunc    func(mod *c.Pointer, image c.Pointer) c.Int
+	getFuncFunc func(fn *c.Pointer, mod c.Pointer, name *c.Char) c.Int
+	launchFunc  func(fn c.Pointer, gx, gy, gz, bx, by, bz, shared uint32, stream c.Pointer, params, extra *c.Pointer) c.Int
+	syncFunc    func() c.Int
+	allocFunc   func(p *c.Pointer, n uintptr, flags uint32) c.Int
+	freeFunc    func(p c.Pointer) c.Int
+
+	getDeviceFunc  func(dev *c.Int, ordinal c.Int) c.Int
+	retainFunc     func(ctx *c.Pointer, dev c.Int) c.Int
+	setCurrentFunc func(ctx c.Pointer) c.Int
+)
+
+// Markers of the extra parameters of launches, whose buffer holds the
+// arguments of a kernel.
+const (
+	launchParamBufferPointer = 1
+	launchParamBufferSize    = 2
+	cudaLaunchParamEnd       = 0
+	hipLaunchParamEnd        = 3
+)
+
+const memAttachGlobal = 1 // CU_MEM_ATTACH_GLOBAL and hipMemAttachGlobal
+
+var cuda = &driver{
+	name: "CUDA",
+	lib:  "libcuda.so.1",
+	syms: [...]string{"cuInit", "cuModuleLoadData", "cuModuleGetFunction", "cuLaunchKernel",
+		"cuCtxSynchronize", "cuMemAllocManaged", "cuMemFree_v2"},
+	end: cudaLaunchParamEnd,
+}
+
+var hip = &driver{
+	name: "HIP",
+	lib:  "libamdhip64.so",
+	syms: [...]string{"hipInit", "hipModuleLoadData", "hipModuleGetFunction", "hipModuleLaunchKernel",
+		"hipDeviceSynchronize", "hipMallocManaged", "hipFree"},
+	end: hipLaunchParamEnd,
+}
+
+// open loads the shared library of d, and initializes it. It reports false if
+// the library isn't found.
+func (d *driver) open() bool {
+	if d.modules != nil {
+		return true
+	}
+	lib := cstring(d.lib)
+	h := dlopen(lib, rtldNow)
+	c.Free(c.Pointer(lib))
+	if h == nil {
+		return false
+	}
+	fns := [...]unsafe.Pointer{
+		unsafe.Pointer(&d.init), unsafe.Pointer(&d.load), unsafe.Pointer(&d.getFunc),
+		unsafe.Pointer(&d.launch), unsafe.Pointer(&d.sync), unsafe.Pointer(&d.alloc),
+		unsafe.Pointer(&d.free),
+	}
+	for i, fn := range fns {
+		d.bind(h, fn, d.syms[i])
+	}
+	d.check("init", d.init(0))
+	if d == cuda {
+		// The driver API of CUDA needs a current context, unlike HIP.
+		var getDevice getDeviceFunc
+		var retain retainFunc
+		var setCurrent setCurrentFunc
+		d.bind(h, unsafe.Pointer(&getDevice), "cuDeviceGet")
+		d.bind(h, unsafe.Pointer(&retain), "cuDevicePrimaryCtxRetain")
+		d.bind(h, unsafe.Pointer(&setCurrent), "cuCtxSetCurrent")
+		var dev c.Int
+		var ctx c.Pointer
+		d.check("get device", getDevice(&dev, 0))
+		d.check("retain context", retain(&ctx, dev))
+		d.check("set context", setCurrent(ctx))
+	}
+	d.modules = make(map[c.Pointer]c.Pointer)
+	return true
+}
+
+// bind sets the C function pointer at fn to the function sym of the shared
+// library h of d.
+func (d *driver) bind(h c.Pointer, fn unsafe.Pointer, sym string) {
+	csym := cstring(sym)
+	p := dlsym(h, csym)
+	c.Free(c.Pointer(csym))
+	if p == nil {
+		fatal(d.name + ": " + sym + " not found")
+	}
+	*(*c.Pointer)(fn) = p
+}
+
+// check panics if the call op of d failed with the error code err.
+func (d *driver) check(op string, err c.Int) {
+	if err != 0 {
+		fatal(d.name + ": " + op + " failed with error " + itoa(int(err)))
+	}
+}
+
+// anyDriver returns the driver of the first GPU that is found.
+func anyDriver() *driver {
+	if cuda.open() {
+		return cuda
+	}
+	if hip.open() {
+		return hip
+	}
+	fatal("no GPU driver found")
+	return nil
+}
+
+// driverOf returns the driver of the GPUs of kernels compiled for gpu.
+func driverOf(gpu string) *driver {
+	d := cuda
+	if gpu == "amdgpu" {
+		d = hip
+	}
+	if !d.open() {
+		fatal(d.name + " driver not found")
+	}
+	return d
+}
+
+// module returns the module of d that the code image of kernels, of imageLen
+// bytes, is loaded to.
+func (d *driver) module(image c.Pointer, imageLen uintptr) c.Pointer {
+	if mod, ok := d.modules[image]; ok {
+		return mod
+	}
+	code := image
+	if d == hip {
+		code = linkAMDGPU(image, imageLen)
+	}
+	var mod c.Pointer
+	d.check("load module", d.load(&mod, code))
+	d.modules[image] = mod
+	return mod
+}
+
+// launch launches the kernel name of the code image of gpu, with the
+// arguments at args, of argsLen bytes, and waits for it to complete. Calls of
+// kernels are compiled to calls of it (see llssa.Builder.KernelLaunch).
+//
+//export llgo_gpu_launch
+func launch(gpu, image *c.Char, imageLen uintptr, name *c.Char, args c.Pointer, argsLen uintptr) {
+	d := driverOf(gostring(gpu))
+	mod := d.module(c.Pointer(image), imageLen)
+	var fn c.Pointer
+	d.check("get function", d.getFunc(&fn, mod, name))
+	extra := [...]uintptr{
+		launchParamBufferPointer, uintptr(args),
+		launchParamBufferSize, uintptr(unsafe.Pointer(&argsLen)),
+		d.end,
+	}
+	d.check("launch", d.launch(fn, grid.X, grid.Y, grid.Z, block.X, block.Y, block.Z, 0, nil, nil,
+		(*c.Pointer)(unsafe.Pointer(&extra[0]))))
+	d.check("sync", d.sync())
+}
+
+// linkAMDGPU links the relocatable code object image, of imageLen bytes, that
+// LLVM compiles AMD kernels to, to the shared code object that HIP loads, by
+// ld.lld of ROCm. The linked code object is never freed, as its module isn't.
+func linkAMDGPU(image c.Pointer, imageLen uintptr) c.Pointer {
+	obj := cstring("/tmp/llgo-gpu-XXXXXX")
+	fd := mkstemp(obj)
+	if fd < 0 || c.Write(fd, image, imageLen) != c.Long(imageLen) {
+		fatal("HIP: can't write the code object of kernels")
+	}
+	c.Close(fd)
+	rocm := cstring("ROCM_PATH")
+	root := gostring(c.Getenv(rocm))
+	c.Free(c.Pointer(rocm))
+	if root == "" {
+		root = "/opt/rocm"
+	}
+	in := gostring(obj)
+	c.Free(c.Pointer(obj))
+	out := in + ".so"
+	cmd := root + "/llvm/bin/ld.lld -shared -o " + out + " " + in
+	ccmd := cstring(cmd)
+	ok := system(ccmd) == 0
+	c.Free(c.Pointer(ccmd))
+	remove(in)
+	if !ok {
+		fatal("HIP: can't link the code object of kernels: " + cmd)
+	}
+	code := readFile(out)
+	remove(out)
+	return code
+}
+
+// readFile returns the contents of the file, which the caller frees.
+func readFile(file string) c.Pointer {
+	cfile := cstring(file)
+	fd := c.Open(cfile, c.ORdonly)
+	c.Free(c.Pointer(cfile))
+	if fd < 0 {
+		fatal("can't read " + file)
+	}
+	n, size := uintptr(0), uintptr(1<<16)
+	buf := c.Malloc(size)
+	for {
+		if n == size {
+			size *= 2
+			buf = realloc(buf, size)
+		}
+		m := c.Read(fd, unsafe.Add(buf, n), size-n)
+		if m <= 0 {
+			break
+		}
+		n += uintptr(m)
+	}
+	c.Close(fd)
+	return buf
+}
+
+// remove removes the file.
+func remove(file string) {
+	cfile := cstring(file)
+	unlink(cfile)
+	c.Free(c.Pointer(cfile))
+}
+
+// -----------------------------------------------------------------------------
+
+func fatal(msg string) {
+	panic("gpu: " + msg)
+}
+
+// cstring returns a copy of s terminated by a NUL, which the caller frees.
+func cstring(s string) *c.Char {
+	buf := c.Calloc(uintptr(len(s)+1), 1)
+	c.Memcpy(buf, *(*c.Pointer)(unsafe.Pointer(&s)), uintptr(len(s)))
+	return (*c.Char)(buf)
+}
+
+// gostring returns the NUL-terminated string s of libc.
+func gostring(s *c.Char) string {
+	if s == nil {
+		return ""
+	}
+	n := c.Strlen(s)
+	b := make([]byte, n)
+	c.Memcpy(*(*c.Pointer)(unsafe.Pointer(&b)), c.Pointer(s), n)
+	return string(b)
+}
+
+func itoa(n int) string {
+	if n < 0 {
+		return "-" + itoa(-n)
+	}
+	var buf [20]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = byte('0' + n%10)
+		if n /= 10; n == 0 {
+			return string(buf[i:])
+		}
+	}
+}
+
+const rtldNow = 2 // RTLD_NOW of Linux and macOS
+
+//go:linkname dlopen dlopen
+func dlopen(path *c.Char, mode c.Int) c.Pointer
+
+//go:linkname dlsym dlsym
+func dlsym(handle c.Pointer, name *c.Char) c.Pointer
+
+//go:linkname mkstemp mkstemp
+func mkstemp(template *c.Char) c.Int
+
+//go:linkname system system
+func system(cmd *c.Char) c.Int
+
+//go:linkname unlink unlink
+func unlink(path *c.Char) c.Int
+
+//go:linkname realloc realloc
+func realloc(p c.Pointer, size uintptr) c.Pointer
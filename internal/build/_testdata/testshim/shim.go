@@ -0,0 +1,5 @@
+package testshim
+
+func Add(a, b int) int {
+	return a + b
+}
@@ -0,0 +1,22 @@
+package testshim
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	t.Helper()
+	if Add(1, 2) != 3 || t.Name() != "TestAdd" {
+		t.FailNow()
+	}
+}
+
+func TestSkip(t *testing.T) {
+	t.SkipNow()
+	t.Fail()
+}
+
+func TestFail(t *testing.T) {
+	t.Fail()
+	if !t.Failed() || t.Skipped() {
+		panic("unreachable")
+	}
+}
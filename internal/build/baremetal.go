@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"github.com/goplus/llgo/x/env/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// Targets of microcontrollers, to be built with Config.Baremetal. Packages are
+// type-checked as linux/arm ones, which have the same sizes of types.
+// Cortex-M0 has neither FPU nor division instructions, which are replaced by
+// calls to the functions of compiler-rt.
+var (
+	TargetCortexM0 = &llssa.Target{
+		GOOS: "linux", GOARCH: "arm",
+		Triple:   "thumbv6m-unknown-unknown-eabi",
+		CPU:      "cortex-m0",
+		Features: "+armv6-m,+strict-align,+thumb-mode",
+		FloatABI: llssa.FloatABISoft,
+	}
+	TargetCortexM4 = &llssa.Target{
+		GOOS: "linux", GOARCH: "arm",
+		Triple:   "thumbv7em-unknown-unknown-eabi",
+		CPU:      "cortex-m4",
+		Features: "+armv7e-m,+dsp,+hwdiv,+soft-float,+strict-align,+thumb-mode",
+	}
+	TargetRISCV32 = &llssa.Target{
+		GOOS: "linux", GOARCH: "arm",
+		Triple:   "riscv32-unknown-none",
+		CPU:      "generic-rv32",
+		Features: "+a,+c,+m,-relax",
+	}
+)
+
+// baremetalFlags returns the clang flags that link an executable without an
+// operating system nor libc, by the linker script of conf. -fno-builtin keeps
+// clang from turning the loops of memset and memcpy into calls to themselves.
+// The builtins of compiler-rt are linked, if clang finds them, as the code of
+// CPUs without FPU or division instructions calls them.
+func baremetalFlags(conf *Config) []string {
+	flags := []string{"-nostdlib", "-ffreestanding", "-fno-builtin", "-static"}
+	if conf.LinkerScript != "" {
+		flags = append(flags, "-T", conf.LinkerScript)
+	}
+	if lib := builtinsLib(conf); lib != "" {
+		flags = append(flags, lib)
+	}
+	return flags
+}
+
+// builtinsLib returns the library of the builtins of compiler-rt for the
+// target of conf, or "" if clang doesn't find it.
+func builtinsLib(conf *Config) string {
+	args := append(clangFlags(conf), "--rtlib=compiler-rt", "-print-libgcc-file-name")
+	out, err := llvm.New().Clang().Output(nil, args...)
+	if err != nil {
+		return ""
+	}
+	lib := strings.TrimSpace(string(out))
+	if _, err := os.Stat(lib); err != nil {
+		return ""
+	}
+	return lib
+}
+
+// baremetalStartup writes the C source of the startup code of the main package
+// pkgPath to workDir.
+func baremetalStartup(pkgPath, workDir string) (string, error) {
+	src := fmt.Sprintf(baremetalRuntime, llssa.Mangle(pkgPath, "init"))
+	file := filepath.Join(workDir, "_baremetal.c")
+	return file, os.WriteFile(file, []byte(src), 0644)
+}
+
+// baremetalRuntime is the C source of the startup code and the libc subset
+// that the llgo runtime needs on a baremetal target. It expects the linker
+// script to define:
+//
+//	_sidata, _sdata, _edata: the load address and the bounds of .data
+//	_sbss, _ebss:            the bounds of .bss
+//	_heap_start, _heap_end:  the bounds of the heap
+//	_stack_top:              the initial stack pointer
+//
+// Packages are initialized with interrupts disabled, so that an interrupt
+// handler never sees a partially initialized package; main runs with them
+// enabled. Memory is allocated by llgo_alloc, a bump allocator which never
+// frees memory. All the functions are weak: a program or a board support
+// package may provide its own allocator (llgo_alloc and llgo_free), malloc,
+// printf and so on.
+const baremetalRuntime = `#include <stddef.h>
+
+#define LLGO_STR2(x) #x
+#define LLGO_STR(x) LLGO_STR2(x)
+#define LLGO_SYM(name) __asm__(LLGO_STR(__USER_LABEL_PREFIX__) name)
+#define LLGO_WEAK __attribute__((weak))
+
+extern char _sidata[], _sdata[], _edata[], _sbss[], _ebss[];
+extern char _heap_start[], _heap_end[], _stack_top[];
+
+extern void llgo_main_init(void) LLGO_SYM(%q);
+extern int main(int argc, char **argv, char **envp);
+
+static char *heapPtr = _heap_start;
+
+LLGO_WEAK void *llgo_alloc(size_t size) {
+	size = (size + 7) & ~(size_t)7;
+	if (size > (size_t)(_heap_end - heapPtr)) {
+		return NULL;
+	}
+	void *p = heapPtr;
+	heapPtr += size;
+	return p;
+}
+
+LLGO_WEAK void llgo_free(void *p) {
+}
+
+LLGO_WEAK void *memset(void *s, int c, size_t n) {
+	unsigned char *p = s;
+	while (n--) {
+		*p++ = (unsigned char)c;
+	}
+	return s;
+}
+
+LLGO_WEAK void *memcpy(void *dst, const void *src, size_t n) {
+	unsigned char *d = dst;
+	const unsigned char *s = src;
+	while (n--) {
+		*d++ = *s++;
+	}
+	return dst;
+}
+
+LLGO_WEAK void *memmove(void *dst, const void *src, size_t n) {
+	unsigned char *d = dst;
+	const unsigned char *s = src;
+	if (d < s) {
+		while (n--) {
+			*d++ = *s++;
+		}
+	} else {
+		while (n--) {
+			d[n] = s[n];
+		}
+	}
+	return dst;
+}
+
+LLGO_WEAK void *malloc(size_t size) {
+	return llgo_alloc(size);
+}
+
+LLGO_WEAK void *calloc(size_t num, size_t size) {
+	void *p = llgo_alloc(num * size);
+	if (p != NULL) {
+		memset(p, 0, num * size);
+	}
+	return p;
+}
+
+LLGO_WEAK void free(void *p) {
+	llgo_free(p);
+}
+
+LLGO_WEAK int printf(const char *format, ...) {
+	return 0;
+}
+
+static void llgo_disable_interrupts(void) {
+#if defined(__arm__)
+	__asm__ volatile("cpsid i" ::: "memory");
+#elif defined(__riscv)
+	__asm__ volatile("csrc mstatus, 8" ::: "memory");
+#endif
+}
+
+static void llgo_enable_interrupts(void) {
+#if defined(__arm__)
+	__asm__ volatile("cpsie i" ::: "memory");
+#elif defined(__riscv)
+	__asm__ volatile("csrs mstatus, 8" ::: "memory");
+#endif
+}
+
+LLGO_WEAK void abort(void) {
+	llgo_disable_interrupts();
+	for (;;) {
+	}
+}
+
+LLGO_WEAK void exit(int code) {
+	abort();
+}
+
+void llgo_reset(void) {
+	for (char *src = _sidata, *dst = _sdata; dst < _edata;) {
+		*dst++ = *src++;
+	}
+	for (char *dst = _sbss; dst < _ebss;) {
+		*dst++ = 0;
+	}
+	llgo_disable_interrupts();
+	llgo_main_init();
+	llgo_enable_interrupts();
+	main(0, NULL, NULL);
+	abort();
+}
+
+#if defined(__arm__)
+__attribute__((section(".isr_vector"), used)) void *const llgo_vectors[2] = {_stack_top, (void *)llgo_reset};
+#elif defined(__riscv)
+__attribute__((naked, section(".text.start"))) void _start(void) {
+	__asm__ volatile("la sp, _stack_top\n\tj llgo_reset");
+}
+#endif
+`
+
+// -----------------------------------------------------------------------------
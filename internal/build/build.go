@@ -0,0 +1,1298 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package build implements the driver that builds Go packages to native
+// executables: it loads packages, compiles them to LLVM IR and links them.
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/internal/ar"
+	"github.com/goplus/llgo/internal/cgo"
+	"github.com/goplus/llgo/internal/mod"
+	"github.com/goplus/llgo/x/env/llvm"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Config represents the configuration of a build.
+type Config struct {
+	Dir    string     // directory in which packages are loaded (empty means the current directory)
+	Env    []string   // environment of the go command that loads packages (nil means the current environment)
+	Output string     // output file, or directory if it ends with a slash (empty means the default)
+	Conf   *cl.Config // configuration of compiling a Go package (nil means the default)
+
+	// Target is the platform for which packages are built (nil means the one
+	// specified by GOOS, GOARCH and GOARM of Env, which default to the host).
+	// Packages are loaded with its GOOS and GOARCH, so that build constraints
+	// are satisfied for it.
+	Target  *llssa.Target
+	Sysroot string // sysroot of the target passed to clang (empty means the default)
+
+	// Diagnostics, if not nil, is where the errors of loading and compiling
+	// packages are written as JSON records, one per line (see Diagnostic), so
+	// that editors and other tools can parse them. All of them are written,
+	// while the first one only is returned.
+	Diagnostics io.Writer
+
+	// Overlay maps the absolute paths of Go files to their contents, which
+	// replace the ones on disk when packages are loaded, or add files to
+	// their directories, as go build -overlay does (see ReadOverlay).
+	Overlay map[string][]byte
+
+	// ModFlag is the -mod flag of the go command that loads packages:
+	// "readonly", "vendor" or "mod" (empty means the default of the go
+	// command, which is vendor if the main module has a vendor directory).
+	// Dependencies are resolved as the go command does, from the vendor
+	// directory, or from the module cache, which they are downloaded to by
+	// GOPROXY.
+	ModFlag string
+
+	// Tags are the build tags satisfied by the packages that are loaded, in
+	// addition to the ones of the target, as go build -tags does.
+	Tags []string
+
+	// WasmExecModel is the execution model of a WebAssembly executable:
+	// WasmCommand (the default) runs main and exits, WasmReactor initializes
+	// packages and exports their functions to be called by the host.
+	WasmExecModel string
+
+	// Baremetal builds an executable that runs without an operating system,
+	// eg. for TargetCortexM4 or TargetRISCV32. Packages are loaded with the
+	// baremetal build tag, which selects the single-threaded profile of the
+	// llgo runtime, and linked without libc by LinkerScript (see
+	// baremetalRuntime for the symbols it must define).
+	Baremetal    bool
+	LinkerScript string
+
+	// LTO is the link-time optimization mode of executables: LTOOff (the
+	// default), LTOThin or LTOFull. With LTO, packages are compiled to
+	// bitcode and optimized together by lld, so that functions are inlined
+	// across packages. Archives of non-main packages contain native objects.
+	LTO string
+
+	// GC is the garbage collector of the llgo runtime: GCBoehm, GCPrecise,
+	// GCNone or GCLeaking. The default is GCBoehm, which links libgc, or
+	// GCLeaking for WebAssembly and baremetal targets. The runtime is loaded
+	// with the build tag gc.<name> of the collector.
+	GC string
+
+	// OutputKind is the kind of output. Unless it is OutputExecutable, the
+	// single package specified by patterns, without its dependencies, is
+	// emitted next to conf.Output: to conf.Output with the extension of the
+	// kind, eg. hello.ll for hello.
+	OutputKind OutputKind
+
+	// RelocModel is the relocation model of the code generated for the
+	// target: llssa.RelocStatic, llssa.RelocPIC or llssa.RelocPIE (empty
+	// means the one of Target, if any, or the default of the toolchain, or
+	// RelocPIC for shared libraries).
+	// Executables are linked as position-independent executables with
+	// RelocPIE, and as executables at fixed addresses with RelocStatic.
+	RelocModel string
+
+	// Sanitizer instruments the functions of packages, and the C code that
+	// they link, with a sanitizer of clang: llssa.SanitizeAddress,
+	// llssa.SanitizeThread or llssa.SanitizeMemory (empty means none), and
+	// links its runtime, which reports the errors that it detects when the
+	// program runs. MemorySanitizer needs the libraries that a program links
+	// to be instrumented too.
+	Sanitizer string
+
+	// CPUModel is the CPU that code is generated for, eg. "skylake" or
+	// "cortex-a72", or CPUNative (empty means the one of the target), and
+	// Features are the features of the CPU that are added, eg. "+avx2", or
+	// removed, eg. "-avx512f", separated by commas (see withCPU).
+	CPUModel string
+	Features string
+
+	// FloatABI is the float ABI of an ARM target: llssa.FloatABIHard,
+	// llssa.FloatABISoft, for CPUs without FPU, or llssa.FloatABISoftFP
+	// (empty means the one of the target, see llssa.Target.FloatABI).
+	FloatABI string
+
+	// Static links executables statically, with no dependency on shared
+	// libraries, so that they can be deployed as a single file: with musl if
+	// $MUSL_SYSROOT is set, for Linux targets, or else with the static
+	// libraries of the toolchain (see checkStatic).
+	Static bool
+
+	// Hardening hardens the program against the exploitation of memory
+	// errors, for security-sensitive deployments: with stack protectors,
+	// control-flow integrity where the target has it, and FORTIFY-style checks
+	// of the memory functions that the runtime calls (see checkHardening).
+	Hardening bool
+
+	// Race detects data races when the program runs, as go build -race does:
+	// the loads and stores of Go code are reported to the runtime of
+	// ThreadSanitizer (see cl.Config.Race), which is linked. The llgo runtime
+	// is loaded with the build tag race, which annotates the synchronization
+	// of goroutines. It is compatible with llssa.SanitizeThread only.
+	Race bool
+
+	// Cover measures the coverage of the statements of the package tested by
+	// Test, as go test -cover does (see cl.Config.Cover): the test binary
+	// prints the percentage of its statements that the tests run and, with
+	// -test.coverprofile=file, writes the coverage profile to file.
+	Cover bool
+
+	coverPkg string // the package whose coverage is measured (see Cover)
+
+	// BuildMode is what a main package is built to: BuildModeExe (the
+	// default), an executable, a C library, BuildModeCArchive (a static
+	// library) or BuildModeCShared (a shared library), or BuildModePlugin, a
+	// shared object loaded by the package github.com/goplus/llgo/plugin.
+	// Libraries initialize the packages when they are loaded instead of
+	// running the main function. The functions that the packages export to C
+	// by //export (see cl.CExportOf) are declared by a C header next to a C
+	// library. The libraries that a static library needs, eg. -lgc, are
+	// linked by the programs that link it.
+	BuildMode string
+
+	// DeadCodeElim compiles only the functions and global variables that are
+	// reachable from the main function, the init functions and the exported
+	// members of the packages specified by patterns (see cl.Reachable).
+	DeadCodeElim bool
+
+	// OptLevel is the optimization level of the LLVM IR of packages and of
+	// the code generated from it. Passes, if any, is an LLVM pass pipeline
+	// (see llssa.Package.Optimize) run after the one of OptLevel.
+	OptLevel OptLevel
+	Passes   string
+
+	// Work keeps the temporary directory of the build, as go build -work
+	// does, and prints its path and the clang commands that compile and link
+	// the files in it: the LLVM IR file of every package that isn't in the
+	// build cache, the object files that the packages are compiled to before
+	// they are linked, and the C files that llgo generates.
+	Work bool
+
+	// TrimPath removes the directories of the source files from the
+	// programs and libraries that are built, as go build -trimpath does (see
+	// cl.Config.TrimPath), so that the same source builds the same binary
+	// wherever it is.
+	TrimPath bool
+
+	CacheDir     string // directory of the build cache (empty means DefaultCacheDir(), "off" disables it)
+	ForceRebuild bool   // rebuild packages even if they are in the build cache
+	NeedMain     bool   // report an error unless patterns specify a single main package
+}
+
+// OutputKind is the kind of output of a build.
+type OutputKind int
+
+const (
+	OutputExecutable OutputKind = iota // executable, or archive of a non-main package
+	OutputObject                       // object file (.o)
+	OutputAssembly                     // assembly (.s)
+	OutputLLVMIR                       // LLVM IR text (.ll)
+	OutputBitcode                      // LLVM bitcode (.bc)
+)
+
+var outputKinds = [...]struct{ name, ext string }{
+	OutputExecutable: {"exe", ""},
+	OutputObject:     {"obj", ".o"},
+	OutputAssembly:   {"asm", ".s"},
+	OutputLLVMIR:     {"llvm", ".ll"},
+	OutputBitcode:    {"bc", ".bc"},
+}
+
+// ParseOutputKind returns the output kind of name: "exe", "obj", "asm", "llvm"
+// or "bc".
+func ParseOutputKind(name string) (OutputKind, error) {
+	for kind, v := range outputKinds {
+		if v.name == name {
+			return OutputKind(kind), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown output kind %q", name)
+}
+
+func (k OutputKind) String() string {
+	return outputKinds[k].name
+}
+
+// Link-time optimization modes, see Config.LTO.
+const (
+	LTOOff  = "off"
+	LTOThin = "thin"
+	LTOFull = "full"
+)
+
+// ltoFlags returns the clang flags that link an executable with the LTO mode
+// of conf. LTO needs lld, which is also the linker of cross builds.
+func ltoFlags(conf *Config) ([]string, error) {
+	switch conf.LTO {
+	case "", LTOOff:
+		return nil, nil
+	case LTOThin, LTOFull:
+		flags := []string{"-flto=" + conf.LTO}
+		if !isWasm(conf) {
+			flags = append(flags, "-fuse-ld=lld")
+		}
+		return flags, nil
+	}
+	return nil, fmt.Errorf("unknown LTO mode %q", conf.LTO)
+}
+
+// relocModel returns the relocation model of conf (see Config.RelocModel).
+// It defaults to the one of conf.Target, or to the one of its build mode or
+// of Android, which only runs position-independent executables.
+func relocModel(conf *Config) string {
+	reloc := conf.RelocModel
+	if reloc == "" && conf.Target != nil {
+		reloc = conf.Target.RelocModel
+	}
+	if reloc == "" && (conf.BuildMode == BuildModeCShared || conf.BuildMode == BuildModePlugin) {
+		reloc = llssa.RelocPIC
+	}
+	if reloc == "" && targetGOOS(conf) == "android" {
+		reloc = llssa.RelocPIE
+	}
+	return reloc
+}
+
+// checkRelocModel reports an error if the relocation model of conf isn't
+// supported by its target, build mode or garbage collector.
+func checkRelocModel(conf *Config) error {
+	switch reloc := relocModel(conf); reloc {
+	case "":
+		return nil
+	case llssa.RelocStatic, llssa.RelocPIC, llssa.RelocPIE:
+		if isWasm(conf) {
+			return fmt.Errorf("relocation model %s isn't supported for WebAssembly targets", reloc)
+		}
+		if lib, _ := isLibrary(conf); lib && conf.BuildMode != BuildModeCArchive && reloc != llssa.RelocPIC {
+			return fmt.Errorf("build mode %s needs relocation model %s", conf.BuildMode, llssa.RelocPIC)
+		}
+		if gc, _ := gcOf(conf); gc == GCPrecise && reloc == llssa.RelocPIE {
+			return errors.New("the precise garbage collector doesn't support position-independent executables")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown relocation model %q", relocModel(conf))
+}
+
+// relocFlags returns the clang flags that compile code, and the ones that
+// link an executable, with the relocation model of conf.
+func relocFlags(conf *Config) (compile, link []string) {
+	reloc := relocModel(conf)
+	switch reloc {
+	case llssa.RelocStatic:
+		compile, link = []string{"-fno-pic"}, []string{"-no-pie"}
+	case llssa.RelocPIC:
+		compile = []string{"-fPIC"}
+	case llssa.RelocPIE:
+		compile, link = []string{"-fPIE"}, []string{"-pie"}
+	}
+	return platformRelocFlags(conf, reloc, compile, link)
+}
+
+// checkSanitizer reports an error if the sanitizer or the race detection of
+// conf isn't supported by its target or garbage collector.
+func checkSanitizer(conf *Config) error {
+	if conf.Race {
+		if isWasm(conf) || conf.Baremetal {
+			return errors.New("race detection isn't supported for WebAssembly or baremetal targets")
+		}
+		if gc, _ := gcOf(conf); gc == GCPrecise {
+			return errors.New("the precise garbage collector doesn't support race detection")
+		}
+		if conf.Sanitizer != "" && conf.Sanitizer != llssa.SanitizeThread {
+			return fmt.Errorf("race detection isn't compatible with sanitizer %s", conf.Sanitizer)
+		}
+	}
+	switch conf.Sanitizer {
+	case "":
+		return nil
+	case llssa.SanitizeAddress, llssa.SanitizeThread, llssa.SanitizeMemory:
+		if isWasm(conf) || conf.Baremetal {
+			return fmt.Errorf("sanitizer %s isn't supported for WebAssembly or baremetal targets", conf.Sanitizer)
+		}
+		if gc, _ := gcOf(conf); gc == GCPrecise {
+			return fmt.Errorf("the precise garbage collector doesn't support sanitizer %s", conf.Sanitizer)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown sanitizer %q", conf.Sanitizer)
+}
+
+// Garbage collectors, see Config.GC.
+const (
+	GCBoehm   = "boehm"   // conservative collector bdwgc
+	GCPrecise = "precise" // collector with precise stack roots, see preciseGC
+	GCNone    = "none"    // no heap: allocating memory is a fatal error
+	GCLeaking = "leaking" // memory is allocated by malloc, and never freed
+)
+
+// gcStrategy is the LLVM GC strategy of functions with GCPrecise.
+const gcStrategy = "statepoint-example"
+
+// gcOf returns the garbage collector of conf.
+func gcOf(conf *Config) (string, error) {
+	switch conf.GC {
+	case "":
+		if conf.Baremetal || isWasm(conf) {
+			return GCLeaking, nil
+		}
+		return GCBoehm, nil
+	case GCBoehm, GCNone, GCLeaking:
+		return conf.GC, nil
+	case GCPrecise:
+		t := conf.target()
+		if orDefault(t.GOOS, runtime.GOOS) != "linux" || orDefault(t.GOARCH, runtime.GOARCH) != "amd64" || conf.Baremetal {
+			return "", errors.New("the precise garbage collector only supports linux/amd64")
+		}
+		return conf.GC, nil
+	}
+	return "", fmt.Errorf("unknown garbage collector %q", conf.GC)
+}
+
+// gcLink returns the files and the clang flags that link the garbage
+// collector of conf. Files are written to workDir.
+func gcLink(conf *Config, workDir string) (files, flags []string, err error) {
+	switch gc, _ := gcOf(conf); gc {
+	case GCBoehm:
+		flags = []string{"-lgc"}
+	case GCPrecise:
+		return preciseGC(workDir)
+	}
+	return
+}
+
+// inlineRuntime writes the runtime functions of pkgs that are inlined at all
+// their call sites (see llssa.InlineBitcode) to a bitcode file in workDir, and
+// returns the clang flags that link it to the module of each package. It
+// returns no flags if the packages aren't optimized, if LTO, which inlines
+// across modules itself, is enabled, or with the precise garbage collector,
+// whose statepoints are already inserted.
+func inlineRuntime(conf *Config, pkgs []*aPackage, workDir string) ([]string, error) {
+	if conf.OptLevel == OptNone || (conf.LTO != "" && conf.LTO != LTOOff) {
+		return nil, nil
+	}
+	if gc, _ := gcOf(conf); gc == GCPrecise {
+		return nil, nil
+	}
+	var files []string
+	for _, p := range pkgs {
+		if p.PkgPath == llssa.PkgRuntime || strings.HasPrefix(p.PkgPath, llssa.PkgRuntime+"/") {
+			files = append(files, p.llFile)
+		}
+	}
+	bc := filepath.Join(workDir, "llgo_runtime.bc")
+	if ok, err := llssa.InlineBitcode(files, bc); !ok {
+		return nil, err
+	}
+	return []string{"-Xclang", "-mlink-builtin-bitcode", "-Xclang", bc}, nil
+}
+
+// OptLevel is an optimization level, as the -O flags of clang specify.
+type OptLevel int
+
+const (
+	OptNone    OptLevel = iota // -O0: no optimization
+	OptLess                    // -O1
+	OptDefault                 // -O2
+	OptMore                    // -O3
+	OptSize                    // -Os: optimize for size
+	OptMinSize                 // -Oz: optimize for size aggressively
+)
+
+var optLevels = [...]string{"0", "1", "2", "3", "s", "z"}
+
+// ParseOptLevel returns the optimization level of name: "0", "1", "2", "3",
+// "s" or "z".
+func ParseOptLevel(name string) (OptLevel, error) {
+	for level, v := range optLevels {
+		if v == name {
+			return OptLevel(level), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown optimization level %q", name)
+}
+
+func (l OptLevel) String() string {
+	return "O" + optLevels[l]
+}
+
+// pipeline returns the LLVM pass pipeline of l, which is empty for OptNone.
+func (l OptLevel) pipeline() string {
+	if l == OptNone {
+		return ""
+	}
+	return "default<" + l.String() + ">"
+}
+
+// ReadOverlay reads the overlay of Config.Overlay from file, a JSON file in the
+// format of the -overlay flag of the go command:
+//
+//	{"Replace": {"/path/a.go": "/tmp/a.go", "b.go": "/tmp/b.go"}}
+//
+// Each file of Replace is replaced by the contents of the file it maps to.
+// Relative paths are relative to the current directory. Files can't be
+// deleted, ie. mapped to "", since the overlay of packages doesn't support it.
+func ReadOverlay(file string) (map[string][]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var overlay struct {
+		Replace map[string]string
+	}
+	if err = json.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("parsing overlay %s: %w", file, err)
+	}
+	ret := make(map[string][]byte, len(overlay.Replace))
+	for from, to := range overlay.Replace {
+		if to == "" {
+			return nil, fmt.Errorf("overlay %s: deleting %s is not supported", file, from)
+		}
+		if from, err = filepath.Abs(from); err != nil {
+			return nil, err
+		}
+		if ret[from], err = os.ReadFile(to); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax |
+	packages.NeedTypesInfo | packages.NeedTypesSizes
+
+// Do builds the packages specified by patterns, and the packages they depend
+// on. If patterns specify a single main package, it is linked to an
+// executable. If they specify a single non-main package and conf.Output is a
+// file, it is archived together with its dependencies to conf.Output.
+// Otherwise the compiled packages are discarded, as go build does.
+//
+// As with go build -o, if conf.Output is an existing directory or ends with a
+// slash, the executable is written to that directory under its default name.
+func Do(patterns []string, conf *Config) error {
+	initial, cgos, err := load(conf, patterns, false)
+	if err != nil {
+		return err
+	}
+	lib, err := isLibrary(conf)
+	if err != nil {
+		return err
+	}
+	if err = checkRelocModel(conf); err != nil {
+		return err
+	}
+	if err = checkSanitizer(conf); err != nil {
+		return err
+	}
+	if err = checkHardening(conf); err != nil {
+		return err
+	}
+	if err = checkStatic(conf, initial); err != nil {
+		return err
+	}
+	if err = checkPlatform(conf); err != nil {
+		return err
+	}
+	if err = checkCPU(conf); err != nil {
+		return err
+	}
+	if conf.NeedMain || lib {
+		if len(initial) != 1 {
+			return fmt.Errorf("patterns %v specify %d packages, want a single main package", patterns, len(initial))
+		}
+		if initial[0].Name != "main" {
+			return fmt.Errorf("package %s is not a main package", initial[0].PkgPath)
+		}
+	}
+	workDir, clean, err := makeWorkDir(conf, "llgo-build-")
+	if err != nil {
+		return err
+	}
+	defer clean()
+
+	conf = compileConf(conf, lib)
+	c, err := newCache(conf)
+	if err != nil {
+		return err
+	}
+	pkgs, needRuntime, err := buildAll(initial, cgos, workDir, c, conf, nil)
+	if err != nil {
+		return err
+	}
+	if len(initial) != 1 {
+		return nil
+	}
+	output, outDir := conf.Output, isDir(conf.Output)
+	if conf.OutputKind != OutputExecutable {
+		if output == "" {
+			output = defaultOutput(conf, initial[0])
+		} else if outDir {
+			output = filepath.Join(output, defaultOutput(conf, initial[0]))
+		}
+		return emit(conf, output, pkgOf(pkgs, initial[0]))
+	}
+	if initial[0].Name != "main" {
+		if output == "" || outDir {
+			return nil
+		}
+		return archive(conf, output, pkgs, workDir)
+	}
+	var files, flags []string
+	if needRuntime {
+		if pkgs, err = appendRuntime(pkgs, workDir, c, conf); err != nil {
+			return err
+		}
+		if files, flags, err = gcLink(conf, workDir); err != nil {
+			return err
+		}
+		inline, err := inlineRuntime(conf, pkgs, workDir)
+		if err != nil {
+			return err
+		}
+		flags = append(flags, inline...)
+	}
+	pkgFiles, err := linkedFiles(conf, pkgs, workDir)
+	if err != nil {
+		return err
+	}
+	files = append(append(pkgFiles, files...), cgoLink(pkgs)...)
+	if output == "" {
+		output = defaultOutput(conf, initial[0])
+	} else if outDir {
+		output = filepath.Join(output, defaultOutput(conf, initial[0]))
+	}
+	if lib {
+		return library(conf, output, initial[0].PkgPath, pkgs, files, flags, workDir)
+	}
+	if isWasm(conf) {
+		wasm, err := wasmFlags(conf)
+		if err != nil {
+			return err
+		}
+		flags = append(flags, wasm...)
+		if conf.WasmExecModel == WasmReactor {
+			initFile, err := reactorInit(initial[0].PkgPath, workDir)
+			if err != nil {
+				return err
+			}
+			files = append(files, initFile)
+		}
+	}
+	if conf.Baremetal {
+		startup, err := baremetalStartup(initial[0].PkgPath, workDir)
+		if err != nil {
+			return err
+		}
+		files = append(files, startup)
+		flags = append(flags, baremetalFlags(conf)...)
+	}
+	return link(conf, output, files, flags...)
+}
+
+// load loads the packages specified by patterns, and their test variants if
+// tests is true. Packages that use cgo are translated (see loadCgo).
+func load(conf *Config, patterns []string, tests bool) ([]*packages.Package, cgoPkgs, error) {
+	gc, err := gcOf(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags := []string{"gc." + gc}
+	if conf.Baremetal {
+		tags = append(tags, "baremetal")
+	}
+	if conf.Race {
+		tags = append(tags, "race")
+	}
+	if conf.Hardening {
+		tags = append(tags, "hardening")
+	}
+	tags = append(tags, conf.Tags...)
+	cfg := &packages.Config{
+		Mode: loadMode, Dir: conf.Dir, Env: conf.loadEnv(), Tests: tests,
+		BuildFlags: []string{"-tags=" + strings.Join(tags, ",")},
+	}
+	if conf.ModFlag != "" {
+		cfg.BuildFlags = append(cfg.BuildFlags, "-mod="+conf.ModFlag)
+	}
+	initial, cgos, err := loadCgo(cfg, conf, patterns, tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	var errs []packages.Error
+	packages.Visit(initial, nil, func(p *packages.Package) {
+		errs = append(errs, p.Errors...)
+		conf.diagnoseLoad(p.PkgPath, p.Errors)
+	})
+	switch len(errs) {
+	case 0:
+		return initial, cgos, nil
+	case 1:
+		return nil, nil, errs[0]
+	default:
+		return nil, nil, fmt.Errorf("%v (and %d more errors)", errs[0], len(errs)-1)
+	}
+}
+
+// aPackage is a package that has been compiled to the LLVM IR file llFile.
+// The C files of a package that uses cgo are compiled to objFiles, with
+// cflags. ldflags are the flags of the C libraries it is linked with (see
+// llgoLinkFlags). cflags and ldflags include the ones of its pragmas (see
+// pkgMeta).
+type aPackage struct {
+	*packages.Package
+	llFile   string
+	objFiles []string
+	cflags   []string
+	ldflags  []string
+	partial  *cl.PartialError // functions that failed to compile, in ContinueOnError mode
+}
+
+// buildAll compiles initial packages and their dependencies to LLVM IR files.
+// Packages are compiled concurrently, each in its own llssa.Program since an
+// LLVM context can't be shared between threads, but pkgs is always in
+// dependency order. A package found in the build cache isn't compiled again:
+// its LLVM IR file is the one in the cache. Packages in replaced, which are
+// implemented by other means, packages implemented by the runtime (see
+// cl.ImplementedByRuntime) and their dependencies aren't compiled. The C files
+// of the packages in cgos are compiled too.
+func buildAll(initial []*packages.Package, cgos cgoPkgs, workDir string, c *cache, conf *Config, replaced map[string]bool) (pkgs []*aPackage, needRuntime bool, err error) {
+	ssaProg, _ := ssautil.AllPackages(initial, ssa.InstantiateGenerics)
+	var reach *cl.Reachable
+	if conf.DeadCodeElim {
+		reach = reachable(ssaProg, initial)
+		var clConf cl.Config
+		if conf.Conf != nil {
+			clConf = *conf.Conf
+		}
+		clConf.Reachable = reach
+		dceConf := *conf
+		dceConf.Conf = &clConf
+		conf = &dceConf
+	}
+
+	var keys []string
+	skip := func(p *packages.Package) bool {
+		return replaced[p.PkgPath] || cl.ImplementedByRuntime(p.PkgPath)
+	}
+	packages.Visit(initial, func(p *packages.Package) bool {
+		return !skip(p)
+	}, func(p *packages.Package) {
+		if err != nil {
+			return
+		}
+		var members []string
+		if reach != nil && p.Types != nil {
+			members = reachableMembers(reach, ssaProg.Package(p.Types))
+		}
+		var key string
+		if key, err = c.key(p, members, cgos[p.PkgPath]); err == nil && needBuild(p) && !skip(p) {
+			pkgs = append(pkgs, &aPackage{Package: p})
+			keys = append(keys, key)
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	errs := make([]error, len(pkgs))
+	rts := make([]bool, len(pkgs))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, p := range pkgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p *aPackage) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if rts[i], errs[i] = buildPkg(ssaProg, p, keys[i], workDir, c, conf); errs[i] == nil {
+				var ldflags []string
+				ldflags, errs[i] = llgoLinkFlags(p.Package)
+				p.ldflags = append(p.ldflags, ldflags...)
+			}
+			if errs[i] == nil && cgos[p.PkgPath] != nil {
+				errs[i] = compileCgo(conf, p, cgos[p.PkgPath], workDir)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+	for i, e := range errs {
+		if e == nil && pkgs[i].partial != nil {
+			conf.warnPartial(pkgs[i].PkgPath, pkgs[i].partial)
+		}
+		if e != nil {
+			conf.diagnoseBuild(pkgs[i].PkgPath, e)
+			if err == nil {
+				err = e
+			}
+		}
+		needRuntime = needRuntime || rts[i]
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return
+}
+
+// reachable returns the package members reachable from the main function,
+// the init functions of packages, the functions exported to C or to the
+// WebAssembly host (see cl.IsExport) and the exported members of the initial
+// packages, which are used by a library or called by a test binary. It builds
+// the functions of all packages to find what they refer to.
+func reachable(ssaProg *ssa.Program, initial []*packages.Package) *cl.Reachable {
+	ssaProg.Build()
+	r := cl.NewReachable()
+	for _, pkg := range ssaProg.AllPackages() {
+		if fn := pkg.Func("init"); fn != nil {
+			r.Add(fn)
+		}
+		for _, m := range pkg.Members {
+			if fn, ok := m.(*ssa.Function); ok && cl.IsExport(fn) {
+				r.Add(fn)
+			}
+		}
+	}
+	for _, p := range initial {
+		pkg := ssaProg.Package(p.Types)
+		if pkg == nil {
+			continue
+		}
+		if fn := pkg.Func("main"); fn != nil && p.Name == "main" {
+			r.Add(fn)
+		}
+		for name, m := range pkg.Members {
+			if token.IsExported(name) {
+				r.Add(m)
+			}
+		}
+	}
+	return r
+}
+
+// reachableMembers returns the sorted names of the reachable members and
+// methods of pkg, which determine what is compiled of it.
+func reachableMembers(r *cl.Reachable, pkg *ssa.Package) []string {
+	if pkg == nil {
+		return nil
+	}
+	var names []string
+	for name, m := range pkg.Members {
+		if r.Has(m) {
+			names = append(names, name)
+		}
+	}
+	for _, fn := range cl.Methods(pkg) {
+		if r.Has(fn) {
+			names = append(names, fn.String())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildPkg compiles package p to an LLVM IR file, and sets p.llFile to it.
+func buildPkg(ssaProg *ssa.Program, p *aPackage, key, workDir string, c *cache, conf *Config) (needRuntime bool, err error) {
+	if llFile, meta, ok := c.get(key); ok {
+		p.llFile = llFile
+		return meta.needRuntime, p.setMeta(&meta)
+	}
+	ssaPkg := ssaProg.Package(p.Types)
+	ssaPkg.Build()
+	clConf := conf.Conf
+	if conf.Cover && p.PkgPath == conf.coverPkg {
+		cover := cl.Config{}
+		if clConf != nil {
+			cover = *clConf
+		}
+		cover.Cover = true
+		clConf = &cover
+	}
+	ret, err := cl.NewPackageEx(newProgram(conf), ssaPkg, p.Syntax, clConf)
+	if errors.As(err, &p.partial) {
+		err = nil // the failed functions trap when called, see warnPartial
+	} else if err != nil {
+		return false, fmt.Errorf("compiling %s: %w", p.PkgPath, err)
+	}
+	pipelines := []string{conf.OptLevel.pipeline(), conf.Passes}
+	if gc, _ := gcOf(conf); gc == GCPrecise {
+		// statepoints must be inserted after optimization, which they hinder
+		pipelines = append(pipelines, "rewrite-statepoints-for-gc")
+	}
+	for _, pipeline := range pipelines {
+		if pipeline == "" {
+			continue
+		}
+		if err = ret.Optimize(pipeline); err != nil {
+			return false, fmt.Errorf("optimizing %s: %w", p.PkgPath, err)
+		}
+	}
+	ll := []byte(ret.String())
+	llFile := filepath.Join(workDir, strings.ReplaceAll(p.PkgPath, "/", "_")+".ll")
+	if err = os.WriteFile(llFile, ll, 0644); err != nil {
+		return
+	}
+	meta := &pkgMeta{needRuntime: ret.NeedRuntime(), cflags: ret.CFlags(), ldflags: ret.LDFlags(), pkgConfig: ret.PkgConfig()}
+	if p.partial == nil { // not cached, so that every build reports the failed functions
+		if err = c.put(key, ll, meta); err != nil {
+			return
+		}
+	}
+	p.llFile = llFile
+	return meta.needRuntime, p.setMeta(meta)
+}
+
+// setMeta sets the flags of the C libraries of p to the ones of its meta m,
+// and the ones that pkg-config reports for its pkg-config packages, which
+// aren't cached as they depend on the system rather than on the source.
+func (p *aPackage) setMeta(m *pkgMeta) error {
+	cflags, ldflags, err := cgo.PkgConfig(m.pkgConfig)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.PkgPath, err)
+	}
+	p.cflags = append(m.cflags[:len(m.cflags):len(m.cflags)], cflags...)
+	p.ldflags = append(m.ldflags[:len(m.ldflags):len(m.ldflags)], ldflags...)
+	return nil
+}
+
+// compileConf returns conf, or a copy of it whose cl.Config compiles the main
+// package as the one of a library if lib is set (see cl.Config.NoMain),
+// reports memory accesses if conf.Race is set, and emits the function tables
+// of tracebacks (see cl.Config.Traceback) unless the target is WebAssembly or
+// baremetal, or a sanitizer, whose runtime reports the faults itself, is
+// enabled, and trims the paths of the source files if conf.TrimPath is set.
+func compileConf(conf *Config, lib bool) *Config {
+	traceback := !isWasm(conf) && !conf.Baremetal && conf.Sanitizer == "" && !conf.Race
+	if !lib && !conf.Race && !traceback && !conf.TrimPath {
+		return conf
+	}
+	var clConf cl.Config
+	if conf.Conf != nil {
+		clConf = *conf.Conf
+	}
+	if lib {
+		clConf.NoMain = true
+	}
+	if conf.Race {
+		clConf.Race = true
+	}
+	if traceback {
+		clConf.Traceback = true
+	}
+	if conf.TrimPath {
+		clConf.TrimPath = true
+	}
+	ret := *conf
+	ret.Conf = &clConf
+	return &ret
+}
+
+var initLLVM sync.Once
+
+// newProgram creates an llssa.Program for the target of conf.
+func newProgram(conf *Config) llssa.Program {
+	initLLVM.Do(func() { llssa.Initialize(llssa.InitAll) })
+	prog := llssa.NewProgram(conf.target())
+	if gc, _ := gcOf(conf); gc == GCPrecise {
+		prog.SetGC(gcStrategy)
+	}
+	prog.SetSanitizer(conf.Sanitizer)
+	prog.SetHardening(conf.Hardening)
+	prog.SetLTO(conf.LTO != "" && conf.LTO != LTOOff)
+	return prog
+}
+
+// target returns the platform for which packages are built.
+func (conf *Config) target() *llssa.Target {
+	t := conf.Target
+	if t == nil {
+		t = &llssa.Target{GOOS: conf.getenv("GOOS"), GOARCH: conf.getenv("GOARCH"), GOARM: conf.getenv("GOARM")}
+	}
+	if reloc := relocModel(conf); reloc != t.RelocModel {
+		ret := *t
+		ret.RelocModel = reloc
+		t = &ret
+	}
+	return withCPU(conf, t)
+}
+
+func (conf *Config) getenv(key string) string {
+	if conf.Env == nil {
+		return os.Getenv(key)
+	}
+	prefix := key + "="
+	for i := len(conf.Env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(conf.Env[i], prefix) {
+			return conf.Env[i][len(prefix):]
+		}
+	}
+	return ""
+}
+
+// vendored reports whether the dependencies of the main module are loaded
+// from its vendor directory, by the -mod flag of conf or of GOFLAGS, or by
+// default.
+func (conf *Config) vendored() bool {
+	flag := conf.ModFlag
+	if flag == "" {
+		for _, f := range strings.Fields(conf.getenv("GOFLAGS")) {
+			if f = strings.TrimLeft(f, "-"); strings.HasPrefix(f, "mod=") {
+				flag = f[len("mod="):]
+			}
+		}
+	}
+	if flag != "" {
+		return flag == "vendor"
+	}
+	m, _, err := mod.Load(orDefault(conf.Dir, "."))
+	return err == nil && mod.Vendored(m)
+}
+
+// loadEnv returns the environment of the go command that loads packages. cgo
+// is disabled, as packages that use it are translated by llgo (see loadCgo).
+func (conf *Config) loadEnv() []string {
+	env := conf.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append(env[:len(env):len(env)], "CGO_ENABLED=0")
+	t := conf.Target
+	if t == nil || (t.GOOS == "" && t.GOARCH == "") {
+		return env
+	}
+	env = append(env, "GOOS="+orDefault(t.GOOS, runtime.GOOS), "GOARCH="+orDefault(t.GOARCH, runtime.GOARCH))
+	if t.GOARM != "" {
+		env = append(env, "GOARM="+t.GOARM)
+	}
+	return env
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// clangFlags returns the clang flags that select the target of conf, and the
+// optimization level of the code generated for it. Clang targets the host by
+// default, so no target flag is needed unless cross compiling, in which case
+// executables are linked by lld, as the host linker may not support the
+// target. WebAssembly modules are always linked by wasm-ld, with the WASI
+// sysroot by default (see wasiSysroot), and macOS executables by ld64, with
+// the SDK of $SDKROOT by default (see linkerOf). Android and iOS targets have
+// the sysroot of their NDK or SDK by default (see mobileSysroot). The CPU of
+// the target is selected if it isn't the default one (see cpuFlags).
+func clangFlags(conf *Config) []string {
+	var flags []string
+	spec := conf.target().Spec()
+	wasm := isWasm(conf)
+	musl := muslSysroot(conf)
+	if musl != "" {
+		spec.Triple = muslTriple(spec.Triple)
+	}
+	if spec.Triple != (&llssa.Target{}).Spec().Triple {
+		flags = append(flags, "--target="+spec.Triple)
+		if !wasm {
+			flags = append(flags, "-fuse-ld=lld")
+		}
+	}
+	flags = append(flags, cpuFlags(conf, spec)...)
+	compile, _ := relocFlags(conf)
+	flags = append(flags, compile...)
+	if san := conf.Sanitizer; san != "" || conf.Race {
+		// It instruments C code, and links the runtime of the sanitizer.
+		if san == "" {
+			san = llssa.SanitizeThread
+		}
+		flags = append(flags, "-fsanitize="+san)
+	}
+	flags = append(flags, hardeningFlags(conf)...)
+	if conf.OptLevel != OptNone {
+		flags = append(flags, "-"+conf.OptLevel.String())
+	}
+	sysroot := conf.Sysroot
+	if sysroot == "" && wasm {
+		sysroot = wasiSysroot()
+	} else if sysroot == "" {
+		sysroot = mobileSysroot(conf)
+	}
+	if sysroot == "" && linkerOf(conf) == linkerDarwin {
+		sysroot = conf.getenv("SDKROOT")
+	} else if sysroot == "" {
+		sysroot = musl
+	}
+	if sysroot != "" {
+		flags = append(flags, "--sysroot="+sysroot)
+	}
+	return flags
+}
+
+// appendRuntime appends the packages of the llgo runtime to pkgs. The runtime
+// isn't in the vendor directory of a module, as its packages don't import it,
+// so if the dependencies are vendored, it's loaded from the module cache.
+func appendRuntime(pkgs []*aPackage, workDir string, c *cache, conf *Config) ([]*aPackage, error) {
+	rtConf := conf
+	if conf.vendored() {
+		rtConf = new(Config)
+		*rtConf = *conf
+		rtConf.ModFlag = "mod"
+	}
+	rt, _, err := load(rtConf, []string{llssa.PkgRuntime}, false)
+	if err != nil {
+		return nil, fmt.Errorf("loading llgo runtime: %w", err)
+	}
+	rtPkgs, _, err := buildAll(rt, nil, workDir, c, conf, nil)
+	if err != nil {
+		return nil, err
+	}
+	return appendNew(pkgs, rtPkgs), nil
+}
+
+func needBuild(p *packages.Package) bool {
+	switch p.PkgPath {
+	case "unsafe":
+		return false
+	}
+	return p.Types != nil
+}
+
+// appendNew appends packages of more that aren't in pkgs yet.
+func appendNew(pkgs, more []*aPackage) []*aPackage {
+	has := make(map[string]bool, len(pkgs))
+	for _, p := range pkgs {
+		has[p.PkgPath] = true
+	}
+	for _, p := range more {
+		if !has[p.PkgPath] {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
+
+func isDir(output string) bool {
+	if output == "" {
+		return false
+	}
+	if strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(filepath.Separator)) {
+		return true
+	}
+	fi, err := os.Stat(output)
+	return err == nil && fi.IsDir()
+}
+
+// defaultOutput returns the name of the executable of main package pkg. As
+// go build does, it ignores the major version suffix of the import path, so
+// the executable of example.com/cmd/v2 is cmd. A WebAssembly module has the
+// .wasm extension, a Windows executable the .exe one, and a C library the one
+// of its build mode, eg. .a.
+func defaultOutput(conf *Config, pkg *packages.Package) string {
+	var ext string
+	if isWasm(conf) {
+		ext = ".wasm"
+	} else if lib, _ := isLibrary(conf); lib {
+		ext = libraryExt(conf)
+	} else {
+		ext = exeExt(conf)
+	}
+	pkgPath := pkg.PkgPath
+	if pkgPath == "command-line-arguments" && len(pkg.GoFiles) > 0 {
+		return strings.TrimSuffix(filepath.Base(pkg.GoFiles[0]), ".go") + ext
+	}
+	name := path.Base(pkgPath)
+	if name != pkgPath && isMajorVersion(name) {
+		name = path.Base(path.Dir(pkgPath))
+	}
+	return name + ext
+}
+
+// isMajorVersion reports whether elem is a major version suffix like v2.
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' || elem == "v1" {
+		return false
+	}
+	for _, c := range elem[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func llFiles(pkgs []*aPackage) []string {
+	files := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		files[i] = p.llFile
+	}
+	return files
+}
+
+// link links LLVM IR files, and other source files that clang accepts, to the
+// executable output for the target of conf. flags are passed to clang too.
+func link(conf *Config, output string, files []string, flags ...string) error {
+	lto, err := ltoFlags(conf)
+	if err != nil {
+		return err
+	}
+	_, reloc := relocFlags(conf)
+	args := append(clangFlags(conf), "-o", output, "-Wno-override-module")
+	args = append(args, reloc...)
+	args = append(args, staticFlags(conf)...)
+	args = append(args, platformFlags(conf)...)
+	args = append(args, mobileFlags(conf)...)
+	args = append(args, lto...)
+	args = append(args, flags...)
+	args = append(args, files...)
+	return execClang(conf, args...)
+}
+
+// pkgOf returns the compiled package of p.
+func pkgOf(pkgs []*aPackage, p *packages.Package) *aPackage {
+	for _, pkg := range pkgs {
+		if pkg.Package == p {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// emit writes package p, in the output kind of conf, to output with the
+// extension of the kind.
+func emit(conf *Config, output string, p *aPackage) error {
+	if p == nil {
+		return nil // nothing to emit, eg. for package unsafe
+	}
+	kind := conf.OutputKind
+	output = strings.TrimSuffix(output, filepath.Ext(output)) + outputKinds[kind].ext
+	var mode []string
+	switch kind {
+	case OutputLLVMIR:
+		ll, err := os.ReadFile(p.llFile)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(output, ll, 0644)
+	case OutputObject:
+		mode = []string{"-c"}
+	case OutputAssembly:
+		mode = []string{"-S"}
+	case OutputBitcode:
+		mode = []string{"-c", "-emit-llvm"}
+	default:
+		return fmt.Errorf("unknown output kind %d", kind)
+	}
+	args := append(clangFlags(conf), mode...)
+	args = append(args, "-o", output, "-Wno-override-module", p.llFile)
+	return execClang(conf, args...)
+}
+
+// archive compiles the packages, and the C files srcs, to object files in
+// workDir and archives them to output.
+func archive(conf *Config, output string, pkgs []*aPackage, workDir string, srcs ...string) error {
+	objs, err := objectFiles(conf, pkgs, workDir)
+	if err != nil {
+		return err
+	}
+	flags := clangFlags(conf)
+	for _, p := range pkgs {
+		objs = append(objs, p.objFiles...)
+	}
+	for _, src := range srcs {
+		obj := strings.TrimSuffix(src, filepath.Ext(src)) + ".o"
+		args := append(flags[:len(flags):len(flags)], "-c", "-o", obj, src)
+		if err := execClang(conf, args...); err != nil {
+			return fmt.Errorf("compiling %s: %w", src, err)
+		}
+		objs = append(objs, obj)
+	}
+	return ar.Create(output, objs)
+}
+
+// objectFiles compiles the LLVM IR files of pkgs to object files in workDir,
+// and returns them. flags are passed to clang too.
+func objectFiles(conf *Config, pkgs []*aPackage, workDir string, flags ...string) ([]string, error) {
+	flags = append(clangFlags(conf), flags...)
+	objs := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		objs[i] = filepath.Join(workDir, strings.ReplaceAll(p.PkgPath, "/", "_")+".o")
+		args := append(flags[:len(flags):len(flags)], "-c", "-o", objs[i], "-Wno-override-module", p.llFile)
+		if err := execClang(conf, args...); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+// linkedFiles returns the files of pkgs that are linked: their LLVM IR files,
+// which clang compiles to temporary object files, or with conf.Work, the
+// object files they are compiled to in workDir, which are kept. With LTO, the
+// object files are bitcode.
+func linkedFiles(conf *Config, pkgs []*aPackage, workDir string) ([]string, error) {
+	if !conf.Work {
+		return llFiles(pkgs), nil
+	}
+	lto, err := ltoFlags(conf)
+	if err != nil {
+		return nil, err
+	}
+	return objectFiles(conf, pkgs, workDir, lto...)
+}
+
+// makeWorkDir creates the temporary directory of a build, whose name starts
+// with pattern, and returns the function that removes it. With conf.Work, the
+// directory is kept, and its path is printed to os.Stderr.
+func makeWorkDir(conf *Config, pattern string) (dir string, clean func(), err error) {
+	if dir, err = os.MkdirTemp("", pattern); err != nil {
+		return
+	}
+	if conf.Work {
+		fmt.Fprintf(os.Stderr, "WORK=%s\n", dir)
+		return dir, func() {}, nil
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// execClang runs clang with args. With conf.Work, the command is printed to
+// os.Stderr first.
+func execClang(conf *Config, args ...string) error {
+	clang := llvm.New().Clang()
+	if conf.Work {
+		fmt.Fprintln(os.Stderr, "clang", strings.Join(args, " "))
+	}
+	return clang.Exec(args...)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/internal/cgo"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultCacheDir returns the default directory of the build cache. It is
+// $LLGOCACHE if set, or the llgo subdirectory of the user cache directory.
+func DefaultCacheDir() string {
+	if dir := os.Getenv("LLGOCACHE"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "off"
+	}
+	return filepath.Join(dir, "llgo")
+}
+
+// A cache is a content-addressed store of compiled packages. A package is
+// keyed by the hash of its source files, the keys of the packages it imports,
+// the compiler and the compile options, so an entry never becomes stale.
+type cache struct {
+	dir   string // empty if the cache is disabled
+	force bool   // don't look entries up, but still store them
+	salt  []byte
+	keys  map[*packages.Package]string
+
+	overlay map[string][]byte // see Config.Overlay
+}
+
+func newCache(conf *Config) (*cache, error) {
+	c := &cache{force: conf.ForceRebuild, keys: make(map[*packages.Package]string), overlay: conf.Overlay}
+	dir := conf.CacheDir
+	if dir == "" {
+		dir = DefaultCacheDir()
+	}
+	if dir == "off" {
+		return c, nil
+	}
+	id, err := compilerID()
+	if err != nil {
+		return nil, err
+	}
+	c.dir = dir
+	c.salt = cacheSalt(id, conf)
+	return c, nil
+}
+
+// cacheIgnored holds the fields of Config that aren't part of the keys of the
+// build cache, as they don't change the code that packages are compiled to:
+// the ones that locate or report the build, and the ones whose effects are
+// hashed otherwise, by the target that Env resolves to, or by the files of
+// packages that Overlay and ModFlag select.
+var cacheIgnored = map[string]bool{
+	"Dir":          true,
+	"Env":          true,
+	"Output":       true,
+	"Diagnostics":  true,
+	"Overlay":      true,
+	"ModFlag":      true,
+	"Work":         true,
+	"CacheDir":     true,
+	"ForceRebuild": true,
+	"NeedMain":     true,
+}
+
+// cacheSalt returns the salt of the keys of the build cache of conf: id, the
+// ID of the compiler, the target, the relocation model and the garbage
+// collector that conf resolves to, and its fields, but the ones of
+// cacheIgnored.
+func cacheSalt(id string, conf *Config) []byte {
+	var b bytes.Buffer
+	spec := conf.target().Spec()
+	gc, _ := gcOf(conf)
+	fmt.Fprintf(&b, "llgo %s %s %s %s reloc=%s gc=%s\n", id, spec.Triple, spec.CPU, spec.Features, relocModel(conf), gc)
+	v := reflect.ValueOf(conf).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if cacheIgnored[name] {
+			continue
+		}
+		f := v.Field(i)
+		if name == "Conf" {
+			var clConf cl.Config
+			if conf.Conf != nil {
+				clConf = *conf.Conf
+			}
+			clConf.Reachable = nil // see key
+			f = reflect.ValueOf(clConf)
+		} else if f.Kind() == reflect.Pointer && !f.IsNil() {
+			f = f.Elem()
+		}
+		fmt.Fprintf(&b, "%s=%+v\n", name, f)
+	}
+	return b.Bytes()
+}
+
+// key returns the cache key of package p. The keys of the packages that p
+// imports must have been computed. members are the names of the members of p
+// to be compiled, if dead code is eliminated: they depend on the packages
+// that import p, so they are part of the key. If p uses cgo, the files of its
+// translation cgoPkg are hashed from its overlay, and so are the files of the
+// overlay of the build.
+func (c *cache) key(p *packages.Package, members []string, cgoPkg *cgo.Package) (string, error) {
+	h := sha256.New()
+	h.Write(c.salt)
+	fmt.Fprintf(h, "pkg %s\n", p.PkgPath)
+	for _, name := range members {
+		fmt.Fprintf(h, "member %s\n", name)
+	}
+	for _, file := range p.CompiledGoFiles {
+		fmt.Fprintf(h, "file %s\n", filepath.Base(file))
+		if cgoPkg != nil {
+			if src, ok := cgoPkg.Overlay[file]; ok {
+				h.Write(src)
+				continue
+			}
+		}
+		if src, ok := c.overlay[file]; ok {
+			h.Write(src)
+			continue
+		}
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+	paths := make([]string, 0, len(p.Imports))
+	for path := range p.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(h, "import %s %s\n", path, c.keys[p.Imports[path]])
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	c.keys[p] = key
+	return key, nil
+}
+
+func (c *cache) file(key, ext string) string {
+	return filepath.Join(c.dir, key[:2], key+ext)
+}
+
+// pkgMeta is what a build needs to know of a compiled package besides its
+// LLVM IR: whether it needs the llgo runtime, and the flags and the
+// pkg-config packages of the C libraries it binds (see cl.initFlags). It is
+// stored in the meta file of the entry of the package, a line per item, eg.
+//
+//	runtime
+//	cflag -I/usr/include/foo
+//	ldflag -lfoo
+//	pkg-config sqlite3
+type pkgMeta struct {
+	needRuntime bool
+	cflags      []string
+	ldflags     []string
+	pkgConfig   []string
+}
+
+func (m *pkgMeta) encode() []byte {
+	var b strings.Builder
+	if m.needRuntime {
+		b.WriteString("runtime\n")
+	}
+	for _, flag := range m.cflags {
+		fmt.Fprintf(&b, "cflag %s\n", flag)
+	}
+	for _, flag := range m.ldflags {
+		fmt.Fprintf(&b, "ldflag %s\n", flag)
+	}
+	for _, pkg := range m.pkgConfig {
+		fmt.Fprintf(&b, "pkg-config %s\n", pkg)
+	}
+	return []byte(b.String())
+}
+
+func decodeMeta(data []byte) (m pkgMeta) {
+	for _, line := range strings.Split(string(data), "\n") {
+		kind, flag, _ := strings.Cut(line, " ")
+		switch kind {
+		case "runtime":
+			m.needRuntime = true
+		case "cflag":
+			m.cflags = append(m.cflags, flag)
+		case "ldflag":
+			m.ldflags = append(m.ldflags, flag)
+		case "pkg-config":
+			m.pkgConfig = append(m.pkgConfig, flag)
+		}
+	}
+	return
+}
+
+// get returns the LLVM IR file of the package with the given key, and its
+// meta. It reports false if there is no entry.
+func (c *cache) get(key string) (llFile string, meta pkgMeta, ok bool) {
+	if c.dir == "" || c.force {
+		return
+	}
+	data, err := os.ReadFile(c.file(key, ".meta"))
+	if err != nil {
+		return
+	}
+	llFile = c.file(key, ".ll")
+	if _, err = os.Stat(llFile); err != nil {
+		return
+	}
+	return llFile, decodeMeta(data), true
+}
+
+// put stores the LLVM IR of the package with the given key, and its meta. The
+// meta file is written last, so readers never see a partial entry.
+func (c *cache) put(key string, ll []byte, meta *pkgMeta) error {
+	if c.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file(key, "")), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.file(key, ".ll"), ll); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.file(key, ".meta"), meta.encode())
+}
+
+func writeFileAtomic(file string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func hashFile(h io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+var compilerIDOnce struct {
+	sync.Once
+	id  string
+	err error
+}
+
+// compilerID identifies the running compiler by the hash of its executable, so
+// that the entries of a different build of llgo are never used.
+func compilerID() (string, error) {
+	c := &compilerIDOnce
+	c.Do(func() {
+		var exe string
+		if exe, c.err = os.Executable(); c.err != nil {
+			return
+		}
+		h := sha256.New()
+		if c.err = hashFile(h, exe); c.err == nil {
+			c.id = hex.EncodeToString(h.Sum(nil))
+		}
+	})
+	return c.id, c.err
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/goplus/llgo/cl"
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// setNonZero sets f, which may be unexported, to a value other than its zero
+// value.
+func setNonZero(t *testing.T, f reflect.Value) {
+	f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+	switch f.Kind() {
+	case reflect.Bool:
+		f.SetBool(true)
+	case reflect.String:
+		f.SetString("x")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.SetUint(1)
+	case reflect.Slice:
+		elem := reflect.New(f.Type().Elem()).Elem()
+		setNonZero(t, elem)
+		f.Set(reflect.Append(f, elem))
+	case reflect.Map:
+		f.Set(reflect.MakeMap(f.Type()))
+		f.SetMapIndex(reflect.New(f.Type().Key()).Elem(), reflect.New(f.Type().Elem()).Elem())
+	case reflect.Pointer:
+		f.Set(reflect.New(f.Type().Elem()))
+		setNonZero(t, f.Elem().Field(0))
+	default:
+		t.Fatalf("can't set a field of type %v", f.Type())
+	}
+}
+
+func TestCacheSalt(t *testing.T) {
+	target := &llssa.Target{GOOS: "linux", GOARCH: "amd64"}
+	base := string(cacheSalt("id", &Config{Target: target}))
+	if string(cacheSalt("id2", &Config{Target: target})) == base {
+		t.Fatal("TestCacheSalt: the compiler doesn't change the salt")
+	}
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Name
+		conf := &Config{Target: target}
+		if name == "Target" {
+			conf.Target = nil
+		}
+		if name == "Diagnostics" {
+			continue // an interface
+		}
+		setNonZero(t, reflect.ValueOf(conf).Elem().Field(i))
+		if changed := string(cacheSalt("id", conf)) != base; changed == cacheIgnored[name] {
+			t.Errorf("TestCacheSalt: %s changes the salt: %v", name, changed)
+		}
+	}
+	// the fields of cl.Config, but Reachable
+	typ = reflect.TypeOf(cl.Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		conf := &Config{Target: target, Conf: new(cl.Config)}
+		setNonZero(t, reflect.ValueOf(conf.Conf).Elem().Field(i))
+		name := typ.Field(i).Name
+		if changed := string(cacheSalt("id", conf)) != base; changed == (name == "Reachable") {
+			t.Errorf("TestCacheSalt: cl.Config.%s changes the salt: %v", name, changed)
+		}
+	}
+}
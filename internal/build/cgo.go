@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/goplus/llgo/internal/cgo"
+	"github.com/goplus/llgo/x/env/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// cgoPkgs are the packages that use cgo, translated by cgo.Translate, by
+// package path.
+type cgoPkgs map[string]*cgo.Package
+
+// loadCgo loads the packages specified by patterns with cfg, in which cgo is
+// disabled (see loadEnv), so that the go command doesn't run cmd/cgo. The
+// packages whose files import "C" are translated, and loaded again with the
+// overlay of their translation, until no package that uses cgo is left.
+//
+// Go files specified by patterns that import "C" are translated before they
+// are loaded, since the go command rejects them with cgo disabled.
+func loadCgo(cfg *packages.Config, conf *Config, patterns []string, tags []string) ([]*packages.Package, cgoPkgs, error) {
+	cgoConf := cgoConfig(conf, tags)
+	cgos := make(cgoPkgs)
+	var goFiles, cFiles []string
+	for _, pattern := range patterns {
+		if !strings.HasSuffix(pattern, ".go") {
+			continue
+		}
+		file := pattern
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(conf.Dir, file)
+		}
+		if cgoConf.ImportsC(file) {
+			cFiles = append(cFiles, file)
+		} else {
+			goFiles = append(goFiles, file)
+		}
+	}
+	if cFiles != nil {
+		pkg, err := cgo.Translate("command-line-arguments", goFiles, cFiles, cgoConf)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pkg != nil {
+			cgos["command-line-arguments"] = pkg
+			patterns = cgoPatterns(patterns, conf.Dir, pkg, cgoConf)
+		}
+	}
+	for {
+		cfg.Overlay = cgos.overlay(conf.Overlay)
+		initial, err := packages.Load(cfg, patterns...)
+		if err != nil {
+			return nil, nil, err
+		}
+		more := false
+		packages.Visit(initial, nil, func(p *packages.Package) {
+			if err != nil || cgos[p.PkgPath] != nil || len(p.IgnoredFiles) == 0 {
+				return
+			}
+			var pkg *cgo.Package
+			if pkg, err = cgo.Translate(p.PkgPath, p.GoFiles, p.IgnoredFiles, cgoConf); pkg != nil {
+				cgos[p.PkgPath] = pkg
+				more = true
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if !more {
+			return initial, cgos, nil
+		}
+	}
+}
+
+// cgoPatterns returns patterns with the Go files of the translation pkg in
+// place of the files it translates or excludes.
+func cgoPatterns(patterns []string, dir string, pkg *cgo.Package, conf *cgo.Config) (ret []string) {
+	for _, pattern := range patterns {
+		file := pattern
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if _, ok := pkg.Overlay[file]; !ok && !conf.ImportsC(file) {
+			ret = append(ret, pattern)
+		}
+	}
+	return append(ret, pkg.GoFiles...)
+}
+
+// cgoConfig returns the configuration of translating cgo packages for the
+// target of conf, loaded with the build tags tags.
+func cgoConfig(conf *Config, tags []string) *cgo.Config {
+	t := conf.target()
+	ret := &cgo.Config{
+		GOOS:   orDefault(t.GOOS, runtime.GOOS),
+		GOARCH: orDefault(t.GOARCH, runtime.GOARCH),
+		Tags:   tags,
+		Clang:  llvm.New().Clang(),
+
+		Overlay: conf.Overlay,
+	}
+	for _, flag := range clangFlags(conf) {
+		if strings.HasPrefix(flag, "--target=") || strings.HasPrefix(flag, "--sysroot=") {
+			ret.Flags = append(ret.Flags, flag)
+		}
+	}
+	return ret
+}
+
+// overlay returns the overlay of the Go files of the translated packages, on
+// top of base.
+func (cgos cgoPkgs) overlay(base map[string][]byte) map[string][]byte {
+	if len(cgos) == 0 {
+		return base
+	}
+	overlay := make(map[string][]byte)
+	for file, src := range base {
+		overlay[file] = src
+	}
+	for _, pkg := range cgos {
+		for file, src := range pkg.Overlay {
+			overlay[file] = src
+		}
+	}
+	return overlay
+}
+
+// compileCgo compiles the C files of the translated package pkg, which p is
+// compiled from, to object files in workDir.
+func compileCgo(conf *Config, p *aPackage, pkg *cgo.Package, workDir string) error {
+	prefix := filepath.Join(workDir, strings.ReplaceAll(p.PkgPath, "/", "_")+"_")
+	var srcs []string
+	for name, src := range pkg.GenCFiles {
+		file := prefix + name
+		if err := os.WriteFile(file, src, 0644); err != nil {
+			return err
+		}
+		srcs = append(srcs, file)
+	}
+	sort.Strings(srcs)
+	flags := append(append(clangFlags(conf), pkg.CFlags...), p.cflags...)
+	for _, src := range append(srcs, pkg.CFiles...) {
+		obj := prefix + strings.TrimSuffix(filepath.Base(src), ".c") + ".o"
+		args := append(flags[:len(flags):len(flags)], "-c", "-o", obj, src)
+		if err := execClang(conf, args...); err != nil {
+			return fmt.Errorf("compiling %s: %w", src, err)
+		}
+		p.objFiles = append(p.objFiles, obj)
+	}
+	p.ldflags = append(p.ldflags, pkg.LDFlags...)
+	return nil
+}
+
+// cgoLink returns the object files of the C files of pkgs, and the flags of
+// their #cgo LDFLAGS directives, which are passed to the linker after them.
+func cgoLink(pkgs []*aPackage) (files []string) {
+	for _, p := range pkgs {
+		files = append(files, p.objFiles...)
+	}
+	for _, p := range pkgs {
+		files = append(files, p.ldflags...)
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"github.com/goplus/llgo/x/env/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// The code of packages is generated for the CPU of their target, with its
+// features, eg. "+avx2", by which LLVM selects the instructions that it
+// generates, and vectorizes loops. Config.CPUModel and Config.Features select
+// them, as the -mcpu and -mattr flags of llc do: the former overrides the CPU
+// of the target, and the latter adds to, or removes from, its features. C code
+// is compiled for them too (see clangFlags).
+//
+// The float ABI of ARM targets is selected by Config.FloatABI, eg. the soft one
+// of Cortex-M0 class microcontrollers, which have no FPU: floating-point
+// numbers are then computed by the functions of compiler-rt, which baremetal
+// executables are linked with (see baremetalFlags).
+
+// CPUNative is the Config.CPUModel of the CPU of the host, with its features,
+// as clang detects them for -march=native. It can't be the one of a target
+// other than the host.
+const CPUNative = "native"
+
+// checkCPU reports an error if conf builds for the CPU of the host, but the
+// target isn't the host, or the CPU of the host can't be detected, or if the
+// float ABI of conf isn't one of an ARM target.
+func checkCPU(conf *Config) error {
+	switch abi := conf.FloatABI; abi {
+	case "", llssa.FloatABIHard, llssa.FloatABISoft, llssa.FloatABISoftFP:
+		if abi != "" && !llssa.IsARM(conf.target().Spec().Triple) {
+			return fmt.Errorf("float ABI %s is supported by ARM targets only", abi)
+		}
+	default:
+		return fmt.Errorf("unknown float ABI %q, want hard, soft or softfp", abi)
+	}
+	if conf.CPUModel != CPUNative {
+		return nil
+	}
+	if conf.Baremetal || crossCompiling(conf) {
+		return errors.New("the CPU of the host can't be the one of another target")
+	}
+	if cpu, _ := hostCPU(); cpu == "" {
+		return errors.New("the CPU of the host isn't detected by clang")
+	}
+	return nil
+}
+
+// crossCompiling reports whether the target of conf isn't the host.
+func crossCompiling(conf *Config) bool {
+	t := conf.Target
+	if t == nil {
+		t = &llssa.Target{GOOS: conf.getenv("GOOS"), GOARCH: conf.getenv("GOARCH"), GOARM: conf.getenv("GOARM")}
+	}
+	return t.Spec().Triple != (&llssa.Target{}).Spec().Triple
+}
+
+var (
+	hostCPUOnce     sync.Once
+	hostCPUName     string
+	hostCPUFeatures string
+)
+
+// hostCPU returns the CPU of the host and its features, or "" if clang doesn't
+// detect it.
+func hostCPU() (cpu, features string) {
+	hostCPUOnce.Do(func() {
+		cpu, features, err := llvm.New().Clang().HostCPU()
+		if err == nil {
+			hostCPUName, hostCPUFeatures = cpu, strings.Join(features, ",")
+		}
+	})
+	return hostCPUName, hostCPUFeatures
+}
+
+// withCPU returns t with the CPU, the features and the float ABI selected by
+// conf, if any.
+func withCPU(conf *Config, t *llssa.Target) *llssa.Target {
+	if conf.FloatABI != "" && conf.FloatABI != t.FloatABI {
+		ret := *t
+		ret.FloatABI = conf.FloatABI
+		t = &ret
+	}
+	cpu, features := conf.CPUModel, conf.Features
+	if cpu == "" && features == "" {
+		return t
+	}
+	spec := t.Spec()
+	if cpu == CPUNative {
+		// The features of the host replace the default ones of the target.
+		cpu, spec.Features = hostCPU()
+	}
+	ret := *t
+	if cpu != "" {
+		ret.CPU = cpu
+	}
+	// The last of the features that are repeated wins.
+	ret.Features = joinFeatures(spec.Features, features)
+	return &ret
+}
+
+func joinFeatures(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "," + b
+}
+
+// cpuFlags returns the clang flags that select the CPU of spec, the target of
+// conf, and its features: they are passed unless it's the default CPU of the
+// host.
+func cpuFlags(conf *Config, spec llssa.TargetSpec) (flags []string) {
+	selected := conf.CPUModel != "" || conf.Features != ""
+	if spec.CPU != "" && spec.CPU != "generic" && (selected || crossCompiling(conf)) {
+		// -mcpu isn't supported for x86, whose -march also selects the CPU.
+		if strings.HasPrefix(spec.Triple, "x86_64-") || strings.HasPrefix(spec.Triple, "i386-") {
+			flags = append(flags, "-march="+spec.CPU)
+		} else {
+			flags = append(flags, "-mcpu="+spec.CPU)
+		}
+	}
+	if selected && spec.Features != "" {
+		for _, f := range strings.Split(spec.Features, ",") {
+			flags = append(flags, "-Xclang", "-target-feature", "-Xclang", f)
+		}
+	}
+	if conf.FloatABI != "" {
+		flags = append(flags, "-mfloat-abi="+conf.FloatABI)
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
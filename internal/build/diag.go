@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/goplus/llgo/cl"
+)
+
+// -----------------------------------------------------------------------------
+
+// Diagnostic is an error of loading or compiling a package, which is written
+// to Config.Diagnostics as a JSON record on a line, eg.
+//
+//	{"package":"example.com/foo","file":"/src/foo/foo.go","line":12,"column":3,"message":"..."}
+//
+// File, Line and Column are omitted if the error has no position.
+type Diagnostic struct {
+	Package string `json:"package,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// diagnoseLoad writes the errors of loading package pkgPath to
+// conf.Diagnostics, if any.
+func (conf *Config) diagnoseLoad(pkgPath string, errs []packages.Error) {
+	if conf.Diagnostics == nil {
+		return
+	}
+	for _, e := range errs {
+		d := Diagnostic{Package: pkgPath, Message: e.Msg}
+		d.File, d.Line, d.Column = splitPos(e.Pos)
+		conf.diagnose(&d)
+	}
+}
+
+// diagnoseBuild writes err, the error of compiling package pkgPath, to
+// conf.Diagnostics, if any: a record per function that fails to compile (see
+// cl.ErrorList), or a record without position for another error.
+func (conf *Config) diagnoseBuild(pkgPath string, err error) {
+	if conf.Diagnostics == nil {
+		return
+	}
+	var list cl.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		conf.diagnose(&Diagnostic{Package: pkgPath, Message: err.Error()})
+		return
+	}
+	for _, e := range list {
+		conf.diagnose(&Diagnostic{
+			Package: pkgPath,
+			File:    e.Pos.Filename, Line: e.Pos.Line, Column: e.Pos.Column,
+			Message: e.Msg,
+		})
+	}
+}
+
+// warnPartial reports e, the functions of package pkgPath that failed to
+// compile in ContinueOnError mode, which are linked as stubs that trap when
+// called: as a warning to os.Stderr, and to conf.Diagnostics, if any.
+func (conf *Config) warnPartial(pkgPath string, e *cl.PartialError) {
+	fmt.Fprintf(os.Stderr, "warning: %s: %v\n", pkgPath, e)
+	conf.diagnoseBuild(pkgPath, e)
+}
+
+func (conf *Config) diagnose(d *Diagnostic) {
+	json.NewEncoder(conf.Diagnostics).Encode(d)
+}
+
+// splitPos splits pos, the position of a packages.Error, file:line:column or
+// file:line, or file, or "" or "-" if unknown.
+func splitPos(pos string) (file string, line, column int) {
+	if pos == "" || pos == "-" {
+		return
+	}
+	file = pos
+	var nums []int
+	for i := 0; i < 2; i++ {
+		j := strings.LastIndexByte(file, ':')
+		if j < 0 {
+			break
+		}
+		n, err := strconv.Atoi(file[j+1:])
+		if err != nil {
+			break
+		}
+		file, nums = file[:j], append([]int{n}, nums...)
+	}
+	switch len(nums) {
+	case 2:
+		line, column = nums[0], nums[1]
+	case 1:
+		line = nums[0]
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,417 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// -----------------------------------------------------------------------------
+
+// preciseGC writes the precise collector and the linker script that gathers
+// its stack maps to workDir, and returns the files and the clang flags that
+// link them. Frame pointers are kept, as the collector walks the stack by
+// them, and executables aren't position independent, so that the addresses
+// of functions in stack maps are resolved by the linker.
+func preciseGC(workDir string) (files, flags []string, err error) {
+	collector := filepath.Join(workDir, "_gc.c")
+	if err = os.WriteFile(collector, []byte(preciseCollector), 0644); err != nil {
+		return
+	}
+	script := filepath.Join(workDir, "_stackmaps.ld")
+	if err = os.WriteFile(script, []byte(stackMapsScript), 0644); err != nil {
+		return
+	}
+	flags = []string{"-fno-omit-frame-pointer", "-no-pie", "-Wl,-T," + script}
+	return []string{collector}, flags, nil
+}
+
+// stackMapsScript is the linker script that places the .llvm_stackmaps
+// sections of all objects between __llgo_stackmaps_start and
+// __llgo_stackmaps_end. It augments the default script of the linker.
+const stackMapsScript = `SECTIONS {
+	.llvm_stackmaps : {
+		__llgo_stackmaps_start = .;
+		KEEP(*(.llvm_stackmaps))
+		__llgo_stackmaps_end = .;
+	}
+}
+INSERT AFTER .rodata;
+`
+
+// preciseCollector is the C source of the precise collector of GCPrecise, a
+// non-moving mark-sweep collector. Stack roots are precise: they are found by
+// the stack maps of the statepoints that rewrite-statepoints-for-gc inserts
+// at the calls of llgo functions. llgo doesn't describe the layout of types
+// yet, so global variables and heap objects are scanned conservatively.
+//
+// Only the stack of the calling thread is scanned, and frames are walked by
+// frame pointers as the x86-64 ABI lays them out, so it supports single
+// threaded programs for linux/amd64.
+//
+// The finalizers of unreachable objects are called at the end of the
+// collection, by the allocation that triggered it. As in Go, an object that
+// is reachable from another object with a finalizer is finalized by a later
+// collection, after the other one.
+const preciseCollector = `#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+
+// The stack maps that LLVM emits for statepoints, in version 3 of the format
+// (see https://llvm.org/docs/StackMaps.html). The .llvm_stackmaps sections
+// of all objects are concatenated by stackMapsScript.
+typedef struct {
+	uint8_t version;
+	uint8_t reserved0;
+	uint16_t reserved1;
+	uint32_t numFunctions;
+	uint32_t numConstants;
+	uint32_t numRecords;
+} smHeader;
+
+typedef struct {
+	uint64_t addr;
+	uint64_t stackSize;
+	uint64_t numRecords;
+} smFunction;
+
+typedef struct {
+	uint8_t kind;
+	uint8_t reserved0;
+	uint16_t size;
+	uint16_t reg;
+	uint16_t reserved1;
+	int32_t offset;
+} smLocation;
+
+typedef struct {
+	uint64_t id;
+	uint32_t offset;
+	uint16_t reserved;
+	uint16_t numLocations;
+	smLocation locations[];
+} smRecord;
+
+enum { locRegister = 1, locDirect, locIndirect, locConstant, locConstantIndex };
+
+// DWARF register numbers of x86-64.
+enum { regRBP = 6, regRSP = 7 };
+
+extern const char __llgo_stackmaps_start[], __llgo_stackmaps_end[];
+extern char __data_start[], _end[];
+extern void *__libc_stack_end;
+
+// A call site is a statepoint, identified by its return address.
+typedef struct {
+	uintptr_t ret;
+	const smRecord *rec;
+} callSite;
+
+static callSite *sites;
+static size_t nsites;
+
+// Every object is preceded by a header. Objects are allocated by calloc, and
+// listed in objs, which is sorted by address when collecting.
+typedef struct {
+	size_t size;
+	size_t marked;
+} objHeader;
+
+static objHeader **objs;
+static size_t nobjs, capObjs;
+static uintptr_t heapLo, heapHi;
+
+static objHeader **work;
+static size_t nwork, capWork;
+
+// The finalizers of objects, which are called with the object and arg, eg.
+// the func value of a Go finalizer, which is kept alive.
+typedef struct {
+	objHeader *obj;
+	void (*fn)(void *, void *);
+	void *arg;
+} finalizer;
+
+static finalizer *fins;
+static size_t nfins, capFins;
+
+// finalizing is set while finalizers are called, which don't trigger
+// collections, so that the objects of the ones to call aren't freed.
+static int finalizing;
+
+#define MIN_THRESHOLD (4 << 20)
+
+static size_t allocated, threshold = MIN_THRESHOLD;
+
+static void *grow(void *p, size_t *cap, size_t elemSize) {
+	*cap = *cap ? *cap * 2 : 1024;
+	p = realloc(p, *cap * elemSize);
+	if (p == NULL) {
+		fprintf(stderr, "fatal error: out of memory\n");
+		abort();
+	}
+	return p;
+}
+
+static const char *align8(const char *p) {
+	return (const char *)(((uintptr_t)p + 7) & ~(uintptr_t)7);
+}
+
+static int cmpSite(const void *a, const void *b) {
+	uintptr_t x = ((const callSite *)a)->ret, y = ((const callSite *)b)->ret;
+	return x < y ? -1 : x > y;
+}
+
+static void loadStackMaps(void) {
+	size_t capSites = 0;
+	const char *p = __llgo_stackmaps_start;
+	while (p < __llgo_stackmaps_end) {
+		const smHeader *h = (const smHeader *)p;
+		if (h->version != 3) {
+			fprintf(stderr, "fatal error: unsupported stack map version %d\n", h->version);
+			abort();
+		}
+		const smFunction *fns = (const smFunction *)(h + 1);
+		p = (const char *)(fns + h->numFunctions) + h->numConstants * sizeof(uint64_t);
+		for (uint32_t i = 0; i < h->numFunctions; i++) {
+			for (uint64_t j = 0; j < fns[i].numRecords; j++) {
+				const smRecord *rec = (const smRecord *)p;
+				if (nsites == capSites) {
+					sites = grow(sites, &capSites, sizeof(callSite));
+				}
+				sites[nsites].ret = fns[i].addr + rec->offset;
+				sites[nsites].rec = rec;
+				nsites++;
+				// Skip the locations, and the live-outs, which aren't used.
+				p = align8((const char *)(rec->locations + rec->numLocations));
+				uint16_t numLiveOuts = ((const uint16_t *)p)[1];
+				p = align8(p + 2 * sizeof(uint16_t) + numLiveOuts * sizeof(uint32_t));
+			}
+		}
+	}
+	qsort(sites, nsites, sizeof(callSite), cmpSite);
+}
+
+static const smRecord *findSite(uintptr_t ret) {
+	size_t lo = 0, hi = nsites;
+	while (lo < hi) {
+		size_t mid = (lo + hi) / 2;
+		if (sites[mid].ret < ret) {
+			lo = mid + 1;
+		} else {
+			hi = mid;
+		}
+	}
+	return lo < nsites && sites[lo].ret == ret ? sites[lo].rec : NULL;
+}
+
+static int cmpObj(const void *a, const void *b) {
+	uintptr_t x = (uintptr_t)*(objHeader *const *)a, y = (uintptr_t)*(objHeader *const *)b;
+	return x < y ? -1 : x > y;
+}
+
+// mark marks the object that p points into, if any.
+static void mark(uintptr_t p) {
+	if (p < heapLo || p > heapHi) {
+		return;
+	}
+	size_t lo = 0, hi = nobjs;
+	while (lo < hi) {
+		size_t mid = (lo + hi) / 2;
+		if ((uintptr_t)(objs[mid] + 1) <= p) {
+			lo = mid + 1;
+		} else {
+			hi = mid;
+		}
+	}
+	if (lo == 0) {
+		return;
+	}
+	objHeader *obj = objs[lo - 1];
+	if (p > (uintptr_t)(obj + 1) + obj->size || obj->marked) {
+		return;
+	}
+	obj->marked = 1;
+	if (nwork == capWork) {
+		work = grow(work, &capWork, sizeof(objHeader *));
+	}
+	work[nwork++] = obj;
+}
+
+// scan marks the objects that the words in [lo, hi) may point to.
+static void scan(const char *lo, const char *hi) {
+	for (const uintptr_t *p = (const uintptr_t *)align8(lo); (const char *)(p + 1) <= hi; p++) {
+		mark(*p);
+	}
+}
+
+// drain marks the objects that the marked objects refer to, transitively.
+static void drain(void) {
+	while (nwork > 0) {
+		objHeader *obj = work[--nwork];
+		scan((const char *)(obj + 1), (const char *)(obj + 1) + obj->size);
+	}
+}
+
+// finalize marks the objects with finalizers that are unreachable, but not
+// from other ones, and moves their finalizers to ready. It returns the number
+// of ready finalizers.
+static size_t finalize(finalizer **ready) {
+	for (size_t i = 0; i < nfins; i++) {
+		objHeader *obj = fins[i].obj;
+		if (!obj->marked) {
+			scan((const char *)(obj + 1), (const char *)(obj + 1) + obj->size);
+			drain();
+		}
+	}
+	size_t n = 0, capReady = 0;
+	for (size_t i = 0; i < nfins;) {
+		objHeader *obj = fins[i].obj;
+		if (obj->marked) {
+			i++;
+			continue;
+		}
+		obj->marked = 1;
+		if (n == capReady) {
+			*ready = grow(*ready, &capReady, sizeof(finalizer));
+		}
+		(*ready)[n++] = fins[i];
+		fins[i] = fins[--nfins];
+	}
+	return n;
+}
+
+// markStack marks the objects that the frames of llgo functions on the stack
+// refer to, as described by the stack maps of their call sites. Frames are
+// walked by frame pointers, up to the bottom of the stack of the main thread.
+static void __attribute__((noinline)) markStack(void) {
+	uintptr_t *fp = __builtin_frame_address(0);
+	while (fp != NULL && (void *)fp < __libc_stack_end && ((uintptr_t)fp & 7) == 0) {
+		uintptr_t *callerFP = (uintptr_t *)fp[0];
+		const smRecord *rec = findSite(fp[1]);
+		for (uint16_t i = 0; rec != NULL && i < rec->numLocations; i++) {
+			// Live references are spilled to the stack by statepoints, so
+			// they are all indirect locations, relative to the stack pointer
+			// or the frame pointer of the caller at the call site.
+			const smLocation *loc = &rec->locations[i];
+			const char *base = NULL;
+			if (loc->kind == locIndirect && loc->reg == regRSP) {
+				base = (const char *)(fp + 2);
+			} else if (loc->kind == locIndirect && loc->reg == regRBP) {
+				base = (const char *)callerFP;
+			}
+			if (base != NULL) {
+				mark(*(const uintptr_t *)(base + loc->offset));
+			}
+		}
+		if (callerFP <= fp) {
+			break;
+		}
+		fp = callerFP;
+	}
+}
+
+static void collect(void) {
+	if (sites == NULL) {
+		loadStackMaps();
+	}
+	qsort(objs, nobjs, sizeof(objHeader *), cmpObj);
+	heapLo = UINTPTR_MAX;
+	heapHi = 0;
+	for (size_t i = 0; i < nobjs; i++) {
+		uintptr_t lo = (uintptr_t)(objs[i] + 1), hi = lo + objs[i]->size;
+		heapLo = lo < heapLo ? lo : heapLo;
+		heapHi = hi > heapHi ? hi : heapHi;
+	}
+	markStack();
+	scan(__data_start, _end);
+	for (size_t i = 0; i < nfins; i++) {
+		mark((uintptr_t)fins[i].arg);
+	}
+	drain();
+	finalizer *ready = NULL;
+	size_t nready = finalize(&ready);
+	size_t n = 0, live = 0;
+	for (size_t i = 0; i < nobjs; i++) {
+		objHeader *obj = objs[i];
+		if (obj->marked) {
+			obj->marked = 0;
+			objs[n++] = obj;
+			live += obj->size;
+		} else {
+			free(obj);
+		}
+	}
+	nobjs = n;
+	allocated = 0;
+	threshold = live > MIN_THRESHOLD ? live : MIN_THRESHOLD;
+	finalizing = 1;
+	for (size_t i = 0; i < nready; i++) {
+		ready[i].fn(ready[i].obj + 1, ready[i].arg);
+	}
+	finalizing = 0;
+	free(ready);
+}
+
+void *llgo_gc_alloc(size_t size) {
+	if (allocated >= threshold && !finalizing) {
+		collect();
+	}
+	objHeader *obj = calloc(1, sizeof(objHeader) + size);
+	if (obj == NULL) {
+		fprintf(stderr, "fatal error: out of memory\n");
+		abort();
+	}
+	obj->size = size;
+	if (nobjs == capObjs) {
+		objs = grow(objs, &capObjs, sizeof(objHeader *));
+	}
+	objs[nobjs++] = obj;
+	allocated += size;
+	return obj + 1;
+}
+
+// llgo_gc_set_finalizer sets the finalizer of the object p, which fn is
+// called with p and arg, or clears it if fn is NULL.
+void llgo_gc_set_finalizer(void *p, void (*fn)(void *, void *), void *arg) {
+	objHeader *obj = (objHeader *)p - 1;
+	for (size_t i = 0; i < nfins; i++) {
+		if (fins[i].obj == obj) {
+			if (fn == NULL) {
+				fins[i] = fins[--nfins];
+			} else {
+				fins[i].fn = fn;
+				fins[i].arg = arg;
+			}
+			return;
+		}
+	}
+	if (fn == NULL) {
+		return;
+	}
+	if (nfins == capFins) {
+		fins = grow(fins, &capFins, sizeof(finalizer));
+	}
+	fins[nfins].obj = obj;
+	fins[nfins].fn = fn;
+	fins[nfins].arg = arg;
+	nfins++;
+}
+`
+
+// -----------------------------------------------------------------------------
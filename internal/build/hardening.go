@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// With Config.Hardening, programs are hardened against the exploitation of
+// memory errors, as the hardened toolchains of Linux distributions harden C:
+// the functions of packages have stack protectors and the control-flow
+// integrity of the target, if it has one (see llssa.Program.SetHardening), C
+// code is compiled with the same, and with _FORTIFY_SOURCE, and the llgo
+// runtime is loaded with the build tag hardening, with which the memory
+// functions that it calls check their arguments.
+
+// checkHardening reports an error if conf hardens a program for a target
+// whose libc has no stack protectors.
+func checkHardening(conf *Config) error {
+	if conf.Hardening && (conf.Baremetal || isWasm(conf)) {
+		return errors.New("hardening isn't supported for WebAssembly or baremetal targets")
+	}
+	return nil
+}
+
+// hardeningFlags returns the clang flags that harden the C code of conf.
+// _FORTIFY_SOURCE needs the optimizations by which clang knows the sizes of
+// objects.
+func hardeningFlags(conf *Config) []string {
+	if !conf.Hardening {
+		return nil
+	}
+	flags := []string{"-fstack-protector-strong"}
+	if conf.OptLevel != OptNone {
+		flags = append(flags, "-D_FORTIFY_SOURCE=2")
+	}
+	switch triple := conf.target().Spec().Triple; {
+	case strings.HasPrefix(triple, "x86_64-") || strings.HasPrefix(triple, "i386-"):
+		flags = append(flags, "-fcf-protection=full")
+	case strings.HasPrefix(triple, "aarch64-") || strings.HasPrefix(triple, "arm64-"):
+		flags = append(flags, "-mbranch-protection=standard")
+	}
+	return flags
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,317 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/goplus/llgo/cl"
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// Build modes, see Config.BuildMode.
+const (
+	BuildModeExe      = "exe"
+	BuildModeCArchive = "c-archive"
+	BuildModeCShared  = "c-shared"
+	BuildModePlugin   = "plugin"
+)
+
+// isLibrary reports whether the main package is built to a C library or a
+// plugin by the build mode of conf.
+func isLibrary(conf *Config) (bool, error) {
+	switch conf.BuildMode {
+	case "", BuildModeExe:
+		return false, nil
+	case BuildModeCArchive, BuildModeCShared, BuildModePlugin:
+		if isWasm(conf) || conf.Baremetal {
+			return false, fmt.Errorf("build mode %s isn't supported for WebAssembly or baremetal targets", conf.BuildMode)
+		}
+		if gc, _ := gcOf(conf); gc == GCPrecise {
+			return false, fmt.Errorf("the precise garbage collector doesn't support build mode %s", conf.BuildMode)
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown build mode %q", conf.BuildMode)
+}
+
+// libraryExt returns the extension of the library of the build mode of conf.
+func libraryExt(conf *Config) string {
+	switch conf.BuildMode {
+	case BuildModeCArchive:
+		if linkerOf(conf) == linkerMSVC {
+			return ".lib"
+		}
+		return ".a"
+	case BuildModePlugin:
+		return ".so"
+	}
+	switch orDefault(conf.target().GOOS, runtime.GOOS) {
+	case "darwin", "ios":
+		return ".dylib"
+	case "windows":
+		return ".dll"
+	}
+	return ".so"
+}
+
+// library links the main package pkgPath to the library output, from pkgs,
+// or from the files and flags that would link an executable of it for a
+// shared library. The header of the functions that pkgs export to C is
+// written next to a C library, eg. libfoo.h for libfoo.a (see cExports).
+func library(conf *Config, output, pkgPath string, pkgs []*aPackage, files, flags []string, workDir string) error {
+	var code []byte
+	if conf.BuildMode == BuildModePlugin {
+		code = pluginFile(pkgPath)
+	} else {
+		exports, err := cExports(pkgs)
+		if err != nil {
+			return err
+		}
+		header := exportHeader(pkgPath, exports)
+		if err = os.WriteFile(strings.TrimSuffix(output, filepath.Ext(output))+".h", header, 0644); err != nil {
+			return err
+		}
+		code = exportFile(pkgPath, header, exports)
+	}
+	src := filepath.Join(workDir, "_export.c")
+	if err := os.WriteFile(src, code, 0644); err != nil {
+		return err
+	}
+	if conf.BuildMode == BuildModeCArchive {
+		// The libraries that the archive needs are linked by the program that
+		// links it, eg. with -lgc.
+		return archive(conf, output, pkgs, workDir, src)
+	}
+	flags = append(flags, "-shared")
+	return link(conf, output, append(files, src), append(flags, sharedFlags(conf, output)...)...)
+}
+
+// cExport is a function that a package exports to C (see cl.CExportOf).
+type cExport struct {
+	name string           // its C name
+	sym  string           // the symbol of the Go function
+	sig  *types.Signature // its Go signature
+}
+
+// cExports returns the functions that pkgs export to C, in the order of their
+// declarations. Only functions whose parameters and result have C types (see
+// cTypeOf) can be exported.
+func cExports(pkgs []*aPackage) ([]cExport, error) {
+	var exports []cExport
+	names := make(map[string]string)
+	for _, p := range pkgs {
+		for _, file := range p.Syntax {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				name, ok := cl.CExportOf(decl)
+				if !ok {
+					continue
+				}
+				pos := p.Fset.Position(decl.Pos())
+				if decl.Recv != nil {
+					return nil, fmt.Errorf("%v: cannot export method %s to C", pos, decl.Name.Name)
+				}
+				if prev, ok := names[name]; ok {
+					return nil, fmt.Errorf("%v: %s is already exported to C by %s", pos, name, prev)
+				}
+				names[name] = llssa.Mangle(p.PkgPath, decl.Name.Name)
+				sig := p.TypesInfo.Defs[decl.Name].Type().(*types.Signature)
+				if err := checkCSig(sig); err != nil {
+					return nil, fmt.Errorf("%v: cannot export %s to C: %w", pos, decl.Name.Name, err)
+				}
+				exports = append(exports, cExport{name, llssa.Mangle(p.PkgPath, decl.Name.Name), sig})
+			}
+		}
+	}
+	return exports, nil
+}
+
+// checkCSig reports an error unless the parameters and the result of sig
+// have C types.
+func checkCSig(sig *types.Signature) error {
+	if sig.Variadic() {
+		return fmt.Errorf("variadic functions aren't supported")
+	}
+	if sig.Results().Len() > 1 {
+		return fmt.Errorf("multiple results aren't supported")
+	}
+	for _, tuple := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tuple.Len(); i++ {
+			if _, err := cTypeOf(tuple.At(i).Type()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cTypes are the C types of the basic Go types, declared by exportHeader.
+var cTypes = map[types.BasicKind]string{
+	types.Bool:          "GoBool",
+	types.Int:           "GoInt",
+	types.Int8:          "GoInt8",
+	types.Int16:         "GoInt16",
+	types.Int32:         "GoInt32",
+	types.Int64:         "GoInt64",
+	types.Uint:          "GoUint",
+	types.Uint8:         "GoUint8",
+	types.Uint16:        "GoUint16",
+	types.Uint32:        "GoUint32",
+	types.Uint64:        "GoUint64",
+	types.Uintptr:       "GoUintptr",
+	types.Float32:       "GoFloat32",
+	types.Float64:       "GoFloat64",
+	types.String:        "GoString",
+	types.UnsafePointer: "void*",
+}
+
+// cTypeOf returns the C type of the Go type t, which is passed to and from C
+// as is: a basic type, or a pointer, which is void* in C.
+func cTypeOf(t types.Type) (string, error) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if ct, ok := cTypes[u.Kind()]; ok {
+			return ct, nil
+		}
+	case *types.Pointer:
+		return "void*", nil
+	}
+	return "", fmt.Errorf("type %v has no C type", t)
+}
+
+// cDecl returns the C declaration of the function name of signature sig, with
+// the parameters named p0, p1, etc.
+func cDecl(name string, sig *types.Signature) string {
+	ret := "void"
+	if sig.Results().Len() == 1 {
+		ret, _ = cTypeOf(sig.Results().At(0).Type())
+	}
+	params := make([]string, sig.Params().Len())
+	for i := range params {
+		t, _ := cTypeOf(sig.Params().At(i).Type())
+		params[i] = fmt.Sprintf("%s p%d", t, i)
+	}
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+	return fmt.Sprintf("%s %s(%s)", ret, name, strings.Join(params, ", "))
+}
+
+// exportHeader returns the C header of the functions that the main package
+// pkgPath and its dependencies export.
+func exportHeader(pkgPath string, exports []cExport) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "/* Code generated by llgo from package %s. DO NOT EDIT. */\n\n", pkgPath)
+	b.WriteString(exportTypes)
+	b.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, e := range exports {
+		fmt.Fprintf(&b, "extern %s;\n", cDecl(e.name, e.sig))
+	}
+	b.WriteString("\n#ifdef __cplusplus\n}\n#endif\n")
+	return b.Bytes()
+}
+
+// exportTypes declares the C types of Go types, as cgo does.
+const exportTypes = `#include <stddef.h>
+#include <stdint.h>
+
+typedef int8_t GoInt8;
+typedef uint8_t GoUint8;
+typedef int16_t GoInt16;
+typedef uint16_t GoUint16;
+typedef int32_t GoInt32;
+typedef uint32_t GoUint32;
+typedef int64_t GoInt64;
+typedef uint64_t GoUint64;
+typedef ptrdiff_t GoInt;
+typedef size_t GoUint;
+typedef uintptr_t GoUintptr;
+typedef float GoFloat32;
+typedef double GoFloat64;
+#ifdef __cplusplus
+typedef bool GoBool;
+#else
+typedef _Bool GoBool;
+#endif
+
+typedef struct { const char *p; ptrdiff_t n; } GoString;
+
+`
+
+// exportFile returns the C source that defines the functions exported to C,
+// which call the Go functions, and initializes the main package pkgPath when
+// the library is loaded. header is the header of the functions.
+func exportFile(pkgPath string, header []byte, exports []cExport) []byte {
+	var b bytes.Buffer
+	b.Write(header)
+	b.WriteString("\n")
+	b.WriteString(symMacros)
+	for _, e := range exports {
+		args := make([]string, e.sig.Params().Len())
+		for i := range args {
+			args[i] = fmt.Sprintf("p%d", i)
+		}
+		ret := ""
+		if e.sig.Results().Len() == 1 {
+			ret = "return "
+		}
+		fmt.Fprintf(&b, "extern %s __asm__(_llgo_xstr(__USER_LABEL_PREFIX__) %q);\n\n", cDecl("_llgo_"+e.name, e.sig), e.sym)
+		fmt.Fprintf(&b, "%s {\n\t%s_llgo_%s(%s);\n}\n\n", cDecl(e.name, e.sig), ret, e.name, strings.Join(args, ", "))
+	}
+	b.WriteString(initCtor(pkgPath))
+	return b.Bytes()
+}
+
+// pluginFile returns the C source of a plugin of the main package pkgPath,
+// which records its import path, and initializes it when it is opened (see
+// the package github.com/goplus/llgo/plugin).
+func pluginFile(pkgPath string) []byte {
+	return []byte(fmt.Sprintf("const char _llgo_plugin_path[] = %q;\n\n%s%s", pkgPath, symMacros, initCtor(pkgPath)))
+}
+
+// symMacros define _llgo_xstr, by which the asm labels of the symbols of Go
+// functions are prefixed with the one of the target.
+const symMacros = `#define _llgo_str(x) #x
+#define _llgo_xstr(x) _llgo_str(x)
+
+`
+
+// initCtor returns the C constructor that initializes the main package
+// pkgPath of a library when it is loaded. It needs symMacros.
+func initCtor(pkgPath string) string {
+	return fmt.Sprintf(`extern void _llgo_main_init(void) __asm__(_llgo_xstr(__USER_LABEL_PREFIX__) %q);
+
+__attribute__((constructor)) static void _llgo_init(void) {
+	_llgo_main_init();
+}
+`, llssa.Mangle(pkgPath, "init"))
+}
+
+// -----------------------------------------------------------------------------
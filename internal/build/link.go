@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"go/constant"
+	"go/types"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// -----------------------------------------------------------------------------
+
+// llgoLinkFlags returns the flags that the packages which declare C libraries,
+// like the py package does, are linked with. They are specified by the
+// LLGoPackage constant of the package, whose value is
+//
+//	link: flags; alternative flags; ...
+//
+// where $(command args) is replaced by the output of the command, eg.
+// $(pkg-config --libs python3-embed). The first alternative whose commands
+// succeed is used. A package whose LLGoPackage isn't such a string has no
+// flags.
+func llgoLinkFlags(p *packages.Package) ([]string, error) {
+	if p.Types == nil {
+		return nil, nil
+	}
+	c, ok := p.Types.Scope().Lookup("LLGoPackage").(*types.Const)
+	if !ok || c.Val().Kind() != constant.String {
+		return nil, nil
+	}
+	v := constant.StringVal(c.Val())
+	if !strings.HasPrefix(v, "link:") {
+		return nil, nil
+	}
+	var err error
+	for _, alt := range strings.Split(v[len("link:"):], ";") {
+		var flags string
+		if flags, err = expandCommands(alt); err == nil {
+			return strings.Fields(flags), nil
+		}
+	}
+	return nil, fmt.Errorf("%s: LLGoPackage: %w", p.PkgPath, err)
+}
+
+// expandCommands replaces $(command args) in s by the output of the command.
+func expandCommands(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "$(")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		j := strings.IndexByte(s[i:], ')')
+		if j < 0 {
+			return "", fmt.Errorf("missing ) in %q", s)
+		}
+		args := strings.Fields(s[i+2 : i+j])
+		if len(args) == 0 {
+			return "", fmt.Errorf("empty command in %q", s)
+		}
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(strings.TrimSpace(string(out)))
+		s = s[i+j+1:]
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// Android and iOS targets are built with the sysroot of the Android NDK, or
+// of the iOS SDK, unless Config.Sysroot is set (see mobileSysroot), usually as
+// shared libraries that apps load (see BuildModeCShared and sharedFlags).
+// Android executables are
+// position independent, as Android doesn't run others, and are linked with
+// libucontext, as bionic, the libc of Android, has no getcontext and
+// makecontext, by which the runtime switches goroutines.
+
+// targetGOOS returns the GOOS of the target of conf. Unlike conf.target, it
+// doesn't depend on the relocation model of conf.
+func targetGOOS(conf *Config) string {
+	if conf.Target == nil {
+		return orDefault(conf.getenv("GOOS"), runtime.GOOS)
+	}
+	return orDefault(conf.Target.GOOS, runtime.GOOS)
+}
+
+// mobileSysroot returns the sysroot of the Android or iOS target of conf, or
+// "" if it isn't found or the target isn't a mobile one. The one of Android is
+// in the NDK of $ANDROID_NDK_HOME, or of $ANDROID_NDK_ROOT, and the one of iOS
+// is $SDKROOT, or the iOS SDK of Xcode on macOS.
+func mobileSysroot(conf *Config) string {
+	switch targetGOOS(conf) {
+	case "android":
+		ndk := orDefault(conf.getenv("ANDROID_NDK_HOME"), conf.getenv("ANDROID_NDK_ROOT"))
+		if ndk == "" {
+			return ""
+		}
+		// The NDK has prebuilt toolchains for x86_64 hosts only, which run on
+		// arm64 macOS by Rosetta.
+		return filepath.Join(ndk, "toolchains", "llvm", "prebuilt", runtime.GOOS+"-x86_64", "sysroot")
+	case "ios":
+		if sdk := conf.getenv("SDKROOT"); sdk != "" || runtime.GOOS != "darwin" {
+			return sdk
+		}
+		out, err := exec.Command("xcrun", "--sdk", "iphoneos", "--show-sdk-path").Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// mobileFlags returns the clang flags that link the libraries that the runtime
+// needs on the Android target of conf.
+func mobileFlags(conf *Config) []string {
+	if targetGOOS(conf) == "android" {
+		return []string{"-lucontext"}
+	}
+	return nil
+}
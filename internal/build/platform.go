@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// An executable is linked by the linker that clang drives for its target, or
+// by the lld flavor of that linker when cross compiling (see clangFlags): ld
+// for ELF targets and MinGW, ld64 for macOS, with the SDK of $SDKROOT as its
+// -syslibroot unless Config.Sysroot is set, and lld-link, or link.exe, for
+// the MSVC ABI of Windows. Other linkers don't have the semantics of GNU ld,
+// so the flags that llgo links with are translated for them, and the features
+// that they can't link are reported by checkPlatform.
+
+// Linker flavors, see linkerOf.
+const (
+	linkerELF    = "elf"    // ld or ld.lld
+	linkerMinGW  = "mingw"  // ld or ld.lld, for the MinGW ABI of Windows
+	linkerDarwin = "darwin" // ld64 or ld64.lld
+	linkerMSVC   = "msvc"   // link.exe or lld-link
+	linkerWasm   = "wasm"   // wasm-ld
+)
+
+// linkerOf returns the flavor of the linker of the target of conf.
+func linkerOf(conf *Config) string {
+	if isWasm(conf) {
+		return linkerWasm
+	}
+	if conf.Baremetal {
+		return linkerELF
+	}
+	switch orDefault(conf.target().GOOS, runtime.GOOS) {
+	case "darwin", "ios":
+		return linkerDarwin
+	case "windows":
+		if strings.HasSuffix(conf.target().Spec().Triple, "-msvc") {
+			return linkerMSVC
+		}
+		return linkerMinGW
+	}
+	return linkerELF
+}
+
+// checkPlatform reports an error if conf needs a feature that the linker of
+// its target doesn't have: the precise garbage collector gathers its stack
+// maps by a GNU linker script, macOS has no static libc, and plugins are
+// loaded by dlopen.
+func checkPlatform(conf *Config) error {
+	linker := linkerOf(conf)
+	if gc, _ := gcOf(conf); gc == GCPrecise && linker != linkerELF {
+		return errors.New("the precise garbage collector is only supported for ELF targets")
+	}
+	if conf.Static && linker == linkerDarwin {
+		return errors.New("macOS executables can't be linked statically")
+	}
+	if conf.BuildMode == BuildModePlugin && (linker == linkerMinGW || linker == linkerMSVC) {
+		return errors.New("build mode plugin isn't supported for Windows")
+	}
+	return nil
+}
+
+// platformFlags returns the clang flags that link the default libraries of
+// the target of conf that the llgo runtime needs. libc, libm, threads and dl
+// are part of libSystem on macOS, which clang links, but on Windows threads
+// and sockets are separate libraries.
+func platformFlags(conf *Config) []string {
+	switch linkerOf(conf) {
+	case linkerMinGW:
+		return []string{"-lpthread", "-lws2_32"}
+	case linkerMSVC:
+		return []string{"-lkernel32", "-lws2_32"}
+	}
+	return nil
+}
+
+// platformRelocFlags translates the clang flags compile and link of the
+// relocation model reloc (see relocFlags) for the target of conf. Executables
+// are position independent by default on macOS, and Windows images are
+// relocated by their base relocations, so they have no such flags.
+func platformRelocFlags(conf *Config, reloc string, compile, link []string) ([]string, []string) {
+	switch linkerOf(conf) {
+	case linkerDarwin:
+		if reloc == llssa.RelocStatic {
+			return compile, []string{"-Wl,-no_pie"}
+		}
+		return compile, nil
+	case linkerMinGW, linkerMSVC:
+		return nil, nil
+	}
+	return compile, link
+}
+
+// sharedFlags returns the clang flags that name the shared library output for
+// the linker of conf, so that the executables linked with it load it by its
+// file name, as Android apps do, rather than by its path: its soname, or its
+// install name, relative to the runtime search paths of the executable, on
+// macOS and iOS.
+func sharedFlags(conf *Config, output string) []string {
+	name := filepath.Base(output)
+	switch linkerOf(conf) {
+	case linkerELF:
+		return []string{"-Wl,-soname," + name}
+	case linkerDarwin:
+		return []string{"-Wl,-install_name,@rpath/" + name}
+	}
+	return nil
+}
+
+// exeExt returns the extension of the executables of the target of conf,
+// which is .exe for Windows.
+func exeExt(conf *Config) string {
+	if linker := linkerOf(conf); linker == linkerMinGW || linker == linkerMSVC {
+		return ".exe"
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// A static executable (see Config.Static) is linked with -static, with musl if
+// $MUSL_SYSROOT is the sysroot of a musl toolchain, which is made for static
+// linking, or else with the static libraries of the libc of the toolchain,
+// eg. glibc, some functions of which, like getaddrinfo, still load shared
+// libraries when they run. The llgo runtime doesn't load shared libraries, but
+// the package github.com/goplus/llgo/plugin does, so it can't be linked.
+
+const pkgPlugin = "github.com/goplus/llgo/plugin"
+
+// checkStatic reports an error if conf.Static is set for a build mode that
+// links a shared object, with a position-independent executable or a
+// sanitizer, whose runtimes are shared libraries, or if the packages pkgs
+// import the package plugin.
+func checkStatic(conf *Config, pkgs []*packages.Package) error {
+	if !conf.Static {
+		return nil
+	}
+	if conf.BuildMode == BuildModeCShared || conf.BuildMode == BuildModePlugin {
+		return fmt.Errorf("build mode %s can't be linked statically", conf.BuildMode)
+	}
+	if relocModel(conf) == llssa.RelocPIE {
+		return errors.New("position-independent executables can't be linked statically")
+	}
+	if conf.Sanitizer != "" || conf.Race {
+		return errors.New("sanitizers and race detection can't be linked statically")
+	}
+	var err error
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if p.PkgPath == pkgPlugin && err == nil {
+			err = fmt.Errorf("package %s loads shared libraries, so it can't be linked statically", pkgPlugin)
+		}
+	})
+	return err
+}
+
+// muslSysroot returns the sysroot of musl that a static executable of conf is
+// linked with, or "" if it's linked with the libc of the toolchain, eg. if
+// conf.Sysroot is set or the target isn't Linux.
+func muslSysroot(conf *Config) string {
+	if !conf.Static || conf.Sysroot != "" || conf.Baremetal || orDefault(conf.target().GOOS, runtime.GOOS) != "linux" {
+		return ""
+	}
+	return os.Getenv("MUSL_SYSROOT")
+}
+
+// muslTriple returns the target triple of musl of the Linux target triple,
+// eg. x86_64-unknown-linux-musl for x86_64-unknown-linux.
+func muslTriple(triple string) string {
+	if strings.HasSuffix(triple, "-gnueabihf") {
+		return strings.TrimSuffix(triple, "-gnueabihf") + "-musleabihf"
+	}
+	return strings.TrimSuffix(triple, "-gnu") + "-musl"
+}
+
+// staticFlags returns the clang flags that link a static executable of conf,
+// if any.
+func staticFlags(conf *Config) []string {
+	if !conf.Static || conf.Baremetal || isWasm(conf) {
+		return nil
+	}
+	return []string{"-static"}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,279 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// replacedByTestShim are the packages implemented by the testing shim, which
+// are linked to test binaries instead of being compiled.
+var replacedByTestShim = map[string]bool{
+	"testing": true,
+}
+
+// Test builds the test binary of the package specified by patterns to
+// conf.Output. The binary runs the TestXxx functions of the package and of
+// its external test package, and accepts the -test.run and -test.v flags.
+//
+// The testing package isn't compiled: a minimal implementation of it, which
+// supports the Fail, FailNow, Failed, SkipNow, Skipped, Helper and Name
+// methods of *testing.T, is linked instead.
+//
+// With conf.Cover, the test binary also accepts -test.coverprofile.
+func Test(patterns []string, conf *Config) error {
+	if conf.Baremetal {
+		return errors.New("tests can't be built for a baremetal target")
+	}
+	if conf.Cover && isWasm(conf) {
+		return errors.New("coverage isn't supported for WebAssembly targets")
+	}
+	if err := checkSanitizer(conf); err != nil {
+		return err
+	}
+	if err := checkHardening(conf); err != nil {
+		return err
+	}
+	conf = compileConf(conf, false)
+	initial, cgos, err := load(conf, patterns, true)
+	if err != nil {
+		return err
+	}
+	if err = checkStatic(conf, initial); err != nil {
+		return err
+	}
+	if err = checkPlatform(conf); err != nil {
+		return err
+	}
+	if err = checkCPU(conf); err != nil {
+		return err
+	}
+	pkgs, pkgPath, err := testPkgs(patterns, initial)
+	if err != nil {
+		return err
+	}
+	if conf.Cover {
+		cover := *conf
+		cover.coverPkg = pkgPath
+		conf = &cover
+	}
+	tests, err := testFuncs(pkgs)
+	if err != nil {
+		return err
+	}
+	workDir, clean, err := makeWorkDir(conf, "llgo-test-")
+	if err != nil {
+		return err
+	}
+	defer clean()
+
+	c, err := newCache(conf)
+	if err != nil {
+		return err
+	}
+	built, needRuntime, err := buildAll(pkgs, cgos, workDir, c, conf, replacedByTestShim)
+	if err != nil {
+		return err
+	}
+	var gcFiles, flags []string
+	if needRuntime {
+		if built, err = appendRuntime(built, workDir, c, conf); err != nil {
+			return err
+		}
+		if gcFiles, flags, err = gcLink(conf, workDir); err != nil {
+			return err
+		}
+		inline, err := inlineRuntime(conf, built, workDir)
+		if err != nil {
+			return err
+		}
+		flags = append(flags, inline...)
+	}
+	mainFile := filepath.Join(workDir, "_testmain.ll")
+	if err = os.WriteFile(mainFile, []byte(genTestMain(newProgram(conf), pkgs, tests, needRuntime).String()), 0644); err != nil {
+		return err
+	}
+	shimFile := filepath.Join(workDir, "_testing.c")
+	if err = os.WriteFile(shimFile, []byte(testingShim), 0644); err != nil {
+		return err
+	}
+	output := conf.Output
+	if output == "" {
+		output = path.Base(pkgs[0].PkgPath) + ".test" + exeExt(conf)
+	}
+	files, err := linkedFiles(conf, built, workDir)
+	if err != nil {
+		return err
+	}
+	files = append(files, mainFile, shimFile)
+	files = append(append(files, gcFiles...), cgoLink(built)...)
+	return link(conf, output, files, flags...)
+}
+
+// testPkgs returns the packages to be tested: the test variant of the package
+// specified by patterns and its external test package, or the package itself
+// if it has no test files, and the path of the package.
+func testPkgs(patterns []string, initial []*packages.Package) (pkgs []*packages.Package, pkgPath string, err error) {
+	var plain []*packages.Package
+	for _, p := range initial {
+		switch {
+		case strings.HasSuffix(p.ID, ".test]"): // p [p.test] or p_test [p.test]
+			pkgs = append(pkgs, p)
+		case p.ID == p.PkgPath && !strings.HasSuffix(p.PkgPath, ".test"):
+			plain = append(plain, p)
+		}
+	}
+	if len(plain) != 1 {
+		return nil, "", fmt.Errorf("patterns %v specify %d packages, want a single package", patterns, len(plain))
+	}
+	if len(pkgs) == 0 {
+		pkgs = plain
+	}
+	return pkgs, plain[0].PkgPath, nil
+}
+
+type testFunc struct {
+	pkg  *packages.Package
+	name string
+	sig  *types.Signature
+}
+
+// testFuncs returns the TestXxx functions of pkgs, in source order.
+func testFuncs(pkgs []*packages.Package) (tests []testFunc, err error) {
+	for _, p := range pkgs {
+		for i, f := range p.Syntax {
+			if !strings.HasSuffix(p.CompiledGoFiles[i], "_test.go") {
+				continue
+			}
+			for _, decl := range f.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv != nil || !isTest(fn.Name.Name, "Test") {
+					continue
+				}
+				sig := p.TypesInfo.Defs[fn.Name].Type().(*types.Signature)
+				if fn.Name.Name == "TestMain" && isTestingParam(sig, "M") {
+					pos := p.Fset.Position(fn.Pos())
+					return nil, fmt.Errorf("%v: TestMain is not supported", pos)
+				}
+				if !isTestingParam(sig, "T") {
+					pos := p.Fset.Position(fn.Pos())
+					return nil, fmt.Errorf("%v: wrong signature for %s, must be: func %s(t *testing.T)", pos, fn.Name.Name, fn.Name.Name)
+				}
+				tests = append(tests, testFunc{p, fn.Name.Name, sig})
+			}
+		}
+	}
+	return
+}
+
+// isTest reports whether name looks like a test (or benchmark, according to
+// prefix). It is a Test (say) if there is a character after Test that is not
+// a lower-case letter. We don't want TesticularCancer.
+func isTest(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) { // "Test" is ok
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// isTestingParam reports whether sig is func(*testing.<name>).
+func isTestingParam(sig *types.Signature, name string) bool {
+	if sig.Params().Len() != 1 || sig.Results().Len() != 0 {
+		return false
+	}
+	ptr, ok := sig.Params().At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "testing" && obj.Name() == name
+}
+
+// genTestMain generates the main package of a test binary. Its main function
+// initializes pkgs and runs the tests by the testing shim, after it passes its
+// arguments and its environment to the runtime, if it is linked:
+//
+//	int main(int argc, char **argv, char **envp) {
+//		runtime.ArgsInit(argc, argv, envp);
+//		llgo_testing_init(argc, argv);
+//		pkg.init();
+//		llgo_testing_run("TestXxx", pkg.TestXxx);
+//		...
+//		return llgo_testing_main();
+//	}
+func genTestMain(prog llssa.Program, pkgs []*packages.Package, tests []testFunc, needRuntime bool) llssa.Package {
+	ret := prog.NewPackage("main", "main")
+
+	tyInt32 := types.Typ[types.Int32]
+	tyPtr := types.Typ[types.UnsafePointer]
+	tyCStr := types.NewPointer(types.Typ[types.Int8])
+	sig := func(results []types.Type, params ...types.Type) *types.Signature {
+		vars := func(typs []types.Type) *types.Tuple {
+			v := make([]*types.Var, len(typs))
+			for i, t := range typs {
+				v[i] = types.NewParam(0, nil, "", t)
+			}
+			return types.NewTuple(v...)
+		}
+		return types.NewSignatureType(nil, nil, nil, vars(params), vars(results), false)
+	}
+	initTesting := ret.NewFunc("llgo_testing_init", sig(nil, tyInt32, tyPtr))
+	runTest := ret.NewFunc("llgo_testing_run", sig(nil, tyCStr, tyPtr)) // the C function pointer of a test
+	finish := ret.NewFunc("llgo_testing_main", sig([]types.Type{tyInt32}))
+
+	fn := ret.NewFunc("main", sig([]types.Type{tyInt32}, tyInt32, tyPtr, tyPtr))
+	b := fn.MakeBody(1)
+	if needRuntime {
+		argsInit := ret.NewFunc(llssa.PkgRuntime+".ArgsInit", sig(nil, tyInt32, tyPtr, tyPtr))
+		b.Call(argsInit.Expr, fn.Param(0), fn.Param(1), fn.Param(2))
+	}
+	b.Call(initTesting.Expr, fn.Param(0), fn.Param(1))
+	for _, p := range pkgs {
+		b.Call(ret.NewFunc(llssa.Mangle(p.PkgPath, "init"), sig(nil)).Expr)
+	}
+	for _, t := range tests {
+		test := ret.NewFunc(llssa.Mangle(t.pkg.PkgPath, t.name), t.sig)
+		b.Call(runTest.Expr, b.CStr(t.name), test.Expr)
+	}
+	b.Return(b.Call(finish.Expr))
+	return ret
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/goplus/llgo/x/env/llvm"
+)
+
+// hasClang reports whether the clang that the build driver runs is found.
+func hasClang() bool {
+	root := llvm.New().Root()
+	if root == "" {
+		_, err := exec.LookPath("clang")
+		return err == nil
+	}
+	_, err := exec.LookPath(filepath.Join(root, "bin", "clang"))
+	return err == nil
+}
+
+// TestTestShim builds the test binary of _testdata/testshim, whose tests call
+// the methods of *testing.T that the testing shim implements, and runs it.
+func TestTestShim(t *testing.T) {
+	if !hasClang() {
+		t.Skip("clang isn't found")
+	}
+	dir, err := filepath.Abs("_testdata/testshim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "testshim.test")
+	if err = Test([]string{"."}, &Config{Dir: dir, Output: output}); err != nil {
+		t.Fatal("TestTestShim: Test failed:", err)
+	}
+	out, err := exec.Command(output, "-test.v").Output()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 1 {
+		t.Fatalf("TestTestShim: the test binary exits with %v, want status 1:\n%s", err, out)
+	}
+	got := regexp.MustCompile(`\(\d+\.\d+s\)`).ReplaceAllString(string(out), "(0.00s)")
+	want := `=== RUN   TestAdd
+--- PASS: TestAdd (0.00s)
+=== RUN   TestSkip
+--- SKIP: TestSkip (0.00s)
+=== RUN   TestFail
+--- FAIL: TestFail (0.00s)
+FAIL
+`
+	if got != want {
+		t.Fatalf("TestTestShim: got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+// testingShim is the C source of the testing runtime linked to test binaries.
+// It implements the methods of *testing.T under the symbol names that llgo
+// gives them, and the llgo_testing_* functions called by the generated main.
+const testingShim = `#include <regex.h>
+#include <setjmp.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+
+#define LLGO_STR2(x) #x
+#define LLGO_STR(x) LLGO_STR2(x)
+#define LLGO_SYM(name) __asm__(LLGO_STR(__USER_LABEL_PREFIX__) name)
+
+typedef struct {
+	const char *data;
+	long len;
+} GoString;
+
+typedef struct {
+	const char *name;
+	int failed;
+	int skipped;
+	jmp_buf done;
+} T;
+
+static int verbose;
+static int filter;
+static regex_t runRe;
+static int ran;
+static int failed;
+static const char *coverProfile;
+
+void llgo_cover_report(const char *profile) LLGO_SYM("github.com/goplus/llgo/internal/runtime.CoverReport") __attribute__((weak));
+
+void llgo_testing_init(int argc, char **argv) {
+	for (int i = 1; i < argc; i++) {
+		const char *arg = argv[i];
+		if (strcmp(arg, "-test.v") == 0 || strcmp(arg, "-test.v=true") == 0) {
+			verbose = 1;
+		} else if (strncmp(arg, "-test.run=", 10) == 0) {
+			if (regcomp(&runRe, arg + 10, REG_EXTENDED | REG_NOSUB) != 0) {
+				fprintf(stderr, "testing: invalid regexp for -test.run: %s\n", arg + 10);
+				exit(2);
+			}
+			filter = 1;
+		} else if (strncmp(arg, "-test.coverprofile=", 19) == 0) {
+			coverProfile = arg + 19;
+		}
+	}
+}
+
+void llgo_testing_run(const char *name, void (*fn)(T *)) {
+	if (filter && regexec(&runRe, name, 0, NULL, 0) != 0) {
+		return;
+	}
+	T t = {name};
+	struct timespec start, end;
+	ran++;
+	if (verbose) {
+		printf("=== RUN   %s\n", name);
+	}
+	clock_gettime(CLOCK_MONOTONIC, &start);
+	if (setjmp(t.done) == 0) {
+		fn(&t);
+	}
+	clock_gettime(CLOCK_MONOTONIC, &end);
+	double secs = (end.tv_sec - start.tv_sec) + (end.tv_nsec - start.tv_nsec) / 1e9;
+	const char *status = t.failed ? "FAIL" : t.skipped ? "SKIP" : "PASS";
+	if (t.failed) {
+		failed = 1;
+	}
+	if (verbose || t.failed) {
+		printf("--- %s: %s (%.2fs)\n", status, name, secs);
+	}
+	fflush(stdout);
+}
+
+int llgo_testing_main(void) {
+	if (ran == 0) {
+		fprintf(stderr, "testing: warning: no tests to run\n");
+	}
+	printf(failed ? "FAIL\n" : "PASS\n");
+	if (llgo_cover_report) {
+		fflush(stdout);
+		llgo_cover_report(coverProfile);
+	}
+	return failed;
+}
+
+void testing_init(void) LLGO_SYM("testing.init");
+void testing_init(void) {
+}
+
+void testing_Fail(T *t) LLGO_SYM("testing.(*T).Fail");
+void testing_Fail(T *t) {
+	t->failed = 1;
+}
+
+void testing_FailNow(T *t) LLGO_SYM("testing.(*T).FailNow");
+void testing_FailNow(T *t) {
+	t->failed = 1;
+	longjmp(t->done, 1);
+}
+
+_Bool testing_Failed(T *t) LLGO_SYM("testing.(*T).Failed");
+_Bool testing_Failed(T *t) {
+	return t->failed;
+}
+
+void testing_SkipNow(T *t) LLGO_SYM("testing.(*T).SkipNow");
+void testing_SkipNow(T *t) {
+	t->skipped = 1;
+	longjmp(t->done, 1);
+}
+
+_Bool testing_Skipped(T *t) LLGO_SYM("testing.(*T).Skipped");
+_Bool testing_Skipped(T *t) {
+	return t->skipped;
+}
+
+void testing_Helper(T *t) LLGO_SYM("testing.(*T).Helper");
+void testing_Helper(T *t) {
+}
+
+GoString testing_Name(T *t) LLGO_SYM("testing.(*T).Name");
+GoString testing_Name(T *t) {
+	GoString s = {t->name, (long)strlen(t->name)};
+	return s;
+}
+
+// The methods are promoted from testing.common, which T embeds first, so that
+// the calls of a test resolve to the methods of *common, with t.
+void testing_common_Fail(T *t) LLGO_SYM("testing.(*common).Fail");
+void testing_common_Fail(T *t) {
+	testing_Fail(t);
+}
+
+void testing_common_FailNow(T *t) LLGO_SYM("testing.(*common).FailNow");
+void testing_common_FailNow(T *t) {
+	testing_FailNow(t);
+}
+
+_Bool testing_common_Failed(T *t) LLGO_SYM("testing.(*common).Failed");
+_Bool testing_common_Failed(T *t) {
+	return testing_Failed(t);
+}
+
+void testing_common_SkipNow(T *t) LLGO_SYM("testing.(*common).SkipNow");
+void testing_common_SkipNow(T *t) {
+	testing_SkipNow(t);
+}
+
+_Bool testing_common_Skipped(T *t) LLGO_SYM("testing.(*common).Skipped");
+_Bool testing_common_Skipped(T *t) {
+	return testing_Skipped(t);
+}
+
+void testing_common_Helper(T *t) LLGO_SYM("testing.(*common).Helper");
+void testing_common_Helper(T *t) {
+}
+
+GoString testing_common_Name(T *t) LLGO_SYM("testing.(*common).Name");
+GoString testing_common_Name(T *t) {
+	return testing_Name(t);
+}
+`
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// WebAssembly execution models, see Config.WasmExecModel.
+const (
+	WasmCommand = "command"
+	WasmReactor = "reactor"
+)
+
+// isWasm reports whether packages are built for WebAssembly.
+func isWasm(conf *Config) bool {
+	return strings.HasPrefix(conf.target().Spec().Triple, "wasm")
+}
+
+// wasmFlags returns the clang flags that select the execution model of a
+// WebAssembly module. All the symbols of a reactor are exported, so that the
+// host can call the functions of its packages.
+func wasmFlags(conf *Config) ([]string, error) {
+	switch conf.WasmExecModel {
+	case "", WasmCommand:
+		return nil, nil
+	case WasmReactor:
+		return []string{"-mexec-model=reactor", "-Wl,--export-dynamic"}, nil
+	}
+	return nil, fmt.Errorf("unknown WebAssembly execution model %q", conf.WasmExecModel)
+}
+
+// wasiSysroot returns the sysroot of WASI: $WASI_SYSROOT, or the share/wasi-sysroot
+// directory of $WASI_SDK_PATH, as installed by wasi-sdk.
+func wasiSysroot() string {
+	if dir := os.Getenv("WASI_SYSROOT"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("WASI_SDK_PATH"); dir != "" {
+		return filepath.Join(dir, "share", "wasi-sysroot")
+	}
+	return ""
+}
+
+// reactorInit writes the C source that initializes the main package pkgPath
+// of a reactor to workDir. A reactor has no main function, so the package is
+// initialized by a constructor, which wasi-libc runs in _initialize.
+func reactorInit(pkgPath, workDir string) (string, error) {
+	src := fmt.Sprintf(`extern void llgo_main_init(void) __asm__(%q);
+
+__attribute__((constructor)) static void llgo_init(void) {
+	llgo_main_init();
+}
+`, llssa.Mangle(pkgPath, "init"))
+	file := filepath.Join(workDir, "_reactor.c")
+	return file, os.WriteFile(file, []byte(src), 0644)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/llgo/x/clang"
+)
+
+// -----------------------------------------------------------------------------
+
+// Bindings are the Go bindings of C headers generated by Bindgen.
+type Bindings struct {
+	Src     []byte   // Go source of the package of the bindings
+	Skipped []string // declarations that have no bindings, and why
+}
+
+// Bindgen generates the Go package pkgName of the bindings of the C headers,
+// which are parsed by clang with conf.Flags. The declarations of the headers,
+// but not of the headers they include, are bound as cgo binds the names C.xxx
+// (see the package doc), except that the bindings are exported, and that
+// basic C types are bound to Go types:
+//
+//	int add(int a, int b);               //llgo:link C.add
+//	                                     func Add(a int32, b int32) int32
+//	struct node { struct node *next; }   type Struct_node struct { Next *Struct_node }
+//	typedef struct { int x, y; } point;  type Point struct { X int32; Y int32 }
+//	enum color { RED, GREEN };           type Enum_color uint32
+//	                                     const RED = 0
+//	#define N 10                         const N = 10
+//
+// The package declares LLGoPackage, so that the pragmas //llgo:link of its
+// bodyless functions are honored where it is imported. Functions are called
+// by the C ABI, and variadic ones are declared with the parameter
+// __llgo_va_list ...any. Static functions and variables, macros that aren't
+// literals, and names reserved by C (which start with _) are not bound.
+func Bindgen(pkgName string, headers []string, conf *Config) (*Bindings, error) {
+	var src bytes.Buffer
+	files := make(map[string]bool)
+	for _, header := range headers {
+		file, err := filepath.Abs(header)
+		if err != nil {
+			return nil, err
+		}
+		files[file] = true
+		fmt.Fprintf(&src, "#include %q\n", file)
+	}
+	t := &translator{
+		conf:    conf,
+		fset:    token.NewFileSet(),
+		decls:   make(map[string]string),
+		anons:   make(map[*clang.Node]string),
+		pkgPath: pkgName,
+		bind:    true,
+	}
+	s, err := t.newScope(src.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	macros, err := conf.Clang.MacroDefs(src.Bytes(), t.clangFlags()...)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Bindings)
+	pos := clang.Positions(s.tu)
+	skip := func(n *clang.Node, err error) {
+		p := pos[n]
+		ret.Skipped = append(ret.Skipped, fmt.Sprintf("%s:%d: %s: %v", filepath.Base(p.File), p.Line, n.Name, err))
+	}
+	for _, n := range s.tu.Inner {
+		var err error
+		if !files[pos[n].File] || n.IsImplicit || n.Name == "" && n.Kind != "EnumDecl" || strings.HasPrefix(n.Name, "_") {
+			continue
+		}
+		switch n.Kind {
+		case "FunctionDecl":
+			if s.statics[n.Name] {
+				skip(n, fmt.Errorf("static functions aren't supported"))
+				continue
+			}
+			err = t.bind1(func() error {
+				_, err := t.function(s, n.Name, s.funcs[n.Name])
+				return err
+			})
+		case "VarDecl":
+			err = fmt.Errorf("variables aren't supported")
+		case "TypedefDecl":
+			ct := &cType{kind: cTypedef, name: n.Name}
+			if anon := s.anonOf[n]; anon != nil && anon.Kind == "RecordDecl" {
+				if under, e := t.typedefType(s, n.Name); e == nil && under.kind == cRecord {
+					// typedef struct {...} name: the record is named by the typedef
+					t.anons[anon] = t.goName("type", n.Name)
+					ct = under
+				}
+			}
+			err = t.bind1(func() error {
+				_, err := t.goType(s, ct)
+				return err
+			})
+		case "RecordDecl":
+			if s.tags[n.TagUsed+" "+n.Name] != n {
+				continue // a declaration of a record defined elsewhere
+			}
+			err = t.bind1(func() error {
+				_, err := t.goType(s, &cType{kind: cRecord, name: n.Name, union: n.TagUsed == "union"})
+				return err
+			})
+		case "EnumDecl":
+			if n.Name != "" {
+				err = t.bind1(func() error {
+					_, err := t.goType(s, &cType{kind: cEnum, name: n.Name})
+					return err
+				})
+			}
+			for _, c := range n.Inner {
+				if c.Kind == "EnumConstantDecl" {
+					t.constant(c.Name, s.consts[c.Name])
+				}
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			skip(n, err)
+		}
+	}
+	for _, m := range macros {
+		if !files[m.File] || strings.HasPrefix(m.Name, "_") {
+			continue
+		}
+		if lit, ok := goLiteral(m.Body); ok {
+			t.constant(m.Name, lit)
+		} else if name := t.goName("const", m.Body); token.IsIdentifier(m.Body) && t.decls[name] != "" {
+			t.constant(m.Name, name)
+		}
+	}
+	if ret.Src, err = t.bindingsFile(pkgName, headers); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// bind1 calls bind, which declares the bindings of a declaration, and removes
+// the ones it declares if it fails, some of which may be incomplete.
+func (t *translator) bind1(bind func() error) error {
+	n := len(t.names)
+	err := bind()
+	if err != nil {
+		for _, name := range t.names[n:] {
+			delete(t.decls, name)
+		}
+		t.names = t.names[:n]
+	}
+	return err
+}
+
+// bindingsFile returns the Go source of the bindings declared by t.
+func (t *translator) bindingsFile(pkgName string, headers []string) ([]byte, error) {
+	var b bytes.Buffer
+	names := make([]string, len(headers))
+	for i, header := range headers {
+		names[i] = filepath.Base(header)
+	}
+	fmt.Fprintf(&b, "// Code generated by llgo bindgen from %s. DO NOT EDIT.\n\n", strings.Join(names, ", "))
+	fmt.Fprintf(&b, "package %s\n\nimport \"unsafe\"\n\nvar _ unsafe.Pointer\n\nconst LLGoPackage = true\n", pkgName)
+	for _, name := range t.names {
+		b.WriteString("\n")
+		b.WriteString(t.decls[name])
+		b.WriteString("\n")
+	}
+	return format.Source(b.Bytes())
+}
+
+// exported returns the exported Go identifier that binds the C name, eg.
+// Point for point.
+func exported(name string) string {
+	switch ch := name[0]; {
+	case ch >= 'a' && ch <= 'z':
+		return string(ch-'a'+'A') + name[1:]
+	case ch == '_':
+		return "X" + name
+	}
+	return name
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,552 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cgo translates the Go packages that use cgo, ie. whose files import
+// "C", to Go packages that llgo compiles: it plays the role of cmd/cgo.
+//
+// The preamble of each file, the comment that precedes import "C", is parsed
+// by clang, and the names C.xxx that the file refers to are replaced by shims
+// declared in the generated file cgo_gotypes.go, as cmd/cgo names them:
+//
+//	C.int          _Ctype_int              type _Ctype_int int32
+//	C.struct_node  _Ctype_struct_node      type _Ctype_struct_node struct{...}
+//	C.size_t       _Ctype_size_t           type _Ctype_size_t = _Ctype_ulong
+//	C.add          _Cfunc_add              //llgo:link C.add
+//	                                       func _Cfunc_add(p0 _Ctype_int, p1 _Ctype_int) _Ctype_int
+//	C.counter      (*_Cvar_counter())      //llgo:link C._cgo_xxx_var_counter
+//	                                       func _Cvar_counter() *_Ctype_int
+//	C.RED          _Cconst_RED             const _Cconst_RED = 0
+//
+// C functions are called directly by the C ABI (see llssa.Package.NewCFunc),
+// and so are the helpers C.CString, C.GoString and the like, which are
+// defined in C (see cgoHelpers).
+// Static functions and variables, which have no external symbol, are reached
+// through wrappers appended to the preamble, which is compiled alongside the
+// package with the C files of its directory.
+package cgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"hash/fnv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llgo/x/clang"
+)
+
+// -----------------------------------------------------------------------------
+
+// Config is the configuration of translating cgo packages.
+type Config struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string   // build tags, in addition to the ones of GOOS, GOARCH and cgo
+	Clang  *clang.Cmd // clang command that parses the preambles
+	Flags  []string   // clang flags that select the target, eg. --target
+
+	// Overlay maps the absolute paths of Go files to their contents, which
+	// replace the ones on disk (see packages.Config.Overlay).
+	Overlay map[string][]byte
+}
+
+// Package is a cgo package translated by Translate. It is loaded by the go
+// command with cgo disabled, and Overlay as the overlay, in which:
+//
+//   - each Go file that imports "C" is translated to x.cgo1.go, and so are
+//     the Go files that are selected only when cgo is enabled;
+//   - the Go files that are selected only when cgo is disabled are excluded;
+//   - the shims are declared in cgo_gotypes.go.
+type Package struct {
+	Overlay map[string][]byte // contents of the Go files by absolute path
+	GoFiles []string          // paths of the files of Overlay that are loaded
+
+	CFiles    []string          // C files of the package directory
+	GenCFiles map[string][]byte // C files generated from the preambles, by name
+	CFlags    []string          // flags of #cgo CFLAGS and CPPFLAGS directives, to compile C files
+	LDFlags   []string          // flags of #cgo LDFLAGS directives, to link executables
+}
+
+// Translate translates the package pkgPath whose Go files, as selected by the
+// go command with cgo disabled, are goFiles, and ignoredFiles the others. It
+// returns nil if the package doesn't use cgo.
+func Translate(pkgPath string, goFiles, ignoredFiles []string, conf *Config) (*Package, error) {
+	files, excluded, err := conf.selectFiles(goFiles, ignoredFiles)
+	if err != nil || files == nil {
+		return nil, err
+	}
+	dir := filepath.Dir(files[0])
+	t := &translator{
+		conf:    conf,
+		fset:    token.NewFileSet(),
+		prefix:  symPrefix(pkgPath),
+		decls:   make(map[string]string),
+		anons:   make(map[*clang.Node]string),
+		cflags:  []string{"-I", dir},
+		pkgPath: pkgPath,
+	}
+	var cgoFiles []*cgoFile
+	for _, file := range files {
+		f, err := t.parseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		cgoFiles = append(cgoFiles, f)
+	}
+	pkgName := cgoFiles[0].ast.Name.Name
+	ret := &Package{Overlay: make(map[string][]byte), GenCFiles: make(map[string][]byte)}
+	for _, file := range excluded {
+		ret.Overlay[file] = []byte("//go:build ignore\n\npackage " + pkgName + "\n")
+	}
+	ldflags := []string{}
+	for _, f := range cgoFiles {
+		if f.imp == nil {
+			continue
+		}
+		flags, err := t.directives(f, dir)
+		if err != nil {
+			return nil, err
+		}
+		ldflags = append(ldflags, flags...)
+	}
+	for _, f := range cgoFiles {
+		goFile := strings.TrimSuffix(f.name, ".go") + ".cgo1.go"
+		var src []byte
+		if f.imp == nil {
+			src = f.rewrite(nil)
+		} else {
+			s, err := t.newScope(f.cSource())
+			if err != nil {
+				return nil, fmt.Errorf("parsing the preamble of %s: %w", f.name, err)
+			}
+			edits, err := t.translate(s, f)
+			if err != nil {
+				return nil, err
+			}
+			src = f.rewrite(edits)
+			cName := strings.TrimSuffix(filepath.Base(f.name), ".go") + ".cgo2.c"
+			ret.GenCFiles[cName] = append(f.cSource(), s.wrappers.String()...)
+		}
+		ret.Overlay[goFile] = src
+		ret.GoFiles = append(ret.GoFiles, goFile)
+	}
+	typesFile := filepath.Join(dir, "cgo_gotypes.go")
+	ret.Overlay[typesFile] = t.typesFile(pkgName)
+	ret.GoFiles = append(ret.GoFiles, typesFile)
+	if src := t.helpersFile(); src != nil {
+		ret.GenCFiles["_cgo_helpers.c"] = src
+	}
+
+	if ret.CFiles, err = conf.cFiles(dir); err != nil {
+		return nil, err
+	}
+	ret.CFlags, ret.LDFlags = t.cflags, ldflags
+	return ret, nil
+}
+
+// ImportsC reports whether the Go file imports "C".
+func (conf *Config) ImportsC(file string) bool {
+	src, err := conf.readFile(file)
+	if err != nil {
+		return false
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), file, src, parser.ImportsOnly)
+	return err == nil && importOfC(f) != nil
+}
+
+// readFile returns the contents of file, from the overlay if it's in it.
+func (conf *Config) readFile(file string) ([]byte, error) {
+	if src, ok := conf.Overlay[file]; ok {
+		return src, nil
+	}
+	return os.ReadFile(file)
+}
+
+// selectFiles returns the Go files that the go command selects when cgo is
+// enabled but not when it is disabled, which include the ones that import
+// "C", and the excluded files, which it selects only when cgo is disabled.
+// files is nil if no file imports "C".
+func (conf *Config) selectFiles(goFiles, ignoredFiles []string) (files, excluded []string, err error) {
+	withCgo, withoutCgo := conf.buildContext(true), conf.buildContext(false)
+	usesCgo := false
+	for _, file := range ignoredFiles {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		dir, name := filepath.Split(file)
+		if ok, e := withCgo.MatchFile(dir, name); e != nil || !ok {
+			continue
+		}
+		files = append(files, file)
+		usesCgo = usesCgo || conf.ImportsC(file)
+	}
+	if !usesCgo {
+		return nil, nil, nil
+	}
+	for _, file := range goFiles {
+		dir, name := filepath.Split(file)
+		with, e1 := withCgo.MatchFile(dir, name)
+		without, e2 := withoutCgo.MatchFile(dir, name)
+		if e1 == nil && e2 == nil && without && !with {
+			excluded = append(excluded, file)
+		}
+	}
+	return
+}
+
+func (conf *Config) buildContext(cgo bool) *build.Context {
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = conf.GOOS, conf.GOARCH
+	ctx.CgoEnabled = cgo
+	ctx.BuildTags = conf.Tags
+	if conf.Overlay != nil {
+		ctx.OpenFile = func(path string) (io.ReadCloser, error) {
+			if src, ok := conf.Overlay[path]; ok {
+				return io.NopCloser(bytes.NewReader(src)), nil
+			}
+			return os.Open(path)
+		}
+	}
+	return &ctx
+}
+
+// cFiles returns the C files of dir that are selected for the target.
+func (conf *Config) cFiles(dir string) (files []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	ctx := conf.buildContext(true)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".c" {
+			continue
+		}
+		if ok, _ := ctx.MatchFile(dir, e.Name()); ok {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	return
+}
+
+// symPrefix returns the prefix of the C symbols of the wrappers of package
+// pkgPath, which must be unique in an executable.
+func symPrefix(pkgPath string) string {
+	h := fnv.New32a()
+	h.Write([]byte(pkgPath))
+	return fmt.Sprintf("_cgo_%08x_", h.Sum32())
+}
+
+// -----------------------------------------------------------------------------
+
+// cgoFile is a Go file of a cgo package.
+type cgoFile struct {
+	name     string
+	src      []byte
+	ast      *ast.File
+	file     *token.File
+	imp      *ast.ImportSpec // import "C", nil if the file doesn't import it
+	preamble string          // with #cgo directives blanked out
+	line     int             // line of the preamble in the file
+}
+
+func (t *translator) parseFile(name string) (*cgoFile, error) {
+	src, err := t.conf.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(t.fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	ret := &cgoFile{name: name, src: src, ast: f, file: t.fset.File(f.Pos()), imp: importOfC(f)}
+	if ret.imp == nil {
+		return ret, nil
+	}
+	if strings.HasSuffix(name, "_test.go") {
+		return nil, fmt.Errorf("%v: use of cgo in test not supported", t.fset.Position(ret.imp.Pos()))
+	}
+	doc := ret.imp.Doc
+	if doc == nil {
+		for _, decl := range f.Decls {
+			if d, ok := decl.(*ast.GenDecl); ok && len(d.Specs) == 1 && d.Specs[0] == ret.imp && !d.Lparen.IsValid() {
+				doc = d.Doc
+			}
+		}
+	}
+	if doc != nil {
+		var lines []string
+		for _, c := range doc.List {
+			if strings.HasPrefix(c.Text, "//") {
+				lines = append(lines, c.Text[2:])
+			} else {
+				lines = append(lines, c.Text[2:len(c.Text)-2])
+			}
+		}
+		ret.preamble = strings.Join(lines, "\n") + "\n"
+		ret.line = t.fset.Position(doc.Pos()).Line
+	}
+	return ret, nil
+}
+
+func importOfC(f *ast.File) *ast.ImportSpec {
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return imp
+		}
+	}
+	return nil
+}
+
+// cSource returns the C source of the preamble of f, with line directives
+// that refer to f.
+func (f *cgoFile) cSource() []byte {
+	return []byte(fmt.Sprintf("#line %d %q\n%s\n", f.line, f.name, f.preamble))
+}
+
+// directives removes the #cgo directives from the preamble of f, adds the
+// C flags they specify to t.cflags, and returns the linker flags.
+//
+//	#cgo [GOOS/GOARCH constraints] CFLAGS|CPPFLAGS|LDFLAGS: flags
+func (t *translator) directives(f *cgoFile, dir string) (ldflags []string, err error) {
+	lines := strings.Split(f.preamble, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#cgo ") && !strings.HasPrefix(line, "#cgo\t") {
+			continue
+		}
+		lines[i] = ""
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue // eg. #cgo noescape f
+		}
+		conds := strings.Fields(line[len("#cgo"):colon])
+		if len(conds) == 0 {
+			return nil, fmt.Errorf("%s:%d: invalid #cgo line: %s", f.name, f.line+i, line)
+		}
+		verb := conds[len(conds)-1]
+		if conds = conds[:len(conds)-1]; len(conds) > 0 && !t.matchConds(conds) {
+			continue
+		}
+		flags := strings.Fields(strings.ReplaceAll(line[colon+1:], "${SRCDIR}", dir))
+		switch verb {
+		case "CFLAGS", "CPPFLAGS":
+			t.cflags = append(t.cflags, flags...)
+		case "LDFLAGS":
+			ldflags = append(ldflags, flags...)
+		case "pkg-config":
+			cflags, libs, err := PkgConfig(flags)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", f.name, f.line+i, err)
+			}
+			t.cflags = append(t.cflags, cflags...)
+			ldflags = append(ldflags, libs...)
+		case "CXXFLAGS", "FFLAGS":
+		default:
+			return nil, fmt.Errorf("%s:%d: unsupported #cgo verb %s", f.name, f.line+i, verb)
+		}
+	}
+	f.preamble = strings.Join(lines, "\n")
+	return
+}
+
+// PkgConfig returns the flags that the C libraries pkgs are compiled and
+// linked with, as the command pkg-config, or $PKG_CONFIG, reports them by
+// --cflags and --libs.
+func PkgConfig(pkgs []string) (cflags, ldflags []string, err error) {
+	if len(pkgs) == 0 {
+		return
+	}
+	cmd := os.Getenv("PKG_CONFIG")
+	if cmd == "" {
+		cmd = "pkg-config"
+	}
+	run := func(opt string) ([]string, error) {
+		out, err := exec.Command(cmd, append([]string{opt, "--"}, pkgs...)...).Output()
+		if err != nil {
+			if e, ok := err.(*exec.ExitError); ok && len(e.Stderr) > 0 {
+				err = errors.New(strings.TrimSpace(string(e.Stderr)))
+			}
+			return nil, fmt.Errorf("%s %s %s: %w", cmd, opt, strings.Join(pkgs, " "), err)
+		}
+		return strings.Fields(string(out)), nil
+	}
+	if cflags, err = run("--cflags"); err != nil {
+		return
+	}
+	ldflags, err = run("--libs")
+	return
+}
+
+// matchConds reports whether one of the space separated options conds, each
+// a list of comma separated terms, eg. linux,!arm64, is satisfied.
+func (t *translator) matchConds(conds []string) bool {
+	ctx := t.conf.buildContext(true)
+	for _, opt := range conds {
+		ok := true
+		for _, term := range strings.Split(opt, ",") {
+			neg := strings.HasPrefix(term, "!")
+			name := strings.TrimPrefix(term, "!")
+			has := name == ctx.GOOS || name == ctx.GOARCH || name == "cgo" || name == "unix" && isUnix(ctx.GOOS)
+			for _, tag := range ctx.BuildTags {
+				has = has || name == tag
+			}
+			ok = ok && has != neg
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnix(goos string) bool {
+	switch goos {
+	case "aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "linux", "netbsd", "openbsd", "solaris":
+		return true
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// edit replaces src[start:end] of a Go file by text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// translate returns the edits that replace the names C.xxx of f by the Go
+// expressions of their shims, and import "C" by a blank import.
+func (t *translator) translate(s *scope, f *cgoFile) (edits []edit, err error) {
+	file := f.file
+	edits = append(edits, edit{file.Offset(f.imp.Path.Pos()), file.Offset(f.imp.Path.End()), `_ "unsafe"`})
+	ast.Inspect(f.ast, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "C" || x.Obj != nil {
+			return true
+		}
+		text, e := t.resolve(s, sel.Sel.Name, 0)
+		if e != nil {
+			err = fmt.Errorf("%v: C.%s: %w", t.fset.Position(sel.Pos()), sel.Sel.Name, e)
+			return false
+		}
+		edits = append(edits, edit{file.Offset(sel.Pos()), file.Offset(sel.End()), text})
+		return false
+	})
+	return
+}
+
+// rewrite returns the Go source of f with edits applied and its build
+// constraints removed, since f is selected for the target. A line directive
+// maps the positions of the result to f, whose lines are kept.
+func (f *cgoFile) rewrite(edits []edit) []byte {
+	for _, cg := range f.ast.Comments {
+		if cg.Pos() > f.ast.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, "//go:build") || strings.HasPrefix(c.Text, "// +build") {
+				off := f.file.Offset(c.Pos())
+				edits = append(edits, edit{off, off + len(c.Text), "//"})
+			}
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "//line %s:1:1\n", f.name)
+	pos := 0
+	for _, e := range edits {
+		b.Write(f.src[pos:e.start])
+		b.WriteString(e.text)
+		pos = e.end
+	}
+	b.Write(f.src[pos:])
+	return b.Bytes()
+}
+
+// typesFile returns the Go source of the shims of package pkgName.
+func (t *translator) typesFile(pkgName string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by llgo from the cgo preambles of %s. DO NOT EDIT.\n\n", t.pkgPath)
+	fmt.Fprintf(&b, "package %s\n\nimport \"unsafe\"\n\nvar _ unsafe.Pointer\n", pkgName)
+	for _, name := range t.names {
+		b.WriteString("\n")
+		b.WriteString(t.decls[name])
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// -----------------------------------------------------------------------------
+
+// goLiteral returns the Go constant of the body of a C macro if it is a
+// literal, eg. 0x10UL or "abc", possibly signed or parenthesized.
+func goLiteral(body string) (string, bool) {
+	for len(body) > 1 && body[0] == '(' && body[len(body)-1] == ')' {
+		body = strings.TrimSpace(body[1 : len(body)-1])
+	}
+	sign := ""
+	if strings.HasPrefix(body, "-") || strings.HasPrefix(body, "+") {
+		sign, body = body[:1], strings.TrimSpace(body[1:])
+	}
+	if body == "" {
+		return "", false
+	}
+	switch body[0] {
+	case '"':
+		if sign != "" {
+			return "", false
+		}
+		if _, err := strconv.Unquote(body); err != nil {
+			return "", false
+		}
+		return body, true
+	case '\'':
+		if _, _, tail, err := strconv.UnquoteChar(body[1:], '\''); err != nil || tail != "'" {
+			return "", false
+		}
+		return sign + body, true
+	}
+	isHex := strings.HasPrefix(body, "0x") || strings.HasPrefix(body, "0X")
+	suffixes := "uUlL"
+	if !isHex && strings.ContainsAny(body, ".eE") {
+		suffixes = "fFlL"
+	}
+	body = strings.TrimRight(body, suffixes)
+	lit, err := parser.ParseExpr(body)
+	if err != nil {
+		return "", false
+	}
+	if b, ok := lit.(*ast.BasicLit); !ok || b.Kind != token.INT && b.Kind != token.FLOAT {
+		return "", false
+	}
+	return sign + body, true
+}
+
+// -----------------------------------------------------------------------------
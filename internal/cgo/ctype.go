@@ -0,0 +1,461 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llgo/x/clang"
+)
+
+// -----------------------------------------------------------------------------
+
+type cKind int
+
+const (
+	cBasic   cKind = iota // name is the cgo name of the type, eg. uint for unsigned int
+	cVoid                 //
+	cPointer              // pointer to elem
+	cArray                // array of len elems, len < 0 if incomplete
+	cFunc                 // function returning elem
+	cRecord               // struct or union tag name, or the anonymous record
+	cEnum                 // enum tag name
+	cTypedef              // typedef name
+)
+
+// cType is a C type, as parsed from its spelling by clang, eg. the type
+// "const char *(*)(int, ...)".
+type cType struct {
+	kind     cKind
+	name     string
+	union    bool        // cRecord is a union
+	anon     *clang.Node // cRecord or cEnum without tag name
+	isConst  bool
+	elem     *cType
+	len      int64
+	params   []*cType
+	variadic bool
+}
+
+// cBasics are the cgo names of the basic C types (eg. C.uint for unsigned
+// int), which are the names of the types by which they are spelled by clang.
+var cBasics = map[string]string{
+	"char":               "char",
+	"signed char":        "schar",
+	"unsigned char":      "uchar",
+	"short":              "short",
+	"unsigned short":     "ushort",
+	"int":                "int",
+	"unsigned int":       "uint",
+	"long":               "long",
+	"unsigned long":      "ulong",
+	"long long":          "longlong",
+	"unsigned long long": "ulonglong",
+	"float":              "float",
+	"double":             "double",
+	"_Bool":              "_Bool",
+}
+
+// cQualifiers are the type qualifiers and the nullability annotations clang
+// may spell types with. Only const is kept, for the C wrappers.
+var cQualifiers = map[string]bool{
+	"const": true, "volatile": true, "restrict": true, "__restrict": true,
+	"_Nonnull": true, "_Nullable": true, "_Null_unspecified": true, "__unaligned": true,
+}
+
+// parseCType parses the spelling s of a C type. anon is the record or enum
+// that an anonymous tag in s refers to, eg. the one a typedef defines.
+func parseCType(s string, anon *clang.Node) (*cType, error) {
+	p := &typeParser{toks: tokenize(s), anon: anon}
+	t, err := p.typ()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %s", p.toks[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing C type %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// tokenize splits the spelling of a C type into tokens. A tag is anonymous if
+// clang spells it as (unnamed struct at file:line:col), which is one token.
+func tokenize(s string) (toks []string) {
+	for i := 0; i < len(s); {
+		switch ch := s[i]; {
+		case ch == ' ':
+			i++
+		case isIdent(ch):
+			j := i + 1
+			for j < len(s) && isIdent(s[j]) {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		case strings.HasPrefix(s[i:], "..."):
+			toks = append(toks, "...")
+			i += 3
+		case strings.HasPrefix(s[i:], "(unnamed ") || strings.HasPrefix(s[i:], "(anonymous "):
+			j := strings.IndexByte(s[i:], ')')
+			if j < 0 {
+				j = len(s) - i - 1
+			}
+			toks = append(toks, s[i:i+j+1])
+			i += j + 1
+		default:
+			toks = append(toks, s[i:i+1])
+			i++
+		}
+	}
+	return
+}
+
+func isIdent(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+func isAnonTag(tok string) bool {
+	return strings.HasPrefix(tok, "(")
+}
+
+type typeParser struct {
+	toks []string
+	pos  int
+	anon *clang.Node
+}
+
+func (p *typeParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *typeParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// typ parses a type name: specifiers, and an abstract declarator.
+func (p *typeParser) typ() (*cType, error) {
+	base, err := p.specifiers()
+	if err != nil {
+		return nil, err
+	}
+	return p.declarator(base)
+}
+
+func (p *typeParser) specifiers() (*cType, error) {
+	var words []string
+	var t *cType
+	isConst := false
+	for {
+		tok := p.peek()
+		switch {
+		case cQualifiers[tok]:
+			isConst = isConst || tok == "const"
+		case tok == "struct" || tok == "union" || tok == "enum":
+			p.next()
+			name := p.peek()
+			if name == "" || name == "*" || name == "(" || name == ")" {
+				return nil, fmt.Errorf("missing tag name of %s", tok)
+			}
+			t = &cType{kind: cRecord, name: name, union: tok == "union"}
+			if tok == "enum" {
+				t.kind = cEnum
+			}
+			if isAnonTag(name) {
+				t.name, t.anon = "", p.anon
+				if t.anon == nil {
+					return nil, fmt.Errorf("%s %s isn't supported", tok, name)
+				}
+			}
+		case tok == "signed" || tok == "unsigned" || tok == "short" || tok == "long" ||
+			tok == "int" || tok == "char" || tok == "float" || tok == "double" ||
+			tok == "void" || tok == "_Bool" || tok == "bool":
+			words = append(words, tok)
+		case tok != "" && isIdent(tok[0]) && t == nil && words == nil:
+			t = &cType{kind: cTypedef, name: tok}
+		default:
+			if t == nil {
+				var err error
+				if t, err = basicOf(words); err != nil {
+					return nil, err
+				}
+			}
+			t.isConst = isConst
+			return t, nil
+		}
+		p.next()
+	}
+}
+
+// basicOf returns the basic type specified by words, eg. unsigned long int.
+func basicOf(words []string) (*cType, error) {
+	var signed, unsigned bool
+	var short, long int
+	var base string
+	for _, w := range words {
+		switch w {
+		case "signed":
+			signed = true
+		case "unsigned":
+			unsigned = true
+		case "short":
+			short++
+		case "long":
+			long++
+		case "int":
+		default:
+			base = w
+		}
+	}
+	name := base
+	switch base {
+	case "void":
+		return &cType{kind: cVoid}, nil
+	case "bool":
+		name = "_Bool"
+	case "char":
+		if signed {
+			name = "signed char"
+		}
+	case "", "int":
+		switch {
+		case short > 0:
+			name = "short"
+		case long > 1:
+			name = "long long"
+		case long > 0:
+			name = "long"
+		case len(words) == 0:
+			return nil, fmt.Errorf("missing type")
+		default:
+			name = "int"
+		}
+	case "double":
+		if long > 0 {
+			return nil, fmt.Errorf("long double isn't supported")
+		}
+	}
+	if unsigned {
+		name = "unsigned " + name
+	}
+	if basic, ok := cBasics[name]; ok {
+		return &cType{kind: cBasic, name: basic}, nil
+	}
+	return nil, fmt.Errorf("%s isn't supported", strings.Join(words, " "))
+}
+
+// declarator parses an abstract declarator that applies to base: * binds
+// looser than the suffixes [n] and (params), so *[3] is an array of pointers
+// and (*)[3] a pointer to an array.
+func (p *typeParser) declarator(base *cType) (*cType, error) {
+	if p.peek() == "*" {
+		p.next()
+		for cQualifiers[p.peek()] {
+			p.next()
+		}
+		return p.declarator(&cType{kind: cPointer, elem: base})
+	}
+	if p.peek() == "(" && p.pos+1 < len(p.toks) && (p.toks[p.pos+1] == "*" || p.toks[p.pos+1] == "^") {
+		start, end := p.pos+1, p.matchParen()
+		if end < 0 {
+			return nil, fmt.Errorf("unbalanced (")
+		}
+		p.pos = end + 1
+		t, err := p.suffixes(base)
+		if err != nil {
+			return nil, err
+		}
+		inner := &typeParser{toks: p.toks[start:end], anon: p.anon}
+		if t, err = inner.declarator(t); err == nil && inner.pos < len(inner.toks) {
+			err = fmt.Errorf("unexpected %s", inner.toks[inner.pos])
+		}
+		return t, err
+	}
+	return p.suffixes(base)
+}
+
+// suffixes parses the array and function suffixes that apply to base, the
+// leftmost one outermost, so [2][3] is an array of 2 arrays of 3 elements.
+func (p *typeParser) suffixes(base *cType) (*cType, error) {
+	switch p.peek() {
+	case "[":
+		p.next()
+		n := int64(-1)
+		if tok := p.peek(); tok != "]" {
+			var err error
+			if n, err = strconv.ParseInt(tok, 0, 64); err != nil {
+				return nil, fmt.Errorf("invalid array length %s", tok)
+			}
+			p.next()
+		}
+		if p.next() != "]" {
+			return nil, fmt.Errorf("missing ]")
+		}
+		elem, err := p.suffixes(base)
+		if err != nil {
+			return nil, err
+		}
+		return &cType{kind: cArray, elem: elem, len: n}, nil
+	case "(":
+		end := p.matchParen()
+		if end < 0 {
+			return nil, fmt.Errorf("unbalanced (")
+		}
+		fn := &cType{kind: cFunc}
+		for _, toks := range splitParams(p.toks[p.pos+1 : end]) {
+			if len(toks) == 1 && toks[0] == "..." {
+				fn.variadic = true
+				continue
+			}
+			param := &typeParser{toks: toks, anon: p.anon}
+			t, err := param.typ()
+			if err == nil && param.pos < len(toks) {
+				err = fmt.Errorf("unexpected %s", toks[param.pos])
+			}
+			if err != nil {
+				return nil, err
+			}
+			if t.kind != cVoid {
+				fn.params = append(fn.params, t)
+			}
+		}
+		p.pos = end + 1
+		ret, err := p.suffixes(base)
+		if err != nil {
+			return nil, err
+		}
+		fn.elem = ret
+		return fn, nil
+	}
+	return base, nil
+}
+
+// matchParen returns the index of the ) that matches the ( at p.pos, or -1.
+func (p *typeParser) matchParen() int {
+	depth := 0
+	for i := p.pos; i < len(p.toks); i++ {
+		switch p.toks[i] {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitParams splits the tokens of a parameter list at its top-level commas.
+func splitParams(toks []string) (params [][]string) {
+	depth, start := 0, 0
+	for i, tok := range toks {
+		switch tok {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+		case ",":
+			if depth == 0 {
+				params = append(params, toks[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(toks) {
+		params = append(params, toks[start:])
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+// cDecl returns the C declaration of name with type t, as in the wrappers of
+// static functions, eg. int (*name)(int) for a pointer to a function.
+func cDecl(t *cType, name string) (string, error) {
+	switch t.kind {
+	case cPointer:
+		inner := "*" + name
+		if k := t.elem.kind; k == cArray || k == cFunc {
+			inner = "(" + inner + ")"
+		}
+		return cDecl(t.elem, inner)
+	case cArray:
+		n := ""
+		if t.len >= 0 {
+			n = strconv.FormatInt(t.len, 10)
+		}
+		return cDecl(t.elem, name+"["+n+"]")
+	case cFunc:
+		params := make([]string, len(t.params))
+		for i, param := range t.params {
+			s, err := cDecl(param, "")
+			if err != nil {
+				return "", err
+			}
+			params[i] = s
+		}
+		if t.variadic {
+			params = append(params, "...")
+		} else if len(params) == 0 {
+			params = []string{"void"}
+		}
+		return cDecl(t.elem, name+"("+strings.Join(params, ", ")+")")
+	}
+	var spec string
+	switch t.kind {
+	case cBasic:
+		for c, basic := range cBasics {
+			if basic == t.name {
+				spec = c
+			}
+		}
+	case cVoid:
+		spec = "void"
+	case cRecord, cEnum:
+		if t.name == "" {
+			return "", fmt.Errorf("anonymous %s can't be spelled", tagOf(t))
+		}
+		spec = tagOf(t) + " " + t.name
+	case cTypedef:
+		spec = t.name
+	}
+	if t.isConst {
+		spec = "const " + spec
+	}
+	if name == "" {
+		return spec, nil
+	}
+	return spec + " " + name, nil
+}
+
+func tagOf(t *cType) string {
+	switch {
+	case t.kind == cEnum:
+		return "enum"
+	case t.union:
+		return "union"
+	}
+	return "struct"
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,856 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgo
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"github.com/goplus/llgo/x/clang"
+)
+
+// -----------------------------------------------------------------------------
+
+// translator translates the files of a cgo package. The shims are declared
+// once for the package, by the first file that refers to them.
+type translator struct {
+	conf    *Config
+	fset    *token.FileSet
+	pkgPath string
+	prefix  string   // prefix of the C symbols of wrappers, see symPrefix
+	cflags  []string // flags of #cgo directives, see directives
+
+	decls map[string]string      // Go declarations of the shims by name
+	names []string               // names of decls in declaration order
+	anons map[*clang.Node]string // Go names of anonymous records
+
+	helpers []string // helpers that the package uses, see cgoHelpers
+
+	bind bool // declares bindings instead of shims, see Bindgen
+}
+
+// goName returns the Go name of the shim of the C entity name of kind, which
+// is basic, type, field, const or func, eg. _Ctype_struct_node for the type
+// struct_node. The bindings that Bindgen generates are named as exported Go
+// identifiers instead, eg. Struct_node, and basic types are Go types.
+func (t *translator) goName(kind, name string) string {
+	if t.bind {
+		if kind == "basic" {
+			return ""
+		}
+		return exported(name)
+	}
+	switch kind {
+	case "basic":
+		kind = "type"
+	case "field":
+		if token.IsKeyword(name) {
+			return "_" + name
+		}
+		return name
+	}
+	return "_C" + kind + "_" + name
+}
+
+// scope holds the declarations of the preamble of a file.
+type scope struct {
+	tu       *clang.Node
+	funcs    map[string]*clang.Node
+	statics  map[string]bool // functions with internal linkage
+	vars     map[string]*clang.Node
+	typedefs map[string]*clang.Node
+	tags     map[string]*clang.Node // records and enums by tag, eg. struct node
+	anonOf   map[*clang.Node]*clang.Node
+	consts   map[string]string // enum constants
+	macros   map[string]string
+	wrappers strings.Builder // C wrappers of static functions and variables
+}
+
+// newScope parses the C source src with clang.
+func (t *translator) newScope(src []byte) (*scope, error) {
+	tu, err := t.conf.Clang.AST(src, t.clangFlags()...)
+	if err != nil {
+		return nil, err
+	}
+	s := &scope{
+		tu:       tu,
+		funcs:    make(map[string]*clang.Node),
+		statics:  make(map[string]bool),
+		vars:     make(map[string]*clang.Node),
+		typedefs: make(map[string]*clang.Node),
+		tags:     make(map[string]*clang.Node),
+		anonOf:   make(map[*clang.Node]*clang.Node),
+		consts:   make(map[string]string),
+	}
+	byID := make(map[string]*clang.Node)
+	s.addDecls(tu.Inner, byID)
+	for _, n := range tu.Inner {
+		if n.Kind == "TypedefDecl" {
+			if tag := ownedTag(n); tag != nil && byID[tag.ID] != nil && byID[tag.ID].Name == "" {
+				s.anonOf[n] = byID[tag.ID]
+			}
+		}
+	}
+	if s.macros, err = t.conf.Clang.Macros(src, t.clangFlags()...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (t *translator) clangFlags() []string {
+	return append(t.conf.Flags[:len(t.conf.Flags):len(t.conf.Flags)], t.cflags...)
+}
+
+// addDecls adds the declarations decls, and the tags declared in records.
+// The anonymous record or enum that a typedef defines is the declaration that
+// precedes it, unless clang reports the tag it owns (see newScope).
+func (s *scope) addDecls(decls []*clang.Node, byID map[string]*clang.Node) {
+	var prev *clang.Node
+	for _, n := range decls {
+		switch n.Kind {
+		case "FunctionDecl":
+			if _, ok := s.funcs[n.Name]; !ok {
+				s.funcs[n.Name] = n
+			}
+			if n.StorageClass == "static" {
+				s.statics[n.Name] = true
+			}
+		case "VarDecl":
+			if _, ok := s.vars[n.Name]; !ok {
+				s.vars[n.Name] = n
+			}
+		case "TypedefDecl":
+			s.typedefs[n.Name] = n
+			if prev != nil && prev.Name == "" {
+				s.anonOf[n] = prev
+			}
+		case "RecordDecl", "EnumDecl":
+			byID[n.ID] = n
+			if n.Name != "" {
+				tag := n.TagUsed + " " + n.Name
+				if n.Kind == "EnumDecl" {
+					tag = "enum " + n.Name
+				}
+				if old, ok := s.tags[tag]; !ok || !old.CompleteDefinition && n.Inner != nil {
+					s.tags[tag] = n
+				}
+			}
+			if n.Kind == "EnumDecl" {
+				s.addEnumConsts(n)
+			} else {
+				s.addDecls(n.Inner, byID)
+			}
+		}
+		prev = nil
+		if n.Kind == "RecordDecl" || n.Kind == "EnumDecl" {
+			prev = n
+		}
+	}
+}
+
+// addEnumConsts adds the constants of enum n, whose values are the ones
+// computed by clang, or the value of the previous constant plus one.
+func (s *scope) addEnumConsts(n *clang.Node) {
+	next := "0"
+	for _, c := range n.Inner {
+		if c.Kind != "EnumConstantDecl" {
+			continue
+		}
+		val := next
+		if v := constValue(c); v != "" {
+			val = v
+		}
+		s.consts[c.Name] = val
+		if i, err := strconv.ParseInt(val, 0, 64); err == nil {
+			next = strconv.FormatInt(i+1, 10)
+		} else {
+			next = "(" + val + ") + 1"
+		}
+	}
+}
+
+// constValue returns the value of the ConstantExpr of an enum constant.
+func constValue(n *clang.Node) string {
+	for _, c := range n.Inner {
+		if c.Kind == "ConstantExpr" && c.Value != "" {
+			return c.Value
+		}
+		if v := constValue(c); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ownedTag returns the record or enum a typedef refers to, as reported by
+// the ElaboratedType or RecordType of its underlying type.
+func ownedTag(n *clang.Node) *clang.Node {
+	for _, c := range n.Inner {
+		if c.OwnedTagDecl != nil {
+			return c.OwnedTagDecl
+		}
+		if c.Decl != nil && (c.Decl.Kind == "RecordDecl" || c.Decl.Kind == "EnumDecl") {
+			return c.Decl
+		}
+		if tag := ownedTag(c); tag != nil {
+			return tag
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// resolve returns the Go expression that replaces C.name in a file whose
+// preamble declares s. depth is the depth of macros that expand to names.
+func (t *translator) resolve(s *scope, name string, depth int) (string, error) {
+	if _, ok := cgoHelpers[name]; ok {
+		return t.helper(s, name)
+	}
+	if name == "malloc" {
+		return t.helper(s, "_CMalloc")
+	}
+	if strings.HasPrefix(name, "sizeof_") {
+		ct := s.typeNamed(name[len("sizeof_"):])
+		if ct == nil {
+			return "", errors.New("unknown type")
+		}
+		size, _, err := t.sizeof(s, ct)
+		if err != nil {
+			return "", err
+		}
+		return t.constant(name, strconv.FormatInt(size, 10)), nil
+	}
+	if ct := s.typeNamed(name); ct != nil {
+		return t.goType(s, ct)
+	}
+	if fn, ok := s.funcs[name]; ok {
+		return t.function(s, name, fn)
+	}
+	if v, ok := s.vars[name]; ok {
+		return t.variable(s, name, v)
+	}
+	if val, ok := s.consts[name]; ok {
+		return t.constant(name, val), nil
+	}
+	if body, ok := s.macros[name]; ok {
+		if lit, ok := goLiteral(body); ok {
+			return t.constant(name, lit), nil
+		}
+		if token.IsIdentifier(body) && depth < 8 {
+			return t.resolve(s, body, depth+1)
+		}
+		return "", fmt.Errorf("macro %s isn't a literal", body)
+	}
+	return "", errors.New("could not determine kind of name")
+}
+
+// constant returns the Go name of the constant name of value val, and declares
+// it.
+func (t *translator) constant(name, val string) string {
+	goName := t.goName("const", name)
+	t.declare(goName, fmt.Sprintf("const %s = %s", goName, val))
+	return goName
+}
+
+// typeNamed returns the type that C.name refers to, eg. C.struct_node, or
+// nil if it isn't a type.
+func (s *scope) typeNamed(name string) *cType {
+	for _, tag := range []string{"struct", "union", "enum"} {
+		if strings.HasPrefix(name, tag+"_") {
+			ct := &cType{kind: cRecord, name: name[len(tag)+1:], union: tag == "union"}
+			if tag == "enum" {
+				ct.kind = cEnum
+			}
+			return ct
+		}
+	}
+	for _, basic := range cBasics {
+		if basic == name {
+			return &cType{kind: cBasic, name: name}
+		}
+	}
+	if _, ok := s.typedefs[name]; ok || name == "size_t" {
+		return &cType{kind: cTypedef, name: name}
+	}
+	return nil
+}
+
+// declare declares the shim name by decl, unless it is declared.
+func (t *translator) declare(name, decl string) {
+	if _, ok := t.decls[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.decls[name] = decl
+}
+
+// goType returns the Go type of the C type ct, and declares the shims it
+// refers to.
+func (t *translator) goType(s *scope, ct *cType) (string, error) {
+	switch ct.kind {
+	case cBasic:
+		name := t.goName("basic", ct.name)
+		if name == "" {
+			return t.basicGo(ct.name), nil
+		}
+		t.declare(name, fmt.Sprintf("type %s %s", name, t.basicGo(ct.name)))
+		return name, nil
+	case cVoid:
+		return "", errors.New("void isn't a Go type")
+	case cPointer:
+		elem, err := t.underlying(s, ct.elem)
+		if err != nil {
+			return "", err
+		}
+		switch elem.kind {
+		case cVoid:
+			return "unsafe.Pointer", nil
+		case cFunc:
+			return "*[0]byte", nil
+		}
+		goElem, err := t.goType(s, ct.elem)
+		return "*" + goElem, err
+	case cArray:
+		elem, err := t.goType(s, ct.elem)
+		n := ct.len
+		if n < 0 {
+			n = 0 // flexible array member
+		}
+		return fmt.Sprintf("[%d]%s", n, elem), err
+	case cFunc:
+		return "", errors.New("a C function isn't a Go type")
+	case cEnum:
+		n := s.tagOf(ct)
+		goElem := "uint32"
+		if n != nil {
+			for _, c := range n.Inner {
+				if c.Kind == "EnumConstantDecl" && strings.HasPrefix(s.consts[c.Name], "-") {
+					goElem = "int32"
+				}
+			}
+		}
+		if ct.name == "" {
+			return goElem, nil
+		}
+		name := t.goName("type", "enum_"+ct.name)
+		t.declare(name, fmt.Sprintf("type %s %s", name, goElem))
+		return name, nil
+	case cRecord:
+		return t.record(s, ct)
+	}
+	name := t.goName("type", ct.name)
+	if _, ok := t.decls[name]; ok {
+		return name, nil
+	}
+	under, err := t.typedefType(s, ct.name)
+	if err != nil {
+		return "", err
+	}
+	t.declare(name, "") // for recursive types
+	goUnder, err := t.goType(s, under)
+	if err != nil {
+		return "", err
+	}
+	t.declare(name, fmt.Sprintf("type %s = %s", name, goUnder))
+	return name, nil
+}
+
+// record returns the Go type of a struct or a union: a struct of the fields
+// of a struct, and an array of the size of a union as cmd/cgo does.
+func (t *translator) record(s *scope, ct *cType) (string, error) {
+	n := s.tagOf(ct)
+	tag := tagOf(ct)
+	name := t.goName("type", tag+"_"+ct.name)
+	if ct.name == "" {
+		if name = t.anons[n]; name == "" {
+			name = t.goName("type", fmt.Sprintf("%s___%d", tag, len(t.anons)))
+			t.anons[n] = name
+		}
+	}
+	if _, ok := t.decls[name]; ok {
+		return name, nil
+	}
+	t.declare(name, "") // for recursive types
+	if n == nil || !n.CompleteDefinition {
+		t.declare(name, fmt.Sprintf("type %s struct{}", name))
+		return name, nil
+	}
+	if ct.union {
+		size, _, err := t.sizeof(s, ct)
+		if err != nil {
+			return "", err
+		}
+		t.declare(name, fmt.Sprintf("type %s [%d]byte", name, size))
+		return name, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	err := s.fields(n, func(i int, f *clang.Node, ft *cType) error {
+		goType, err := t.goType(s, ft)
+		if err != nil {
+			return err
+		}
+		fname := f.Name
+		if fname == "" {
+			fname = fmt.Sprintf("anon%d", i)
+		}
+		fname = t.goName("field", fname)
+		fmt.Fprintf(&b, "\t%s %s\n", fname, goType)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", tag, ct.name, err)
+	}
+	b.WriteString("}")
+	t.declare(name, b.String())
+	return name, nil
+}
+
+// fields calls fn for the fields of record n with their types.
+func (s *scope) fields(n *clang.Node, fn func(i int, f *clang.Node, ft *cType) error) error {
+	var prev *clang.Node
+	i := 0
+	for _, f := range n.Inner {
+		switch f.Kind {
+		case "RecordDecl", "EnumDecl":
+			prev = f
+		case "FieldDecl":
+			if f.IsBitfield {
+				return errors.New("bit fields aren't supported")
+			}
+			ft, err := parseCType(f.Type.QualType, prev)
+			if err != nil {
+				return err
+			}
+			if err = fn(i, f, ft); err != nil {
+				return err
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// tagOf returns the declaration of the record or enum ct.
+func (s *scope) tagOf(ct *cType) *clang.Node {
+	if ct.name == "" {
+		return ct.anon
+	}
+	return s.tags[tagOf(ct)+" "+ct.name]
+}
+
+// typedefType returns the type that the typedef name stands for. size_t is
+// known even if the preamble doesn't include stddef.h, as C.malloc uses it.
+//
+// The anonymous record or enum that a typedef defines is spelled as it is
+// named for linkage, eg. struct point for typedef struct {...} point, or as
+// (unnamed struct at file:line:col).
+func (t *translator) typedefType(s *scope, name string) (*cType, error) {
+	n, ok := s.typedefs[name]
+	if !ok {
+		if t.conf.GOOS == "windows" && t.ptrSize() == 8 {
+			return &cType{kind: cBasic, name: "ulonglong"}, nil
+		}
+		return &cType{kind: cBasic, name: "ulong"}, nil
+	}
+	anon := s.anonOf[n]
+	ct, err := parseCType(n.Type.QualType, anon)
+	if err != nil || anon == nil {
+		return ct, err
+	}
+	base := ct
+	for base.elem != nil {
+		base = base.elem
+	}
+	if base.name == name && base.kind != cBasic {
+		base.kind, base.name, base.anon, base.union = cRecord, "", anon, anon.TagUsed == "union"
+		if anon.Kind == "EnumDecl" {
+			base.kind = cEnum
+		}
+	}
+	return ct, nil
+}
+
+// underlying returns ct with its typedefs resolved.
+func (t *translator) underlying(s *scope, ct *cType) (*cType, error) {
+	for ct.kind == cTypedef {
+		var err error
+		if ct, err = t.typedefType(s, ct.name); err != nil {
+			return nil, err
+		}
+	}
+	return ct, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// basicGo returns the Go type of the basic C type name for the target.
+func (t *translator) basicGo(name string) string {
+	switch name {
+	case "char":
+		if t.charUnsigned() {
+			return "uint8"
+		}
+		return "int8"
+	case "schar":
+		return "int8"
+	case "uchar":
+		return "uint8"
+	case "short":
+		return "int16"
+	case "ushort":
+		return "uint16"
+	case "int":
+		return "int32"
+	case "uint":
+		return "uint32"
+	case "long":
+		return fmt.Sprintf("int%d", t.longSize()*8)
+	case "ulong":
+		return fmt.Sprintf("uint%d", t.longSize()*8)
+	case "longlong":
+		return "int64"
+	case "ulonglong":
+		return "uint64"
+	case "float":
+		return "float32"
+	case "double":
+		return "float64"
+	}
+	return "bool" // _Bool
+}
+
+// charUnsigned reports whether char is unsigned on the target, as it is on
+// ARM, except on Apple and Windows platforms, and RISC-V.
+func (t *translator) charUnsigned() bool {
+	switch t.conf.GOARCH {
+	case "arm", "arm64":
+		return t.conf.GOOS != "darwin" && t.conf.GOOS != "ios" && t.conf.GOOS != "windows"
+	case "riscv64", "ppc64", "ppc64le", "s390x":
+		return true
+	}
+	return false
+}
+
+// ptrSize returns the size of pointers on the target.
+func (t *translator) ptrSize() int64 {
+	switch t.conf.GOARCH {
+	case "386", "arm", "wasm", "mips", "mipsle":
+		return 4
+	}
+	return 8
+}
+
+// longSize returns the size of long on the target, which is 4 on Windows.
+func (t *translator) longSize() int64 {
+	if t.conf.GOOS == "windows" {
+		return 4
+	}
+	return t.ptrSize()
+}
+
+// sizeof returns the size and the alignment of the C type ct.
+func (t *translator) sizeof(s *scope, ct *cType) (size, align int64, err error) {
+	switch ct.kind {
+	case cBasic:
+		switch ct.name {
+		case "char", "schar", "uchar", "_Bool":
+			size = 1
+		case "short", "ushort":
+			size = 2
+		case "int", "uint", "float":
+			size = 4
+		case "long", "ulong":
+			size = t.longSize()
+		default:
+			size = 8
+		}
+		return size, size, nil
+	case cPointer:
+		return t.ptrSize(), t.ptrSize(), nil
+	case cEnum:
+		return 4, 4, nil
+	case cArray:
+		if size, align, err = t.sizeof(s, ct.elem); ct.len < 0 {
+			return 0, align, err
+		}
+		return size * ct.len, align, err
+	case cTypedef:
+		under, err := t.typedefType(s, ct.name)
+		if err != nil {
+			return 0, 0, err
+		}
+		return t.sizeof(s, under)
+	case cRecord:
+		n := s.tagOf(ct)
+		if n == nil || !n.CompleteDefinition {
+			return 0, 0, fmt.Errorf("%s %s is incomplete", tagOf(ct), ct.name)
+		}
+		align = 1
+		err = s.fields(n, func(i int, f *clang.Node, ft *cType) error {
+			fsize, falign, err := t.sizeof(s, ft)
+			if falign > align {
+				align = falign
+			}
+			if ct.union {
+				if fsize > size {
+					size = fsize
+				}
+			} else {
+				size = (size+falign-1)/falign*falign + fsize
+			}
+			return err
+		})
+		return (size + align - 1) / align * align, align, err
+	}
+	return 0, 0, errors.New("type has no size")
+}
+
+// -----------------------------------------------------------------------------
+
+// function returns the shim of the C function name declared by fn, which is
+// a bodyless Go function linked to it, or to its wrapper if it is static.
+func (t *translator) function(s *scope, name string, fn *clang.Node) (string, error) {
+	goName := t.goName("func", name)
+	if _, ok := t.decls[goName]; ok {
+		return goName, nil
+	}
+	ct, err := parseCType(fn.Type.QualType, nil)
+	if err != nil {
+		return "", err
+	}
+	if ct.kind != cFunc {
+		return "", errors.New("not a function")
+	}
+	if ct.variadic && !t.bind {
+		return "", errors.New("calling variadic C functions isn't supported")
+	}
+	params := make([]string, len(ct.params))
+	cParams := make([]string, len(ct.params))
+	args := make([]string, len(ct.params))
+	for i, param := range ct.params {
+		goType, err := t.goType(s, param)
+		if err != nil {
+			return "", err
+		}
+		args[i] = fmt.Sprintf("p%d", i)
+		if t.bind {
+			args[i] = paramName(fn, i)
+		}
+		params[i] = args[i] + " " + goType
+		if cParams[i], err = cDecl(param, args[i]); err != nil {
+			return "", err
+		}
+	}
+	var result string
+	ret, err := t.underlying(s, ct.elem)
+	if err != nil {
+		return "", err
+	}
+	if ret.kind != cVoid {
+		if result, err = t.goType(s, ct.elem); err != nil {
+			return "", err
+		}
+		result = " " + result
+	}
+	sym := name
+	if s.statics[name] {
+		sym = t.prefix + name
+		if len(cParams) == 0 {
+			cParams = []string{"void"}
+		}
+		sig, err := cDecl(ct.elem, sym+"("+strings.Join(cParams, ", ")+")")
+		if err != nil {
+			return "", err
+		}
+		call := fmt.Sprintf("%s(%s);", name, strings.Join(args, ", "))
+		if ret.kind != cVoid {
+			call = "return " + call
+		}
+		fmt.Fprintf(&s.wrappers, "%s { %s }\n", sig, call)
+	}
+	if ct.variadic {
+		params = append(params, llssa.NameValist+" ...any")
+	}
+	t.declare(goName, fmt.Sprintf("//llgo:link C.%s\nfunc %s(%s)%s", sym, goName, strings.Join(params, ", "), result))
+	return goName, nil
+}
+
+// paramName returns the Go name of the i-th parameter of the C function fn,
+// which is named as in its declaration if it is named there.
+func paramName(fn *clang.Node, i int) string {
+	n := 0
+	for _, c := range fn.Inner {
+		if c.Kind != "ParmVarDecl" {
+			continue
+		}
+		if n++; n <= i {
+			continue
+		}
+		switch name := c.Name; {
+		case name == "" || name == "_":
+		case token.IsKeyword(name) || name == "unsafe" || name == llssa.NameValist || types.Universe.Lookup(name) != nil:
+			return name + "_"
+		default:
+			return name
+		}
+		break
+	}
+	return fmt.Sprintf("p%d", i)
+}
+
+// variable returns the shim of the C variable name declared by v: a wrapper
+// returns its address, which is dereferenced.
+func (t *translator) variable(s *scope, name string, v *clang.Node) (string, error) {
+	goName := "_Cvar_" + name
+	expr := "(*" + goName + "())"
+	if _, ok := t.decls[goName]; ok {
+		return expr, nil
+	}
+	ct, err := parseCType(v.Type.QualType, nil)
+	if err != nil {
+		return "", err
+	}
+	goType, err := t.goType(s, ct)
+	if err != nil {
+		return "", err
+	}
+	sym := t.prefix + "var_" + name
+	fmt.Fprintf(&s.wrappers, "__typeof__(%s) *%s(void) { return &%s; }\n", name, sym, name)
+	t.declare(goName, fmt.Sprintf("//llgo:link C.%s\nfunc %s() *%s", sym, goName, goType))
+	return expr, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// cgoHelpers are the functions that cgo provides to convert values between Go
+// and C, by name: the C types their Go declarations refer to, their Go
+// signatures and their C definitions. They are C functions, compiled in
+// _cgo_helpers.c, which receive and return Go strings and slices by the C ABI
+// as the structs _GoString_ and _GoSlice_.
+var cgoHelpers = map[string]struct {
+	deps []string
+	sig  string
+	def  string
+}{
+	"_CMalloc": {[]string{"size_t"}, "(n _Ctype_size_t) unsafe.Pointer", `void *%s_CMalloc(size_t n) {
+	void *p = malloc(n ? n : 1);
+	if (p == NULL) {
+		fputs("runtime: C malloc failed\n", stderr);
+		abort();
+	}
+	return p;
+}`},
+	"CString": {[]string{"char"}, "(s string) *_Ctype_char", `char *%sCString(_GoString_ s) {
+	char *p = %[1]s_CMalloc(s.n + 1);
+	memcpy(p, s.p, s.n);
+	p[s.n] = 0;
+	return p;
+}`},
+	"CBytes": {nil, "(b []byte) unsafe.Pointer", `void *%sCBytes(_GoSlice_ b) {
+	void *p = %[1]s_CMalloc(b.n);
+	memcpy(p, b.p, b.n);
+	return p;
+}`},
+	"GoString": {[]string{"char"}, "(s *_Ctype_char) string", `_GoString_ %sGoString(const char *s) {
+	return %[1]sGoStringN(s, s ? strlen(s) : 0);
+}`},
+	"GoStringN": {[]string{"char", "int"}, "(s *_Ctype_char, n _Ctype_int) string", `_GoString_ %sGoStringN(const char *s, int n) {
+	_GoString_ ret = {NULL, 0};
+	if (n > 0) {
+		char *p = _cgo_alloc(n);
+		memcpy(p, s, n);
+		ret.p = p, ret.n = n;
+	}
+	return ret;
+}`},
+	"GoBytes": {[]string{"int"}, "(p unsafe.Pointer, n _Ctype_int) []byte", `_GoSlice_ %sGoBytes(const void *p, int n) {
+	_GoSlice_ ret = {NULL, 0, 0};
+	if (n > 0) {
+		ret.p = _cgo_alloc(n);
+		memcpy(ret.p, p, n);
+		ret.n = ret.c = n;
+	}
+	return ret;
+}`},
+}
+
+// helpersHeader is the beginning of _cgo_helpers.c. Go memory is allocated by
+// runtime.AllocZ, whose symbol is named with the asm label of the target.
+const helpersHeader = `#include <stddef.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef struct { const char *p; ptrdiff_t n; } _GoString_;
+typedef struct { void *p; ptrdiff_t n; ptrdiff_t c; } _GoSlice_;
+
+#define _cgo_str(x) #x
+#define _cgo_xstr(x) _cgo_str(x)
+
+extern void *_cgo_alloc(size_t n) __asm__(_cgo_xstr(__USER_LABEL_PREFIX__) "github.com/goplus/llgo/internal/runtime.AllocZ");
+
+`
+
+// helperDeps are the helpers that the C definitions of helpers call.
+var helperDeps = map[string][]string{
+	"CString":  {"_CMalloc"},
+	"CBytes":   {"_CMalloc"},
+	"GoString": {"GoStringN"},
+}
+
+// helper returns the Go name of the helper name, and declares it.
+func (t *translator) helper(s *scope, name string) (string, error) {
+	h := cgoHelpers[name]
+	for _, dep := range h.deps {
+		if _, err := t.goType(s, s.typeNamed(dep)); err != nil {
+			return "", err
+		}
+	}
+	goName := "_Cfunc_" + name
+	if _, ok := t.decls[goName]; !ok {
+		for _, dep := range helperDeps[name] {
+			if _, err := t.helper(s, dep); err != nil {
+				return "", err
+			}
+		}
+		t.helpers = append(t.helpers, name)
+		t.declare(goName, fmt.Sprintf("//llgo:link C.%s%s\nfunc %s%s", t.prefix, name, goName, h.sig))
+	}
+	return goName, nil
+}
+
+// helpersFile returns the C source of the helpers that the package uses, or
+// nil if it uses none.
+func (t *translator) helpersFile() []byte {
+	if len(t.helpers) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString(helpersHeader)
+	for _, name := range t.helpers {
+		fmt.Fprintf(&b, cgoHelpers[name].def+"\n\n", t.prefix)
+	}
+	return []byte(b.String())
+}
+
+// -----------------------------------------------------------------------------
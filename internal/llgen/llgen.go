@@ -62,13 +62,13 @@ func Gen(pkgPath, inFile string, src any) string {
 	}
 	pkg := types.NewPackage(pkgPath, name)
 	imp := packages.NewImporter(fset)
+	prog := llssa.NewProgram(nil)
 	ssaPkg, _, err := ssautil.BuildPackage(
-		&types.Config{Importer: imp}, fset, pkg, files, ssa.SanityCheckFunctions)
+		&types.Config{Importer: imp, Sizes: prog.TypeSizes()}, fset, pkg, files, ssa.SanityCheckFunctions)
 	check(err)
 
 	ssaPkg.WriteTo(os.Stderr)
 
-	prog := llssa.NewProgram(nil)
 	ret, err := cl.NewPackage(prog, ssaPkg, files)
 	check(err)
 
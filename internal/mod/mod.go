@@ -17,8 +17,13 @@
 package mod
 
 import (
+	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/goplus/mod"
 	"github.com/goplus/mod/gopmod"
@@ -46,3 +51,79 @@ func Load(dir string) (ret *Module, pkgPath string, err error) {
 	pkgPath = path.Join(ret.Path(), filepath.ToSlash(relPath))
 	return
 }
+
+// Vendored reports whether the go command loads the dependencies of module m
+// from its vendor directory by default, that is if it has a
+// vendor/modules.txt file and its go version is at least 1.14.
+func Vendored(m *Module) bool {
+	if _, err := os.Stat(filepath.Join(m.Root(), "vendor", "modules.txt")); err != nil {
+		return false
+	}
+	if m.File == nil || m.Go == nil {
+		return false
+	}
+	major, minor, _ := strings.Cut(m.Go.Version, ".")
+	minor, _, _ = strings.Cut(minor, ".")
+	x, _ := strconv.Atoi(major)
+	y, _ := strconv.Atoi(minor)
+	return x > 1 || x == 1 && y >= 14
+}
+
+// TempModule creates a temporary directory that contains a go.mod file of
+// module main, whose go version is the one of the go command (see goVersion).
+// It is used to load Go files that aren't in any module. The caller should
+// remove the directory when it is no longer needed.
+func TempModule() (dir string, err error) {
+	if dir, err = os.MkdirTemp("", "llgo-mod-"); err != nil {
+		return
+	}
+	if err = os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module main\n\ngo "+goVersion()+"\n"), 0644); err != nil {
+		os.RemoveAll(dir)
+	}
+	return
+}
+
+// goVersion returns the language version of the go command, eg. 1.22 for
+// go1.22.3, so that files outside of modules may use all the features of the
+// toolchain that loads them. If the go command doesn't report its version,
+// it's the one of the toolchain that built llgo.
+func goVersion() string {
+	if out, err := exec.Command("go", "env", "GOVERSION").Output(); err == nil {
+		if v, ok := langVersion(strings.TrimSpace(string(out))); ok {
+			return v
+		}
+	}
+	if v, ok := langVersion(runtime.Version()); ok {
+		return v
+	}
+	return "1.18"
+}
+
+// langVersion returns the language version of the Go release v, eg. 1.22 for
+// go1.22.3 or go1.22rc1. It fails for development versions, eg. "devel
+// go1.23-abcdef Mon Jan 1 00:00:00 2024 +0000".
+func langVersion(v string) (string, bool) {
+	if !strings.HasPrefix(v, "go") {
+		return "", false
+	}
+	major, rest, _ := strings.Cut(v[2:], ".")
+	i := 0
+	for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+		i++
+	}
+	if _, err := strconv.Atoi(major); err != nil || i == 0 {
+		return "", false
+	}
+	return major + "." + rest[:i], true
+}
+
+// Get runs go get query, eg. example.com/cmd@v1.2.0, in the module in dir,
+// with the workspace disabled.
+func Get(dir, query string) error {
+	cmd := exec.Command("go", "get", query)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
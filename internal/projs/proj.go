@@ -19,6 +19,7 @@ package projs
 import (
 	"errors"
 	"path/filepath"
+	"strings"
 	"syscall"
 )
 
@@ -65,6 +66,9 @@ func ParseOne(args ...string) (proj Proj, next []string, err error) {
 }
 
 func isFile(fname string) bool {
+	if strings.Contains(fname, "@") { // pkg@version
+		return false
+	}
 	n := len(filepath.Ext(fname))
 	return n > 1
 }
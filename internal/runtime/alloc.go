@@ -0,0 +1,36 @@
+//go:build !gc.boehm && !gc.precise && !gc.none
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// AllocZ allocates a zero-initialized variable of size bytes on the heap. With
+// gc=leaking, the heap is the one of libc, and memory is never freed.
+func AllocZ(size uintptr) unsafe.Pointer {
+	memProfileAlloc(size)
+	return c.Calloc(1, size)
+}
+
+// gcSetFinalizer does nothing: as memory is never freed, finalizers are never
+// called.
+func gcSetFinalizer(p, fn unsafe.Pointer) {}
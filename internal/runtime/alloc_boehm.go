@@ -0,0 +1,95 @@
+//go:build gc.boehm
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// AllocZ allocates a zero-initialized variable of size bytes on the heap
+// managed by the Boehm GC, a conservative collector. It initializes itself on
+// the first allocation, and registers the data and bss segments of the
+// program, where global variables live, as roots.
+func AllocZ(size uintptr) unsafe.Pointer {
+	memProfileAlloc(size)
+	return c.GCMalloc(size)
+}
+
+// gcSetFinalizer sets the finalizer of the object p, which runFinalizer calls
+// fn with, or clears it if fn is nil. The Boehm GC calls the finalizers of
+// unreachable objects in the allocations after a collection, and finalizes an
+// object that is reachable from another object with a finalizer after it.
+func gcSetFinalizer(p, fn unsafe.Pointer) {
+	if fn == nil {
+		c.GCRegisterFinalizer(p, nil, nil, nil, nil)
+		return
+	}
+	c.GCRegisterFinalizer(p, runFinalizer, fn, nil, nil)
+}
+
+// gcDisabled reports whether gcSetPercent disabled the collector.
+var gcDisabled bool
+
+// gcSetPercent tunes the collector for debug.SetGCPercent: the heap grows by
+// 1/divisor of its size between collections, and the default divisor of the
+// Boehm GC, 3, stands for 100 percent, the default of Go. A negative percent
+// disables the collector.
+func gcSetPercent(percent int) {
+	if percent < 0 {
+		if !gcDisabled {
+			c.GCDisable()
+			gcDisabled = true
+		}
+		return
+	}
+	if gcDisabled {
+		c.GCEnable()
+		gcDisabled = false
+	}
+	divisor := uintptr(1000)
+	if percent > 0 {
+		divisor = uintptr(300 / percent)
+		if divisor < 1 {
+			divisor = 1
+		}
+	}
+	c.GCSetFreeSpaceDivisor(divisor)
+}
+
+// gcFreeOSMemory runs a collection and returns the free memory to the OS,
+// for debug.FreeOSMemory.
+func gcFreeOSMemory() {
+	c.GCGcollectAndUnmap()
+}
+
+// gcRecordStats makes the collector report its collections to gcEvent.
+func gcRecordStats() {
+	c.GCSetOnCollectionEvent(gcCollectionEvent)
+}
+
+func gcCollectionEvent(event c.Int) {
+	switch event {
+	case c.GCEventStart:
+		gcEvent(true)
+	case c.GCEventEnd:
+		gcEvent(false)
+	}
+}
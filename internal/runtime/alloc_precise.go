@@ -0,0 +1,44 @@
+//go:build gc.precise
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// AllocZ allocates a zero-initialized variable of size bytes on the heap
+// managed by the precise collector, which is linked to executables by the
+// build driver. The collector finds the pointers on the stack by the stack
+// maps that LLVM emits for the calls of llgo functions.
+func AllocZ(size uintptr) unsafe.Pointer {
+	memProfileAlloc(size)
+	return c.GCAlloc(size)
+}
+
+// gcSetFinalizer sets the finalizer of the object p, which runFinalizer calls
+// fn with, or clears it if fn is nil (see preciseCollector in internal/build).
+func gcSetFinalizer(p, fn unsafe.Pointer) {
+	if fn == nil {
+		c.GCSetFinalizer(p, nil, nil)
+		return
+	}
+	c.GCSetFinalizer(p, runFinalizer, fn)
+}
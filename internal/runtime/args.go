@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The C entry point main of a program that uses the os package passes its
+// arguments and its environment to ArgsInit before it initializes the
+// packages (see cMainSig in cl). The compiler turns os.Args into the variable
+// Args below, and os.Getenv, os.LookupEnv and os.Environ into calls to the
+// functions below, which read the environment that the program was started
+// with, as os.Setenv isn't supported.
+
+// Args implements os.Args.
+var Args []string
+
+// envp is the NULL-terminated array of the "key=value" strings of the
+// environment, or nil if ArgsInit wasn't called.
+var envp *c.Pointer
+
+// ArgsInit sets Args and the environment from the parameters of main.
+func ArgsInit(argc int32, argv, env unsafe.Pointer) {
+	argc, argv, env = sysargs(argc, argv, env)
+	if argc > 0 {
+		args := unsafe.Slice((**c.Char)(argv), argc)
+		Args = unsafe.Slice((*string)(AllocZ(uintptr(argc)*unsafe.Sizeof(""))), argc)
+		for i, arg := range args {
+			Args[i] = gostring(arg)
+		}
+	}
+	envp = (*c.Pointer)(env)
+}
+
+// envAt returns the ith string of the environment, or nil past its end.
+func envAt(i int) *c.Char {
+	if envp == nil {
+		return nil
+	}
+	return *(**c.Char)(unsafe.Add(unsafe.Pointer(envp), uintptr(i)*unsafe.Sizeof(envp)))
+}
+
+// envHasKey reports whether e, a "key=value" string of the environment, is
+// the one of key.
+func envHasKey(e *c.Char, key string) bool {
+	h := (*stringHeader)(unsafe.Pointer(&key))
+	for i := 0; i < h.len; i++ {
+		ch := *(*c.Char)(unsafe.Add(h.data, i))
+		if ch == 0 || *(*c.Char)(unsafe.Add(c.Pointer(e), i)) != ch {
+			return false
+		}
+	}
+	return *(*c.Char)(unsafe.Add(c.Pointer(e), h.len)) == '='
+}
+
+// OsLookupEnv implements os.LookupEnv.
+func OsLookupEnv(key string) (string, bool) {
+	for i := 0; ; i++ {
+		e := envAt(i)
+		if e == nil {
+			return "", false
+		}
+		if envHasKey(e, key) {
+			return gostring((*c.Char)(unsafe.Add(c.Pointer(e), len(key)+1))), true
+		}
+	}
+}
+
+// OsGetenv implements os.Getenv.
+func OsGetenv(key string) string {
+	v, _ := OsLookupEnv(key)
+	return v
+}
+
+// OsEnviron implements os.Environ.
+func OsEnviron() []string {
+	n := 0
+	for envAt(n) != nil {
+		n++
+	}
+	ret := unsafe.Slice((*string)(AllocZ(uintptr(n)*unsafe.Sizeof(""))), n)
+	for i := range ret {
+		ret[i] = gostring(envAt(i))
+	}
+	return ret
+}
+
+// gostring returns a copy of the NUL-terminated string s of C.
+func gostring(s *c.Char) string {
+	n := uintptr(0)
+	for *(*c.Char)(unsafe.Add(c.Pointer(s), n)) != 0 {
+		n++
+	}
+	buf := AllocZ(n)
+	c.Memcpy(buf, c.Pointer(s), n)
+	return *(*string)(unsafe.Pointer(&stringHeader{buf, int(n)}))
+}
@@ -0,0 +1,28 @@
+//go:build !wasip1
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// sysargs returns the arguments and the environment of the process, which
+// are the parameters of main. On baremetal targets, main is called without
+// any.
+func sysargs(argc int32, argv, envp unsafe.Pointer) (int32, unsafe.Pointer, unsafe.Pointer) {
+	return argc, argv, envp
+}
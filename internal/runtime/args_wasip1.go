@@ -0,0 +1,47 @@
+//go:build wasip1
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// sysargs returns the arguments and the environment of the process, which
+// WASI passes to the module by functions rather than to main, whose
+// parameters are ignored.
+func sysargs(int32, unsafe.Pointer, unsafe.Pointer) (int32, unsafe.Pointer, unsafe.Pointer) {
+	var argc, argvSize, envc, envSize uintptr
+	if c.WasiArgsSizesGet(&argc, &argvSize) != 0 {
+		argc, argvSize = 0, 0
+	}
+	argv := AllocZ((argc + 1) * unsafe.Sizeof(uintptr(0)))
+	if argc > 0 && c.WasiArgsGet((**c.Char)(argv), (*c.Char)(AllocZ(argvSize))) != 0 {
+		argc = 0
+	}
+	if c.WasiEnvironSizesGet(&envc, &envSize) != 0 {
+		envc, envSize = 0, 0
+	}
+	envp := AllocZ((envc + 1) * unsafe.Sizeof(uintptr(0)))
+	if envc > 0 && c.WasiEnvironGet((**c.Char)(envp), (*c.Char)(AllocZ(envSize))) != 0 {
+		c.Memset(envp, 0, (envc+1)*unsafe.Sizeof(uintptr(0)))
+	}
+	return int32(argc), argv, envp
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package c declares the libc functions used by the llgo runtime.
+//
+// The func types that the package names, eg. SigHandler, are types of C
+// function pointers, as it is a C package, which declares LLGoPackage: the
+// func values of other types are closures, which C can't call, and are
+// converted to them (see llssa.IsCFuncType).
+package c
+
+import "unsafe"
+
+const (
+	LLGoPackage = true
+)
+
+type (
+	Char    = int8
+	Int     = int32
+	Uint    = uint32
+	Long    = int64
+	Ulong   = uint64
+	Pointer = unsafe.Pointer
+)
+
+//go:linkname Malloc malloc
+func Malloc(size uintptr) Pointer
+
+//go:linkname Calloc calloc
+func Calloc(num uintptr, size uintptr) Pointer
+
+//go:linkname Free free
+func Free(ptr Pointer)
+
+//go:linkname Memcmp memcmp
+func Memcmp(s1, s2 Pointer, n uintptr) Int
+
+//go:linkname Printf printf
+func Printf(format *Char, __llgo_va_list ...any) Int
+
+//go:linkname Snprintf snprintf
+func Snprintf(buf *Char, n uintptr, format *Char, __llgo_va_list ...any) Int
+
+//go:linkname Strtod strtod
+func Strtod(s *Char, end **Char) float64
+
+//go:linkname Exit exit
+func Exit(code Int)
+
+//go:linkname Abort abort
+func Abort()
+
+//go:linkname Getenv getenv
+func Getenv(name *Char) *Char
+
+//go:linkname Atoi atoi
+func Atoi(s *Char) Int
@@ -0,0 +1,29 @@
+//go:build linux && !amd64 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package c
+
+// EpollEvent represents a struct epoll_event, which isn't packed on targets
+// other than amd64. The data of the events that the runtime registers is a
+// file descriptor: the fd of the union epoll_data, which is at its start
+// whatever the byte order of the target.
+type EpollEvent struct {
+	Events uint32
+	_      int32
+	Fd     int32
+	_      int32
+}
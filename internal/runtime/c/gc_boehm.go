@@ -0,0 +1,82 @@
+//go:build gc.boehm
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The functions of the Boehm GC (bdwgc), declared in <gc/gc.h>.
+
+//go:linkname GCInit GC_init
+func GCInit()
+
+//go:linkname GCMalloc GC_malloc
+func GCMalloc(size uintptr) Pointer
+
+//go:linkname GCMallocAtomic GC_malloc_atomic
+func GCMallocAtomic(size uintptr) Pointer
+
+//go:linkname GCFree GC_free
+func GCFree(ptr Pointer)
+
+//go:linkname GCAddRoots GC_add_roots
+func GCAddRoots(low, high Pointer)
+
+//go:linkname GCCollect GC_gcollect
+func GCCollect()
+
+//go:linkname GCRemoveRoots GC_remove_roots
+func GCRemoveRoots(low, high Pointer)
+
+//go:linkname GCGcollectAndUnmap GC_gcollect_and_unmap
+func GCGcollectAndUnmap()
+
+//go:linkname GCDisable GC_disable
+func GCDisable()
+
+//go:linkname GCEnable GC_enable
+func GCEnable()
+
+//go:linkname GCSetFreeSpaceDivisor GC_set_free_space_divisor
+func GCSetFreeSpaceDivisor(divisor uintptr)
+
+// GCFinalizationProc is a finalizer, which is called with the object and the
+// argument it was registered with.
+type GCFinalizationProc func(obj, arg Pointer)
+
+// GCRegisterFinalizer sets the finalizer of the object p, which fn is called
+// with p and arg, or clears it if fn is nil. The previous finalizer and arg
+// are stored to ofn and oarg, unless they are nil.
+//
+//go:linkname GCRegisterFinalizer GC_register_finalizer
+func GCRegisterFinalizer(p Pointer, fn GCFinalizationProc, arg Pointer, ofn, oarg *Pointer)
+
+// Events of a collection, which GCSetOnCollectionEvent reports.
+const (
+	GCEventStart = 0
+	GCEventEnd   = 5
+)
+
+// GCOnCollectionEventProc is called on the events of collections.
+type GCOnCollectionEventProc func(event Int)
+
+// GCSetOnCollectionEvent sets the function that is called on the events of
+// collections, with the allocation lock held: it must not allocate.
+//
+//go:linkname GCSetOnCollectionEvent GC_set_on_collection_event
+func GCSetOnCollectionEvent(fn GCOnCollectionEventProc)
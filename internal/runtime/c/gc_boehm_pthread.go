@@ -0,0 +1,67 @@
+//go:build gc.boehm && !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// GCPthreadCreate creates a thread as PthreadCreate does, and registers it to
+// the collector, which scans its stack.
+//
+//go:linkname GCPthreadCreate GC_pthread_create
+func GCPthreadCreate(th *Pthread, attr Pointer, routine ThreadRoutine, arg Pointer) Int
+
+// GCRegisterAltstack registers an alternate stack of the current thread: when
+// the stack pointer of the thread is in it, the collector scans it instead of
+// the stack of the thread.
+//
+//go:linkname GCRegisterAltstack GC_register_altstack
+func GCRegisterAltstack(normstack Pointer, normstackSize uintptr, altstack Pointer, altstackSize uintptr)
+
+// GCStackBase is the base of the stack of a thread, the end from which it
+// grows.
+type GCStackBase struct {
+	MemBase Pointer
+}
+
+// GCSuccess is the result of the functions below that succeed.
+const GCSuccess = 0
+
+// GCAllowRegisterThreads allows threads that the collector didn't create to
+// register themselves with GCRegisterMyThread. It must be called by a thread
+// that the collector knows.
+//
+//go:linkname GCAllowRegisterThreads GC_allow_register_threads
+func GCAllowRegisterThreads()
+
+// GCThreadIsRegistered reports whether the collector knows the calling
+// thread.
+//
+//go:linkname GCThreadIsRegistered GC_thread_is_registered
+func GCThreadIsRegistered() Int
+
+// GCGetStackBase gets the base of the stack of the calling thread.
+//
+//go:linkname GCGetStackBase GC_get_stack_base
+func GCGetStackBase(sb *GCStackBase) Int
+
+// GCRegisterMyThread registers the calling thread, whose stack base is sb, to
+// the collector, which scans its stack then.
+//
+//go:linkname GCRegisterMyThread GC_register_my_thread
+func GCRegisterMyThread(sb *GCStackBase) Int
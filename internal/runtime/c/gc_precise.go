@@ -0,0 +1,37 @@
+//go:build gc.precise
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The allocator of the precise collector, which the build driver links to
+// executables (see preciseCollector in internal/build).
+
+//go:linkname GCAlloc llgo_gc_alloc
+func GCAlloc(size uintptr) Pointer
+
+// GCFinalizer is a finalizer, which is called with the object and the
+// argument it was set with.
+type GCFinalizer func(obj, arg Pointer)
+
+// GCSetFinalizer sets the finalizer of the object p, which fn is called with
+// p and arg, or clears it if fn is nil.
+//
+//go:linkname GCSetFinalizer llgo_gc_set_finalizer
+func GCSetFinalizer(p Pointer, fn GCFinalizer, arg Pointer)
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The types and functions of bionic, the libc of Android, that differ in glibc
+// (see libc_linux.go). Android is linux to the other files of the package.
+
+//go:linkname errnoLocation __errno
+func errnoLocation() *Int
+
+// SigactionT represents a struct sigaction of bionic on 64-bit targets.
+type SigactionT struct {
+	Flags    Int
+	Handler  SigactionHandler // sa_sigaction
+	Mask     uint64
+	Restorer Pointer
+}
+
+// Addrinfo represents a struct addrinfo of bionic, which, as the one of BSD,
+// has Canonname before Addr.
+type Addrinfo struct {
+	Flags     Int
+	Family    Int
+	Socktype  Int
+	Protocol  Int
+	Addrlen   Uint
+	Canonname *Char
+	Addr      Pointer
+	Next      *Addrinfo
+}
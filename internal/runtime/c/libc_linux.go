@@ -0,0 +1,47 @@
+//go:build !android && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The types and functions of glibc, and of other libcs of Linux whose ABI
+// agrees, that differ in bionic, the libc of Android (see libc_android.go).
+
+//go:linkname errnoLocation __errno_location
+func errnoLocation() *Int
+
+// SigactionT represents a struct sigaction of glibc.
+type SigactionT struct {
+	Handler  SigactionHandler // sa_sigaction
+	Mask     [16]uint64
+	Flags    Int
+	Restorer Pointer
+}
+
+// Addrinfo represents a struct addrinfo of glibc.
+type Addrinfo struct {
+	Flags     Int
+	Family    Int
+	Socktype  Int
+	Protocol  Int
+	Addrlen   Uint
+	Addr      Pointer
+	Canonname *Char
+	Next      *Addrinfo
+}
@@ -0,0 +1,69 @@
+//go:build hardening
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// With hardening, the memory functions that the runtime calls check their
+// arguments, as the _chk functions of glibc do with _FORTIFY_SOURCE: a size
+// that is larger than any object, as a negative one converted to uintptr is,
+// or memcpy of overlapping memory, aborts the program, rather than letting it
+// overwrite memory that an attacker may control.
+
+//go:linkname memcpy memcpy
+func memcpy(dst, src Pointer, n uintptr) Pointer
+
+//go:linkname memmove memmove
+func memmove(dst, src Pointer, n uintptr) Pointer
+
+//go:linkname memset memset
+func memset(s Pointer, c Int, n uintptr) Pointer
+
+// maxObject is the size of the largest object, PTRDIFF_MAX.
+const maxObject = ^uintptr(0) >> 1
+
+var overflow = [...]Char{'*', '*', '*', ' ', 'b', 'u', 'f', 'f', 'e', 'r', ' ', 'o', 'v', 'e', 'r', 'f', 'l', 'o', 'w', ' ', 'd', 'e', 't', 'e', 'c', 't', 'e', 'd', ' ', '*', '*', '*', '\n', 0}
+
+// chkFail reports that a buffer overflow is detected, and aborts the program.
+func chkFail() {
+	Printf(&overflow[0])
+	Abort()
+}
+
+func Memcpy(dst, src Pointer, n uintptr) Pointer {
+	d, s := uintptr(dst), uintptr(src)
+	if n > maxObject || d < s+n && s < d+n {
+		chkFail()
+	}
+	return memcpy(dst, src, n)
+}
+
+func Memmove(dst, src Pointer, n uintptr) Pointer {
+	if n > maxObject {
+		chkFail()
+	}
+	return memmove(dst, src, n)
+}
+
+func Memset(s Pointer, c Int, n uintptr) Pointer {
+	if n > maxObject {
+		chkFail()
+	}
+	return memset(s, c, n)
+}
@@ -0,0 +1,64 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package c
+
+import _ "unsafe"
+
+//go:linkname Socket socket
+func Socket(domain, typ, protocol Int) Int
+
+//go:linkname Bind bind
+func Bind(fd Int, addr Pointer, addrlen Uint) Int
+
+//go:linkname Listen listen
+func Listen(fd Int, backlog Int) Int
+
+//go:linkname Accept accept
+func Accept(fd Int, addr Pointer, addrlen *Uint) Int
+
+//go:linkname Connect connect
+func Connect(fd Int, addr Pointer, addrlen Uint) Int
+
+//go:linkname Setsockopt setsockopt
+func Setsockopt(fd, level, name Int, val Pointer, vallen Uint) Int
+
+//go:linkname Getsockopt getsockopt
+func Getsockopt(fd, level, name Int, val Pointer, vallen *Uint) Int
+
+//go:linkname Fcntl fcntl
+func Fcntl(fd, cmd Int, __llgo_va_list ...any) Int
+
+//go:linkname Getaddrinfo getaddrinfo
+func Getaddrinfo(node, service *Char, hints *Addrinfo, res **Addrinfo) Int
+
+//go:linkname Freeaddrinfo freeaddrinfo
+func Freeaddrinfo(res *Addrinfo)
+
+//go:linkname GaiStrerror gai_strerror
+func GaiStrerror(errcode Int) *Char
+
+// Constants of sockets, which are the same on all supported platforms.
+const (
+	AfUnspec   = 0
+	AfInet     = 2
+	SockStream = 1
+	AiPassive  = 0x1
+	Somaxconn  = 128
+	FGetfl     = 3
+	FSetfl     = 4
+)
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package c
+
+import _ "unsafe"
+
+// Addrinfo represents a struct addrinfo.
+type Addrinfo struct {
+	Flags     Int
+	Family    Int
+	Socktype  Int
+	Protocol  Int
+	Addrlen   Uint
+	Canonname *Char
+	Addr      Pointer
+	Next      *Addrinfo
+}
+
+// Constants of sockets.
+const (
+	AfInet6     = 30
+	SolSocket   = 0xffff
+	SoReuseaddr = 0x4
+	SoError     = 0x1007
+	ONonblock   = 0x4
+	EAGAIN      = 35
+	EINPROGRESS = 36
+)
+
+//go:linkname Kqueue kqueue
+func Kqueue() Int
+
+//go:linkname Kevent kevent
+func Kevent(kq Int, changes *KeventT, nchanges Int, events *KeventT, nevents Int, timeout *Timespec) Int
+
+// KeventT represents a struct kevent.
+type KeventT struct {
+	Ident  uintptr
+	Filter int16
+	Flags  uint16
+	Fflags uint32
+	Data   int64
+	Udata  Pointer
+}
+
+// Constants of kqueue.
+const (
+	EvfiltRead  = -1
+	EvfiltWrite = -2
+	EvAdd       = 0x1
+	EvClear     = 0x20
+	EvEOF       = 0x8000
+	EvError     = 0x4000
+)
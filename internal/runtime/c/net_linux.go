@@ -0,0 +1,52 @@
+//go:build !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package c
+
+import _ "unsafe"
+
+// Constants of sockets.
+const (
+	AfInet6     = 10
+	SolSocket   = 1
+	SoReuseaddr = 2
+	SoError     = 4
+	ONonblock   = 0x800
+	EAGAIN      = 11
+	EINPROGRESS = 115
+)
+
+//go:linkname EpollCreate1 epoll_create1
+func EpollCreate1(flags Int) Int
+
+//go:linkname EpollCtl epoll_ctl
+func EpollCtl(epfd, op, fd Int, event *EpollEvent) Int
+
+//go:linkname EpollWait epoll_wait
+func EpollWait(epfd Int, events *EpollEvent, maxevents, timeout Int) Int
+
+// Constants of epoll.
+const (
+	EpollCloexec = 0x80000
+	EpollCtlAdd  = 1
+	EpollIn      = 0x1
+	EpollOut     = 0x4
+	EpollErr     = 0x8
+	EpollHup     = 0x10
+	EpollRdhup   = 0x2000
+	EpollEt      = 0x80000000
+)
@@ -0,0 +1,77 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+//go:linkname Open open
+func Open(path *Char, flags Int, __llgo_va_list ...any) Int
+
+//go:linkname Read read
+func Read(fd Int, buf Pointer, n uintptr) Long
+
+//go:linkname Write write
+func Write(fd Int, buf Pointer, n uintptr) Long
+
+//go:linkname Pipe pipe
+func Pipe(fds *[2]Int) Int
+
+//go:linkname Close close
+func Close(fd Int) Int
+
+//go:linkname Syscall syscall
+func Syscall(number Long, __llgo_va_list ...any) Long
+
+//go:linkname Mmap mmap
+func Mmap(addr Pointer, length uintptr, prot, flags, fd Int, offset Long) Pointer
+
+//go:linkname Munmap munmap
+func Munmap(addr Pointer, length uintptr) Int
+
+//go:linkname Mprotect mprotect
+func Mprotect(addr Pointer, length uintptr, prot Int) Int
+
+// Protections of Mmap and Mprotect, which are the same on all supported
+// platforms.
+const (
+	ProtNone  = 0x0
+	ProtRead  = 0x1
+	ProtWrite = 0x2
+	ProtExec  = 0x4
+)
+
+// MapPrivate is the flag of Mmap of a private mapping.
+const MapPrivate = 0x2
+
+//go:linkname Strlen strlen
+func Strlen(s *Char) uintptr
+
+//go:linkname Strerror strerror
+func Strerror(errnum Int) *Char
+
+// Errno returns the value of errno of the calling thread.
+func Errno() Int {
+	return *errnoLocation()
+}
+
+// Error numbers, which are the same on all supported platforms.
+const (
+	EINTR = 4
+	EBADF = 9
+)
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+//go:linkname errnoLocation __error
+func errnoLocation() *Int
+
+//go:linkname PthreadMainNp pthread_main_np
+func PthreadMainNp() Int
+
+// Flags of Open.
+const (
+	ORdonly = 0x0
+	OWronly = 0x1
+	ORdwr   = 0x2
+	OCreat  = 0x200
+	OTrunc  = 0x400
+)
+
+// Flags of Mmap.
+const (
+	MapAnon      = 0x1000
+	MapNoreserve = 0x40
+)
+
+// SIGBUS is the signal of a bus error.
+const SIGBUS = 10
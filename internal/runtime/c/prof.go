@@ -0,0 +1,51 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The functions that the profilers of the runtime use: the CPU profiler is
+// driven by the SIGPROF signals of an interval timer, and both profilers
+// record the return addresses of the call stack by backtrace, as tracebacks
+// do.
+
+// Timeval represents a struct timeval.
+type Timeval struct {
+	Sec  Long
+	Usec Long
+}
+
+// Itimerval represents a struct itimerval.
+type Itimerval struct {
+	Interval Timeval
+	Value    Timeval
+}
+
+// The profiling timer and its signal, which are the same on all supported
+// platforms.
+const (
+	ItimerProf = 2
+	SIGPROF    = 27
+)
+
+//go:linkname Setitimer setitimer
+func Setitimer(which Int, new, old *Itimerval) Int
+
+//go:linkname Backtrace backtrace
+func Backtrace(buf *uintptr, size Int) Int
@@ -0,0 +1,88 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// PthreadMutex represents a pthread_mutex_t. It is large enough (and aligned
+// enough) to hold a pthread_mutex_t on all supported platforms.
+type PthreadMutex [8]uintptr
+
+// PthreadCond represents a pthread_cond_t. It is large enough (and aligned
+// enough) to hold a pthread_cond_t on all supported platforms.
+type PthreadCond [8]uintptr
+
+//go:linkname PthreadMutexInit pthread_mutex_init
+func PthreadMutexInit(m *PthreadMutex, attr Pointer) Int
+
+//go:linkname PthreadMutexDestroy pthread_mutex_destroy
+func PthreadMutexDestroy(m *PthreadMutex) Int
+
+//go:linkname PthreadMutexLock pthread_mutex_lock
+func PthreadMutexLock(m *PthreadMutex) Int
+
+//go:linkname PthreadMutexUnlock pthread_mutex_unlock
+func PthreadMutexUnlock(m *PthreadMutex) Int
+
+//go:linkname PthreadCondInit pthread_cond_init
+func PthreadCondInit(c *PthreadCond, attr Pointer) Int
+
+//go:linkname PthreadCondDestroy pthread_cond_destroy
+func PthreadCondDestroy(c *PthreadCond) Int
+
+//go:linkname PthreadCondWait pthread_cond_wait
+func PthreadCondWait(c *PthreadCond, m *PthreadMutex) Int
+
+//go:linkname PthreadCondSignal pthread_cond_signal
+func PthreadCondSignal(c *PthreadCond) Int
+
+//go:linkname PthreadCondBroadcast pthread_cond_broadcast
+func PthreadCondBroadcast(c *PthreadCond) Int
+
+//go:linkname PthreadCondTimedwait pthread_cond_timedwait
+func PthreadCondTimedwait(c *PthreadCond, m *PthreadMutex, abstime *Timespec) Int
+
+// Pthread represents a pthread_t.
+type Pthread uintptr
+
+// PthreadKey represents a pthread_key_t. It is large enough to hold a
+// pthread_key_t on all supported platforms.
+type PthreadKey uintptr
+
+// ThreadRoutine is the start routine of a thread, which is called with the
+// argument of PthreadCreate.
+type ThreadRoutine func(arg Pointer) Pointer
+
+//go:linkname PthreadCreate pthread_create
+func PthreadCreate(th *Pthread, attr Pointer, routine ThreadRoutine, arg Pointer) Int
+
+//go:linkname PthreadKeyCreate pthread_key_create
+func PthreadKeyCreate(key *PthreadKey, destructor Pointer) Int
+
+//go:linkname PthreadGetspecific pthread_getspecific
+func PthreadGetspecific(key PthreadKey) Pointer
+
+//go:linkname PthreadSetspecific pthread_setspecific
+func PthreadSetspecific(key PthreadKey, v Pointer) Int
+
+//go:linkname Usleep usleep
+func Usleep(usec Uint) Int
+
+//go:linkname Sysconf sysconf
+func Sysconf(name Int) Long
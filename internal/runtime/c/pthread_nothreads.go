@@ -0,0 +1,57 @@
+//go:build wasip1 || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// WASI modules and baremetal programs are single-threaded: there is no other
+// thread to wait for, so the pthread functions are implemented here rather
+// than linked from libc.
+
+// PthreadMutex represents a pthread_mutex_t.
+type PthreadMutex [8]uintptr
+
+// PthreadCond represents a pthread_cond_t.
+type PthreadCond [8]uintptr
+
+func PthreadMutexInit(m *PthreadMutex, attr Pointer) Int { return 0 }
+
+func PthreadMutexDestroy(m *PthreadMutex) Int { return 0 }
+
+func PthreadMutexLock(m *PthreadMutex) Int { return 0 }
+
+func PthreadMutexUnlock(m *PthreadMutex) Int { return 0 }
+
+func PthreadCondInit(c *PthreadCond, attr Pointer) Int { return 0 }
+
+func PthreadCondDestroy(c *PthreadCond) Int { return 0 }
+
+var deadlock = [...]Char{'f', 'a', 't', 'a', 'l', ' ', 'e', 'r', 'r', 'o', 'r', ':', ' ', 'a', 'l', 'l', ' ', 'g', 'o', 'r', 'o', 'u', 't', 'i', 'n', 'e', 's', ' ', 'a', 'r', 'e', ' ', 'a', 's', 'l', 'e', 'e', 'p', ' ', '-', ' ', 'd', 'e', 'a', 'd', 'l', 'o', 'c', 'k', '!', '\n', 0}
+
+// PthreadCondWait never returns: as no other thread can signal c, waiting
+// for it is a deadlock.
+func PthreadCondWait(c *PthreadCond, m *PthreadMutex) Int {
+	Printf(&deadlock[0])
+	Abort()
+	return 0
+}
+
+func PthreadCondSignal(c *PthreadCond) Int { return 0 }
+
+func PthreadCondBroadcast(c *PthreadCond) Int { return 0 }
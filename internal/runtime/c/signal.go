@@ -0,0 +1,63 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// Signals of fatal errors, which are the same on all supported platforms,
+// except SIGBUS.
+const (
+	SIGILL  = 4
+	SIGFPE  = 8
+	SIGSEGV = 11
+)
+
+// Signals that terminate a program, which are the same on all supported
+// platforms.
+const (
+	SIGHUP  = 1
+	SIGINT  = 2
+	SIGQUIT = 3
+	SIGTRAP = 5
+	SIGABRT = 6
+	SIGKILL = 9
+	SIGPIPE = 13
+	SIGALRM = 14
+	SIGTERM = 15
+)
+
+// SigHandler is the handler of a signal, which is called with its number.
+type SigHandler func(sig Int)
+
+// SigactionHandler is the handler of a signal set by Sigaction with the
+// SA_SIGINFO flag, which is called with its number, its information and the
+// context of the thread it interrupted.
+type SigactionHandler func(sig Int, info *Siginfo, ctx Pointer)
+
+// Signal sets the handler of signal sig. The handler is called on the stack
+// of the thread that receives the signal.
+//
+//go:linkname Signal signal
+func Signal(sig Int, handler SigHandler) Pointer
+
+// Sigaction sets the action of signal sig to act, if it isn't nil, and stores
+// the previous one in oact, if it isn't nil.
+//
+//go:linkname Sigaction sigaction
+func Sigaction(sig Int, act, oact *SigactionT) Int
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+// SigactionT represents a struct sigaction.
+type SigactionT struct {
+	Handler SigactionHandler // sa_sigaction
+	Mask    uint32
+	Flags   Int
+}
+
+// Siginfo represents the beginning of a siginfo_t, up to the address of the
+// fault of SIGSEGV and SIGBUS.
+type Siginfo struct {
+	Signo  Int
+	Errno  Int
+	Code   Int
+	Pid    Int
+	Uid    uint32
+	Status Int
+	Addr   Pointer
+}
+
+// Flags of SigactionT.
+const (
+	SaSiginfo = 0x40
+	SaRestart = 0x2
+)
+
+// NSIG is the number of signals, plus one.
+const NSIG = 32
@@ -0,0 +1,38 @@
+//go:build !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+// Siginfo represents the beginning of a siginfo_t, up to the address of the
+// fault of SIGSEGV and SIGBUS.
+type Siginfo struct {
+	Signo Int
+	Errno Int
+	Code  Int
+	_     Int
+	Addr  Pointer
+}
+
+// Flags of SigactionT.
+const (
+	SaSiginfo = 0x4
+	SaRestart = 0x10000000
+)
+
+// NSIG is the number of signals, plus one.
+const NSIG = 65
@@ -0,0 +1,34 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// Timespec represents a struct timespec.
+type Timespec struct {
+	Sec  Long
+	Nsec Long
+}
+
+// ClockRealtime is the clock of the wall time, which pthread_cond_timedwait
+// uses.
+const ClockRealtime = 0
+
+//go:linkname ClockGettime clock_gettime
+func ClockGettime(clock Int, ts *Timespec) Int
@@ -0,0 +1,40 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The functions of <ucontext.h>, which save and switch user-level contexts.
+// They are used by the goroutine scheduler of the runtime. See Ucontext for
+// the fields of a ucontext_t that the runtime sets.
+
+//go:linkname Getcontext getcontext
+func Getcontext(ucp *Ucontext) Int
+
+//go:linkname Setcontext setcontext
+func Setcontext(ucp *Ucontext) Int
+
+//go:linkname Swapcontext swapcontext
+func Swapcontext(oucp, ucp *Ucontext) Int
+
+// ContextFunc is the function that a context made by Makecontext calls.
+type ContextFunc func()
+
+//go:linkname Makecontext makecontext
+func Makecontext(ucp *Ucontext, fn ContextFunc, argc Int, __llgo_va_list ...any)
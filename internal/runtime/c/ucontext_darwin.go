@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+// Ucontext represents a ucontext_t. Link and Stack must be set before calling
+// Makecontext. It is large enough to hold the machine context, which follows
+// the fields declared here.
+type Ucontext struct {
+	OnStack  Int
+	SigMask  Uint
+	Stack    StackT
+	Link     *Ucontext
+	McSize   uintptr
+	Mcontext Pointer
+	_        [4608 - 56]byte
+}
+
+// StackT represents a stack_t.
+type StackT struct {
+	Sp    Pointer
+	Size  uintptr
+	Flags Int
+}
+
+// ScNprocessorsOnln is the sysconf name of the number of online processors.
+const ScNprocessorsOnln = 58
+
+// ScPagesize is the sysconf name of the size of a page.
+const ScPagesize = 29
@@ -0,0 +1,42 @@
+//go:build !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+// Ucontext represents a ucontext_t of glibc. Link and Stack, which must be set
+// before calling Makecontext, are at the same offsets on amd64, arm64 and
+// s390x, and it is large enough to hold the machine context of each.
+type Ucontext struct {
+	Flags uintptr
+	Link  *Ucontext
+	Stack StackT
+	_     [4608 - 40]byte
+}
+
+// StackT represents a stack_t.
+type StackT struct {
+	Sp    Pointer
+	Flags Int
+	Size  uintptr
+}
+
+// ScNprocessorsOnln is the sysconf name of the number of online processors.
+const ScNprocessorsOnln = 84
+
+// ScPagesize is the sysconf name of the size of a page.
+const ScPagesize = 30
@@ -0,0 +1,58 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The functions of the unwinder of the platform, which panics unwind the
+// stack with, as the exceptions of C++ do: libgcc_s on Linux, and the
+// unwinder of libSystem on macOS.
+
+// UnwindException represents a struct _Unwind_Exception, the header of an
+// exception, which must be aligned to 16 bytes.
+type UnwindException struct {
+	Class   uint64
+	Cleanup Pointer
+	Private [2]uintptr
+}
+
+// The actions that the unwinder passes to a stop function.
+const (
+	UaCleanupPhase = 2
+	UaForceUnwind  = 8
+	UaEndOfStack   = 16
+)
+
+// The reason codes of the unwinder.
+const (
+	UrcNoReason   = 0
+	UrcEndOfStack = 5
+)
+
+// UnwindStopFn is the stop function of a forced unwinding, which is called
+// with each frame to unwind, and with UaEndOfStack in actions at the end of
+// the stack. It returns UrcNoReason to unwind the frame.
+type UnwindStopFn func(version, actions Int, class uint64, exc *UnwindException, ctx, param Pointer) Int
+
+// UnwindForcedUnwind unwinds the stack with the exception exc, running the
+// cleanups of the frames, ie. the landing pads, until stop stops it. It only
+// returns if the unwinding fails.
+//
+//go:linkname UnwindForcedUnwind _Unwind_ForcedUnwind
+func UnwindForcedUnwind(exc *UnwindException, stop UnwindStopFn, param Pointer) Int
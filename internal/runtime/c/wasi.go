@@ -0,0 +1,59 @@
+//go:build wasip1
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package c
+
+import _ "unsafe"
+
+// The WASI (wasi_snapshot_preview1) functions, as declared by wasi-libc in
+// <wasi/api.h>. They return an errno, which is 0 on success.
+
+type Errno = uint16
+
+// Iovec represents a __wasi_ciovec_t.
+type Iovec struct {
+	Buf    Pointer
+	BufLen uintptr
+}
+
+//go:linkname WasiFdWrite __wasi_fd_write
+func WasiFdWrite(fd Int, iovs *Iovec, iovsLen uintptr, nwritten *uintptr) Errno
+
+//go:linkname WasiFdRead __wasi_fd_read
+func WasiFdRead(fd Int, iovs *Iovec, iovsLen uintptr, nread *uintptr) Errno
+
+//go:linkname WasiProcExit __wasi_proc_exit
+func WasiProcExit(code Uint)
+
+//go:linkname WasiArgsSizesGet __wasi_args_sizes_get
+func WasiArgsSizesGet(argc *uintptr, argvBufSize *uintptr) Errno
+
+//go:linkname WasiArgsGet __wasi_args_get
+func WasiArgsGet(argv **Char, argvBuf *Char) Errno
+
+//go:linkname WasiEnvironSizesGet __wasi_environ_sizes_get
+func WasiEnvironSizesGet(count *uintptr, bufSize *uintptr) Errno
+
+//go:linkname WasiEnvironGet __wasi_environ_get
+func WasiEnvironGet(environ **Char, environBuf *Char) Errno
+
+//go:linkname WasiClockTimeGet __wasi_clock_time_get
+func WasiClockTimeGet(id Uint, precision uint64, time *uint64) Errno
+
+//go:linkname WasiRandomGet __wasi_random_get
+func WasiRandomGet(buf Pointer, bufLen uintptr) Errno
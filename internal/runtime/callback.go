@@ -0,0 +1,184 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// Func values are closures, which C can't call, so they are converted to C
+// function pointers when they are passed to C, eg. as callbacks (see
+// llssa.Builder.CFunc and Callback). A function of a package is called by C
+// through a wrapper, f$callback, that calls EnterCallback first, as C may call
+// it on a thread that the runtime didn't create. Other func values are called
+// through trampolines: a few instructions, which NewCallback allocates, that
+// pass the address of their entry, the function and the context of the func
+// value, to a wrapper of its signature, which calls EnterCallback and then the
+// func value.
+//
+// The code of trampolines is written once, when their chunk is mapped, which
+// is then made executable but not writable: NewCallback only writes entries,
+// which aren't executable. Trampolines are never freed, as C may keep
+// callbacks indefinitely, so the contexts of func values are kept alive: func
+// values should be converted once, eg. when a callback is registered to a C
+// library, rather than in a loop.
+//
+// A callback that C calls on a thread that the runtime didn't create runs on
+// an M without a P, which EnterCallback attaches to the thread, as the
+// goroutine of the M: it sleeps on the thread when it blocks, as the main
+// goroutine does on the main thread (see threadPark).
+
+// trampolinesChunk is the number of trampolines mapped at once.
+const trampolinesChunk = 256
+
+// callbackEntry is the entry of a trampoline: the function and the context of
+// the func value that it calls, and the wrapper of its signature.
+type callbackEntry struct {
+	fn, ctx, wrapper unsafe.Pointer
+}
+
+// trampolineChunk is a chunk of trampolines, whose code is code, followed by
+// their entries, and the contexts of their func values, which the chunk keeps
+// alive, as the collector doesn't scan entries.
+type trampolineChunk struct {
+	next    *trampolineChunk
+	code    c.Pointer
+	entries *[trampolinesChunk]callbackEntry
+	ctxs    [trampolinesChunk]unsafe.Pointer
+}
+
+// trampolines is the current chunk of trampolines, which links the previous
+// ones, protected by lock, a spin lock. n trampolines of the chunk are used.
+var trampolines struct {
+	lock  int32
+	chunk *trampolineChunk
+	n     int
+}
+
+// newTrampolineChunk maps a chunk of trampolines, whose code is written and
+// then made executable, and whose entries are left writable.
+func newTrampolineChunk() *trampolineChunk {
+	page := uintptr(c.Sysconf(c.ScPagesize))
+	codeSize := (trampolineSize*trampolinesChunk + page - 1) &^ (page - 1)
+	size := codeSize + unsafe.Sizeof([trampolinesChunk]callbackEntry{})
+	code := c.Mmap(nil, size, c.ProtRead|c.ProtWrite, c.MapPrivate|c.MapAnon, -1, 0)
+	if uintptr(code) == ^uintptr(0) {
+		return nil
+	}
+	entrySize := unsafe.Sizeof(callbackEntry{})
+	for i := uintptr(0); i < trampolinesChunk; i++ {
+		off := i * trampolineSize
+		writeTrampoline(unsafe.Add(code, off), codeSize+i*entrySize-off)
+	}
+	if c.Mprotect(code, codeSize, c.ProtRead|c.ProtExec) != 0 {
+		c.Munmap(code, size)
+		return nil
+	}
+	ch := (*trampolineChunk)(AllocZ(unsafe.Sizeof(trampolineChunk{})))
+	ch.code = code
+	ch.entries = (*[trampolinesChunk]callbackEntry)(unsafe.Add(code, codeSize))
+	return ch
+}
+
+// NewCallback returns a trampoline that calls the func value of function fn
+// and context ctx through wrapper, the wrapper of its signature.
+func NewCallback(fn, ctx, wrapper unsafe.Pointer) unsafe.Pointer {
+	EnableCallbacks()
+	for !atomic.CompareAndSwapInt32(&trampolines.lock, 0, 1) {
+	}
+	ch := trampolines.chunk
+	if ch == nil || trampolines.n == trampolinesChunk {
+		if ch = newTrampolineChunk(); ch == nil {
+			atomic.StoreInt32(&trampolines.lock, 0)
+			fatal("can't map the trampolines of callbacks")
+		}
+		ch.next = trampolines.chunk
+		trampolines.chunk, trampolines.n = ch, 0
+	}
+	i := trampolines.n
+	ch.ctxs[i] = ctx
+	ch.entries[i] = callbackEntry{fn, ctx, wrapper}
+	trampolines.n++
+	atomic.StoreInt32(&trampolines.lock, 0)
+	return unsafe.Add(ch.code, i*trampolineSize)
+}
+
+// putUint32 stores v in b in little-endian order.
+func putUint32(b []byte, v uint32) {
+	b[0], b[1], b[2], b[3] = byte(v), byte(v>>8), byte(v>>16), byte(v>>24)
+}
+
+// EnableCallbacks is called before a func value is passed to C, which may
+// then call it on a thread that the runtime didn't create: it allows the
+// collector to register such threads, and keeps the scheduler from reporting
+// a deadlock when all goroutines sleep, as C may wake them.
+func EnableCallbacks() {
+	if atomic.LoadInt32(&sched.callbacks) == 0 {
+		gcAllowRegisterThreads()
+		atomic.StoreInt32(&sched.callbacks, 1)
+	}
+}
+
+// callbackMs are the Ms that EnterCallback attached to threads that the
+// runtime didn't create, linked by m.link, which keep them alive, protected by
+// lock, a spin lock.
+var callbackMs struct {
+	lock int32
+	ms   *m
+}
+
+// callbackThread is set once the current thread is known to be able to run
+// callbacks, see EnterCallback.
+//
+//llgo:threadlocal
+var callbackThread bool
+
+// EnterCallback is called by the wrappers of func values that C calls, before
+// they run: if C calls them on a thread that the runtime didn't create, it
+// registers the thread to the collector, so that it scans the stack of the
+// callback, and attaches an M without a P to the thread, whose goroutine runs
+// the callback, and the next ones on the thread.
+func EnterCallback() {
+	if callbackThread {
+		return
+	}
+	callbackThread = true
+	if getm() != nil || isMainThread() {
+		return
+	}
+	gcRegisterThread()
+	if !schedInited() {
+		schedinit()
+	}
+	gp := (*g)(AllocZ(unsafe.Sizeof(g{})))
+	gp.goid = atomic.AddUint64(&sched.goidgen, 1)
+	gp.rand = newRand(atomic.AddUint32(&randSeq, 1))
+	gp.park = (*threadPark)(AllocZ(unsafe.Sizeof(threadPark{})))
+	gp.park.init()
+	mp := (*m)(AllocZ(unsafe.Sizeof(m{})))
+	mp.curg = gp
+	for !atomic.CompareAndSwapInt32(&callbackMs.lock, 0, 1) {
+	}
+	mp.link, callbackMs.ms = callbackMs.ms, mp
+	atomic.StoreInt32(&callbackMs.lock, 0)
+	curm = mp
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package runtime implements the runtime support functions that the code
+// generated by llgo calls.
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// -----------------------------------------------------------------------------
+
+// Chan is the runtime representation of a Go channel.
+//
+// Elements are kept in a ring buffer of cap slots. The goroutines
+// blocked in sends and receives on the channel, and in selects whose cases
+// are on it, wait in its sendq and recvq (see sudog): an operation that finds
+// a goroutine blocked in the opposite one completes both, handing the value
+// off directly, so that the send on an unbuffered channel completes only when
+// a receiver takes its value. Operations are serialized by the mutex of the
+// channel, whose unlock releases the memory operations that precede it to
+// the thread that locks it next: a send or a close happens before the receive
+// that it completes, as the Go memory model requires, even on weakly ordered
+// CPUs, eg. ARM. For the race detector, this is annotated by raceacquire and
+// racerelease.
+type Chan struct {
+	mutex  c.PthreadMutex
+	recvq  sudogQueue // goroutines blocked in receives
+	sendq  sudogQueue // goroutines blocked in sends
+	data   unsafe.Pointer
+	getp   int
+	len    int
+	cap    int
+	closed bool
+}
+
+// A waiter is a goroutine blocked in ChanSend, ChanRecv or SelectBlock, whose
+// operations wait in the channels as sudogs. The first goroutine that claims
+// one of them, by setting state, completes it and wakes the waiter, and the
+// other ones are then skipped.
+type waiter struct {
+	lock   c.PthreadMutex
+	q      waitq
+	state  uint32 // 0, or 1 + the index of the case that completed; accessed atomically
+	recvOK bool   // the operation was completed by a send or a receive, rather than by a close
+	woken  bool
+}
+
+func newWaiter() *waiter {
+	w := (*waiter)(AllocZ(unsafe.Sizeof(waiter{})))
+	c.PthreadMutexInit(&w.lock, nil)
+	return w
+}
+
+// park blocks until w is woken.
+func (w *waiter) park() {
+	c.PthreadMutexLock(&w.lock)
+	for !w.woken {
+		w.q.wait(&w.lock)
+	}
+	c.PthreadMutexUnlock(&w.lock)
+}
+
+// wake wakes w, whose operation was completed, by a send or a receive if ok,
+// or else by a close.
+func (w *waiter) wake(ok bool) {
+	c.PthreadMutexLock(&w.lock)
+	w.recvOK = ok
+	w.woken = true
+	w.q.wakeAll()
+	c.PthreadMutexUnlock(&w.lock)
+}
+
+// A sudog is an operation of a waiter on a channel: the send of the value
+// pointed to by elem, or the receive of a value into elem, that is the case
+// idx of a select.
+type sudog struct {
+	w          *waiter
+	elem       unsafe.Pointer
+	idx        int
+	prev, next *sudog
+	queued     bool
+}
+
+// sudogQueue is a FIFO of sudogs, protected by the mutex of its channel.
+type sudogQueue struct {
+	head, tail *sudog
+}
+
+func (q *sudogQueue) push(sg *sudog) {
+	sg.prev, sg.next = q.tail, nil
+	if q.tail == nil {
+		q.head = sg
+	} else {
+		q.tail.next = sg
+	}
+	q.tail = sg
+	sg.queued = true
+}
+
+// remove removes sg from q, if it is still there.
+func (q *sudogQueue) remove(sg *sudog) {
+	if !sg.queued {
+		return
+	}
+	if sg.prev == nil {
+		q.head = sg.next
+	} else {
+		sg.prev.next = sg.next
+	}
+	if sg.next == nil {
+		q.tail = sg.prev
+	} else {
+		sg.next.prev = sg.prev
+	}
+	sg.prev, sg.next = nil, nil
+	sg.queued = false
+}
+
+// claim removes the first sudog of q whose waiter it claims, and the ones
+// before it, whose waiters were claimed by other channels, and returns it, or
+// nil if there is none.
+func (q *sudogQueue) claim() *sudog {
+	for sg := q.head; sg != nil; sg = q.head {
+		q.remove(sg)
+		if atomic.CompareAndSwapUint32(&sg.w.state, 0, uint32(sg.idx)+1) {
+			return sg
+		}
+	}
+	return nil
+}
+
+// NewChan creates a channel whose elements are eltSize bytes long.
+func NewChan(eltSize, cap int) *Chan {
+	p := (*Chan)(AllocZ(unsafe.Sizeof(Chan{})))
+	c.PthreadMutexInit(&p.mutex, nil)
+	p.data = AllocZ(uintptr(cap * eltSize))
+	p.cap = cap
+	return p
+}
+
+// ChanLen returns len(p).
+//
+//llgo:inline
+func ChanLen(p *Chan) int {
+	if p == nil {
+		return 0
+	}
+	if p.cap == 0 {
+		return 0
+	}
+	return p.len
+}
+
+// ChanCap returns cap(p).
+//
+//llgo:inline
+func ChanCap(p *Chan) int {
+	if p == nil {
+		return 0
+	}
+	return p.cap
+}
+
+// ChanClose closes the channel p.
+func ChanClose(p *Chan) {
+	if p == nil {
+		fatal("close of nil channel")
+	}
+	c.PthreadMutexLock(&p.mutex)
+	if p.closed {
+		c.PthreadMutexUnlock(&p.mutex)
+		fatal("close of closed channel")
+	}
+	racerelease(unsafe.Pointer(p))
+	p.closed = true
+	for sg := p.recvq.claim(); sg != nil; sg = p.recvq.claim() {
+		sg.w.wake(false) // the receiver zeroes its value
+	}
+	for sg := p.sendq.claim(); sg != nil; sg = p.sendq.claim() {
+		sg.w.wake(false) // the sender panics
+	}
+	c.PthreadMutexUnlock(&p.mutex)
+}
+
+// ChanTrySend sends the value pointed to by v to the channel p if it can do
+// so without blocking. It reports whether the value was sent.
+func ChanTrySend(p *Chan, v unsafe.Pointer, eltSize int) bool {
+	if p == nil {
+		return false
+	}
+	c.PthreadMutexLock(&p.mutex)
+	ok := p.trySend(v, eltSize)
+	c.PthreadMutexUnlock(&p.mutex)
+	return ok
+}
+
+// ChanSend sends the value pointed to by v to the channel p, blocking until
+// the channel is ready to accept it.
+func ChanSend(p *Chan, v unsafe.Pointer, eltSize int) {
+	if p == nil {
+		fatal("all goroutines are asleep - deadlock!")
+	}
+	c.PthreadMutexLock(&p.mutex)
+	if p.trySend(v, eltSize) {
+		c.PthreadMutexUnlock(&p.mutex)
+		return
+	}
+	w := newWaiter()
+	p.sendq.push(&sudog{w: w, elem: v})
+	c.PthreadMutexUnlock(&p.mutex)
+	w.park()
+	if !w.recvOK {
+		fatal("send on closed channel")
+	}
+}
+
+// ChanTryRecv receives a value from the channel p into v if it can do so
+// without blocking. selected reports whether the receive happened; recvOK
+// reports whether the value was delivered by a send rather than being the
+// zero value returned because p is closed.
+func ChanTryRecv(p *Chan, v unsafe.Pointer, eltSize int) (selected, recvOK bool) {
+	if p == nil {
+		return
+	}
+	c.PthreadMutexLock(&p.mutex)
+	selected, recvOK = p.tryRecv(v, eltSize)
+	c.PthreadMutexUnlock(&p.mutex)
+	return
+}
+
+// ChanRecv receives a value from the channel p into v, blocking until one is
+// available. It reports whether the value was delivered by a send.
+func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
+	if p == nil {
+		fatal("all goroutines are asleep - deadlock!")
+	}
+	c.PthreadMutexLock(&p.mutex)
+	if selected, ok := p.tryRecv(v, eltSize); selected {
+		c.PthreadMutexUnlock(&p.mutex)
+		return ok
+	}
+	w := newWaiter()
+	p.recvq.push(&sudog{w: w, elem: v})
+	c.PthreadMutexUnlock(&p.mutex)
+	w.park()
+	raceacquire(unsafe.Pointer(p))
+	if !w.recvOK {
+		c.Memset(v, 0, uintptr(eltSize))
+	}
+	return w.recvOK
+}
+
+// trySend hands the value pointed to by v off to a receiver blocked on p, or
+// else puts it in the buffer of p, if it isn't full. It must be called with
+// p.mutex held.
+func (p *Chan) trySend(v unsafe.Pointer, eltSize int) bool {
+	if p.closed {
+		c.PthreadMutexUnlock(&p.mutex)
+		fatal("send on closed channel")
+	}
+	if sg := p.recvq.claim(); sg != nil {
+		racerelease(unsafe.Pointer(p))
+		c.Memcpy(sg.elem, v, uintptr(eltSize))
+		sg.w.wake(true)
+		return true
+	}
+	if p.len == p.cap {
+		return false
+	}
+	racerelease(unsafe.Pointer(p))
+	c.Memcpy(unsafe.Add(p.data, (p.getp+p.len)%p.cap*eltSize), v, uintptr(eltSize))
+	p.len++
+	return true
+}
+
+// tryRecv receives a value into v from the buffer of p, refilling it from a
+// sender blocked on p, or else from such a sender, or else the zero value if
+// p is closed. It must be called with p.mutex held.
+func (p *Chan) tryRecv(v unsafe.Pointer, eltSize int) (selected, recvOK bool) {
+	if p.len > 0 {
+		raceacquire(unsafe.Pointer(p))
+		c.Memcpy(v, unsafe.Add(p.data, p.getp*eltSize), uintptr(eltSize))
+		p.getp++
+		if p.getp >= p.cap {
+			p.getp = 0
+		}
+		p.len--
+		if sg := p.sendq.claim(); sg != nil { // the buffer was full
+			c.Memcpy(unsafe.Add(p.data, (p.getp+p.len)%p.cap*eltSize), sg.elem, uintptr(eltSize))
+			p.len++
+			sg.w.wake(true)
+		}
+		return true, true
+	}
+	if sg := p.sendq.claim(); sg != nil {
+		raceacquire(unsafe.Pointer(p))
+		c.Memcpy(v, sg.elem, uintptr(eltSize))
+		sg.w.wake(true)
+		return true, true
+	}
+	if p.closed {
+		raceacquire(unsafe.Pointer(p))
+		c.Memset(v, 0, uintptr(eltSize))
+		return true, false
+	}
+	return false, false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * Portions of this file are derived from runtime/complex.go of Go:
+ *
+ * Copyright 2010 The Go Authors. All rights reserved.
+ * Use of this source code is governed by a BSD-style license that can be
+ * found in the LICENSE-GO file.
+ */
+
+package runtime
+
+import "unsafe"
+
+const maxFloat64 = 0x1p1023 * (1 + (1 - 0x1p-52))
+
+func isNaN(f float64) bool {
+	return f != f
+}
+
+func isInf(f float64) bool {
+	return f > maxFloat64 || f < -maxFloat64
+}
+
+func isFinite(f float64) bool {
+	return !isNaN(f - f)
+}
+
+func abs(f float64) float64 {
+	return copysign(f, 1)
+}
+
+func posInf() float64 {
+	bits := uint64(0x7ff) << 52
+	return *(*float64)(unsafe.Pointer(&bits))
+}
+
+// copysign returns a value with the magnitude of x and the sign of y.
+func copysign(x, y float64) float64 {
+	const sign = 1 << 63
+	bits := *(*uint64)(unsafe.Pointer(&x))&^sign | *(*uint64)(unsafe.Pointer(&y))&sign
+	return *(*float64)(unsafe.Pointer(&bits))
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Complex128Div returns n/m as the runtime of Go divides complex numbers, by
+// the algorithm of Smith, which scales the operands so that the intermediate
+// results don't overflow, and corrects the result to infinities and zeros
+// when it is NaN, as C99 does (see ISO/IEC 9899:1999, G.5.1). complex64
+// values are divided as complex128 ones (see llssa.Builder.BinOp).
+func Complex128Div(n complex128, m complex128) complex128 {
+	var e, f float64 // complex(e, f) = n/m
+
+	// Algorithm for robust complex division as described in
+	// Robert L. Smith: Algorithm 116: Complex division. Commun. ACM 5(8): 435 (1962).
+	if abs(real(m)) >= abs(imag(m)) {
+		ratio := imag(m) / real(m)
+		denom := real(m) + ratio*imag(m)
+		e = (real(n) + imag(n)*ratio) / denom
+		f = (imag(n) - real(n)*ratio) / denom
+	} else {
+		ratio := real(m) / imag(m)
+		denom := imag(m) + ratio*real(m)
+		e = (real(n)*ratio + imag(n)) / denom
+		f = (imag(n)*ratio - real(n)) / denom
+	}
+
+	if isNaN(e) && isNaN(f) {
+		a, b := real(n), imag(n)
+		c, d := real(m), imag(m)
+		inf := posInf()
+
+		switch {
+		case c == 0 && d == 0 && (!isNaN(a) || !isNaN(b)):
+			e = copysign(inf, c) * a
+			f = copysign(inf, c) * b
+
+		case (isInf(a) || isInf(b)) && isFinite(c) && isFinite(d):
+			a = copysign(boolToFloat(isInf(a)), a)
+			b = copysign(boolToFloat(isInf(b)), b)
+			e = inf * (a*c + b*d)
+			f = inf * (b*c - a*d)
+
+		case (isInf(c) || isInf(d)) && isFinite(a) && isFinite(b):
+			c = copysign(boolToFloat(isInf(c)), c)
+			d = copysign(boolToFloat(isInf(d)), d)
+			e = 0 * (a*c + b*d)
+			f = 0 * (b*c - a*d)
+		}
+	}
+	return complex(e, f)
+}
@@ -0,0 +1,127 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The functions of the packages whose coverage is measured (see
+// cl.Config.Cover) count how many times their blocks run, and register their
+// counters with CoverRegister when their package is initialized. The testing
+// runtime calls CoverReport once the tests have run.
+
+// coverFunc is the coverage of a function.
+type coverFunc struct {
+	ctrs   *uint32 // counters of its blocks
+	n      int     // number of its blocks
+	blocks string  // description of its blocks, one line per block
+	next   *coverFunc
+}
+
+var coverFuncs, coverLast *coverFunc
+
+var (
+	coverFormat   = [...]c.Char{'c', 'o', 'v', 'e', 'r', 'a', 'g', 'e', ':', ' ', '%', '.', '1', 'f', '%', '%', ' ', 'o', 'f', ' ', 's', 't', 'a', 't', 'e', 'm', 'e', 'n', 't', 's', '\n', 0}
+	coverNoFormat = [...]c.Char{'c', 'o', 'v', 'e', 'r', 'a', 'g', 'e', ':', ' ', '[', 'n', 'o', ' ', 's', 't', 'a', 't', 'e', 'm', 'e', 'n', 't', 's', ']', '\n', 0}
+)
+
+// CoverRegister registers the n counters ctrs of the blocks of a function,
+// which blocks describes in the format of the coverage profiles of the Go
+// toolchain, without the counts.
+func CoverRegister(ctrs *uint32, n int, blocks string) {
+	f := (*coverFunc)(AllocZ(unsafe.Sizeof(coverFunc{})))
+	f.ctrs, f.n, f.blocks = ctrs, n, blocks
+	if coverLast == nil {
+		coverFuncs = f
+	} else {
+		coverLast.next = f
+	}
+	coverLast = f
+}
+
+// CoverReport prints the percentage of the statements that have run and, if
+// profile isn't nil, writes the coverage profile to the file profile. It does
+// nothing if no counters are registered.
+func CoverReport(profile *c.Char) {
+	if coverFuncs == nil {
+		return
+	}
+	fd := -1
+	if profile != nil {
+		fd = int(c.Open(profile, c.OWronly|c.OCreat|c.OTrunc, c.Uint(0666)))
+		if fd < 0 {
+			fatal(concat("can't create ", gostring(profile), ": ", Errno(c.Errno()).Error()))
+		}
+		coverWrite(fd, "mode: count\n")
+	}
+	var stmts, covered int
+	for f := coverFuncs; f != nil; f = f.next {
+		ctrs := unsafe.Slice(f.ctrs, f.n)
+		blocks := f.blocks
+		for i := 0; i < f.n; i++ {
+			end := 0
+			for blocks[end] != '\n' {
+				end++
+			}
+			line := blocks[:end]
+			blocks = blocks[end+1:]
+			if line == "" {
+				continue
+			}
+			n := coverStmts(line)
+			stmts += n
+			if ctrs[i] != 0 {
+				covered += n
+			}
+			if fd >= 0 {
+				coverWrite(fd, concat(line, " ", utoa(uint64(ctrs[i])), "\n"))
+			}
+		}
+	}
+	if fd >= 0 {
+		c.Close(c.Int(fd))
+	}
+	if stmts == 0 {
+		c.Printf(&coverNoFormat[0])
+		return
+	}
+	c.Printf(&coverFormat[0], 100*float64(covered)/float64(stmts))
+}
+
+// coverStmts returns the number of statements of the block that line
+// describes, which ends it.
+func coverStmts(line string) int {
+	n, mul := 0, 1
+	for i := len(line) - 1; line[i] != ' '; i-- {
+		n += int(line[i]-'0') * mul
+		mul *= 10
+	}
+	return n
+}
+
+// coverWrite writes s to the coverage profile fd.
+func coverWrite(fd int, s string) {
+	h := (*stringHeader)(unsafe.Pointer(&s))
+	if _, errno := write(fd, unsafe.Slice((*byte)(h.data), h.len)); errno != 0 {
+		fatal(concat("can't write the coverage profile: ", Errno(errno).Error()))
+	}
+}
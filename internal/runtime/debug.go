@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "sync/atomic"
+
+// The runtime/debug package isn't compiled: the compiler turns calls to
+// debug.SetGCPercent, FreeOSMemory and ReadGCStats into calls to the functions
+// below, and the initialization of debug into DebugInit, which starts to
+// record the statistics of collections. The collector is tuned by the hooks
+// of the allocator: gcSetPercent, gcFreeOSMemory and gcRecordStats, which
+// only do something with gc=boehm.
+
+// gcPauseCap is the number of recent pauses that gcStats records.
+const gcPauseCap = 256
+
+// gcStats records the collections, protected by lock, which is a spin lock
+// as the collector reports them while allocations are locked.
+var gcStats struct {
+	lock       int32
+	numGC      int64
+	pauseTotal int64
+	lastGC     Time
+	start      Time              // start of the current collection
+	pause      [gcPauseCap]int64 // durations of the recent pauses, in a ring
+	pauseEnd   [gcPauseCap]Time  // ends of the recent pauses
+}
+
+// gcPercent is the setting of debug.SetGCPercent.
+var gcPercent = 100
+
+// GCStats is the runtime representation of debug.GCStats.
+type GCStats struct {
+	LastGC         Time
+	NumGC          int64
+	PauseTotal     int64
+	Pause          []int64
+	PauseEnd       []Time
+	PauseQuantiles []int64
+}
+
+// DebugInit implements the initialization of the runtime/debug package. It
+// starts to record the statistics of collections.
+func DebugInit() {
+	gcRecordStats()
+}
+
+// DebugSetGCPercent implements debug.SetGCPercent.
+func DebugSetGCPercent(percent int) int {
+	old := gcPercent
+	if percent < 0 {
+		percent = -1
+	}
+	gcPercent = percent
+	gcSetPercent(percent)
+	return old
+}
+
+// DebugFreeOSMemory implements debug.FreeOSMemory.
+func DebugFreeOSMemory() {
+	gcFreeOSMemory()
+}
+
+// DebugReadGCStats implements debug.ReadGCStats.
+func DebugReadGCStats(stats *GCStats) {
+	// stats are filled from a copy of gcStats, as allocating with its lock
+	// held would deadlock if a collection ran.
+	for !atomic.CompareAndSwapInt32(&gcStats.lock, 0, 1) {
+	}
+	gs := gcStats
+	atomic.StoreInt32(&gcStats.lock, 0)
+	n := int(gs.numGC)
+	if n > gcPauseCap {
+		n = gcPauseCap
+	}
+	stats.Pause = stats.Pause[:0]
+	stats.PauseEnd = stats.PauseEnd[:0]
+	for i := 0; i < n; i++ { // the most recent pause first
+		j := (int(gs.numGC) - 1 - i) % gcPauseCap
+		stats.Pause = append(stats.Pause, gs.pause[j])
+		stats.PauseEnd = append(stats.PauseEnd, gs.pauseEnd[j])
+	}
+	stats.LastGC = gs.lastGC
+	stats.NumGC = gs.numGC
+	stats.PauseTotal = gs.pauseTotal
+	if nq := len(stats.PauseQuantiles); nq > 0 {
+		if n == 0 {
+			for i := range stats.PauseQuantiles {
+				stats.PauseQuantiles[i] = 0
+			}
+			return
+		}
+		sorted := gs.pause[:n]
+		for i := 1; i < n; i++ { // insertion sort
+			for j := i; j > 0 && sorted[j-1] > sorted[j]; j-- {
+				sorted[j-1], sorted[j] = sorted[j], sorted[j-1]
+			}
+		}
+		for i := 0; i < nq-1; i++ {
+			stats.PauseQuantiles[i] = sorted[n*i/(nq-1)]
+		}
+		stats.PauseQuantiles[nq-1] = sorted[n-1]
+	}
+}
+
+// gcEvent records the start and the end of collections in gcStats.
+func gcEvent(start bool) {
+	now := TimeNow()
+	for !atomic.CompareAndSwapInt32(&gcStats.lock, 0, 1) {
+	}
+	if start {
+		gcStats.start = now
+	} else {
+		d := TimeSince(gcStats.start)
+		i := gcStats.numGC % gcPauseCap
+		gcStats.pause[i] = d
+		gcStats.pauseEnd[i] = now
+		gcStats.pauseTotal += d
+		gcStats.lastGC = now
+		gcStats.numGC++
+	}
+	atomic.StoreInt32(&gcStats.lock, 0)
+}
@@ -0,0 +1,29 @@
+//go:build !gc.boehm
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+// The hooks of runtime/debug do nothing, as the heap isn't managed by the
+// Boehm GC: memory is never freed, or it is managed by the precise collector,
+// which can't be tuned yet.
+
+func gcSetPercent(percent int) {}
+
+func gcFreeOSMemory() {}
+
+func gcRecordStats() {}
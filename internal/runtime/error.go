@@ -0,0 +1,351 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * Portions of this file are derived from runtime/error.go of Go:
+ *
+ * Copyright 2010 The Go Authors. All rights reserved.
+ * Use of this source code is governed by a BSD-style license that can be
+ * found in the LICENSE-GO file.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+var fatalFormat = [...]c.Char{'f', 'a', 't', 'a', 'l', ' ', 'e', 'r', 'r', 'o', 'r', ':', ' ', '%', 's', '\n', 0}
+
+type stringHeader struct {
+	data c.Pointer
+	len  int
+}
+
+type sliceHeader struct {
+	data c.Pointer
+	len  int
+	cap  int
+}
+
+// fatal reports an unrecoverable runtime error, with the stack trace of the
+// thread, and aborts the program.
+func fatal(msg string) {
+	c.Printf(&fatalFormat[0], mallocString(msg))
+	traceback(1)
+	c.Abort()
+}
+
+// mallocString returns a copy of s terminated by NUL, allocated by c.Malloc
+// rather than by the collector, since errors may occur in the collector.
+func mallocString(s string) *c.Char {
+	buf := (*c.Char)(c.Malloc(uintptr(len(s) + 1)))
+	c.Memcpy(c.Pointer(buf), (*stringHeader)(unsafe.Pointer(&s)).data, uintptr(len(s)))
+	*(*c.Char)(unsafe.Add(c.Pointer(buf), len(s))) = 0
+	return buf
+}
+
+// Error identifies a run-time error, as runtime.Error of Go does.
+type Error interface {
+	error
+	// RuntimeError is a no-op function that serves to distinguish types that
+	// are run-time errors from ordinary errors.
+	RuntimeError()
+}
+
+// runtimeError is a run-time error of a fixed message, as errorString of the
+// runtime of Go is.
+type runtimeError string
+
+func (e runtimeError) RuntimeError() {}
+
+func (e runtimeError) Error() string {
+	return concat("runtime error: ", string(e))
+}
+
+// plainError is a run-time error whose message doesn't have the prefix
+// "runtime error: ", eg. the assignment to an entry of a nil map.
+type plainError string
+
+func (e plainError) RuntimeError() {}
+
+func (e plainError) Error() string {
+	return string(e)
+}
+
+// boundsError is the failure of an index or a slice expression, or of the
+// conversion of a slice to an array: x is the failing index, or the length of
+// the slice to convert, and y is the length, the capacity or the other index
+// that it's checked against.
+type boundsError struct {
+	x      int64
+	y      int
+	signed bool // x is signed, so it may be negative
+	code   boundsErrorCode
+}
+
+type boundsErrorCode uint8
+
+const (
+	boundsIndex      boundsErrorCode = iota // s[x], 0 <= x < len(s) failed
+	boundsSliceAlen                         // s[?:x], 0 <= x <= len(s) failed
+	boundsSliceAcap                         // s[?:x], 0 <= x <= cap(s) failed
+	boundsSliceB                            // s[x:y], 0 <= x <= y failed
+	boundsSlice3Alen                        // s[?:?:x], 0 <= x <= len(s) failed
+	boundsSlice3Acap                        // s[?:?:x], 0 <= x <= cap(s) failed
+	boundsSlice3B                           // s[?:x:y], 0 <= x <= y failed
+	boundsSlice3C                           // s[x:y:?], 0 <= x <= y failed
+	boundsConvert                           // (*[x]T)(s), 0 <= x <= len(s) failed
+)
+
+// boundsErrorFmt holds the pieces of the messages of the bounds errors, which
+// are pre + x + mid + y + post.
+var boundsErrorFmt = [...][3]string{
+	boundsIndex:      {"index out of range [", "] with length ", ""},
+	boundsSliceAlen:  {"slice bounds out of range [:", "] with length ", ""},
+	boundsSliceAcap:  {"slice bounds out of range [:", "] with capacity ", ""},
+	boundsSliceB:     {"slice bounds out of range [", ":", "]"},
+	boundsSlice3Alen: {"slice bounds out of range [::", "] with length ", ""},
+	boundsSlice3Acap: {"slice bounds out of range [::", "] with capacity ", ""},
+	boundsSlice3B:    {"slice bounds out of range [:", ":", "]"},
+	boundsSlice3C:    {"slice bounds out of range [", ":", ":]"},
+	boundsConvert:    {"cannot convert slice with length ", " to array or pointer to array with length ", ""},
+}
+
+// boundsNegErrorFmt holds the pieces of the messages of the bounds errors of
+// a negative x, which are pre + x + post.
+var boundsNegErrorFmt = [...][2]string{
+	boundsIndex:      {"index out of range [", "]"},
+	boundsSliceAlen:  {"slice bounds out of range [:", "]"},
+	boundsSliceAcap:  {"slice bounds out of range [:", "]"},
+	boundsSliceB:     {"slice bounds out of range [", ":]"},
+	boundsSlice3Alen: {"slice bounds out of range [::", "]"},
+	boundsSlice3Acap: {"slice bounds out of range [::", "]"},
+	boundsSlice3B:    {"slice bounds out of range [:", ":]"},
+	boundsSlice3C:    {"slice bounds out of range [", "::]"},
+}
+
+func (e boundsError) RuntimeError() {}
+
+func (e boundsError) Error() string {
+	if e.signed && e.x < 0 {
+		f := &boundsNegErrorFmt[e.code]
+		return concat("runtime error: ", f[0], itoa(e.x), f[1])
+	}
+	f := &boundsErrorFmt[e.code]
+	return concat("runtime error: ", f[0], utoa(uint64(e.x)), f[1], itoa(int64(e.y)), f[2])
+}
+
+// TypeAssertionError is the failure of the type assertion x.(want), where x
+// is an interface of type iface whose dynamic type is have, nil if x is nil,
+// as runtime.TypeAssertionError of Go.
+type TypeAssertionError struct {
+	iface, have, want *Type
+}
+
+func (e *TypeAssertionError) RuntimeError() {}
+
+func (e *TypeAssertionError) Error() string {
+	have := "nil"
+	if e.have != nil {
+		have = e.have.Str
+	}
+	msg := concat("interface conversion: ", e.iface.Str, " is ", have, ", not ", e.want.Str)
+	if e.have != nil && have == e.want.Str {
+		if e.have.PkgPath != e.want.PkgPath {
+			msg = concat(msg, " (types from different packages)")
+		} else {
+			msg = concat(msg, " (types from different scopes)")
+		}
+	}
+	return msg
+}
+
+// itoa returns the decimal representation of v.
+func itoa(v int64) string {
+	if v < 0 {
+		return concat("-", utoa(uint64(-v)))
+	}
+	return utoa(uint64(v))
+}
+
+// utoa returns the decimal representation of v.
+func utoa(v uint64) string {
+	var buf [20]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = byte('0' + v%10)
+		v /= 10
+		if v == 0 {
+			break
+		}
+	}
+	n := len(buf) - i
+	s := AllocZ(uintptr(n))
+	c.Memcpy(s, c.Pointer(&buf[i]), uintptr(n))
+	return *(*string)(unsafe.Pointer(&stringHeader{s, n}))
+}
+
+// PanicIndex reports the index x out of the range of the length y.
+func PanicIndex(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsIndex})
+}
+
+// PanicIndexU reports the unsigned index x out of the range of the length y.
+func PanicIndexU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsIndex})
+}
+
+// PanicSliceAlen reports the bound x of s[:x] greater than the length y.
+func PanicSliceAlen(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSliceAlen})
+}
+
+// PanicSliceAlenU is PanicSliceAlen of an unsigned x.
+func PanicSliceAlenU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSliceAlen})
+}
+
+// PanicSliceAcap reports the bound x of s[:x] greater than the capacity y.
+func PanicSliceAcap(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSliceAcap})
+}
+
+// PanicSliceAcapU is PanicSliceAcap of an unsigned x.
+func PanicSliceAcapU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSliceAcap})
+}
+
+// PanicSliceB reports the bound x of s[x:y] greater than y.
+func PanicSliceB(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSliceB})
+}
+
+// PanicSliceBU is PanicSliceB of an unsigned x.
+func PanicSliceBU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSliceB})
+}
+
+// PanicSlice3Alen reports the bound x of s[::x] greater than the length y.
+func PanicSlice3Alen(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSlice3Alen})
+}
+
+// PanicSlice3AlenU is PanicSlice3Alen of an unsigned x.
+func PanicSlice3AlenU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSlice3Alen})
+}
+
+// PanicSlice3Acap reports the bound x of s[::x] greater than the capacity y.
+func PanicSlice3Acap(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSlice3Acap})
+}
+
+// PanicSlice3AcapU is PanicSlice3Acap of an unsigned x.
+func PanicSlice3AcapU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSlice3Acap})
+}
+
+// PanicSlice3B reports the bound x of s[:x:y] greater than y.
+func PanicSlice3B(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSlice3B})
+}
+
+// PanicSlice3BU is PanicSlice3B of an unsigned x.
+func PanicSlice3BU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSlice3B})
+}
+
+// PanicSlice3C reports the bound x of s[x:y:] greater than y.
+func PanicSlice3C(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsSlice3C})
+}
+
+// PanicSlice3CU is PanicSlice3C of an unsigned x.
+func PanicSlice3CU(x uint, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: false, code: boundsSlice3C})
+}
+
+// PanicSliceConvert reports the conversion of a slice of the length x to a
+// pointer to an array of the greater length y.
+func PanicSliceConvert(x int, y int) {
+	Panic(boundsError{x: int64(x), y: y, signed: true, code: boundsConvert})
+}
+
+// PanicDivide reports the integer division by zero.
+func PanicDivide() {
+	Panic(runtimeError("integer divide by zero"))
+}
+
+// PanicUnsafeSliceLen reports the negative length of unsafe.Slice, or one
+// that doesn't fit in an int.
+func PanicUnsafeSliceLen() {
+	Panic(runtimeError("unsafe.Slice: len out of range"))
+}
+
+// PanicUnsafeSliceNilPtr reports the nil pointer of unsafe.Slice, whose length
+// isn't zero.
+func PanicUnsafeSliceNilPtr() {
+	Panic(runtimeError("unsafe.Slice: ptr is nil and len is not zero"))
+}
+
+// PanicUnsafeStringLen reports the negative length of unsafe.String, or one
+// that doesn't fit in an int.
+func PanicUnsafeStringLen() {
+	Panic(runtimeError("unsafe.String: len out of range"))
+}
+
+// PanicUnsafeStringNilPtr reports the nil pointer of unsafe.String, whose
+// length isn't zero.
+func PanicUnsafeStringNilPtr() {
+	Panic(runtimeError("unsafe.String: ptr is nil and len is not zero"))
+}
+
+// WrapNilFailed reports the call of the value method typ.method, eg. "foo.T"
+// and "M", through a nil *T.
+func WrapNilFailed(typ, method string) {
+	name := typ
+	for i := len(typ) - 1; i >= 0; i-- {
+		if typ[i] == '.' {
+			name = typ[i+1:]
+			break
+		}
+	}
+	Panic(plainError(concat("value method ", typ, ".", method, " called using nil *", name, " pointer")))
+}
+
+// concat returns the concatenation of ss.
+func concat(ss ...string) string {
+	n := 0
+	for _, s := range ss {
+		n += len(s)
+	}
+	buf := AllocZ(uintptr(n))
+	off := 0
+	for _, s := range ss {
+		c.Memcpy(unsafe.Add(buf, off), (*stringHeader)(unsafe.Pointer(&s)).data, uintptr(len(s)))
+		off += len(s)
+	}
+	return *(*string)(unsafe.Pointer(&stringHeader{buf, n}))
+}
+
+// sliceData returns the pointer to the underlying array of b.
+func sliceData(b []byte) c.Pointer {
+	return (*sliceHeader)(unsafe.Pointer(&b)).data
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// SetFinalizer implements runtime.SetFinalizer: the finalizer of obj, a
+// pointer to an object allocated on the heap, is set to finalizer, a func that
+// is called with obj when the object is unreachable, or cleared if finalizer
+// is nil. Finalizers are run by the collectors (see gcSetFinalizer), and the
+// results of finalizer are ignored.
+func SetFinalizer(obj, finalizer any) {
+	x := (*eface)(unsafe.Pointer(&obj))
+	if x.typ == nil {
+		fatal("runtime.SetFinalizer: first argument is nil")
+	}
+	if x.typ.Kind != kindPointer {
+		fatal(concat("runtime.SetFinalizer: first argument is ", x.typ.Str, ", not pointer"))
+	}
+	if x.data == nil {
+		return
+	}
+	fn := (*eface)(unsafe.Pointer(&finalizer))
+	if fn.typ != nil && fn.typ.Kind != kindFunc {
+		fatal(concat("runtime.SetFinalizer: second argument is ", fn.typ.Str, ", not a function"))
+	}
+	gcSetFinalizer(x.data, fn.data)
+}
+
+// runFinalizer calls the finalizer that fn points to, a func value, with obj.
+// The collectors keep fn alive while the finalizer is set.
+func runFinalizer(obj, fn unsafe.Pointer) {
+	(*(*func(unsafe.Pointer))(fn))(obj)
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// This file implements the lightweight formatter that calls of fmt.Print,
+// Println and Printf are lowered to when the types of their arguments are
+// known statically (see llssa.Builder.Fmt): each value is formatted by the
+// function of its kind and written to the standard output, so programs that
+// print values don't need the reflect based implementation of fmt.
+
+// Flags of a verb. They must be the same as the ones of llssa.FmtVerb.
+const (
+	fmtMinus = 1 << iota
+	fmtPlus
+	fmtSharp
+	fmtSpace
+	fmtZero
+)
+
+const (
+	fmtDigits      = "0123456789abcdefx"
+	fmtUpperDigits = "0123456789ABCDEFX"
+)
+
+// FmtString formats s according to the verb %v or %s.
+func FmtString(s string, verb rune, width, prec, flags int) {
+	if width < 0 && prec < 0 {
+		h := (*stringHeader)(unsafe.Pointer(&s))
+		fmtWrite(*(*[]byte)(unsafe.Pointer(&sliceHeader{h.data, h.len, h.len})))
+		return
+	}
+	var buf [64]byte
+	fmtWrite(fmtAppendString(buf[:0], s, width, prec, flags))
+}
+
+// FmtBool formats v according to the verb %v or %t.
+func FmtBool(v bool, verb rune, width, prec, flags int) {
+	if v {
+		FmtString("true", verb, width, -1, flags)
+	} else {
+		FmtString("false", verb, width, -1, flags)
+	}
+}
+
+// FmtInt formats v according to the verb %v, %d, %b, %o, %x, %X or %c.
+func FmtInt(v int64, verb rune, width, prec, flags int) {
+	var buf [72]byte
+	if v < 0 && verb != 'c' {
+		fmtWrite(fmtAppendInteger(buf[:0], uint64(-v), true, verb, width, prec, flags))
+	} else {
+		fmtWrite(fmtAppendInteger(buf[:0], uint64(v), false, verb, width, prec, flags))
+	}
+}
+
+// FmtUint formats v according to the verb %v, %d, %b, %o, %x, %X or %c.
+func FmtUint(v uint64, verb rune, width, prec, flags int) {
+	var buf [80]byte
+	fmtWrite(fmtAppendInteger(buf[:0], v, false, verb, width, prec, flags))
+}
+
+// FmtPointer formats p according to the verb %v or %p: in hexadecimal with a
+// leading 0x, unless the flag # is set, or as <nil> for a nil pointer and %v.
+func FmtPointer(p unsafe.Pointer, verb rune, width, prec, flags int) {
+	if p == nil && verb == 'v' {
+		FmtString("<nil>", verb, width, -1, flags)
+		return
+	}
+	var buf [80]byte
+	fmtWrite(fmtAppendInteger(buf[:0], uint64(uintptr(p)), false, 'x', width, prec, flags^fmtSharp))
+}
+
+// FmtFloat formats v according to the verb %v, %g, %e or %f. The precision
+// of %v, and of %g if it isn't specified, is the smallest number of digits
+// that represent v exactly.
+func FmtFloat(v float64, verb rune, width, prec, flags int) {
+	var buf [64]byte
+	fmtWrite(fmtAppendFloat(buf[:0], v, 64, verb, width, prec, flags))
+}
+
+// FmtFloat32 formats v as FmtFloat does, with the digits of a float32.
+func FmtFloat32(v float32, verb rune, width, prec, flags int) {
+	var buf [64]byte
+	fmtWrite(fmtAppendFloat(buf[:0], float64(v), 32, verb, width, prec, flags))
+}
+
+// fmtAppendString appends s to dst, truncated to prec runes and padded to
+// width runes.
+func fmtAppendString(dst []byte, s string, width, prec, flags int) []byte {
+	n := 0
+	for i := 0; i < len(s); n++ {
+		if n == prec {
+			s = s[:i]
+			break
+		}
+		i += fmtRuneLen(s[i:])
+	}
+	h := (*stringHeader)(unsafe.Pointer(&s))
+	return fmtAppendPad(dst, *(*[]byte)(unsafe.Pointer(&sliceHeader{h.data, h.len, h.len})), width-n, flags)
+}
+
+// fmtAppendFloat appends v, which is a float32 if bitSize is 32, formatted
+// by appendFloat.
+func fmtAppendFloat(dst []byte, v float64, bitSize int, verb rune, width, prec, flags int) []byte {
+	switch verb {
+	case 'e', 'f':
+		if prec < 0 {
+			prec = 6
+		}
+	default:
+		verb = 'g'
+	}
+	var buf [32]byte
+	b := appendFloat(buf[:1], v, byte(verb), prec, bitSize) // buf[0] is for the sign
+	if b[1] == '-' || b[1] == '+' {
+		b = b[1:]
+	} else {
+		b[0] = '+'
+	}
+	if b[0] == '+' && flags&fmtSpace != 0 && flags&fmtPlus == 0 {
+		b[0] = ' '
+	}
+	if b[1] == 'I' || b[1] == 'N' { // infinities and NaN aren't padded with zeros
+		if b[1] == 'N' && flags&(fmtPlus|fmtSpace) == 0 {
+			b = b[1:]
+		}
+		return fmtAppendPad(dst, b, width-len(b), flags&^fmtZero)
+	}
+	if b[0] == '+' && flags&fmtPlus == 0 {
+		b = b[1:]
+	}
+	n := width - len(b)
+	if flags&fmtZero != 0 && n > 0 && (b[0] == '-' || b[0] == '+' || b[0] == ' ') {
+		dst, b = append(dst, b[0]), b[1:] // the zeros follow the sign
+	}
+	return fmtAppendPad(dst, b, n, flags)
+}
+
+// fmtAppendInteger appends the integer whose absolute value is v, with at
+// least prec digits, or as many as fill width if the flag 0 is set and prec
+// isn't specified, as fmt does.
+func fmtAppendInteger(dst []byte, v uint64, neg bool, verb rune, width, prec, flags int) []byte {
+	if verb == 'c' {
+		var buf [4]byte
+		return fmtAppendPad(dst, buf[:encodeRune(buf[:], rune(v))], width-1, flags)
+	}
+	if prec == 0 && v == 0 {
+		return fmtAppendPad(dst, nil, width, flags&^fmtZero)
+	}
+	if prec < 0 && flags&fmtZero != 0 && flags&fmtMinus == 0 && width > 0 {
+		prec = width
+		if neg || flags&(fmtPlus|fmtSpace) != 0 {
+			prec-- // leave room for the sign
+		}
+	}
+	base, digits := uint64(10), fmtDigits
+	switch verb {
+	case 'b':
+		base = 2
+	case 'o':
+		base = 8
+	case 'x':
+		base = 16
+	case 'X':
+		base, digits = 16, fmtUpperDigits
+	}
+	var buf [64]byte
+	i := len(buf)
+	for v >= base {
+		i--
+		buf[i] = digits[v%base]
+		v /= base
+	}
+	i--
+	buf[i] = digits[v]
+	zeros := prec - (len(buf) - i)
+	var pre [3]byte // the sign and the prefix of the base
+	npre := 0
+	switch {
+	case neg:
+		pre[0], npre = '-', 1
+	case flags&fmtPlus != 0:
+		pre[0], npre = '+', 1
+	case flags&fmtSpace != 0:
+		pre[0], npre = ' ', 1
+	}
+	if flags&fmtSharp != 0 {
+		switch base {
+		case 2:
+			pre[npre], pre[npre+1] = '0', 'b'
+			npre += 2
+		case 8:
+			if zeros <= 0 && buf[i] != '0' {
+				zeros = 1
+			}
+		case 16:
+			pre[npre], pre[npre+1] = '0', digits[16]
+			npre += 2
+		}
+	}
+	if zeros < 0 {
+		zeros = 0
+	}
+	n := width - npre - zeros - (len(buf) - i)
+	if flags&fmtMinus == 0 {
+		dst = fmtAppendPadding(dst, ' ', n)
+	}
+	dst = fmtAppendPadding(append(dst, pre[:npre]...), '0', zeros)
+	dst = append(dst, buf[i:]...)
+	if flags&fmtMinus != 0 {
+		dst = fmtAppendPadding(dst, ' ', n)
+	}
+	return dst
+}
+
+// fmtAppendPad appends b, preceded by n spaces, or by n zeros for the flag 0,
+// or followed by n spaces for the flag -.
+func fmtAppendPad(dst, b []byte, n, flags int) []byte {
+	switch {
+	case n <= 0:
+		return append(dst, b...)
+	case flags&fmtMinus != 0:
+		return fmtAppendPadding(append(dst, b...), ' ', n)
+	case flags&fmtZero != 0:
+		return append(fmtAppendPadding(dst, '0', n), b...)
+	default:
+		return append(fmtAppendPadding(dst, ' ', n), b...)
+	}
+}
+
+// fmtAppendPadding appends n times the byte ch.
+func fmtAppendPadding(dst []byte, ch byte, n int) []byte {
+	for ; n > 0; n-- {
+		dst = append(dst, ch)
+	}
+	return dst
+}
+
+// fmtRuneLen returns the length of the rune that s begins with, or 1 if it
+// isn't valid UTF-8, as utf8.DecodeRuneInString does.
+func fmtRuneLen(s string) int {
+	n, lo, hi := 0, byte(0x80), byte(0xbf)
+	switch c := s[0]; {
+	case c < 0xc2:
+		return 1
+	case c < 0xe0:
+		n = 2
+	case c < 0xf0:
+		n = 3
+		if c == 0xe0 {
+			lo = 0xa0
+		} else if c == 0xed { // surrogates
+			hi = 0x9f
+		}
+	case c < 0xf5:
+		n = 4
+		if c == 0xf0 {
+			lo = 0x90
+		} else if c == 0xf4 {
+			hi = 0x8f
+		}
+	default:
+		return 1
+	}
+	if len(s) < n || s[1] < lo || s[1] > hi {
+		return 1
+	}
+	for i := 2; i < n; i++ {
+		if s[i]&0xc0 != 0x80 {
+			return 1
+		}
+	}
+	return n
+}
+
+// encodeRune writes the UTF-8 encoding of r to buf, which must be large
+// enough, and returns the number of bytes written. Invalid runes are encoded
+// as U+FFFD.
+func encodeRune(buf []byte, r rune) int {
+	switch {
+	case r >= 0 && r < 0x80:
+		buf[0] = byte(r)
+		return 1
+	case r >= 0 && r < 0x800:
+		buf[0] = byte(0xc0 | r>>6)
+		buf[1] = byte(0x80 | r&0x3f)
+		return 2
+	case r < 0 || r > 0x10ffff || (r >= 0xd800 && r < 0xe000):
+		r = 0xfffd
+		fallthrough
+	case r < 0x10000:
+		buf[0] = byte(0xe0 | r>>12)
+		buf[1] = byte(0x80 | r>>6&0x3f)
+		buf[2] = byte(0x80 | r&0x3f)
+		return 3
+	default:
+		buf[0] = byte(0xf0 | r>>18)
+		buf[1] = byte(0x80 | r>>12&0x3f)
+		buf[2] = byte(0x80 | r>>6&0x3f)
+		buf[3] = byte(0x80 | r&0x3f)
+		return 4
+	}
+}
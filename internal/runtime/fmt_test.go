@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// fmtAppend appends v formatted by the verb of format, which has a single
+// verb, as the functions of the formatter that llssa.Builder.Fmt calls do.
+func fmtAppend(t *testing.T, format string, v any) []byte {
+	flags, width, prec := 0, -1, -1
+	i := 1
+flags:
+	for ; i < len(format); i++ {
+		switch format[i] {
+		case '-':
+			flags |= fmtMinus
+		case '+':
+			flags |= fmtPlus
+		case '#':
+			flags |= fmtSharp
+		case ' ':
+			flags |= fmtSpace
+		case '0':
+			flags |= fmtZero
+		default:
+			break flags
+		}
+	}
+	if flags&fmtMinus != 0 {
+		flags &^= fmtZero
+	}
+	num := func() (n int) {
+		for ; format[i] >= '0' && format[i] <= '9'; i++ {
+			n = n*10 + int(format[i]-'0')
+		}
+		return
+	}
+	if format[i] >= '1' && format[i] <= '9' {
+		width = num()
+	}
+	if format[i] == '.' {
+		i++
+		prec = num()
+	}
+	verb, _ := utf8.DecodeRuneInString(format[i:])
+	if verb == 'v' {
+		flags &^= fmtPlus
+	}
+	switch v := v.(type) {
+	case string:
+		return fmtAppendString(nil, v, width, prec, flags)
+	case bool:
+		return fmtAppendString(nil, fmt.Sprint(v), width, -1, flags)
+	case int:
+		if v < 0 && verb != 'c' {
+			return fmtAppendInteger(nil, uint64(-v), true, verb, width, prec, flags)
+		}
+		return fmtAppendInteger(nil, uint64(v), false, verb, width, prec, flags)
+	case uint64:
+		return fmtAppendInteger(nil, v, false, verb, width, prec, flags)
+	case float64:
+		return fmtAppendFloat(nil, v, 64, verb, width, prec, flags)
+	case float32:
+		return fmtAppendFloat(nil, float64(v), 32, verb, width, prec, flags)
+	case unsafe.Pointer:
+		return fmtAppendInteger(nil, uint64(uintptr(v)), false, 'x', width, prec, flags^fmtSharp)
+	}
+	t.Fatalf("fmtAppend: unexpected value %T", v)
+	return nil
+}
+
+func TestFmt(t *testing.T) {
+	var x int
+	tests := []struct {
+		format string
+		v      any
+	}{
+		{"%s", "hello"},
+		{"%8s", "héllo"},
+		{"%-8s", "héllo"},
+		{"%.2s", "héllo"},
+		{"%5.1s", "日本語"},
+		{"%.3s", "\xffab"},
+		{"%08s", "ab"},
+		{"%05s", "-a"},
+		{"%v", ""},
+		{"%6t", true},
+		{"%-7v", false},
+		{"%d", 42},
+		{"%d", -42},
+		{"%+d", 42},
+		{"% d", 42},
+		{"%+v", 42},
+		{"%5d", -42},
+		{"%-5d", 42},
+		{"%05d", -42},
+		{"%+05d", 42},
+		{"%.3d", 7},
+		{"%.3d", -7},
+		{"%6.3d", 7},
+		{"%06.3d", 7},
+		{"%.0d", 0},
+		{"%3.0d", 0},
+		{"%b", 5},
+		{"%#b", 5},
+		{"%#o", 8},
+		{"%#o", 0},
+		{"%#x", 255},
+		{"%#X", 255},
+		{"%#x", -255},
+		{"%#08x", 255},
+		{"%x", uint64(math.MaxUint64)},
+		{"%.20d", uint64(1)},
+		{"%-#80.70o", 8},
+		{"%c", int('é')},
+		{"%3c", int('x')},
+		{"%03c", int('x')},
+		{"%v", 1.5},
+		{"%g", 1e21},
+		{"%.3g", math.Pi},
+		{"%e", 1234.5678},
+		{"%.2f", -1.005},
+		{"%08.3f", -3.14159},
+		{"%+f", 2.5},
+		{"% f", 2.5},
+		{"%6v", math.Inf(-1)},
+		{"%06v", math.NaN()},
+		{"%+v", math.NaN()},
+		{"%v", float32(0.1)},
+		{"%#s", "x"},
+		{"%#d", 10},
+		{"%p", unsafe.Pointer(&x)},
+		{"%#p", unsafe.Pointer(&x)},
+		{"%20p", unsafe.Pointer(&x)},
+	}
+	for _, tt := range tests {
+		want := fmt.Sprintf(tt.format, tt.v)
+		if got := string(fmtAppend(t, tt.format, tt.v)); got != want {
+			t.Errorf("TestFmt: %q of %v: got %q, want %q", tt.format, tt.v, got, want)
+		}
+	}
+}
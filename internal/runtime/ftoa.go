@@ -0,0 +1,599 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * Portions of this file are derived from strconv/decimal.go and strconv/ftoa.go of Go:
+ *
+ * Copyright 2009 The Go Authors. All rights reserved.
+ * Use of this source code is governed by a BSD-style license that can be
+ * found in the LICENSE-GO file.
+ */
+
+package runtime
+
+import "unsafe"
+
+// This file implements the formatting of floating-point numbers of strconv,
+// which calls of strconv.FormatFloat, AppendFloat and FormatComplex are
+// compiled to (see rtIntrinsics in cl), and which the lowered calls of fmt
+// (see FmtFloat) and print use too, so that their output is the one of Go
+// rather than the one of the printf of libc, which differs between libcs and
+// in the precision of %v. The shortest decimal digits that parse back to a
+// float are found by Grisu3, with 64-bit integers, and otherwise, as for the
+// digits rounded to a precision, half to even, exactly, by the multiprecision
+// decimal arithmetic that strconv falls back to.
+
+// decimal is a nonnegative decimal number: 0.d[0]d[1]...d[nd-1] * 10^dp, whose
+// digits are ASCII, without trailing zeros. It's large enough for the exact
+// value of any float64, eg. the 767 significant digits of the largest
+// denormal.
+type decimal struct {
+	d  [800]byte
+	nd int
+	dp int
+}
+
+// floatInfo describes the layout of the bits of a float.
+type floatInfo struct {
+	mantbits uint
+	expbits  uint
+	bias     int
+}
+
+var (
+	float32info = floatInfo{23, 8, -127}
+	float64info = floatInfo{52, 11, -1023}
+)
+
+// maxShift is the largest shift of a decimal by a step, so that a digit
+// shifted by it fits in an uint64, with the carry.
+const maxShift = 60
+
+// assign sets a to v.
+func (a *decimal) assign(v uint64) {
+	var buf [20]byte
+	n := 0
+	for v > 0 {
+		buf[n] = byte('0' + v%10)
+		v /= 10
+		n++
+	}
+	for i := 0; i < n; i++ {
+		a.d[i] = buf[n-1-i]
+	}
+	a.nd, a.dp = n, n
+	a.trim()
+}
+
+// shift multiplies a by 2^k, or divides it by 2^-k for a negative k.
+func (a *decimal) shift(k int) {
+	for ; k > maxShift; k -= maxShift {
+		a.shl(maxShift)
+	}
+	for ; k < -maxShift; k += maxShift {
+		a.shr(maxShift)
+	}
+	switch {
+	case k > 0:
+		a.shl(uint(k))
+	case k < 0:
+		a.shr(uint(-k))
+	}
+}
+
+// shl multiplies a by 2^k, from the last digit to the first one, whose carry
+// is prepended.
+func (a *decimal) shl(k uint) {
+	var carry uint64
+	for i := a.nd - 1; i >= 0; i-- {
+		v := uint64(a.d[i]-'0')<<k + carry
+		a.d[i] = byte('0' + v%10)
+		carry = v / 10
+	}
+	var buf [20]byte
+	n := 0
+	for ; carry > 0; carry /= 10 {
+		buf[n] = byte('0' + carry%10)
+		n++
+	}
+	copy(a.d[n:], a.d[:a.nd])
+	for i := 0; i < n; i++ {
+		a.d[i] = buf[n-1-i]
+	}
+	a.nd += n
+	if a.nd > len(a.d) {
+		a.nd = len(a.d)
+	}
+	a.dp += n
+	a.trim()
+}
+
+// shr divides a by 2^k, from the first digit to the last one, whose remainder
+// appends digits. The digits of the quotient are written over the ones that
+// are read, which they never pass, as the first one is nonzero.
+func (a *decimal) shr(k uint) {
+	var rem uint64
+	r := 0
+	for ; rem>>k == 0; r++ {
+		if r >= a.nd {
+			if rem == 0 {
+				a.nd = 0
+				return
+			}
+			for rem>>k == 0 {
+				rem *= 10
+				r++
+			}
+			break
+		}
+		rem = rem*10 + uint64(a.d[r]-'0')
+	}
+	a.dp -= r - 1
+	mask := uint64(1)<<k - 1
+	w := 0
+	for ; r < a.nd; r++ {
+		a.d[w] = byte('0' + rem>>k)
+		w++
+		rem = (rem&mask)*10 + uint64(a.d[r]-'0')
+	}
+	for ; rem > 0 && w < len(a.d); w++ {
+		a.d[w] = byte('0' + rem>>k)
+		rem = (rem & mask) * 10
+	}
+	a.nd = w
+	a.trim()
+}
+
+// trim removes the trailing zeros of a.
+func (a *decimal) trim() {
+	for a.nd > 0 && a.d[a.nd-1] == '0' {
+		a.nd--
+	}
+	if a.nd == 0 {
+		a.dp = 0
+	}
+}
+
+// round rounds a to nd digits, half to even.
+func (a *decimal) round(nd int) {
+	if nd < 0 || nd >= a.nd {
+		return
+	}
+	up := a.d[nd] > '5' || a.d[nd] == '5' && (nd+1 < a.nd || nd > 0 && (a.d[nd-1]-'0')%2 == 1)
+	if up {
+		a.roundUp(nd)
+	} else {
+		a.roundDown(nd)
+	}
+}
+
+// roundDown truncates a to nd digits.
+func (a *decimal) roundDown(nd int) {
+	if nd < 0 || nd >= a.nd {
+		return
+	}
+	a.nd = nd
+	a.trim()
+}
+
+// roundUp rounds a up to nd digits.
+func (a *decimal) roundUp(nd int) {
+	if nd < 0 || nd >= a.nd {
+		return
+	}
+	i := nd - 1
+	for i >= 0 && a.d[i] == '9' {
+		i--
+	}
+	if i < 0 {
+		a.d[0] = '1'
+		a.nd = 1
+		a.dp++
+		return
+	}
+	a.d[i]++
+	a.nd = i + 1
+}
+
+// roundShortest rounds a, the exact value of the float mant*2^(exp-mantbits),
+// to the shortest digits that are closer to it than to the neighbouring
+// floats, so that parsing them yields the float again. The halfway points
+// between the float and its neighbours, which parse to the even one, are
+// upper and lower: the digits of a can be rounded down if they differ from the
+// ones of lower, and rounded up if they differ enough from the ones of upper.
+func (a *decimal) roundShortest(mant uint64, exp int, flt *floatInfo) {
+	if mant == 0 {
+		a.nd = 0
+		return
+	}
+	minexp := flt.bias + 1
+	// An integer whose trailing zeros are more than the bits below the
+	// mantissa is already the shortest.
+	if exp > minexp && 332*(a.dp-a.nd) >= 100*(exp-int(flt.mantbits)) {
+		return
+	}
+	var upper, lower decimal
+	upper.assign(mant*2 + 1)
+	upper.shift(exp - int(flt.mantbits) - 1)
+	// The float below the smallest mantissa of an exponent is closer, unless
+	// it's denormal.
+	mantlo, explo := mant-1, exp
+	if mant <= 1<<flt.mantbits && exp != minexp {
+		mantlo, explo = mant*2-1, exp-1
+	}
+	lower.assign(mantlo*2 + 1)
+	lower.shift(explo - int(flt.mantbits) - 1)
+	inclusive := mant%2 == 0 // the halfway points parse to the float
+	// The digits are compared at the positions of the ones of upper, which has
+	// the most integral digits. upperdelta is 0 while the digits of a and
+	// upper are equal, 1 while a is one less than upper in the last digit,
+	// followed by 9s in a and 0s in upper, and 2 as a is less than upper by
+	// more than a unit of the last digit.
+	upperdelta := 0
+	for ui := 0; ; ui++ {
+		mi := ui - upper.dp + a.dp
+		if mi >= a.nd {
+			break
+		}
+		li := ui - upper.dp + lower.dp
+		l := byte('0')
+		if li >= 0 && li < lower.nd {
+			l = lower.d[li]
+		}
+		m := byte('0')
+		if mi >= 0 {
+			m = a.d[mi]
+		}
+		u := byte('0')
+		if ui < upper.nd {
+			u = upper.d[ui]
+		}
+		okdown := l != m || inclusive && li+1 == lower.nd
+		switch {
+		case upperdelta == 0 && m+1 < u:
+			upperdelta = 2
+		case upperdelta == 0 && m != u:
+			upperdelta = 1
+		case upperdelta == 1 && (m != '9' || u != '0'):
+			upperdelta = 2
+		}
+		okup := upperdelta > 0 && (inclusive || upperdelta > 1 || ui+1 < upper.nd)
+		switch {
+		case okdown && okup:
+			a.round(mi + 1)
+			return
+		case okdown:
+			a.roundDown(mi + 1)
+			return
+		case okup:
+			a.roundUp(mi + 1)
+			return
+		}
+	}
+}
+
+// appendFloat appends the representation of v, which is a float32 if bitSize
+// is 32, in the format fmt, with the precision prec, to dst as
+// strconv.AppendFloat does. The formats are 'b', 'e', 'E', 'f', 'g', 'G', 'x'
+// and 'X'; others are appended as %fmt.
+func appendFloat(dst []byte, v float64, fmt byte, prec, bitSize int) []byte {
+	var bits uint64
+	flt := &float64info
+	if bitSize == 32 {
+		f := float32(v)
+		bits = uint64(*(*uint32)(unsafe.Pointer(&f)))
+		flt = &float32info
+	} else {
+		bits = *(*uint64)(unsafe.Pointer(&v))
+	}
+	neg := bits>>(flt.expbits+flt.mantbits) != 0
+	exp := int(bits>>flt.mantbits) & (1<<flt.expbits - 1)
+	mant := bits & (uint64(1)<<flt.mantbits - 1)
+	switch exp {
+	case 1<<flt.expbits - 1:
+		switch {
+		case mant != 0:
+			return append(dst, "NaN"...)
+		case neg:
+			return append(dst, "-Inf"...)
+		}
+		return append(dst, "+Inf"...)
+	case 0: // denormal
+		exp++
+	default:
+		mant |= uint64(1) << flt.mantbits
+	}
+	exp += flt.bias
+	if fmt == 'b' {
+		if neg {
+			dst = append(dst, '-')
+		}
+		dst = appendUint(dst, mant)
+		dst = append(dst, 'p')
+		exp -= int(flt.mantbits)
+		if exp >= 0 {
+			dst = append(dst, '+')
+		}
+		return appendInt(dst, int64(exp))
+	}
+	if fmt == 'x' || fmt == 'X' {
+		return appendHexFloat(dst, neg, mant, exp, flt, prec, fmt)
+	}
+	if prec < 0 {
+		var d shortDecimal
+		if d.assignShortest(mant, exp, flt) {
+			return formatDigits(dst, neg, d.d[:d.nd], d.dp, prec, fmt)
+		}
+	}
+	return bigFtoa(dst, neg, mant, exp, flt, prec, fmt)
+}
+
+// appendHexFloat appends v, the float mant*2^(exp-mantbits), in the format
+// 'x' or 'X': -0x1.hhhp±dd, with prec hexadecimal digits after the point, or
+// as many as needed if prec is negative, or -0x0p+00 if v is zero.
+func appendHexFloat(dst []byte, neg bool, mant uint64, exp int, flt *floatInfo, prec int, fmt byte) []byte {
+	if mant == 0 {
+		exp = 0
+	}
+	mant <<= 60 - flt.mantbits // the leading 1, if any, is bit 60
+	for mant != 0 && mant&(1<<60) == 0 {
+		mant <<= 1
+		exp--
+	}
+	if prec >= 0 && prec < 15 { // round to nearest even
+		shift := uint(prec * 4)
+		extra := (mant << shift) & (1<<60 - 1)
+		mant >>= 60 - shift
+		if extra|(mant&1) > 1<<59 {
+			mant++
+		}
+		mant <<= 60 - shift
+		if mant&(1<<61) != 0 {
+			mant >>= 1
+			exp++
+		}
+	}
+	digits, p := fmtDigits, byte('p')
+	if fmt == 'X' {
+		digits, p = fmtUpperDigits, 'P'
+	}
+	if neg {
+		dst = append(dst, '-')
+	}
+	dst = append(dst, '0', fmt, '0'+byte(mant>>60&1))
+	mant <<= 4
+	if prec < 0 && mant != 0 || prec > 0 {
+		dst = append(dst, '.')
+		for i := 0; prec < 0 && mant != 0 || i < prec; i++ {
+			dst = append(dst, digits[mant>>60&15])
+			mant <<= 4
+		}
+	}
+	dst = append(dst, p)
+	if exp < 0 {
+		dst, exp = append(dst, '-'), -exp
+	} else {
+		dst = append(dst, '+')
+	}
+	if exp < 10 {
+		dst = append(dst, '0')
+	}
+	return appendUint(dst, uint64(exp))
+}
+
+// bigFtoa appends v, the float mant*2^(exp-mantbits), as appendFloat does, by
+// the exact decimal value of v.
+func bigFtoa(dst []byte, neg bool, mant uint64, exp int, flt *floatInfo, prec int, fmt byte) []byte {
+	var d decimal
+	d.assign(mant)
+	d.shift(exp - int(flt.mantbits))
+	if prec < 0 {
+		d.roundShortest(mant, exp, flt)
+	} else {
+		switch fmt {
+		case 'e', 'E':
+			d.round(prec + 1)
+		case 'f':
+			d.round(d.dp + prec)
+		case 'g', 'G':
+			if prec == 0 {
+				prec = 1
+			}
+			d.round(prec)
+		}
+	}
+	return formatDigits(dst, neg, d.d[:d.nd], d.dp, prec, fmt)
+}
+
+// formatDigits appends the float 0.digs*10^dp, whose digits are rounded to the
+// precision prec, or are the shortest ones if prec is negative, in the format
+// fmt.
+func formatDigits(dst []byte, neg bool, digs []byte, dp, prec int, fmt byte) []byte {
+	nd := len(digs)
+	shortest := prec < 0
+	if shortest {
+		switch fmt {
+		case 'e', 'E':
+			prec = nd - 1
+		case 'f':
+			prec = nd - dp
+		case 'g', 'G':
+			prec = nd
+		}
+		if prec < 0 {
+			prec = 0
+		}
+	}
+	switch fmt {
+	case 'e', 'E':
+		return appendFloatE(dst, neg, digs, dp, prec, fmt)
+	case 'f':
+		return appendFloatF(dst, neg, digs, dp, prec)
+	case 'g', 'G':
+		// %e is used if the exponent is less than -4, or not less than the
+		// precision, which is 6 for the shortest digits.
+		eprec := prec
+		if eprec > nd && nd >= dp {
+			eprec = nd
+		}
+		if shortest {
+			eprec = 6
+		}
+		if exp := dp - 1; exp < -4 || exp >= eprec {
+			if prec > nd {
+				prec = nd
+			}
+			return appendFloatE(dst, neg, digs, dp, prec-1, fmt+'e'-'g')
+		}
+		if prec > dp {
+			prec = nd
+		}
+		if prec -= dp; prec < 0 {
+			prec = 0
+		}
+		return appendFloatF(dst, neg, digs, dp, prec)
+	}
+	return append(dst, '%', fmt)
+}
+
+// appendFloatE appends 0.digs*10^dp in the format %e, or %E if fmt is 'E':
+// -d.ddddde±dd.
+func appendFloatE(dst []byte, neg bool, digs []byte, dp, prec int, fmt byte) []byte {
+	if neg {
+		dst = append(dst, '-')
+	}
+	nd := len(digs)
+	ch := byte('0')
+	if nd > 0 {
+		ch = digs[0]
+	}
+	dst = append(dst, ch)
+	if prec > 0 {
+		dst = append(dst, '.')
+		i, m := 1, prec+1
+		if m > nd {
+			m = nd
+		}
+		if i < m {
+			dst = append(dst, digs[i:m]...)
+			i = m
+		}
+		for ; i <= prec; i++ {
+			dst = append(dst, '0')
+		}
+	}
+	dst = append(dst, fmt)
+	exp := dp - 1
+	if nd == 0 {
+		exp = 0
+	}
+	if exp < 0 {
+		dst = append(dst, '-')
+		exp = -exp
+	} else {
+		dst = append(dst, '+')
+	}
+	if exp < 10 {
+		dst = append(dst, '0')
+	}
+	return appendInt(dst, int64(exp))
+}
+
+// appendFloatF appends 0.digs*10^dp in the format %f: -ddddd.ddddd.
+func appendFloatF(dst []byte, neg bool, digs []byte, dp, prec int) []byte {
+	if neg {
+		dst = append(dst, '-')
+	}
+	nd := len(digs)
+	if dp > 0 {
+		m := dp
+		if m > nd {
+			m = nd
+		}
+		dst = append(dst, digs[:m]...)
+		for ; m < dp; m++ {
+			dst = append(dst, '0')
+		}
+	} else {
+		dst = append(dst, '0')
+	}
+	if prec > 0 {
+		dst = append(dst, '.')
+		for i := 1; i <= prec; i++ {
+			ch := byte('0')
+			if j := dp + i - 1; 0 <= j && j < nd {
+				ch = digs[j]
+			}
+			dst = append(dst, ch)
+		}
+	}
+	return dst
+}
+
+// appendInt appends the decimal representation of v to dst.
+func appendInt(dst []byte, v int64) []byte {
+	if v < 0 {
+		return appendUint(append(dst, '-'), uint64(-v))
+	}
+	return appendUint(dst, uint64(v))
+}
+
+// appendUint appends the decimal representation of v to dst.
+func appendUint(dst []byte, v uint64) []byte {
+	var buf [20]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = byte('0' + v%10)
+		v /= 10
+		if v == 0 {
+			break
+		}
+	}
+	return append(dst, buf[i:]...)
+}
+
+// StrconvFormatFloat implements strconv.FormatFloat.
+func StrconvFormatFloat(f float64, fmt byte, prec, bitSize int) string {
+	return bytesToString(appendFloat(nil, f, fmt, prec, bitSize))
+}
+
+// StrconvAppendFloat implements strconv.AppendFloat.
+func StrconvAppendFloat(dst []byte, f float64, fmt byte, prec, bitSize int) []byte {
+	return appendFloat(dst, f, fmt, prec, bitSize)
+}
+
+// StrconvFormatComplex implements strconv.FormatComplex: (re±imi), where the
+// parts are float32 if bitSize is 64.
+func StrconvFormatComplex(c complex128, fmt byte, prec, bitSize int) string {
+	bitSize >>= 1
+	b := append([]byte(nil), '(')
+	b = appendFloat(b, real(c), fmt, prec, bitSize)
+	n := len(b)
+	b = appendFloat(b, imag(c), fmt, prec, bitSize)
+	if b[n] != '+' && b[n] != '-' {
+		b = append(b, 0)
+		copy(b[n+1:], b[n:])
+		b[n] = '+'
+	}
+	return bytesToString(append(b, 'i', ')'))
+}
+
+// bytesToString returns the string of the bytes of b, which mustn't be
+// modified afterwards.
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&stringHeader{sliceData(b), len(b)}))
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestAppendFloat(t *testing.T) {
+	vals := []float64{
+		0, math.Copysign(0, -1), 1, -1.5, 0.1, 1.0 / 3, 123456789, 1e21, 1e-7,
+		math.Pi, math.MaxFloat64, math.SmallestNonzeroFloat64, 0x1.fffffffffffffp0,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+	}
+	for _, v := range vals {
+		for _, fmt := range []byte("beEfgGxXq") {
+			for _, prec := range []int{-1, 0, 1, 3, 17} {
+				for _, bitSize := range []int{32, 64} {
+					want := string(strconv.AppendFloat(nil, v, fmt, prec, bitSize))
+					if got := string(appendFloat(nil, v, fmt, prec, bitSize)); got != want {
+						t.Errorf("TestAppendFloat: %v in format %c, precision %d, size %d: got %q, want %q",
+							v, fmt, prec, bitSize, got, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * Portions of this file are derived from strconv/extfloat.go of Go:
+ *
+ * Copyright 2011 The Go Authors. All rights reserved.
+ * Use of this source code is governed by a BSD-style license that can be
+ * found in the LICENSE-GO file.
+ */
+
+package runtime
+
+// This file implements the fast path of the shortest digits of a float (see
+// appendFloat): the algorithm Grisu3 of Florian Loitsch, "Printing
+// Floating-Point Numbers Quickly and Accurately with Integers" (PLDI 2010), as
+// strconv had it before Ryu. It computes the digits with 64-bit integers and
+// an approximate power of ten, and gives up, for about 0.5% of the floats,
+// when the error of the approximation doesn't let it tell the shortest digits
+// from the ones of a neighbouring float, which bigFtoa then finds exactly.
+
+// shortDecimal is the decimal number 0.d[0]d[1]...d[nd-1] * 10^dp, whose
+// digits are ASCII, without trailing zeros, that is short enough for the
+// shortest digits of a float.
+type shortDecimal struct {
+	d  [32]byte
+	nd int
+	dp int
+}
+
+// extFloat is the number mant*2^exp.
+type extFloat struct {
+	mant uint64
+	exp  int
+}
+
+const (
+	firstPowerOfTen = -348
+	stepPowerOfTen  = 8
+)
+
+// powersOfTen are the powers of ten from 10^firstPowerOfTen, by steps of
+// stepPowerOfTen, with normalized mantissas, rounded to the nearest.
+var powersOfTen = [...]extFloat{
+	{0xfa8fd5a0081c0288, -1220}, // 1e-348
+	{0xbaaee17fa23ebf76, -1193}, // 1e-340
+	{0x8b16fb203055ac76, -1166}, // 1e-332
+	{0xcf42894a5dce35ea, -1140}, // 1e-324
+	{0x9a6bb0aa55653b2d, -1113}, // 1e-316
+	{0xe61acf033d1a45df, -1087}, // 1e-308
+	{0xab70fe17c79ac6ca, -1060}, // 1e-300
+	{0xff77b1fcbebcdc4f, -1034}, // 1e-292
+	{0xbe5691ef416bd60c, -1007}, // 1e-284
+	{0x8dd01fad907ffc3c, -980},  // 1e-276
+	{0xd3515c2831559a83, -954},  // 1e-268
+	{0x9d71ac8fada6c9b5, -927},  // 1e-260
+	{0xea9c227723ee8bcb, -901},  // 1e-252
+	{0xaecc49914078536d, -874},  // 1e-244
+	{0x823c12795db6ce57, -847},  // 1e-236
+	{0xc21094364dfb5637, -821},  // 1e-228
+	{0x9096ea6f3848984f, -794},  // 1e-220
+	{0xd77485cb25823ac7, -768},  // 1e-212
+	{0xa086cfcd97bf97f4, -741},  // 1e-204
+	{0xef340a98172aace5, -715},  // 1e-196
+	{0xb23867fb2a35b28e, -688},  // 1e-188
+	{0x84c8d4dfd2c63f3b, -661},  // 1e-180
+	{0xc5dd44271ad3cdba, -635},  // 1e-172
+	{0x936b9fcebb25c996, -608},  // 1e-164
+	{0xdbac6c247d62a584, -582},  // 1e-156
+	{0xa3ab66580d5fdaf6, -555},  // 1e-148
+	{0xf3e2f893dec3f126, -529},  // 1e-140
+	{0xb5b5ada8aaff80b8, -502},  // 1e-132
+	{0x87625f056c7c4a8b, -475},  // 1e-124
+	{0xc9bcff6034c13053, -449},  // 1e-116
+	{0x964e858c91ba2655, -422},  // 1e-108
+	{0xdff9772470297ebd, -396},  // 1e-100
+	{0xa6dfbd9fb8e5b88f, -369},  // 1e-92
+	{0xf8a95fcf88747d94, -343},  // 1e-84
+	{0xb94470938fa89bcf, -316},  // 1e-76
+	{0x8a08f0f8bf0f156b, -289},  // 1e-68
+	{0xcdb02555653131b6, -263},  // 1e-60
+	{0x993fe2c6d07b7fac, -236},  // 1e-52
+	{0xe45c10c42a2b3b06, -210},  // 1e-44
+	{0xaa242499697392d3, -183},  // 1e-36
+	{0xfd87b5f28300ca0e, -157},  // 1e-28
+	{0xbce5086492111aeb, -130},  // 1e-20
+	{0x8cbccc096f5088cc, -103},  // 1e-12
+	{0xd1b71758e219652c, -77},   // 1e-4
+	{0x9c40000000000000, -50},   // 1e4
+	{0xe8d4a51000000000, -24},   // 1e12
+	{0xad78ebc5ac620000, 3},     // 1e20
+	{0x813f3978f8940984, 30},    // 1e28
+	{0xc097ce7bc90715b3, 56},    // 1e36
+	{0x8f7e32ce7bea5c70, 83},    // 1e44
+	{0xd5d238a4abe98068, 109},   // 1e52
+	{0x9f4f2726179a2245, 136},   // 1e60
+	{0xed63a231d4c4fb27, 162},   // 1e68
+	{0xb0de65388cc8ada8, 189},   // 1e76
+	{0x83c7088e1aab65db, 216},   // 1e84
+	{0xc45d1df942711d9a, 242},   // 1e92
+	{0x924d692ca61be758, 269},   // 1e100
+	{0xda01ee641a708dea, 295},   // 1e108
+	{0xa26da3999aef774a, 322},   // 1e116
+	{0xf209787bb47d6b85, 348},   // 1e124
+	{0xb454e4a179dd1877, 375},   // 1e132
+	{0x865b86925b9bc5c2, 402},   // 1e140
+	{0xc83553c5c8965d3d, 428},   // 1e148
+	{0x952ab45cfa97a0b3, 455},   // 1e156
+	{0xde469fbd99a05fe3, 481},   // 1e164
+	{0xa59bc234db398c25, 508},   // 1e172
+	{0xf6c69a72a3989f5c, 534},   // 1e180
+	{0xb7dcbf5354e9bece, 561},   // 1e188
+	{0x88fcf317f22241e2, 588},   // 1e196
+	{0xcc20ce9bd35c78a5, 614},   // 1e204
+	{0x98165af37b2153df, 641},   // 1e212
+	{0xe2a0b5dc971f303a, 667},   // 1e220
+	{0xa8d9d1535ce3b396, 694},   // 1e228
+	{0xfb9b7cd9a4a7443c, 720},   // 1e236
+	{0xbb764c4ca7a44410, 747},   // 1e244
+	{0x8bab8eefb6409c1a, 774},   // 1e252
+	{0xd01fef10a657842c, 800},   // 1e260
+	{0x9b10a4e5e9913129, 827},   // 1e268
+	{0xe7109bfba19c0c9d, 853},   // 1e276
+	{0xac2820d9623bf429, 880},   // 1e284
+	{0x80444b5e7aa7cf85, 907},   // 1e292
+	{0xbf21e44003acdd2d, 933},   // 1e300
+	{0x8e679c2f5e44ff8f, 960},   // 1e308
+	{0xd433179d9c8cb841, 986},   // 1e316
+	{0x9e19db92b4e31ba9, 1013},  // 1e324
+	{0xeb96bf6ebadf77d9, 1039},  // 1e332
+	{0xaf87023b9bf0ee6b, 1066},  // 1e340
+}
+
+// uint64pow10 are the powers of ten that fit in an uint64.
+var uint64pow10 = [...]uint64{
+	1e00, 1e01, 1e02, 1e03, 1e04, 1e05, 1e06, 1e07, 1e08, 1e09,
+	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19,
+}
+
+// normalize shifts the mantissa of f, which is nonzero, so that its highest
+// bit is set.
+func (f *extFloat) normalize() {
+	for shift := 32; shift > 0; shift >>= 1 {
+		if f.mant>>(64-shift) == 0 {
+			f.mant <<= uint(shift)
+			f.exp -= shift
+		}
+	}
+}
+
+// multiply sets f to the product f*g, rounded, but not normalized.
+func (f *extFloat) multiply(g extFloat) {
+	hi, lo := mul64(f.mant, g.mant)
+	f.mant = hi + lo>>63
+	f.exp += g.exp + 64
+}
+
+// mul64 returns the 128-bit product of x and y, as bits.Mul64 does.
+func mul64(x, y uint64) (hi, lo uint64) {
+	const mask32 = 1<<32 - 1
+	x0, x1 := x&mask32, x>>32
+	y0, y1 := y&mask32, y>>32
+	w0 := x0 * y0
+	t := x1*y0 + w0>>32
+	w1, w2 := t&mask32, t>>32
+	w1 += x0 * y1
+	return x1*y1 + w2 + w1>>32, x * y
+}
+
+// frexp10 multiplies f by the power of ten 10^-exp10 of powersOfTen, whose
+// index is returned too, that puts the binary exponent of f between -60 and
+// -32, so that the integral part of f, whose digits are found by divisions,
+// is less than 2^32, and its fractional part can be multiplied by 10 without
+// overflowing.
+func (f *extFloat) frexp10() (exp10, index int) {
+	const expMin, expMax = -60, -32
+	approxExp10 := ((expMin+expMax)/2 - f.exp) * 28 / 93 // log2(10) is about 93/28
+	i := (approxExp10 - firstPowerOfTen) / stepPowerOfTen
+	for {
+		exp := f.exp + powersOfTen[i].exp + 64
+		if exp < expMin {
+			i++
+		} else if exp > expMax {
+			i--
+		} else {
+			break
+		}
+	}
+	f.multiply(powersOfTen[i])
+	return -(firstPowerOfTen + i*stepPowerOfTen), i
+}
+
+// assignShortest sets d to the shortest digits of the float
+// mant*2^(exp-mantbits), that parse back to it, and reports whether it could
+// find them.
+func (d *shortDecimal) assignShortest(mant uint64, exp int, flt *floatInfo) bool {
+	d.nd, d.dp = 0, 0
+	if mant == 0 {
+		return true
+	}
+	e := exp - int(flt.mantbits)
+	if e <= 0 && mant == mant>>uint(-e)<<uint(-e) { // an integer less than 2^(mantbits+1)
+		d.assignInt(mant >> uint(-e))
+		return true
+	}
+	// The halfway points between the float and its neighbours, as roundShortest
+	// computes them.
+	f := extFloat{mant, e}
+	upper := extFloat{2*mant + 1, e - 1}
+	lower := extFloat{2*mant - 1, e - 1}
+	if mant == 1<<flt.mantbits && exp != flt.bias+1 {
+		lower = extFloat{4*mant - 1, e - 2}
+	}
+	upper.normalize()
+	f.mant <<= uint(f.exp - upper.exp)
+	f.exp = upper.exp
+	lower.mant <<= uint(lower.exp - upper.exp)
+	lower.exp = upper.exp
+
+	exp10, i := upper.frexp10()
+	f.multiply(powersOfTen[i])
+	lower.multiply(powersOfTen[i])
+	// Take a margin for the rounding of the multiplications.
+	upper.mant++
+	lower.mant--
+
+	// The shortest digits are the ones of upper, truncated, where they are
+	// above lower, and then decremented to get closer to f.
+	shift := uint(-upper.exp)
+	integer := uint32(upper.mant >> shift)
+	fraction := upper.mant - uint64(integer)<<shift
+	allowance := upper.mant - lower.mant // how far below upper the digits can be
+	targetGap := upper.mant - f.mant     // how far below upper f is
+
+	integerDigits := 0
+	for pow := uint64(1); integerDigits < len(uint64pow10) && pow <= uint64(integer); pow *= 10 {
+		integerDigits++
+	}
+	d.dp = integerDigits + exp10
+	for i := 0; i < integerDigits; i++ {
+		pow := uint64pow10[integerDigits-i-1]
+		digit := integer / uint32(pow)
+		d.d[i] = byte('0' + digit)
+		integer -= digit * uint32(pow)
+		if diff := uint64(integer)<<shift + fraction; diff < allowance {
+			d.nd = i + 1
+			return d.adjustLastDigit(diff, targetGap, allowance, pow<<shift, 2)
+		}
+	}
+	d.nd = integerDigits
+	// The fraction is less than 2^60, so that it doesn't overflow as it's
+	// multiplied by 10.
+	multiplier := uint64(1)
+	for d.nd < len(d.d) {
+		fraction *= 10
+		multiplier *= 10
+		digit := fraction >> shift
+		d.d[d.nd] = byte('0' + digit)
+		d.nd++
+		fraction -= digit << shift
+		if fraction < allowance*multiplier {
+			return d.adjustLastDigit(fraction, targetGap*multiplier, allowance*multiplier, 1<<shift, multiplier*2)
+		}
+	}
+	return false
+}
+
+// adjustLastDigit decrements the last digit of d, which is diff below upper,
+// while it gets closer to f, which is target below upper, and reports whether
+// the digits are then certainly the closest ones to f, within maxDiff below
+// upper, in spite of the error of ulpBinary of the approximation. A unit of
+// the last digit is ulpDecimal.
+func (d *shortDecimal) adjustLastDigit(diff, target, maxDiff, ulpDecimal, ulpBinary uint64) bool {
+	if ulpDecimal < 2*ulpBinary {
+		return false // the approximation is too wide
+	}
+	for diff+ulpDecimal/2+ulpBinary < target {
+		d.d[d.nd-1]--
+		diff += ulpDecimal
+	}
+	if diff+ulpDecimal <= target+ulpDecimal/2+ulpBinary {
+		return false // the next digit down may be as close
+	}
+	if diff < ulpBinary || diff > maxDiff-ulpBinary {
+		return false // the digits may be out of the range
+	}
+	d.trim()
+	return true
+}
+
+// assignInt sets d to v.
+func (d *shortDecimal) assignInt(v uint64) {
+	var buf [20]byte
+	n := 0
+	for ; v > 0; v /= 10 {
+		buf[n] = byte('0' + v%10)
+		n++
+	}
+	for i := 0; i < n; i++ {
+		d.d[i] = buf[n-1-i]
+	}
+	d.nd, d.dp = n, n
+	d.trim()
+}
+
+// trim removes the trailing zeros of d.
+func (d *shortDecimal) trim() {
+	for d.nd > 0 && d.d[d.nd-1] == '0' {
+		d.nd--
+	}
+	if d.nd == 0 {
+		d.dp = 0
+	}
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// -----------------------------------------------------------------------------
+
+// The keys of a map, and the structs and arrays compared by the == operator,
+// are hashed and compared by functions that llgo generates for their type
+// (see llssa.Package.hashFunc), which call the ones below, and the values
+// of interfaces by the ones of their dynamic types (see Type.Hasher). The
+// hashes are seeded per map, so that an attacker that chooses the keys can't
+// predict which of them collide (hash flooding): bytes are hashed with AES
+// instructions if the CPU has them (see hasAES), or else with wyhash.
+
+// Memhash returns the hash of the n bytes pointed to by p.
+func Memhash(p unsafe.Pointer, n, seed uintptr) uintptr {
+	if hasAES() {
+		return aeshash(p, n, seed)
+	}
+	return wyhash(p, n, seed)
+}
+
+// Strhash returns the hash of the string pointed to by p.
+func Strhash(p unsafe.Pointer, seed uintptr) uintptr {
+	s := (*stringHeader)(p)
+	return Memhash(s.data, uintptr(s.len), seed)
+}
+
+// F32hash returns the hash of the float32 pointed to by p. +0 and -0 have the
+// same hash, and NaNs, which are all different, have random ones.
+func F32hash(p unsafe.Pointer, seed uintptr) uintptr {
+	switch f := *(*float32)(p); {
+	case f == 0:
+		return Memhash(nil, 0, seed)
+	case f != f:
+		return uintptr(fastrandn(grand(), 1<<31))
+	}
+	return Memhash(p, 4, seed)
+}
+
+// F64hash returns the hash of the float64 pointed to by p, as F32hash does.
+func F64hash(p unsafe.Pointer, seed uintptr) uintptr {
+	switch f := *(*float64)(p); {
+	case f == 0:
+		return Memhash(nil, 0, seed)
+	case f != f:
+		return uintptr(fastrandn(grand(), 1<<31))
+	}
+	return Memhash(p, 8, seed)
+}
+
+// C64hash returns the hash of the complex64 pointed to by p.
+func C64hash(p unsafe.Pointer, seed uintptr) uintptr {
+	return F32hash(unsafe.Add(p, 4), F32hash(p, seed))
+}
+
+// C128hash returns the hash of the complex128 pointed to by p.
+func C128hash(p unsafe.Pointer, seed uintptr) uintptr {
+	return F64hash(unsafe.Add(p, 8), F64hash(p, seed))
+}
+
+// Interhash returns the hash of the interface pointed to by p, from its
+// dynamic type. It panics if the type isn't comparable.
+func Interhash(p unsafe.Pointer, seed uintptr) uintptr {
+	e := (*eface)(p)
+	if e.typ == nil {
+		return seed
+	}
+	seed ^= uintptr(unsafe.Pointer(e.typ))
+	if e.typ.isDirect() {
+		return typehash(e.typ, unsafe.Pointer(&e.data), seed)
+	}
+	return typehash(e.typ, e.data, seed)
+}
+
+// ArrayHash returns the hash of the array of n elements of size bytes pointed
+// to by p, whose elements hash hashes.
+func ArrayHash(p unsafe.Pointer, n, size uintptr, hash func(key unsafe.Pointer, seed uintptr) uintptr, seed uintptr) uintptr {
+	for i := uintptr(0); i < n; i++ {
+		seed = hash(unsafe.Add(p, i*size), seed)
+	}
+	return seed
+}
+
+// ArrayEqual reports whether the arrays of n elements of size bytes pointed to
+// by p and q are equal, comparing their elements by equal, in order, until two
+// of them differ.
+func ArrayEqual(p, q unsafe.Pointer, n, size uintptr, equal func(x, y unsafe.Pointer) bool) bool {
+	for i := uintptr(0); i < n; i++ {
+		if !equal(unsafe.Add(p, i*size), unsafe.Add(q, i*size)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Memequal reports whether the n bytes pointed to by p and q are equal.
+func Memequal(p, q unsafe.Pointer, n uintptr) bool {
+	return p == q || c.Memcmp(p, q, n) == 0
+}
+
+// Strequal reports whether the strings pointed to by p and q are equal.
+func Strequal(p, q unsafe.Pointer) bool {
+	return *(*string)(p) == *(*string)(q)
+}
+
+// F32equal reports whether the float32s pointed to by p and q are equal.
+func F32equal(p, q unsafe.Pointer) bool {
+	return *(*float32)(p) == *(*float32)(q)
+}
+
+// F64equal reports whether the float64s pointed to by p and q are equal.
+func F64equal(p, q unsafe.Pointer) bool {
+	return *(*float64)(p) == *(*float64)(q)
+}
+
+// C64equal reports whether the complex64s pointed to by p and q are equal.
+func C64equal(p, q unsafe.Pointer) bool {
+	return *(*complex64)(p) == *(*complex64)(q)
+}
+
+// C128equal reports whether the complex128s pointed to by p and q are equal.
+func C128equal(p, q unsafe.Pointer) bool {
+	return *(*complex128)(p) == *(*complex128)(q)
+}
+
+// Interequal reports whether the interfaces pointed to by p and q are equal,
+// which they are if they have the same dynamic type and equal values.
+func Interequal(p, q unsafe.Pointer) bool {
+	x, y := (*eface)(p), (*eface)(q)
+	if x.typ != y.typ {
+		return false
+	}
+	if x.typ == nil || x.typ.isDirect() {
+		return x.data == y.data
+	}
+	return typeequal(x.typ, x.data, y.data)
+}
+
+// typehash returns the hash of the value of the dynamic type t of an
+// interface, pointed to by p, by the hash function of t. It panics if t isn't
+// comparable.
+func typehash(t *Type, p unsafe.Pointer, seed uintptr) uintptr {
+	if t.Hasher == nil {
+		Panic(runtimeError(concat("hash of unhashable type ", t.Str)))
+	}
+	return t.Hasher(p, seed)
+}
+
+// typeequal reports whether the values of the dynamic type t of interfaces,
+// pointed to by p and q, are equal, by the equal function of t. It panics if
+// t isn't comparable.
+func typeequal(t *Type, p, q unsafe.Pointer) bool {
+	if t.Equal == nil {
+		Panic(runtimeError(concat("comparing uncomparable type ", t.Str)))
+	}
+	return t.Equal(p, q)
+}
+
+// -----------------------------------------------------------------------------
+
+// Constants of wyhash, as in the runtime of gc.
+const (
+	wyp0 = 0xa0761d6478bd642f
+	wyp1 = 0xe7037ed1a0b428db
+	wyp2 = 0x8ebc6af09c88c6e3
+	wyp3 = 0x589965cc75374cc3
+	wyp4 = 0x1d8e4e27c47d124f
+)
+
+// wyhash returns the wyhash of the n bytes pointed to by p.
+func wyhash(p unsafe.Pointer, n, seed uintptr) uintptr {
+	var a, b uint64
+	h := uint64(seed) ^ wyp0
+	switch {
+	case n == 0:
+		return uintptr(h)
+	case n < 4:
+		a = uint64(*(*byte)(p))
+		a |= uint64(*(*byte)(unsafe.Add(p, n>>1))) << 8
+		a |= uint64(*(*byte)(unsafe.Add(p, n-1))) << 16
+	case n == 4:
+		a = read4(p)
+		b = a
+	case n < 8:
+		a = read4(p)
+		b = read4(unsafe.Add(p, n-4))
+	case n == 8:
+		a = read8(p)
+		b = a
+	case n <= 16:
+		a = read8(p)
+		b = read8(unsafe.Add(p, n-8))
+	default:
+		l := n
+		if l > 48 {
+			h1, h2 := h, h
+			for ; l > 48; l -= 48 {
+				h = wymix(read8(p)^wyp1, read8(unsafe.Add(p, 8))^h)
+				h1 = wymix(read8(unsafe.Add(p, 16))^wyp2, read8(unsafe.Add(p, 24))^h1)
+				h2 = wymix(read8(unsafe.Add(p, 32))^wyp3, read8(unsafe.Add(p, 40))^h2)
+				p = unsafe.Add(p, 48)
+			}
+			h ^= h1 ^ h2
+		}
+		for ; l > 16; l -= 16 {
+			h = wymix(read8(p)^wyp1, read8(unsafe.Add(p, 8))^h)
+			p = unsafe.Add(p, 16)
+		}
+		a = read8(unsafe.Add(p, l-16))
+		b = read8(unsafe.Add(p, l-8))
+	}
+	return uintptr(wymix(wyp4^uint64(n), wymix(a^wyp1, b^h)))
+}
+
+// wymix returns the xor of the high and low halves of the 128-bit product of
+// a and b.
+func wymix(a, b uint64) uint64 {
+	const mask32 = 1<<32 - 1
+	a0, a1 := a&mask32, a>>32
+	b0, b1 := b&mask32, b>>32
+	w0 := a0 * b0
+	t := a1*b0 + w0>>32
+	w1 := t&mask32 + a0*b1
+	hi := a1*b1 + t>>32 + w1>>32
+	return hi ^ a*b
+}
+
+// read4 reads a little-endian uint32 at p, which may be unaligned, byte by
+// byte, so that wyhash hashes the same bytes alike on big-endian targets.
+func read4(p unsafe.Pointer) uint64 {
+	q := (*[4]byte)(p)
+	return uint64(q[0]) | uint64(q[1])<<8 | uint64(q[2])<<16 | uint64(q[3])<<24
+}
+
+// read8 reads a little-endian uint64 at p, which may be unaligned.
+func read8(p unsafe.Pointer) uint64 {
+	return read4(p) | read4(unsafe.Add(p, 4))<<32
+}
+
+// -----------------------------------------------------------------------------
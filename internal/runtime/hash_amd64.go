@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/asm"
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// aesState is 1 if the CPU has AES instructions, -1 if it hasn't, and 0 until
+// hasAES checks it.
+var aesState int8
+
+// hasAES reports whether the CPU has AES instructions, which CPUID reports in
+// bit 25 of ECX for leaf 1.
+func hasAES() bool {
+	if aesState == 0 {
+		aesState = -1
+		if asm.Constraint("cpuid", "={rcx},{rax},{rcx},~{rbx},~{rdx}", 1, 0)&(1<<25) != 0 {
+			aesState = 1
+		}
+	}
+	return aesState > 0
+}
+
+// aeshash returns the hash of the n bytes pointed to by p, by rounds of AES
+// encryption of its 16-byte blocks, keyed by seed.
+func aeshash(p unsafe.Pointer, n, seed uintptr) uintptr {
+	key := [2]uint64{uint64(seed) ^ wyp0, uint64(seed) ^ wyp1}
+	state := [2]uint64{uint64(n) ^ wyp2, uint64(seed) ^ wyp3}
+	sp, kp := unsafe.Pointer(&state), unsafe.Pointer(&key)
+	for ; n >= 16; n -= 16 {
+		aesround(sp, p, kp)
+		p = unsafe.Add(p, 16)
+	}
+	if n > 0 {
+		var tail [2]uint64
+		c.Memcpy(unsafe.Pointer(&tail), p, n)
+		aesround(sp, unsafe.Pointer(&tail), kp)
+	}
+	// more rounds, so that every bit of the last block affects the hash
+	for i := 0; i < 3; i++ {
+		aesround(sp, kp, kp)
+	}
+	return uintptr(state[0] ^ state[1])
+}
+
+// aesround sets the 16 bytes pointed to by state to one round of the AES
+// encryption of their xor with the 16 bytes pointed to by block, which may be
+// unaligned, with the round key pointed to by key.
+func aesround(state, block, key unsafe.Pointer) {
+	asm.Constraint("movdqu (%rdi), %xmm0\n\tmovdqu (%rsi), %xmm1\n\tpxor %xmm1, %xmm0\n\t"+
+		"movdqu (%rdx), %xmm1\n\taesenc %xmm1, %xmm0\n\tmovdqu %xmm0, (%rdi)",
+		"{rdi},{rsi},{rdx},~{xmm0},~{xmm1},~{memory}",
+		uintptr(state), uintptr(block), uintptr(key))
+}
@@ -0,0 +1,361 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// -----------------------------------------------------------------------------
+
+// Map is the runtime representation of a Go map.
+//
+// As with gc, entries are kept in 2^B buckets of bucketCnt slots, which are
+// chained to overflow buckets when they are full, and the bucket of a key is
+// chosen by the low bits of its hash, which is seeded per map. The iteration
+// order is unspecified: an iteration starts at a pseudo-random bucket and
+// slot (see MapIterInit), so that a program doesn't depend on the order in
+// which the entries were added.
+//
+// A map that grows gets a new array of buckets, where its entries are copied,
+// rather than moved: the old array, which the iterations started before the
+// growth still walk, isn't modified anymore. Such iterations look the keys up
+// in the new array, so that they skip the entries deleted since, and return
+// the current elements (see MapIterNext).
+type Map struct {
+	count   int    // number of entries, see MapLen
+	flags   uint32 // hashWriting while the map is written
+	B       uint8  // log2 of the number of buckets
+	seed    uintptr
+	buckets unsafe.Pointer
+	hash    func(key unsafe.Pointer, seed uintptr) uintptr
+	equal   func(x, y unsafe.Pointer) bool
+	keySize uintptr
+	eltSize uintptr
+	elemOff uintptr // offset of the elements in a bucket
+	ovfOff  uintptr // offset of the overflow pointer in a bucket
+}
+
+const (
+	bucketCnt = 8 // slots of a bucket
+
+	// A bucket grows when the average number of entries of the buckets is
+	// greater than loadFactorNum/loadFactorDen, as with gc.
+	loadFactorNum = 13
+	loadFactorDen = 2
+
+	// hashWriting is set in the flags of a map while a goroutine writes to
+	// it, so that a concurrent access can be detected, as with gc. It is a
+	// best-effort check, which doesn't synchronize the accesses.
+	hashWriting = 1
+)
+
+// A bucket is laid out as:
+//
+//	tophash  [bucketCnt]uint8 // top byte of the hashes of the keys, or 0 if the slot is empty
+//	keys     [bucketCnt]K
+//	elems    [bucketCnt]V     // at elemOff
+//	overflow *bucket          // at ovfOff
+const keysOff = bucketCnt
+
+// NewMap creates a map whose keys and elements are keySize and eltSize bytes
+// long, with room for hint entries. hash and equal hash and compare the keys
+// (see Memhash).
+func NewMap(keySize, eltSize uintptr, hash func(key unsafe.Pointer, seed uintptr) uintptr, equal func(x, y unsafe.Pointer) bool, hint int) *Map {
+	m := (*Map)(AllocZ(unsafe.Sizeof(Map{})))
+	m.hash, m.equal = hash, equal
+	m.keySize, m.eltSize = keySize, eltSize
+	m.elemOff = alignUp(keysOff+bucketCnt*keySize, 8)
+	m.ovfOff = alignUp(m.elemOff+bucketCnt*eltSize, unsafe.Sizeof(uintptr(0)))
+	for overLoadFactor(hint, m.B) {
+		m.B++
+	}
+	m.seed = uintptr(fastrandn(grand(), 1<<31))
+	m.buckets = m.newBuckets(m.B)
+	return m
+}
+
+// MapLen returns len(m).
+//
+//llgo:inline
+func MapLen(m *Map) int {
+	if m == nil {
+		return 0
+	}
+	return m.count
+}
+
+// MapAccess copies the element of the key pointed to by key in the map m to
+// elem, if m has such a key, and reports whether it has. The caller zeroes
+// elem beforehand, as m may be nil.
+func MapAccess(m *Map, key, elem unsafe.Pointer) bool {
+	if m == nil || m.count == 0 {
+		return false
+	}
+	if m.flags&hashWriting != 0 {
+		fatal("concurrent map read and map write")
+	}
+	b, i := m.find(key, m.hash(key, m.seed))
+	if b == nil {
+		return false
+	}
+	c.Memcpy(elem, m.elemAt(b, i), m.eltSize)
+	return true
+}
+
+// MapAssign sets the element of the key pointed to by key in the map m to the
+// one pointed to by elem.
+func MapAssign(m *Map, key, elem unsafe.Pointer) {
+	if m == nil {
+		Panic(plainError("assignment to entry in nil map"))
+	}
+	hash := m.hash(key, m.seed) // before the write starts, as it may panic
+	m.startWrite()
+	if b, i := m.find(key, hash); b != nil {
+		// the key is copied too, as keys may be equal and differ, eg. +0 and -0
+		c.Memcpy(m.keyAt(b, i), key, m.keySize)
+		c.Memcpy(m.elemAt(b, i), elem, m.eltSize)
+		m.endWrite()
+		return
+	}
+	if overLoadFactor(m.count+1, m.B) {
+		m.grow()
+	}
+	m.insert(m.buckets, m.B, hash, key, elem)
+	m.count++
+	m.endWrite()
+}
+
+// MapDelete deletes the key pointed to by key from the map m, if it is there.
+func MapDelete(m *Map, key unsafe.Pointer) {
+	if m == nil || m.count == 0 {
+		return
+	}
+	hash := m.hash(key, m.seed)
+	m.startWrite()
+	if b, i := m.find(key, hash); b != nil {
+		*(*uint8)(unsafe.Add(b, i)) = 0
+		c.Memset(m.keyAt(b, i), 0, m.keySize)
+		c.Memset(m.elemAt(b, i), 0, m.eltSize)
+		if m.count--; m.count == 0 {
+			// reseed, so that an attacker can't grow the chains of a map
+			// that is repeatedly emptied, as with gc
+			m.seed = uintptr(fastrandn(grand(), 1<<31))
+		}
+	}
+	m.endWrite()
+}
+
+// MapClear deletes all the entries of the map m.
+func MapClear(m *Map) {
+	if m == nil || m.count == 0 {
+		return
+	}
+	m.startWrite()
+	// a new array, so that the iterations in progress skip all the entries
+	m.buckets = m.newBuckets(m.B)
+	m.count = 0
+	m.seed = uintptr(fastrandn(grand(), 1<<31))
+	m.endWrite()
+}
+
+func (m *Map) startWrite() {
+	if m.flags&hashWriting != 0 {
+		fatal("concurrent map writes")
+	}
+	m.flags ^= hashWriting
+}
+
+func (m *Map) endWrite() {
+	if m.flags&hashWriting == 0 {
+		fatal("concurrent map writes")
+	}
+	m.flags &^= hashWriting
+}
+
+// overLoadFactor reports whether count entries need more than 2^B buckets.
+func overLoadFactor(count int, B uint8) bool {
+	return count > bucketCnt && uintptr(count) > loadFactorNum*(uintptr(1)<<B)/loadFactorDen
+}
+
+func (m *Map) newBuckets(B uint8) unsafe.Pointer {
+	return AllocZ(m.bucketSize() << B)
+}
+
+func (m *Map) bucketSize() uintptr {
+	return m.ovfOff + unsafe.Sizeof(uintptr(0))
+}
+
+func (m *Map) bucketAt(buckets unsafe.Pointer, idx uintptr) unsafe.Pointer {
+	return unsafe.Add(buckets, idx*m.bucketSize())
+}
+
+func (m *Map) keyAt(b unsafe.Pointer, i int) unsafe.Pointer {
+	return unsafe.Add(b, keysOff+uintptr(i)*m.keySize)
+}
+
+func (m *Map) elemAt(b unsafe.Pointer, i int) unsafe.Pointer {
+	return unsafe.Add(b, m.elemOff+uintptr(i)*m.eltSize)
+}
+
+func (m *Map) overflow(b unsafe.Pointer) *unsafe.Pointer {
+	return (*unsafe.Pointer)(unsafe.Add(b, m.ovfOff))
+}
+
+func alignUp(n, align uintptr) uintptr {
+	return (n + align - 1) &^ (align - 1)
+}
+
+// tophash returns the top byte of hash, which isn't 0, as 0 marks the empty
+// slots.
+func tophash(hash uintptr) uint8 {
+	top := uint8(hash >> (unsafe.Sizeof(hash)*8 - 8))
+	if top == 0 {
+		top = 1
+	}
+	return top
+}
+
+// find returns the bucket and the slot of the key pointed to by key, whose
+// hash is hash, in the map m, or a nil bucket if m has no such key.
+func (m *Map) find(key unsafe.Pointer, hash uintptr) (unsafe.Pointer, int) {
+	top := tophash(hash)
+	b := m.bucketAt(m.buckets, hash&(uintptr(1)<<m.B-1))
+	for ; b != nil; b = *m.overflow(b) {
+		for i := 0; i < bucketCnt; i++ {
+			if *(*uint8)(unsafe.Add(b, i)) == top && m.equal(key, m.keyAt(b, i)) {
+				return b, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// insert puts a new entry in the first empty slot of the chain of the bucket
+// of hash in buckets, of which there are 2^B.
+func (m *Map) insert(buckets unsafe.Pointer, B uint8, hash uintptr, key, elem unsafe.Pointer) {
+	b := m.bucketAt(buckets, hash&(uintptr(1)<<B-1))
+	for {
+		for i := 0; i < bucketCnt; i++ {
+			if top := (*uint8)(unsafe.Add(b, i)); *top == 0 {
+				*top = tophash(hash)
+				c.Memcpy(m.keyAt(b, i), key, m.keySize)
+				c.Memcpy(m.elemAt(b, i), elem, m.eltSize)
+				return
+			}
+		}
+		ovf := m.overflow(b)
+		if *ovf == nil {
+			*ovf = AllocZ(m.bucketSize())
+		}
+		b = *ovf
+	}
+}
+
+// grow doubles the number of buckets of the map m, copying its entries to a
+// new array and leaving the old one to the iterations that walk it.
+func (m *Map) grow() {
+	B := m.B + 1
+	buckets := m.newBuckets(B)
+	for idx := uintptr(0); idx < uintptr(1)<<m.B; idx++ {
+		for b := m.bucketAt(m.buckets, idx); b != nil; b = *m.overflow(b) {
+			for i := 0; i < bucketCnt; i++ {
+				if *(*uint8)(unsafe.Add(b, i)) != 0 {
+					k := m.keyAt(b, i)
+					m.insert(buckets, B, m.hash(k, m.seed), k, m.elemAt(b, i))
+				}
+			}
+		}
+	}
+	m.buckets, m.B = buckets, B
+}
+
+// -----------------------------------------------------------------------------
+
+// MapIter is the state of a range loop over a map.
+type MapIter struct {
+	m       *Map
+	buckets unsafe.Pointer // buckets of m when the iteration started
+	B       uint8
+	offset  uint8   // first slot visited in each bucket
+	start   uintptr // first bucket visited
+	visited uintptr // number of buckets visited
+	b       unsafe.Pointer
+	i       int // number of slots of b visited
+}
+
+// MapIterInit starts an iteration over the map m, from a pseudo-random bucket
+// and slot.
+func MapIterInit(m *Map, it *MapIter) {
+	*it = MapIter{m: m}
+	if m == nil || m.count == 0 {
+		return
+	}
+	it.buckets, it.B = m.buckets, m.B
+	r := grand()
+	it.start = uintptr(fastrandn(r, 1<<31)) & (uintptr(1)<<m.B - 1)
+	it.offset = uint8(fastrandn(r, bucketCnt))
+}
+
+// MapIterNext copies the key and the element of the next entry of the
+// iteration it to key and elem, if any. It reports whether there was one.
+//
+// The entries deleted before they are reached aren't returned. The entries
+// added during the iteration may be returned or not.
+func MapIterNext(it *MapIter, key, elem unsafe.Pointer) bool {
+	m := it.m
+	if it.buckets == nil {
+		return false
+	}
+	if m.flags&hashWriting != 0 {
+		fatal("concurrent map iteration and map write")
+	}
+	for {
+		if it.b == nil {
+			if it.visited == uintptr(1)<<it.B {
+				return false
+			}
+			it.b = m.bucketAt(it.buckets, (it.start+it.visited)&(uintptr(1)<<it.B-1))
+			it.visited++
+			it.i = 0
+		}
+		for it.i < bucketCnt {
+			i := int((uint8(it.i) + it.offset) & (bucketCnt - 1))
+			it.i++
+			if *(*uint8)(unsafe.Add(it.b, i)) == 0 {
+				continue
+			}
+			k, e := m.keyAt(it.b, i), m.elemAt(it.b, i)
+			if it.buckets != m.buckets && m.equal(k, k) {
+				// the map grew or was cleared: the entry may have been
+				// deleted or updated since
+				b, j := m.find(k, m.hash(k, m.seed))
+				if b == nil {
+					continue
+				}
+				k, e = m.keyAt(b, j), m.elemAt(b, j)
+			}
+			c.Memcpy(key, k, m.keySize)
+			c.Memcpy(elem, e, m.eltSize)
+			return true
+		}
+		it.b = *m.overflow(it.b)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,470 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The net package isn't compiled: the compiler turns calls to net.Dial,
+// net.Listen, the Read, Write and Close methods of net.TCPConn (and other
+// connections, which embed the same type), and the Accept, AcceptTCP and Close
+// methods of net.TCPListener into calls to the functions below (see
+// rtIntrinsics in cl). Only TCP is supported, and addresses are resolved by
+// getaddrinfo, which blocks.
+//
+// Sockets are non-blocking, and registered to the network poller, epoll or
+// kqueue, in edge-triggered mode. A goroutine whose I/O would block waits in a
+// waitq of the pollDesc of its socket until the poller, polled by idle Ms and
+// sysmon, reports that the socket is ready.
+
+// pollDesc is the poll state of a socket.
+type pollDesc struct {
+	fd      int
+	lock    c.PthreadMutex
+	rq      waitq // goroutines waiting to read
+	wq      waitq // goroutines waiting to write
+	rready  bool  // readiness reported but not consumed yet
+	wready  bool
+	closing bool
+}
+
+// netpollEvent is a readiness event that the poller reports.
+type netpollEvent struct {
+	fd          int
+	read, write bool
+}
+
+const netpollBatch = 128
+
+var netpolls struct {
+	lock c.PthreadMutex
+	fd   c.Int       // epoll or kqueue
+	pds  []*pollDesc // indexed by socket
+}
+
+func netpollinit() {
+	c.PthreadMutexInit(&netpolls.lock, nil)
+	if netpolls.fd = netpollcreate(); netpolls.fd < 0 {
+		fatal("can't create the network poller")
+	}
+	netpoll = netpollReady
+}
+
+// netpollOpen registers the socket fd to the poller.
+func netpollOpen(fd int) (*pollDesc, c.Int) {
+	pd := (*pollDesc)(AllocZ(unsafe.Sizeof(pollDesc{})))
+	pd.fd = fd
+	c.PthreadMutexInit(&pd.lock, nil)
+	c.PthreadMutexLock(&netpolls.lock)
+	for len(netpolls.pds) <= fd {
+		netpolls.pds = append(netpolls.pds, nil)
+	}
+	netpolls.pds[fd] = pd
+	c.PthreadMutexUnlock(&netpolls.lock)
+	if netpollctl(netpolls.fd, fd) < 0 {
+		errno := c.Errno()
+		netpollClose(pd)
+		return nil, errno
+	}
+	return pd, 0
+}
+
+// netpollClose unregisters pd from the poller, wakes the goroutines waiting
+// for it, and closes its socket.
+func netpollClose(pd *pollDesc) c.Int {
+	c.PthreadMutexLock(&netpolls.lock)
+	netpolls.pds[pd.fd] = nil
+	c.PthreadMutexUnlock(&netpolls.lock)
+	c.PthreadMutexLock(&pd.lock)
+	pd.closing = true
+	pd.rq.wakeAll()
+	pd.wq.wakeAll()
+	c.PthreadMutexUnlock(&pd.lock)
+	if c.Close(c.Int(pd.fd)) < 0 {
+		return c.Errno()
+	}
+	return 0
+}
+
+// netpollReady implements netpoll.
+func netpollReady() *g {
+	if atomic.LoadInt32(&netpollWaiters) == 0 {
+		return nil
+	}
+	var evs [netpollBatch]netpollEvent
+	n := netpollwait(netpolls.fd, &evs)
+	var list *g
+	for i := 0; i < n; i++ {
+		ev := &evs[i]
+		c.PthreadMutexLock(&netpolls.lock)
+		var pd *pollDesc
+		if ev.fd < len(netpolls.pds) {
+			pd = netpolls.pds[ev.fd]
+		}
+		c.PthreadMutexUnlock(&netpolls.lock)
+		if pd == nil {
+			continue
+		}
+		c.PthreadMutexLock(&pd.lock)
+		if ev.read {
+			pd.rready = true
+			list = netpollTake(&pd.rq, list)
+		}
+		if ev.write {
+			pd.wready = true
+			list = netpollTake(&pd.wq, list)
+		}
+		c.PthreadMutexUnlock(&pd.lock)
+	}
+	return list
+}
+
+// netpollTake adds the goroutines waiting in q to list, but the ones of
+// threadParks, eg. the main goroutine, which can't be run by an M and are made
+// runnable instead.
+func netpollTake(q *waitq, list *g) *g {
+	for gp := q.q.pop(); gp != nil; gp = q.q.pop() {
+		if gp.park != nil {
+			ready(gp)
+		} else {
+			gp.link = list
+			list = gp
+		}
+	}
+	return list
+}
+
+// wait waits for the socket of pd to be ready to write, or to read. It fails
+// if pd is closed.
+func (pd *pollDesc) wait(write bool) bool {
+	q, ready := &pd.rq, &pd.rready
+	if write {
+		q, ready = &pd.wq, &pd.wready
+	}
+	c.PthreadMutexLock(&pd.lock)
+	for !*ready && !pd.closing {
+		atomic.AddInt32(&netpollWaiters, 1)
+		q.wait(&pd.lock)
+		atomic.AddInt32(&netpollWaiters, -1)
+	}
+	*ready = false
+	ok := !pd.closing
+	c.PthreadMutexUnlock(&pd.lock)
+	return ok
+}
+
+// -----------------------------------------------------------------------------
+
+// Conn is the runtime counterpart of net.Conn.
+type Conn interface {
+	Read(b []byte) (int, error)
+	Write(b []byte) (int, error)
+	Close() error
+}
+
+// Listener is the runtime counterpart of net.Listener.
+type Listener interface {
+	Accept() (Conn, error)
+	Close() error
+}
+
+// TCPConn is the runtime representation of net.TCPConn.
+type TCPConn struct {
+	pd *pollDesc
+}
+
+func (tc *TCPConn) Read(b []byte) (int, error)  { return NetConnRead(tc, b) }
+func (tc *TCPConn) Write(b []byte) (int, error) { return NetConnWrite(tc, b) }
+func (tc *TCPConn) Close() error                { return NetConnClose(tc) }
+
+// TCPListener is the runtime representation of net.TCPListener.
+type TCPListener struct {
+	pd *pollDesc
+}
+
+func (l *TCPListener) Accept() (Conn, error) { return TCPListenerAccept(l) }
+func (l *TCPListener) Close() error          { return TCPListenerClose(l) }
+
+// OpError is the runtime counterpart of net.OpError.
+type OpError struct {
+	Op   string
+	Net  string
+	Addr string
+	Err  error
+}
+
+func (e *OpError) Error() string {
+	if e.Addr == "" {
+		return concat(e.Op, " ", e.Net, ": ", errorText(e.Err))
+	}
+	return concat(e.Op, " ", e.Net, " ", e.Addr, ": ", errorText(e.Err))
+}
+
+type gaiError c.Int
+
+func (e gaiError) Error() string {
+	return gostring(c.GaiStrerror(c.Int(e)))
+}
+
+type errorString string
+
+func (e errorString) Error() string {
+	return string(e)
+}
+
+func newOpError(op, network, addr string, err error) error {
+	e := (*OpError)(AllocZ(unsafe.Sizeof(OpError{})))
+	e.Op = op
+	e.Net = network
+	e.Addr = addr
+	e.Err = err
+	return e
+}
+
+func newTCPConn(pd *pollDesc) *TCPConn {
+	tc := (*TCPConn)(AllocZ(unsafe.Sizeof(TCPConn{})))
+	tc.pd = pd
+	return tc
+}
+
+// NetDial implements net.Dial.
+func NetDial(network, address string) (Conn, error) {
+	if !schedInited() {
+		schedinit()
+	}
+	res, err := resolve(network, address, 0)
+	if err != nil {
+		return nil, newOpError("dial", network, address, err)
+	}
+	var pd *pollDesc
+	var errno c.Int
+	for ai := res; ai != nil; ai = ai.Next {
+		if pd, errno = socket(ai); errno != 0 {
+			continue
+		}
+		if errno = connect(pd, ai); errno == 0 {
+			break
+		}
+		netpollClose(pd)
+	}
+	c.Freeaddrinfo(res)
+	if errno != 0 {
+		return nil, newOpError("dial", network, address, Errno(errno))
+	}
+	return newTCPConn(pd), nil
+}
+
+// NetListen implements net.Listen.
+func NetListen(network, address string) (Listener, error) {
+	if !schedInited() {
+		schedinit()
+	}
+	res, err := resolve(network, address, c.AiPassive)
+	if err != nil {
+		return nil, newOpError("listen", network, address, err)
+	}
+	pd, errno := socket(res)
+	if errno == 0 {
+		one := c.Int(1)
+		c.Setsockopt(c.Int(pd.fd), c.SolSocket, c.SoReuseaddr, c.Pointer(&one), c.Uint(unsafe.Sizeof(one)))
+		if c.Bind(c.Int(pd.fd), res.Addr, res.Addrlen) < 0 || c.Listen(c.Int(pd.fd), c.Somaxconn) < 0 {
+			errno = c.Errno()
+			netpollClose(pd)
+		}
+	}
+	c.Freeaddrinfo(res)
+	if errno != 0 {
+		return nil, newOpError("listen", network, address, Errno(errno))
+	}
+	l := (*TCPListener)(AllocZ(unsafe.Sizeof(TCPListener{})))
+	l.pd = pd
+	return l, nil
+}
+
+// NetConnRead implements net.(*TCPConn).Read.
+func NetConnRead(tc *TCPConn, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for {
+		n := c.Read(c.Int(tc.pd.fd), sliceData(b), uintptr(len(b)))
+		if n > 0 {
+			return int(n), nil
+		}
+		if n == 0 {
+			return 0, eofError{}
+		}
+		errno := c.Errno()
+		if errno == c.EAGAIN && tc.pd.wait(false) || errno == c.EINTR {
+			continue
+		}
+		if errno == c.EAGAIN {
+			errno = c.EBADF
+		}
+		return 0, newOpError("read", "tcp", "", Errno(errno))
+	}
+}
+
+// NetConnWrite implements net.(*TCPConn).Write.
+func NetConnWrite(tc *TCPConn, b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		n := c.Write(c.Int(tc.pd.fd), sliceData(b[written:]), uintptr(len(b)-written))
+		if n >= 0 {
+			written += int(n)
+			continue
+		}
+		errno := c.Errno()
+		if errno == c.EAGAIN && tc.pd.wait(true) || errno == c.EINTR {
+			continue
+		}
+		if errno == c.EAGAIN {
+			errno = c.EBADF
+		}
+		return written, newOpError("write", "tcp", "", Errno(errno))
+	}
+	return written, nil
+}
+
+// NetConnClose implements net.(*TCPConn).Close.
+func NetConnClose(tc *TCPConn) error {
+	if errno := netpollClose(tc.pd); errno != 0 {
+		return newOpError("close", "tcp", "", Errno(errno))
+	}
+	return nil
+}
+
+// TCPListenerAccept implements net.(*TCPListener).Accept.
+func TCPListenerAccept(l *TCPListener) (Conn, error) {
+	tc, err := TCPListenerAcceptTCP(l)
+	if err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+// TCPListenerAcceptTCP implements net.(*TCPListener).AcceptTCP.
+func TCPListenerAcceptTCP(l *TCPListener) (*TCPConn, error) {
+	for {
+		fd := c.Accept(c.Int(l.pd.fd), nil, nil)
+		if fd >= 0 {
+			pd, errno := newPollDesc(fd)
+			if errno != 0 {
+				return nil, newOpError("accept", "tcp", "", Errno(errno))
+			}
+			return newTCPConn(pd), nil
+		}
+		errno := c.Errno()
+		if errno == c.EAGAIN && l.pd.wait(false) || errno == c.EINTR {
+			continue
+		}
+		if errno == c.EAGAIN {
+			errno = c.EBADF
+		}
+		return nil, newOpError("accept", "tcp", "", Errno(errno))
+	}
+}
+
+// TCPListenerClose implements net.(*TCPListener).Close.
+func TCPListenerClose(l *TCPListener) error {
+	if errno := netpollClose(l.pd); errno != 0 {
+		return newOpError("close", "tcp", "", Errno(errno))
+	}
+	return nil
+}
+
+// resolve resolves the address "host:port" of network by getaddrinfo.
+func resolve(network, address string, flags c.Int) (*c.Addrinfo, error) {
+	var hints c.Addrinfo
+	switch network {
+	case "tcp":
+		hints.Family = c.AfUnspec
+	case "tcp4":
+		hints.Family = c.AfInet
+	case "tcp6":
+		hints.Family = c.AfInet6
+	default:
+		return nil, errorString(concat("unknown network ", network))
+	}
+	hints.Socktype = c.SockStream
+	hints.Flags = flags
+	i := len(address) - 1
+	for i >= 0 && address[i] != ':' {
+		i--
+	}
+	if i < 0 {
+		return nil, errorString("missing port in address")
+	}
+	host, port := address[:i], address[i+1:]
+	if len(host) > 1 && host[0] == '[' && host[len(host)-1] == ']' {
+		host = host[1 : len(host)-1]
+	}
+	var node *c.Char
+	if host != "" {
+		node = cstring(host)
+	}
+	var res *c.Addrinfo
+	if ret := c.Getaddrinfo(node, cstring(port), &hints, &res); ret != 0 {
+		return nil, gaiError(ret)
+	}
+	return res, nil
+}
+
+// socket creates a non-blocking socket for ai and registers it to the poller.
+func socket(ai *c.Addrinfo) (*pollDesc, c.Int) {
+	fd := c.Socket(ai.Family, ai.Socktype, ai.Protocol)
+	if fd < 0 {
+		return nil, c.Errno()
+	}
+	return newPollDesc(fd)
+}
+
+// newPollDesc makes the socket fd non-blocking and registers it to the poller.
+func newPollDesc(fd c.Int) (*pollDesc, c.Int) {
+	if c.Fcntl(fd, c.FSetfl, c.Fcntl(fd, c.FGetfl)|c.ONonblock) < 0 {
+		errno := c.Errno()
+		c.Close(fd)
+		return nil, errno
+	}
+	return netpollOpen(int(fd))
+}
+
+// connect connects the socket of pd to the address of ai, waiting for the
+// connection to be established.
+func connect(pd *pollDesc, ai *c.Addrinfo) c.Int {
+	if c.Connect(c.Int(pd.fd), ai.Addr, ai.Addrlen) == 0 {
+		return 0
+	}
+	if errno := c.Errno(); errno != c.EINPROGRESS && errno != c.EINTR {
+		return errno
+	}
+	if !pd.wait(true) {
+		return c.EBADF
+	}
+	var errno c.Int
+	n := c.Uint(unsafe.Sizeof(errno))
+	if c.Getsockopt(c.Int(pd.fd), c.SolSocket, c.SoError, c.Pointer(&errno), &n) < 0 {
+		return c.Errno()
+	}
+	return errno
+}
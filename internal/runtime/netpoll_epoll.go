@@ -0,0 +1,50 @@
+//go:build linux && !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package runtime
+
+import "github.com/goplus/llgo/internal/runtime/c"
+
+func netpollcreate() c.Int {
+	return c.EpollCreate1(c.EpollCloexec)
+}
+
+// netpollctl registers fd to the epoll kq, for both reading and writing.
+func netpollctl(kq c.Int, fd int) c.Int {
+	var ev c.EpollEvent
+	ev.Events = c.EpollIn | c.EpollOut | c.EpollRdhup | c.EpollEt
+	ev.Fd = int32(fd)
+	return c.EpollCtl(kq, c.EpollCtlAdd, c.Int(fd), &ev)
+}
+
+// netpollwait returns the events of the epoll kq, without blocking.
+func netpollwait(kq c.Int, evs *[netpollBatch]netpollEvent) int {
+	var buf [netpollBatch]c.EpollEvent
+	n := int(c.EpollWait(kq, &buf[0], netpollBatch, 0))
+	for i := 0; i < n; i++ {
+		e := buf[i].Events
+		evs[i] = netpollEvent{
+			fd:    int(buf[i].Fd),
+			read:  e&(c.EpollIn|c.EpollErr|c.EpollHup|c.EpollRdhup) != 0,
+			write: e&(c.EpollOut|c.EpollErr|c.EpollHup) != 0,
+		}
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
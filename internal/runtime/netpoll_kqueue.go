@@ -0,0 +1,51 @@
+//go:build darwin
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package runtime
+
+import "github.com/goplus/llgo/internal/runtime/c"
+
+func netpollcreate() c.Int {
+	return c.Kqueue()
+}
+
+// netpollctl registers fd to the kqueue kq, for both reading and writing.
+func netpollctl(kq c.Int, fd int) c.Int {
+	var changes [2]c.KeventT
+	changes[0] = c.KeventT{Ident: uintptr(fd), Filter: c.EvfiltRead, Flags: c.EvAdd | c.EvClear}
+	changes[1] = c.KeventT{Ident: uintptr(fd), Filter: c.EvfiltWrite, Flags: c.EvAdd | c.EvClear}
+	return c.Kevent(kq, &changes[0], 2, nil, 0, nil)
+}
+
+// netpollwait returns the events of the kqueue kq, without blocking.
+func netpollwait(kq c.Int, evs *[netpollBatch]netpollEvent) int {
+	var buf [netpollBatch]c.KeventT
+	var ts c.Timespec
+	n := int(c.Kevent(kq, nil, 0, &buf[0], netpollBatch, &ts))
+	for i := 0; i < n; i++ {
+		ev := &buf[i]
+		evs[i] = netpollEvent{
+			fd:    int(ev.Ident),
+			read:  ev.Filter == c.EvfiltRead || ev.Flags&c.EvError != 0,
+			write: ev.Filter == c.EvfiltWrite || ev.Flags&c.EvError != 0,
+		}
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
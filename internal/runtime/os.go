@@ -0,0 +1,315 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The os and syscall packages aren't compiled: the compiler turns calls to
+// os.Open, os.Create, os.OpenFile, os.Exit, the Read, Write, WriteString and
+// Close methods of os.File, and to syscall.Open, Read, Write, Close, Exit,
+// Syscall and Syscall6 into calls to the functions below (see rtIntrinsics in
+// cl), and os.Stdin, os.Stdout and os.Stderr into the variables below, which
+// OsInit, called instead of the initialization of os, sets, as it sets
+// os.Interrupt and os.Kill (see SignalNotify).
+//
+// Files are file descriptors of libc. The flags and permissions that programs
+// pass are the ones of the syscall package for the target, which are the ones
+// of libc. As the io package isn't available to the runtime, the end of a file
+// is reported by an error whose message is EOF, but which isn't io.EOF.
+
+// Errno is the runtime representation of syscall.Errno.
+type Errno uintptr
+
+func (e Errno) Error() string {
+	return gostring(c.Strerror(c.Int(e)))
+}
+
+// PathError is the runtime representation of os.PathError.
+type PathError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return concat(e.Op, " ", e.Path, ": ", errorText(e.Err))
+}
+
+// errorText returns err.Error() of an error of the runtime, by a type switch,
+// as llgo doesn't compile the calls of the methods of interfaces.
+func errorText(err error) string {
+	switch e := err.(type) {
+	case Errno:
+		return e.Error()
+	case errorString:
+		return e.Error()
+	case eofError:
+		return e.Error()
+	case gaiError:
+		return e.Error()
+	case *PathError:
+		return e.Error()
+	case *OpError:
+		return e.Error()
+	case runtimeError:
+		return e.Error()
+	case plainError:
+		return e.Error()
+	case boundsError:
+		return e.Error()
+	}
+	return "unknown error"
+}
+
+type eofError struct{}
+
+func (eofError) Error() string {
+	return "EOF"
+}
+
+// File is the runtime representation of os.File.
+type File struct {
+	fd   int // -1 once closed
+	name string
+}
+
+// Stdin, Stdout and Stderr implement os.Stdin, os.Stdout and os.Stderr.
+var Stdin, Stdout, Stderr *File
+
+// OsInit initializes the os package. It may be called more than once.
+func OsInit() {
+	if Stdin == nil {
+		Stdin = newFile(0, "/dev/stdin")
+		Stdout = newFile(1, "/dev/stdout")
+		Stderr = newFile(2, "/dev/stderr")
+		Interrupt = Signal(c.SIGINT)
+		Kill = Signal(c.SIGKILL)
+	}
+}
+
+func newFile(fd int, name string) *File {
+	f := (*File)(AllocZ(unsafe.Sizeof(File{})))
+	f.fd = fd
+	f.name = name
+	return f
+}
+
+func newPathError(op, path string, errno c.Int) error {
+	e := (*PathError)(AllocZ(unsafe.Sizeof(PathError{})))
+	e.Op = op
+	e.Path = path
+	e.Err = Errno(errno)
+	return e
+}
+
+// OsOpen implements os.Open.
+func OsOpen(name string) (*File, error) {
+	return OsOpenFile(name, c.ORdonly, 0)
+}
+
+// OsCreate implements os.Create.
+func OsCreate(name string) (*File, error) {
+	return OsOpenFile(name, c.ORdwr|c.OCreat|c.OTrunc, 0666)
+}
+
+// OsOpenFile implements os.OpenFile.
+func OsOpenFile(name string, flag int, perm uint32) (*File, error) {
+	fd, errno := open(name, flag, perm)
+	if errno != 0 {
+		return nil, newPathError("open", name, errno)
+	}
+	return newFile(fd, name), nil
+}
+
+// OsExit implements os.Exit.
+func OsExit(code int) {
+	c.Exit(c.Int(code))
+}
+
+// FileRead implements os.(*File).Read.
+func FileRead(f *File, b []byte) (int, error) {
+	if f.fd < 0 {
+		return 0, newPathError("read", f.name, c.EBADF)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, errno := read(f.fd, b)
+	if errno != 0 {
+		return 0, newPathError("read", f.name, errno)
+	}
+	if n == 0 {
+		return 0, eofError{}
+	}
+	return n, nil
+}
+
+// FileWrite implements os.(*File).Write.
+func FileWrite(f *File, b []byte) (int, error) {
+	if f.fd < 0 {
+		return 0, newPathError("write", f.name, c.EBADF)
+	}
+	n, errno := write(f.fd, b)
+	if errno != 0 {
+		return n, newPathError("write", f.name, errno)
+	}
+	return n, nil
+}
+
+// FileWriteString implements os.(*File).WriteString.
+func FileWriteString(f *File, s string) (int, error) {
+	h := (*stringHeader)(unsafe.Pointer(&s))
+	return FileWrite(f, unsafe.Slice((*byte)(h.data), h.len))
+}
+
+// FileClose implements os.(*File).Close.
+func FileClose(f *File) error {
+	if f.fd < 0 {
+		return newPathError("close", f.name, c.EBADF)
+	}
+	ret := c.Close(c.Int(f.fd))
+	f.fd = -1
+	if ret < 0 {
+		return newPathError("close", f.name, c.Errno())
+	}
+	return nil
+}
+
+// SyscallOpen implements syscall.Open.
+func SyscallOpen(path string, mode int, perm uint32) (int, error) {
+	fd, errno := open(path, mode, perm)
+	if errno != 0 {
+		return -1, Errno(errno)
+	}
+	return fd, nil
+}
+
+// SyscallRead implements syscall.Read.
+func SyscallRead(fd int, p []byte) (int, error) {
+	n, errno := read(fd, p)
+	if errno != 0 {
+		return -1, Errno(errno)
+	}
+	return n, nil
+}
+
+// SyscallWrite implements syscall.Write.
+func SyscallWrite(fd int, p []byte) (int, error) {
+	n, errno := write(fd, p)
+	if errno != 0 {
+		return -1, Errno(errno)
+	}
+	return n, nil
+}
+
+// SyscallClose implements syscall.Close.
+func SyscallClose(fd int) error {
+	if c.Close(c.Int(fd)) < 0 {
+		return Errno(c.Errno())
+	}
+	return nil
+}
+
+// SyscallExit implements syscall.Exit.
+func SyscallExit(code int) {
+	c.Exit(c.Int(code))
+}
+
+// SyscallSyscall implements syscall.Syscall by the syscall function of libc.
+func SyscallSyscall(trap, a1, a2, a3 uintptr) (r1, r2 uintptr, err Errno) {
+	return SyscallSyscall6(trap, a1, a2, a3, 0, 0, 0)
+}
+
+// SyscallSyscall6 implements syscall.Syscall6 by the syscall function of libc.
+func SyscallSyscall6(trap, a1, a2, a3, a4, a5, a6 uintptr) (r1, r2 uintptr, err Errno) {
+	ret := c.Syscall(c.Long(trap), a1, a2, a3, a4, a5, a6)
+	if ret == -1 {
+		return uintptr(ret), 0, Errno(c.Errno())
+	}
+	return uintptr(ret), 0, 0
+}
+
+// open opens the file name, retrying if it is interrupted by a signal.
+func open(name string, flag int, perm uint32) (int, c.Int) {
+	path := cstring(name)
+	for {
+		fd := c.Open(path, c.Int(flag), c.Uint(perm))
+		if fd >= 0 {
+			return int(fd), 0
+		}
+		if errno := c.Errno(); errno != c.EINTR {
+			return -1, errno
+		}
+	}
+}
+
+// read reads up to len(b) bytes from fd, retrying if it is interrupted by a
+// signal.
+func read(fd int, b []byte) (int, c.Int) {
+	for {
+		n := c.Read(c.Int(fd), sliceData(b), uintptr(len(b)))
+		if n >= 0 {
+			return int(n), 0
+		}
+		if errno := c.Errno(); errno != c.EINTR {
+			return 0, errno
+		}
+	}
+}
+
+// write writes all of b to fd, unless an error occurs. It returns the number
+// of bytes written.
+func write(fd int, b []byte) (int, c.Int) {
+	written := 0
+	for written < len(b) {
+		n := c.Write(c.Int(fd), sliceData(b[written:]), uintptr(len(b)-written))
+		if n < 0 {
+			if errno := c.Errno(); errno != c.EINTR {
+				return written, errno
+			}
+			continue
+		}
+		written += int(n)
+	}
+	return written, 0
+}
+
+// cstring returns a copy of s terminated by a NUL, for libc.
+func cstring(s string) *c.Char {
+	buf := AllocZ(uintptr(len(s) + 1))
+	c.Memcpy(buf, (*stringHeader)(unsafe.Pointer(&s)).data, uintptr(len(s)))
+	return (*c.Char)(buf)
+}
+
+// printString writes s to stderr. It doesn't allocate, so it can be called
+// by signal handlers.
+func printString(s string) {
+	c.Write(2, (*stringHeader)(unsafe.Pointer(&s)).data, uintptr(len(s)))
+}
+
+// fmtWrite writes b to the standard output by write(2), as os.Stdout does,
+// so that the output of the formatter isn't reordered with the one of
+// os.Stdout by the buffer of stdio.
+func fmtWrite(b []byte) {
+	write(1, b)
+}
@@ -0,0 +1,144 @@
+//go:build wasip1 || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "github.com/goplus/llgo/internal/runtime/c"
+
+// Files aren't supported on wasip1 and baremetal targets, so the functions of
+// the os and syscall packages that the runtime implements, except the ones
+// that exit, aren't supported.
+
+// Errno is the runtime representation of syscall.Errno.
+type Errno uintptr
+
+// File is the runtime representation of os.File.
+type File struct {
+	fd   int
+	name string
+}
+
+// Stdin, Stdout and Stderr implement os.Stdin, os.Stdout and os.Stderr.
+var Stdin, Stdout, Stderr *File
+
+// OsInit initializes the os package.
+func OsInit() {}
+
+// OsOpen implements os.Open.
+func OsOpen(name string) (*File, error) {
+	fatal("os is not supported on this target")
+	return nil, nil
+}
+
+// OsCreate implements os.Create.
+func OsCreate(name string) (*File, error) {
+	fatal("os is not supported on this target")
+	return nil, nil
+}
+
+// OsOpenFile implements os.OpenFile.
+func OsOpenFile(name string, flag int, perm uint32) (*File, error) {
+	fatal("os is not supported on this target")
+	return nil, nil
+}
+
+// OsExit implements os.Exit.
+func OsExit(code int) {
+	c.Exit(c.Int(code))
+}
+
+// FileRead implements os.(*File).Read.
+func FileRead(f *File, b []byte) (int, error) {
+	fatal("os is not supported on this target")
+	return 0, nil
+}
+
+// FileWrite implements os.(*File).Write.
+func FileWrite(f *File, b []byte) (int, error) {
+	fatal("os is not supported on this target")
+	return 0, nil
+}
+
+// FileWriteString implements os.(*File).WriteString.
+func FileWriteString(f *File, s string) (int, error) {
+	fatal("os is not supported on this target")
+	return 0, nil
+}
+
+// FileClose implements os.(*File).Close.
+func FileClose(f *File) error {
+	fatal("os is not supported on this target")
+	return nil
+}
+
+// SyscallOpen implements syscall.Open.
+func SyscallOpen(path string, mode int, perm uint32) (int, error) {
+	fatal("syscall is not supported on this target")
+	return 0, nil
+}
+
+// SyscallRead implements syscall.Read.
+func SyscallRead(fd int, p []byte) (int, error) {
+	fatal("syscall is not supported on this target")
+	return 0, nil
+}
+
+// SyscallWrite implements syscall.Write.
+func SyscallWrite(fd int, p []byte) (int, error) {
+	fatal("syscall is not supported on this target")
+	return 0, nil
+}
+
+// SyscallClose implements syscall.Close.
+func SyscallClose(fd int) error {
+	fatal("syscall is not supported on this target")
+	return nil
+}
+
+// SyscallExit implements syscall.Exit.
+func SyscallExit(code int) {
+	c.Exit(c.Int(code))
+}
+
+// SyscallSyscall implements syscall.Syscall.
+func SyscallSyscall(trap, a1, a2, a3 uintptr) (r1, r2 uintptr, err Errno) {
+	fatal("syscall is not supported on this target")
+	return 0, 0, 0
+}
+
+// SyscallSyscall6 implements syscall.Syscall6.
+func SyscallSyscall6(trap, a1, a2, a3, a4, a5, a6 uintptr) (r1, r2 uintptr, err Errno) {
+	fatal("syscall is not supported on this target")
+	return 0, 0, 0
+}
+
+var fmtWriteFormat = [...]c.Char{'%', '.', '*', 's', 0}
+
+// printString writes s to the standard output, as stderr isn't supported.
+func printString(s string) {
+	if len(s) > 0 {
+		c.Printf(&fmtWriteFormat[0], c.Int(len(s)), stringData(s))
+	}
+}
+
+// fmtWrite writes b to the standard output, as the formatter does.
+func fmtWrite(b []byte) {
+	if len(b) > 0 {
+		c.Printf(&fmtWriteFormat[0], c.Int(len(b)), sliceData(b))
+	}
+}
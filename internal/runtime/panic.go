@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// printPanicValue prints the value v of a panic, as the runtime of Go does:
+// the values of basic types as print does, and the ones of other named basic
+// types with their type, eg. main.T(1). The values of other types are printed
+// as their type and the address of their data, as the methods of interfaces,
+// eg. Error, can't be called, except the run-time errors of the runtime.
+func printPanicValue(v any) {
+	e := (*eface)(unsafe.Pointer(&v))
+	t := e.typ
+	if t == nil {
+		printString("nil")
+		return
+	}
+	switch v := v.(type) {
+	case runtimeError:
+		printString(v.Error())
+		return
+	case plainError:
+		printString(v.Error())
+		return
+	case boundsError:
+		printString(v.Error())
+		return
+	case *TypeAssertionError:
+		printString(v.Error())
+		return
+	}
+	if t.Kind < kindBool || t.Kind > kindComplex128 && t.Kind != kindString {
+		printString("(")
+		printString(t.Str)
+		printString(") 0x")
+		printUint(uint64(uintptr(e.data)), 16)
+		return
+	}
+	custom := t.PkgPath != ""
+	if custom {
+		printString(t.Str)
+		printString("(")
+		if t.Kind == kindString {
+			printString("\"")
+		}
+	}
+	switch p := e.data; t.Kind {
+	case kindBool:
+		PrintBool(*(*bool)(p))
+	case kindInt:
+		PrintInt(int64(*(*int)(p)))
+	case kindInt8:
+		PrintInt(int64(*(*int8)(p)))
+	case kindInt16:
+		PrintInt(int64(*(*int16)(p)))
+	case kindInt32:
+		PrintInt(int64(*(*int32)(p)))
+	case kindInt64:
+		PrintInt(*(*int64)(p))
+	case kindUint:
+		PrintUint(uint64(*(*uint)(p)))
+	case kindUint8:
+		PrintUint(uint64(*(*uint8)(p)))
+	case kindUint16:
+		PrintUint(uint64(*(*uint16)(p)))
+	case kindUint32:
+		PrintUint(uint64(*(*uint32)(p)))
+	case kindUint64:
+		PrintUint(*(*uint64)(p))
+	case kindUintptr:
+		PrintUint(uint64(*(*uintptr)(p)))
+	case kindFloat32:
+		PrintFloat32(*(*float32)(p))
+	case kindFloat64:
+		PrintFloat(*(*float64)(p))
+	case kindComplex64:
+		PrintComplex64(*(*complex64)(p))
+	case kindComplex128:
+		PrintComplex(*(*complex128)(p))
+	case kindString:
+		printString(*(*string)(p))
+	}
+	if custom {
+		if t.Kind == kindString {
+			printString("\"")
+		}
+		printString(")")
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !((linux || darwin) && (amd64 || arm64)) || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "github.com/goplus/llgo/internal/runtime/c"
+
+// Panics don't unwind the stack on the other targets (see
+// llssa.Program.HasUnwinding): a panic aborts the program, without making the
+// deferred calls, so it is never recovered.
+
+// Panic implements the builtin panic: it prints v, with the stack trace of the
+// thread, and exits with status 2.
+func Panic(v any) {
+	printString("panic: ")
+	printPanicValue(v)
+	printString("\n")
+	traceback(1)
+	c.Exit(2)
+}
+
+// Recover implements the builtin recover, which returns nil, as no panic is
+// ever recovered.
+func Recover() any {
+	return nil
+}
+
+// _panic is the record of a panic, which isn't needed.
+type _panic struct{}
@@ -0,0 +1,167 @@
+//go:build (linux || darwin) && (amd64 || arm64) && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// A panic unwinds the stack of its goroutine by a forced unwinding of the
+// unwinder of the platform (see llssa.Program.HasUnwinding), whose exception
+// is the header of the record of the panic. The landing pads of the functions
+// with deferred calls tell the panic the frame it unwinds (see PanicDefers),
+// make the deferred calls, which may recover it (see Recover), and return from
+// the function if one did (see PanicRecovered).
+//
+// A deferred call that panics while a panic unwinds its frame aborts it: the
+// new panic unwinds the frame again, and the aborted panic ends with it, or
+// is printed with it if it reaches the end of the stack, where the program
+// exits with status 2:
+//
+//	panic: first [recovered]
+//		panic: second
+
+// panicClass is the class of the exceptions of panics, "GOLLGO\0\0", which
+// tells them from the exceptions of other languages, eg. of C++.
+const panicClass = 0x474f4c4c474f0000
+
+// _panic is the record of a panic.
+type _panic struct {
+	exc       c.UnwindException // first, so that it has the address of the record
+	arg       any
+	link      *_panic // the previous panic of the goroutine, if any
+	frame     *uint8  // the frame whose deferred calls run, see PanicDefers
+	recovered bool
+	aborted   bool
+	pcs       [maxDepth]uintptr // stack trace of the panic, if tracebacks are enabled
+	npcs      int
+}
+
+// mainPanics are the panics of the main goroutine, which may panic before the
+// scheduler is initialized, newest first. The other goroutines keep theirs in
+// g.panics.
+var mainPanics *_panic
+
+// panics returns the address of the list of the panics of the current
+// goroutine.
+func panics() **_panic {
+	if gp := getg(); gp != nil && gp != sched.mainG {
+		return &gp.panics
+	}
+	return &mainPanics
+}
+
+// Panic implements the builtin panic: it unwinds the stack of the goroutine
+// with a new panic of value v, and doesn't return.
+func Panic(v any) {
+	p := (*_panic)(AllocZ(unsafe.Sizeof(_panic{})))
+	p.exc.Class = panicClass
+	p.arg = v
+	head := panics()
+	p.link = *head
+	*head = p
+	if funcTabs != nil {
+		p.npcs = int(c.Backtrace(&p.pcs[0], maxDepth))
+	}
+	c.UnwindForcedUnwind(&p.exc, unwindStop, nil)
+	fatalPanic(p) // the unwinding failed, eg. at a frame without unwind tables
+}
+
+// unwindStop is the stop function of the unwinding of panics, which reaches
+// the end of the stack if the panic isn't recovered.
+func unwindStop(version, actions c.Int, class uint64, exc *c.UnwindException, ctx, param c.Pointer) c.Int {
+	if actions&c.UaEndOfStack != 0 {
+		fatalPanic((*_panic)(unsafe.Pointer(exc)))
+	}
+	return c.UrcNoReason
+}
+
+// fatalPanic prints the panics of the goroutine, the newest of which is p, and
+// the stack trace of p, and exits with status 2.
+func fatalPanic(p *_panic) {
+	printPanics(p)
+	if p.npcs > 0 {
+		printTraceback(p.pcs[1:p.npcs], p.npcs == maxDepth) // without the frame of Panic
+	}
+	c.Exit(2)
+}
+
+// printPanics prints the panic p after the previous ones.
+func printPanics(p *_panic) {
+	if p.link != nil {
+		printPanics(p.link)
+		printString("\t")
+	}
+	printString("panic: ")
+	printPanicValue(p.arg)
+	if p.recovered {
+		printString(" [recovered]")
+	}
+	printString("\n")
+}
+
+// PanicDefers is called by the landing pad of a frame, which the address of
+// its mask of deferred calls identifies, with the exception exc that unwinds
+// it, before the deferred calls are made: the panics whose deferred calls run
+// in the frame are aborted by the panic of exc. The exceptions that aren't
+// panics are ignored.
+func PanicDefers(exc unsafe.Pointer, frame *uint8) {
+	p := (*_panic)(exc)
+	if p.exc.Class != panicClass {
+		return
+	}
+	for q := p.link; q != nil; q = q.link {
+		if q.frame == frame {
+			q.aborted = true
+		}
+	}
+	p.frame = frame
+}
+
+// PanicRecovered is called by the landing pad of a frame after the deferred
+// calls are made, and reports whether one of them recovered the panic of the
+// exception exc: the panic and the ones it aborted then end, and the function
+// returns normally.
+func PanicRecovered(exc unsafe.Pointer) bool {
+	p := (*_panic)(exc)
+	if p.exc.Class != panicClass || !p.recovered {
+		return false
+	}
+	q := p.link
+	for q != nil && q.aborted {
+		q = q.link
+	}
+	*panics() = q
+	return true
+}
+
+// Recover implements the builtin recover: it recovers the newest panic of the
+// goroutine, unless it is recovered already, and returns its value, or nil if
+// there is none. Go only recovers a panic in the deferred calls that the panic
+// makes, while Recover also does in the functions that they call.
+func Recover() any {
+	p := *panics()
+	if p == nil || p.recovered {
+		return nil
+	}
+	p.recovered = true
+	return p.arg
+}
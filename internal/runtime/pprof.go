@@ -0,0 +1,523 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The runtime/pprof package isn't compiled: the compiler turns calls to
+// pprof.StartCPUProfile, StopCPUProfile and WriteHeapProfile into calls to the
+// functions below, and the initialization of pprof into PprofInit, which
+// enables the sampling of heap allocations.
+//
+// The CPU profiler records the call stack of the thread that a SIGPROF of the
+// profiling timer interrupts, cpuProfileHz times per second of CPU time. The
+// heap profiler records the call stack of AllocZ every memProfileRate bytes
+// allocated on average. As the garbage collectors don't report the memory
+// that they free, heap profiles only have the alloc_objects and alloc_space
+// sample types.
+//
+// Profiles are written in the protocol buffer format of pprof, uncompressed.
+// Their locations are the addresses of the stacks, which go tool pprof
+// symbolizes by the program binary that the mappings of the profile name.
+// They can only be written to an *os.File, as the runtime can't call the
+// methods of other io.Writers.
+
+const (
+	profMaxDepth   = 32   // maximum depth of a recorded call stack
+	profBuckets    = 4096 // maximum number of distinct call stacks
+	cpuProfileHz   = 100
+	memProfileRate = 512 * 1024
+)
+
+// profBucket records the samples of a call stack: count is their number and
+// size the number of bytes that they allocated, for a heap profile.
+type profBucket struct {
+	hash  uintptr
+	depth int
+	stk   [profMaxDepth]uintptr // return addresses, except the one of a leaf of a CPU profile
+	count int64
+	size  int64
+}
+
+// profTable is a hash table of the buckets of a profile, open addressed,
+// which is allocated by libc as AllocZ is profiled. lock is a spin lock, as
+// the table is updated by signal handlers.
+type profTable struct {
+	lock    int32
+	buckets *[profBuckets]profBucket
+	lost    int64 // number of samples lost as the table was busy or full
+}
+
+// add adds a sample of the call stack stk[:depth] to table t. If t is locked,
+// it waits for it to be unlocked if wait is set, and drops the sample
+// otherwise.
+func (t *profTable) add(stk *[profMaxDepth]uintptr, depth int, count, size int64, wait bool) {
+	for !atomic.CompareAndSwapInt32(&t.lock, 0, 1) {
+		if !wait {
+			atomic.AddInt64(&t.lost, 1)
+			return
+		}
+	}
+	h := uintptr(depth)
+	for i := 0; i < depth; i++ {
+		h = h*31 + stk[i]
+	}
+	for i := uintptr(0); i < profBuckets; i++ {
+		b := &t.buckets[(h+i)%profBuckets]
+		if b.depth == 0 {
+			b.hash, b.depth, b.stk = h, depth, *stk
+		} else if b.hash != h || !b.is(stk, depth) {
+			continue
+		}
+		b.count += count
+		b.size += size
+		atomic.StoreInt32(&t.lock, 0)
+		return
+	}
+	t.lost++
+	atomic.StoreInt32(&t.lock, 0)
+}
+
+// is reports whether the call stack of b is stk[:depth].
+func (b *profBucket) is(stk *[profMaxDepth]uintptr, depth int) bool {
+	if b.depth != depth {
+		return false
+	}
+	for i := 0; i < depth; i++ {
+		if b.stk[i] != stk[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// newProfBuckets returns zeroed buckets, allocated by libc.
+func newProfBuckets() *[profBuckets]profBucket {
+	return (*[profBuckets]profBucket)(c.Calloc(1, unsafe.Sizeof([profBuckets]profBucket{})))
+}
+
+// profStack records the call stack in stk, and returns its depth. Its first
+// skip frames, the first of which is the one of profStack, are skipped.
+func profStack(stk *[profMaxDepth]uintptr, skip int) int {
+	var buf [profMaxDepth + 4]uintptr
+	n := int(c.Backtrace(&buf[0], c.Int(profMaxDepth+skip))) - skip
+	for i := 0; i < n; i++ {
+		stk[i] = buf[skip+i]
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// -----------------------------------------------------------------------------
+
+var cpuProf struct {
+	profTable
+	file  *File // where the profile is written; nil if the profiler is off
+	start int64 // nanotime of the start of the profile
+	wall  int64 // time of the start of the profile, in Unix nanoseconds
+}
+
+// PprofStartCPUProfile implements pprof.StartCPUProfile.
+func PprofStartCPUProfile(w any) error {
+	f, err := profFile(w)
+	if err != nil {
+		return err
+	}
+	if cpuProf.file != nil {
+		return errorString("cpu profiling already in use")
+	}
+	var stk [profMaxDepth]uintptr
+	profStack(&stk, 0) // loads the unwinder, which must not be loaded by a signal handler
+	cpuProf.buckets = newProfBuckets()
+	cpuProf.lost = 0
+	cpuProf.file = f
+	cpuProf.start = nanotime()
+	cpuProf.wall = unixNano()
+	c.Signal(c.SIGPROF, cpuProfSignal)
+	cpuProfTimer(1e6 / cpuProfileHz)
+	return nil
+}
+
+// PprofStopCPUProfile implements pprof.StopCPUProfile: it writes the profile
+// and stops the profiler.
+func PprofStopCPUProfile() {
+	f := cpuProf.file
+	if f == nil {
+		return
+	}
+	cpuProfTimer(0)
+	for !atomic.CompareAndSwapInt32(&cpuProf.lock, 0, 1) {
+	}
+	var w profWriter
+	w.start(cpuProf.wall, nanotime()-cpuProf.start)
+	w.sampleType("samples", "count")
+	w.sampleType("cpu", "nanoseconds")
+	w.period("cpu", "nanoseconds", 1e9/cpuProfileHz)
+	w.samples(cpuProf.buckets, true)
+	if err := w.writeTo(f); err != nil {
+		profError(err)
+	}
+	c.Free(c.Pointer(cpuProf.buckets))
+	cpuProf.buckets = nil
+	cpuProf.file = nil
+	atomic.StoreInt32(&cpuProf.lock, 0)
+}
+
+// cpuProfTimer sets the interval of the profiling timer to usec microseconds,
+// or stops it if usec is 0.
+func cpuProfTimer(usec c.Long) {
+	var it c.Itimerval
+	it.Interval.Usec = usec
+	it.Value.Usec = usec
+	c.Setitimer(c.ItimerProf, &it, nil)
+}
+
+// cpuProfSignal is the handler of SIGPROF. It is left installed once the
+// profiler stops, as a pending SIGPROF would kill the program otherwise.
+func cpuProfSignal(sig c.Int) {
+	if cpuProf.buckets == nil {
+		return
+	}
+	var stk [profMaxDepth]uintptr
+	// skips profStack, cpuProfSignal and the trampoline of the signal
+	if n := profStack(&stk, 3); n > 0 {
+		cpuProf.add(&stk, n, 1, 0, false)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+var memProf struct {
+	profTable
+	on    bool
+	bytes uint64 // number of bytes allocated since the profiler is on
+}
+
+// PprofInit implements the initialization of the runtime/pprof package. It
+// enables the heap profiler.
+func PprofInit() {
+	if !memProf.on {
+		memProf.buckets = newProfBuckets()
+		memProf.on = true
+	}
+}
+
+// memProfileAlloc records an allocation of size bytes by AllocZ if the
+// allocations since the previous sample reach memProfileRate bytes, so that
+// each sample stands for memProfileRate bytes.
+func memProfileAlloc(size uintptr) {
+	if !memProf.on || size == 0 {
+		return
+	}
+	n := atomic.AddUint64(&memProf.bytes, uint64(size))
+	k := int64(n/memProfileRate - (n-uint64(size))/memProfileRate)
+	if k == 0 {
+		return
+	}
+	count := k * memProfileRate / int64(size)
+	if count == 0 {
+		count = 1
+	}
+	var stk [profMaxDepth]uintptr
+	// skips profStack, memProfileAlloc and AllocZ
+	if depth := profStack(&stk, 3); depth > 0 {
+		memProf.add(&stk, depth, count, count*int64(size), true)
+	}
+}
+
+// PprofWriteHeapProfile implements pprof.WriteHeapProfile.
+func PprofWriteHeapProfile(w any) error {
+	f, err := profFile(w)
+	if err != nil {
+		return err
+	}
+	if !memProf.on {
+		return errorString("heap profiling is off")
+	}
+	buckets := (*[profBuckets]profBucket)(c.Malloc(unsafe.Sizeof([profBuckets]profBucket{})))
+	for !atomic.CompareAndSwapInt32(&memProf.lock, 0, 1) {
+	}
+	*buckets = *memProf.buckets
+	atomic.StoreInt32(&memProf.lock, 0)
+	var pw profWriter
+	pw.start(unixNano(), 0)
+	pw.sampleType("alloc_objects", "count")
+	pw.sampleType("alloc_space", "bytes")
+	pw.period("space", "bytes", memProfileRate)
+	pw.samples(buckets, false)
+	c.Free(c.Pointer(buckets))
+	return pw.writeTo(f)
+}
+
+// -----------------------------------------------------------------------------
+
+// profFile returns the *os.File w, as the runtime represents it.
+func profFile(w any) (*File, error) {
+	e := (*eface)(unsafe.Pointer(&w))
+	if e.typ == nil || e.typ.Str != "*os.File" {
+		return nil, errorString("pprof: profiles can only be written to an *os.File")
+	}
+	return (*File)(e.data), nil
+}
+
+// profError reports an error of pprof.StopCPUProfile, which returns none.
+func profError(err error) {
+	msg := cstring(concat("runtime/pprof: ", errorText(err), "\n"))
+	c.Write(2, c.Pointer(msg), c.Strlen(msg))
+}
+
+// unixNano returns the current time in Unix nanoseconds.
+func unixNano() int64 {
+	var ts c.Timespec
+	c.ClockGettime(c.ClockRealtime, &ts)
+	return ts.Sec*1e9 + ts.Nsec
+}
+
+// profWriter encodes a profile.proto message of pprof.
+type profWriter struct {
+	out    protoBuf // the message
+	msg    protoBuf // an embedded message
+	packed protoBuf // a packed repeated field of an embedded message
+	nstr   int64    // length of the string table
+}
+
+// Fields of the messages of profile.proto.
+const (
+	pbSampleType    = 1
+	pbSample        = 2
+	pbMapping       = 3
+	pbLocation      = 4
+	pbStringTable   = 6
+	pbTimeNanos     = 9
+	pbDurationNanos = 10
+	pbPeriodType    = 11
+	pbPeriod        = 12
+)
+
+// str adds s to the string table of the profile, and returns its index. The
+// string table can be interleaved with the other fields.
+func (w *profWriter) str(s string) int64 {
+	w.out.string(pbStringTable, s)
+	w.nstr++
+	return w.nstr - 1
+}
+
+func (w *profWriter) start(timeNanos, durationNanos int64) {
+	w.str("")
+	w.out.uint64(pbTimeNanos, uint64(timeNanos))
+	w.out.uint64(pbDurationNanos, uint64(durationNanos))
+}
+
+func (w *profWriter) valueType(field int, typ, unit string) {
+	w.msg.uint64(1, uint64(w.str(typ)))
+	w.msg.uint64(2, uint64(w.str(unit)))
+	w.out.message(field, &w.msg)
+}
+
+func (w *profWriter) sampleType(typ, unit string) {
+	w.valueType(pbSampleType, typ, unit)
+}
+
+func (w *profWriter) period(typ, unit string, period int64) {
+	w.valueType(pbPeriodType, typ, unit)
+	w.out.uint64(pbPeriod, uint64(period))
+}
+
+// samples writes the samples of buckets, of a CPU profile if cpu is set, and
+// their locations, one per address. The location of a return address is the
+// one of its call: the leaves of the stacks of CPU profiles aren't return
+// addresses.
+func (w *profWriter) samples(buckets *[profBuckets]profBucket, cpu bool) {
+	maps := profMappings()
+	for i, m := range maps {
+		w.msg.uint64(1, uint64(i+1))
+		w.msg.uint64(2, uint64(m.start))
+		w.msg.uint64(3, uint64(m.limit))
+		w.msg.uint64(4, uint64(m.offset))
+		w.msg.uint64(5, uint64(w.str(m.file)))
+		w.out.message(pbMapping, &w.msg)
+	}
+	locs := newProfLocs()
+	for i := range buckets {
+		b := &buckets[i]
+		if b.depth == 0 {
+			continue
+		}
+		for j := 0; j < b.depth; j++ {
+			addr := b.stk[j]
+			if j > 0 || !cpu {
+				addr--
+			}
+			id, added := locs.id(addr)
+			if added {
+				w.msg.uint64(1, id)
+				w.msg.uint64(2, uint64(profMappingOf(maps, addr)))
+				w.msg.uint64(3, uint64(addr))
+				w.out.message(pbLocation, &w.msg)
+			}
+			w.packed.varint(id)
+		}
+		w.msg.message(1, &w.packed)
+		w.packed.varint(uint64(b.count))
+		if cpu {
+			w.packed.varint(uint64(b.count * (1e9 / cpuProfileHz)))
+		} else {
+			w.packed.varint(uint64(b.size))
+		}
+		w.msg.message(2, &w.packed)
+		w.out.message(pbSample, &w.msg)
+	}
+	locs.free()
+}
+
+// writeTo writes the profile to f, and frees the buffers of w.
+func (w *profWriter) writeTo(f *File) error {
+	_, errno := write(f.fd, unsafe.Slice((*byte)(w.out.data), w.out.len))
+	w.out.free()
+	w.msg.free()
+	w.packed.free()
+	if errno != 0 {
+		return newPathError("write", f.name, errno)
+	}
+	return nil
+}
+
+// profMapping is a mapping of the executable code of a file in memory.
+type profMapping struct {
+	start, limit, offset uintptr
+	file                 string
+}
+
+// profMappingOf returns the id of the mapping of maps that addr is in, or 0.
+func profMappingOf(maps []profMapping, addr uintptr) int {
+	for i, m := range maps {
+		if m.start <= addr && addr < m.limit {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// profLocs maps the addresses of a profile to the ids of their locations. It
+// is a hash table, open addressed, allocated by libc.
+type profLocs struct {
+	addrs *[profBuckets * profMaxDepth]uintptr
+	ids   *[profBuckets * profMaxDepth]uint64
+	n     uint64
+}
+
+func newProfLocs() *profLocs {
+	l := (*profLocs)(c.Calloc(1, unsafe.Sizeof(profLocs{})))
+	l.addrs = (*[profBuckets * profMaxDepth]uintptr)(c.Calloc(profBuckets*profMaxDepth, unsafe.Sizeof(uintptr(0))))
+	l.ids = (*[profBuckets * profMaxDepth]uint64)(c.Calloc(profBuckets*profMaxDepth, 8))
+	return l
+}
+
+// id returns the id of the location of addr, and whether it is a new one.
+func (l *profLocs) id(addr uintptr) (uint64, bool) {
+	for i := addr % (profBuckets * profMaxDepth); ; i = (i + 1) % (profBuckets * profMaxDepth) {
+		if l.ids[i] == 0 {
+			l.n++
+			l.addrs[i], l.ids[i] = addr, l.n
+			return l.n, true
+		}
+		if l.addrs[i] == addr {
+			return l.ids[i], false
+		}
+	}
+}
+
+func (l *profLocs) free() {
+	c.Free(c.Pointer(l.addrs))
+	c.Free(c.Pointer(l.ids))
+	c.Free(c.Pointer(l))
+}
+
+// protoBuf is a buffer of a protocol buffer message, allocated by libc.
+type protoBuf struct {
+	data c.Pointer
+	len  int
+	cap  int
+}
+
+func (b *protoBuf) grow(n int) {
+	if b.len+n <= b.cap {
+		return
+	}
+	size := b.cap*2 + n
+	data := c.Malloc(uintptr(size))
+	c.Memcpy(data, b.data, uintptr(b.len))
+	c.Free(b.data)
+	b.data, b.cap = data, size
+}
+
+func (b *protoBuf) put(p c.Pointer, n int) {
+	b.grow(n)
+	c.Memcpy(unsafe.Add(b.data, b.len), p, uintptr(n))
+	b.len += n
+}
+
+func (b *protoBuf) varint(v uint64) {
+	var buf [10]byte
+	n := 0
+	for v >= 0x80 {
+		buf[n] = byte(v) | 0x80
+		v >>= 7
+		n++
+	}
+	buf[n] = byte(v)
+	b.put(c.Pointer(&buf[0]), n+1)
+}
+
+// uint64 encodes field, a varint, unless it is 0.
+func (b *protoBuf) uint64(field int, v uint64) {
+	if v != 0 {
+		b.varint(uint64(field)<<3 | 0)
+		b.varint(v)
+	}
+}
+
+// bytes encodes field, a length-delimited one.
+func (b *protoBuf) bytes(field int, p c.Pointer, n int) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(n))
+	b.put(p, n)
+}
+
+func (b *protoBuf) string(field int, s string) {
+	b.bytes(field, (*stringHeader)(unsafe.Pointer(&s)).data, len(s))
+}
+
+// message encodes field, the message m, and empties m.
+func (b *protoBuf) message(field int, m *protoBuf) {
+	b.bytes(field, m.data, m.len)
+	m.len = 0
+}
+
+func (b *protoBuf) free() {
+	c.Free(b.data)
+	*b = protoBuf{}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// profMappings returns the mappings of the images that dyld loaded, each of
+// which starts at its Mach-O header and is assumed to end where the next one
+// starts.
+func profMappings() (maps []profMapping) {
+	n := int(dyldImageCount())
+	for i := 0; i < n; i++ {
+		var m profMapping
+		m.start = uintptr(dyldGetImageHeader(c.Uint(i)))
+		m.limit = ^uintptr(0)
+		m.file = gostring(dyldGetImageName(c.Uint(i)))
+		maps = append(maps, m)
+	}
+	for i := range maps {
+		for _, m := range maps {
+			if m.start > maps[i].start && m.start < maps[i].limit {
+				maps[i].limit = m.start
+			}
+		}
+	}
+	return
+}
+
+//go:linkname dyldImageCount _dyld_image_count
+func dyldImageCount() c.Uint
+
+//go:linkname dyldGetImageHeader _dyld_get_image_header
+func dyldGetImageHeader(i c.Uint) unsafe.Pointer
+
+//go:linkname dyldGetImageName _dyld_get_image_name
+func dyldGetImageName(i c.Uint) *c.Char
@@ -0,0 +1,113 @@
+//go:build !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// profMappings returns the mappings of the executable code of the files that
+// the process maps, as /proc/self/maps lists them, eg.
+//
+//	55d0c4a00000-55d0c4a21000 r-xp 00002000 fd:01 1234 /usr/bin/prog
+func profMappings() (maps []profMapping) {
+	fd, errno := open("/proc/self/maps", c.ORdonly, 0)
+	if errno != 0 {
+		return nil
+	}
+	var buf protoBuf
+	for {
+		buf.grow(4096)
+		n := c.Read(c.Int(fd), unsafe.Add(buf.data, buf.len), uintptr(buf.cap-buf.len))
+		if n < 0 && c.Errno() == c.EINTR {
+			continue
+		}
+		if n <= 0 {
+			break
+		}
+		buf.len += int(n)
+	}
+	c.Close(c.Int(fd))
+	text := unsafe.Slice((*byte)(buf.data), buf.len)
+	for len(text) > 0 {
+		end := 0
+		for end < len(text) && text[end] != '\n' {
+			end++
+		}
+		line := text[:end]
+		if end < len(text) {
+			end++
+		}
+		text = text[end:]
+
+		var m profMapping
+		var addrs, perms, offset []byte
+		addrs, line = nextField(line)
+		perms, line = nextField(line)
+		offset, line = nextField(line)
+		_, line = nextField(line) // device
+		_, line = nextField(line) // inode
+		for len(line) > 0 && line[0] == ' ' {
+			line = line[1:]
+		}
+		if len(perms) < 3 || perms[2] != 'x' || len(line) == 0 || line[0] != '/' {
+			continue
+		}
+		m.start, addrs = parseHex(addrs)
+		m.limit, _ = parseHex(addrs[1:]) // skips '-'
+		m.offset, _ = parseHex(offset)
+		m.file = concat(*(*string)(unsafe.Pointer(&line))) // copies line, as buf is freed
+		maps = append(maps, m)
+	}
+	buf.free()
+	return
+}
+
+// parseHex parses the hexadecimal number that starts line, and returns it and
+// the rest of line.
+func parseHex(line []byte) (v uintptr, rest []byte) {
+	i := 0
+	for ; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case '0' <= ch && ch <= '9':
+			v = v<<4 | uintptr(ch-'0')
+		case 'a' <= ch && ch <= 'f':
+			v = v<<4 | uintptr(ch-'a'+10)
+		default:
+			return v, line[i:]
+		}
+	}
+	return v, line[i:]
+}
+
+// nextField returns the field of line that follows a space, and the rest of
+// line.
+func nextField(line []byte) (field, rest []byte) {
+	for len(line) > 0 && line[0] == ' ' {
+		line = line[1:]
+	}
+	i := 0
+	for i < len(line) && line[i] != ' ' {
+		i++
+	}
+	return line[:i], line[i:]
+}
@@ -0,0 +1,41 @@
+//go:build wasip1 || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+// Profiling isn't supported on wasip1 and baremetal targets.
+
+// PprofInit implements the initialization of the runtime/pprof package.
+func PprofInit() {}
+
+// PprofStartCPUProfile implements pprof.StartCPUProfile.
+func PprofStartCPUProfile(w any) error {
+	fatal("pprof is not supported on this target")
+	return nil
+}
+
+// PprofStopCPUProfile implements pprof.StopCPUProfile.
+func PprofStopCPUProfile() {}
+
+// PprofWriteHeapProfile implements pprof.WriteHeapProfile.
+func PprofWriteHeapProfile(w any) error {
+	fatal("pprof is not supported on this target")
+	return nil
+}
+
+func memProfileAlloc(size uintptr) {}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// The calls of the builtin functions print and println are compiled to calls
+// of the functions below, one for each argument (see llssa.Builder.Print),
+// which write their values to stderr as the ones of Go do: integers in
+// decimal, floats in their shortest representation, as the %v of fmt, complex
+// numbers as (re±imi) and pointers in hexadecimal.
+
+// PrintBool prints v.
+func PrintBool(v bool) {
+	if v {
+		printString("true")
+	} else {
+		printString("false")
+	}
+}
+
+// PrintInt prints v.
+func PrintInt(v int64) {
+	if v < 0 {
+		printString("-")
+		v = -v
+	}
+	printUint(uint64(v), 10)
+}
+
+// PrintUint prints v.
+func PrintUint(v uint64) {
+	printUint(v, 10)
+}
+
+// PrintFloat prints v.
+func PrintFloat(v float64) {
+	var buf [24]byte
+	printBytes(appendFloat(buf[:0], v, 'g', -1, 64))
+}
+
+// PrintFloat32 prints v.
+func PrintFloat32(v float32) {
+	var buf [24]byte
+	printBytes(appendFloat(buf[:0], float64(v), 'g', -1, 32))
+}
+
+// PrintComplex prints v.
+func PrintComplex(v complex128) {
+	printComplex(v, 64)
+}
+
+// PrintComplex64 prints v.
+func PrintComplex64(v complex64) {
+	printComplex(complex128(v), 32)
+}
+
+// printComplex prints v, whose parts are float32 if bitSize is 32.
+func printComplex(v complex128, bitSize int) {
+	var buf [24]byte
+	printString("(")
+	printBytes(appendFloat(buf[:0], real(v), 'g', -1, bitSize))
+	b := appendFloat(buf[:0], imag(v), 'g', -1, bitSize)
+	if b[0] != '+' && b[0] != '-' {
+		printString("+")
+	}
+	printBytes(b)
+	printString("i)")
+}
+
+// PrintString prints s.
+func PrintString(s string) {
+	printString(s)
+}
+
+// PrintPointer prints p.
+func PrintPointer(p unsafe.Pointer) {
+	printString("0x")
+	printUint(uint64(uintptr(p)), 16)
+}
+
+// printBytes writes b to stderr.
+func printBytes(b []byte) {
+	printString(*(*string)(unsafe.Pointer(&stringHeader{sliceData(b), len(b)})))
+}
+
+// printUint writes v in base 10 or 16 to stderr, without allocating.
+func printUint(v, base uint64) {
+	const digits = "0123456789abcdef"
+	var buf [20]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = digits[v%base]
+		v /= base
+		if v == 0 {
+			break
+		}
+	}
+	printBytes(buf[i:])
+}
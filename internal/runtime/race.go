@@ -0,0 +1,45 @@
+//go:build race
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// The runtime is built with the build tag race when programs are built with
+// race detection (see cl.Config.Race), and linked to the runtime of
+// ThreadSanitizer, which intercepts pthread functions, eg. the ones of
+// channels. The synchronization that it can't see, as it is done by atomics,
+// or as goroutines are switched on their threads by the ucontext functions,
+// is annotated by raceacquire and racerelease: an access that follows
+// raceacquire(addr) happens after the ones that precede a racerelease(addr)
+// that it observes. Goroutines that run on the same thread, one at a time,
+// aren't told apart.
+
+func raceacquire(addr unsafe.Pointer) {
+	tsanAcquire(addr)
+}
+
+func racerelease(addr unsafe.Pointer) {
+	tsanRelease(addr)
+}
+
+//go:linkname tsanAcquire __tsan_acquire
+func tsanAcquire(addr unsafe.Pointer)
+
+//go:linkname tsanRelease __tsan_release
+func tsanRelease(addr unsafe.Pointer)
@@ -0,0 +1,572 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The reflect package isn't compiled: the compiler turns calls to
+// reflect.TypeOf, reflect.ValueOf, and to the methods of reflect.Type,
+// reflect.Value and reflect.Kind that are listed in rtIntrinsics in cl into
+// calls to the functions below. A reflect.Type is an interface whose data is
+// a type descriptor, and a reflect.Value has the layout of Value. Only the
+// kind, name, size and string of a type are described unless the program is
+// compiled for reflection (see cl.Config.Reflect). Only the Values of
+// methods can be called (see ReflectValueCall).
+
+// Value is the runtime representation of reflect.Value.
+type Value struct {
+	typ  *Type
+	ptr  unsafe.Pointer // the value if typ is direct, its address otherwise
+	flag uintptr
+}
+
+// The flag of the Value of a method, whose typ and ptr are the ones of its
+// receiver, is flagMethod | i<<flagMethodShift, where i is the index of the
+// method in the Methods of typ (see ReflectValueMethod).
+const (
+	flagMethod      = 1
+	flagMethodShift = 1
+)
+
+// reflectStructField has the layout of reflect.StructField.
+type reflectStructField struct {
+	Name      string
+	PkgPath   string
+	Type      any // a reflect.Type
+	Tag       string
+	Offset    uintptr
+	Index     []int
+	Anonymous bool
+}
+
+// reflectMethod has the layout of reflect.Method.
+type reflectMethod struct {
+	Name    string
+	PkgPath string
+	Type    any // a reflect.Type
+	Func    Value
+	Index   int
+}
+
+var kindNames = [...]string{
+	kindInvalid:       "invalid",
+	kindBool:          "bool",
+	kindInt:           "int",
+	kindInt8:          "int8",
+	kindInt16:         "int16",
+	kindInt32:         "int32",
+	kindInt64:         "int64",
+	kindUint:          "uint",
+	kindUint8:         "uint8",
+	kindUint16:        "uint16",
+	kindUint32:        "uint32",
+	kindUint64:        "uint64",
+	kindUintptr:       "uintptr",
+	kindFloat32:       "float32",
+	kindFloat64:       "float64",
+	kindComplex64:     "complex64",
+	kindComplex128:    "complex128",
+	kindArray:         "array",
+	kindChan:          "chan",
+	kindFunc:          "func",
+	kindInterface:     "interface",
+	kindMap:           "map",
+	kindPointer:       "ptr",
+	kindSlice:         "slice",
+	kindString:        "string",
+	kindStruct:        "struct",
+	kindUnsafePointer: "unsafe.Pointer",
+}
+
+// ReflectKindString implements reflect.Kind.String.
+func ReflectKindString(k uint) string {
+	if k < uint(len(kindNames)) {
+		return kindNames[k]
+	}
+	return "kind?"
+}
+
+// toType returns the reflect.Type of the descriptor t.
+func toType(t *Type) (ret any) {
+	if t != nil {
+		ret = t
+	}
+	return
+}
+
+// typeOf returns the descriptor of the reflect.Type t.
+func typeOf(t any) *Type {
+	return (*Type)((*eface)(unsafe.Pointer(&t)).data)
+}
+
+// ReflectTypeOf implements reflect.TypeOf.
+func ReflectTypeOf(i any) any {
+	return toType((*eface)(unsafe.Pointer(&i)).typ)
+}
+
+// ReflectTypeKind implements reflect.Type.Kind.
+func ReflectTypeKind(t any) uint {
+	return uint(typeOf(t).Kind)
+}
+
+// ReflectTypeName implements reflect.Type.Name.
+func ReflectTypeName(t any) string {
+	return typeOf(t).Name
+}
+
+// ReflectTypePkgPath implements reflect.Type.PkgPath.
+func ReflectTypePkgPath(t any) string {
+	return typeOf(t).PkgPath
+}
+
+// ReflectTypeString implements reflect.Type.String.
+func ReflectTypeString(t any) string {
+	return typeOf(t).Str
+}
+
+// ReflectTypeSize implements reflect.Type.Size.
+func ReflectTypeSize(t any) uintptr {
+	return typeOf(t).Size
+}
+
+// ReflectTypeElem implements reflect.Type.Elem.
+func ReflectTypeElem(t any) any {
+	rt := typeOf(t)
+	switch rt.Kind {
+	case kindArray, kindChan, kindMap, kindPointer, kindSlice:
+		return toType(rt.Elem)
+	}
+	fatal(concat("reflect: Elem of invalid type ", rt.Str))
+	return nil
+}
+
+// ReflectTypeKey implements reflect.Type.Key.
+func ReflectTypeKey(t any) any {
+	rt := typeOf(t)
+	if rt.Kind != kindMap {
+		fatal(concat("reflect: Key of non-map type ", rt.Str))
+	}
+	return toType(rt.Key)
+}
+
+// ReflectTypeLen implements reflect.Type.Len.
+func ReflectTypeLen(t any) int {
+	rt := typeOf(t)
+	if rt.Kind != kindArray {
+		fatal(concat("reflect: Len of non-array type ", rt.Str))
+	}
+	return int(rt.Len)
+}
+
+// ReflectTypeNumField implements reflect.Type.NumField.
+func ReflectTypeNumField(t any) int {
+	rt := typeOf(t)
+	if rt.Kind != kindStruct {
+		fatal(concat("reflect: NumField of non-struct type ", rt.Str))
+	}
+	return len(rt.Fields)
+}
+
+// ReflectTypeField implements reflect.Type.Field.
+func ReflectTypeField(t any, i int) reflectStructField {
+	rt := typeOf(t)
+	if rt.Kind != kindStruct {
+		fatal(concat("reflect: Field of non-struct type ", rt.Str))
+	}
+	f := &rt.Fields[i]
+	index := (*int)(AllocZ(unsafe.Sizeof(i)))
+	*index = i
+	ret := reflectStructField{
+		Name:      f.Name,
+		Type:      toType(f.Typ),
+		Offset:    f.Offset,
+		Index:     unsafe.Slice(index, 1),
+		Anonymous: f.Embedded,
+	}
+	if len(f.Name) > 0 && !(f.Name[0] >= 'A' && f.Name[0] <= 'Z') {
+		ret.PkgPath = rt.PkgPath
+	}
+	return ret
+}
+
+// ReflectTypeNumMethod implements reflect.Type.NumMethod.
+func ReflectTypeNumMethod(t any) int {
+	return len(typeOf(t).Methods)
+}
+
+// ReflectTypeMethod implements reflect.Type.Method. The Func of the method
+// is the zero Value, as only method values can be called.
+func ReflectTypeMethod(t any, i int) reflectMethod {
+	m := &typeOf(t).Methods[i]
+	return reflectMethod{Name: m.Name, Type: toType(m.Typ), Index: i}
+}
+
+// ReflectValueOf implements reflect.ValueOf.
+func ReflectValueOf(i any) Value {
+	e := (*eface)(unsafe.Pointer(&i))
+	return Value{typ: e.typ, ptr: e.data}
+}
+
+// ReflectValueKind implements reflect.Value.Kind.
+func ReflectValueKind(v Value) uint {
+	return uint(v.kind())
+}
+
+// ReflectValueType implements reflect.Value.Type.
+func ReflectValueType(v Value) any {
+	if v.typ == nil {
+		fatal("reflect: call of reflect.Value.Type on zero Value")
+	}
+	return toType(v.rtype())
+}
+
+// ReflectValueBool implements reflect.Value.Bool.
+func ReflectValueBool(v Value) bool {
+	v.mustBe(kindBool, "Bool")
+	return *(*bool)(v.ptr)
+}
+
+// ReflectValueInt implements reflect.Value.Int.
+func ReflectValueInt(v Value) int64 {
+	switch v.kind() {
+	case kindInt:
+		return int64(*(*int)(v.ptr))
+	case kindInt8:
+		return int64(*(*int8)(v.ptr))
+	case kindInt16:
+		return int64(*(*int16)(v.ptr))
+	case kindInt32:
+		return int64(*(*int32)(v.ptr))
+	case kindInt64:
+		return *(*int64)(v.ptr)
+	}
+	reflectPanic("call of reflect.Value.Int on ", v.typ)
+	return 0
+}
+
+// ReflectValueUint implements reflect.Value.Uint.
+func ReflectValueUint(v Value) uint64 {
+	switch v.kind() {
+	case kindUint:
+		return uint64(*(*uint)(v.ptr))
+	case kindUint8:
+		return uint64(*(*uint8)(v.ptr))
+	case kindUint16:
+		return uint64(*(*uint16)(v.ptr))
+	case kindUint32:
+		return uint64(*(*uint32)(v.ptr))
+	case kindUint64:
+		return *(*uint64)(v.ptr)
+	case kindUintptr:
+		return uint64(*(*uintptr)(v.ptr))
+	}
+	reflectPanic("call of reflect.Value.Uint on ", v.typ)
+	return 0
+}
+
+// ReflectValueFloat implements reflect.Value.Float.
+func ReflectValueFloat(v Value) float64 {
+	switch v.kind() {
+	case kindFloat32:
+		return float64(*(*float32)(v.ptr))
+	case kindFloat64:
+		return *(*float64)(v.ptr)
+	}
+	reflectPanic("call of reflect.Value.Float on ", v.typ)
+	return 0
+}
+
+// ReflectValueString implements reflect.Value.String.
+func ReflectValueString(v Value) string {
+	switch v.kind() {
+	case kindInvalid:
+		return "<invalid Value>"
+	case kindString:
+		return *(*string)(v.ptr)
+	}
+	return concat("<", v.rtype().Str, " Value>")
+}
+
+// ReflectValueLen implements reflect.Value.Len.
+func ReflectValueLen(v Value) int {
+	switch v.kind() {
+	case kindArray:
+		return int(v.typ.Len)
+	case kindChan:
+		return ChanLen((*Chan)(v.ptr))
+	case kindSlice:
+		return (*sliceHeader)(v.ptr).len
+	case kindString:
+		return (*stringHeader)(v.ptr).len
+	}
+	reflectPanic("call of reflect.Value.Len on ", v.typ)
+	return 0
+}
+
+// ReflectValueIndex implements reflect.Value.Index.
+func ReflectValueIndex(v Value, i int) Value {
+	switch v.kind() {
+	case kindArray:
+		if uint(i) >= uint(v.typ.Len) {
+			fatal("reflect: array index out of range")
+		}
+		return elemValue(v.typ.Elem, unsafe.Add(v.ptr, uintptr(i)*v.typ.Elem.Size))
+	case kindSlice:
+		s := (*sliceHeader)(v.ptr)
+		if uint(i) >= uint(s.len) {
+			fatal("reflect: slice index out of range")
+		}
+		return elemValue(v.typ.Elem, unsafe.Add(s.data, uintptr(i)*v.typ.Elem.Size))
+	case kindString:
+		s := *(*string)(v.ptr)
+		if uint(i) >= uint(len(s)) {
+			fatal("reflect: string index out of range")
+		}
+		return ReflectValueOf(s[i])
+	}
+	reflectPanic("call of reflect.Value.Index on ", v.typ)
+	return Value{}
+}
+
+// ReflectValueNumField implements reflect.Value.NumField.
+func ReflectValueNumField(v Value) int {
+	v.mustBe(kindStruct, "NumField")
+	return len(v.typ.Fields)
+}
+
+// ReflectValueField implements reflect.Value.Field.
+func ReflectValueField(v Value, i int) Value {
+	v.mustBe(kindStruct, "Field")
+	f := &v.typ.Fields[i]
+	return elemValue(f.Typ, unsafe.Add(v.ptr, f.Offset))
+}
+
+// ReflectValueElem implements reflect.Value.Elem.
+func ReflectValueElem(v Value) Value {
+	switch v.kind() {
+	case kindInterface:
+		e := (*eface)(v.ptr)
+		return Value{typ: e.typ, ptr: e.data}
+	case kindPointer:
+		if v.ptr == nil {
+			return Value{}
+		}
+		return elemValue(v.typ.Elem, v.ptr)
+	}
+	reflectPanic("call of reflect.Value.Elem on ", v.typ)
+	return Value{}
+}
+
+// ReflectValueIsNil implements reflect.Value.IsNil.
+func ReflectValueIsNil(v Value) bool {
+	switch v.kind() {
+	case kindChan, kindMap, kindPointer, kindUnsafePointer:
+		return v.ptr == nil
+	case kindFunc: // the function of a closure, or a C function pointer
+		return v.flag&flagMethod == 0 && *(*unsafe.Pointer)(v.ptr) == nil
+	case kindInterface:
+		return (*eface)(v.ptr).typ == nil
+	case kindSlice:
+		return (*sliceHeader)(v.ptr).data == nil
+	}
+	reflectPanic("call of reflect.Value.IsNil on ", v.typ)
+	return false
+}
+
+// ReflectValueIsValid implements reflect.Value.IsValid.
+func ReflectValueIsValid(v Value) bool {
+	return v.typ != nil
+}
+
+// ReflectValueInterface implements reflect.Value.Interface.
+func ReflectValueInterface(v Value) (ret any) {
+	switch v.kind() {
+	case kindInvalid:
+		fatal("reflect: call of reflect.Value.Interface on zero Value")
+	case kindFunc:
+		if v.flag&flagMethod != 0 {
+			fatal("reflect: call of reflect.Value.Interface on method Value")
+		}
+	case kindInterface:
+		return *(*any)(v.ptr)
+	}
+	e := (*eface)(unsafe.Pointer(&ret))
+	e.typ, e.data = v.typ, v.ptr
+	return
+}
+
+// ReflectValueNumMethod implements reflect.Value.NumMethod.
+func ReflectValueNumMethod(v Value) int {
+	if v.typ == nil {
+		fatal("reflect: call of reflect.Value.NumMethod on zero Value")
+	}
+	return len(v.rtype().Methods)
+}
+
+// ReflectValueMethod implements reflect.Value.Method. The method of the
+// dynamic value of an interface is looked up in its type by name.
+func ReflectValueMethod(v Value, i int) Value {
+	if v.typ == nil {
+		fatal("reflect: call of reflect.Value.Method on zero Value")
+	}
+	if uint(i) >= uint(len(v.rtype().Methods)) {
+		fatal("reflect: Method index out of range")
+	}
+	if v.typ.Kind == kindInterface {
+		e := (*eface)(v.ptr)
+		if e.typ == nil {
+			fatal("reflect: Method on nil interface value")
+		}
+		name := v.typ.Methods[i].Name
+		for i = 0; e.typ.Methods[i].Name != name; i++ {
+		}
+		v = Value{typ: e.typ, ptr: e.data}
+	}
+	return Value{typ: v.typ, ptr: v.ptr, flag: flagMethod | uintptr(i)<<flagMethodShift}
+}
+
+// ReflectValueCall implements reflect.Value.Call for the Values of methods
+// (see ReflectValueMethod). The Call function of the method, which is
+// generated for its signature, is passed the addresses of the arguments,
+// assigned to the types of the parameters, and the ones of the results, and
+// calls the method on the data of the receiver as an interface would.
+func ReflectValueCall(v Value, in []Value) []Value {
+	v.mustBe(kindFunc, "Call")
+	if v.flag&flagMethod == 0 {
+		fatal("reflect: only the Values of methods can be called")
+	}
+	m := &v.typ.Methods[v.flag>>flagMethodShift]
+	if m.Call == nil {
+		fatal(concat("reflect: method ", m.Name, " of ", v.typ.Str, " isn't compiled"))
+	}
+	ft := m.Typ
+	n, variadic := len(ft.In), ft.Len != 0
+	if !variadic && len(in) != n || variadic && len(in) < n-1 {
+		fatal("reflect: Call with wrong number of input arguments")
+	}
+	args := allocPtrs(n)
+	for i, t := range ft.In {
+		if variadic && i == n-1 {
+			args[i] = variadicArg(in[i:], t)
+			break
+		}
+		args[i] = argOf(in[i], t)
+	}
+	results := allocPtrs(len(ft.Out))
+	for i, t := range ft.Out {
+		results[i] = AllocZ(t.Size)
+	}
+	var call func(fn, recv, args, results unsafe.Pointer)
+	*(*[2]unsafe.Pointer)(unsafe.Pointer(&call)) = [2]unsafe.Pointer{m.Call}
+	call(m.Ifn, v.ptr, (*sliceHeader)(unsafe.Pointer(&args)).data, (*sliceHeader)(unsafe.Pointer(&results)).data)
+	ret := unsafe.Slice((*Value)(AllocZ(uintptr(len(ft.Out))*unsafe.Sizeof(Value{}))), len(ft.Out))
+	for i, t := range ft.Out {
+		ret[i] = elemValue(t, results[i])
+	}
+	return ret
+}
+
+// elemValue returns the Value of type t at the address ptr.
+func elemValue(t *Type, ptr unsafe.Pointer) Value {
+	if t.isDirect() {
+		return Value{typ: t, ptr: *(*unsafe.Pointer)(ptr)}
+	}
+	return Value{typ: t, ptr: ptr}
+}
+
+func (v Value) kind() uintptr {
+	if v.typ == nil {
+		return kindInvalid
+	}
+	return v.rtype().Kind
+}
+
+// rtype returns the type of v, which is the func type of the method if v is
+// the Value of a method.
+func (v Value) rtype() *Type {
+	if v.flag&flagMethod != 0 {
+		return v.typ.Methods[v.flag>>flagMethodShift].Typ
+	}
+	return v.typ
+}
+
+// argOf returns the address of the value of x assigned to a parameter of type
+// t, which may be the address of x.
+func argOf(x Value, t *Type) unsafe.Pointer {
+	switch {
+	case x.typ == nil:
+		fatal("reflect: Call using zero Value argument")
+	case x.flag&flagMethod != 0:
+		fatal("reflect: Call using method Value argument")
+	case x.typ == t:
+		if t.isDirect() {
+			p := allocPtrs(1)
+			p[0] = x.ptr
+			return unsafe.Pointer(&p[0])
+		}
+		return x.ptr
+	case t.Kind == kindInterface:
+		e := (*eface)(AllocZ(unsafe.Sizeof(eface{})))
+		if x.typ.Kind == kindInterface {
+			*e = *(*eface)(x.ptr)
+		} else {
+			e.typ, e.data = x.typ, x.ptr
+		}
+		return unsafe.Pointer(e)
+	}
+	fatal(concat("reflect: Call using ", x.typ.Str, " as type ", t.Str))
+	return nil
+}
+
+// variadicArg returns the address of the slice of type t of the values of xs,
+// passed to the variadic parameter of a function.
+func variadicArg(xs []Value, t *Type) unsafe.Pointer {
+	s := (*sliceHeader)(AllocZ(unsafe.Sizeof(sliceHeader{})))
+	size := t.Elem.Size
+	s.data = AllocZ(uintptr(len(xs)) * size)
+	s.len, s.cap = len(xs), len(xs)
+	for i, x := range xs {
+		c.Memmove(unsafe.Add(s.data, uintptr(i)*size), argOf(x, t.Elem), size)
+	}
+	return unsafe.Pointer(s)
+}
+
+// allocPtrs returns a slice of n pointers, allocated on the heap.
+func allocPtrs(n int) []unsafe.Pointer {
+	var p unsafe.Pointer
+	return unsafe.Slice((*unsafe.Pointer)(AllocZ(uintptr(n)*unsafe.Sizeof(p))), n)
+}
+
+func (v Value) mustBe(kind uintptr, method string) {
+	if v.kind() != kind {
+		reflectPanic(concat("call of reflect.Value.", method, " on "), v.typ)
+	}
+}
+
+// reflectPanic reports a misuse of reflect about the type t, which may be
+// nil, as a fatal error.
+func reflectPanic(msg string, t *Type) {
+	str := "zero Value"
+	if t != nil {
+		str = concat(ReflectKindString(uint(t.Kind)), " Value")
+	}
+	fatal(concat("reflect: ", msg, str))
+}
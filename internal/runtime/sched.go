@@ -0,0 +1,733 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The goroutine scheduler multiplexes goroutines (G) onto threads (M), as the
+// one of Go does. There are GOMAXPROCS processors (P), each of which has a
+// local run queue and is owned by a single M. An M runs the goroutines of the
+// queue of its P, then the ones of the global run queue, and then steals half
+// of the queue of another P. It sleeps when there is nothing to run.
+//
+// Goroutines have stacks that grow on demand (see stackinit), and are
+// switched by the ucontext functions of libc: an M switches from its
+// scheduling context g0, which runs on the stack of the thread, to a
+// goroutine, and back. Goroutines yield when they block on a channel or when
+// they are preempted, which the code that llgo generates checks in function
+// prologues (see cl.Config.Preempt), and on the back edges of loops if they
+// are compiled with cl.Config.PreemptLoops: the sysmon thread requests
+// preemption when a goroutine has run for more than a time slice, while other
+// goroutines are runnable. C functions that block hold the P of their
+// goroutine, as Ps aren't handed off yet.
+//
+// The main goroutine is an exception: it runs on the main thread, which isn't
+// an M, so that the stack of the main thread is never switched, and sleeps
+// there when it blocks (see threadPark). So does the goroutine of a thread
+// that the runtime didn't create, eg. one of a C library, which calls Go
+// functions as callbacks: its M has no P (see EnterCallback).
+//
+// The M of a thread is the thread-local variable curm (see getm), which LLVM
+// accesses relative to the thread pointer, eg. the %fs segment on amd64 or
+// TPIDR_EL0 on arm64, rather than by a call of pthread_getspecific, as
+// Preempt, in the prologues of functions, and every blocking operation find
+// the current goroutine by it. Goroutines have IDs, as the ones of Go do,
+// which tracebacks print.
+//
+// A goroutine that is resumed by another M is handed off by the sequentially
+// consistent atomics of the run queues, or by the mutexes of the global run
+// queue and of the waitq it waited in, which g0 unlocks once the goroutine is
+// switched out: the M that resumes it sees the context that Swapcontext saved,
+// and the memory operations of the goroutine, on weakly ordered CPUs too.
+
+const (
+	maxProcs  = 256
+	runqSize  = 256
+	timeSlice = 10000 // in microseconds
+)
+
+// g is a goroutine.
+type g struct {
+	ctx    c.Ucontext
+	stack  c.Pointer
+	fn     func(c.Pointer)
+	arg    c.Pointer
+	link   *g // next goroutine in a gQueue
+	dead   bool
+	rand   uint32      // state of the PRNG of select (see Select)
+	goid   uint64      // ID of the goroutine, 1 for the main one
+	park   *threadPark // the thread it sleeps on, if it runs on its own
+	panics *_panic     // panics of the goroutine, newest first, see Panic
+}
+
+// threadPark is the thread of a goroutine that runs on a thread of its own,
+// rather than on the Ms of Ps: the main goroutine, or the goroutine of a
+// thread that the runtime didn't create (see EnterCallback). The goroutine
+// sleeps there when it blocks, until ready wakes it.
+type threadPark struct {
+	lock  c.PthreadMutex
+	cond  c.PthreadCond
+	ready bool
+}
+
+func (pk *threadPark) init() {
+	c.PthreadMutexInit(&pk.lock, nil)
+	c.PthreadCondInit(&pk.cond, nil)
+}
+
+// gQueue is a FIFO of goroutines, linked by g.link.
+type gQueue struct {
+	head, tail *g
+	n          int
+}
+
+func (q *gQueue) push(gp *g) {
+	gp.link = nil
+	if q.tail == nil {
+		q.head = gp
+	} else {
+		q.tail.link = gp
+	}
+	q.tail = gp
+	q.n++
+}
+
+func (q *gQueue) pop() *g {
+	gp := q.head
+	if gp != nil {
+		q.head = gp.link
+		if q.head == nil {
+			q.tail = nil
+		}
+		gp.link = nil
+		q.n--
+	}
+	return gp
+}
+
+// p is a processor. Its run queue is a lock-free ring buffer: only its M puts
+// goroutines to it, while other Ms may take goroutines from it.
+type p struct {
+	id       int
+	runqhead uint32 // accessed atomically
+	runqtail uint32 // accessed atomically
+	runq     [runqSize]*g
+}
+
+// m is a thread that runs goroutines. The M of a thread that the runtime
+// didn't create has no P, and only runs its goroutine (see EnterCallback).
+type m struct {
+	g0     g  // scheduling context
+	curg   *g // goroutine being run, or nil; accessed atomically by sysmon
+	p      *p
+	link   *m // next M of callbackMs, if it has no P
+	thread c.Pthread
+
+	schedtick  uint32 // incremented when a goroutine is run; accessed atomically
+	sysmontick uint32 // schedtick seen by sysmon
+	preempt    uint32 // preemption requested by sysmon; accessed atomically
+	fastrand   uint32
+
+	// What g0 does with curg once curg is switched out, unless it's dead.
+	yield  bool            // put it to the global run queue
+	unlock *c.PthreadMutex // unlock the mutex of the waitq it waits in
+}
+
+var sched struct {
+	lock    c.PthreadMutex
+	idle    c.PthreadCond // broadcast when goroutines are made runnable
+	runq    gQueue        // global run queue
+	nmidle  int32         // number of Ms waiting for idle
+	nm      int32         // number of Ms; accessed atomically
+	nprocs  int32         // GOMAXPROCS, Ms of greater Ps are stopped; accessed atomically
+	allp    [maxProcs]*p
+	allm    [maxProcs]*m
+	goidgen uint64 // last goroutine ID; accessed atomically
+	inited  uint32 // set atomically once the scheduler is initialized, see schedInited
+	initing int32  // spin lock of schedinit
+
+	// The main goroutine, and whether it waits.
+	mainPark    threadPark
+	mainG       *g
+	mainWaiting int32 // protected by lock; cleared atomically by ready
+
+	callbacks int32 // set atomically once C may call Go on its threads, see EnableCallbacks
+}
+
+// curm is the M of the current thread, or nil if the thread isn't an M, eg.
+// the main thread.
+//
+//llgo:threadlocal
+var curm *m
+
+// preemptFlag is set when sysmon requests an M to preempt its goroutine. It is
+// checked in the prologue of functions, which call Preempt if it is set.
+var preemptFlag uint32
+
+// netpoll, if not nil, is the network poller: it returns the goroutines whose
+// I/O is ready, linked by g.link, without blocking. Idle Ms and sysmon poll
+// the network. It is set by netpollinit.
+var netpoll func() *g
+
+// netpollWaiters is the number of goroutines waiting for I/O, which aren't
+// deadlocked as the network poller can wake them; accessed atomically.
+var netpollWaiters int32
+
+var gomaxprocsEnv = [...]c.Char{'G', 'O', 'M', 'A', 'X', 'P', 'R', 'O', 'C', 'S', 0}
+var selectSeedEnv = [...]c.Char{'L', 'L', 'G', 'O', '_', 'S', 'E', 'L', 'E', 'C', 'T', 'S', 'E', 'E', 'D', 0}
+
+// schedinit initializes the scheduler, once. It is called by the main
+// goroutine, before other goroutines can exist, or by callbacks on threads
+// that the runtime didn't create, eg. the ones of C libraries, which may call
+// it concurrently: the other callers wait for the first one to finish.
+// sched.inited is set last, once the Ms of the Ps and sysmon are started.
+func schedinit() {
+	for !atomic.CompareAndSwapInt32(&sched.initing, 0, 1) {
+	}
+	if sched.inited == 0 {
+		c.PthreadMutexInit(&sched.lock, nil)
+		c.PthreadCondInit(&sched.idle, nil)
+		sched.mainPark.init()
+		stackinit()
+		timerinit()
+		netpollinit()
+		sched.mainG = (*g)(AllocZ(unsafe.Sizeof(g{})))
+		sched.mainG.goid = 1
+		sched.mainG.park = &sched.mainPark
+		sched.goidgen = 1
+		n := 0
+		if s := c.Getenv(&gomaxprocsEnv[0]); s != nil {
+			n = int(c.Atoi(s))
+		}
+		if n < 1 {
+			n = int(c.Sysconf(c.ScNprocessorsOnln))
+		}
+		procresize(n)
+		var th c.Pthread
+		if newThread(&th, sysmon, nil) != 0 {
+			fatal("can't create the sysmon thread")
+		}
+		atomic.StoreUint32(&sched.inited, 1)
+	}
+	atomic.StoreInt32(&sched.initing, 0)
+}
+
+// schedInited reports whether the scheduler is initialized, which the callers
+// of schedinit check first, as it takes a lock. sched.inited is loaded
+// atomically: once it is seen set, the state that schedinit initialized is
+// seen too, on weakly ordered CPUs, eg. ARM, as well.
+func schedInited() bool {
+	return atomic.LoadUint32(&sched.inited) != 0
+}
+
+// procresize sets GOMAXPROCS to n, starting the Ms of new Ps.
+func procresize(n int) {
+	if n < 1 {
+		n = 1
+	} else if n > maxProcs {
+		n = maxProcs
+	}
+	c.PthreadMutexLock(&sched.lock)
+	for i := int(sched.nm); i < n; i++ {
+		mp := (*m)(AllocZ(unsafe.Sizeof(m{})))
+		mp.p = (*p)(AllocZ(unsafe.Sizeof(p{})))
+		mp.p.id = i
+		mp.fastrand = uint32(i)*2654435761 + 1
+		sched.allp[i] = mp.p
+		sched.allm[i] = mp
+		if newThread(&mp.thread, mstart, c.Pointer(mp)) != 0 {
+			c.PthreadMutexUnlock(&sched.lock)
+			fatal("can't create a thread")
+		}
+		atomic.AddInt32(&sched.nm, 1)
+	}
+	atomic.StoreInt32(&sched.nprocs, int32(n))
+	c.PthreadCondBroadcast(&sched.idle)
+	c.PthreadMutexUnlock(&sched.lock)
+}
+
+// GOMAXPROCS sets the maximum number of threads that can run goroutines
+// simultaneously, besides the main goroutine, and returns the previous
+// setting. If n < 1, it doesn't change the setting. The initial setting is
+// the value of the GOMAXPROCS environment variable, or the number of CPUs.
+func GOMAXPROCS(n int) int {
+	if !schedInited() {
+		schedinit()
+	}
+	ret := int(atomic.LoadInt32(&sched.nprocs))
+	if n > 0 {
+		procresize(n)
+	}
+	return ret
+}
+
+// grand returns the state of the PRNG of the current goroutine.
+func grand() *uint32 {
+	if mp := getm(); mp != nil {
+		return &mp.curg.rand
+	}
+	if mainRand == 0 {
+		mainRand = newRand(0)
+	}
+	return &mainRand
+}
+
+// randSeed returns the seed of the PRNGs of goroutines: the value of
+// LLGO_SELECTSEED if it is set, or else the time.
+func randSeed() uint32 {
+	if s := c.Getenv(&selectSeedEnv[0]); s != nil {
+		return uint32(c.Atoi(s))
+	}
+	return uint32(nanotime())
+}
+
+// getm returns the M of the current thread, or nil if the thread isn't an M:
+// then it runs the main goroutine.
+//
+// It is never inlined: LLVM computes the address of a thread-local variable
+// once per function, while a goroutine that is switched out, eg. by Gosched,
+// may resume on another thread, whose M a stale address wouldn't find.
+//
+//go:noinline
+func getm() *m {
+	return curm
+}
+
+// getg returns the current goroutine, or nil if it is the main one and the
+// scheduler isn't initialized.
+func getg() *g {
+	if mp := getm(); mp != nil {
+		return mp.curg
+	}
+	return sched.mainG
+}
+
+// goid returns the ID of the current goroutine.
+func goid() uint64 {
+	if gp := getg(); gp != nil {
+		return gp.goid
+	}
+	return 1
+}
+
+// Go creates a goroutine that calls fn(arg). It implements the go statement.
+func Go(fn func(unsafe.Pointer), arg unsafe.Pointer) {
+	if !schedInited() {
+		schedinit()
+	}
+	gp := (*g)(AllocZ(unsafe.Sizeof(g{})))
+	gp.stack = newStack()
+	gp.fn, gp.arg = fn, arg
+	gp.goid = atomic.AddUint64(&sched.goidgen, 1)
+	gp.rand = newRand(atomic.AddUint32(&randSeq, 1))
+	c.Getcontext(&gp.ctx)
+	gp.ctx.Stack.Sp = gp.stack
+	gp.ctx.Stack.Size = stackSize
+	gp.ctx.Link = nil
+	c.Makecontext(&gp.ctx, goentry, 0)
+	racerelease(unsafe.Pointer(gp)) // the go statement happens before gp runs
+	ready(gp)
+}
+
+func goentry() {
+	gp := getm().curg
+	gp.fn(gp.arg)
+	gp.dead = true
+	c.Setcontext(&getm().g0.ctx)
+}
+
+// mstart is the start routine of the thread of an M.
+func mstart(arg c.Pointer) c.Pointer {
+	curm = (*m)(arg)
+	schedule(curm)
+	return nil
+}
+
+// schedule runs goroutines on g0 of mp forever.
+func schedule(mp *m) {
+	for {
+		gp := findRunnable(mp)
+		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&mp.curg)), unsafe.Pointer(gp))
+		atomic.AddUint32(&mp.schedtick, 1)
+		switchStack(gp.stack)
+		// gp continues what it did on the M that ran it before, which the race
+		// detector can't see, as goroutines are queued by atomics.
+		raceacquire(unsafe.Pointer(gp))
+		c.Swapcontext(&mp.g0.ctx, &gp.ctx)
+		racerelease(unsafe.Pointer(gp))
+		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&mp.curg)), nil)
+		switch {
+		case gp.dead:
+			freeStack(gp.stack)
+		case mp.yield:
+			mp.yield = false
+			globrunqput(gp)
+		case mp.unlock != nil:
+			c.PthreadMutexUnlock(mp.unlock)
+			mp.unlock = nil
+		}
+	}
+}
+
+// findRunnable returns a goroutine to be run by mp, waiting for one if there
+// is none.
+func findRunnable(mp *m) *g {
+	pp := mp.p
+	for {
+		if atomic.LoadInt32(&sched.nprocs) <= int32(pp.id) {
+			stopP(mp)
+			continue
+		}
+		if gp := runqget(pp); gp != nil {
+			return gp
+		}
+		if gp := globrunqget(); gp != nil {
+			return gp
+		}
+		if gp := pollNetwork(); gp != nil {
+			return gp
+		}
+		if gp := steal(mp); gp != nil {
+			return gp
+		}
+		c.PthreadMutexLock(&sched.lock)
+		if !hasRunnable() {
+			atomic.AddInt32(&sched.nmidle, 1)
+			checkDeadlock()
+			c.PthreadCondWait(&sched.idle, &sched.lock)
+			atomic.AddInt32(&sched.nmidle, -1)
+		}
+		c.PthreadMutexUnlock(&sched.lock)
+	}
+}
+
+// stopP moves the goroutines of the P of mp, which GOMAXPROCS stopped, to the
+// global run queue, and waits until the P is started again.
+func stopP(mp *m) {
+	pp := mp.p
+	for gp := runqget(pp); gp != nil; gp = runqget(pp) {
+		globrunqput(gp)
+	}
+	c.PthreadMutexLock(&sched.lock)
+	for atomic.LoadInt32(&sched.nprocs) <= int32(pp.id) {
+		atomic.AddInt32(&sched.nmidle, 1)
+		checkDeadlock()
+		c.PthreadCondWait(&sched.idle, &sched.lock)
+		atomic.AddInt32(&sched.nmidle, -1)
+	}
+	c.PthreadMutexUnlock(&sched.lock)
+}
+
+// checkDeadlock aborts the program if all Ms are idle, no goroutine is
+// runnable, no timer is pending, no signal is notified and the main goroutine
+// waits, as nothing can wake it, unless C may call Go on threads that the
+// runtime didn't create. It must be called with sched.lock held.
+func checkDeadlock() {
+	if sched.nmidle == atomic.LoadInt32(&sched.nm) && sched.mainWaiting != 0 && atomic.LoadInt32(&netpollWaiters) == 0 &&
+		atomic.LoadInt32(&timers.n) == 0 && atomic.LoadInt32(&sigNotifying) == 0 && atomic.LoadInt32(&sched.callbacks) == 0 &&
+		!hasRunnable() {
+		fatal("all goroutines are asleep - deadlock!")
+	}
+}
+
+// hasRunnable reports whether a run queue isn't empty. It must be called with
+// sched.lock held.
+func hasRunnable() bool {
+	if sched.runq.n > 0 {
+		return true
+	}
+	for i := int32(0); i < atomic.LoadInt32(&sched.nm); i++ {
+		pp := sched.allp[i]
+		if atomic.LoadUint32(&pp.runqtail) != atomic.LoadUint32(&pp.runqhead) {
+			return true
+		}
+	}
+	return false
+}
+
+// ready makes gp runnable.
+func ready(gp *g) {
+	if pk := gp.park; pk != nil {
+		c.PthreadMutexLock(&pk.lock)
+		pk.ready = true
+		if gp == sched.mainG {
+			atomic.StoreInt32(&sched.mainWaiting, 0)
+		}
+		c.PthreadCondSignal(&pk.cond)
+		c.PthreadMutexUnlock(&pk.lock)
+		return
+	}
+	mp := getm()
+	if mp == nil || mp.p == nil || atomic.LoadInt32(&sched.nprocs) <= int32(mp.p.id) {
+		globrunqput(gp)
+		return
+	}
+	runqput(mp.p, gp)
+	wakep()
+}
+
+// wakep wakes the idle Ms, if any, to run goroutines that were made runnable.
+func wakep() {
+	if atomic.LoadInt32(&sched.nmidle) > 0 {
+		c.PthreadMutexLock(&sched.lock)
+		c.PthreadCondBroadcast(&sched.idle)
+		c.PthreadMutexUnlock(&sched.lock)
+	}
+}
+
+func globrunqput(gp *g) {
+	c.PthreadMutexLock(&sched.lock)
+	sched.runq.push(gp)
+	c.PthreadCondBroadcast(&sched.idle)
+	c.PthreadMutexUnlock(&sched.lock)
+}
+
+func globrunqget() *g {
+	c.PthreadMutexLock(&sched.lock)
+	gp := sched.runq.pop()
+	c.PthreadMutexUnlock(&sched.lock)
+	return gp
+}
+
+// pollNetwork puts the goroutines that netpoll returns, but the first one, to
+// the global run queue, and returns the first one.
+func pollNetwork() *g {
+	if netpoll == nil {
+		return nil
+	}
+	gp := netpoll()
+	if gp != nil {
+		for next := gp.link; next != nil; {
+			ngp := next
+			next = next.link
+			globrunqput(ngp)
+		}
+		gp.link = nil
+	}
+	return gp
+}
+
+// runqput puts gp to the local run queue of pp, or to the global one if it's
+// full. It must be called by the M of pp.
+func runqput(pp *p, gp *g) {
+	for {
+		h := atomic.LoadUint32(&pp.runqhead)
+		t := pp.runqtail
+		if t-h < runqSize {
+			pp.runq[t%runqSize] = gp
+			atomic.StoreUint32(&pp.runqtail, t+1)
+			return
+		}
+		if runqputslow(pp, gp, h, t) {
+			return
+		}
+	}
+}
+
+// runqputslow moves gp and half of the local run queue of pp to the global
+// one. It fails if other Ms took goroutines from the queue meanwhile.
+func runqputslow(pp *p, gp *g, h, t uint32) bool {
+	var batch [runqSize/2 + 1]*g
+	n := (t - h) / 2
+	for i := uint32(0); i < n; i++ {
+		batch[i] = pp.runq[(h+i)%runqSize]
+	}
+	if !atomic.CompareAndSwapUint32(&pp.runqhead, h, h+n) {
+		return false
+	}
+	batch[n] = gp
+	c.PthreadMutexLock(&sched.lock)
+	for i := uint32(0); i <= n; i++ {
+		sched.runq.push(batch[i])
+	}
+	c.PthreadCondBroadcast(&sched.idle)
+	c.PthreadMutexUnlock(&sched.lock)
+	return true
+}
+
+// runqget takes a goroutine from the local run queue of pp. It must be called
+// by the M of pp.
+func runqget(pp *p) *g {
+	for {
+		h := atomic.LoadUint32(&pp.runqhead)
+		t := pp.runqtail
+		if t == h {
+			return nil
+		}
+		gp := pp.runq[h%runqSize]
+		if atomic.CompareAndSwapUint32(&pp.runqhead, h, h+1) {
+			return gp
+		}
+	}
+}
+
+// steal steals half of the goroutines of the run queue of another P, trying
+// the Ps in a random order, and puts them to the empty run queue of mp.
+func steal(mp *m) *g {
+	n := uint32(atomic.LoadInt32(&sched.nprocs))
+	mp.fastrand ^= mp.fastrand << 13
+	mp.fastrand ^= mp.fastrand >> 17
+	mp.fastrand ^= mp.fastrand << 5
+	start := mp.fastrand % n
+	for i := uint32(0); i < n; i++ {
+		victim := sched.allp[(start+i)%n]
+		if victim == mp.p {
+			continue
+		}
+		if gp := runqsteal(mp.p, victim); gp != nil {
+			return gp
+		}
+	}
+	return nil
+}
+
+// runqsteal moves half of the goroutines of the run queue of victim to the
+// one of pp, which must be empty, and returns one of them.
+func runqsteal(pp, victim *p) *g {
+	t := pp.runqtail
+	for {
+		h := atomic.LoadUint32(&victim.runqhead)
+		vt := atomic.LoadUint32(&victim.runqtail)
+		n := vt - h
+		n -= n / 2
+		if n == 0 {
+			return nil
+		}
+		if n > runqSize/2 { // h and vt are inconsistent
+			continue
+		}
+		for i := uint32(0); i < n; i++ {
+			pp.runq[(t+i)%runqSize] = victim.runq[(h+i)%runqSize]
+		}
+		if atomic.CompareAndSwapUint32(&victim.runqhead, h, h+n) {
+			n--
+			gp := pp.runq[(t+n)%runqSize]
+			if n > 0 {
+				atomic.StoreUint32(&pp.runqtail, t+n)
+			}
+			return gp
+		}
+	}
+}
+
+// Gosched yields the processor, allowing other goroutines to run.
+func Gosched() {
+	mp := getm()
+	if mp == nil || mp.p == nil { // the goroutines of threadParks don't use a processor
+		return
+	}
+	mp.yield = true
+	c.Swapcontext(&mp.curg.ctx, &mp.g0.ctx)
+}
+
+// Preempt yields the processor if sysmon requested it. It is called in the
+// prologue of functions when preemptFlag is set.
+//
+//llgo:inline
+func Preempt() {
+	if mp := getm(); mp != nil && atomic.SwapUint32(&mp.preempt, 0) != 0 {
+		Gosched()
+	}
+}
+
+// sysmon requests the preemption of goroutines that have run for more than a
+// time slice while other goroutines are runnable, and polls the network.
+func sysmon(arg c.Pointer) c.Pointer {
+	for {
+		c.Usleep(timeSlice)
+		if gp := pollNetwork(); gp != nil {
+			globrunqput(gp)
+		}
+		c.PthreadMutexLock(&sched.lock)
+		waiting := hasRunnable()
+		c.PthreadMutexUnlock(&sched.lock)
+		var flag uint32
+		for i := int32(0); i < atomic.LoadInt32(&sched.nm); i++ {
+			mp := sched.allm[i]
+			tick := atomic.LoadUint32(&mp.schedtick)
+			running := atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&mp.curg))) != nil
+			if waiting && running && tick == mp.sysmontick {
+				atomic.StoreUint32(&mp.preempt, 1)
+				flag = 1
+			}
+			mp.sysmontick = tick
+		}
+		atomic.StoreUint32(&preemptFlag, flag)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// waitq is a queue of goroutines waiting for an event, eg. a channel being
+// ready. It is protected by the mutex of its owner.
+type waitq struct {
+	q gQueue
+}
+
+// wait parks the current goroutine in q until wakeAll is called. mutex, which
+// must be held, is unlocked while the goroutine is parked and locked again
+// before wait returns, as with pthread_cond_wait.
+func (q *waitq) wait(mutex *c.PthreadMutex) {
+	if !schedInited() { // there is no other goroutine to wake the main one
+		c.PthreadMutexUnlock(mutex)
+		fatal("all goroutines are asleep - deadlock!")
+	}
+	mp := getm()
+	if mp != nil && mp.p != nil {
+		q.q.push(mp.curg)
+		mp.unlock = mutex
+		c.Swapcontext(&mp.curg.ctx, &mp.g0.ctx)
+		c.PthreadMutexLock(mutex)
+		return
+	}
+	gp := getg()
+	pk := gp.park
+	q.q.push(gp)
+	c.PthreadMutexLock(&pk.lock)
+	c.PthreadMutexUnlock(mutex)
+	if gp == sched.mainG {
+		c.PthreadMutexLock(&sched.lock)
+		if !pk.ready {
+			sched.mainWaiting = 1
+			checkDeadlock()
+		}
+		c.PthreadMutexUnlock(&sched.lock)
+	}
+	for !pk.ready {
+		c.PthreadCondWait(&pk.cond, &pk.lock)
+	}
+	pk.ready = false
+	c.PthreadMutexUnlock(&pk.lock)
+	c.PthreadMutexLock(mutex)
+}
+
+// wakeAll makes the goroutines waiting in q runnable. It must be called with
+// the mutex of q held.
+func (q *waitq) wakeAll() {
+	for gp := q.q.pop(); gp != nil; gp = q.q.pop() {
+		ready(gp)
+	}
+}
+
+// -----------------------------------------------------------------------------
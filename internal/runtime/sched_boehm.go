@@ -0,0 +1,66 @@
+//go:build gc.boehm && !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The collector scans the stacks of the threads it knows, from their stack
+// pointers. Threads of the scheduler are created by the collector, and the
+// stacks of goroutines are roots, as they aren't scanned when the goroutines
+// don't run. When a thread runs a goroutine, the stack of the goroutine is
+// the alternate stack of the thread, which is only changed on g0, on the
+// stack of the thread, so that the collector can't see a stack pointer that
+// is in none of them.
+
+func newThread(th *c.Pthread, start c.ThreadRoutine, arg c.Pointer) c.Int {
+	return c.GCPthreadCreate(th, nil, start, arg)
+}
+
+func newStack() c.Pointer {
+	stack := mapStack()
+	c.GCAddRoots(stack, unsafe.Add(stack, stackSize))
+	return stack
+}
+
+func freeStack(stack c.Pointer) {
+	c.GCRemoveRoots(stack, unsafe.Add(stack, stackSize))
+	unmapStack(stack)
+}
+
+func switchStack(stack c.Pointer) {
+	c.GCRegisterAltstack(nil, 0, stack, stackSize)
+}
+
+func gcAllowRegisterThreads() {
+	c.GCAllowRegisterThreads()
+}
+
+func gcRegisterThread() {
+	if c.GCThreadIsRegistered() != 0 {
+		return
+	}
+	var sb c.GCStackBase
+	if c.GCGetStackBase(&sb) != c.GCSuccess || c.GCRegisterMyThread(&sb) != c.GCSuccess {
+		fatal("can't register a thread to the garbage collector")
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !gc.boehm && !gc.precise && !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "github.com/goplus/llgo/internal/runtime/c"
+
+// newThread starts a thread of the scheduler.
+func newThread(th *c.Pthread, start c.ThreadRoutine, arg c.Pointer) c.Int {
+	return c.PthreadCreate(th, nil, start, arg)
+}
+
+// newStack allocates the stack of a goroutine.
+func newStack() c.Pointer {
+	return mapStack()
+}
+
+// freeStack frees the stack of a goroutine that exited.
+func freeStack(stack c.Pointer) {
+	unmapStack(stack)
+}
+
+// switchStack is called on g0 before switching to a goroutine that runs on
+// stack.
+func switchStack(stack c.Pointer) {}
+
+// gcAllowRegisterThreads is called before a thread that the runtime didn't
+// create may run Go code, eg. a callback of C (see EnterCallback).
+func gcAllowRegisterThreads() {}
+
+// gcRegisterThread registers the calling thread, which the runtime may not
+// have created, to the collector, so that it scans its stack.
+func gcRegisterThread() {}
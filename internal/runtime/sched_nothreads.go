@@ -0,0 +1,83 @@
+//go:build wasip1 || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// There are no threads on wasip1 and baremetal targets, so there is no
+// scheduler, and only the main goroutine runs.
+
+var preemptFlag uint32
+
+// Go creates a goroutine that calls fn(arg). It implements the go statement.
+func Go(fn func(unsafe.Pointer), arg unsafe.Pointer) {
+	fatal("goroutines are not supported on this target")
+}
+
+// GOMAXPROCS returns 1, the number of threads that run goroutines.
+func GOMAXPROCS(n int) int {
+	return 1
+}
+
+// Gosched yields the processor, allowing other goroutines to run.
+func Gosched() {}
+
+// Preempt yields the processor if it was requested.
+//
+//llgo:inline
+func Preempt() {}
+
+// grand returns the state of the PRNG of the current goroutine.
+func grand() *uint32 {
+	if mainRand == 0 {
+		mainRand = newRand(0)
+	}
+	return &mainRand
+}
+
+// randSeed returns the seed of the PRNG of the main goroutine, which is fixed,
+// as there may be no source of entropy on these targets.
+func randSeed() uint32 {
+	return 1
+}
+
+// waitq is a queue of goroutines waiting for an event, eg. a channel being
+// ready.
+type waitq struct{}
+
+// wait parks the current goroutine until wakeAll is called, which can't
+// happen as it is the only one.
+func (q *waitq) wait(mutex *c.PthreadMutex) {
+	c.PthreadMutexUnlock(mutex)
+	fatal("all goroutines are asleep - deadlock!")
+}
+
+// wakeAll makes the goroutines waiting in q runnable.
+func (q *waitq) wakeAll() {}
+
+// EnableCallbacks is called before a func value is passed to C, which can't
+// call it on other threads on these targets.
+func EnableCallbacks() {}
+
+// EnterCallback is called by the wrappers of func values that C calls.
+func EnterCallback() {}
@@ -0,0 +1,41 @@
+//go:build gc.precise && !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "github.com/goplus/llgo/internal/runtime/c"
+
+// The precise collector only scans the stack of the thread that allocates, so
+// goroutines aren't supported with it yet.
+
+func newThread(th *c.Pthread, start c.ThreadRoutine, arg c.Pointer) c.Int {
+	return c.PthreadCreate(th, nil, start, arg)
+}
+
+func newStack() c.Pointer {
+	fatal("goroutines are not supported by the precise garbage collector")
+	return nil
+}
+
+func freeStack(stack c.Pointer) {}
+
+func switchStack(stack c.Pointer) {}
+
+func gcAllowRegisterThreads() {}
+
+func gcRegisterThread() {}
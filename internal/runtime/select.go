@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// A select statement that has a default case and several communication cases
+// is compiled to a call to Select, and one without default case to a call to
+// SelectBlock (see llssa.Builder.Select). As with gc, the
+// cases are tried in a pseudo-random order, so that a program doesn't depend
+// on a case being chosen because of its position, and no case starves the
+// others. The order is drawn from a PRNG of the current goroutine, whose seed
+// is derived from the environment variable LLGO_SELECTSEED if it is set: the
+// goroutines of a program then choose the same cases each time it runs, as
+// long as they are created in the same order, eg. to reproduce a test.
+//
+// The channels of the cases are locked, in the order of their addresses so
+// that selects don't deadlock each other, while the cases are tried and, in
+// SelectBlock if none is ready, while the select is put in the sendqs and the
+// recvqs of the channels as a waiter: the first goroutine that claims it
+// completes its case, handing the value off directly, and the select is then
+// removed from the other channels.
+
+// SelectCase is a communication case of a select statement: a send of the
+// value pointed to by val to ch, or a receive from ch into val.
+type SelectCase struct {
+	ch      *Chan
+	val     unsafe.Pointer
+	eltSize int
+	send    bool
+}
+
+// Select performs one of the n cases that are ready, without blocking. It
+// returns the index of the case, or -1 if no case is ready, and whether the
+// value it received was delivered by a send.
+func Select(cases *SelectCase, n int) (selected int, recvOK bool) {
+	var buf [32]int
+	order, lockorder := selectOrder(cases, n, buf[:])
+	selectLock(cases, lockorder)
+	selected, recvOK = selectTry(cases, order)
+	selectUnlock(cases, lockorder)
+	return
+}
+
+// SelectBlock performs one of the n cases, blocking until one is ready. It
+// returns the index of the case, and whether the value it received was
+// delivered by a send.
+func SelectBlock(cases *SelectCase, n int) (selected int, recvOK bool) {
+	var buf [32]int
+	order, lockorder := selectOrder(cases, n, buf[:])
+	selectLock(cases, lockorder)
+	if selected, recvOK = selectTry(cases, order); selected >= 0 {
+		selectUnlock(cases, lockorder)
+		return
+	}
+	w := newWaiter()
+	sgs := unsafe.Slice((*sudog)(AllocZ(uintptr(n)*unsafe.Sizeof(sudog{}))), n)
+	for i := 0; i < n; i++ {
+		if cs := selectCase(cases, i); cs.ch != nil {
+			sg := &sgs[i]
+			sg.w, sg.elem, sg.idx = w, cs.val, i
+			if cs.send {
+				cs.ch.sendq.push(sg)
+			} else {
+				cs.ch.recvq.push(sg)
+			}
+		}
+	}
+	selectUnlock(cases, lockorder)
+	w.park()
+	selectLock(cases, lockorder)
+	for i := 0; i < n; i++ {
+		if cs := selectCase(cases, i); cs.ch == nil {
+			continue
+		} else if cs.send {
+			cs.ch.sendq.remove(&sgs[i])
+		} else {
+			cs.ch.recvq.remove(&sgs[i])
+		}
+	}
+	selectUnlock(cases, lockorder)
+	selected = int(w.state) - 1
+	cs := selectCase(cases, selected)
+	if cs.send {
+		if !w.recvOK {
+			fatal("send on closed channel")
+		}
+		return selected, false
+	}
+	raceacquire(unsafe.Pointer(cs.ch))
+	if !w.recvOK {
+		c.Memset(cs.val, 0, uintptr(cs.eltSize))
+	}
+	return selected, w.recvOK
+}
+
+// selectOrder returns the order in which the n cases are tried, a
+// pseudo-random permutation, and the order in which their channels are
+// locked, by address, without nil and duplicated channels. They are sliced
+// from buf if it is large enough.
+func selectOrder(cases *SelectCase, n int, buf []int) (order, lockorder []int) {
+	if 2*n > len(buf) {
+		buf = unsafe.Slice((*int)(AllocZ(uintptr(2*n)*unsafe.Sizeof(0))), 2*n)
+	}
+	order, lockorder = buf[:n], buf[n:n]
+	r := grand()
+	for i := 0; i < n; i++ { // a random permutation, by Fisher-Yates
+		j := fastrandn(r, uint32(i+1))
+		order[i] = order[j]
+		order[j] = i
+	}
+	for i := 0; i < n; i++ { // by insertion
+		ch := selectCase(cases, i).ch
+		if ch == nil {
+			continue
+		}
+		j := len(lockorder)
+		for j > 0 && uintptr(unsafe.Pointer(selectCase(cases, lockorder[j-1]).ch)) > uintptr(unsafe.Pointer(ch)) {
+			j--
+		}
+		if j > 0 && selectCase(cases, lockorder[j-1]).ch == ch {
+			continue
+		}
+		lockorder = lockorder[:len(lockorder)+1]
+		copy(lockorder[j+1:], lockorder[j:])
+		lockorder[j] = i
+	}
+	return
+}
+
+func selectLock(cases *SelectCase, lockorder []int) {
+	for _, i := range lockorder {
+		c.PthreadMutexLock(&selectCase(cases, i).ch.mutex)
+	}
+}
+
+func selectUnlock(cases *SelectCase, lockorder []int) {
+	for _, i := range lockorder {
+		c.PthreadMutexUnlock(&selectCase(cases, i).ch.mutex)
+	}
+}
+
+// selectTry performs the first case in order that is ready, with the channels
+// of the cases locked. It returns its index, or -1 if no case is ready, and
+// whether the value it received was delivered by a send.
+func selectTry(cases *SelectCase, order []int) (selected int, recvOK bool) {
+	for _, i := range order {
+		cs := selectCase(cases, i)
+		if cs.ch == nil {
+			continue
+		}
+		if cs.send {
+			if cs.ch.trySend(cs.val, cs.eltSize) {
+				return i, false
+			}
+		} else if ok, recvOK := cs.ch.tryRecv(cs.val, cs.eltSize); ok {
+			return i, recvOK
+		}
+	}
+	return -1, false
+}
+
+// selectCase returns the case i of cases.
+func selectCase(cases *SelectCase, i int) *SelectCase {
+	return (*SelectCase)(unsafe.Add(unsafe.Pointer(cases), uintptr(i)*unsafe.Sizeof(SelectCase{})))
+}
+
+// mainRand is the state of the PRNG of the main goroutine, or 0 until it is
+// seeded.
+var mainRand uint32
+
+// randSeq numbers the goroutines whose PRNGs are seeded, from 1, as the main
+// goroutine is numbered 0.
+var randSeq uint32
+
+// newRand returns the initial state of the PRNG of the goroutine seq.
+func newRand(seq uint32) uint32 {
+	x := (randSeed() + seq) * 2654435761
+	if x == 0 { // the state of a xorshift generator must not be 0
+		x = 1
+	}
+	return x
+}
+
+// fastrandn returns a pseudo-random number in [0, n) from the xorshift
+// generator of state r.
+func fastrandn(r *uint32, n uint32) uint32 {
+	x := *r
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	*r = x
+	return uint32(uint64(x) * uint64(n) >> 32)
+}
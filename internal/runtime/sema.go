@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// -----------------------------------------------------------------------------
+
+// Semaphores are counters that goroutines wait for to be positive, as the ones
+// of the Go runtime. Their waiters are kept in a table of waitqs, which is
+// indexed by a hash of their address, so that a semaphore is a single uint32.
+
+const semTabSize = 251
+
+type semaRoot struct {
+	lock  c.PthreadMutex
+	waitq waitq
+}
+
+var (
+	semtable     [semTabSize]semaRoot
+	semtableInit uint32 // 0: not initialized, 1: being initialized, 2: initialized
+)
+
+func semroot(addr *uint32) *semaRoot {
+	if atomic.LoadUint32(&semtableInit) != 2 {
+		if atomic.CompareAndSwapUint32(&semtableInit, 0, 1) {
+			for i := 0; i < semTabSize; i++ {
+				c.PthreadMutexInit(&semtable[i].lock, nil)
+			}
+			atomic.StoreUint32(&semtableInit, 2)
+		}
+		for atomic.LoadUint32(&semtableInit) != 2 {
+		}
+	}
+	return &semtable[(uintptr(unsafe.Pointer(addr))>>3)%semTabSize]
+}
+
+func cansemacquire(addr *uint32) bool {
+	for {
+		v := atomic.LoadUint32(addr)
+		if v == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(addr, v, v-1) {
+			return true
+		}
+	}
+}
+
+// semacquire waits until *addr > 0 and then decrements it.
+func semacquire(addr *uint32) {
+	if cansemacquire(addr) {
+		return
+	}
+	root := semroot(addr)
+	c.PthreadMutexLock(&root.lock)
+	for !cansemacquire(addr) {
+		root.waitq.wait(&root.lock)
+	}
+	c.PthreadMutexUnlock(&root.lock)
+}
+
+// semrelease increments *addr and wakes its waiters. As semaphores share
+// waitqs, all the waiters of the waitq of addr are woken.
+func semrelease(addr *uint32) {
+	atomic.AddUint32(addr, 1)
+	root := semroot(addr)
+	c.PthreadMutexLock(&root.lock)
+	root.waitq.wakeAll()
+	c.PthreadMutexUnlock(&root.lock)
+}
+
+// -----------------------------------------------------------------------------
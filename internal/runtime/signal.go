@@ -0,0 +1,291 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The os/signal package isn't compiled: the compiler turns calls to
+// signal.Notify, Stop, Ignore and Reset into calls to the functions below,
+// os.Interrupt and os.Kill into the variables below, and the String methods of
+// os.Signal and syscall.Signal into SignalString and SyscallSignalString.
+//
+// The handler of a notified signal only records it as pending, and wakes the
+// thread that delivers signals by a pipe, as it can't lock the channels. The
+// thread sends the pending signals to the channels that they are notified to,
+// without blocking, as Go does: a signal is dropped for a channel that isn't
+// ready to receive it. Signals are delivered as Signal values.
+
+// Signal is the runtime representation of syscall.Signal.
+type Signal int
+
+// Interrupt and Kill implement os.Interrupt and os.Kill. They are set by
+// OsInit.
+var Interrupt, Kill any
+
+// sigWords is the number of words of a set of signals.
+const sigWords = (c.NSIG + 31) / 32
+
+// sigSet is a set of signals.
+type sigSet [sigWords]uint32
+
+func (s *sigSet) has(sig int) bool {
+	return s[sig/32]&(1<<(sig%32)) != 0
+}
+
+func (s *sigSet) add(sig int) {
+	s[sig/32] |= 1 << (sig % 32)
+}
+
+func (s *sigSet) remove(sig int) {
+	s[sig/32] &^= 1 << (sig % 32)
+}
+
+// sigNotify is a channel that signals are notified to.
+type sigNotify struct {
+	ch   *Chan
+	sigs sigSet
+	next *sigNotify
+}
+
+var sigs struct {
+	lock     c.PthreadMutex // protects notifies and handled
+	notifies *sigNotify
+	handled  sigSet // signals whose handler is notifySignal
+	pending  sigSet // accessed atomically by notifySignal
+	pipe     [2]c.Int
+	inited   bool
+}
+
+// sigNotifying is the number of channels that signals are notified to, which
+// the main goroutine may wait for without being deadlocked; accessed
+// atomically.
+var sigNotifying int32
+
+// lockSigs locks sigs, initializing them first if needed.
+func lockSigs() {
+	if !schedInited() {
+		schedinit()
+	}
+	c.PthreadMutexLock(&sched.lock)
+	if !sigs.inited {
+		siginit()
+	}
+	c.PthreadMutexUnlock(&sched.lock)
+	c.PthreadMutexLock(&sigs.lock)
+}
+
+// siginit starts the thread that delivers signals. It is called with
+// sched.lock held.
+func siginit() {
+	c.PthreadMutexInit(&sigs.lock, nil)
+	if c.Pipe(&sigs.pipe) != 0 {
+		fatal("can't create the pipe of signals")
+	}
+	var th c.Pthread
+	if newThread(&th, sigDeliver, nil) != 0 {
+		fatal("can't create the thread of signals")
+	}
+	sigs.inited = true
+}
+
+// sigNumbers returns the numbers of the signals ss, or of all signals if ss
+// is empty, but the ones that can't be notified: SIGKILL, and the signals of
+// fatal errors.
+func sigNumbers(ss []any) (ret sigSet) {
+	if len(ss) == 0 {
+		for sig := 1; sig < c.NSIG; sig++ {
+			ret.add(sig)
+		}
+	}
+	for _, s := range ss {
+		e := (*eface)(unsafe.Pointer(&s))
+		if e.typ == nil {
+			continue
+		}
+		if sig := *(*int)(e.data); sig > 0 && sig < c.NSIG {
+			ret.add(sig)
+		}
+	}
+	for _, sig := range [...]int{c.SIGKILL, c.SIGILL, c.SIGFPE, c.SIGSEGV, c.SIGBUS} {
+		ret.remove(sig)
+	}
+	return
+}
+
+// SignalNotify implements signal.Notify.
+func SignalNotify(ch *Chan, ss []any) {
+	if ch == nil {
+		fatal("os/signal: Notify using nil channel")
+	}
+	set := sigNumbers(ss)
+	lockSigs()
+	n := sigs.notifies
+	for n != nil && n.ch != ch {
+		n = n.next
+	}
+	if n == nil {
+		n = (*sigNotify)(AllocZ(unsafe.Sizeof(sigNotify{})))
+		n.ch, n.next = ch, sigs.notifies
+		sigs.notifies = n
+		atomic.AddInt32(&sigNotifying, 1)
+	}
+	for sig := 1; sig < c.NSIG; sig++ {
+		if !set.has(sig) {
+			continue
+		}
+		n.sigs.add(sig)
+		if !sigs.handled.has(sig) {
+			sigs.handled.add(sig)
+			c.Signal(c.Int(sig), notifySignal)
+		}
+	}
+	c.PthreadMutexUnlock(&sigs.lock)
+}
+
+// SignalStop implements signal.Stop.
+func SignalStop(ch *Chan) {
+	lockSigs()
+	for pn := &sigs.notifies; *pn != nil; pn = &(*pn).next {
+		if (*pn).ch == ch {
+			*pn = (*pn).next
+			atomic.AddInt32(&sigNotifying, -1)
+			break
+		}
+	}
+	c.PthreadMutexUnlock(&sigs.lock)
+}
+
+// SignalIgnore implements signal.Ignore.
+func SignalIgnore(ss []any) {
+	sigReset(sigNumbers(ss), ignoreSignal)
+}
+
+// SignalReset implements signal.Reset.
+func SignalReset(ss []any) {
+	sigReset(sigNumbers(ss), nil)
+}
+
+// sigReset stops notifying the signals of set, whose handler becomes handler,
+// or the default action if it's nil.
+func sigReset(set sigSet, handler c.SigHandler) {
+	lockSigs()
+	for n := sigs.notifies; n != nil; n = n.next {
+		for sig := 1; sig < c.NSIG; sig++ {
+			if set.has(sig) {
+				n.sigs.remove(sig)
+			}
+		}
+	}
+	for sig := 1; sig < c.NSIG; sig++ {
+		if set.has(sig) && (sigs.handled.has(sig) || handler != nil) {
+			sigs.handled.remove(sig)
+			c.Signal(c.Int(sig), handler)
+		}
+	}
+	c.PthreadMutexUnlock(&sigs.lock)
+}
+
+// notifySignal is the handler of notified signals. It mustn't allocate nor
+// lock.
+func notifySignal(sig c.Int) {
+	w := &sigs.pending[sig/32]
+	for {
+		old := atomic.LoadUint32(w)
+		if atomic.CompareAndSwapUint32(w, old, old|1<<(sig%32)) {
+			break
+		}
+	}
+	var b byte
+	c.Write(sigs.pipe[1], c.Pointer(&b), 1)
+}
+
+// ignoreSignal is the handler of ignored signals.
+func ignoreSignal(sig c.Int) {}
+
+// sigDeliver is the start routine of the thread that delivers the pending
+// signals to the channels that they are notified to.
+func sigDeliver(arg c.Pointer) c.Pointer {
+	var buf [16]byte
+	for {
+		if c.Read(sigs.pipe[0], c.Pointer(&buf[0]), uintptr(len(buf))) <= 0 && c.Errno() != c.EINTR {
+			fatal("can't read the pipe of signals")
+		}
+		var pending sigSet
+		for i := range pending {
+			pending[i] = atomic.SwapUint32(&sigs.pending[i], 0)
+		}
+		c.PthreadMutexLock(&sigs.lock)
+		for sig := 1; sig < c.NSIG; sig++ {
+			if !pending.has(sig) {
+				continue
+			}
+			v := any(Signal(sig))
+			for n := sigs.notifies; n != nil; n = n.next {
+				if n.sigs.has(sig) {
+					ChanTrySend(n.ch, unsafe.Pointer(&v), int(unsafe.Sizeof(v)))
+				}
+			}
+		}
+		c.PthreadMutexUnlock(&sigs.lock)
+	}
+}
+
+// sigNames are the names of the signals that are the same on all supported
+// platforms, as Go names them.
+var sigNames = [...]string{
+	c.SIGHUP:  "hangup",
+	c.SIGINT:  "interrupt",
+	c.SIGQUIT: "quit",
+	c.SIGILL:  "illegal instruction",
+	c.SIGTRAP: "trace/breakpoint trap",
+	c.SIGABRT: "aborted",
+	c.SIGFPE:  "floating point exception",
+	c.SIGKILL: "killed",
+	c.SIGSEGV: "segmentation fault",
+	c.SIGPIPE: "broken pipe",
+	c.SIGALRM: "alarm clock",
+	c.SIGTERM: "terminated",
+}
+
+var sigFormat = [...]c.Char{'s', 'i', 'g', 'n', 'a', 'l', ' ', '%', 'd', 0}
+
+// SyscallSignalString implements syscall.Signal.String.
+func SyscallSignalString(s Signal) string {
+	if s > 0 && int(s) < len(sigNames) && sigNames[s] != "" {
+		return sigNames[s]
+	}
+	if s == c.SIGBUS {
+		return "bus error"
+	}
+	var buf [32]c.Char
+	c.Snprintf(&buf[0], uintptr(len(buf)), &sigFormat[0], c.Int(s))
+	return gostring(&buf[0])
+}
+
+// SignalString implements os.Signal.String, for the signals that are
+// syscall.Signals.
+func SignalString(s any) string {
+	e := (*eface)(unsafe.Pointer(&s))
+	return SyscallSignalString(*(*Signal)(e.data))
+}
@@ -0,0 +1,54 @@
+//go:build wasip1 || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+// There are no signals on wasip1 and baremetal targets, so the functions of
+// the os/signal package that the runtime implements aren't supported.
+
+// Signal is the runtime representation of syscall.Signal.
+type Signal int
+
+// Interrupt and Kill implement os.Interrupt and os.Kill.
+var Interrupt, Kill any
+
+// SignalNotify implements signal.Notify.
+func SignalNotify(ch *Chan, ss []any) {
+	fatal("signals are not supported on this target")
+}
+
+// SignalStop implements signal.Stop.
+func SignalStop(ch *Chan) {}
+
+// SignalIgnore implements signal.Ignore.
+func SignalIgnore(ss []any) {}
+
+// SignalReset implements signal.Reset.
+func SignalReset(ss []any) {}
+
+// SyscallSignalString implements syscall.Signal.String.
+func SyscallSignalString(s Signal) string {
+	fatal("signals are not supported on this target")
+	return ""
+}
+
+// SignalString implements os.Signal.String.
+func SignalString(s any) string {
+	fatal("signals are not supported on this target")
+	return ""
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// -----------------------------------------------------------------------------
+
+// The builtins append and copy are compiled to calls of SliceAppend and
+// SliceCopy (see llssa.Builder.BuiltinCall), which are passed the elements to
+// append or copy by their address and number, as they may be the bytes of a
+// string, and the size of the elements.
+
+// SliceAppend returns append(s, x...), where x are the num elements of etSize
+// bytes at data. If the capacity of s is too small, the elements of s and x
+// are copied to a new underlying array, which grows as the one of gc does.
+func SliceAppend(s []byte, data unsafe.Pointer, num, etSize int) []byte {
+	if num == 0 {
+		return s
+	}
+	h := (*sliceHeader)(unsafe.Pointer(&s))
+	n := h.len + num
+	if n > h.cap {
+		newcap := growCap(h.cap, n)
+		p := AllocZ(uintptr(newcap * etSize))
+		c.Memcpy(p, h.data, uintptr(h.len*etSize))
+		h.data, h.cap = p, newcap
+	}
+	c.Memmove(unsafe.Add(h.data, h.len*etSize), data, uintptr(num*etSize))
+	h.len = n
+	return s
+}
+
+// growCap returns the capacity of the underlying array of a slice of capacity
+// old that grows to n elements: twice the capacity, and 1.25 times plus 192
+// elements from 256 elements, as gc does, or n if it is larger.
+func growCap(old, n int) int {
+	if n > old+old {
+		return n
+	}
+	const threshold = 256
+	if old < threshold {
+		return old + old
+	}
+	newcap := old
+	for newcap < n {
+		newcap += (newcap + 3*threshold) >> 2
+	}
+	return newcap
+}
+
+// SliceCopy copies min(dstLen, srcLen) elements of etSize bytes from src to
+// dst, which may overlap, and returns their number, as copy(dst, src) does.
+func SliceCopy(dst unsafe.Pointer, dstLen int, src unsafe.Pointer, srcLen, etSize int) int {
+	n := dstLen
+	if srcLen < n {
+		n = srcLen
+	}
+	if n > 0 {
+		c.Memmove(dst, src, uintptr(n*etSize))
+	}
+	return n
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,85 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The stacks of goroutines are reserved by mmap, and the OS commits their
+// pages when they are first touched, so that they grow on demand: a goroutine
+// only uses the memory of the part of its stack that it reached, and stacks
+// can be much larger than the memory that most goroutines need. Guard pages
+// below a stack make a goroutine that overflows it fault, instead of
+// corrupting the memory below. Stacks are configured by environment variables:
+//
+//	LLGO_STACKSIZE   size of a stack in bytes, 1 MiB by default
+//	LLGO_STACKGUARD  number of guard pages below a stack, 1 by default
+//
+// Sizes are rounded up to a multiple of the page size.
+
+const defaultStackSize = 1 << 20
+
+var (
+	stackSize  uintptr // size of a stack, without its guard pages
+	stackGuard uintptr // size of the guard pages of a stack
+)
+
+var (
+	stackSizeEnv  = [...]c.Char{'L', 'L', 'G', 'O', '_', 'S', 'T', 'A', 'C', 'K', 'S', 'I', 'Z', 'E', 0}
+	stackGuardEnv = [...]c.Char{'L', 'L', 'G', 'O', '_', 'S', 'T', 'A', 'C', 'K', 'G', 'U', 'A', 'R', 'D', 0}
+)
+
+// stackinit reads the configuration of stacks. It is called by schedinit.
+func stackinit() {
+	page := uintptr(c.Sysconf(c.ScPagesize))
+	stackSize = defaultStackSize
+	if s := c.Getenv(&stackSizeEnv[0]); s != nil {
+		if n := c.Atoi(s); n > 0 {
+			stackSize = uintptr(n)
+		}
+	}
+	stackSize = (stackSize + page - 1) &^ (page - 1)
+	stackGuard = page
+	if s := c.Getenv(&stackGuardEnv[0]); s != nil {
+		if n := c.Atoi(s); n >= 0 {
+			stackGuard = uintptr(n) * page
+		}
+	}
+}
+
+// mapStack reserves a stack and its guard pages, and returns the lowest
+// address of the stack.
+func mapStack() c.Pointer {
+	mem := c.Mmap(nil, stackGuard+stackSize, c.ProtRead|c.ProtWrite, c.MapPrivate|c.MapAnon|c.MapNoreserve, -1, 0)
+	if uintptr(mem) == ^uintptr(0) {
+		fatal("out of memory")
+	}
+	if stackGuard > 0 && c.Mprotect(mem, stackGuard, c.ProtNone) != 0 {
+		fatal("can't protect the guard pages of a stack")
+	}
+	return unsafe.Add(mem, stackGuard)
+}
+
+// unmapStack releases a stack that mapStack reserved.
+func unmapStack(stack c.Pointer) {
+	c.Munmap(unsafe.Add(stack, -int(stackGuard)), stackGuard+stackSize)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// -----------------------------------------------------------------------------
+
+// The conversions between strings and []byte copy the bytes, as the ones of a
+// string are immutable, unless llgo proves that the result can share them
+// (see llssa.Builder.Convert).
+
+// StringToBytes returns []byte(s), whose bytes are a copy of the ones of s.
+func StringToBytes(s string) []byte {
+	p := AllocZ(uintptr(len(s)))
+	c.Memcpy(p, stringData(s), uintptr(len(s)))
+	return *(*[]byte)(unsafe.Pointer(&sliceHeader{p, len(s), len(s)}))
+}
+
+// BytesToString returns string(b), whose bytes are a copy of the ones of b.
+func BytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	p := AllocZ(uintptr(len(b)))
+	c.Memcpy(p, sliceData(b), uintptr(len(b)))
+	return *(*string)(unsafe.Pointer(&stringHeader{p, len(b)}))
+}
+
+// StringEqual reports whether x == y.
+func StringEqual(x, y string) bool {
+	return len(x) == len(y) && Memequal(stringData(x), stringData(y), uintptr(len(x)))
+}
+
+// StringCompare compares x and y byte by byte, and returns -1 if x < y, 0 if
+// x == y and +1 if x > y.
+func StringCompare(x, y string) int {
+	n := len(x)
+	if len(y) < n {
+		n = len(y)
+	}
+	if r := c.Memcmp(stringData(x), stringData(y), uintptr(n)); r != 0 {
+		if r < 0 {
+			return -1
+		}
+		return +1
+	}
+	switch {
+	case len(x) < len(y):
+		return -1
+	case len(x) > len(y):
+		return +1
+	}
+	return 0
+}
+
+// stringData returns the pointer to the bytes of s.
+func stringData(s string) c.Pointer {
+	return (*stringHeader)(unsafe.Pointer(&s)).data
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// -----------------------------------------------------------------------------
+
+// The sync package isn't compiled: the compiler turns calls to the methods of
+// its Mutex, RWMutex, WaitGroup and Once types into calls to the functions
+// below (see rtIntrinsics in cl), whose types have the same size as the
+// ones of sync and are unlocked when zero. They block on semaphores, so that
+// waiting goroutines are parked rather than their threads. Operations are
+// sequentially consistent atomics, so that an unlock (or a Done, or the
+// return of the function of Once.Do) happens before the lock (or the Wait,
+// or the return of Once.Do) that observes it, also on weakly ordered CPUs,
+// eg. ARM, for which LLVM emits the barriers of sequential consistency. This
+// is annotated for the race detector, which can't see atomics (see
+// raceacquire).
+
+// Mutex is the runtime representation of sync.Mutex.
+type Mutex struct {
+	state int32 // 0: unlocked, 1: locked, 2: locked with possible waiters
+	sema  uint32
+}
+
+// MutexLock implements sync.(*Mutex).Lock.
+//
+//llgo:inline
+func MutexLock(m *Mutex) {
+	if !atomic.CompareAndSwapInt32(&m.state, 0, 1) {
+		for atomic.SwapInt32(&m.state, 2) != 0 {
+			semacquire(&m.sema)
+		}
+	}
+	raceacquire(unsafe.Pointer(m))
+}
+
+// MutexTryLock implements sync.(*Mutex).TryLock.
+//
+//llgo:inline
+func MutexTryLock(m *Mutex) bool {
+	if !atomic.CompareAndSwapInt32(&m.state, 0, 1) {
+		return false
+	}
+	raceacquire(unsafe.Pointer(m))
+	return true
+}
+
+// MutexUnlock implements sync.(*Mutex).Unlock.
+//
+//llgo:inline
+func MutexUnlock(m *Mutex) {
+	racerelease(unsafe.Pointer(m))
+	switch atomic.SwapInt32(&m.state, 0) {
+	case 0:
+		fatal("sync: unlock of unlocked mutex")
+	case 2:
+		semrelease(&m.sema)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+const rwmutexMaxReaders = 1 << 30
+
+// RWMutex is the runtime representation of sync.RWMutex. Its algorithm is the
+// one of sync.
+type RWMutex struct {
+	w           Mutex  // held by the writer
+	writerSem   uint32 // for the writer to wait for readers to complete
+	readerSem   uint32 // for readers to wait for the writer to complete
+	readerCount int32  // number of readers, minus rwmutexMaxReaders if a writer is pending
+	readerWait  int32  // number of departing readers the writer waits for
+}
+
+// RWMutexRLock implements sync.(*RWMutex).RLock.
+func RWMutexRLock(rw *RWMutex) {
+	if atomic.AddInt32(&rw.readerCount, 1) < 0 {
+		semacquire(&rw.readerSem)
+	}
+	raceacquire(unsafe.Pointer(&rw.readerSem))
+}
+
+// RWMutexTryRLock implements sync.(*RWMutex).TryRLock.
+func RWMutexTryRLock(rw *RWMutex) bool {
+	for {
+		n := atomic.LoadInt32(&rw.readerCount)
+		if n < 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&rw.readerCount, n, n+1) {
+			raceacquire(unsafe.Pointer(&rw.readerSem))
+			return true
+		}
+	}
+}
+
+// RWMutexRUnlock implements sync.(*RWMutex).RUnlock.
+func RWMutexRUnlock(rw *RWMutex) {
+	// The writer that waits for the readers acquires writerSem.
+	racerelease(unsafe.Pointer(&rw.writerSem))
+	if r := atomic.AddInt32(&rw.readerCount, -1); r < 0 {
+		if r+1 == 0 || r+1 == -rwmutexMaxReaders {
+			fatal("sync: RUnlock of unlocked RWMutex")
+		}
+		if atomic.AddInt32(&rw.readerWait, -1) == 0 {
+			semrelease(&rw.writerSem)
+		}
+	}
+}
+
+// RWMutexLock implements sync.(*RWMutex).Lock.
+func RWMutexLock(rw *RWMutex) {
+	MutexLock(&rw.w)
+	r := atomic.AddInt32(&rw.readerCount, -rwmutexMaxReaders) + rwmutexMaxReaders
+	if r != 0 && atomic.AddInt32(&rw.readerWait, r) != 0 {
+		semacquire(&rw.writerSem)
+	}
+	raceacquire(unsafe.Pointer(&rw.readerSem))
+	raceacquire(unsafe.Pointer(&rw.writerSem))
+}
+
+// RWMutexTryLock implements sync.(*RWMutex).TryLock.
+func RWMutexTryLock(rw *RWMutex) bool {
+	if !MutexTryLock(&rw.w) {
+		return false
+	}
+	if !atomic.CompareAndSwapInt32(&rw.readerCount, 0, -rwmutexMaxReaders) {
+		MutexUnlock(&rw.w)
+		return false
+	}
+	raceacquire(unsafe.Pointer(&rw.readerSem))
+	raceacquire(unsafe.Pointer(&rw.writerSem))
+	return true
+}
+
+// RWMutexUnlock implements sync.(*RWMutex).Unlock.
+func RWMutexUnlock(rw *RWMutex) {
+	racerelease(unsafe.Pointer(&rw.readerSem))
+	r := atomic.AddInt32(&rw.readerCount, rwmutexMaxReaders)
+	if r >= rwmutexMaxReaders {
+		fatal("sync: Unlock of unlocked RWMutex")
+	}
+	for i := int32(0); i < r; i++ {
+		semrelease(&rw.readerSem)
+	}
+	MutexUnlock(&rw.w)
+}
+
+// -----------------------------------------------------------------------------
+
+// WaitGroup is the runtime representation of sync.WaitGroup.
+type WaitGroup struct {
+	state uint64 // high 32 bits are the counter, low 32 bits are the number of waiters
+	sema  uint32
+}
+
+// WaitGroupAdd implements sync.(*WaitGroup).Add.
+func WaitGroupAdd(wg *WaitGroup, delta int) {
+	if delta < 0 {
+		racerelease(unsafe.Pointer(wg))
+	}
+	state := atomic.AddUint64(&wg.state, uint64(delta)<<32)
+	v := int32(state >> 32)
+	w := uint32(state)
+	if v < 0 {
+		fatal("sync: negative WaitGroup counter")
+	}
+	if w != 0 && delta > 0 && v == int32(delta) {
+		fatal("sync: WaitGroup misuse: Add called concurrently with Wait")
+	}
+	if v > 0 || w == 0 {
+		return
+	}
+	// The counter is 0 and there are waiters, which can't change the state
+	// any more: reset the number of waiters and wake them.
+	if atomic.LoadUint64(&wg.state) != state {
+		fatal("sync: WaitGroup misuse: Add called concurrently with Wait")
+	}
+	atomic.StoreUint64(&wg.state, 0)
+	for ; w != 0; w-- {
+		semrelease(&wg.sema)
+	}
+}
+
+// WaitGroupDone implements sync.(*WaitGroup).Done.
+func WaitGroupDone(wg *WaitGroup) {
+	WaitGroupAdd(wg, -1)
+}
+
+// WaitGroupWait implements sync.(*WaitGroup).Wait.
+func WaitGroupWait(wg *WaitGroup) {
+	for {
+		state := atomic.LoadUint64(&wg.state)
+		if state>>32 == 0 {
+			raceacquire(unsafe.Pointer(wg))
+			return
+		}
+		if atomic.CompareAndSwapUint64(&wg.state, state, state+1) {
+			semacquire(&wg.sema)
+			if atomic.LoadUint64(&wg.state) != 0 {
+				fatal("sync: WaitGroup is reused before previous Wait has returned")
+			}
+			raceacquire(unsafe.Pointer(wg))
+			return
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Once is the runtime representation of sync.Once.
+type Once struct {
+	done uint32
+	m    Mutex
+}
+
+// OnceDo implements sync.(*Once).Do. As in sync.(*Once).doSlow, o is done and
+// unlocked by deferred calls, so that o is done, and the callers of OnceDo
+// blocked on it are released, even if f panics.
+func OnceDo(o *Once, f func()) {
+	if atomic.LoadUint32(&o.done) != 0 {
+		raceacquire(unsafe.Pointer(o))
+		return
+	}
+	MutexLock(&o.m)
+	defer MutexUnlock(&o.m)
+	if o.done == 0 {
+		defer onceDone(o)
+		f()
+	}
+}
+
+// onceDone marks o done, after f of OnceDo returned or panicked.
+func onceDone(o *Once) {
+	racerelease(unsafe.Pointer(o))
+	atomic.StoreUint32(&o.done, 1)
+}
+
+// -----------------------------------------------------------------------------
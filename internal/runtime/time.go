@@ -0,0 +1,268 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// The time package isn't compiled: the compiler turns calls to time.Now,
+// time.Since, time.Sleep, time.NewTimer, time.After and the Stop and Reset
+// methods of time.Timer into calls to the functions below (see rtIntrinsics
+// in cl).
+//
+// Timers are kept in a heap ordered by the time at which they fire, which a
+// dedicated thread, timerproc, waits for. A sleeping goroutine waits for its
+// timer in a waitq, so that it is parked rather than its thread.
+
+// Time is the runtime representation of time.Time. As Times that the runtime
+// creates have no monotonic reading, wall only holds nanoseconds and ext holds
+// the seconds since January 1, year 1. A nil loc is UTC.
+type Time struct {
+	wall uint64
+	ext  int64
+	loc  unsafe.Pointer
+}
+
+// unixToInternal is the number of seconds from January 1, year 1 to the Unix
+// epoch.
+const unixToInternal = (1969*365 + 1969/4 - 1969/100 + 1969/400) * 86400
+
+// timer is a timer of the timer heap.
+type timer struct {
+	when   int64 // monotonic time at which it fires, in nanoseconds
+	index  int   // index in timers.heap, if active
+	active bool
+	ch     *Chan // channel of a Timer, to which the current time is sent
+	waitq  waitq // goroutines waiting for it to fire, for Sleep
+	fired  bool
+}
+
+// Timer is the runtime representation of time.Timer, whose first field is C.
+type Timer struct {
+	C *Chan
+	t timer
+}
+
+var timers struct {
+	lock    c.PthreadMutex
+	cond    c.PthreadCond // signaled when the first timer changes
+	heap    []*timer
+	n       int32 // len(heap); accessed atomically
+	started bool
+}
+
+func timerinit() {
+	c.PthreadMutexInit(&timers.lock, nil)
+	c.PthreadCondInit(&timers.cond, nil)
+}
+
+// nanotime returns the monotonic time in nanoseconds.
+func nanotime() int64 {
+	var ts c.Timespec
+	c.ClockGettime(c.ClockMonotonic, &ts)
+	return ts.Sec*1e9 + ts.Nsec
+}
+
+// TimeNow implements time.Now.
+func TimeNow() Time {
+	var ts c.Timespec
+	c.ClockGettime(c.ClockRealtime, &ts)
+	return Time{wall: uint64(ts.Nsec), ext: ts.Sec + unixToInternal}
+}
+
+// TimeSince implements time.Since for the Times that TimeNow returns.
+func TimeSince(t Time) int64 {
+	now := TimeNow()
+	return (now.ext-t.ext)*1e9 + int64(now.wall&(1<<30-1)) - int64(t.wall&(1<<30-1))
+}
+
+// TimeSleep implements time.Sleep.
+func TimeSleep(d int64) {
+	if d <= 0 {
+		return
+	}
+	if !schedInited() {
+		schedinit()
+	}
+	t := (*timer)(AllocZ(unsafe.Sizeof(timer{})))
+	c.PthreadMutexLock(&timers.lock)
+	addTimer(t, nanotime()+d)
+	for !t.fired {
+		t.waitq.wait(&timers.lock)
+	}
+	c.PthreadMutexUnlock(&timers.lock)
+}
+
+// TimeNewTimer implements time.NewTimer.
+func TimeNewTimer(d int64) *Timer {
+	if !schedInited() {
+		schedinit()
+	}
+	tm := (*Timer)(AllocZ(unsafe.Sizeof(Timer{})))
+	tm.C = NewChan(int(unsafe.Sizeof(Time{})), 1)
+	tm.t.ch = tm.C
+	c.PthreadMutexLock(&timers.lock)
+	addTimer(&tm.t, nanotime()+d)
+	c.PthreadMutexUnlock(&timers.lock)
+	return tm
+}
+
+// TimeAfter implements time.After.
+func TimeAfter(d int64) *Chan {
+	return TimeNewTimer(d).C
+}
+
+// TimerStop implements time.(*Timer).Stop.
+func TimerStop(tm *Timer) bool {
+	c.PthreadMutexLock(&timers.lock)
+	active := tm.t.active
+	if active {
+		delTimer(&tm.t)
+	}
+	c.PthreadMutexUnlock(&timers.lock)
+	return active
+}
+
+// TimerReset implements time.(*Timer).Reset.
+func TimerReset(tm *Timer, d int64) bool {
+	c.PthreadMutexLock(&timers.lock)
+	active := tm.t.active
+	if active {
+		delTimer(&tm.t)
+	}
+	addTimer(&tm.t, nanotime()+d)
+	c.PthreadMutexUnlock(&timers.lock)
+	return active
+}
+
+// addTimer adds t to the heap, to fire at when. It must be called with
+// timers.lock held.
+func addTimer(t *timer, when int64) {
+	if !timers.started {
+		var th c.Pthread
+		if newThread(&th, timerproc, nil) != 0 {
+			c.PthreadMutexUnlock(&timers.lock)
+			fatal("can't create the timer thread")
+		}
+		timers.started = true
+	}
+	t.when = when
+	t.active = true
+	t.index = len(timers.heap)
+	timers.heap = append(timers.heap, t)
+	atomic.StoreInt32(&timers.n, int32(len(timers.heap)))
+	siftupTimer(t.index)
+	if t.index == 0 {
+		c.PthreadCondSignal(&timers.cond)
+	}
+}
+
+// delTimer removes t from the heap. It must be called with timers.lock held.
+func delTimer(t *timer) {
+	h := timers.heap
+	last := len(h) - 1
+	i := t.index
+	if i != last {
+		h[i] = h[last]
+		h[i].index = i
+	}
+	h[last] = nil
+	timers.heap = h[:last]
+	atomic.StoreInt32(&timers.n, int32(last))
+	t.active = false
+	if i != last {
+		siftupTimer(i)
+		siftdownTimer(i)
+	}
+}
+
+func siftupTimer(i int) {
+	h := timers.heap
+	t := h[i]
+	for i > 0 {
+		p := (i - 1) / 2
+		if t.when >= h[p].when {
+			break
+		}
+		h[i] = h[p]
+		h[i].index = i
+		i = p
+	}
+	h[i] = t
+	t.index = i
+}
+
+func siftdownTimer(i int) {
+	h := timers.heap
+	n := len(h)
+	t := h[i]
+	for {
+		child := 2*i + 1
+		if child >= n {
+			break
+		}
+		if child+1 < n && h[child+1].when < h[child].when {
+			child++
+		}
+		if t.when <= h[child].when {
+			break
+		}
+		h[i] = h[child]
+		h[i].index = i
+		i = child
+	}
+	h[i] = t
+	t.index = i
+}
+
+// timerproc fires the timers of the heap when they are due: it sends the
+// current time to the channel of a Timer, which is dropped if the channel is
+// full, and wakes the goroutines that sleep on other timers.
+func timerproc(arg c.Pointer) c.Pointer {
+	c.PthreadMutexLock(&timers.lock)
+	for {
+		if len(timers.heap) == 0 {
+			c.PthreadCondWait(&timers.cond, &timers.lock)
+			continue
+		}
+		t := timers.heap[0]
+		if d := t.when - nanotime(); d > 0 {
+			var ts c.Timespec
+			c.ClockGettime(c.ClockRealtime, &ts)
+			abs := ts.Sec*1e9 + ts.Nsec + d
+			ts.Sec, ts.Nsec = abs/1e9, abs%1e9
+			c.PthreadCondTimedwait(&timers.cond, &timers.lock, &ts)
+			continue
+		}
+		if t.ch != nil {
+			now := TimeNow()
+			ChanTrySend(t.ch, unsafe.Pointer(&now), int(unsafe.Sizeof(now)))
+		} else {
+			t.fired = true
+			t.waitq.wakeAll()
+		}
+		// deleted once its goroutines are woken, so that the timer is
+		// pending for checkDeadlock until then
+		delTimer(t)
+	}
+}
@@ -0,0 +1,75 @@
+//go:build wasip1 || baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// There is no timer thread on wasip1 and baremetal targets, so the functions
+// of the time package that the runtime implements aren't supported.
+
+// Time is the runtime representation of time.Time.
+type Time struct {
+	wall uint64
+	ext  int64
+	loc  unsafe.Pointer
+}
+
+// Timer is the runtime representation of time.Timer.
+type Timer struct {
+	C *Chan
+}
+
+// TimeNow implements time.Now.
+func TimeNow() Time {
+	fatal("time is not supported on this target")
+	return Time{}
+}
+
+// TimeSince implements time.Since.
+func TimeSince(t Time) int64 {
+	fatal("time is not supported on this target")
+	return 0
+}
+
+// TimeSleep implements time.Sleep.
+func TimeSleep(d int64) {
+	fatal("time is not supported on this target")
+}
+
+// TimeNewTimer implements time.NewTimer.
+func TimeNewTimer(d int64) *Timer {
+	fatal("time is not supported on this target")
+	return nil
+}
+
+// TimeAfter implements time.After.
+func TimeAfter(d int64) *Chan {
+	fatal("time is not supported on this target")
+	return nil
+}
+
+// TimerStop implements time.(*Timer).Stop.
+func TimerStop(tm *Timer) bool {
+	return false
+}
+
+// TimerReset implements time.(*Timer).Reset.
+func TimerReset(tm *Timer, d int64) bool {
+	return false
+}
@@ -0,0 +1,170 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// Programs compiled with tracebacks (see cl.Config.Traceback) register the
+// function tables of their packages (see llssa.Package.FuncTab) with
+// FuncTabRegister, which also installs the handlers of the signals of fatal
+// errors, eg. segmentation faults. Fatal errors print the stack trace of the
+// thread on which they occur: the functions of its frames, which are found by
+// the return addresses that backtrace unwinds, by the function tables, with
+// the position of their declaration and the offset of the address in them,
+// after the ID of the goroutine that runs on the thread:
+//
+//	goroutine 1 [running]:
+//	main.crash(...)
+//		/home/user/crash/main.go:8 +0x1c
+//
+// The frames of the functions that aren't in a table, eg. the ones of C, are
+// printed as ?(...). A segmentation fault or a bus error at an address of the
+// first page is the dereference of a nil pointer, which is reported as the
+// panic of Go is, although it can't be recovered.
+
+// funcInfo is an entry of a function table.
+type funcInfo struct {
+	entry uintptr
+	name  string // empty for the end of the functions of a package
+	file  string
+	line  int
+}
+
+// funcTab is a function table.
+type funcTab struct {
+	tab  *funcInfo
+	n    int
+	next *funcTab
+}
+
+var funcTabs *funcTab
+
+// FuncTabRegister registers the function table tab of a package, which has n
+// entries.
+func FuncTabRegister(tab unsafe.Pointer, n int) {
+	if funcTabs == nil {
+		var act c.SigactionT
+		act.Handler = fatalSignal
+		act.Flags = c.SaSiginfo
+		for _, sig := range [...]c.Int{c.SIGILL, c.SIGFPE, c.SIGSEGV, c.SIGBUS} {
+			c.Sigaction(sig, &act, nil)
+		}
+	}
+	t := (*funcTab)(AllocZ(unsafe.Sizeof(funcTab{})))
+	t.tab, t.n, t.next = (*funcInfo)(tab), n, funcTabs
+	funcTabs = t
+}
+
+// findFunc returns the entry of the function tables that is the closest one
+// before pc, or nil.
+func findFunc(pc uintptr) (ret *funcInfo) {
+	for t := funcTabs; t != nil; t = t.next {
+		tab := unsafe.Slice(t.tab, t.n)
+		for i := range tab {
+			if f := &tab[i]; f.entry <= pc && (ret == nil || f.entry > ret.entry) {
+				ret = f
+			}
+		}
+	}
+	return
+}
+
+// nilPage is the size of the first page of the address space, which is never
+// mapped, so that dereferencing a nil pointer faults in it.
+const nilPage = 0x1000
+
+// fatalSignal is the handler of the signals of fatal errors.
+func fatalSignal(sig c.Int, info *c.Siginfo, ctx c.Pointer) {
+	var name string
+	switch sig {
+	case c.SIGILL:
+		name = "SIGILL: illegal instruction"
+	case c.SIGFPE:
+		name = "SIGFPE: floating-point exception"
+	case c.SIGSEGV:
+		name = "SIGSEGV: segmentation violation"
+	case c.SIGBUS:
+		name = "SIGBUS: bus error"
+	}
+	if (sig == c.SIGSEGV || sig == c.SIGBUS) && uintptr(info.Addr) < nilPage {
+		printString("panic: runtime error: invalid memory address or nil pointer dereference\n[signal ")
+		printString(name)
+		printString(" addr=0x")
+		printUint(uint64(uintptr(info.Addr)), 16)
+		printString("]\n")
+	} else {
+		printString("unexpected signal: ")
+		printString(name)
+		printString("\n")
+	}
+	traceback(3) // skips traceback, fatalSignal and the trampoline of the signal
+	c.Exit(2)
+}
+
+// maxDepth is the maximum number of frames of a stack trace.
+const maxDepth = 64
+
+// traceback prints the stack trace of the calling thread to stderr, headed by
+// the ID of the current goroutine, without its first skip frames, the first
+// of which is the one of traceback. It prints nothing if no function table is
+// registered.
+func traceback(skip int) {
+	if funcTabs == nil {
+		return
+	}
+	var pcs [maxDepth]uintptr
+	n := int(c.Backtrace(&pcs[0], maxDepth))
+	if skip > n {
+		skip = n
+	}
+	printTraceback(pcs[skip:n], n == maxDepth)
+}
+
+// printTraceback prints the stack trace of the return addresses pcs, which
+// backtrace unwound, as traceback does. elided tells that pcs has maxDepth
+// frames, after which others may be.
+func printTraceback(pcs []uintptr, elided bool) {
+	printString("\ngoroutine ")
+	printUint(goid(), 10)
+	printString(" [running]:\n")
+	for _, pc := range pcs {
+		f := findFunc(pc)
+		if f == nil || f.name == "" {
+			printString("?(...)\n\tpc=0x")
+			printUint(uint64(pc), 16)
+			printString("\n")
+			continue
+		}
+		printString(f.name)
+		printString("(...)\n\t")
+		printString(f.file)
+		printString(":")
+		printUint(uint64(f.line), 10)
+		printString(" +0x")
+		printUint(uint64(pc-f.entry), 16)
+		printString("\n")
+	}
+	if elided {
+		printString("...additional frames elided...\n")
+	}
+}
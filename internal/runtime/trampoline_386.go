@@ -0,0 +1,42 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// trampolineSize is the size of the code of a trampoline.
+const trampolineSize = 16
+
+// writeTrampoline writes the code of a trampoline at code, which passes the
+// address of its entry, entry bytes after code, to the wrapper of the entry in
+// ecx, the register of nest parameters, and jumps to it:
+//
+//	call 1f
+//	1: pop ecx
+//	lea ecx, [ecx+entry-5]
+//	jmp [ecx+entry.wrapper]
+func writeTrampoline(code unsafe.Pointer, entry uintptr) {
+	b := (*[trampolineSize]byte)(code)
+	b[0], b[1], b[2], b[3], b[4] = 0xe8, 0, 0, 0, 0
+	b[5] = 0x59
+	b[6], b[7] = 0x8d, 0x89
+	putUint32(b[8:], uint32(entry-5))
+	b[12], b[13], b[14] = 0xff, 0x61, byte(unsafe.Offsetof(callbackEntry{}.wrapper))
+	b[15] = 0xcc // int3
+}
@@ -0,0 +1,39 @@
+//go:build !wasip1 && !baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// trampolineSize is the size of the code of a trampoline.
+const trampolineSize = 16
+
+// writeTrampoline writes the code of a trampoline at code, which passes the
+// address of its entry, entry bytes after code, to the wrapper of the entry in
+// r10, the register of nest parameters, and jumps to it:
+//
+//	lea r10, [rip+entry]
+//	jmp [rip+entry.wrapper]
+func writeTrampoline(code unsafe.Pointer, entry uintptr) {
+	b := (*[trampolineSize]byte)(code)
+	b[0], b[1], b[2] = 0x4c, 0x8d, 0x15
+	putUint32(b[3:], uint32(entry-7))
+	b[7], b[8] = 0xff, 0x25
+	putUint32(b[9:], uint32(entry+unsafe.Offsetof(callbackEntry{}.wrapper)-13))
+	b[13], b[14], b[15] = 0xcc, 0xcc, 0xcc // int3
+}
@@ -0,0 +1,31 @@
+//go:build !wasip1 && !baremetal && !amd64 && !386
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// trampolineSize is the size of the code of a trampoline.
+const trampolineSize = 16
+
+// writeTrampoline is never called: the compiler only converts functions of
+// packages to C function pointers on targets without trampolines (see
+// llssa.Program.HasTrampolines).
+func writeTrampoline(code unsafe.Pointer, entry uintptr) {
+	fatal("trampolines are not supported on this target")
+}
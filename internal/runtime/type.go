@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import "unsafe"
+
+// Type is a type descriptor, which llgo emits for the types of the values
+// that are converted to interfaces (see llssa.Package.TypeDesc): the tab of
+// an interface is the descriptor of its dynamic type. Elem, Key, Fields and
+// Methods are only set if the program is compiled for reflection (see
+// cl.Config.Reflect).
+type Type struct {
+	Size    uintptr
+	Kind    uintptr // a reflect.Kind
+	Hash    uint32  // hash of the canonical string of the type
+	Str     string  // eg. "[]main.T"
+	Name    string  // name of named and basic types
+	PkgPath string  // package path of named types
+	Elem    *Type   // element type of arrays, chans, maps, pointers and slices
+	Key     *Type   // key type of maps
+	Len     uintptr // length of arrays, 1 for variadic func types
+	Fields  []StructField
+	Methods []Method // exported methods, sorted by name
+	In      []*Type  // parameters of func types
+	Out     []*Type  // results of func types
+
+	// Hasher and Equal hash and compare the values of the type that their
+	// parameters point to. They are nil if the type isn't comparable.
+	Hasher func(key unsafe.Pointer, seed uintptr) uintptr
+	Equal  func(x, y unsafe.Pointer) bool
+}
+
+// StructField describes a field of a struct type.
+type StructField struct {
+	Name     string
+	Typ      *Type
+	Offset   uintptr
+	Embedded bool
+}
+
+// Method describes a method of a type. Its functions are nil if it isn't
+// compiled as a function, eg. if it is a method of an interface.
+type Method struct {
+	Name string
+	Typ  *Type          // a func type, without the receiver
+	Ifn  unsafe.Pointer // the method called on the data of an interface
+	Tfn  unsafe.Pointer // the method called on a value of the type
+	Call unsafe.Pointer // calls Ifn with its arguments in memory, see ReflectValueCall
+}
+
+// Kinds of types, the values of reflect.Kind.
+const (
+	kindInvalid = iota
+	kindBool
+	kindInt
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+	kindUint
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+	kindUintptr
+	kindFloat32
+	kindFloat64
+	kindComplex64
+	kindComplex128
+	kindArray
+	kindChan
+	kindFunc
+	kindInterface
+	kindMap
+	kindPointer
+	kindSlice
+	kindString
+	kindStruct
+	kindUnsafePointer
+)
+
+// eface is the representation of interfaces.
+type eface struct {
+	typ  *Type
+	data unsafe.Pointer
+}
+
+// isDirect reports whether the values of t, whose representation is a
+// pointer, are the data of interfaces, rather than being pointed to by it.
+// Func values are closures, of two words, so they are pointed to, as the C
+// function pointers of package c are (see llssa.IsCFuncType).
+func (t *Type) isDirect() bool {
+	switch t.Kind {
+	case kindChan, kindMap, kindPointer, kindUnsafePointer:
+		return true
+	}
+	return false
+}
+
+// TypeAssertFailed reports the failure of the type assertion x.(want), where
+// x is an interface of type iface whose dynamic type is have, nil if x is nil.
+func TypeAssertFailed(iface, have, want *Type) {
+	Panic(&TypeAssertionError{iface, have, want})
+}
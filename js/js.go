@@ -0,0 +1,286 @@
+//go:build wasm
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package js gives llgo programs compiled to WebAssembly access to the
+// JavaScript host that runs them, eg. a browser or Node.js, as syscall/js does
+// for Go programs.
+//
+// JavaScript values can't be stored in the linear memory of a module, so a
+// Value refers to a value kept in a table by the glue of the host,
+// llgo_js.mjs in this directory, which implements the functions of the module
+// llgo_js that the package imports. The host instantiates a module with them
+// and the imports of WASI, eg. in Node.js:
+//
+//	import { Host } from "./llgo_js.mjs";
+//	import { WASI } from "node:wasi";
+//
+//	const host = new Host(), wasi = new WASI({ version: "preview1" });
+//	const { instance } = await WebAssembly.instantiate(bytes, {
+//		...host.imports,
+//		wasi_snapshot_preview1: wasi.wasiImport,
+//	});
+//	host.setInstance(instance);
+//	wasi.start(instance);
+//
+// A Value keeps the value it refers to alive until it is released by
+// Release. The values returned by Undefined, Null, Global and Bool needn't be
+// released. A JavaScript exception thrown by a function that a Value calls
+// unwinds the module, and is rethrown to the caller of its exported function.
+//
+// Go functions are exported to JavaScript by the pragma //go:wasmexport, eg.
+//
+//	//go:wasmexport add
+//	func add(a, b int32) int32
+//
+// which the host calls as instance.exports.add(1, 2). The parameters and
+// results of exported functions must be numbers or pointers, and so must be
+// the ones of the functions imported from the host by the pragma
+// //go:wasmimport.
+package js
+
+import "unsafe"
+
+// LLGoPackage marks the package as one whose pragmas are honored where it is
+// imported.
+const LLGoPackage = true
+
+type ref = uint32
+
+// The references to the values predefined by the glue.
+const (
+	refUndefined ref = iota
+	refNull
+	refTrue
+	refFalse
+	refGlobal
+)
+
+// Value represents a JavaScript value.
+type Value struct {
+	ref ref
+}
+
+// Undefined returns the JavaScript value undefined.
+func Undefined() Value {
+	return Value{refUndefined}
+}
+
+// Null returns the JavaScript value null.
+func Null() Value {
+	return Value{refNull}
+}
+
+// Global returns the JavaScript global object, globalThis.
+func Global() Value {
+	return Value{refGlobal}
+}
+
+// Bool returns the JavaScript boolean b.
+func Bool(b bool) Value {
+	if b {
+		return Value{refTrue}
+	}
+	return Value{refFalse}
+}
+
+// Number returns the JavaScript number x.
+func Number(x float64) Value {
+	return Value{valueNumber(x)}
+}
+
+// String returns the JavaScript string s.
+func String(s string) Value {
+	return Value{valueString(unsafe.Pointer(&s))}
+}
+
+// Type represents the JavaScript type of a Value, as reported by typeof.
+type Type int
+
+const (
+	TypeUndefined Type = iota
+	TypeNull
+	TypeBoolean
+	TypeNumber
+	TypeString
+	TypeSymbol
+	TypeObject
+	TypeFunction
+	TypeBigInt
+)
+
+// Type returns the JavaScript type of v.
+func (v Value) Type() Type {
+	return Type(valueType(v.ref))
+}
+
+// IsUndefined reports whether v is undefined.
+func (v Value) IsUndefined() bool {
+	return v.Type() == TypeUndefined
+}
+
+// IsNull reports whether v is null.
+func (v Value) IsNull() bool {
+	return v.Type() == TypeNull
+}
+
+// Equal reports whether v and w are equal by the JavaScript operator ===.
+func (v Value) Equal(w Value) bool {
+	return valueEqual(v.ref, w.ref) != 0
+}
+
+// Get returns the JavaScript property p of v, v[p].
+func (v Value) Get(p string) Value {
+	return Value{valueGet(v.ref, unsafe.Pointer(&p))}
+}
+
+// Set sets the JavaScript property p of v to x, v[p] = x.
+func (v Value) Set(p string, x Value) {
+	valueSet(v.ref, unsafe.Pointer(&p), x.ref)
+}
+
+// Index returns the JavaScript index i of v, v[i].
+func (v Value) Index(i int) Value {
+	return Value{valueIndex(v.ref, uint32(i))}
+}
+
+// SetIndex sets the JavaScript index i of v to x, v[i] = x.
+func (v Value) SetIndex(i int, x Value) {
+	valueSetIndex(v.ref, uint32(i), x.ref)
+}
+
+// Length returns the JavaScript property length of v.
+func (v Value) Length() int {
+	return int(valueLength(v.ref))
+}
+
+// Call calls the JavaScript method m of v with the arguments vs, v[m](...vs).
+func (v Value) Call(m string, vs ...Value) Value {
+	return Value{valueCall(v.ref, unsafe.Pointer(&m), args(vs), uint32(len(vs)))}
+}
+
+// Invoke calls the JavaScript function v with the arguments vs, v(...vs).
+func (v Value) Invoke(vs ...Value) Value {
+	return Value{valueInvoke(v.ref, args(vs), uint32(len(vs)))}
+}
+
+// New calls the JavaScript constructor v with the arguments vs, new v(...vs).
+func (v Value) New(vs ...Value) Value {
+	return Value{valueNew(v.ref, args(vs), uint32(len(vs)))}
+}
+
+// Float returns the JavaScript number v as a float64.
+func (v Value) Float() float64 {
+	return valueFloat(v.ref)
+}
+
+// Int returns the JavaScript number v truncated to an int.
+func (v Value) Int() int {
+	return int(valueFloat(v.ref))
+}
+
+// Bool returns the JavaScript boolean v. The glue refers to booleans by
+// refTrue and refFalse only.
+func (v Value) Bool() bool {
+	return v.ref == refTrue
+}
+
+// Truthy reports whether v is considered true by JavaScript, !!v.
+func (v Value) Truthy() bool {
+	return valueTruthy(v.ref) != 0
+}
+
+// String returns v converted to a JavaScript string, String(v), in UTF-8.
+func (v Value) String() string {
+	n := valueStringLength(v.ref)
+	if n == 0 {
+		return ""
+	}
+	buf := make([]byte, n)
+	valueStringCopy(v.ref, *(*unsafe.Pointer)(unsafe.Pointer(&buf)))
+	return *(*string)(unsafe.Pointer(&buf))
+}
+
+// args returns the pointer to the array of the references of vs.
+func args(vs []Value) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&vs))
+}
+
+// Release releases the value that v refers to, so that the host can collect
+// it. v must not be used afterwards.
+func (v Value) Release() {
+	if v.ref > refGlobal {
+		valueRelease(v.ref)
+	}
+}
+
+// The functions of the glue. Strings are passed as pointers to Go strings,
+// and arguments as pointers to arrays of references.
+
+//go:wasmimport llgo_js value_number
+func valueNumber(x float64) ref
+
+//go:wasmimport llgo_js value_string
+func valueString(s unsafe.Pointer) ref
+
+//go:wasmimport llgo_js value_type
+func valueType(v ref) uint32
+
+//go:wasmimport llgo_js value_equal
+func valueEqual(v, w ref) uint32
+
+//go:wasmimport llgo_js value_get
+func valueGet(v ref, p unsafe.Pointer) ref
+
+//go:wasmimport llgo_js value_set
+func valueSet(v ref, p unsafe.Pointer, x ref)
+
+//go:wasmimport llgo_js value_index
+func valueIndex(v ref, i uint32) ref
+
+//go:wasmimport llgo_js value_set_index
+func valueSetIndex(v ref, i uint32, x ref)
+
+//go:wasmimport llgo_js value_length
+func valueLength(v ref) uint32
+
+//go:wasmimport llgo_js value_call
+func valueCall(v ref, m unsafe.Pointer, args unsafe.Pointer, nargs uint32) ref
+
+//go:wasmimport llgo_js value_invoke
+func valueInvoke(v ref, args unsafe.Pointer, nargs uint32) ref
+
+//go:wasmimport llgo_js value_new
+func valueNew(v ref, args unsafe.Pointer, nargs uint32) ref
+
+//go:wasmimport llgo_js value_float
+func valueFloat(v ref) float64
+
+//go:wasmimport llgo_js value_truthy
+func valueTruthy(v ref) uint32
+
+// valueStringLength returns the length of String(v) in UTF-8, which the glue
+// keeps until valueStringCopy copies it to buf.
+//
+//go:wasmimport llgo_js value_string_length
+func valueStringLength(v ref) uint32
+
+//go:wasmimport llgo_js value_string_copy
+func valueStringCopy(v ref, buf unsafe.Pointer)
+
+//go:wasmimport llgo_js value_release
+func valueRelease(v ref)
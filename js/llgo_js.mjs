@@ -0,0 +1,126 @@
+// Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The glue of the host of llgo programs compiled to WebAssembly that import
+// the package github.com/goplus/llgo/js. It implements the module llgo_js,
+// whose functions refer to JavaScript values by indexes of a table.
+
+const typeOf = {
+  undefined: 0,
+  boolean: 2,
+  number: 3,
+  string: 4,
+  symbol: 5,
+  object: 6,
+  function: 7,
+  bigint: 8,
+};
+
+export class Host {
+  constructor() {
+    // The values predefined by the package js: refUndefined, refNull,
+    // refTrue, refFalse and refGlobal.
+    this.values = [undefined, null, true, false, globalThis];
+    this.free = [];
+    this.str = null; // the string encoded by value_string_length
+    this.memory = null;
+    this.encoder = new TextEncoder();
+    this.decoder = new TextDecoder();
+  }
+
+  // setInstance must be called with the instance of the module before it
+  // runs, so that the glue can access its memory.
+  setInstance(instance) {
+    this.memory = instance.exports.memory;
+  }
+
+  // ref returns the reference to the value v, adding it to the table if it
+  // isn't predefined.
+  ref(v) {
+    switch (v) {
+      case undefined:
+        return 0;
+      case null:
+        return 1;
+      case true:
+        return 2;
+      case false:
+        return 3;
+      case globalThis:
+        return 4;
+    }
+    if (this.free.length > 0) {
+      const r = this.free.pop();
+      this.values[r] = v;
+      return r;
+    }
+    this.values.push(v);
+    return this.values.length - 1;
+  }
+
+  // string returns the Go string at the address p of the memory: a pointer
+  // to its bytes and its length, which are 32-bit on wasm32.
+  string(p) {
+    const view = new DataView(this.memory.buffer);
+    const data = view.getUint32(p, true), len = view.getInt32(p + 4, true);
+    return this.decoder.decode(new Uint8Array(this.memory.buffer, data, len));
+  }
+
+  // args returns the values of the array of n references at the address p.
+  args(p, n) {
+    const refs = new Uint32Array(this.memory.buffer, p, n);
+    return Array.from(refs, (r) => this.values[r]);
+  }
+
+  get imports() {
+    const v = (r) => this.values[r];
+    return {
+      llgo_js: {
+        value_number: (x) => this.ref(x),
+        value_string: (s) => this.ref(this.string(s)),
+        value_type: (r) => (r === 1 ? 1 : typeOf[typeof v(r)]),
+        value_equal: (r, w) => (v(r) === v(w) ? 1 : 0),
+        value_get: (r, p) => this.ref(Reflect.get(v(r), this.string(p))),
+        value_set: (r, p, x) => {
+          Reflect.set(v(r), this.string(p), v(x));
+        },
+        value_index: (r, i) => this.ref(v(r)[i]),
+        value_set_index: (r, i, x) => {
+          v(r)[i] = v(x);
+        },
+        value_length: (r) => v(r).length,
+        value_call: (r, m, args, n) => {
+          const obj = v(r);
+          return this.ref(Reflect.apply(obj[this.string(m)], obj, this.args(args, n)));
+        },
+        value_invoke: (r, args, n) => this.ref(Reflect.apply(v(r), undefined, this.args(args, n))),
+        value_new: (r, args, n) => this.ref(Reflect.construct(v(r), this.args(args, n))),
+        value_float: (r) => Number(v(r)),
+        value_truthy: (r) => (v(r) ? 1 : 0),
+        value_string_length: (r) => {
+          this.str = this.encoder.encode(String(v(r)));
+          return this.str.length;
+        },
+        value_string_copy: (r, buf) => {
+          new Uint8Array(this.memory.buffer, buf, this.str.length).set(this.str);
+          this.str = null;
+        },
+        value_release: (r) => {
+          this.values[r] = undefined;
+          this.free.push(r);
+        },
+      },
+    };
+  }
+}
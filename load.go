@@ -17,12 +17,46 @@
 package llgo
 
 import (
+	"io"
+
 	"github.com/goplus/llgo/ssa"
 )
 
 // -----------------------------------------------------------------------------
 
 type Config struct {
+	Target *ssa.Target // platform for which packages are built (nil means the one of GOOS/GOARCH)
+
+	WasmExecModel string   // "command" (the default) or "reactor", for WebAssembly targets
+	Baremetal     bool     // build for a target without an operating system
+	LinkerScript  string   // linker script of a baremetal executable
+	LTO           string   // link-time optimization: "off" (the default), "thin" or "full"
+	GC            string   // garbage collector: "boehm" (the default), "precise", "none" or "leaking"
+	RelocModel    string   // relocation model: "static", "pic" or "pie" (empty means the default of the toolchain)
+	Sanitizer     string   // sanitizer: "address", "thread" or "memory" (empty means none)
+	Race          bool     // detect data races, with the runtime of ThreadSanitizer
+	Hardening     bool     // harden programs with stack protectors, control-flow integrity and checked memory functions
+	Static        bool     // link executables statically, with musl if $MUSL_SYSROOT is set
+	CPUModel      string   // CPU that code is generated for, eg. "skylake", or "native" (empty means the one of the target)
+	Features      string   // features of the CPU added or removed, eg. "+avx2,-avx512f"
+	FloatABI      string   // float ABI of ARM targets: "hard", "soft" or "softfp" (empty means the one of the target)
+	Tags          []string // build tags satisfied in addition to the ones of the target
+	Overlay       string   // JSON file of the overlay of Go files, in the format of the -overlay flag of the go command
+	ModFlag       string   // -mod flag of the go command: "readonly", "vendor" or "mod" (empty means its default)
+
+	DeadCodeElim    bool               // compile only the functions and variables that are reachable
+	Preempt         bool               // check for preemption in function prologues, so that goroutines can be preempted
+	PreemptLoops    bool               // check for preemption on the back edges of loops too, if Preempt is set
+	Reflect         bool               // emit type descriptors rich enough for the reflect package, which makes binaries bigger
+	MathIntrinsics  bool               // lower functions of math and math/bits to LLVM intrinsics
+	DebugInfo       ssa.DebugInfoLevel // how much DWARF debug information is generated
+	ContinueOnError bool               // compile the functions that fail to compile to stubs that trap, reporting them as warnings
+	Verify          bool               // check the LLVM IR of every compiled function, to debug the compiler
+
+	OptLevel string // optimization level: "0" (the default), "1", "2", "3", "s" or "z"
+	Passes   string // LLVM pass pipeline run after the one of OptLevel
+
+	Diagnostics io.Writer // where build errors are also written as JSON records (see build.Diagnostic), if not nil
 }
 
 // LoadDir loads Go packages from a specified directory.
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package plugin loads plugins, the shared objects that main packages are
+// built to by llgo build -buildmode=plugin, and looks up their exported
+// functions and variables, as the plugin package of Go does.
+//
+// The symbol of a member of a package is named by the import path of the
+// package and the name of the member (see SymbolName), whatever the build,
+// so that a program can look up the members of plugins built separately. A
+// plugin records the import path of its main package, which is
+// command-line-arguments if it is built from files.
+//
+// A plugin has its own copies of the packages it imports, which are
+// initialized when it is opened, and is never unloaded.
+package plugin
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/internal/runtime/c"
+)
+
+// LLGoPackage specifies that programs which import the package are linked
+// with libdl, which libc includes on recent systems.
+const LLGoPackage = "link: -ldl"
+
+// PathSymbol is the symbol of the NUL-terminated import path of the main
+// package of a plugin, which it defines.
+const PathSymbol = "_llgo_plugin_path"
+
+// Plugin is a loaded plugin.
+type Plugin struct {
+	pkgPath string // import path of the main package
+	handle  c.Pointer
+}
+
+// Symbol is the address of a function or a variable of a plugin. A function
+// is called through a C function pointer of its type, ie. a func type named
+// in a package that declares LLGoPackage, as func values are closures, and a
+// variable through a pointer to it:
+//
+//	type AddFunc func(a, b int) int // in a package that declares LLGoPackage
+//
+//	sym, err := p.Lookup("Add")
+//	add := *(*AddFunc)(unsafe.Pointer(&sym))
+//
+//	sym, err = p.Lookup("Count")
+//	count := (*int)(sym)
+type Symbol = unsafe.Pointer
+
+// Error is an error that occurs when a plugin is opened or looked up.
+type Error struct {
+	Op   string // "open" or "lookup"
+	Name string // path of the plugin, or name of the symbol
+	Err  string // description of the error
+}
+
+func (e *Error) Error() string {
+	return "plugin: " + e.Op + " " + e.Name + ": " + e.Err
+}
+
+// SymbolName returns the symbol of the member name of the package pkgPath.
+func SymbolName(pkgPath, name string) string {
+	return pkgPath + "." + name
+}
+
+// Open opens the plugin of the file path, and initializes its packages.
+func Open(path string) (*Plugin, error) {
+	cpath := cstring(path)
+	h := dlopen(cpath, rtldNow)
+	c.Free(c.Pointer(cpath))
+	if h == nil {
+		return nil, &Error{"open", path, gostring(dlerror())}
+	}
+	pkgPath := lookup(h, PathSymbol)
+	if pkgPath == nil {
+		return nil, &Error{"open", path, "not a plugin built by llgo"}
+	}
+	return &Plugin{gostring((*c.Char)(pkgPath)), h}, nil
+}
+
+// Lookup returns the exported function or variable name of the main package
+// of plugin p.
+func (p *Plugin) Lookup(name string) (Symbol, error) {
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return nil, &Error{"lookup", name, "not an exported name"}
+	}
+	sym := lookup(p.handle, SymbolName(p.pkgPath, name))
+	if sym == nil {
+		return nil, &Error{"lookup", name, "symbol not found in plugin " + p.pkgPath}
+	}
+	return sym, nil
+}
+
+// lookup returns the address of the symbol sym of the shared object h, or nil.
+func lookup(h c.Pointer, sym string) c.Pointer {
+	csym := cstring(sym)
+	ret := dlsym(h, csym)
+	c.Free(c.Pointer(csym))
+	return ret
+}
+
+// cstring returns a copy of s terminated by a NUL, which the caller frees.
+func cstring(s string) *c.Char {
+	buf := c.Calloc(uintptr(len(s)+1), 1)
+	c.Memcpy(buf, *(*c.Pointer)(unsafe.Pointer(&s)), uintptr(len(s)))
+	return (*c.Char)(buf)
+}
+
+// gostring returns the NUL-terminated string s of libc.
+func gostring(s *c.Char) string {
+	if s == nil {
+		return ""
+	}
+	n := c.Strlen(s)
+	b := make([]byte, n)
+	c.Memcpy(*(*c.Pointer)(unsafe.Pointer(&b)), c.Pointer(s), n)
+	return string(b)
+}
+
+const rtldNow = 2 // RTLD_NOW of Linux and macOS
+
+//go:linkname dlopen dlopen
+func dlopen(path *c.Char, mode c.Int) c.Pointer
+
+//go:linkname dlsym dlsym
+func dlsym(handle c.Pointer, name *c.Char) c.Pointer
+
+//go:linkname dlerror dlerror
+func dlerror() *c.Char
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package math declares functions of the Python module math.
+package math
+
+import (
+	_ "unsafe"
+
+	"github.com/goplus/llgo/py"
+)
+
+const LLGoPackage = "py.math"
+
+// Sqrt returns the square root of the number x.
+//
+//go:linkname Sqrt py.sqrt
+func Sqrt(x *py.Object) *py.Object
+
+// Pow returns x raised to the power y.
+//
+//go:linkname Pow py.pow
+func Pow(x, y *py.Object) *py.Object
+
+// Sin returns the sine of x, in radians.
+//
+//go:linkname Sin py.sin
+func Sin(x *py.Object) *py.Object
+
+// Cos returns the cosine of x, in radians.
+//
+//go:linkname Cos py.cos
+func Cos(x *py.Object) *py.Object
+
+// Floor returns the floor of x as an int.
+//
+//go:linkname Floor py.floor
+func Floor(x *py.Object) *py.Object
+
+// Factorial returns the factorial of the int n.
+//
+//go:linkname Factorial py.factorial
+func Factorial(n *py.Object) *py.Object
+
+// Gcd returns the greatest common divisor of the ints a and b.
+//
+//go:linkname Gcd py.gcd
+func Gcd(a, b *py.Object) *py.Object
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package py declares the CPython C API, by which llgo programs embed the
+// Python interpreter and call Python functions.
+//
+// A package declares the functions of a Python module if its LLGoPackage
+// constant is "py.<module>": each bodyless function linked to py.<name>, eg.
+//
+//	const LLGoPackage = "py.math"
+//
+//	//go:linkname Sqrt py.sqrt
+//	func Sqrt(x *py.Object) *py.Object
+//
+// is the function name of the module. Its calls are compiled to calls of the
+// C API, which initialize the interpreter if it isn't initialized, import the
+// module and call the function with the arguments, which are Python objects.
+// The result is a new reference, which the caller must release by DecRef, or
+// nil if the function raised an exception (see ErrOccurred).
+//
+// Functions that return a new reference are documented as such; the others
+// return borrowed references, which the caller must not release.
+package py
+
+import _ "unsafe"
+
+// LLGoPackage specifies the flags that programs which import the package are
+// linked with: the ones of python3-embed if pkg-config knows it, or else
+// -lpython3.
+const LLGoPackage = "link: $(pkg-config --libs python3-embed); -lpython3"
+
+// Object is a Python object, a PyObject of the C API.
+type Object struct {
+	Unused [8]byte
+}
+
+// Char is a C char.
+type Char = int8
+
+// -----------------------------------------------------------------------------
+
+// Initialize initializes the Python interpreter. It is a no-op if the
+// interpreter is initialized.
+//
+//go:linkname Initialize Py_Initialize
+func Initialize()
+
+// Finalize finalizes the Python interpreter, and releases all the objects.
+//
+//go:linkname Finalize Py_Finalize
+func Finalize()
+
+// IncRef increments the reference count of o, which may be nil.
+//
+//go:linkname IncRef Py_IncRef
+func IncRef(o *Object)
+
+// DecRef decrements the reference count of o, which may be nil, and releases
+// o if it drops to zero.
+//
+//go:linkname DecRef Py_DecRef
+func DecRef(o *Object)
+
+// -----------------------------------------------------------------------------
+
+// Import imports the module name, eg. "os.path", and returns a new reference
+// to it.
+//
+//go:linkname Import PyImport_ImportModule
+func Import(name *Char) *Object
+
+// GetAttr returns a new reference to the attribute name of o, eg. o.name.
+//
+//go:linkname GetAttr PyObject_GetAttrString
+func GetAttr(o *Object, name *Char) *Object
+
+// Call calls the callable object fn with the arguments args, which end with
+// nil, and returns a new reference to its result.
+//
+//go:linkname Call PyObject_CallFunctionObjArgs
+func Call(fn *Object, __llgo_va_list ...any) *Object
+
+// CallObject calls the callable object fn with the tuple args, which may be
+// nil if there are no arguments, and returns a new reference to its result.
+//
+//go:linkname CallObject PyObject_CallObject
+func CallObject(fn, args *Object) *Object
+
+// Repr returns a new reference to the string repr(o).
+//
+//go:linkname Repr PyObject_Repr
+func Repr(o *Object) *Object
+
+// Str returns a new reference to the string str(o).
+//
+//go:linkname Str PyObject_Str
+func Str(o *Object) *Object
+
+// -----------------------------------------------------------------------------
+
+// Float returns a new reference to the Python float v.
+//
+//go:linkname Float PyFloat_FromDouble
+func Float(v float64) *Object
+
+// FloatVal returns the value of the Python float o, or -1 if o isn't a
+// float and an exception is raised.
+//
+//go:linkname FloatVal PyFloat_AsDouble
+func FloatVal(o *Object) float64
+
+// Long returns a new reference to the Python int v.
+//
+//go:linkname Long PyLong_FromLongLong
+func Long(v int64) *Object
+
+// LongVal returns the value of the Python int o, or -1 if o isn't an int that
+// fits in an int64 and an exception is raised.
+//
+//go:linkname LongVal PyLong_AsLongLong
+func LongVal(o *Object) int64
+
+// Bool returns a new reference to True if v isn't zero, or else to False.
+//
+//go:linkname Bool PyBool_FromLong
+func Bool(v int64) *Object
+
+// IsTrue returns 1 if o is true, 0 if it is false, or -1 on error.
+//
+//go:linkname IsTrue PyObject_IsTrue
+func IsTrue(o *Object) int32
+
+// String returns a new reference to the Python string of the UTF-8 C string s.
+//
+//go:linkname String PyUnicode_FromString
+func String(s *Char) *Object
+
+// CStr returns the UTF-8 C string of the Python string o, which is owned by o.
+//
+//go:linkname CStr PyUnicode_AsUTF8
+func CStr(o *Object) *Char
+
+// -----------------------------------------------------------------------------
+
+// NewTuple returns a new reference to a tuple of n items, which must be set by
+// TupleSetItem before the tuple is used.
+//
+//go:linkname NewTuple PyTuple_New
+func NewTuple(n int) *Object
+
+// TupleSetItem sets the item i of the tuple t to o, whose reference it steals.
+// It returns 0, or -1 on error.
+//
+//go:linkname TupleSetItem PyTuple_SetItem
+func TupleSetItem(t *Object, i int, o *Object) int32
+
+// TupleGetItem returns the item i of the tuple t, a borrowed reference.
+//
+//go:linkname TupleGetItem PyTuple_GetItem
+func TupleGetItem(t *Object, i int) *Object
+
+// NewList returns a new reference to a list of n items, which must be set by
+// ListSetItem before the list is used.
+//
+//go:linkname NewList PyList_New
+func NewList(n int) *Object
+
+// ListSetItem sets the item i of the list l to o, whose reference it steals.
+// It returns 0, or -1 on error.
+//
+//go:linkname ListSetItem PyList_SetItem
+func ListSetItem(l *Object, i int, o *Object) int32
+
+// ListGetItem returns the item i of the list l, a borrowed reference.
+//
+//go:linkname ListGetItem PyList_GetItem
+func ListGetItem(l *Object, i int) *Object
+
+// Len returns len(o), or -1 on error.
+//
+//go:linkname Len PyObject_Length
+func Len(o *Object) int
+
+// -----------------------------------------------------------------------------
+
+// ErrOccurred returns the type of the exception that is raised, a borrowed
+// reference, or nil if no exception is raised.
+//
+//go:linkname ErrOccurred PyErr_Occurred
+func ErrOccurred() *Object
+
+// ErrPrint prints the exception that is raised to sys.stderr, and clears it.
+//
+//go:linkname ErrPrint PyErr_Print
+func ErrPrint()
+
+// ErrClear clears the exception that is raised, if any.
+//
+//go:linkname ErrClear PyErr_Clear
+func ErrClear()
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// AtomicOp is the operation of an atomic read-modify-write instruction.
+type AtomicOp = llvm.AtomicRMWBinOp
+
+const (
+	AtomicXchg = llvm.AtomicRMWBinOpXchg
+	AtomicAdd  = llvm.AtomicRMWBinOpAdd
+	AtomicSub  = llvm.AtomicRMWBinOpSub
+	AtomicAnd  = llvm.AtomicRMWBinOpAnd
+	AtomicOr   = llvm.AtomicRMWBinOpOr
+	AtomicXor  = llvm.AtomicRMWBinOpXor
+)
+
+// All atomic instructions use the sequentially consistent ordering, which is
+// what the Go memory model requires for sync/atomic.
+const seqCst = llvm.AtomicOrderingSequentiallyConsistent
+
+// AtomicRMW atomically applies op to the value at the pointer ptr and val,
+// stores the result at ptr, and returns the old value.
+func (b Builder) AtomicRMW(op AtomicOp, ptr, val Expr) Expr {
+	if debugInstr {
+		log.Printf("AtomicRMW %v, %v, %v\n", op, ptr.impl, val.impl)
+	}
+	ret := b.impl.CreateAtomicRMW(op, ptr.impl, val.impl, seqCst, false)
+	return Expr{ret, val.Type}
+}
+
+// AtomicCmpXchg atomically compares the value at the pointer ptr with old,
+// and if they are equal, stores new at ptr. It returns whether the swap was
+// performed.
+func (b Builder) AtomicCmpXchg(ptr, old, new Expr) Expr {
+	if debugInstr {
+		log.Printf("AtomicCmpXchg %v, %v, %v\n", ptr.impl, old.impl, new.impl)
+	}
+	ret := b.impl.CreateAtomicCmpXchg(ptr.impl, old.impl, new.impl, seqCst, seqCst, false)
+	return Expr{b.impl.CreateExtractValue(ret, 1, ""), b.prog.Bool()}
+}
+
+// AtomicLoad atomically loads the value at the pointer ptr.
+func (b Builder) AtomicLoad(ptr Expr) Expr {
+	if debugInstr {
+		log.Printf("AtomicLoad %v\n", ptr.impl)
+	}
+	ret := b.Load(ptr)
+	ret.impl.SetOrdering(seqCst)
+	return ret
+}
+
+// AtomicStore atomically stores val at the pointer ptr.
+func (b Builder) AtomicStore(ptr, val Expr) {
+	if debugInstr {
+		log.Printf("AtomicStore %v, %v\n", ptr.impl, val.impl)
+	}
+	ret := b.impl.CreateStore(val.impl, ptr.impl)
+	ret.SetOrdering(seqCst)
+}
+
+// -----------------------------------------------------------------------------
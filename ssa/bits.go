@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// BitsOp is an operation on the bits of an unsigned integer (see Bits), the
+// one of a function of package math/bits.
+type BitsOp int
+
+const (
+	BitsLeadingZeros  BitsOp = iota // number of leading zero bits
+	BitsTrailingZeros               // number of trailing zero bits
+	BitsOnesCount                   // number of one bits
+	BitsLen                         // minimum number of bits that represent x
+	BitsReverse                     // x with its bits in reverse order
+	BitsReverseBytes                // x with its bytes in reverse order
+)
+
+// Bits returns op applied to the unsigned integer x, which is computed by an
+// intrinsic of LLVM, eg. llvm.ctlz, that is a single instruction where the
+// target has one, eg. lzcnt on amd64. Counts are ints, as in math/bits, and
+// so is the result of BitsLen.
+func (b Builder) Bits(op BitsOp, x Expr) Expr {
+	if debugInstr {
+		log.Printf("Bits %d, %v\n", op, x.impl)
+	}
+	width := x.ll.IntTypeWidth()
+	suffix := ".i" + strconv.Itoa(width)
+	zeroPoison := llvm.ConstInt(b.prog.tyInt1(), 0, false) // the counts of 0 are the width of x
+	var n llvm.Value
+	switch op {
+	case BitsLeadingZeros, BitsLen:
+		n = b.intrinsic("llvm.ctlz"+suffix, x.ll, x.impl, zeroPoison)
+	case BitsTrailingZeros:
+		n = b.intrinsic("llvm.cttz"+suffix, x.ll, x.impl, zeroPoison)
+	case BitsOnesCount:
+		n = b.intrinsic("llvm.ctpop"+suffix, x.ll, x.impl)
+	case BitsReverse:
+		return Expr{b.intrinsic("llvm.bitreverse"+suffix, x.ll, x.impl), x.Type}
+	case BitsReverseBytes:
+		if width == 8 {
+			return x
+		}
+		return Expr{b.intrinsic("llvm.bswap"+suffix, x.ll, x.impl), x.Type}
+	default:
+		panic("Bits: invalid operation - " + strconv.Itoa(int(op)))
+	}
+	if op == BitsLen {
+		n = b.impl.CreateSub(llvm.ConstInt(x.ll, uint64(width), false), n, "")
+	}
+	return b.castInt(Expr{n, x.Type}, b.prog.Int())
+}
+
+// RotateLeft returns the unsigned integer x rotated left by k bits, or right
+// by -k bits if k is negative, as bits.RotateLeft does, by the intrinsic
+// llvm.fshl, whose shift is k modulo the width of x.
+func (b Builder) RotateLeft(x, k Expr) Expr {
+	if debugInstr {
+		log.Printf("RotateLeft %v, %v\n", x.impl, k.impl)
+	}
+	name := "llvm.fshl.i" + strconv.Itoa(x.ll.IntTypeWidth())
+	return Expr{b.intrinsic(name, x.ll, x.impl, x.impl, b.castInt(k, x.Type).impl), x.Type}
+}
+
+// intrinsic calls the LLVM intrinsic name, whose result is of type ret, with
+// args.
+func (b Builder) intrinsic(name string, ret llvm.Type, args ...llvm.Value) llvm.Value {
+	params := make([]llvm.Type, len(args))
+	for i, arg := range args {
+		params[i] = arg.Type()
+	}
+	ft := llvm.FunctionType(ret, params, false)
+	mod := b.fn.pkg.mod
+	fn := mod.NamedFunction(name)
+	if fn.IsNil() {
+		fn = llvm.AddFunction(mod, name, ft)
+	}
+	return llvm.CreateCall(b.impl, ft, fn, args)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,394 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// C functions are declared with the LLVM types of the C ABI of the target,
+// which differ from the ones of Go functions for aggregates (structs and
+// arrays): LLVM passes the fields of an aggregate as separate arguments,
+// while C passes a small aggregate packed in registers, and a large one in
+// memory. The classification of aggregates depends on the target:
+//
+//   - x86-64 System V: an aggregate of up to 16 bytes is split into
+//     eightbytes, which are passed in integer registers, or in SSE registers
+//     if they only contain floats, eg. struct{x, y float32; n int32} is
+//     coerced to {<2 x float>, i32}. A larger aggregate, or one that doesn't
+//     fit in the remaining registers, is passed on the stack (byval).
+//   - AArch64 AAPCS: a homogeneous floating-point aggregate of up to four
+//     floats or doubles is passed in SIMD registers, as [n x float]. Another
+//     aggregate of up to 16 bytes is coerced to i64 or [2 x i64], and a
+//     larger one is passed by a pointer to a copy made by the caller.
+//   - other targets: an aggregate of up to two pointer words is coerced to
+//     integers of the same size, and a larger one is passed byval.
+//
+// On all targets, an aggregate result that isn't returned in registers is
+// returned in memory allocated by the caller, whose address is passed as a
+// hidden first parameter (sret).
+
+// cPass is how a parameter or the result of a C function is passed.
+type cPass int
+
+const (
+	cDirect   cPass = iota // as its LLVM type
+	cCoerce                // as the LLVM type abi, of the same size
+	cByval                 // on the stack, by a pointer with the byval attribute
+	cIndirect              // by a pointer to a copy, or for the result, sret
+)
+
+type cParam struct {
+	pass cPass
+	typ  llvm.Type // LLVM type of the Go value
+	abi  llvm.Type // type that the value is passed as
+}
+
+type cFunc struct {
+	params []cParam
+	ret    cParam
+	ft     llvm.Type // LLVM type of the C function
+}
+
+// cRegs are the argument registers of x86-64 that remain available.
+type cRegs struct {
+	ints int
+	sses int
+}
+
+// cFuncOf returns how the parameters and the result of the C function whose
+// Go signature is sig are passed.
+func (p Program) cFuncOf(sig *types.Signature) *cFunc {
+	ft := p.llvmSignature(sig).ll
+	regs := &cRegs{ints: 6, sses: 8}
+	ret := &cFunc{ret: p.cParamOf(ft.ReturnType(), true, regs)}
+	params := make([]llvm.Type, 0, ft.ParamTypesCount()+1)
+	if ret.ret.pass == cIndirect {
+		params = append(params, ret.ret.abi)
+		regs.ints--
+	}
+	for _, t := range ft.ParamTypes() {
+		param := p.cParamOf(t, false, regs)
+		ret.params = append(ret.params, param)
+		params = append(params, param.abi)
+	}
+	tret := ret.ret.abi
+	if ret.ret.pass == cIndirect {
+		tret = p.tyVoid()
+	}
+	ret.ft = llvm.FunctionType(tret, params, ft.IsFunctionVarArg())
+	return ret
+}
+
+// cParamOf returns how a value of the LLVM type t is passed to a C function,
+// or returned by it if ret. regs are the registers of x86-64 that remain for
+// the parameters.
+func (p Program) cParamOf(t llvm.Type, ret bool, regs *cRegs) cParam {
+	switch p.target.goarch() {
+	case "amd64":
+		return p.cParamAMD64(t, ret, regs)
+	case "arm64":
+		return p.cParamARM64(t, ret)
+	}
+	if !isAggregate(t) {
+		return cParam{cDirect, t, t}
+	}
+	size, word := int(p.td.TypeAllocSize(t)), p.td.PointerSize()
+	switch {
+	case size > 2*word:
+		return p.cMemory(t, ret, cByval)
+	case size > word:
+		ints := []llvm.Type{p.ctx.IntType(word * 8), p.ctx.IntType((size - word) * 8)}
+		return cParam{cCoerce, t, p.ctx.StructType(ints, false)}
+	case size > 0:
+		return cParam{cCoerce, t, p.ctx.IntType(size * 8)}
+	}
+	return cParam{cDirect, t, t}
+}
+
+// cMemory returns a parameter of type t passed in memory, by pass, or a
+// result returned in memory if ret.
+func (p Program) cMemory(t llvm.Type, ret bool, pass cPass) cParam {
+	if ret {
+		pass = cIndirect
+	}
+	return cParam{pass, t, llvm.PointerType(t, 0)}
+}
+
+func isAggregate(t llvm.Type) bool {
+	switch t.TypeKind() {
+	case llvm.StructTypeKind, llvm.ArrayTypeKind:
+		return true
+	}
+	return false
+}
+
+// Classes of the eightbytes of an aggregate, by the x86-64 System V ABI. The
+// SSE class is split into the ones of floats and doubles to find the type
+// of the eightbyte.
+const (
+	clsNone = iota
+	clsFloat
+	clsDouble
+	clsInteger
+)
+
+func (p Program) cParamAMD64(t llvm.Type, ret bool, regs *cRegs) cParam {
+	if !isAggregate(t) {
+		switch kind := t.TypeKind(); {
+		case ret:
+		case kind == llvm.FloatTypeKind || kind == llvm.DoubleTypeKind:
+			regs.sses--
+		default:
+			regs.ints--
+		}
+		return cParam{cDirect, t, t}
+	}
+	size := int(p.td.TypeAllocSize(t))
+	if size == 0 {
+		return cParam{cDirect, t, t}
+	}
+	if size > 16 {
+		return p.cMemory(t, ret, cByval)
+	}
+	var cls [2]int
+	p.classifyAMD64(t, 0, cls[:])
+	n := (size + 7) / 8
+	elems := make([]llvm.Type, n)
+	ints, sses := 0, 0
+	for i := range elems {
+		bytes := size - i*8
+		if bytes > 8 {
+			bytes = 8
+		}
+		switch cls[i] {
+		case clsFloat:
+			elems[i] = p.ctx.FloatType()
+			if bytes > 4 {
+				elems[i] = llvm.VectorType(elems[i], 2)
+			}
+			sses++
+		case clsDouble:
+			elems[i] = p.ctx.DoubleType()
+			sses++
+		default: // padding is passed as integers
+			elems[i] = p.ctx.IntType(bytes * 8)
+			ints++
+		}
+	}
+	if !ret {
+		if ints > regs.ints || sses > regs.sses {
+			return p.cMemory(t, ret, cByval)
+		}
+		regs.ints -= ints
+		regs.sses -= sses
+	}
+	if n == 1 {
+		return cParam{cCoerce, t, elems[0]}
+	}
+	return cParam{cCoerce, t, p.ctx.StructType(elems, false)}
+}
+
+// classifyAMD64 merges the classes of the scalars of the value of type t at
+// offset off of an aggregate into the classes cls of its eightbytes.
+func (p Program) classifyAMD64(t llvm.Type, off uint64, cls []int) {
+	switch t.TypeKind() {
+	case llvm.StructTypeKind:
+		for i, elem := range t.StructElementTypes() {
+			p.classifyAMD64(elem, off+p.td.ElementOffset(t, i), cls)
+		}
+	case llvm.ArrayTypeKind:
+		elem := t.ElementType()
+		size := p.td.TypeAllocSize(elem)
+		for i := 0; i < t.ArrayLength(); i++ {
+			p.classifyAMD64(elem, off+uint64(i)*size, cls)
+		}
+	case llvm.FloatTypeKind:
+		if c := &cls[off/8]; *c == clsNone {
+			*c = clsFloat
+		}
+	case llvm.DoubleTypeKind:
+		if c := &cls[off/8]; *c == clsNone {
+			*c = clsDouble
+		}
+	default:
+		cls[off/8] = clsInteger
+	}
+}
+
+func (p Program) cParamARM64(t llvm.Type, ret bool) cParam {
+	if !isAggregate(t) {
+		return cParam{cDirect, t, t}
+	}
+	size := int(p.td.TypeAllocSize(t))
+	if size == 0 {
+		return cParam{cDirect, t, t}
+	}
+	if elem, n := hfaOf(t); n > 0 && n <= 4 {
+		return cParam{cCoerce, t, llvm.ArrayType(elem, n)}
+	}
+	switch {
+	case size > 16:
+		return p.cMemory(t, ret, cIndirect)
+	case size <= 8 && ret:
+		return cParam{cCoerce, t, p.ctx.IntType(size * 8)}
+	case size <= 8:
+		return cParam{cCoerce, t, p.ctx.Int64Type()}
+	case p.td.ABITypeAlignment(t) == 16:
+		return cParam{cCoerce, t, p.ctx.IntType(128)}
+	}
+	return cParam{cCoerce, t, llvm.ArrayType(p.ctx.Int64Type(), 2)}
+}
+
+// hfaOf returns the element type and the number of elements of t if it is a
+// homogeneous floating-point aggregate: all its scalars are either floats or
+// doubles. n is 0 otherwise.
+func hfaOf(t llvm.Type) (elem llvm.Type, n int) {
+	switch t.TypeKind() {
+	case llvm.StructTypeKind:
+		for _, fld := range t.StructElementTypes() {
+			e, m := hfaOf(fld)
+			if m == 0 || (n > 0 && e != elem) {
+				return elem, 0
+			}
+			elem, n = e, n+m
+		}
+		return
+	case llvm.ArrayTypeKind:
+		if elem, n = hfaOf(t.ElementType()); n > 0 {
+			n *= t.ArrayLength()
+		}
+		return
+	case llvm.FloatTypeKind, llvm.DoubleTypeKind:
+		return t, 1
+	}
+	return elem, 0
+}
+
+// addCAttrs adds the byval and sret attributes of the parameters of fn that
+// are passed in memory by add, eg. the AddCallSiteAttribute of a call.
+func (p Program) addCAttrs(add func(i int, a llvm.Attribute), fn *cFunc) {
+	first := 1 // index of the attributes of the first parameter
+	if fn.ret.pass == cIndirect {
+		add(first, p.ctx.CreateTypeAttribute(llvm.AttributeKindID("sret"), fn.ret.typ))
+		first++
+	}
+	for i, param := range fn.params {
+		if param.pass == cByval {
+			add(first+i, p.ctx.CreateTypeAttribute(llvm.AttributeKindID("byval"), param.typ))
+		}
+	}
+}
+
+// NewCFunc creates the declaration of the C function name, whose Go signature
+// is sig, following the C ABI of the target for aggregates (see cPass). The
+// function can only be called by Builder.Call, not used as a func value.
+func (p Package) NewCFunc(name string, sig *types.Signature) Function {
+	prog := p.prog
+	cfn := prog.cFuncOf(sig)
+	fn := llvm.AddFunction(p.mod, name, cfn.ft)
+	prog.addCAttrs(fn.AddAttributeAtIndex, cfn)
+	ret := newFunction(fn, &aType{cfn.ft, sig, vkCFunc}, p, prog)
+	p.fns[name] = ret
+	return ret
+}
+
+// cPromote returns the variadic argument x of a C function with the default
+// argument promotions of C: float is passed as double, and the integers
+// narrower than int, including bool, as int.
+func (b Builder) cPromote(x Expr) llvm.Value {
+	t := x.impl.Type()
+	switch t.TypeKind() {
+	case llvm.FloatTypeKind:
+		return b.impl.CreateFPExt(x.impl, b.prog.ctx.DoubleType(), "")
+	case llvm.IntegerTypeKind:
+		if t.IntTypeWidth() >= 32 {
+			break
+		}
+		tint := b.prog.ctx.Int32Type()
+		if x.kind == vkSigned {
+			return b.impl.CreateSExt(x.impl, tint, "")
+		}
+		return b.impl.CreateZExt(x.impl, tint, "")
+	}
+	return x.impl
+}
+
+// callC calls the C function fn, whose Go signature is sig, with args.
+func (b Builder) callC(fn Expr, sig *types.Signature, args []Expr) Expr {
+	prog := b.prog
+	cfn := prog.cFuncOf(sig)
+	vals := make([]llvm.Value, 0, len(args)+1)
+	var sret llvm.Value
+	if cfn.ret.pass == cIndirect {
+		sret = b.cTemp(cfn.ret)
+		vals = append(vals, sret)
+	}
+	for i, arg := range args {
+		if i >= len(cfn.params) { // variadic arguments
+			vals = append(vals, b.cPromote(arg))
+			continue
+		}
+		switch param := cfn.params[i]; param.pass {
+		case cCoerce:
+			tmp := b.cTemp(param)
+			b.impl.CreateStore(arg.impl, tmp)
+			vals = append(vals, llvm.CreateLoad(b.impl, param.abi, tmp))
+		case cByval, cIndirect:
+			tmp := b.cTemp(param)
+			b.impl.CreateStore(arg.impl, tmp)
+			vals = append(vals, tmp)
+		default:
+			vals = append(vals, arg.impl)
+		}
+	}
+	call := b.call(cfn.ft, fn.impl, vals)
+	prog.addCAttrs(call.AddCallSiteAttribute, cfn)
+	ret := Expr{call, prog.retType(sig)}
+	switch cfn.ret.pass {
+	case cCoerce:
+		tmp := b.cTemp(cfn.ret)
+		b.impl.CreateStore(call, tmp)
+		ret.impl = llvm.CreateLoad(b.impl, cfn.ret.typ, tmp)
+	case cIndirect:
+		ret.impl = llvm.CreateLoad(b.impl, cfn.ret.typ, sret)
+	}
+	return ret
+}
+
+// cTemp allocates the memory that a value of param is passed through: it is
+// large enough, and aligned enough, for both the Go value and its ABI type.
+func (b Builder) cTemp(param cParam) llvm.Value {
+	td := b.prog.td
+	t := param.typ
+	if param.pass == cCoerce && td.TypeAllocSize(param.abi) > td.TypeAllocSize(t) {
+		t = param.abi
+	}
+	align := td.ABITypeAlignment(t)
+	if word := td.PointerSize(); align < word {
+		align = word
+	}
+	ret := b.entryAlloca(t)
+	ret.SetAlignment(align)
+	return ret
+}
+
+// -----------------------------------------------------------------------------
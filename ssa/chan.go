@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// SelectState represents a communication case of a select statement.
+type SelectState struct {
+	Chan  Expr // channel to use (for send or receive)
+	Value Expr // value to send (for send)
+	Send  bool // direction of case (true for send, false for receive)
+}
+
+// The Select instruction tests whether (or blocks until) one
+// of the specified sent or received states is entered.
+//
+// Let n be the number of States for which Dir==RECV and T_i (0<=i<n)
+// be the element type of each such state's Chan.
+// Select returns an n+2-tuple
+//
+//	(index int, recvOk bool, r_0 T_0, ... r_n-1 T_n-1)
+//
+// The tuple's components, described below, must be accessed via the
+// Extract instruction.
+//
+// If Blocking, select waits until exactly one state holds, i.e. a
+// channel becomes ready for the designated operation of sending or
+// receiving; select chooses one among the ready states
+// pseudorandomly, performs the send or receive operation, and sets
+// 'index' to the index of the chosen channel.
+//
+// If !Blocking, select doesn't block if no states hold; instead it
+// returns immediately with index equal to -1.
+//
+// If the chosen channel was used for a receive, the r_i component is
+// set to the received value, where i is the index of that state among
+// all n receive states; otherwise r_i has the zero value of type T_i.
+// Note that the receive index i is not the same as the state
+// index index.
+//
+// The second component of the triple, recvOk, is a boolean whose value
+// is true iff the selected operation was a receive and the receive
+// successfully yielded a value.
+func (b Builder) Select(states []*SelectState, blocking bool) Expr {
+	if debugInstr {
+		log.Printf("Select %v, %v\n", len(states), blocking)
+	}
+	if !blocking {
+		if len(states) == 1 {
+			return b.trySelect(states[0])
+		}
+		return b.rtSelect(states, "Select")
+	}
+	return b.rtSelect(states, "SelectBlock")
+}
+
+// trySelect implements a select statement that has exactly one communication
+// case and a default case, by a non-blocking send or receive instead of the
+// generic select runtime:
+//
+//	case ch <- v:       =>  selected := runtime.ChanTrySend(ch, &v, sizeof(v))
+//	case v, ok := <-ch: =>  selected, ok := runtime.ChanTryRecv(ch, &v, sizeof(v))
+func (b Builder) trySelect(state *SelectState) Expr {
+	prog := b.prog
+	elem := prog.Type(state.Chan.t.Underlying().(*types.Chan).Elem())
+	ptr := b.alloca(elem)
+	ptr.Type = prog.Type(tyUnsafePtr)
+	params := []types.Type{tyUnsafePtr, tyUnsafePtr, tyInt}
+	var selected, recvOK Expr
+	tret := []types.Type{tyInt, tyBool}
+	if state.Send {
+		b.impl.CreateStore(state.Value.impl, ptr.impl)
+		fn := b.rtFunc("ChanTrySend", params, []types.Type{tyBool})
+		selected = b.Call(fn, state.Chan, ptr, b.sizeof(elem))
+		recvOK = prog.BoolVal(false)
+	} else {
+		fn := b.rtFunc("ChanTryRecv", params, []types.Type{tyBool, tyBool})
+		ret := b.Call(fn, state.Chan, ptr, b.sizeof(elem))
+		selected = b.Extract(ret, 0)
+		recvOK = b.Extract(ret, 1)
+		tret = append(tret, elem.t)
+	}
+	idx := b.impl.CreateSelect(selected.impl, prog.Val(0).impl, prog.Val(-1).impl, "")
+	flds := []llvm.Value{idx, recvOK.impl}
+	if !state.Send {
+		flds = append(flds, llvm.CreateLoad(b.impl, elem.ll, ptr.impl))
+	}
+	return b.aggregateValue(prog.Type(newTuple(tret...)), flds...)
+}
+
+// tySelectCase is the type of the cases of runtime.Select, which must match
+// runtime.SelectCase.
+var tySelectCase = types.NewStruct([]*types.Var{
+	types.NewField(0, nil, "ch", tyUnsafePtr, false),
+	types.NewField(0, nil, "val", tyUnsafePtr, false),
+	types.NewField(0, nil, "eltSize", tyInt, false),
+	types.NewField(0, nil, "send", tyBool, false),
+}, nil)
+
+// rtSelect implements a select statement by the runtime function fn, which
+// tries the cases in a pseudo-random order: runtime.Select for one that has
+// several communication cases and a default case, or runtime.SelectBlock,
+// which blocks until a case is ready, for one without default case:
+//
+//	cases := [n]runtime.SelectCase{{ch, &v, sizeof(v), send}, ...}
+//	selected, ok := runtime.Select(&cases[0], n)
+//
+// The values of the receive cases are zeroed first, as only the selected one
+// is received.
+func (b Builder) rtSelect(states []*SelectState, fn string) Expr {
+	prog := b.prog
+	n := len(states)
+	tcase := prog.Type(tySelectCase)
+	cases := b.alloca(prog.Type(types.NewArray(tySelectCase, int64(n))))
+	tret := []types.Type{tyInt, tyBool}
+	var recvs []Expr
+	for i, state := range states {
+		elem := prog.Type(state.Chan.t.Underlying().(*types.Chan).Elem())
+		ptr := b.alloca(elem)
+		if state.Send {
+			b.impl.CreateStore(state.Value.impl, ptr.impl)
+		} else {
+			b.impl.CreateStore(llvm.ConstNull(elem.ll), ptr.impl)
+			recvs = append(recvs, ptr)
+			tret = append(tret, elem.t)
+		}
+		c := b.aggregateValue(tcase, state.Chan.impl, ptr.impl, b.sizeof(elem).impl, prog.BoolVal(state.Send).impl)
+		b.Store(b.IndexAddr(cases, prog.Val(i)), c)
+	}
+	params := []types.Type{tyUnsafePtr, tyInt}
+	sel := b.rtFunc(fn, params, []types.Type{tyInt, tyBool})
+	cases.Type = prog.Type(tyUnsafePtr)
+	ret := b.Call(sel, cases, prog.Val(n))
+	flds := []llvm.Value{b.Extract(ret, 0).impl, b.Extract(ret, 1).impl}
+	for _, ptr := range recvs {
+		flds = append(flds, b.Load(ptr).impl)
+	}
+	return b.aggregateValue(prog.Type(newTuple(tret...)), flds...)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// Func values are closures: a pointer to a function, which takes the context
+// of the closure before its parameters, and the context, a pointer, eg. to the
+// free variables of an anonymous function (see MakeClosure). They are called
+// as fn(ctx, args...), so that a closure costs no more than an indirect call.
+//
+// C calls functions by pointers, without a context, so func values are
+// converted to C function pointers, whose types are named in C packages (see
+// IsCFuncType), eg. when they are passed to C as callbacks: a function of a
+// package is called by C through a wrapper (see CFunc), and other func values
+// through trampolines (see Callback).
+
+// HasTrampolines reports whether func values, other than functions of
+// packages, can be converted to C function pointers on the target, ie.
+// whether the runtime writes trampolines for it (see Callback).
+func (p Program) HasTrampolines() bool {
+	switch p.target.goarch() {
+	case "amd64", "386":
+		return true
+	}
+	return false
+}
+
+// The MakeClosure instruction yields the func value of type t of a closure,
+// which calls the function fn with the free variables bindings before its
+// arguments:
+//
+//	func(args...) { fn(bindings..., args...) }  =>  {fn$stub, &struct{bindings...}}
+//
+// fn$stub is a private function of the package that unpacks the bindings of
+// its context, its first parameter, and calls fn with its other parameters.
+// Without bindings, eg. for the func value of a function of a package, the
+// context is nil, and fn$stub ignores it.
+func (b Builder) MakeClosure(fn Expr, t Type, bindings ...Expr) Expr {
+	if debugInstr {
+		log.Printf("MakeClosure %v, %v\n", fn.impl.Name(), bindings)
+	}
+	prog := b.prog
+	flds := make([]*types.Var, len(bindings))
+	for i, v := range bindings {
+		flds[i] = types.NewField(token.NoPos, nil, "_", v.Type.t, false)
+	}
+	tstruc := prog.Type(types.NewStruct(flds, nil))
+	ctx := prog.Null(prog.Type(tyUnsafePtr))
+	if len(bindings) > 0 {
+		ctx = b.allocZ(tstruc)
+		for i, v := range bindings {
+			b.Store(b.FieldAddr(ctx, i), v)
+		}
+	}
+	stub := b.fn.pkg.closureStub(fn, t, tstruc, len(bindings))
+	tfn := t.ll.StructElementTypes()[0]
+	return b.aggregateValue(t, b.impl.CreatePointerCast(stub.impl, tfn, ""),
+		b.impl.CreatePointerCast(ctx.impl, prog.tyVoidPtr(), ""))
+}
+
+// funcValue returns the func value of the function fn of the package, whose
+// context is nil (see MakeClosure).
+func (b Builder) funcValue(fn Expr) Expr {
+	return b.MakeClosure(fn, b.prog.Type(fn.t))
+}
+
+// closureStub returns fn$stub, which calls fn with the nbindings fields of the
+// struct of type tstruc that its first parameter points to, and its other
+// parameters, which are the ones of the func type t.
+func (p Package) closureStub(fn Expr, t Type, tstruc Type, nbindings int) Expr {
+	name := fn.impl.Name() + "$stub"
+	if ret := p.FuncOf(name); ret != nil {
+		return ret.Expr
+	}
+	sig := t.t.Underlying().(*types.Signature)
+	ret := p.NewFunc(name, closureSig(sig))
+	ret.impl.SetLinkage(llvm.PrivateLinkage)
+	b := ret.MakeBody(1)
+	n := sig.Params().Len()
+	args := make([]Expr, 0, nbindings+n)
+	if nbindings > 0 {
+		ctx := ret.Param(0)
+		ctx.Type = p.prog.Pointer(tstruc)
+		for i := 0; i < nbindings; i++ {
+			args = append(args, b.Load(b.FieldAddr(ctx, i)))
+		}
+	}
+	for i := 1; i <= n; i++ {
+		args = append(args, ret.Param(i))
+	}
+	if r := b.TailCall(fn, args...); sig.Results().Len() > 0 {
+		b.Return(r)
+	} else {
+		b.Return()
+	}
+	return ret.Expr
+}
+
+// callClosure calls the func value fn, of signature sig, with args.
+func (b Builder) callClosure(fn Expr, sig *types.Signature, args []Expr) Expr {
+	prog := b.prog
+	ft := prog.llvmSignature(closureSig(sig)).ll
+	vals := make([]llvm.Value, 0, len(args)+1)
+	vals = append(vals, b.impl.CreateExtractValue(fn.impl, 1, ""))
+	vals = append(vals, llvmValues(args)...)
+	call := b.call(ft, b.impl.CreateExtractValue(fn.impl, 0, ""), vals)
+	return Expr{call, prog.retType(sig)}
+}
+
+// -----------------------------------------------------------------------------
+
+// CFunc returns the C function pointer of type t (see IsCFuncType) to the
+// function fn of the package, whose signature must be the one of t, with
+// parameters and a result that C passes as Go does (see CDirect):
+//
+//	c.SigHandler(f)  =>  f$callback
+//
+// f$callback is a private function of the package that calls
+// runtime.EnterCallback and then f, as C may call it on a thread that the
+// runtime didn't create. If raw, eg. for the functions of the runtime, which
+// C calls on the threads that the runtime creates or in signal handlers, C
+// calls fn directly.
+func (b Builder) CFunc(t Type, fn Expr, raw bool) Expr {
+	if debugInstr {
+		log.Printf("CFunc %v, %v, %v\n", t.t, fn.impl.Name(), raw)
+	}
+	if !raw {
+		b.Call(b.rtFunc("EnableCallbacks", nil, nil))
+		fn = b.fn.pkg.callbackWrapper(fn)
+	}
+	return Expr{b.impl.CreatePointerCast(fn.impl, t.ll, ""), t}
+}
+
+// callbackWrapper returns fn$callback, which calls runtime.EnterCallback and
+// then fn with its parameters (see CFunc).
+func (p Package) callbackWrapper(fn Expr) Expr {
+	name := fn.impl.Name() + "$callback"
+	if ret := p.FuncOf(name); ret != nil {
+		return ret.Expr
+	}
+	sig := fn.t.(*types.Signature)
+	ret := p.NewFunc(name, sig)
+	ret.impl.SetLinkage(llvm.PrivateLinkage)
+	b := ret.MakeBody(1)
+	b.Call(b.rtFunc("EnterCallback", nil, nil))
+	args := make([]Expr, sig.Params().Len())
+	for i := range args {
+		args[i] = ret.Param(i)
+	}
+	if r := b.Call(fn, args...); sig.Results().Len() > 0 {
+		b.Return(r)
+	} else {
+		b.Return()
+	}
+	return ret.Expr
+}
+
+// Callback converts the func value x to the C function pointer type t (see
+// IsCFuncType), whose signature must be the one of x, with parameters and a
+// result that C passes as Go does (see CDirect), by a trampoline:
+//
+//	c.SigHandler(x)  =>  runtime.NewCallback(x.fn, x.ctx, __llgo_callback.T)
+//
+// runtime.NewCallback writes the function and the context of x to the entry
+// of a trampoline, whose code passes the address of the entry to the wrapper
+// __llgo_callback.T in the register of nest parameters, eg. r10 on amd64, and
+// jumps to it. The wrapper, which is generated once for the signature T of t,
+// calls runtime.EnterCallback and then x. The target must have trampolines
+// (see HasTrampolines).
+func (b Builder) Callback(t Type, x Expr) Expr {
+	if debugInstr {
+		log.Printf("Callback %v, %v\n", t.t, x.impl)
+	}
+	prog := b.prog
+	voidPtr := prog.Type(tyUnsafePtr)
+	fn := Expr{b.impl.CreatePointerCast(b.impl.CreateExtractValue(x.impl, 0, ""), voidPtr.ll, ""), voidPtr}
+	ctx := Expr{b.impl.CreateExtractValue(x.impl, 1, ""), voidPtr}
+	wrapper := b.fn.pkg.trampWrapper(x.t.Underlying().(*types.Signature))
+	wrapper = Expr{b.impl.CreatePointerCast(wrapper.impl, voidPtr.ll, ""), voidPtr}
+	params := []types.Type{tyUnsafePtr, tyUnsafePtr, tyUnsafePtr}
+	ret := b.Call(b.rtFunc("NewCallback", params, []types.Type{tyUnsafePtr}), fn, ctx, wrapper)
+	return Expr{b.impl.CreatePointerCast(ret.impl, t.ll, ""), t}
+}
+
+// trampWrapper returns __llgo_callback.T for the signature sig, which calls
+// runtime.EnterCallback and then the func value that its nest parameter
+// points to, with its other parameters (see Callback).
+func (p Package) trampWrapper(sig *types.Signature) Expr {
+	tsig := closureSig(sig)
+	fn, b, ok := p.keyFunc("__llgo_callback.", sig, tsig)
+	if ok {
+		return fn.Expr
+	}
+	prog := p.prog
+	fn.impl.AddAttributeAtIndex(1, prog.ctx.CreateEnumAttribute(llvm.AttributeKindID("nest"), 0))
+	b.Call(b.rtFunc("EnterCallback", nil, nil))
+	closure := fn.Param(0)
+	closure.Type = prog.Pointer(prog.Type(sig))
+	n := sig.Params().Len()
+	args := make([]Expr, n)
+	for i := range args {
+		args[i] = fn.Param(i + 1)
+	}
+	if r := b.Call(b.Load(closure), args...); sig.Results().Len() > 0 {
+		b.Return(r)
+	} else {
+		b.Return()
+	}
+	return fn.Expr
+}
+
+// CDirect reports whether C passes the parameters and the result of the
+// functions of signature sig as Go does, eg. integers, floats and pointers,
+// rather than coercing them or passing them in memory: only such functions
+// can be converted to C function pointers (see CFunc and Callback).
+func (p Program) CDirect(sig *types.Signature) bool {
+	cfn := p.cFuncOf(sig)
+	if cfn.ret.pass != cDirect {
+		return false
+	}
+	for _, param := range cfn.params {
+		if param.pass != cDirect {
+			return false
+		}
+	}
+	return !sig.Variadic()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// A complex value is represented as a {real, imag} LLVM struct, that is
+// {float, float} for complex64 and {double, double} for complex128.
+
+// complexElem returns the type of the real/imag part of a complex type.
+func (p Program) complexElem(t Type) Type {
+	if t.t.Underlying().(*types.Basic).Kind() == types.Complex64 {
+		return p.Float32()
+	}
+	return p.Float64()
+}
+
+// complexOf returns the complex type whose parts are of type t.
+func (p Program) complexOf(t Type) Type {
+	if t.t.Underlying().(*types.Basic).Kind() == types.Float32 {
+		return p.Type(types.Typ[types.Complex64])
+	}
+	return p.Type(types.Typ[types.Complex128])
+}
+
+func (b Builder) complexParts(x Expr) (re, im llvm.Value) {
+	re = b.impl.CreateExtractValue(x.impl, 0, "")
+	im = b.impl.CreateExtractValue(x.impl, 1, "")
+	return
+}
+
+func (b Builder) makeComplex(re, im llvm.Value, t Type) Expr {
+	return b.aggregateValue(t, re, im)
+}
+
+// complexOp implements + - * / on complex values.
+func (b Builder) complexOp(op token.Token, x, y Expr) Expr {
+	if op == token.QUO {
+		return b.complexDiv(x, y)
+	}
+	xr, xi := b.complexParts(x)
+	yr, yi := b.complexParts(y)
+	fop := func(op llvm.Opcode, lhs, rhs llvm.Value) llvm.Value {
+		return llvm.CreateBinOp(b.impl, op, lhs, rhs)
+	}
+	var re, im llvm.Value
+	switch op {
+	case token.ADD:
+		re, im = fop(llvm.FAdd, xr, yr), fop(llvm.FAdd, xi, yi)
+	case token.SUB:
+		re, im = fop(llvm.FSub, xr, yr), fop(llvm.FSub, xi, yi)
+	case token.MUL: // (a+bi)(c+di) = (ac-bd) + (ad+bc)i
+		re = fop(llvm.FSub, fop(llvm.FMul, xr, yr), fop(llvm.FMul, xi, yi))
+		im = fop(llvm.FAdd, fop(llvm.FMul, xr, yi), fop(llvm.FMul, xi, yr))
+	default:
+		panic("complexOp: invalid operator - " + op.String())
+	}
+	return b.makeComplex(re, im, x.Type)
+}
+
+// complexDiv implements / on complex values by runtime.Complex128Div, as gc
+// does: it scales the operands so that the intermediate results don't
+// overflow, and handles infinities and NaNs. complex64 values are divided as
+// complex128 ones.
+func (b Builder) complexDiv(x, y Expr) Expr {
+	prog := b.prog
+	tc128 := types.Typ[types.Complex128]
+	fn := b.rtFunc("Complex128Div", []types.Type{tc128, tc128}, []types.Type{tc128})
+	t := prog.Type(tc128)
+	if x.ll == t.ll {
+		ret := b.Call(fn, x, y)
+		ret.Type = x.Type
+		return ret
+	}
+	f64 := prog.Float64().ll
+	ext := func(v Expr) Expr {
+		re, im := b.complexParts(v)
+		return b.makeComplex(b.impl.CreateFPExt(re, f64, ""), b.impl.CreateFPExt(im, f64, ""), t)
+	}
+	re, im := b.complexParts(b.Call(fn, ext(x), ext(y)))
+	f32 := prog.complexElem(x.Type).ll
+	return b.makeComplex(b.impl.CreateFPTrunc(re, f32, ""), b.impl.CreateFPTrunc(im, f32, ""), x.Type)
+}
+
+// complexCmp implements == and != on complex values.
+func (b Builder) complexCmp(op token.Token, x, y Expr) Expr {
+	xr, xi := b.complexParts(x)
+	yr, yi := b.complexParts(y)
+	tret := b.prog.Bool()
+	switch op {
+	case token.EQL:
+		re := llvm.CreateFCmp(b.impl, llvm.FloatOEQ, xr, yr)
+		im := llvm.CreateFCmp(b.impl, llvm.FloatOEQ, xi, yi)
+		return Expr{llvm.CreateBinOp(b.impl, llvm.And, re, im), tret}
+	case token.NEQ:
+		re := llvm.CreateFCmp(b.impl, llvm.FloatUNE, xr, yr)
+		im := llvm.CreateFCmp(b.impl, llvm.FloatUNE, xi, yi)
+		return Expr{llvm.CreateBinOp(b.impl, llvm.Or, re, im), tret}
+	}
+	panic("complexCmp: invalid operator - " + op.String())
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"fmt"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// convert converts x to type t, where the underlying types of x and t are
+// numbers or pointers (see Convert):
+//
+//	int64(i)              =>  sext i, trunc i or i, by the sizes of the types
+//	float64(i)            =>  sitofp i (uitofp for an unsigned i)
+//	int(f)                =>  fptosi f (fptoui for an unsigned result)
+//	float32(f)            =>  fptrunc f (fpext from float32)
+//	complex64(c)          =>  the parts of c converted as floats
+//	unsafe.Pointer(p)     =>  the pointer p
+//	uintptr(p)            =>  ptrtoint p
+//	unsafe.Pointer(u)     =>  inttoptr u
+//
+// The values of types with identical underlying types, eg. the ones of a type
+// parameter, are reinterpreted as t (see ChangeType). Other conversions are
+// unsupported.
+func (b Builder) convert(t Type, x Expr) Expr {
+	switch {
+	case x.ll == t.ll:
+		return Expr{x.impl, t}
+	case isInt(x.kind) && isInt(t.kind):
+		return b.castInt(x, t)
+	case isInt(x.kind) && t.kind == vkFloat:
+		if x.kind == vkSigned {
+			return Expr{b.impl.CreateSIToFP(x.impl, t.ll, ""), t}
+		}
+		return Expr{b.impl.CreateUIToFP(x.impl, t.ll, ""), t}
+	case x.kind == vkFloat && isInt(t.kind):
+		if t.kind == vkSigned {
+			return Expr{b.impl.CreateFPToSI(x.impl, t.ll, ""), t}
+		}
+		return Expr{b.impl.CreateFPToUI(x.impl, t.ll, ""), t}
+	case x.kind == vkFloat && t.kind == vkFloat:
+		return Expr{b.castFloat(x.impl, t.ll), t}
+	case x.kind == vkComplex && t.kind == vkComplex:
+		re, im := b.complexParts(x)
+		tf := b.prog.complexElem(t).ll
+		return b.makeComplex(b.castFloat(re, tf), b.castFloat(im, tf), t)
+	}
+	xptr := x.ll.TypeKind() == llvm.PointerTypeKind
+	tptr := t.ll.TypeKind() == llvm.PointerTypeKind
+	switch {
+	case xptr && tptr:
+		return Expr{b.impl.CreatePointerCast(x.impl, t.ll, ""), t}
+	case xptr && isInt(t.kind):
+		return Expr{b.impl.CreatePtrToInt(x.impl, t.ll, ""), t}
+	case isInt(x.kind) && tptr:
+		return Expr{b.impl.CreateIntToPtr(x.impl, t.ll, ""), t}
+	}
+	if types.Identical(x.t.Underlying(), t.t.Underlying()) {
+		return b.changeType(t, x)
+	}
+	panic(fmt.Sprintf("unsupported conversion from %v to %v", x.t, t.t))
+}
+
+// isInt reports whether the values of kind are integers.
+func isInt(kind valueKind) bool {
+	return kind == vkSigned || kind == vkUnsigned
+}
+
+// castFloat converts the floating-point number x to the floating-point type t.
+func (b Builder) castFloat(x llvm.Value, t llvm.Type) llvm.Value {
+	switch xt := x.Type(); {
+	case xt == t:
+		return x
+	case xt.TypeKind() == llvm.DoubleTypeKind:
+		return b.impl.CreateFPTrunc(x, t, "")
+	default:
+		return b.impl.CreateFPExt(x, t, "")
+	}
+}
+
+// The ChangeType instruction applies to X a value-preserving type
+// change to Type(), which has the same underlying type as X, or is a
+// channel or pointer type whose underlying type only differs from the one
+// of X by its direction or its struct tags.
+//
+// Example printed form:
+//
+//	t1 = changetype *int <- IntPtr (t0)
+func (b Builder) ChangeType(t Type, x Expr) Expr {
+	if debugInstr {
+		log.Printf("ChangeType %v, %v\n", t.t, x.impl)
+	}
+	return b.changeType(t, x)
+}
+
+// changeType reinterprets x as t, whose underlying type is identical to the
+// one of x, or whose LLVM type is a pointer like the one of x.
+func (b Builder) changeType(t Type, x Expr) Expr {
+	switch {
+	case x.ll == t.ll:
+		return Expr{x.impl, t}
+	case x.ll.TypeKind() == llvm.PointerTypeKind:
+		return Expr{b.impl.CreatePointerCast(x.impl, t.ll, ""), t}
+	}
+	// named structs of identical types are distinct LLVM types
+	ptr := b.entryAlloca(x.ll)
+	b.impl.CreateStore(x.impl, ptr)
+	addr := b.impl.CreatePointerCast(ptr, llvm.PointerType(t.ll, 0), "")
+	return Expr{llvm.CreateLoad(b.impl, t.ll, addr), t}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,342 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"debug/dwarf"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	"github.com/goplus/llvm"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// -----------------------------------------------------------------------------
+
+// DebugInfoLevel specifies how much DWARF debug information is generated.
+type DebugInfoLevel int
+
+const (
+	DebugInfoNone  DebugInfoLevel = iota // no debug information
+	DebugInfoLines                       // line tables only
+	DebugInfoFull                        // line tables, variables and types
+)
+
+// dwarfLangGo is LLVMDWARFSourceLanguageGo. Note that llvm.DW_LANG_Go is the
+// DWARF language code, which the LLVM C API doesn't accept.
+const dwarfLangGo llvm.DwarfLang = 0x15
+
+type aDebugInfo struct {
+	di    *llvm.DIBuilder
+	cu    llvm.Metadata
+	level DebugInfoLevel
+	files map[string]llvm.Metadata
+	typs  typeutil.Map // types.Type => llvm.Metadata
+}
+
+// InitDebugInfo enables generating debug information of the specified level
+// for the package. file is the path of the main source file of the package.
+func (p Package) InitDebugInfo(level DebugInfoLevel, file string) {
+	if level == DebugInfoNone {
+		return
+	}
+	di := llvm.NewDIBuilder(p.mod)
+	dir, name := filepath.Split(file)
+	cu := di.CreateCompileUnit(llvm.DICompileUnit{
+		Language: dwarfLangGo,
+		File:     name,
+		Dir:      dir,
+		Producer: "llgo",
+	})
+	p.dbg = &aDebugInfo{di: di, cu: cu, level: level, files: make(map[string]llvm.Metadata)}
+
+	p.addModuleFlag(moduleFlagWarning, "Debug Info Version", 3)
+	p.addModuleFlag(moduleFlagWarning, "Dwarf Version", 4)
+}
+
+// FinishDebugInfo finalizes the debug information of the package. It must be
+// called after all functions of the package are built.
+func (p Package) FinishDebugInfo() {
+	if dbg := p.dbg; dbg != nil {
+		dbg.di.Finalize()
+		dbg.di.Destroy()
+		p.dbg = nil
+	}
+}
+
+func (p *aDebugInfo) file(filename string) llvm.Metadata {
+	if f, ok := p.files[filename]; ok {
+		return f
+	}
+	dir, name := filepath.Split(filename)
+	f := p.di.CreateFile(name, dir)
+	p.files[filename] = f
+	return f
+}
+
+// SetPos attaches debug information to the function declared at pos. It
+// must be called before the function body is built.
+func (p Function) SetPos(pos token.Position) {
+	dbg := p.pkg.dbg
+	if dbg == nil || !pos.IsValid() {
+		return
+	}
+	file := dbg.file(pos.Filename)
+	var params []llvm.Metadata
+	if dbg.level >= DebugInfoFull {
+		params = append(params, llvm.Metadata{}) // results are not described
+		for _, t := range p.params {
+			params = append(params, p.pkg.diType(t))
+		}
+	}
+	sp := dbg.di.CreateFunction(file, llvm.DIFunction{
+		Name:         p.impl.Name(),
+		LinkageName:  p.impl.Name(),
+		File:         file,
+		Line:         pos.Line,
+		Type:         dbg.di.CreateSubroutineType(llvm.DISubroutineType{File: file, Parameters: params}),
+		IsDefinition: true,
+		ScopeLine:    pos.Line,
+		Flags:        llvm.FlagPrototyped,
+	})
+	p.impl.SetSubprogram(sp)
+	p.sp = sp
+}
+
+// SetPos sets the source position of the instructions built afterwards.
+func (b Builder) SetPos(pos token.Position) {
+	if sp := b.fn.sp; !sp.IsNil() && pos.IsValid() {
+		b.impl.SetCurrentDebugLocation(uint(pos.Line), uint(pos.Column), sp, llvm.Metadata{})
+	}
+}
+
+// DebugParam describes the ith parameter of the function, named name and
+// declared at pos. It is a no-op unless full debug information is enabled.
+func (b Builder) DebugParam(i int, name string, pos token.Position) {
+	fn := b.fn
+	dbg := fn.pkg.dbg
+	if fn.sp.IsNil() || dbg.level < DebugInfoFull {
+		return
+	}
+	param := fn.Param(i)
+	file := dbg.file(pos.Filename)
+	v := dbg.di.CreateParameterVariable(fn.sp, llvm.DIParameterVariable{
+		Name:           name,
+		File:           file,
+		Line:           pos.Line,
+		Type:           fn.pkg.diType(param.Type),
+		AlwaysPreserve: true,
+		ArgNo:          i + 1,
+	})
+	loc := llvm.DebugLoc{Line: uint(pos.Line), Col: uint(pos.Column), Scope: fn.sp}
+	dbg.di.InsertValueAtEnd(param.impl, v, dbg.di.CreateExpression(nil), loc, b.impl.GetInsertBlock())
+}
+
+// DebugVar describes the local variable at address ptr, named name and
+// declared at pos. It is a no-op unless full debug information is enabled.
+func (b Builder) DebugVar(ptr Expr, name string, pos token.Position) {
+	fn := b.fn
+	dbg := fn.pkg.dbg
+	if fn.sp.IsNil() || dbg.level < DebugInfoFull {
+		return
+	}
+	file := dbg.file(pos.Filename)
+	v := dbg.di.CreateAutoVariable(fn.sp, llvm.DIAutoVariable{
+		Name: name,
+		File: file,
+		Line: pos.Line,
+		Type: fn.pkg.diType(b.prog.Elem(ptr.Type)),
+	})
+	loc := llvm.DebugLoc{Line: uint(pos.Line), Col: uint(pos.Column), Scope: fn.sp}
+	dbg.di.InsertDeclareAtEnd(ptr.impl, v, dbg.di.CreateExpression(nil), loc, b.impl.GetInsertBlock())
+}
+
+// -----------------------------------------------------------------------------
+
+func (p Package) diType(t Type) llvm.Metadata {
+	dbg := p.dbg
+	if v := dbg.typs.At(t.t); v != nil {
+		return v.(llvm.Metadata)
+	}
+	var ret llvm.Metadata
+	if named, ok := t.t.(*types.Named); ok {
+		// A named type may refer to itself, so a placeholder is registered
+		// before its underlying type is described.
+		tmp := dbg.di.CreateReplaceableCompositeType(dbg.cu, llvm.DIReplaceableCompositeType{
+			Tag:  dwarf.TagStructType,
+			Name: named.String(),
+		})
+		dbg.typs.Set(t.t, tmp)
+		ret = p.diUnderlying(named.String(), t)
+		tmp.ReplaceAllUsesWith(ret)
+	} else {
+		ret = p.diUnderlying("", t)
+	}
+	dbg.typs.Set(t.t, ret)
+	return ret
+}
+
+func (p Package) diUnderlying(name string, t Type) llvm.Metadata {
+	prog := p.prog
+	di := p.dbg.di
+	if name == "" {
+		name = t.t.String()
+	}
+	size := prog.td.TypeAllocSize(t.ll) * 8
+	align := uint32(prog.td.ABITypeAlignment(t.ll) * 8)
+	switch u := t.t.Underlying().(type) {
+	case *types.Basic:
+		var enc llvm.DwarfTypeEncoding
+		switch info := u.Info(); {
+		case info&types.IsBoolean != 0:
+			enc = llvm.DW_ATE_boolean
+		case info&types.IsUnsigned != 0:
+			enc = llvm.DW_ATE_unsigned
+		case info&types.IsInteger != 0:
+			enc = llvm.DW_ATE_signed
+		case info&types.IsFloat != 0:
+			enc = llvm.DW_ATE_float
+		case info&types.IsComplex != 0:
+			enc = llvm.DW_ATE_complex_float
+		case info&types.IsString != 0:
+			return p.diStruct(name, t, []string{"str", "len"}, []types.Type{
+				types.NewPointer(types.Typ[types.Uint8]), types.Typ[types.Int]})
+		default: // unsafe.Pointer
+			enc = llvm.DW_ATE_address
+		}
+		return di.CreateBasicType(llvm.DIBasicType{Name: name, SizeInBits: size, Encoding: enc})
+	case *types.Pointer:
+		return di.CreatePointerType(llvm.DIPointerType{
+			Pointee: p.diType(prog.Type(u.Elem())), SizeInBits: size, AlignInBits: align, Name: name})
+	case *types.Array:
+		return di.CreateArrayType(llvm.DIArrayType{
+			SizeInBits:  size,
+			AlignInBits: align,
+			ElementType: p.diType(prog.Type(u.Elem())),
+			Subscripts:  []llvm.DISubrange{{Count: u.Len()}},
+		})
+	case *types.Slice:
+		return p.diStruct(name, t, []string{"array", "len", "cap"}, []types.Type{
+			types.NewPointer(u.Elem()), types.Typ[types.Int], types.Typ[types.Int]})
+	case *types.Struct:
+		n := u.NumFields()
+		names := make([]string, n)
+		typs := make([]types.Type, n)
+		for i := 0; i < n; i++ {
+			fld := u.Field(i)
+			names[i], typs[i] = fld.Name(), fld.Type()
+		}
+		return p.diStruct(name, t, names, typs)
+	case *types.Chan:
+		return p.diOpaquePointer(name, t, PkgRuntime+".Chan")
+	case *types.Map:
+		return p.diOpaquePointer(name, t, PkgRuntime+".Map")
+	case *types.Interface: // see runtime.eface
+		return p.diStruct(name, t, []string{"typ", "data"}, []types.Type{tyUnsafePtr, tyUnsafePtr})
+	case *types.Signature:
+		if t.kind == vkFuncPtr {
+			fn := p.diSubroutine(nil, u)
+			return di.CreatePointerType(llvm.DIPointerType{Pointee: fn, SizeInBits: size, AlignInBits: align, Name: name})
+		}
+		return p.diClosure(name, t, u)
+	}
+	return di.CreateBasicType(llvm.DIBasicType{Name: name, SizeInBits: size, Encoding: llvm.DW_ATE_address})
+}
+
+// diSubroutine describes the functions of signature sig, which take a
+// parameter of type ctx first if it isn't nil. Their results are not
+// described.
+func (p Package) diSubroutine(ctx types.Type, sig *types.Signature) llvm.Metadata {
+	prog := p.prog
+	params := []llvm.Metadata{{}}
+	if ctx != nil {
+		params = append(params, p.diType(prog.Type(ctx)))
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, p.diType(prog.Type(sig.Params().At(i).Type())))
+	}
+	return p.dbg.di.CreateSubroutineType(llvm.DISubroutineType{Parameters: params, Flags: llvm.FlagPrototyped})
+}
+
+// diClosure describes the func values of type t, of signature sig, as structs
+// of a pointer to their function, which takes their context first, and of
+// the context (see MakeClosure).
+func (p Package) diClosure(name string, t Type, sig *types.Signature) llvm.Metadata {
+	prog := p.prog
+	dbg := p.dbg
+	word := prog.td.PointerSize() * 8
+	fn := dbg.di.CreatePointerType(llvm.DIPointerType{
+		Pointee:     p.diSubroutine(tyUnsafePtr, sig),
+		SizeInBits:  uint64(word),
+		AlignInBits: uint32(word),
+	})
+	member := func(i int, name string, typ llvm.Metadata) llvm.Metadata {
+		return dbg.di.CreateMemberType(dbg.cu, llvm.DIMemberType{
+			Name:         name,
+			SizeInBits:   uint64(word),
+			AlignInBits:  uint32(word),
+			OffsetInBits: prog.td.ElementOffset(t.ll, i) * 8,
+			Type:         typ,
+		})
+	}
+	elems := []llvm.Metadata{member(0, "fn", fn), member(1, "ctx", p.diType(prog.Type(tyUnsafePtr)))}
+	return dbg.di.CreateStructType(dbg.cu, llvm.DIStructType{
+		Name:        name,
+		SizeInBits:  prog.td.TypeAllocSize(t.ll) * 8,
+		AlignInBits: uint32(prog.td.ABITypeAlignment(t.ll) * 8),
+		Elements:    elems,
+	})
+}
+
+// diOpaquePointer describes the values of type t, eg. of a chan type, as
+// pointers to the struct elem of the runtime, whose layout isn't described.
+func (p Package) diOpaquePointer(name string, t Type, elem string) llvm.Metadata {
+	prog := p.prog
+	dbg := p.dbg
+	st := dbg.di.CreateStructType(dbg.cu, llvm.DIStructType{Name: elem, Flags: llvm.FlagFwdDecl})
+	return dbg.di.CreatePointerType(llvm.DIPointerType{
+		Pointee:     st,
+		SizeInBits:  prog.td.TypeAllocSize(t.ll) * 8,
+		AlignInBits: uint32(prog.td.ABITypeAlignment(t.ll) * 8),
+		Name:        name,
+	})
+}
+
+func (p Package) diStruct(name string, t Type, names []string, typs []types.Type) llvm.Metadata {
+	prog := p.prog
+	dbg := p.dbg
+	elems := make([]llvm.Metadata, len(names))
+	for i, fname := range names {
+		ft := prog.Type(typs[i])
+		elems[i] = dbg.di.CreateMemberType(dbg.cu, llvm.DIMemberType{
+			Name:         fname,
+			SizeInBits:   prog.td.TypeAllocSize(ft.ll) * 8,
+			AlignInBits:  uint32(prog.td.ABITypeAlignment(ft.ll) * 8),
+			OffsetInBits: prog.td.ElementOffset(t.ll, i) * 8,
+			Type:         p.diType(ft),
+		})
+	}
+	return dbg.di.CreateStructType(dbg.cu, llvm.DIStructType{
+		Name:        name,
+		SizeInBits:  prog.td.TypeAllocSize(t.ll) * 8,
+		AlignInBits: uint32(prog.td.ABITypeAlignment(t.ll) * 8),
+		Elements:    elems,
+	})
+}
+
+// -----------------------------------------------------------------------------
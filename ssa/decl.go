@@ -17,8 +17,10 @@
 package ssa
 
 import (
+	"errors"
 	"go/types"
 	"strconv"
+	"strings"
 
 	"github.com/goplus/llvm"
 )
@@ -42,6 +44,7 @@ type NamedConst = *aNamedConst
 
 type aGlobal struct {
 	Expr
+	gbl llvm.Value
 }
 
 // A Global is a named Value holding the address of a package-level
@@ -50,7 +53,20 @@ type Global = *aGlobal
 
 // Init initializes the global variable with the given value.
 func (g Global) Init(v Expr) {
-	g.impl.SetInitializer(v.impl)
+	g.gbl.SetInitializer(v.impl)
+}
+
+// SetVisibility sets the visibility of the global variable.
+func (g Global) SetVisibility(v Visibility) {
+	g.gbl.SetVisibility(visibilityToLLVM[v])
+}
+
+// SetThreadLocal makes the global variable thread-local: each thread has its
+// own instance of it, which the code that LLVM generates finds relative to
+// the thread pointer, eg. the %fs segment on amd64 or TPIDR_EL0 on arm64, and
+// which the linker relaxes to a constant offset from it in executables.
+func (g Global) SetThreadLocal() {
+	g.gbl.SetThreadLocal(true)
 }
 
 // -----------------------------------------------------------------------------
@@ -106,8 +122,10 @@ func (g Global) Init(v Expr) {
 // respectively, and is nil in the generic method.
 type aFunction struct {
 	Expr
+	pkg  Package
 	prog Program
 	blks []BasicBlock
+	sp   llvm.Metadata // DISubprogram, if debug information is enabled
 
 	params  []Type
 	hasVArg bool
@@ -116,9 +134,9 @@ type aFunction struct {
 // Function represents a function or method.
 type Function = *aFunction
 
-func newFunction(fn llvm.Value, t Type, prog Program) Function {
+func newFunction(fn llvm.Value, t Type, pkg Package, prog Program) Function {
 	params, hasVArg := newParams(t, prog)
-	return &aFunction{Expr{fn, t}, prog, nil, params, hasVArg}
+	return &aFunction{Expr{fn, t}, pkg, prog, nil, llvm.Metadata{}, params, hasVArg}
 }
 
 func newParams(fn Type, prog Program) (params []Type, hasVArg bool) {
@@ -146,7 +164,7 @@ func (p Function) NewBuilder() Builder {
 	prog := p.prog
 	b := prog.ctx.NewBuilder()
 	b.Finalize()
-	return &aBuilder{b, p, prog}
+	return &aBuilder{impl: b, fn: p, prog: prog}
 }
 
 // MakeBody creates nblk basic blocks for the function, and creates
@@ -162,20 +180,117 @@ func (p Function) MakeBody(nblk int) Builder {
 func (p Function) MakeBlocks(nblk int) []BasicBlock {
 	if p.blks == nil {
 		p.blks = make([]BasicBlock, 0, nblk)
+		if gc := p.prog.gc; gc != "" {
+			p.impl.SetGC(gc)
+			p.impl.AddTargetDependentFunctionAttr("frame-pointer", "all")
+		}
+		if s := p.prog.sanitizer; s != "" {
+			p.impl.AddFunctionAttr(p.prog.ctx.CreateEnumAttribute(llvm.AttributeKindID("sanitize_"+s), 0))
+		}
+		if p.prog.hardening {
+			p.impl.AddFunctionAttr(p.prog.ctx.CreateEnumAttribute(llvm.AttributeKindID("sspstrong"), 0))
+		}
 	}
 	n := len(p.blks)
 	f := p.impl
 	for i := 0; i < nblk; i++ {
-		label := "_llgo_" + strconv.Itoa(i)
+		label := "_llgo_" + strconv.Itoa(n+i)
 		blk := llvm.AddBasicBlock(f, label)
 		p.blks = append(p.blks, &aBasicBlock{blk, p, n + i})
 	}
 	return p.blks[n:]
 }
 
+// MakeTrap discards the body of the function built so far, and makes a new
+// body that traps. It is used to stub out a function that fails to compile.
+func (p Function) MakeTrap() {
+	old := p.impl
+	name := old.Name()
+	old.SetName("")
+	fn := llvm.AddFunction(p.pkg.mod, name, p.ll)
+	fn.SetFunctionCallConv(old.FunctionCallConv())
+	old.ReplaceAllUsesWith(fn)
+	old.EraseFromParentAsFunction()
+	p.impl, p.blks, p.sp = fn, nil, llvm.Metadata{}
+	p.MakeBody(1).Trap()
+}
+
 // Block returns the ith basic block of the function.
 func (p Function) Block(idx int) BasicBlock {
 	return p.blks[idx]
 }
 
+// SetSection places the code of the function in the section name of the
+// object file, instead of the text section.
+func (p Function) SetSection(name string) {
+	p.impl.SetSection(name)
+}
+
+// SetAlignment aligns the code of the function to align bytes, a power of two.
+func (p Function) SetAlignment(align int) {
+	p.impl.SetAlignment(align)
+}
+
+// SetWeak makes the function a weak definition, which a non-weak definition
+// of the same symbol overrides when they are linked, eg. a default hook that
+// a program or a C library can replace.
+func (p Function) SetWeak() {
+	p.impl.SetLinkage(llvm.WeakAnyLinkage)
+}
+
+// SetLinkOnce makes the function a definition that the linker merges with
+// the ones of the same symbol, which must be identical, eg. a wrapper that
+// every package that uses it defines.
+func (p Function) SetLinkOnce() {
+	p.impl.SetLinkage(llvm.LinkOnceODRLinkage)
+}
+
+// SetFastCC makes the function use the fast calling convention of LLVM,
+// which passes more arguments and results in registers than the one of C,
+// eg. the multiple results of a Go function. The function must only be called
+// directly by Builder.Call, which uses the convention of the callee, and
+// neither through a pointer nor by C.
+func (p Function) SetFastCC() {
+	p.impl.SetFunctionCallConv(llvm.FastCallConv)
+}
+
+// SetVisibility sets the visibility of the function.
+func (p Function) SetVisibility(v Visibility) {
+	p.impl.SetVisibility(visibilityToLLVM[v])
+}
+
+// Verify checks the LLVM IR of the function by the LLVM verifier, and returns
+// an error that describes it if it's invalid, eg. because of a bug of the code
+// generation, which LLVM would fail on later, when it compiles the package.
+func (p Function) Verify() error {
+	if llvm.VerifyFunction(p.impl, llvm.ReturnStatusAction) == nil {
+		return nil
+	}
+	// only the verifier of modules describes what is invalid, so the module
+	// is verified too: the functions that were built before are valid
+	// unless they failed already
+	if err := llvm.VerifyModule(p.pkg.mod, llvm.ReturnStatusAction); err != nil {
+		return errors.New(strings.TrimSpace(err.Error()))
+	}
+	return errors.New("invalid LLVM IR")
+}
+
+// -----------------------------------------------------------------------------
+
+// Visibility is the visibility of a symbol that a package defines outside of
+// the shared library or the executable that it is linked in.
+type Visibility int
+
+const (
+	VisibilityDefault   Visibility = iota // visible, and may be overridden by another module
+	VisibilityHidden                      // not visible
+	VisibilityProtected                   // visible, but not overridden by another module
+)
+
+var visibilityToLLVM = [...]llvm.Visibility{
+	VisibilityDefault:   llvm.DefaultVisibility,
+	VisibilityHidden:    llvm.HiddenVisibility,
+	VisibilityProtected: llvm.ProtectedVisibility,
+}
+
 // -----------------------------------------------------------------------------
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// The values of a comparable type T are hashed and compared, as map keys or
+// by the == and != operators on structs and arrays, by __llgo_hash.T and
+// __llgo_equal.T, where T is the canonical string of the type (see
+// writeTypeKey). They are generated in the packages that use them, with
+// linkonce_odr linkage as type descriptors, which refer to them too (see
+// TypeDesc). The values whose bytes are equal
+// if and only if they are (see memComparable) are hashed and compared as
+// memory, by runtime.Memhash and runtime.Memequal; the fields of other
+// structs and the elements of other arrays are hashed and compared one by
+// one, by the functions of their types.
+
+var (
+	// func(key unsafe.Pointer, seed uintptr) uintptr
+	tyHashFunc = types.NewSignatureType(nil, nil, nil, newTuple(tyUnsafePtr, tyUintptr), newTuple(tyUintptr), false)
+
+	// func(x, y unsafe.Pointer) bool
+	tyEqualFunc = types.NewSignatureType(nil, nil, nil, newTuple(tyUnsafePtr, tyUnsafePtr), newTuple(tyBool), false)
+)
+
+// compositeCmp compares the structs or the arrays x and y, op being == or
+// !=, by the equal function of their type:
+//
+//	x == y  =>  __llgo_equal.T(&x, &y)
+func (b Builder) compositeCmp(op token.Token, x, y Expr) Expr {
+	eq := b.Call(b.fn.pkg.equalFunc(x.t), b.addrOf(x), b.addrOf(y))
+	if op == token.NEQ {
+		return Expr{b.impl.CreateNot(eq.impl, ""), eq.Type}
+	}
+	return eq
+}
+
+// hashFunc returns __llgo_hash.T for the type t, which hashes the value of
+// type t that its first parameter points to, generating it if the package
+// doesn't have it yet.
+func (p Package) hashFunc(t types.Type) Expr {
+	fn, b, ok := p.keyFunc("__llgo_hash.", t, tyHashFunc)
+	if ok {
+		return fn.Expr
+	}
+	prog := p.prog
+	key, seed := fn.Param(0), fn.Param(1)
+	tptr := prog.Type(tyUnsafePtr)
+	if prog.memComparable(t) {
+		hash := b.rtFunc("Memhash", []types.Type{tyUnsafePtr, tyUintptr, tyUintptr}, []types.Type{tyUintptr})
+		b.Return(b.Call(hash, key, b.uintptrSizeof(prog.Type(t)), seed))
+		return fn.Expr
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		ptr := Expr{key.impl, prog.Pointer(prog.Type(t))}
+		for i := 0; i < u.NumFields(); i++ {
+			if f := u.Field(i); f.Name() != "_" {
+				fld := b.FieldAddr(ptr, i)
+				seed = b.Call(p.hashFunc(f.Type()), Expr{fld.impl, tptr}, seed)
+			}
+		}
+		b.Return(seed)
+	case *types.Array:
+		elem := prog.Type(u.Elem())
+		params := []types.Type{tyUnsafePtr, tyUintptr, tyUintptr, tyHashFunc, tyUintptr}
+		hash := b.rtFunc("ArrayHash", params, []types.Type{tyUintptr})
+		n := prog.IntVal(uint64(u.Len()), prog.Type(tyUintptr))
+		b.Return(b.Call(hash, key, n, b.uintptrSizeof(elem), b.funcValue(p.hashFunc(u.Elem())), seed))
+	default:
+		hash := b.rtFunc(rtKeyFuncs(t)+"hash", []types.Type{tyUnsafePtr, tyUintptr}, []types.Type{tyUintptr})
+		b.Return(b.Call(hash, key, seed))
+	}
+	return fn.Expr
+}
+
+// equalFunc returns __llgo_equal.T for the type t, which compares the values
+// of type t that its parameters point to, generating it if the package
+// doesn't have it yet. As for the == operator, the fields of structs are
+// compared in order, until two of them differ.
+func (p Package) equalFunc(t types.Type) Expr {
+	fn, b, ok := p.keyFunc("__llgo_equal.", t, tyEqualFunc)
+	if ok {
+		return fn.Expr
+	}
+	prog := p.prog
+	x, y := fn.Param(0), fn.Param(1)
+	tptr := prog.Type(tyUnsafePtr)
+	if prog.memComparable(t) {
+		equal := b.rtFunc("Memequal", []types.Type{tyUnsafePtr, tyUnsafePtr, tyUintptr}, []types.Type{tyBool})
+		b.Return(b.Call(equal, x, y, b.uintptrSizeof(prog.Type(t))))
+		return fn.Expr
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		ptrt := prog.Pointer(prog.Type(t))
+		px, py := Expr{x.impl, ptrt}, Expr{y.impl, ptrt}
+		ne := fn.MakeBlocks(1)[0]
+		for i := 0; i < u.NumFields(); i++ {
+			if f := u.Field(i); f.Name() != "_" {
+				fx, fy := b.FieldAddr(px, i), b.FieldAddr(py, i)
+				eq := b.Call(p.equalFunc(f.Type()), Expr{fx.impl, tptr}, Expr{fy.impl, tptr})
+				next := fn.MakeBlocks(1)[0]
+				b.If(eq, next, ne)
+				b.SetBlock(next)
+			}
+		}
+		b.Return(prog.BoolVal(true))
+		b.SetBlock(ne)
+		b.Return(prog.BoolVal(false))
+	case *types.Array:
+		elem := prog.Type(u.Elem())
+		params := []types.Type{tyUnsafePtr, tyUnsafePtr, tyUintptr, tyUintptr, tyEqualFunc}
+		equal := b.rtFunc("ArrayEqual", params, []types.Type{tyBool})
+		n := prog.IntVal(uint64(u.Len()), prog.Type(tyUintptr))
+		b.Return(b.Call(equal, x, y, n, b.uintptrSizeof(elem), b.funcValue(p.equalFunc(u.Elem()))))
+	default:
+		equal := b.rtFunc(rtKeyFuncs(t)+"equal", []types.Type{tyUnsafePtr, tyUnsafePtr}, []types.Type{tyBool})
+		b.Return(b.Call(equal, x, y))
+	}
+	return fn.Expr
+}
+
+// keyFunc returns the function prefix+T of signature sig for the type t, and
+// whether the package has it already. If it hasn't, the function is created,
+// with a builder of its body.
+func (p Package) keyFunc(prefix string, t types.Type, sig *types.Signature) (Function, Builder, bool) {
+	var key strings.Builder
+	local := p.writeTypeKey(&key, t)
+	name := prefix + key.String()
+	if fn := p.FuncOf(name); fn != nil {
+		return fn, nil, true
+	}
+	fn := p.NewFunc(name, sig)
+	if local {
+		fn.impl.SetLinkage(llvm.InternalLinkage)
+	} else {
+		fn.impl.SetLinkage(llvm.LinkOnceODRLinkage)
+	}
+	return fn, fn.MakeBody(1), false
+}
+
+// memComparable reports whether two values of type t are equal if and only
+// if their bytes are: t has neither floats, strings nor interfaces, which
+// have several representations of equal values, nor padding or blank fields,
+// whose bytes are undefined.
+func (p Program) memComparable(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&(types.IsFloat|types.IsComplex|types.IsString) == 0
+	case *types.Pointer, *types.Chan:
+		return true
+	case *types.Array:
+		return p.memComparable(u.Elem())
+	case *types.Struct:
+		ll := p.Type(t).ll
+		off := uint64(0)
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if f.Name() == "_" || !p.memComparable(f.Type()) || p.td.ElementOffset(ll, i) != off {
+				return false
+			}
+			off += p.td.TypeAllocSize(p.Type(f.Type()).ll)
+		}
+		return off == p.td.TypeAllocSize(ll)
+	}
+	return false
+}
+
+// rtKeyFuncs returns the prefix of the runtime functions that hash and
+// compare the values of type t, which isn't memComparable, a struct nor an
+// array, eg. "Str" for runtime.Strhash and runtime.Strequal.
+func rtKeyFuncs(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.String:
+			return "Str"
+		case types.Float32:
+			return "F32"
+		case types.Float64:
+			return "F64"
+		case types.Complex64:
+			return "C64"
+		case types.Complex128:
+			return "C128"
+		}
+	case *types.Interface:
+		return "Inter"
+	}
+	panic("unsupported hash and equal functions of type " + t.String())
+}
+
+// -----------------------------------------------------------------------------
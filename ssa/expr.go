@@ -23,6 +23,7 @@ import (
 	"go/token"
 	"go/types"
 	"log"
+	"strconv"
 
 	"github.com/goplus/llvm"
 )
@@ -65,6 +66,20 @@ func (p Program) IntVal(v uint64, t Type) Expr {
 	return Expr{ret, t}
 }
 
+func (p Program) FloatVal(v float64, t Type) Expr {
+	ret := llvm.ConstFloat(t.ll, v)
+	return Expr{ret, t}
+}
+
+// ComplexVal returns a complex constant of type t (complex64 or complex128).
+func (p Program) ComplexVal(v complex128, t Type) Expr {
+	tf := p.complexElem(t)
+	re := llvm.ConstFloat(tf.ll, real(v))
+	im := llvm.ConstFloat(tf.ll, imag(v))
+	ret := p.ctx.ConstStruct([]llvm.Value{re, im}, false)
+	return Expr{ret, t}
+}
+
 func (p Program) Val(v interface{}) Expr {
 	switch v := v.(type) {
 	case int:
@@ -72,29 +87,65 @@ func (p Program) Val(v interface{}) Expr {
 	case bool:
 		return p.BoolVal(v)
 	case float64:
-		t := p.Float64()
-		ret := llvm.ConstFloat(t.ll, v)
-		return Expr{ret, t}
+		return p.FloatVal(v, p.Float64())
+	case complex128:
+		return p.ComplexVal(v, p.Type(types.Typ[types.Complex128]))
 	}
 	panic("todo")
 }
 
 func (b Builder) Const(v constant.Value, typ Type) Expr {
-	switch t := typ.t.(type) {
+	if v == nil { // the zero value of a non-basic type, eg. a nil interface
+		return b.prog.Null(typ)
+	}
+	switch t := typ.t.Underlying().(type) {
 	case *types.Basic:
 		kind := t.Kind()
 		switch {
-		case kind == types.Bool:
+		case kind == types.Bool || kind == types.UntypedBool:
 			return b.prog.BoolVal(constant.BoolVal(v))
 		case kind >= types.Int && kind <= types.Uintptr:
+			v = constant.ToInt(v) // eg. 1e9, an untyped float constant
 			if v, exact := constant.Uint64Val(v); exact {
 				return b.prog.IntVal(v, typ)
 			}
+			if v, exact := constant.Int64Val(v); exact {
+				return b.prog.IntVal(uint64(v), typ)
+			}
+		case kind == types.Float32 || kind == types.Float64:
+			fv, _ := constant.Float64Val(v)
+			return b.prog.FloatVal(fv, typ)
+		case kind == types.Complex64 || kind == types.Complex128:
+			re, _ := constant.Float64Val(constant.Real(v))
+			im, _ := constant.Float64Val(constant.Imag(v))
+			return b.prog.ComplexVal(complex(re, im), typ)
+		case kind == types.String || kind == types.UntypedString:
+			return Expr{b.fn.pkg.constString(constant.StringVal(v)), typ}
 		}
 	}
 	panic("todo")
 }
 
+// CStr returns a pointer (of type *int8) to the null-terminated constant
+// string v, which is stored in a global of the package, shared by the
+// constants whose bytes are equal (see constBytes).
+func (b Builder) CStr(v string) Expr {
+	t := b.prog.Type(types.NewPointer(types.Typ[types.Int8]))
+	g := b.fn.pkg.constBytes(v + "\x00")
+	g.SetAlignment(1)
+	return Expr{llvm.ConstPointerCast(g, t.ll), t}
+}
+
+// constPtr returns the constant pointer v, eg. the address of a global, as a
+// value of the pointer type t, which is in the address space of Go pointers
+// (see Program.SetGC).
+func (p Program) constPtr(v llvm.Value, t Type) llvm.Value {
+	if p.gc == "" {
+		return v
+	}
+	return llvm.ConstPointerCast(v, t.ll)
+}
+
 // -----------------------------------------------------------------------------
 
 const (
@@ -176,7 +227,7 @@ var uintPredOpToLLVM = []llvm.IntPredicate{
 
 var floatPredOpToLLVM = []llvm.FloatPredicate{
 	token.EQL - predOpBase: llvm.FloatOEQ,
-	token.NEQ - predOpBase: llvm.FloatONE,
+	token.NEQ - predOpBase: llvm.FloatUNE, // true if x or y is NaN
 	token.LSS - predOpBase: llvm.FloatOLT,
 	token.LEQ - predOpBase: llvm.FloatOLE,
 	token.GTR - predOpBase: llvm.FloatOGT,
@@ -201,26 +252,49 @@ func (b Builder) BinOp(op token.Token, x, y Expr) Expr {
 	case isMathOp(op): // op: + - * / %
 		kind := x.kind
 		switch kind {
-		case vkString, vkComplex:
+		case vkString:
 			panic("todo")
+		case vkComplex:
+			return b.complexOp(op, x, y)
+		case vkSigned, vkUnsigned:
+			if op == token.QUO || op == token.REM {
+				return b.intDiv(op, x, y)
+			}
 		}
 		idx := mathOpIdx(op, kind)
 		if llop := mathOpToLLVM[idx]; llop != 0 {
 			return Expr{llvm.CreateBinOp(b.impl, llop, x.impl, y.impl), x.Type}
 		}
 	case isLogicOp(op): // op: & | ^ << >> &^
-		if op == token.AND_NOT {
-			panic("todo")
+		switch op {
+		case token.AND_NOT:
+			return Expr{b.impl.CreateAnd(x.impl, b.impl.CreateNot(y.impl, ""), ""), x.Type}
+		case token.SHL, token.SHR:
+			return b.shift(op, x, y)
 		}
-		kind := x.kind
 		llop := logicOpToLLVM[op-logicOpBase]
-		if op == token.SHR && kind == vkUnsigned {
-			llop = llvm.LShr // Logical Shift Right
-		}
 		return Expr{llvm.CreateBinOp(b.impl, llop, x.impl, y.impl), x.Type}
 	case isPredOp(op): // op: == != < <= < >=
+		switch x.t.Underlying().(type) {
+		case *types.Struct, *types.Array:
+			return b.compositeCmp(op, x, y)
+		case *types.Interface:
+			if y.impl.IsConstant() && y.impl.IsNull() { // x == nil
+				return b.nilCmp(op, b.impl.CreateExtractValue(x.impl, 0, ""))
+			}
+			return b.compositeCmp(op, x, y)
+		case *types.Slice: // s == nil
+			return b.nilCmp(op, b.impl.CreateExtractValue(x.impl, 0, ""))
+		case *types.Signature: // f == nil
+			if x.kind == vkClosure {
+				return b.nilCmp(op, b.impl.CreateExtractValue(x.impl, 0, ""))
+			}
+		}
 		tret := b.prog.Bool()
 		kind := x.kind
+		if x.ll.TypeKind() == llvm.PointerTypeKind { // pointers, maps, chans and C function pointers
+			kind = vkUnsigned
+		}
 		switch kind {
 		case vkSigned:
 			pred := intPredOpToLLVM[op-predOpBase]
@@ -231,13 +305,99 @@ func (b Builder) BinOp(op token.Token, x, y Expr) Expr {
 		case vkFloat:
 			pred := floatPredOpToLLVM[op-predOpBase]
 			return Expr{llvm.CreateFCmp(b.impl, pred, x.impl, y.impl), tret}
-		case vkString, vkComplex, vkBool:
-			panic("todo")
+		case vkComplex:
+			return b.complexCmp(op, x, y)
+		case vkString:
+			return b.stringCmp(op, x, y)
+		case vkBool:
+			pred := uintPredOpToLLVM[op-predOpBase]
+			return Expr{llvm.CreateICmp(b.impl, pred, x.impl, y.impl), tret}
 		}
 	}
 	panic("todo")
 }
 
+// nilCmp compares the pointer ptr, eg. the data of a slice, to nil, op being
+// == or !=.
+func (b Builder) nilCmp(op token.Token, ptr llvm.Value) Expr {
+	if op == token.EQL {
+		return Expr{b.impl.CreateIsNull(ptr, ""), b.prog.Bool()}
+	}
+	return Expr{b.impl.CreateIsNotNull(ptr, ""), b.prog.Bool()}
+}
+
+// shift returns x << y or x >> y. The count y, which is an unsigned integer or
+// a nonnegative signed one (see Go spec), may be as large as the width of x
+// or larger, in which case LLVM leaves the result undefined, while Go shifts
+// all the bits of x out: the result is then 0, or -1 for a right shift of a
+// negative signed x.
+func (b Builder) shift(op token.Token, x, y Expr) Expr {
+	llop := logicOpToLLVM[op-logicOpBase]
+	if op == token.SHR && x.kind == vkUnsigned {
+		llop = llvm.LShr // Logical Shift Right
+	}
+	width := uint64(x.ll.IntTypeWidth())
+	if c := y.impl.IsAConstantInt(); !c.IsNil() {
+		if n := c.ZExtValue(); n >= width {
+			if llop != llvm.AShr {
+				return Expr{llvm.ConstNull(x.ll), x.Type}
+			}
+			y = Expr{llvm.ConstInt(y.ll, width-1, false), y.Type}
+		}
+		n := llvm.ConstInt(x.ll, y.impl.ZExtValue(), false)
+		return Expr{llvm.CreateBinOp(b.impl, llop, x.impl, n), x.Type}
+	}
+	over := b.impl.CreateICmp(llvm.IntUGE, y.impl, llvm.ConstInt(y.ll, width, false), "")
+	n := b.castInt(y, x.Type).impl // y is nonnegative, so sign extending it zero extends it
+	if llop == llvm.AShr {
+		n = b.impl.CreateSelect(over, llvm.ConstInt(x.ll, width-1, false), n, "")
+		return Expr{b.impl.CreateAShr(x.impl, n, ""), x.Type}
+	}
+	ret := llvm.CreateBinOp(b.impl, llop, x.impl, n)
+	return Expr{b.impl.CreateSelect(over, llvm.ConstNull(x.ll), ret, ""), x.Type}
+}
+
+// intDiv returns x / y or x % y of the integers x and y. If y is zero,
+// runtime.PanicDivide panics, or the kernel traps on a GPU (see isGPU). The
+// signed division of the minimum integer by -1 overflows, which is undefined
+// in LLVM, so its quotient is the negation of x that wraps around, and its
+// remainder is 0, as Go specifies.
+func (b Builder) intDiv(op token.Token, x, y Expr) Expr {
+	llop := mathOpToLLVM[mathOpIdx(op, x.kind)]
+	if c := y.impl.IsAConstantInt(); !c.IsNil() { // nonzero, as Go requires
+		if x.kind == vkSigned && c.SExtValue() == -1 {
+			if op == token.QUO {
+				return Expr{b.impl.CreateNeg(x.impl, ""), x.Type}
+			}
+			return Expr{llvm.ConstNull(x.ll), x.Type}
+		}
+		return Expr{llvm.CreateBinOp(b.impl, llop, x.impl, y.impl), x.Type}
+	}
+	blks := b.fn.MakeBlocks(2)
+	fail, next := blks[0], blks[1]
+	zero := llvm.ConstNull(y.ll)
+	b.impl.CreateCondBr(b.impl.CreateICmp(llvm.IntEQ, y.impl, zero, ""), fail.impl, next.impl)
+	b.SetBlock(fail)
+	if b.prog.isGPU() {
+		b.Trap()
+	} else {
+		b.Call(b.rtFunc("PanicDivide", nil, nil))
+		b.impl.CreateUnreachable()
+	}
+	b.SetBlock(next)
+	if x.kind == vkUnsigned {
+		return Expr{llvm.CreateBinOp(b.impl, llop, x.impl, y.impl), x.Type}
+	}
+	minus1 := b.impl.CreateICmp(llvm.IntEQ, y.impl, llvm.ConstAllOnes(y.ll), "")
+	divisor := b.impl.CreateSelect(minus1, llvm.ConstInt(y.ll, 1, false), y.impl, "")
+	ret := llvm.CreateBinOp(b.impl, llop, x.impl, divisor)
+	ovf := zero
+	if op == token.QUO {
+		ovf = b.impl.CreateNeg(x.impl, "")
+	}
+	return Expr{b.impl.CreateSelect(minus1, ovf, ret, ""), x.Type}
+}
+
 // The UnOp instruction yields the result of (op x).
 // ARROW is channel receive.
 // MUL is pointer indirection (load).
@@ -252,6 +412,24 @@ func (b Builder) UnOp(op token.Token, x Expr) Expr {
 	if debugInstr {
 		log.Printf("UnOp %v, %v\n", op, x.impl)
 	}
+	switch op {
+	case token.NOT:
+		return Expr{b.impl.CreateNot(x.impl, ""), x.Type}
+	case token.XOR:
+		if isInt(x.kind) {
+			return Expr{b.impl.CreateNot(x.impl, ""), x.Type}
+		}
+	case token.SUB:
+		switch x.kind {
+		case vkSigned, vkUnsigned:
+			return Expr{b.impl.CreateNeg(x.impl, ""), x.Type}
+		case vkFloat:
+			return Expr{b.impl.CreateFNeg(x.impl, ""), x.Type}
+		case vkComplex:
+			re, im := b.complexParts(x)
+			return b.makeComplex(b.impl.CreateFNeg(re, ""), b.impl.CreateFNeg(im, ""), x.Type)
+		}
+	}
 	panic("todo")
 }
 
@@ -269,10 +447,99 @@ func (b Builder) Store(ptr, val Expr) Builder {
 	if debugInstr {
 		log.Printf("Store %v, %v\n", ptr.impl.Name(), val.impl)
 	}
+	if val.impl.IsConstant() && val.impl.IsNull() {
+		b.zero(ptr, val.Type)
+		return b
+	}
 	b.impl.CreateStore(val.impl, ptr.impl)
 	return b
 }
 
+// largeAggregate is the size in bytes from which structs and arrays are
+// copied by llvm.memcpy rather than by a load and a store, which LLVM splits
+// in loads and stores of their elements, that are slow to compile and to run.
+const largeAggregate = 64
+
+// Copy copies the value at the pointer src to the pointer dst:
+//
+//	*dst = *src  =>  llvm.memcpy(dst, src, sizeof(*src), false)
+//
+// Structs and arrays of at least largeAggregate bytes are copied by
+// llvm.memcpy, with the alignment of their type, and other values by a load
+// and a store.
+func (b Builder) Copy(dst, src Expr) {
+	if debugInstr {
+		log.Printf("Copy %v, %v\n", dst.impl.Name(), src.impl.Name())
+	}
+	prog := b.prog
+	t := prog.Elem(src.Type)
+	size := prog.td.TypeAllocSize(t.ll)
+	switch t.t.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		if size >= largeAggregate {
+			b.memcpy(dst, src, size, prog.td.ABITypeAlignment(t.ll))
+			return
+		}
+	}
+	b.Store(dst, b.Load(src))
+}
+
+// zero stores the zero value of type t at the pointer ptr. Structs and arrays
+// of at least largeAggregate bytes are zeroed by llvm.memset, as they are
+// copied by llvm.memcpy (see Copy).
+func (b Builder) zero(ptr Expr, t Type) {
+	prog := b.prog
+	size := prog.td.TypeAllocSize(t.ll)
+	switch t.t.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		if size >= largeAggregate {
+			b.memset(ptr, size, prog.td.ABITypeAlignment(t.ll))
+			return
+		}
+	}
+	b.impl.CreateStore(llvm.ConstNull(t.ll), ptr.impl)
+}
+
+// memset zeroes size bytes at ptr, which is aligned to align bytes, by the
+// intrinsic llvm.memset.
+func (b Builder) memset(ptr Expr, size uint64, align int) {
+	prog := b.prog
+	tsize := prog.Type(tyUintptr).ll
+	name := "llvm.memset.p" + strconv.Itoa(prog.ptrAddrSpace()) + ".i" + strconv.Itoa(tsize.IntTypeWidth())
+	ft := llvm.FunctionType(prog.tyVoid(), []llvm.Type{ptr.ll, prog.tyInt8(), tsize, prog.tyInt1()}, false)
+	fn := b.memIntrinsic(name, ft)
+	args := []llvm.Value{ptr.impl, llvm.ConstInt(prog.tyInt8(), 0, false), llvm.ConstInt(tsize, size, false), llvm.ConstInt(prog.tyInt1(), 0, false)}
+	call := llvm.CreateCall(b.impl, ft, fn, args)
+	call.AddCallSiteAttribute(1, prog.ctx.CreateEnumAttribute(llvm.AttributeKindID("align"), uint64(align)))
+}
+
+// memIntrinsic returns the intrinsic name of type ft, declaring it in the
+// current package if it isn't declared yet.
+func (b Builder) memIntrinsic(name string, ft llvm.Type) llvm.Value {
+	mod := b.fn.pkg.mod
+	fn := mod.NamedFunction(name)
+	if fn.IsNil() {
+		fn = llvm.AddFunction(mod, name, ft)
+	}
+	return fn
+}
+
+// memcpy copies size bytes from src to dst, which are aligned to align bytes,
+// by the intrinsic llvm.memcpy.
+func (b Builder) memcpy(dst, src Expr, size uint64, align int) {
+	prog := b.prog
+	ptr := "p" + strconv.Itoa(prog.ptrAddrSpace())
+	tsize := prog.Type(tyUintptr).ll
+	name := "llvm.memcpy." + ptr + "." + ptr + ".i" + strconv.Itoa(tsize.IntTypeWidth())
+	ft := llvm.FunctionType(prog.tyVoid(), []llvm.Type{dst.ll, src.ll, tsize, prog.tyInt1()}, false)
+	fn := b.memIntrinsic(name, ft)
+	args := []llvm.Value{dst.impl, src.impl, llvm.ConstInt(tsize, size, false), llvm.ConstInt(prog.tyInt1(), 0, false)}
+	call := llvm.CreateCall(b.impl, ft, fn, args)
+	kind := llvm.AttributeKindID("align")
+	call.AddCallSiteAttribute(1, prog.ctx.CreateEnumAttribute(kind, uint64(align)))
+	call.AddCallSiteAttribute(2, prog.ctx.CreateEnumAttribute(kind, uint64(align)))
+}
+
 // The IndexAddr instruction yields the address of the element at
 // index `idx` of collection `x`.  `idx` is an integer expression.
 //
@@ -280,7 +547,9 @@ func (b Builder) Store(ptr, val Expr) Builder {
 // Index (string), or MapUpdate instead.
 //
 // Dynamically, this instruction panics if `x` evaluates to a nil *array
-// pointer.
+// pointer, or if `idx` is out of the range of the length of `x`, which is
+// reported by runtime.PanicIndex. The constant indices of arrays are checked
+// by the type checker.
 //
 // Example printed form:
 //
@@ -292,10 +561,240 @@ func (b Builder) IndexAddr(x, idx Expr) Expr {
 	prog := b.prog
 	telem := prog.Index(x.Type)
 	pt := prog.Pointer(telem)
+	if x.kind == vkSlice {
+		n := Expr{b.impl.CreateExtractValue(x.impl, 1, ""), prog.Int()}
+		b.boundsCheck(idx, n)
+		x = b.dataOf(x, pt.t)
+	} else if !idx.impl.IsConstant() {
+		n := x.t.Underlying().(*types.Pointer).Elem().Underlying().(*types.Array).Len()
+		b.boundsCheck(idx, prog.Val(int(n)))
+	}
 	indices := []llvm.Value{idx.impl}
 	return Expr{llvm.CreateInBoundsGEP(b.impl, telem.ll, x.impl, indices), pt}
 }
 
+// The Index instruction yields element Index of collection X, an array or
+// a string. Index is an integer expression. If it is out of the range of the
+// length of X, runtime.PanicIndex panics (see IndexAddr).
+//
+// Example printed form:
+//
+//	t2 = t0[t1]
+func (b Builder) Index(x, idx Expr) Expr {
+	if debugInstr {
+		log.Printf("Index %v, %v\n", x.impl, idx.impl)
+	}
+	prog := b.prog
+	if x.kind == vkString {
+		n := Expr{b.impl.CreateExtractValue(x.impl, 1, ""), prog.Int()}
+		b.boundsCheck(idx, n)
+		tbyte := prog.Type(types.Typ[types.Byte])
+		data := b.dataOf(x, types.NewPointer(types.Typ[types.Byte]))
+		ptr := llvm.CreateInBoundsGEP(b.impl, tbyte.ll, data.impl, []llvm.Value{idx.impl})
+		return Expr{llvm.CreateLoad(b.impl, tbyte.ll, ptr), tbyte}
+	}
+	telem := prog.Index(x.Type)
+	if c := idx.impl.IsAConstantInt(); !c.IsNil() { // checked by the type checker
+		return Expr{b.impl.CreateExtractValue(x.impl, int(c.ZExtValue()), ""), telem}
+	}
+	n := x.t.Underlying().(*types.Array).Len()
+	b.boundsCheck(idx, prog.Val(int(n)))
+	arr := b.entryAlloca(x.ll)
+	b.impl.CreateStore(x.impl, arr)
+	zero := llvm.ConstNull(idx.ll)
+	ptr := llvm.CreateInBoundsGEP(b.impl, x.ll, arr, []llvm.Value{zero, idx.impl})
+	return Expr{llvm.CreateLoad(b.impl, telem.ll, ptr), telem}
+}
+
+// boundsCheck panics by runtime.PanicIndex, or runtime.PanicIndexU for an
+// unsigned idx, if the index idx isn't in the range [0, n).
+func (b Builder) boundsCheck(idx, n Expr) {
+	b.checkBounds("Index", llvm.IntUGE, idx, n)
+}
+
+// sliceCheck panics by the runtime.Panic function of kind, eg.
+// runtime.PanicSliceB, if the bound x of a slice expression is negative or
+// greater than y, unless both are constants, which the type checker checks.
+func (b Builder) sliceCheck(kind string, x, y Expr) {
+	if x.impl.IsConstant() && y.impl.IsConstant() {
+		return
+	}
+	b.checkBounds(kind, llvm.IntUGT, x, y)
+}
+
+// checkBounds calls the runtime.Panic function of kind, eg. runtime.PanicIndex,
+// or the one of the suffix U for an unsigned x, with x and the int y, if x and
+// y, compared as unsigned integers, satisfy the predicate pred, which a
+// negative x does as a huge one. On a GPU, which has no runtime, the kernel
+// traps instead (see isGPU).
+func (b Builder) checkBounds(kind string, pred llvm.IntPredicate, x, y Expr) {
+	prog := b.prog
+	cx, cy := x, y
+	if prog.td.TypeSizeInBits(x.ll) > prog.td.TypeSizeInBits(y.ll) {
+		cy = b.castInt(y, x.Type)
+	} else {
+		cx = b.castInt(x, y.Type)
+	}
+	blks := b.fn.MakeBlocks(2)
+	fail, next := blks[0], blks[1]
+	b.impl.CreateCondBr(b.impl.CreateICmp(pred, cx.impl, cy.impl, ""), fail.impl, next.impl)
+	b.SetBlock(fail)
+	if prog.isGPU() {
+		b.Trap()
+		b.SetBlock(next)
+		return
+	}
+	if x.kind == vkUnsigned {
+		tyUint := types.Typ[types.Uint]
+		fn := b.rtFunc("Panic"+kind+"U", []types.Type{tyUint, tyInt}, nil)
+		b.Call(fn, b.castInt(x, prog.Type(tyUint)), y)
+	} else {
+		fn := b.rtFunc("Panic"+kind, []types.Type{tyInt, tyInt}, nil)
+		b.Call(fn, b.castInt(x, y.Type), y)
+	}
+	b.impl.CreateUnreachable()
+	b.SetBlock(next)
+}
+
+// The Slice instruction yields a slice of an existing string, slice or *array
+// X between optional integer bounds Low, High and Max, which are the zero
+// value of Expr if they are absent. The result is a string if X is, the type
+// of X if it is a slice, or a slice of the elements of the array that X
+// points to.
+//
+// Dynamically, this instruction panics if the bounds aren't in order, or
+// greater than the capacity of X (its length if it is a string), which is
+// reported by runtime.PanicSliceB and the like.
+//
+// Example printed form:
+//
+//	t1 = slice t0[1:]
+//	t3 = slice t2[t1:5:8]
+func (b Builder) Slice(x, low, high, max Expr) Expr {
+	if debugInstr {
+		log.Printf("Slice %v, %v, %v, %v\n", x.impl, low.impl, high.impl, max.impl)
+	}
+	prog := b.prog
+	tint := prog.Int()
+	var t Type
+	var data, n, c Expr
+	a := "Alen" // High and Max are checked against the length or the capacity
+	switch x.kind {
+	case vkString:
+		t = x.Type
+		data = b.dataOf(x, types.NewPointer(types.Typ[types.Byte]))
+		n = Expr{b.impl.CreateExtractValue(x.impl, 1, ""), tint}
+	case vkSlice:
+		t = x.Type
+		data = b.dataOf(x, types.NewPointer(x.t.Underlying().(*types.Slice).Elem()))
+		n = Expr{b.impl.CreateExtractValue(x.impl, 1, ""), tint}
+		c = Expr{b.impl.CreateExtractValue(x.impl, 2, ""), tint}
+		a = "Acap"
+	default:
+		arr := x.t.Underlying().(*types.Pointer).Elem().Underlying().(*types.Array)
+		t = prog.Type(types.NewSlice(arr.Elem()))
+		pt := prog.Pointer(prog.Index(x.Type))
+		data = Expr{b.impl.CreatePointerCast(x.impl, pt.ll, ""), pt}
+		n = prog.Val(int(arr.Len()))
+		c = n
+	}
+	limit := c
+	if c.Type == nil {
+		limit = n
+	}
+	if max.Type != nil {
+		b.sliceCheck("Slice3"+a, max, limit)
+		c = b.castInt(max, tint)
+		limit = c
+	}
+	if high.Type != nil {
+		if max.Type != nil {
+			b.sliceCheck("Slice3B", high, limit)
+		} else {
+			b.sliceCheck("Slice"+a, high, limit)
+		}
+		n = b.castInt(high, tint)
+	}
+	if low.Type != nil && !(low.impl.IsConstant() && low.impl.IsNull()) {
+		if max.Type != nil {
+			b.sliceCheck("Slice3C", low, n)
+		} else {
+			b.sliceCheck("SliceB", low, n)
+		}
+		low = b.castInt(low, tint)
+		elem := prog.Elem(data.Type)
+		data.impl = llvm.CreateInBoundsGEP(b.impl, elem.ll, data.impl, []llvm.Value{low.impl})
+		n.impl = b.impl.CreateSub(n.impl, low.impl, "")
+		if c.Type != nil {
+			c.impl = b.impl.CreateSub(c.impl, low.impl, "")
+		}
+	}
+	if x.kind == vkString {
+		return b.aggregateValue(t, data.impl, n.impl)
+	}
+	return b.aggregateValue(t, data.impl, n.impl, c.impl)
+}
+
+// The SliceToArrayPointer instruction yields the conversion of slice X to
+// array pointer t, the pointer to the underlying array of X, which is nil
+// if X is a nil slice.
+//
+// Dynamically, this instruction panics if the length of X is less than the
+// length of the array, which is reported by runtime.PanicSliceConvert.
+//
+// Example printed form:
+//
+//	t1 = slice to array pointer *[4]byte <- []byte (t0)
+func (b Builder) SliceToArrayPointer(t Type, x Expr) Expr {
+	if debugInstr {
+		log.Printf("SliceToArrayPointer %v, %v\n", t.t, x.impl)
+	}
+	prog := b.prog
+	arr := t.t.Underlying().(*types.Pointer).Elem().Underlying().(*types.Array)
+	if k := arr.Len(); k != 0 {
+		n := Expr{b.impl.CreateExtractValue(x.impl, 1, ""), prog.Int()}
+		b.checkBounds("SliceConvert", llvm.IntULT, n, prog.Val(int(k)))
+	}
+	data := b.impl.CreateExtractValue(x.impl, 0, "")
+	return Expr{b.impl.CreatePointerCast(data, t.ll, ""), t}
+}
+
+// The FieldAddr instruction yields the address of Field of *struct X.
+//
+// The field is identified by its index within the field list of the
+// struct type of X.
+//
+// Dynamically, this instruction panics if X evaluates to a nil pointer.
+//
+// Example printed form:
+//
+//	t1 = &t0.name [#1]
+func (b Builder) FieldAddr(x Expr, idx int) Expr {
+	if debugInstr {
+		log.Printf("FieldAddr %v, %d\n", x.impl, idx)
+	}
+	prog := b.prog
+	tstruc := prog.Elem(x.Type)
+	pt := prog.Pointer(prog.Field(tstruc, idx))
+	return Expr{llvm.CreateStructGEP(b.impl, tstruc.ll, x.impl, idx), pt}
+}
+
+// The Field instruction yields the Field of struct X.
+//
+// The field is identified by its index within the field list of the
+// struct type of X; by using numeric indices we avoid ambiguity of
+// package-local identifiers and permit compact representations.
+//
+// Example printed form:
+//
+//	t1 = t0.name [#1]
+func (b Builder) Field(x Expr, idx int) Expr {
+	if debugInstr {
+		log.Printf("Field %v, %d\n", x.impl, idx)
+	}
+	return Expr{b.impl.CreateExtractValue(x.impl, idx, ""), b.prog.Field(x.Type, idx)}
+}
+
 // The Alloc instruction reserves space for a variable of the given type,
 // zero-initializes it, and yields its address.
 //
@@ -319,13 +818,14 @@ func (b Builder) Alloc(t Type, heap bool) (ret Expr) {
 	if debugInstr {
 		log.Printf("Alloc %v, %v\n", t.ll, heap)
 	}
-	telem := b.prog.Elem(t)
+	prog := b.prog
+	telem := prog.Elem(t)
 	if heap {
-		ret.impl = llvm.CreateAlloca(b.impl, telem.ll)
+		ret = b.allocZ(telem)
 	} else {
-		panic("todo")
+		ret = b.alloca(telem)
+		b.zero(ret, telem)
 	}
-	// TODO: zero-initialize
 	ret.Type = t
 	return
 }
@@ -352,14 +852,333 @@ func (b Builder) Call(fn Expr, args ...Expr) (ret Expr) {
 		}
 		log.Println(b.String())
 	}
-	switch t := fn.t.(type) {
+	var ft llvm.Type
+	switch t := fn.t.Underlying().(type) {
 	case *types.Signature:
+		switch fn.kind {
+		case vkCFunc, vkFuncPtr:
+			return b.callC(fn, t, args)
+		case vkClosure:
+			return b.callClosure(fn, t, args)
+		}
+		ft = b.prog.llvmSignature(t).ll
 		ret.Type = b.prog.retType(t)
 	default:
 		panic("todo")
 	}
-	ret.impl = llvm.CreateCall(b.impl, fn.ll, fn.impl, llvmValues(args))
+	ret.impl = b.call(ft, fn.impl, llvmValues(args))
+	if f := fn.impl.IsAFunction(); !f.IsNil() && f.FunctionCallConv() != llvm.CCallConv {
+		ret.impl.SetInstructionCallConv(f.FunctionCallConv())
+	}
 	return
 }
 
+// TailCall is like Call, but the call is marked as a tail call, which LLVM
+// can compile to a jump, so that recursion in tail position doesn't grow the
+// stack. The function must return the result of the call right after it, and
+// the callee mustn't access its stack variables. Calls of C functions, whose
+// results may be converted, and calls with a GC strategy, which are rewritten
+// to statepoints (see Program.SetGC), aren't marked.
+func (b Builder) TailCall(fn Expr, args ...Expr) Expr {
+	ret := b.Call(fn, args...)
+	if fn.kind != vkCFunc && b.prog.gc == "" {
+		ret.impl.SetTailCall(true)
+	}
+	return ret
+}
+
+// InlineAsm executes the assembly code of the target, in the AT&T syntax on
+// x86, and returns its output of type ret, which is Program.Void() if it has
+// none. constraints binds the output and the operands args to registers or
+// memory, and lists the clobbered registers, eg. "={rax},{rax},{rdi},~{rcx},
+// ~{r11},~{memory}" (see "Inline Assembler Expressions" in the LLVM Language
+// Reference). The assembly is assumed to have side effects, so that it is
+// neither removed nor moved by optimizations.
+func (b Builder) InlineAsm(code, constraints string, ret Type, args ...Expr) Expr {
+	if debugInstr {
+		log.Printf("InlineAsm %q, %q, %v\n", code, constraints, args)
+	}
+	params := make([]llvm.Type, len(args))
+	vals := make([]llvm.Value, len(args))
+	for i, arg := range args {
+		params[i], vals[i] = arg.ll, arg.impl
+	}
+	ft := llvm.FunctionType(ret.ll, params, false)
+	asm := llvm.InlineAsm(ft, code, constraints, true, false, llvm.InlineAsmDialectATT, false)
+	return Expr{llvm.CreateCall(b.impl, ft, asm, vals), ret}
+}
+
+// KeepAlive keeps the object that the interface x points to alive up to this
+// point, for runtime.KeepAlive: the data of x is the operand of an empty
+// inline assembly, which optimizations don't remove.
+func (b Builder) KeepAlive(x Expr) {
+	if debugInstr {
+		log.Printf("KeepAlive %v\n", x.impl)
+	}
+	data := Expr{b.impl.CreateExtractValue(x.impl, 1, ""), b.prog.Type(tyUnsafePtr)}
+	b.InlineAsm("", "r", b.prog.Void(), data)
+}
+
+// The Extract instruction yields component Index of Tuple.
+//
+// This is used to access the results of instructions with multiple
+// return values, such as Call, TypeAssert, Next, UnOp(ARROW) and
+// IndexExpr(Map).
+//
+// Example printed form:
+//
+//	t1 = extract t0 #1
+func (b Builder) Extract(x Expr, index int) (ret Expr) {
+	if debugInstr {
+		log.Printf("Extract %v, %d\n", x.impl, index)
+	}
+	t := b.prog.Type(x.t.(*types.Tuple).At(index).Type())
+	return Expr{b.impl.CreateExtractValue(x.impl, index, ""), t}
+}
+
+// The MakeInterface instruction yields an interface value whose dynamic type
+// is the type of x and whose dynamic value is x: t is the interface type. The
+// tab of the interface is the address of the type descriptor of the type of
+// x (see Package.TypeDesc), as interface method tables aren't implemented.
+// Values whose representation is a pointer are the data of the interface,
+// while other values are copied to the heap (see isDirect).
+//
+// Example printed form:
+//
+//	t1 = make interface{} <- int (42:int)
+//	t2 = make Stringer <- t0
+func (b Builder) MakeInterface(t Type, x Expr) (ret Expr) {
+	if debugInstr {
+		log.Printf("MakeInterface %v, %v\n", t.t, x.impl)
+	}
+	prog := b.prog
+	tab := b.fn.pkg.TypeDesc(x.t)
+	var data llvm.Value
+	if isDirect(x.Type) {
+		data = b.impl.CreatePointerCast(x.impl, prog.tyVoidPtr(), "")
+	} else {
+		ptr := b.allocZ(x.Type)
+		b.Store(ptr, x)
+		data = b.impl.CreatePointerCast(ptr.impl, prog.tyVoidPtr(), "")
+	}
+	return b.aggregateValue(t, tab.impl, data)
+}
+
+// BuiltinCall emits a call to the builtin function fn (eg. real, imag, complex).
+func (b Builder) BuiltinCall(fn string, args ...Expr) (ret Expr) {
+	if debugInstr {
+		var b bytes.Buffer
+		fmt.Fprint(&b, "BuiltinCall ", fn)
+		for _, arg := range args {
+			fmt.Fprint(&b, ", ", arg.impl)
+		}
+		log.Println(b.String())
+	}
+	switch fn {
+	case "print", "println":
+		b.Print(args, fn == "println")
+		return
+	case "real":
+		re, _ := b.complexParts(args[0])
+		return Expr{re, b.prog.complexElem(args[0].Type)}
+	case "imag":
+		_, im := b.complexParts(args[0])
+		return Expr{im, b.prog.complexElem(args[0].Type)}
+	case "complex":
+		t := b.prog.complexOf(args[0].Type)
+		return b.makeComplex(args[0].impl, args[1].impl, t)
+	case "append":
+		return b.sliceAppend(args[0], args[1])
+	case "copy":
+		return b.sliceCopy(args[0], args[1])
+	case "Add": // unsafe.Add
+		return b.unsafeAdd(args[0], args[1])
+	case "Slice": // unsafe.Slice
+		return b.unsafeSlice(args[0], args[1])
+	case "String": // unsafe.String
+		return b.unsafeString(args[0], args[1])
+	case "SliceData": // unsafe.SliceData
+		elem := args[0].t.Underlying().(*types.Slice).Elem()
+		return b.dataOf(args[0], types.NewPointer(elem))
+	case "StringData": // unsafe.StringData
+		return b.dataOf(args[0], types.NewPointer(types.Typ[types.Byte]))
+	case "len", "cap":
+		switch args[0].kind {
+		case vkString, vkSlice:
+			idx := 1
+			if fn == "cap" {
+				idx = 2
+			}
+			return Expr{b.impl.CreateExtractValue(args[0].impl, idx, ""), b.prog.Int()}
+		}
+		if _, ok := args[0].t.Underlying().(*types.Map); ok && fn == "len" {
+			return b.mapLen(args[0])
+		}
+	case "delete":
+		b.mapDelete(args[0], args[1])
+		return
+	case "clear":
+		if _, ok := args[0].t.Underlying().(*types.Map); ok {
+			b.mapClear(args[0])
+			return
+		}
+	case "ssa:wrapnilchk": // the receiver of a wrapper of a value method
+		return b.wrapNilCheck(args[0], args[1], args[2])
+	case "recover":
+		return b.Call(b.rtFunc("Recover", nil, []types.Type{tyAny}))
+	}
+	panic("todo")
+}
+
+// wrapNilCheck returns the receiver x of the wrapper of the value method
+// typ.method, eg. "foo.T" and "M", that is called through a *T: if x is nil,
+// runtime.WrapNilFailed panics.
+func (b Builder) wrapNilCheck(x, typ, method Expr) Expr {
+	blks := b.fn.MakeBlocks(2)
+	fail, next := blks[0], blks[1]
+	b.impl.CreateCondBr(b.impl.CreateIsNull(x.impl, ""), fail.impl, next.impl)
+	b.SetBlock(fail)
+	tyString := types.Typ[types.String]
+	fn := b.rtFunc("WrapNilFailed", []types.Type{tyString, tyString}, nil)
+	b.Call(fn, typ, method)
+	b.impl.CreateUnreachable()
+	b.SetBlock(next)
+	return x
+}
+
+// -----------------------------------------------------------------------------
+
+// aggregateValue builds a value of the aggregate type t from its fields.
+func (b Builder) aggregateValue(t Type, flds ...llvm.Value) Expr {
+	ret := llvm.Undef(t.ll)
+	for i, fld := range flds {
+		ret = b.impl.CreateInsertValue(ret, fld, i, "")
+	}
+	return Expr{ret, t}
+}
+
+// castInt converts the integer x to the integer type t.
+func (b Builder) castInt(x Expr, t Type) Expr {
+	if x.ll == t.ll {
+		return Expr{x.impl, t}
+	}
+	var ret llvm.Value
+	td := b.prog.td
+	switch {
+	case td.TypeSizeInBits(x.ll) > td.TypeSizeInBits(t.ll):
+		ret = b.impl.CreateTrunc(x.impl, t.ll, "")
+	case x.kind == vkSigned:
+		ret = b.impl.CreateSExt(x.impl, t.ll, "")
+	default:
+		ret = b.impl.CreateZExt(x.impl, t.ll, "")
+	}
+	return Expr{ret, t}
+}
+
+// dataOf returns the data pointer of a string or slice.
+func (b Builder) dataOf(x Expr, t types.Type) Expr {
+	return Expr{b.impl.CreateExtractValue(x.impl, 0, ""), b.prog.Type(t)}
+}
+
+// unsafeAdd returns unsafe.Pointer(uintptr(ptr) + uintptr(len)).
+func (b Builder) unsafeAdd(ptr, len Expr) Expr {
+	prog := b.prog
+	offset := b.castInt(len, prog.Int())
+	ret := llvm.CreateGEP(b.impl, prog.tyInt8(), ptr.impl, []llvm.Value{offset.impl})
+	return Expr{ret, prog.Type(types.Typ[types.UnsafePointer])}
+}
+
+// unsafeSlice returns a slice whose underlying array starts at ptr and whose
+// length and capacity are len.
+func (b Builder) unsafeSlice(ptr, len Expr) Expr {
+	prog := b.prog
+	elem := ptr.t.Underlying().(*types.Pointer).Elem()
+	n := b.unsafeLen("PanicUnsafeSlice", ptr, len)
+	return b.aggregateValue(prog.Type(types.NewSlice(elem)), ptr.impl, n.impl, n.impl)
+}
+
+// sliceAppend returns append(s, x...), where x is a slice of the elements of
+// s, or a string if s is a []byte:
+//
+//	append(s, x...)  =>  runtime.SliceAppend(s, data(x), len(x), sizeof(elem))
+func (b Builder) sliceAppend(s, x Expr) Expr {
+	prog := b.prog
+	tint := prog.Int()
+	params := []types.Type{tyBytes, tyUnsafePtr, tyInt, tyInt}
+	fn := b.rtFunc("SliceAppend", params, []types.Type{tyBytes})
+	data := b.dataOf(x, tyUnsafePtr)
+	n := Expr{b.impl.CreateExtractValue(x.impl, 1, ""), tint}
+	ret := b.Call(fn, Expr{s.impl, prog.Type(tyBytes)}, data, n, b.elemSize(s))
+	ret.Type = s.Type
+	return ret
+}
+
+// sliceCopy returns copy(dst, src), where src is a slice of the elements of
+// dst, or a string if dst is a []byte:
+//
+//	copy(dst, src)  =>  runtime.SliceCopy(data(dst), len(dst), data(src), len(src), sizeof(elem))
+func (b Builder) sliceCopy(dst, src Expr) Expr {
+	prog := b.prog
+	tint := prog.Int()
+	params := []types.Type{tyUnsafePtr, tyInt, tyUnsafePtr, tyInt, tyInt}
+	fn := b.rtFunc("SliceCopy", params, []types.Type{tyInt})
+	ndst := Expr{b.impl.CreateExtractValue(dst.impl, 1, ""), tint}
+	nsrc := Expr{b.impl.CreateExtractValue(src.impl, 1, ""), tint}
+	return b.Call(fn, b.dataOf(dst, tyUnsafePtr), ndst, b.dataOf(src, tyUnsafePtr), nsrc, b.elemSize(dst))
+}
+
+// elemSize returns the size in bytes of the elements of the slice s, as an
+// int.
+func (b Builder) elemSize(s Expr) Expr {
+	prog := b.prog
+	elem := prog.Type(s.t.Underlying().(*types.Slice).Elem())
+	return prog.IntVal(prog.td.TypeAllocSize(elem.ll), prog.Int())
+}
+
+// unsafeString returns a string value whose underlying bytes start at ptr and
+// whose length is len.
+func (b Builder) unsafeString(ptr, len Expr) Expr {
+	prog := b.prog
+	n := b.unsafeLen("PanicUnsafeString", ptr, len)
+	return b.aggregateValue(prog.Type(types.Typ[types.String]), ptr.impl, n.impl)
+}
+
+// unsafeLen returns len, the length of unsafe.Slice or unsafe.String, as an
+// int. As gc does, the runtime function panics+"Len" panics if len is
+// negative, or doesn't fit in an int, and panics+"NilPtr" panics if ptr is nil
+// while len isn't zero.
+func (b Builder) unsafeLen(panics string, ptr, len Expr) Expr {
+	prog := b.prog
+	n := b.castInt(len, prog.Int())
+	zero := llvm.ConstNull(n.ll)
+	lenBits, intBits := prog.td.TypeSizeInBits(len.ll), prog.td.TypeSizeInBits(n.ll)
+	if len.kind != vkUnsigned || lenBits >= intBits { // len may be negative as an int
+		bad := b.impl.CreateICmp(llvm.IntSLT, n.impl, zero, "")
+		if lenBits > intBits {
+			back := b.impl.CreateSExt(n.impl, len.ll, "")
+			if len.kind == vkUnsigned {
+				back = b.impl.CreateZExt(n.impl, len.ll, "")
+			}
+			ovf := b.impl.CreateICmp(llvm.IntNE, back, len.impl, "")
+			bad = b.impl.CreateOr(bad, ovf, "")
+		}
+		b.unsafeCheck(bad, panics+"Len")
+	}
+	isNil := b.impl.CreateICmp(llvm.IntEQ, ptr.impl, llvm.ConstNull(ptr.ll), "")
+	nonzero := b.impl.CreateICmp(llvm.IntNE, n.impl, zero, "")
+	b.unsafeCheck(b.impl.CreateAnd(isNil, nonzero, ""), panics+"NilPtr")
+	return n
+}
+
+// unsafeCheck calls the runtime function fn, which panics, if bad is true.
+func (b Builder) unsafeCheck(bad llvm.Value, fn string) {
+	blks := b.fn.MakeBlocks(2)
+	fail, next := blks[0], blks[1]
+	b.impl.CreateCondBr(bad, fail.impl, next.impl)
+	b.SetBlock(fail)
+	b.Call(b.rtFunc(fn, nil, nil))
+	b.impl.CreateUnreachable()
+	b.SetBlock(next)
+}
+
 // -----------------------------------------------------------------------------
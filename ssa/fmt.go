@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/constant"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// Flags of a FmtVerb. They are the same as the ones of the formatter of the
+// runtime.
+const (
+	FmtMinus = 1 << iota // '-': pad with spaces on the right
+	FmtPlus              // '+': always print the sign of numbers
+	FmtSharp             // '#': alternate format, eg. 0x for %#x
+	FmtSpace             // ' ': leave a space for the sign of positive numbers
+	FmtZero              // '0': pad with leading zeros
+)
+
+// FmtVerb represents a verb of a format string of the fmt package, eg. %-8d.
+// Width and Prec are -1 if they aren't specified.
+type FmtVerb struct {
+	Verb  rune
+	Flags int
+	Width int
+	Prec  int
+}
+
+// FmtValue is the verb that formats a value as fmt.Print does, ie. %v.
+var FmtValue = FmtVerb{Verb: 'v', Width: -1, Prec: -1}
+
+// Fmt formats x, whose type must be a boolean, numeric (but not complex),
+// string or pointer type, according to verb and writes it to the standard
+// output. It calls the lightweight formatter of the runtime, which is what
+// calls of fmt.Print, Println and Printf are lowered to when the types of
+// their arguments are known statically:
+//
+//	fmt.Println("n =", n)  =>  runtime.FmtString("n =", 'v', -1, -1, 0)
+//	                           runtime.FmtString(" ", 's', -1, -1, 0)
+//	                           runtime.FmtInt(int64(n), 'v', -1, -1, 0)
+//	                           runtime.FmtString("\n", 's', -1, -1, 0)
+func (b Builder) Fmt(x Expr, verb FmtVerb) {
+	if debugInstr {
+		log.Printf("Fmt %v, %c\n", x.impl, verb.Verb)
+	}
+	prog := b.prog
+	var fn string
+	var t types.Type
+	switch x.kind {
+	case vkBool:
+		fn, t = "FmtBool", types.Typ[types.Bool]
+	case vkSigned:
+		fn, t = "FmtInt", types.Typ[types.Int64]
+		x = b.castInt(x, prog.Type(t))
+	case vkUnsigned:
+		fn, t = "FmtUint", types.Typ[types.Uint64]
+		x = b.castInt(x, prog.Type(t))
+	case vkFloat:
+		fn, t = "FmtFloat", types.Typ[types.Float64]
+		if x.ll == prog.Float32().ll {
+			fn, t = "FmtFloat32", types.Typ[types.Float32]
+		} else if tf := prog.Float64(); x.ll != tf.ll {
+			x = Expr{b.impl.CreateFPExt(x.impl, tf.ll, ""), tf}
+		}
+	case vkString:
+		fn, t = "FmtString", types.Typ[types.String]
+	default:
+		fn, t = "FmtPointer", tyUnsafePtr
+		x = Expr{b.impl.CreatePointerCast(x.impl, prog.tyVoidPtr(), ""), prog.Type(t)}
+	}
+	tyInt32, tyInt := types.Typ[types.Int32], types.Typ[types.Int]
+	rtFn := b.rtFunc(fn, []types.Type{t, tyInt32, tyInt, tyInt, tyInt}, nil)
+	b.Call(rtFn, x,
+		prog.IntVal(uint64(verb.Verb), prog.Type(tyInt32)),
+		prog.IntVal(uint64(verb.Width), prog.Int()),
+		prog.IntVal(uint64(verb.Prec), prog.Int()),
+		prog.IntVal(uint64(verb.Flags), prog.Int()))
+}
+
+// Print writes args, whose types must be boolean, numeric, string or pointer
+// types, to stderr as the builtin print does, or println if ln: separated by
+// spaces and followed by a newline. Each argument is written by the print
+// function of the runtime for its kind:
+//
+//	println("n =", n)  =>  runtime.PrintString("n =")
+//	                       runtime.PrintString(" ")
+//	                       runtime.PrintInt(int64(n))
+//	                       runtime.PrintString("\n")
+func (b Builder) Print(args []Expr, ln bool) {
+	if debugInstr {
+		log.Printf("Print %d, %v\n", len(args), ln)
+	}
+	prog := b.prog
+	tyString := types.Typ[types.String]
+	printString := func(s string) {
+		fn := b.rtFunc("PrintString", []types.Type{tyString}, nil)
+		b.Call(fn, b.Const(constant.MakeString(s), prog.Type(tyString)))
+	}
+	for i, x := range args {
+		if ln && i > 0 {
+			printString(" ")
+		}
+		var fn string
+		var t types.Type
+		switch x.kind {
+		case vkBool:
+			fn, t = "PrintBool", types.Typ[types.Bool]
+		case vkSigned:
+			fn, t = "PrintInt", types.Typ[types.Int64]
+			x = b.castInt(x, prog.Type(t))
+		case vkUnsigned:
+			fn, t = "PrintUint", types.Typ[types.Uint64]
+			x = b.castInt(x, prog.Type(t))
+		case vkFloat:
+			fn, t = "PrintFloat", types.Typ[types.Float64]
+			if x.ll == prog.Float32().ll {
+				fn, t = "PrintFloat32", types.Typ[types.Float32]
+			}
+		case vkComplex:
+			fn, t = "PrintComplex", types.Typ[types.Complex128]
+			if x.t.Underlying().(*types.Basic).Kind() == types.Complex64 {
+				fn, t = "PrintComplex64", types.Typ[types.Complex64]
+			}
+		case vkString:
+			fn, t = "PrintString", tyString
+		default:
+			ptr := x.impl
+			if x.kind == vkClosure { // the function of a func value
+				ptr = b.impl.CreateExtractValue(ptr, 0, "")
+			} else if x.ll.TypeKind() != llvm.PointerTypeKind { // eg. slices and interfaces
+				panic("unsupported print of a value of type " + x.t.String())
+			}
+			fn, t = "PrintPointer", tyUnsafePtr
+			x = Expr{b.impl.CreatePointerCast(ptr, prog.tyVoidPtr(), ""), prog.Type(t)}
+		}
+		b.Call(b.rtFunc(fn, []types.Type{t}, nil), x)
+	}
+	if ln {
+		printString("\n")
+	}
+}
+
+// FmtString writes the constant string s to the standard output, as the
+// literal text of a format string.
+func (b Builder) FmtString(s string) {
+	x := b.Const(constant.MakeString(s), b.prog.Type(types.Typ[types.String]))
+	b.Fmt(x, FmtVerb{Verb: 's', Width: -1, Prec: -1})
+}
+
+// -----------------------------------------------------------------------------
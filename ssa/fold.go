@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// go/ssa doesn't fold the branches on constants, eg. on a constant flag:
+//
+//	const debug = false
+//	if debug { ... }
+//
+// or on a condition that an instantiation of a generic function makes
+// constant. The LLVM builder folds the instructions whose operands are
+// constants, and Builder.If and Builder.Switch fold the branches on constants
+// to jumps, which leave the blocks they don't take unreachable. Package.Prune
+// removes them, so that the module is smaller even if it isn't optimized.
+
+// constBranch returns the integer value of the condition cond of a branch, and
+// whether it is a constant.
+func constBranch(cond llvm.Value) (uint64, bool) {
+	if cond.IsAConstantInt().IsNil() {
+		return 0, false
+	}
+	return cond.ZExtValue(), true
+}
+
+// Prune removes the basic blocks of the functions of the package that are
+// unreachable from their entry, eg. the ones that a branch on a constant
+// doesn't take. It must be called after all functions of the package are
+// built.
+func (p Package) Prune() {
+	for fn := p.mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.BasicBlocksCount() > 1 {
+			pruneBlocks(fn)
+		}
+	}
+}
+
+// pruneBlocks removes the basic blocks of fn that are unreachable from its
+// entry.
+func pruneBlocks(fn llvm.Value) {
+	entry := fn.EntryBasicBlock()
+	reached := map[llvm.BasicBlock]bool{entry: true}
+	work := []llvm.BasicBlock{entry}
+	for len(work) > 0 {
+		blk := work[len(work)-1]
+		work = work[:len(work)-1]
+		term := blk.LastInstruction()
+		if term.IsNil() {
+			continue
+		}
+		for i, n := 0, term.OperandsCount(); i < n; i++ {
+			op := term.Operand(i)
+			if op.IsABasicBlock().IsNil() {
+				continue
+			}
+			if succ := op.AsBasicBlock(); !reached[succ] {
+				reached[succ] = true
+				work = append(work, succ)
+			}
+		}
+	}
+	var dead []llvm.BasicBlock
+	for blk := fn.FirstBasicBlock(); !blk.IsNil(); blk = llvm.NextBasicBlock(blk) {
+		if !reached[blk] {
+			dead = append(dead, blk)
+		}
+	}
+	// the instructions of a dead block are only used by the dead blocks that
+	// it dominates, and the dead blocks are only branched to by dead blocks,
+	// so their instructions are removed before the blocks are
+	for _, blk := range dead {
+		for instr := blk.FirstInstruction(); !instr.IsNil(); {
+			next := llvm.NextInstruction(instr)
+			if t := instr.Type(); t.TypeKind() != llvm.VoidTypeKind {
+				instr.ReplaceAllUsesWith(llvm.Undef(t))
+			}
+			instr.EraseFromParentAsInstruction()
+			instr = next
+		}
+	}
+	if len(dead) == 0 {
+		return
+	}
+	b := fn.GlobalParent().Context().NewBuilder()
+	defer b.Dispose()
+	for blk := fn.FirstBasicBlock(); !blk.IsNil(); blk = llvm.NextBasicBlock(blk) {
+		if reached[blk] {
+			prunePhis(b, blk, reached)
+		}
+	}
+	for _, blk := range dead {
+		blk.EraseFromParent()
+	}
+}
+
+// prunePhis removes the incoming values of the phi nodes of the block blk
+// from the blocks that aren't reached. As a phi node can't remove them, it is
+// replaced by a new one.
+func prunePhis(b llvm.Builder, blk llvm.BasicBlock, reached map[llvm.BasicBlock]bool) {
+	for phi := blk.FirstInstruction(); !phi.IsNil() && !phi.IsAPHINode().IsNil(); {
+		next := llvm.NextInstruction(phi)
+		var vals []llvm.Value
+		var blks []llvm.BasicBlock
+		for i, n := 0, phi.IncomingCount(); i < n; i++ {
+			if pred := phi.IncomingBlock(i); reached[pred] {
+				vals = append(vals, phi.IncomingValue(i))
+				blks = append(blks, pred)
+			}
+		}
+		if len(vals) < phi.IncomingCount() {
+			b.SetInsertPointBefore(phi)
+			repl := b.CreatePHI(phi.Type(), "")
+			if len(vals) > 0 {
+				repl.AddIncoming(vals, blks)
+			}
+			phi.ReplaceAllUsesWith(repl)
+			phi.EraseFromParentAsInstruction()
+		}
+		phi = next
+	}
+}
+
+// -----------------------------------------------------------------------------
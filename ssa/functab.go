@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// AddFuncInfo adds function fn, whose Go name is name, eg. "main.(*T).M",
+// and which is declared at pos, to the function table of the package (see
+// FuncTab).
+func (p Package) AddFuncInfo(fn Function, name string, pos token.Position) {
+	prog := p.prog
+	p.funcs = append(p.funcs, llvm.ConstStruct([]llvm.Value{
+		prog.constVoidPtr(fn.impl),
+		p.constString(name),
+		p.constString(pos.Filename),
+		llvm.ConstInt(prog.tyInt(), uint64(pos.Line), false),
+	}, false))
+}
+
+// FuncTab returns the function table of the package, an array of the
+// functions that AddFuncInfo added, as an unsafe.Pointer, and its length.
+// The runtime symbolizes the addresses of code by the function tables of the
+// packages, whose entries are:
+//
+//	struct {
+//		entry unsafe.Pointer // address of the function
+//		name  string
+//		file  string
+//		line  int
+//	}
+//
+// The last entry, whose name is empty, is an empty function that FuncTab adds
+// after the functions of the package, which the linker keeps together: an
+// address that follows it isn't the one of a function of the package.
+func (p Package) FuncTab() (tab Expr, n int) {
+	prog := p.prog
+	typ := prog.Type(types.Typ[types.UnsafePointer])
+	if len(p.funcs) == 0 {
+		return prog.Null(typ), 0
+	}
+	end := llvm.AddFunction(p.mod, "", llvm.FunctionType(prog.tyVoid(), nil, false))
+	end.SetLinkage(llvm.PrivateLinkage)
+	b := prog.ctx.NewBuilder()
+	b.SetInsertPointAtEnd(llvm.AddBasicBlock(end, ""))
+	b.CreateRetVoid()
+	b.Dispose()
+	p.funcs = append(p.funcs, llvm.ConstStruct([]llvm.Value{
+		prog.constVoidPtr(end),
+		p.constString(""),
+		p.constString(""),
+		llvm.ConstInt(prog.tyInt(), 0, false),
+	}, false))
+	tyInfo := prog.ctx.StructType([]llvm.Type{prog.tyVoidPtr(), prog.tyString(), prog.tyString(), prog.tyInt()}, false)
+	v := llvm.ConstArray(tyInfo, p.funcs)
+	g := llvm.AddGlobal(p.mod, v.Type(), "")
+	g.SetInitializer(v)
+	g.SetGlobalConstant(true)
+	g.SetLinkage(llvm.PrivateLinkage)
+	return Expr{prog.constVoidPtr(g), typ}, len(p.funcs)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// The Go instruction creates a new goroutine that calls the function fn with
+// the arguments args, which are evaluated by the current goroutine:
+//
+//	go fn(args...)  =>  runtime.Go(fn$go, &struct{args...})
+//
+// fn$go is a private function of the package that unpacks the arguments and
+// calls fn. The pointer to the arguments is nil if there is none. If fn is a
+// func value, eg. a closure or a method value, it is saved before the
+// arguments, and called by a wrapper shared by the go statements of its
+// signature:
+//
+//	go f(args...)  =>  runtime.Go(__llgo_go.struct{f, args...}, &struct{f, args...})
+func (b Builder) Go(fn Expr, args ...Expr) {
+	if debugInstr {
+		log.Printf("Go %v, %v\n", fn.impl.Name(), args)
+	}
+	prog := b.prog
+	if fn.kind == vkClosure {
+		args = append([]Expr{fn}, args...)
+	}
+	flds := make([]*types.Var, len(args))
+	for i, arg := range args {
+		flds[i] = types.NewField(token.NoPos, nil, "_", arg.Type.t, false)
+	}
+	tstruc := prog.Type(types.NewStruct(flds, nil))
+	ptr := prog.Null(prog.Type(tyUnsafePtr))
+	if len(args) > 0 {
+		ptr = b.allocZ(tstruc)
+		for i, arg := range args {
+			b.Store(b.FieldAddr(ptr, i), arg)
+		}
+		ptr.Type = prog.Type(tyUnsafePtr)
+	}
+	var goFn Expr
+	if fn.kind == vkClosure {
+		goFn = b.fn.pkg.goClosureWrapper(tstruc, len(args))
+	} else {
+		goFn = b.fn.pkg.goWrapper(fn, tstruc, len(args))
+	}
+	rtGo := b.rtFunc("Go", []types.Type{goFn.Type.t, tyUnsafePtr}, nil)
+	b.Call(rtGo, b.funcValue(goFn), ptr)
+}
+
+// goWrapper returns fn$go, which calls fn with the nargs fields of the
+// struct of type tstruc that its parameter points to.
+func (p Package) goWrapper(fn Expr, tstruc Type, nargs int) Expr {
+	name := fn.impl.Name() + "$go"
+	if ret := p.FuncOf(name); ret != nil {
+		return ret.Expr
+	}
+	params := newTuple(tyUnsafePtr)
+	ret := p.NewFunc(name, types.NewSignatureType(nil, nil, nil, params, nil, false))
+	ret.impl.SetLinkage(llvm.PrivateLinkage)
+	b := ret.MakeBody(1)
+	ptr := ret.Param(0)
+	ptr.Type = p.prog.Pointer(tstruc)
+	args := make([]Expr, nargs)
+	for i := range args {
+		args[i] = b.Load(b.FieldAddr(ptr, i))
+	}
+	b.Call(fn, args...)
+	b.Return()
+	return ret.Expr
+}
+
+// goClosureWrapper returns __llgo_go.T, where T is tstruc, which calls the
+// func value of the first field of the struct that its parameter points to
+// with the nargs-1 other fields.
+func (p Package) goClosureWrapper(tstruc Type, nargs int) Expr {
+	params := newTuple(tyUnsafePtr)
+	fn, b, ok := p.keyFunc("__llgo_go.", tstruc.t, types.NewSignatureType(nil, nil, nil, params, nil, false))
+	if ok {
+		return fn.Expr
+	}
+	ptr := fn.Param(0)
+	ptr.Type = p.prog.Pointer(tstruc)
+	args := make([]Expr, nargs)
+	for i := range args {
+		args[i] = b.Load(b.FieldAddr(ptr, i))
+	}
+	b.Call(args[0], args[1:]...)
+	b.Return()
+	return fn.Expr
+}
+
+// PreemptCheck inserts a preemption check in the prologue of the function,
+// which must have its basic blocks: if the runtime requests preemption, as it
+// does when goroutines have been running for too long, runtime.Preempt is
+// called to let other goroutines run.
+//
+//	_llgo_prologue:
+//		if runtime.preemptFlag != 0 { runtime.Preempt() }
+//		goto _llgo_0
+func (b Builder) PreemptCheck() {
+	if debugInstr {
+		log.Println("PreemptCheck")
+	}
+	prog := b.prog
+	blk := b.fn.blks[0].impl
+	entry := llvm.InsertBasicBlock(blk, "_llgo_prologue")
+	slow := llvm.InsertBasicBlock(blk, "_llgo_preempt")
+	b.impl.SetInsertPointAtEnd(entry)
+	flag := b.AtomicLoad(b.rtVar("preemptFlag", types.Typ[types.Uint32]))
+	zero := prog.IntVal(0, flag.Type)
+	b.impl.CreateCondBr(b.BinOp(token.NEQ, flag, zero).impl, slow, blk)
+	b.impl.SetInsertPointAtEnd(slow)
+	b.Call(b.rtFunc("Preempt", nil, nil))
+	b.impl.CreateBr(blk)
+	b.impl.SetInsertPointAtEnd(blk)
+}
+
+// Safepoint inserts a preemption check, as the one of PreemptCheck, at the
+// insertion point, eg. on the back edge of a loop: a loop that calls no
+// function, eg. one that spins, would otherwise never be preempted, and would
+// starve the goroutines that wait for its processor.
+//
+//	if runtime.preemptFlag != 0 { runtime.Preempt() }
+func (b Builder) Safepoint() {
+	if debugInstr {
+		log.Println("Safepoint")
+	}
+	blks := b.fn.MakeBlocks(2)
+	slow, next := blks[0], blks[1]
+	flag := b.AtomicLoad(b.rtVar("preemptFlag", types.Typ[types.Uint32]))
+	zero := b.prog.IntVal(0, flag.Type)
+	b.impl.CreateCondBr(b.BinOp(token.NEQ, flag, zero).impl, slow.impl, next.impl)
+	b.SetBlock(slow)
+	b.Call(b.rtFunc("Preempt", nil, nil))
+	b.Jump(next)
+	b.SetBlock(next)
+}
+
+// -----------------------------------------------------------------------------
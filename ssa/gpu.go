@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// GPUs of kernels, see KernelTarget.
+const (
+	GPUNVPTX  = "nvptx"  // NVIDIA GPUs, whose kernels are PTX
+	GPUAMDGPU = "amdgpu" // AMD GPUs, whose kernels are code objects of ROCm
+)
+
+// KernelLauncher is the C function that launches a kernel on the GPU: it is
+// defined by the package github.com/goplus/llgo/gpu (see Builder.KernelLaunch).
+const KernelLauncher = "llgo_gpu_launch"
+
+// KernelTarget returns the target of the kernels of gpu, eg. GPUNVPTX, for
+// the processor cpu, eg. sm_70 or gfx90a, or for the default one of gpu if
+// cpu is empty. It reports false if gpu is unknown.
+func KernelTarget(gpu, cpu string) (*Target, bool) {
+	switch gpu {
+	case GPUNVPTX:
+		return &Target{GOARCH: "nvptx64", Triple: "nvptx64-nvidia-cuda", CPU: orDefault(cpu, "sm_52")}, true
+	case GPUAMDGPU:
+		return &Target{GOARCH: "amdgcn", Triple: "amdgcn-amd-amdhsa", CPU: orDefault(cpu, "gfx900")}, true
+	}
+	return nil, false
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// isGPU reports whether the program is the code of the kernels of a GPU (see
+// KernelTarget), which has no runtime: the checks that call the runtime to
+// panic, eg. of bounds, call llvm.trap instead, which aborts the kernel.
+func (p Program) isGPU() bool {
+	return strings.HasPrefix(p.triple, "nvptx") || strings.HasPrefix(p.triple, "amdgcn")
+}
+
+// Calling conventions of kernels.
+const (
+	callConvPTXKernel    llvm.CallConv = 71
+	callConvAMDGPUKernel llvm.CallConv = 91
+)
+
+// SetKernel makes the function a kernel, an entry point of the code of the GPU
+// of its program (see KernelTarget) that the host launches.
+func (p Function) SetKernel() {
+	if strings.HasPrefix(p.prog.triple, "amdgcn") {
+		p.impl.SetFunctionCallConv(callConvAMDGPUKernel)
+	} else {
+		p.ptxKernel().SetFunctionCallConv(callConvPTXKernel)
+	}
+}
+
+// ptxKernel returns the function of the PTX kernel of the function. The NVPTX
+// backend of LLVM 14 crashes on the pointer parameters of functions with
+// opaque pointers, so the kernel of a function that has any takes them as
+// integers, which its body, moved from the function, converts back.
+func (p Function) ptxKernel() llvm.Value {
+	fn := p.impl
+	ft := fn.GlobalValueType()
+	params := ft.ParamTypes()
+	hasPtr := false
+	for i, t := range params {
+		if t.TypeKind() == llvm.PointerTypeKind {
+			params[i] = p.prog.ctx.Int64Type()
+			hasPtr = true
+		}
+	}
+	if !hasPtr {
+		return fn
+	}
+	name := fn.Name()
+	fn.SetName("")
+	kernel := llvm.AddFunction(p.pkg.mod, name, llvm.FunctionType(ft.ReturnType(), params, false))
+	tmp := p.prog.ctx.AddBasicBlock(kernel, "")
+	last := tmp
+	for _, bb := range fn.BasicBlocks() {
+		bb.MoveAfter(last)
+		last = bb
+	}
+	tmp.EraseFromParent()
+	entry := kernel.FirstBasicBlock()
+	b := p.prog.ctx.NewBuilder()
+	defer b.Dispose()
+	b.SetInsertPointBefore(entry.FirstInstruction())
+	for i, arg := range kernel.Params() {
+		if t := ft.ParamTypes()[i]; t.TypeKind() == llvm.PointerTypeKind {
+			arg = b.CreateIntToPtr(arg, t, "")
+		}
+		fn.Param(i).ReplaceAllUsesWith(arg)
+	}
+	fn.EraseFromParentAsFunction()
+	p.impl = kernel
+	return kernel
+}
+
+// KernelLaunch launches the kernel name of the code image of gpu, and waits
+// for it to complete, by calling KernelLauncher:
+//
+//	void llgo_gpu_launch(const char *gpu, const char *image, uintptr_t imageLen,
+//		const char *name, void *args, uintptr_t argsLen);
+//
+// args points to a copy of the arguments of the kernel, laid out as the
+// parameters of a kernel are in the constant memory of the GPU: as the fields
+// of a C struct.
+func (b Builder) KernelLaunch(gpu string, image []byte, name string, args ...Expr) {
+	if debugInstr {
+		log.Printf("KernelLaunch %s, %s\n", gpu, name)
+	}
+	prog := b.prog
+	fields := make([]llvm.Type, len(args))
+	for i, arg := range args {
+		fields[i] = arg.ll
+	}
+	st := prog.ctx.StructType(fields, false)
+	buf := b.entryAlloca(st)
+	for i, arg := range args {
+		b.impl.CreateStore(arg.impl, b.impl.CreateStructGEP(st, buf, i, ""))
+	}
+	pkg := b.fn.pkg
+	fn := pkg.FuncOf(KernelLauncher)
+	if fn == nil {
+		pchar := types.NewPointer(types.Typ[types.Int8])
+		params := newTuple(pchar, pchar, tyUintptr, pchar, tyUnsafePtr, tyUintptr)
+		fn = pkg.NewFunc(KernelLauncher, types.NewSignatureType(nil, nil, nil, params, nil, false))
+	}
+	uptr := prog.Type(tyUintptr)
+	ptr := Expr{b.impl.CreatePointerCast(buf, prog.tyVoidPtr(), ""), prog.Type(tyUnsafePtr)}
+	b.Call(fn.Expr, b.CStr(gpu), b.CStr(string(image)), prog.IntVal(uint64(len(image)), uptr),
+		b.CStr(name), ptr, prog.IntVal(prog.td.TypeAllocSize(st), uptr))
+}
+
+// AMDGPUDim returns the number of threads of the blocks of the grid of the
+// running AMD kernel, its work-groups, along axis (0, 1 or 2), or if grid,
+// the number of blocks of the grid along axis, which AMD GPUs have no
+// intrinsic for: they are read from the dispatch packet of HSA of the kernel,
+// which has the size of the grid in threads, rounded up to blocks:
+//
+//	block := uint32(*(*uint16)(dispatch + 4 + 2*axis))
+//	grid := (*(*uint32)(dispatch + 12 + 4*axis) + block - 1) / block
+func (b Builder) AMDGPUDim(grid bool, axis int) Expr {
+	if debugInstr {
+		log.Printf("AMDGPUDim %v, %d\n", grid, axis)
+	}
+	prog := b.prog
+	ctx := prog.ctx
+	i8, i16, i32 := ctx.Int8Type(), ctx.Int16Type(), ctx.Int32Type()
+	ft := llvm.FunctionType(llvm.PointerType(i8, 4), nil, false) // the constant address space
+	mod := b.fn.pkg.mod
+	fn := mod.NamedFunction("llvm.amdgcn.dispatch.ptr")
+	if fn.IsNil() {
+		fn = llvm.AddFunction(mod, "llvm.amdgcn.dispatch.ptr", ft)
+	}
+	dispatch := b.impl.CreateCall(ft, fn, nil, "")
+	load := func(t llvm.Type, off int) llvm.Value {
+		ptr := b.impl.CreateInBoundsGEP(i8, dispatch, []llvm.Value{llvm.ConstInt(i32, uint64(off), false)}, "")
+		return b.impl.CreateLoad(t, ptr, "")
+	}
+	n := b.impl.CreateZExt(load(i16, 4+2*axis), i32, "")
+	if grid {
+		size := b.impl.CreateAdd(load(i32, 12+4*axis), b.impl.CreateSub(n, llvm.ConstInt(i32, 1, false), ""), "")
+		n = b.impl.CreateUDiv(size, n, "")
+	}
+	return Expr{n, prog.Type(types.Typ[types.Uint32])}
+}
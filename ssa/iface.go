@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// TypeAssert returns x.(t), where x is an interface and t is either a concrete
+// type, whose descriptor is compared with the dynamic type of x (see
+// TypeDesc), or an empty interface. If commaOk, it returns the tuple
+// (x.(t), ok), with the zero value of t if !ok; otherwise a failed assertion
+// is reported by runtime.TypeAssertFailed, which aborts the program.
+func (b Builder) TypeAssert(x Expr, t Type, commaOk bool) Expr {
+	if debugInstr {
+		log.Printf("TypeAssert %v, %v, %v\n", x.impl, t.t, commaOk)
+	}
+	tab := b.impl.CreateExtractValue(x.impl, 0, "")
+	var ok llvm.Value
+	if isInterface(t.t) {
+		ok = b.impl.CreateICmp(llvm.IntNE, tab, llvm.ConstNull(tab.Type()), "")
+	} else {
+		ok = b.impl.CreateICmp(llvm.IntEQ, tab, b.fn.pkg.typeDesc(t.t), "")
+	}
+	return b.typeAssert(x, t, ok, commaOk)
+}
+
+// TypeSwitch returns the index, as an int, of the first type of typs, which
+// are concrete types, that is the dynamic type of the interface x, or
+// len(typs) if none is. The hash of the dynamic type is looked up by a switch
+// instruction, which LLVM lowers to a jump table or a binary search, and only
+// the descriptors of the types of the same hash are compared with it:
+//
+//	if tab == nil { goto done }
+//	k := len(typs)
+//	if tab != nil {
+//		switch tab.Hash {
+//		case hash(T0): if tab == &T0 { k = 0 }
+//		...
+//		}
+//	}
+//
+// The assertions of the cases of a type switch, whose dynamic type it looks
+// up once, are then compiled by TypeSwitchCase.
+func (b Builder) TypeSwitch(x Expr, typs []types.Type) Expr {
+	if debugInstr {
+		log.Printf("TypeSwitch %v, %d cases\n", x.impl, len(typs))
+	}
+	prog := b.prog
+	pkg := b.fn.pkg
+	tyInt := prog.Int()
+	k := b.alloca(tyInt)
+	b.Store(k, prog.IntVal(uint64(len(typs)), tyInt))
+	tab := b.impl.CreateExtractValue(x.impl, 0, "")
+	blks := b.fn.MakeBlocks(2)
+	lookup, done := blks[0], blks[1]
+	nilTab := b.impl.CreateICmp(llvm.IntEQ, tab, llvm.ConstNull(tab.Type()), "")
+	b.impl.CreateCondBr(nilTab, done.impl, lookup.impl)
+	b.SetBlock(lookup)
+	tyHash := prog.Type(types.Typ[types.Uint32])
+	phash := llvm.CreateStructGEP(b.impl, prog.tyTypeDesc(), b.impl.CreatePointerCast(tab, llvm.PointerType(prog.tyTypeDesc(), 0), ""), 2)
+	hash := Expr{llvm.CreateLoad(b.impl, tyHash.ll, phash), tyHash}
+
+	// the cases by hash, in the order of their first types
+	var hashes []uint32
+	cases := make(map[uint32][]int)
+next:
+	for i, t := range typs {
+		h := pkg.hashOf(t)
+		for _, j := range cases[h] {
+			if types.Identical(typs[j], t) {
+				continue next // the first case of t matches
+			}
+		}
+		if cases[h] == nil {
+			hashes = append(hashes, h)
+		}
+		cases[h] = append(cases[h], i)
+	}
+	vals := make([]Expr, len(hashes))
+	cblks := b.fn.MakeBlocks(len(hashes))
+	for i, h := range hashes {
+		vals[i] = prog.IntVal(uint64(h), tyHash)
+	}
+	b.Switch(hash, vals, cblks, done)
+	for i, h := range hashes {
+		b.SetBlock(cblks[i])
+		for _, j := range cases[h] {
+			blks := b.fn.MakeBlocks(2)
+			match, next := blks[0], blks[1]
+			eq := b.impl.CreateICmp(llvm.IntEQ, tab, pkg.typeDesc(typs[j]), "")
+			b.impl.CreateCondBr(eq, match.impl, next.impl)
+			b.SetBlock(match)
+			b.Store(k, prog.IntVal(uint64(j), tyInt))
+			b.Jump(done)
+			b.SetBlock(next)
+		}
+		b.Jump(done)
+	}
+	b.SetBlock(done)
+	return b.Load(k)
+}
+
+// TypeSwitchCase returns the tuple (x.(t), k == i), which is the one of the
+// assertion of the ith case of a type switch, of type t, whose index of the
+// dynamic type of x is k (see TypeSwitch).
+func (b Builder) TypeSwitchCase(x Expr, t Type, k Expr, i int) Expr {
+	if debugInstr {
+		log.Printf("TypeSwitchCase %v, %v, %v, %d\n", x.impl, t.t, k.impl, i)
+	}
+	ok := b.impl.CreateICmp(llvm.IntEQ, k.impl, llvm.ConstInt(k.ll, uint64(i), false), "")
+	return b.typeAssert(x, t, ok, true)
+}
+
+// typeAssert returns x.(t), or (x.(t), ok) if commaOk, given whether the
+// assertion succeeds.
+func (b Builder) typeAssert(x Expr, t Type, ok llvm.Value, commaOk bool) Expr {
+	prog := b.prog
+	if !commaOk {
+		blks := b.fn.MakeBlocks(2)
+		fail, next := blks[0], blks[1]
+		b.impl.CreateCondBr(ok, next.impl, fail.impl)
+		b.SetBlock(fail)
+		pkg := b.fn.pkg
+		fn := b.rtFunc("TypeAssertFailed", []types.Type{tyUnsafePtr, tyUnsafePtr, tyUnsafePtr}, nil)
+		tab := Expr{b.impl.CreateExtractValue(x.impl, 0, ""), prog.Type(tyUnsafePtr)}
+		b.Call(fn, pkg.TypeDesc(x.t), tab, pkg.TypeDesc(t.t))
+		b.impl.CreateUnreachable()
+		b.SetBlock(next)
+	}
+	var v Expr
+	switch data := b.impl.CreateExtractValue(x.impl, 1, ""); {
+	case isInterface(t.t):
+		v = Expr{x.impl, t}
+		if commaOk {
+			v.impl = b.impl.CreateSelect(ok, x.impl, llvm.ConstNull(t.ll), "")
+		}
+	case isDirect(t): // see MakeInterface
+		v = Expr{b.impl.CreatePointerCast(data, t.ll, ""), t}
+		if commaOk {
+			v.impl = b.impl.CreateSelect(ok, v.impl, llvm.ConstNull(t.ll), "")
+		}
+	default:
+		ptr := b.impl.CreatePointerCast(data, llvm.PointerType(t.ll, 0), "")
+		if commaOk {
+			zero := b.alloca(t)
+			b.impl.CreateStore(llvm.ConstNull(t.ll), zero.impl)
+			ptr = b.impl.CreateSelect(ok, ptr, zero.impl, "")
+		}
+		v = Expr{llvm.CreateLoad(b.impl, t.ll, ptr), t}
+	}
+	if !commaOk {
+		return v
+	}
+	return b.aggregateValue(prog.Type(newTuple(t.t, tyBool)), v.impl, ok)
+}
+
+// isDirect reports whether the values of type t are the data of interfaces,
+// rather than being copied to the heap: the ones whose representation is a
+// pointer, but C function pointers, as the runtime knows whether the values
+// of a type are direct by its kind (see runtime.Type.isDirect), and func
+// values are closures.
+func isDirect(t Type) bool {
+	return t.ll.TypeKind() == llvm.PointerTypeKind && t.kind != vkFuncPtr
+}
+
+func isInterface(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Interface)
+	return ok
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"os"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// SetAlwaysInline makes the function inlined at all its call sites, including
+// the ones of other packages if it is a runtime function (see InlineBitcode).
+func (p Function) SetAlwaysInline() {
+	p.impl.AddFunctionAttr(p.prog.ctx.CreateEnumAttribute(llvm.AttributeKindID("alwaysinline"), 0))
+}
+
+// SetNoInline makes the function never inlined.
+func (p Function) SetNoInline() {
+	p.impl.AddFunctionAttr(p.prog.ctx.CreateEnumAttribute(llvm.AttributeKindID("noinline"), 0))
+}
+
+func isAlwaysInline(fn llvm.Value) bool {
+	return !fn.GetEnumFunctionAttribute(llvm.AttributeKindID("alwaysinline")).IsNil()
+}
+
+// InlineBitcode writes the functions of the LLVM IR files, which are the ones
+// of the runtime packages, that are inlined at all their call sites (see
+// Function.SetAlwaysInline) to the bitcode file output. They are defined
+// available_externally, and the other functions and variables are declared
+// only, so that output can be linked to the module of each package, where
+// LLVM inlines and simplifies them per call site, without duplicating the
+// runtime (see the -mlink-builtin-bitcode flag of clang). It reports whether
+// there are such functions; output isn't written if not.
+func InlineBitcode(files []string, output string) (bool, error) {
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	var mod llvm.Module
+	for _, file := range files {
+		buf, err := llvm.NewMemoryBufferFromFile(file)
+		if err != nil {
+			return false, err
+		}
+		m, err := ctx.ParseIR(buf) // takes the ownership of buf
+		if err != nil {
+			return false, err
+		}
+		if mod.IsNil() {
+			mod = m
+		} else if err = llvm.LinkModules(mod, m); err != nil { // destroys m
+			return false, err
+		}
+	}
+	if mod.IsNil() {
+		return false, nil
+	}
+	defer mod.Dispose()
+	inline := false
+	for fn := mod.FirstFunction(); !fn.IsNil(); {
+		next := llvm.NextFunction(fn)
+		if !fn.IsDeclaration() && isExternal(fn) {
+			if isAlwaysInline(fn) {
+				fn.SetLinkage(llvm.AvailableExternallyLinkage)
+				inline = true
+			} else {
+				declareOnly(mod, fn)
+			}
+		}
+		fn = next
+	}
+	if !inline {
+		return false, nil
+	}
+	for g := mod.FirstGlobal(); !g.IsNil(); {
+		next := llvm.NextGlobal(g)
+		if g.Linkage() == llvm.AppendingLinkage { // eg. llvm.global_ctors
+			g.EraseFromParentAsGlobal()
+		} else if !g.IsDeclaration() && isExternal(g) {
+			name := g.Name()
+			g.SetName("")
+			decl := llvm.AddGlobal(mod, g.GlobalValueType(), name)
+			decl.SetThreadLocal(g.IsThreadLocal())
+			g.ReplaceAllUsesWith(decl)
+			g.EraseFromParentAsGlobal()
+		}
+		g = next
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return false, err
+	}
+	err = llvm.WriteBitcodeToFile(mod, f)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err == nil, err
+}
+
+// isExternal reports whether the global value v is visible to other modules.
+func isExternal(v llvm.Value) bool {
+	switch v.Linkage() {
+	case llvm.PrivateLinkage, llvm.InternalLinkage:
+		return false
+	}
+	return true
+}
+
+// declareOnly replaces the function fn of mod by its declaration.
+func declareOnly(mod llvm.Module, fn llvm.Value) {
+	name := fn.Name()
+	fn.SetName("")
+	decl := llvm.AddFunction(mod, name, fn.GlobalValueType())
+	fn.ReplaceAllUsesWith(decl)
+	fn.EraseFromParentAsFunction()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import "strings"
+
+// -----------------------------------------------------------------------------
+
+// The symbol of a member of a Go package, eg. a function or a variable, is
+//
+//	<package path>.<name>
+//
+// where name is the name of the member in the package: F for a function,
+// T.M or (*T).M for a method of the type T, F$1 for the first closure of F,
+// and F[int,string] for an instantiation of a generic function. The path and
+// the name are escaped, so that the symbol is a single word that tools can
+// split: the bytes that aren't printable ASCII, ' ', '"' and '%' are escaped
+// as %xx, xx being their hexadecimal value, and so are the dots of the last
+// element of the path, as the Go linker does, so that the path ends at the
+// first dot after its last slash, eg. gopkg.in/yaml%2ev3.Unmarshal.
+//
+// Demangle restores the package path and the name of a symbol, eg. for a
+// profiler or a debugger to show them, as llgo filt does.
+
+// Mangle returns the symbol of the member name of the package pkgPath.
+func Mangle(pkgPath, name string) string {
+	var b strings.Builder
+	last := strings.LastIndexByte(pkgPath, '/')
+	for i := 0; i < len(pkgPath); i++ {
+		escape(&b, pkgPath[i], i > last && pkgPath[i] == '.')
+	}
+	b.WriteByte('.')
+	for i := 0; i < len(name); i++ {
+		escape(&b, name[i], false)
+	}
+	return b.String()
+}
+
+func escape(b *strings.Builder, c byte, dot bool) {
+	const hex = "0123456789abcdef"
+	if c <= ' ' || c == '"' || c == '%' || c >= 0x7f || dot {
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+		return
+	}
+	b.WriteByte(c)
+}
+
+// Demangle returns the package path and the name of the member of a Go
+// package whose symbol is sym (see Mangle), or false if sym has no package
+// path, eg. if it's the one of a C function.
+func Demangle(sym string) (pkgPath, name string, ok bool) {
+	// The last slash of the path precedes the brackets of type arguments,
+	// and the parenthesis of a method, whose types may have paths too.
+	end := strings.IndexAny(sym, "[(")
+	if end < 0 {
+		end = len(sym)
+	}
+	start := strings.LastIndexByte(sym[:end], '/') + 1
+	dot := strings.IndexByte(sym[start:end], '.')
+	if dot < 0 {
+		return "", "", false
+	}
+	dot += start
+	if pkgPath, ok = unescape(sym[:dot]); !ok {
+		return
+	}
+	name, ok = unescape(sym[dot+1:])
+	return pkgPath, name, ok && pkgPath != "" && name != ""
+}
+
+func unescape(s string) (string, bool) {
+	if strings.IndexByte(s, '%') < 0 {
+		return s, true
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' {
+			if i+2 >= len(s) {
+				return "", false
+			}
+			hi, ok1 := unhex(s[i+1])
+			lo, ok2 := unhex(s[i+2])
+			if !ok1 || !ok2 {
+				return "", false
+			}
+			c = hi<<4 | lo
+			i += 2
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), true
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// -----------------------------------------------------------------------------
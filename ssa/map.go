@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ssa
+
+import (
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// A map is a pointer to a runtime.Map, whose keys are passed to the runtime
+// by address, with the functions that hash and compare them (see hashFunc
+// and equalFunc).
+
+// tyMapIter is the type of the iterators of range loops over maps, which must
+// match runtime.MapIter.
+var tyMapIter = types.NewStruct([]*types.Var{
+	types.NewField(0, nil, "m", tyUnsafePtr, false),
+	types.NewField(0, nil, "buckets", tyUnsafePtr, false),
+	types.NewField(0, nil, "B", types.Typ[types.Uint8], false),
+	types.NewField(0, nil, "offset", types.Typ[types.Uint8], false),
+	types.NewField(0, nil, "start", tyUintptr, false),
+	types.NewField(0, nil, "visited", tyUintptr, false),
+	types.NewField(0, nil, "b", tyUnsafePtr, false),
+	types.NewField(0, nil, "i", tyInt, false),
+}, nil)
+
+// MakeMap creates a map of type t, with room for hint entries if hint isn't
+// nil:
+//
+//	make(map[K]V, hint)  =>  runtime.NewMap(sizeof(K), sizeof(V), __llgo_hash.K, __llgo_equal.K, hint)
+func (b Builder) MakeMap(t Type, hint Expr) Expr {
+	if debugInstr {
+		log.Printf("MakeMap %v, %v\n", t.t, hint.impl)
+	}
+	prog := b.prog
+	pkg := b.fn.pkg
+	mt := t.t.Underlying().(*types.Map)
+	hash, equal := b.funcValue(pkg.hashFunc(mt.Key())), b.funcValue(pkg.equalFunc(mt.Key()))
+	if hint.impl.IsNil() {
+		hint = prog.Val(0)
+	} else {
+		hint = b.castInt(hint, prog.Int())
+	}
+	params := []types.Type{tyUintptr, tyUintptr, tyHashFunc, tyEqualFunc, tyInt}
+	fn := b.rtFunc("NewMap", params, []types.Type{tyUnsafePtr})
+	ret := b.Call(fn, b.uintptrSizeof(prog.Type(mt.Key())), b.uintptrSizeof(prog.Type(mt.Elem())), hash, equal, hint)
+	ret.Type = t
+	return ret
+}
+
+// MapLookup returns the element of key in the map m, and whether m has key if
+// commaOk:
+//
+//	v := m[key]      =>  v := zero; runtime.MapAccess(m, &key, &v)
+//	v, ok := m[key]  =>  v := zero; ok := runtime.MapAccess(m, &key, &v)
+func (b Builder) MapLookup(m, key Expr, commaOk bool) Expr {
+	if debugInstr {
+		log.Printf("MapLookup %v, %v, %v\n", m.impl, key.impl, commaOk)
+	}
+	prog := b.prog
+	elem := prog.Type(m.t.Underlying().(*types.Map).Elem())
+	ptr := b.alloca(elem)
+	b.impl.CreateStore(llvm.ConstNull(elem.ll), ptr.impl)
+	fn := b.rtFunc("MapAccess", []types.Type{tyUnsafePtr, tyUnsafePtr, tyUnsafePtr}, []types.Type{tyBool})
+	ok := b.Call(fn, m, b.addrOf(key), Expr{ptr.impl, prog.Type(tyUnsafePtr)})
+	v := b.Load(ptr)
+	if !commaOk {
+		return v
+	}
+	return b.aggregateValue(prog.Type(newTuple(elem.t, tyBool)), v.impl, ok.impl)
+}
+
+// MapUpdate sets the element of key in the map m to val:
+//
+//	m[key] = val  =>  runtime.MapAssign(m, &key, &val)
+func (b Builder) MapUpdate(m, key, val Expr) {
+	if debugInstr {
+		log.Printf("MapUpdate %v, %v, %v\n", m.impl, key.impl, val.impl)
+	}
+	fn := b.rtFunc("MapAssign", []types.Type{tyUnsafePtr, tyUnsafePtr, tyUnsafePtr}, nil)
+	b.Call(fn, m, b.addrOf(key), b.addrOf(val))
+}
+
+// MapRange starts a range loop over the map m, and returns its iterator,
+// which MapNext advances:
+//
+//	range m  =>  it := runtime.MapIter{}; runtime.MapIterInit(m, &it)
+func (b Builder) MapRange(m Expr) Expr {
+	if debugInstr {
+		log.Printf("MapRange %v\n", m.impl)
+	}
+	prog := b.prog
+	it := b.alloca(prog.Type(tyMapIter))
+	it.Type = prog.Type(tyUnsafePtr)
+	fn := b.rtFunc("MapIterInit", []types.Type{tyUnsafePtr, tyUnsafePtr}, nil)
+	b.Call(fn, m, it)
+	return it
+}
+
+// MapNext advances the iterator it of a range loop over a map of type t (see
+// MapRange), and returns the tuple (ok, k, v) of the next entry, where ok is
+// false if there is none:
+//
+//	ok, k, v := next it  =>  ok := runtime.MapIterNext(it, &k, &v)
+func (b Builder) MapNext(it Expr, t Type) Expr {
+	if debugInstr {
+		log.Printf("MapNext %v\n", it.impl)
+	}
+	prog := b.prog
+	mt := t.t.Underlying().(*types.Map)
+	key, elem := prog.Type(mt.Key()), prog.Type(mt.Elem())
+	kptr, vptr := b.alloca(key), b.alloca(elem)
+	tptr := prog.Type(tyUnsafePtr)
+	fn := b.rtFunc("MapIterNext", []types.Type{tyUnsafePtr, tyUnsafePtr, tyUnsafePtr}, []types.Type{tyBool})
+	ok := b.Call(fn, it, Expr{kptr.impl, tptr}, Expr{vptr.impl, tptr})
+	tret := prog.Type(newTuple(tyBool, key.t, elem.t))
+	return b.aggregateValue(tret, ok.impl, b.Load(kptr).impl, b.Load(vptr).impl)
+}
+
+// mapLen returns len(m) of the map m.
+func (b Builder) mapLen(m Expr) Expr {
+	fn := b.rtFunc("MapLen", []types.Type{tyUnsafePtr}, []types.Type{tyInt})
+	return b.Call(fn, m)
+}
+
+// mapDelete deletes key from the map m.
+func (b Builder) mapDelete(m, key Expr) {
+	fn := b.rtFunc("MapDelete", []types.Type{tyUnsafePtr, tyUnsafePtr}, nil)
+	b.Call(fn, m, b.addrOf(key))
+}
+
+// mapClear deletes all the entries of the map m.
+func (b Builder) mapClear(m Expr) {
+	fn := b.rtFunc("MapClear", []types.Type{tyUnsafePtr}, nil)
+	b.Call(fn, m)
+}
+
+// addrOf stores x in a temporary variable, and returns its address as an
+// unsafe.Pointer.
+func (b Builder) addrOf(x Expr) Expr {
+	ptr := b.alloca(x.Type)
+	b.impl.CreateStore(x.impl, ptr.impl)
+	ptr.Type = b.prog.Type(tyUnsafePtr)
+	return ptr
+}
+
+// uintptrSizeof returns the size of type t in bytes as a uintptr constant.
+func (b Builder) uintptrSizeof(t Type) Expr {
+	prog := b.prog
+	return prog.IntVal(prog.td.TypeAllocSize(t.ll), prog.Type(tyUintptr))
+}
+
+// -----------------------------------------------------------------------------
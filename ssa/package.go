@@ -19,6 +19,7 @@ package ssa
 import (
 	"go/constant"
 	"go/types"
+	"strings"
 
 	"github.com/goplus/llvm"
 	"golang.org/x/tools/go/types/typeutil"
@@ -92,48 +93,244 @@ func Initialize(flags InitFlags) {
 
 type aProgram struct {
 	ctx  llvm.Context
-	typs typeutil.Map
-
-	target *Target
-	td     llvm.TargetData
-	// tm  llvm.TargetMachine
-
-	intType   llvm.Type
-	int1Type  llvm.Type
-	int8Type  llvm.Type
-	int16Type llvm.Type
-	int32Type llvm.Type
-	int64Type llvm.Type
-	voidType  llvm.Type
-	voidPtrTy llvm.Type
+	typs typeutil.Map // types.Type => Type
+	sigs typeutil.Map // *types.Signature => Type of LLVM function type
+
+	target    *Target
+	td        llvm.TargetData
+	tm        llvm.TargetMachine
+	triple    string // empty if target isn't specified
+	gc        string // GC strategy of functions, see SetGC
+	sanitizer string // sanitizer of functions, see SetSanitizer
+	hardening bool   // functions are hardened, see SetHardening
+	lto       bool   // constant strings are shared by packages, see SetLTO
+
+	intType    llvm.Type
+	int1Type   llvm.Type
+	int8Type   llvm.Type
+	int16Type  llvm.Type
+	int32Type  llvm.Type
+	int64Type  llvm.Type
+	c64Type    llvm.Type
+	c128Type   llvm.Type
+	stringType llvm.Type
+	sliceType  llvm.Type
+	ifaceType  llvm.Type
+	voidType   llvm.Type
+	voidPtrTy  llvm.Type
+	descType   llvm.Type // see TypeDesc
 
 	voidTy Type
 	boolTy Type
 	intTy  Type
+	f32Ty  Type
 	f64Ty  Type
 }
 
 // A Program presents a program.
 type Program = *aProgram
 
-// NewProgram creates a new program.
+// NewProgram creates a new program. If target isn't nil, the data layout of
+// the program is the one of target, and its packages are tagged with the
+// target triple, so the targets that target refers to must have been
+// initialized (see Initialize). Otherwise the default data layout of LLVM is
+// used, and packages are compiled for the host.
 func NewProgram(target *Target) Program {
-	if target == nil {
-		target = &Target{}
-	}
 	ctx := llvm.NewContext()
 	ctx.Finalize()
-	td := llvm.NewTargetData("") // TODO(xsw): target config
-	return &aProgram{ctx: ctx, target: target, td: td}
+	if target == nil {
+		td := llvm.NewTargetData("")
+		return &aProgram{ctx: ctx, target: &Target{}, td: td}
+	}
+	p := &aProgram{ctx: ctx, target: target}
+	p.td = p.targetMachine().CreateTargetData()
+	p.triple = target.Spec().Triple
+	return p
+}
+
+// SetGC sets the LLVM GC strategy of the functions that the program defines,
+// eg. "statepoint-example". With a strategy, Go pointers are in address space
+// 1, which LLVM treats as references managed by a collector, so that the
+// rewrite-statepoints-for-gc pass (see Package.Optimize) can record the stack
+// slots of those that are live at each call in stack maps. Frame pointers are
+// kept to let the collector walk the stack, and all variables are allocated
+// on the heap, as stack maps don't describe the pointers that stack memory
+// holds. SetGC must be called before any type or package is created.
+func (p Program) SetGC(strategy string) {
+	p.gc = strategy
+}
+
+// Sanitizers, see Program.SetSanitizer.
+const (
+	SanitizeAddress = "address" // AddressSanitizer: out-of-bounds accesses, use after free, etc.
+	SanitizeThread  = "thread"  // ThreadSanitizer: data races
+	SanitizeMemory  = "memory"  // MemorySanitizer: reads of uninitialized memory
+)
+
+// SetSanitizer marks the functions that the program defines to be
+// instrumented by the sanitizer s, eg. SanitizeAddress, when their modules
+// are compiled by clang with -fsanitize=s. It must be called before any
+// function is defined.
+func (p Program) SetSanitizer(s string) {
+	p.sanitizer = s
+}
+
+// SetHardening hardens the functions that the program defines against the
+// exploitation of memory errors: with stack protectors, which abort a function
+// whose buffers on the stack overflow before it returns, and with the
+// control-flow integrity that the hardware enforces where the target has it,
+// CET on x86 and BTI and pointer authentication on arm64. It must be called
+// before any package is created.
+func (p Program) SetHardening(on bool) {
+	p.hardening = on
+}
+
+// SetLTO makes the globals of the constant strings of the packages of the
+// program shared by all of them when they are linked, with LTO, which merges
+// the globals of the same name (see constBytes). It must be called before
+// any package is created.
+func (p Program) SetLTO(lto bool) {
+	p.lto = lto
+}
+
+// ptrAddrSpace returns the address space of Go pointers.
+func (p Program) ptrAddrSpace() int {
+	if p.gc != "" {
+		return 1
+	}
+	return 0
 }
 
 // NewPackage creates a new package.
 func (p Program) NewPackage(name, pkgPath string) Package {
 	mod := p.ctx.NewModule(pkgPath)
 	mod.Finalize()
+	if p.triple != "" {
+		mod.SetTarget(p.triple)
+		mod.SetDataLayout(p.td.String())
+	}
 	fns := make(map[string]Function)
 	gbls := make(map[string]Global)
-	return &aPackage{mod, fns, gbls, p}
+	descs := make(map[string]llvm.Value)
+	consts := make(map[llvm.Value]llvm.Value)
+	locals := make(map[*types.TypeName]int)
+	ret := &aPackage{mod: mod, fns: fns, vars: gbls, descs: descs, locals: locals, consts: consts, prog: p}
+	switch p.target.RelocModel {
+	case RelocPIE:
+		ret.addModuleFlag(moduleFlagMax, "PIE Level", 2)
+		fallthrough
+	case RelocPIC:
+		ret.addModuleFlag(moduleFlagMax, "PIC Level", 2)
+	}
+	if p.hardening {
+		ret.addCFIFlags()
+	}
+	return ret
+}
+
+// addCFIFlags adds the module flags of the control-flow integrity of the
+// target of the package, if it has one, as clang does with -fcf-protection=full
+// and -mbranch-protection=standard.
+func (p Package) addCFIFlags() {
+	triple := p.prog.target.Spec().Triple
+	switch {
+	case strings.HasPrefix(triple, "x86_64-") || strings.HasPrefix(triple, "i386-"):
+		// Indirect branch tracking, and shadow stacks.
+		p.addModuleFlag(moduleFlagOverride, "cf-protection-branch", 1)
+		p.addModuleFlag(moduleFlagOverride, "cf-protection-return", 1)
+	case strings.HasPrefix(triple, "aarch64-") || strings.HasPrefix(triple, "arm64-"):
+		// Branch target identification, and the return addresses of
+		// non-leaf functions signed with the key A.
+		p.addModuleFlag(moduleFlagMin, "branch-target-enforcement", 1)
+		p.addModuleFlag(moduleFlagMin, "sign-return-address", 1)
+		p.addModuleFlag(moduleFlagMin, "sign-return-address-all", 0)
+		p.addModuleFlag(moduleFlagMin, "sign-return-address-with-bkey", 0)
+	}
+}
+
+// Behaviors of module flags when modules are linked, see addModuleFlag.
+const (
+	moduleFlagWarning  = 2 // warn if the values of the modules differ
+	moduleFlagOverride = 4 // take the value of the module that has the flag
+	moduleFlagMax      = 7 // take the largest value
+	moduleFlagMin      = 8 // take the smallest value
+)
+
+// addModuleFlag adds the module flag name, whose value is val, to the
+// package. behavior specifies how its values are merged when modules are
+// linked.
+func (p Package) addModuleFlag(behavior int, name string, val uint64) {
+	ctx := p.prog.ctx
+	i32 := ctx.Int32Type()
+	p.mod.AddNamedMetadataOperand("llvm.module.flags", ctx.MDNode([]llvm.Metadata{
+		llvm.ConstInt(i32, uint64(behavior), false).ConstantAsMetadata(),
+		ctx.MDString(name),
+		llvm.ConstInt(i32, val, false).ConstantAsMetadata(),
+	}))
+}
+
+// TypeSizes returns the sizes of Go types computed from the target data
+// layout. Use it to type-check Go packages so that unsafe.Sizeof, Alignof
+// and Offsetof fold to the same values that the generated code assumes.
+func (p Program) TypeSizes() types.Sizes {
+	wordSize := int64(p.td.PointerSize())
+	maxAlign := int64(p.td.ABITypeAlignment(p.tyInt64()))
+	if align := int64(p.td.ABITypeAlignment(p.ctx.DoubleType())); align > maxAlign {
+		maxAlign = align
+	}
+	return &typeSizes{types.StdSizes{WordSize: wordSize, MaxAlign: maxAlign}}
+}
+
+// typeSizes are the sizes of types.StdSizes, but for func values, which are
+// closures of two words (see MakeClosure), unlike the C function pointers of
+// C packages (see IsCFuncType). The sizes of arrays and structs are computed
+// from the ones of their elements and fields, as with types.StdSizes.
+type typeSizes struct {
+	types.StdSizes
+}
+
+func (s *typeSizes) Sizeof(t types.Type) int64 {
+	switch u := t.Underlying().(type) {
+	case *types.Signature:
+		if !IsCFuncType(t) {
+			return 2 * s.WordSize
+		}
+	case *types.Array:
+		n := u.Len()
+		if n <= 0 {
+			return 0
+		}
+		size := s.Sizeof(u.Elem())
+		return alignTo(size, s.Alignof(u.Elem()))*(n-1) + size
+	case *types.Struct:
+		n := u.NumFields()
+		if n == 0 {
+			return 0
+		}
+		fields := make([]*types.Var, n)
+		for i := range fields {
+			fields[i] = u.Field(i)
+		}
+		offsets := s.Offsetsof(fields)
+		return offsets[n-1] + s.Sizeof(fields[n-1].Type())
+	}
+	return s.StdSizes.Sizeof(t)
+}
+
+func (s *typeSizes) Offsetsof(fields []*types.Var) []int64 {
+	offsets := make([]int64, len(fields))
+	var off int64
+	for i, f := range fields {
+		off = alignTo(off, s.Alignof(f.Type()))
+		offsets[i] = off
+		off += s.Sizeof(f.Type())
+	}
+	return offsets
+}
+
+// alignTo returns x rounded up to a multiple of a.
+func alignTo(x, a int64) int64 {
+	return (x + a - 1) / a * a
 }
 
 // Void returns void type.
@@ -160,6 +357,14 @@ func (p Program) Int() Type {
 	return p.intTy
 }
 
+// Float32 returns float32 type.
+func (p Program) Float32() Type {
+	if p.f32Ty == nil {
+		p.f32Ty = p.Type(types.Typ[types.Float32])
+	}
+	return p.f32Ty
+}
+
 // Float64 returns float64 type.
 func (p Program) Float64() Type {
 	if p.f64Ty == nil {
@@ -183,6 +388,19 @@ type aPackage struct {
 	fns  map[string]Function
 	vars map[string]Global
 	prog Program
+	dbg  *aDebugInfo // nil if debug information is disabled
+
+	descs   map[string]llvm.Value     // type descriptors, see TypeDesc
+	locals  map[*types.TypeName]int   // numbers of types declared in functions, see writeTypeKey
+	consts  map[llvm.Value]llvm.Value // globals of constant data, see constGlobal
+	reflect bool                      // see SetReflect
+	funcs   []llvm.Value              // entries of the function table, see AddFuncInfo
+	cflags  []string                  // see AddCFlags
+	ldflags []string                  // see AddLDFlags
+	pkgcfgs []string                  // see AddPkgConfig
+
+	mfunc       func(sel *types.Selection) Function // see SetMethodFunc
+	needRuntime bool
 }
 
 type Package = *aPackage
@@ -195,7 +413,21 @@ func (p Package) NewConst(name string, val constant.Value) NamedConst {
 func (p Package) NewVar(name string, typ types.Type) Global {
 	t := p.prog.Type(typ)
 	gbl := llvm.AddGlobal(p.mod, t.ll, name)
-	ret := &aGlobal{Expr{gbl, t}}
+	ret := &aGlobal{Expr{p.prog.constPtr(gbl, t), t}, gbl}
+	p.vars[name] = ret
+	return ret
+}
+
+// NewZeroVar creates a new global variable whose address is of type typ,
+// and whose value, unlike the one of NewVar, is of the element type of typ,
+// initialized to its zero value: as it is zero-initialized, the variable is
+// placed in the .bss section, which takes no space in the object file.
+func (p Package) NewZeroVar(name string, typ types.Type) Global {
+	t := p.prog.Type(typ)
+	elem := p.prog.Elem(t)
+	gbl := llvm.AddGlobal(p.mod, elem.ll, name)
+	gbl.SetInitializer(llvm.ConstNull(elem.ll))
+	ret := &aGlobal{Expr{p.prog.constPtr(gbl, t), t}, gbl}
 	p.vars[name] = ret
 	return ret
 }
@@ -204,7 +436,7 @@ func (p Package) NewVar(name string, typ types.Type) Global {
 func (p Package) NewFunc(name string, sig *types.Signature) Function {
 	t := p.prog.llvmSignature(sig)
 	fn := llvm.AddFunction(p.mod, name, t.ll)
-	ret := newFunction(fn, t, p.prog)
+	ret := newFunction(fn, t, p, p.prog)
 	p.fns[name] = ret
 	return ret
 }
@@ -219,6 +451,57 @@ func (p Package) VarOf(name string) Global {
 	return p.vars[name]
 }
 
+// NeedRuntime reports whether the package calls functions of the llgo
+// runtime (see PkgRuntime), so the runtime must be linked with it.
+func (p Package) NeedRuntime() bool {
+	return p.needRuntime
+}
+
+// AddCFlags adds flags, eg. -I dir, to the ones that the C files of the
+// package are compiled with.
+func (p Package) AddCFlags(flags ...string) {
+	p.cflags = append(p.cflags, flags...)
+}
+
+// CFlags returns the flags added by AddCFlags.
+func (p Package) CFlags() []string {
+	return p.cflags
+}
+
+// AddLDFlags adds flags, eg. -lsqlite3, to the ones that the programs which
+// link the package are linked with.
+func (p Package) AddLDFlags(flags ...string) {
+	p.ldflags = append(p.ldflags, flags...)
+}
+
+// LDFlags returns the flags added by AddLDFlags.
+func (p Package) LDFlags() []string {
+	return p.ldflags
+}
+
+// AddPkgConfig adds the C libraries pkgs, whose flags of compiling and linking
+// are reported by pkg-config when the package is built, as the ones of
+// AddCFlags and AddLDFlags.
+func (p Package) AddPkgConfig(pkgs ...string) {
+	p.pkgcfgs = append(p.pkgcfgs, pkgs...)
+}
+
+// PkgConfig returns the C libraries added by AddPkgConfig.
+func (p Package) PkgConfig() []string {
+	return p.pkgcfgs
+}
+
+// Optimize runs the LLVM passes of pipeline, eg. "default<O2>" or
+// "function(instcombine)", on the package by the new pass manager. See the
+// documentation of opt for the syntax of pipelines. If the program has no
+// target, the passes are tuned for the host, so the native target must have
+// been initialized (see Initialize).
+func (p Package) Optimize(pipeline string) error {
+	opts := llvm.NewPassBuilderOptions()
+	defer opts.Dispose()
+	return p.mod.RunPasses(pipeline, p.prog.targetMachine(), opts)
+}
+
 // -----------------------------------------------------------------------------
 
 // String returns a string representation of the package.
@@ -226,7 +509,7 @@ func (p Package) String() string {
 	return p.mod.String()
 }
 
-/*
+// CodeGenFileType is the kind of the code that CodeGen generates.
 type CodeGenFileType = llvm.CodeGenFileType
 
 const (
@@ -234,7 +517,9 @@ const (
 	ObjectFile   = llvm.ObjectFile
 )
 
-func (p *Package) CodeGen(ft CodeGenFileType) (ret []byte, err error) {
+// CodeGen generates the assembly or the object code of the package for the
+// target of its program, eg. the PTX of kernels (see Function.SetKernel).
+func (p Package) CodeGen(ft CodeGenFileType) (ret []byte, err error) {
 	buf, err := p.prog.targetMachine().EmitToMemoryBuffer(p.mod, ft)
 	if err != nil {
 		return
@@ -244,6 +529,7 @@ func (p *Package) CodeGen(ft CodeGenFileType) (ret []byte, err error) {
 	return
 }
 
+/*
 func (p *Package) Bitcode() []byte {
 	buf := llvm.WriteBitcodeToMemoryBuffer(p.mod)
 	ret := buf.Bytes()
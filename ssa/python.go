@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// cFunc returns the C function fn, declaring it in the current package if it
+// isn't declared yet. params and results specify its Go signature.
+func (b Builder) cFunc(fn string, params, results []types.Type) Expr {
+	pkg := b.fn.pkg
+	if f := pkg.FuncOf(fn); f != nil {
+		return f.Expr
+	}
+	vars := make([]*types.Var, len(params))
+	for i, t := range params {
+		if t == nil {
+			vars[i] = VArg()
+		} else {
+			vars[i] = types.NewParam(0, nil, "", t)
+		}
+	}
+	sig := types.NewSignatureType(nil, nil, nil, types.NewTuple(vars...), newTuple(results...), false)
+	return pkg.NewCFunc(fn, sig).Expr
+}
+
+// PyCall calls the function name of the Python module mod with args, which
+// are Python objects, and returns its result, a new reference to a Python
+// object of type ret, or nil if the call raised an exception. It calls the
+// CPython C API, which initializes the interpreter if it isn't initialized:
+//
+//	math.Sqrt(x)  =>  Py_Initialize()
+//	                  t1 = PyImport_ImportModule("math")
+//	                  t2 = PyObject_GetAttrString(t1, "sqrt")
+//	                  Py_DecRef(t1)
+//	                  t3 = PyObject_CallFunctionObjArgs(t2, x, NULL)
+//	                  Py_DecRef(t2)
+//
+// The calls of the functions of the packages that declare Python modules are
+// compiled to it (see the py package).
+func (b Builder) PyCall(mod, name string, ret Type, args ...Expr) Expr {
+	if debugInstr {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "PyCall %s.%s", mod, name)
+		for _, arg := range args {
+			fmt.Fprint(&b, ", ", arg.impl)
+		}
+		log.Println(b.String())
+	}
+	prog := b.prog
+	obj := []types.Type{tyUnsafePtr}
+	cstr := types.NewPointer(types.Typ[types.Int8])
+	b.Call(b.cFunc("Py_Initialize", nil, nil))
+	m := b.Call(b.cFunc("PyImport_ImportModule", []types.Type{cstr}, obj), b.CStr(mod))
+	fn := b.Call(b.cFunc("PyObject_GetAttrString", []types.Type{tyUnsafePtr, cstr}, obj), m, b.CStr(name))
+	decRef := b.cFunc("Py_DecRef", obj, nil)
+	b.Call(decRef, m)
+	vals := make([]Expr, 0, len(args)+2)
+	vals = append(vals, fn)
+	for _, arg := range args {
+		vals = append(vals, b.pyObject(arg))
+	}
+	vals = append(vals, prog.Null(prog.Type(tyUnsafePtr)))
+	r := b.Call(b.cFunc("PyObject_CallFunctionObjArgs", []types.Type{tyUnsafePtr, nil}, obj), vals...)
+	b.Call(decRef, fn)
+	return Expr{b.impl.CreatePointerCast(r.impl, ret.ll, ""), ret}
+}
+
+// pyObject returns the pointer to a Python object x as an unsafe.Pointer.
+func (b Builder) pyObject(x Expr) Expr {
+	t := b.prog.Type(tyUnsafePtr)
+	if x.ll.TypeKind() != llvm.PointerTypeKind {
+		panic("PyCall: argument isn't a Python object")
+	}
+	return Expr{b.impl.CreatePointerCast(x.impl, t.ll, ""), t}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+	"log"
+	"strconv"
+)
+
+// -----------------------------------------------------------------------------
+
+// RaceRead reports the load of the value at the pointer ptr to the runtime
+// of ThreadSanitizer, which detects data races, before it happens:
+//
+//	*ptr  =>  __tsan_read8(ptr); load *ptr
+//
+// Values of 1, 2, 4, 8 or 16 bytes are reported by __tsan_read<size>, and
+// other ones by __tsan_read_range. Empty values aren't reported.
+func (b Builder) RaceRead(ptr Expr) {
+	if debugInstr {
+		log.Printf("RaceRead %v\n", ptr.impl)
+	}
+	b.raceAccess("__tsan_read", ptr)
+}
+
+// RaceWrite reports the store of a value at the pointer ptr to the runtime of
+// ThreadSanitizer, before it happens, as RaceRead does for loads.
+func (b Builder) RaceWrite(ptr Expr) {
+	if debugInstr {
+		log.Printf("RaceWrite %v\n", ptr.impl)
+	}
+	b.raceAccess("__tsan_write", ptr)
+}
+
+func (b Builder) raceAccess(fn string, ptr Expr) {
+	prog := b.prog
+	size := prog.td.TypeAllocSize(prog.Elem(ptr.Type).ll)
+	if size == 0 {
+		return
+	}
+	addr := Expr{b.impl.CreatePointerCast(ptr.impl, prog.tyVoidPtr(), ""), prog.Type(tyUnsafePtr)}
+	switch size {
+	case 1, 2, 4, 8, 16:
+		b.Call(b.cFunc(fn+strconv.FormatUint(size, 10), []types.Type{tyUnsafePtr}, nil), addr)
+	default:
+		n := prog.IntVal(size, prog.Type(tyUintptr))
+		b.Call(b.cFunc(fn+"_range", []types.Type{tyUnsafePtr, tyUintptr}, nil), addr, n)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	// PkgRuntime is the package path of the llgo runtime.
+	PkgRuntime = "github.com/goplus/llgo/internal/runtime"
+)
+
+var (
+	tyBool      = types.Typ[types.Bool]
+	tyInt       = types.Typ[types.Int]
+	tyUintptr   = types.Typ[types.Uintptr]
+	tyUnsafePtr = types.Typ[types.UnsafePointer]
+	tyAny       = types.NewInterfaceType(nil, nil)
+)
+
+func newTuple(typs ...types.Type) *types.Tuple {
+	vars := make([]*types.Var, len(typs))
+	for i, t := range typs {
+		vars[i] = types.NewParam(0, nil, "", t)
+	}
+	return types.NewTuple(vars...)
+}
+
+// rtFunc returns the runtime function fn, declaring it in the current package
+// if it isn't declared yet. params and results specify its Go signature, which
+// must be ABI compatible with the Go declaration in the runtime package.
+func (b Builder) rtFunc(fn string, params, results []types.Type) Expr {
+	pkg := b.fn.pkg
+	pkg.needRuntime = true
+	name := PkgRuntime + "." + fn
+	if f := pkg.FuncOf(name); f != nil {
+		return f.Expr
+	}
+	sig := types.NewSignatureType(nil, nil, nil, newTuple(params...), newTuple(results...), false)
+	return pkg.NewFunc(name, sig).Expr
+}
+
+// RuntimeCall calls the runtime function fn, whose Go signature is sig, with
+// args. It is used to compile calls to functions of Go packages that the
+// runtime implements.
+func (b Builder) RuntimeCall(fn string, sig *types.Signature, args ...Expr) Expr {
+	pkg := b.fn.pkg
+	pkg.needRuntime = true
+	name := PkgRuntime + "." + fn
+	f := pkg.FuncOf(name)
+	if f == nil {
+		f = pkg.NewFunc(name, sig)
+	}
+	return b.Call(f.Expr, args...)
+}
+
+// RuntimeVar returns the variable name of the llgo runtime (see PkgRuntime),
+// declaring it in the package if it isn't declared yet. As for NewVar, typ is
+// the type of its address. It is used to compile references to variables of
+// Go packages that the runtime implements.
+func (p Package) RuntimeVar(name string, typ types.Type) Global {
+	p.needRuntime = true
+	name = PkgRuntime + "." + name
+	if ret := p.VarOf(name); ret != nil {
+		return ret
+	}
+	return p.NewVar(name, typ)
+}
+
+// rtVar returns the address of the runtime variable name of type typ,
+// declaring it in the current package if it isn't declared yet.
+func (b Builder) rtVar(name string, typ types.Type) Expr {
+	return b.fn.pkg.RuntimeVar(name, types.NewPointer(typ)).Expr
+}
+
+// alloca allocates a variable of type t in the entry block of the function,
+// so that it is allocated only once even if the current block is in a loop.
+// With a GC strategy, the variable is allocated on the heap instead, by
+// allocZ, each time alloca is executed (see Program.SetGC).
+func (b Builder) alloca(t Type) Expr {
+	if b.prog.gc != "" {
+		return b.allocZ(t)
+	}
+	return Expr{b.entryAlloca(t.ll), b.prog.Pointer(t)}
+}
+
+// entryAlloca allocates a variable of the LLVM type t on the stack, in the
+// entry block of the function so that it is allocated once per call.
+func (b Builder) entryAlloca(t llvm.Type) llvm.Value {
+	entry := b.fn.impl.EntryBasicBlock()
+	tmp := b.prog.ctx.NewBuilder()
+	defer tmp.Dispose()
+	if first := entry.FirstInstruction(); first.IsNil() {
+		tmp.SetInsertPointAtEnd(entry)
+	} else {
+		tmp.SetInsertPointBefore(first)
+	}
+	return llvm.CreateAlloca(tmp, t)
+}
+
+// allocZ allocates a zero-initialized variable of type t on the heap by the
+// runtime, and returns its address. runtime.AllocZ doesn't panic, so it is
+// never invoked (see SetUnwind): the variables allocated before the calls of
+// a function, eg. its named results, are then defined in its landing pad.
+func (b Builder) allocZ(t Type) Expr {
+	prog := b.prog
+	size := prog.IntVal(prog.td.TypeAllocSize(t.ll), prog.Type(tyUintptr))
+	fn := b.rtFunc("AllocZ", []types.Type{tyUintptr}, []types.Type{tyUnsafePtr})
+	unwind := b.unwind
+	b.unwind = nil
+	ret := b.Call(fn, size)
+	b.unwind = unwind
+	ret.Type = prog.Pointer(t)
+	return ret
+}
+
+// sizeof returns the size of type t in bytes as an int constant.
+func (b Builder) sizeof(t Type) Expr {
+	prog := b.prog
+	return prog.IntVal(prog.td.TypeAllocSize(t.ll), prog.Int())
+}
+
+// -----------------------------------------------------------------------------
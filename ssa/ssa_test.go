@@ -17,9 +17,13 @@
 package ssa
 
 import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
 	"go/constant"
 	"go/token"
 	"go/types"
+	"strings"
 	"testing"
 )
 
@@ -192,6 +196,55 @@ _llgo_0:
 `)
 }
 
+func TestCStr(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	params := types.NewTuple(types.NewVar(0, nil, "s", types.NewPointer(types.Typ[types.Int8])))
+	puts := pkg.NewFunc("puts", types.NewSignatureType(nil, nil, nil, params, nil, false))
+	b := pkg.NewFunc("main", types.NewSignatureType(nil, nil, nil, nil, nil, false)).MakeBody(1)
+	b.Call(puts.Expr, b.CStr("Hello"))
+	b.Return()
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+@0 = private unnamed_addr constant [6 x i8] c"Hello\00", align 1
+
+declare void @puts(ptr)
+
+define void @main() {
+_llgo_0:
+  call void @puts(ptr @0)
+  ret void
+}
+`)
+}
+
+func TestConstPool(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetLTO(true)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	params := types.NewTuple(types.NewVar(0, nil, "s", types.NewPointer(types.Typ[types.Int8])))
+	puts := pkg.NewFunc("puts", types.NewSignatureType(nil, nil, nil, params, nil, false))
+	b := pkg.NewFunc("main", types.NewSignatureType(nil, nil, nil, nil, nil, false)).MakeBody(1)
+	b.Call(puts.Expr, b.CStr("Hi"))
+	b.Call(puts.Expr, b.CStr("Hi"))
+	b.Return()
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+@llgo.bytes.56ebfdeba985b005cba44fc2853f1080b4be09fefe37c1f3041dd87c9f1f3b8a = linkonce_odr hidden unnamed_addr constant [3 x i8] c"Hi\00", align 1
+
+declare void @puts(ptr)
+
+define void @main() {
+_llgo_0:
+  call void @puts(ptr @llgo.bytes.56ebfdeba985b005cba44fc2853f1080b4be09fefe37c1f3041dd87c9f1f3b8a)
+  call void @puts(ptr @llgo.bytes.56ebfdeba985b005cba44fc2853f1080b4be09fefe37c1f3041dd87c9f1f3b8a)
+  ret void
+}
+`)
+}
+
 func TestFuncMultiRet(t *testing.T) {
 	prog := NewProgram(nil)
 	pkg := prog.NewPackage("bar", "foo/bar")
@@ -269,6 +322,23 @@ _llgo_2:                                          ; preds = %_llgo_0
 `)
 }
 
+func TestVerify(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	rets := types.NewTuple(types.NewVar(0, nil, "", types.Typ[types.Int]))
+	sig := types.NewSignatureType(nil, nil, nil, nil, rets, false)
+	fn := pkg.NewFunc("fn", sig)
+	fn.MakeBody(1).Return(prog.Val(1))
+	if err := fn.Verify(); err != nil {
+		t.Fatal("Verify:", err)
+	}
+	bad := pkg.NewFunc("bad", sig)
+	bad.MakeBody(1) // no terminator
+	if err := bad.Verify(); err == nil || !strings.Contains(err.Error(), "terminator") {
+		t.Fatal("Verify:", err)
+	}
+}
+
 func TestPrintf(t *testing.T) {
 	prog := NewProgram(nil)
 	pkg := prog.NewPackage("bar", "foo/bar")
@@ -284,6 +354,20 @@ declare i32 @printf(ptr, ...)
 `)
 }
 
+func TestPrintUnsupported(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	params := types.NewTuple(types.NewVar(0, nil, "s", types.NewSlice(types.Typ[types.Int])))
+	fn := pkg.NewFunc("fn", types.NewSignatureType(nil, nil, nil, params, nil, false))
+	b := fn.MakeBody(1)
+	defer func() {
+		if r := recover(); r != "unsupported print of a value of type []int" {
+			t.Fatal("Print:", r)
+		}
+	}()
+	b.Print([]Expr{fn.Param(0)}, true)
+}
+
 func TestBinOp(t *testing.T) {
 	prog := NewProgram(nil)
 	pkg := prog.NewPackage("bar", "foo/bar")
@@ -334,3 +418,429 @@ _llgo_0:
 }
 `)
 }
+
+func TestConvert(t *testing.T) {
+	src := types.NewPackage("bar", "foo/bar")
+	fields := []*types.Var{types.NewField(0, src, "x", types.Typ[types.Int], false)}
+	a := types.NewNamed(types.NewTypeName(0, src, "A", nil), types.NewStruct(fields, nil), nil)
+	b := types.NewNamed(types.NewTypeName(0, src, "B", nil), types.NewStruct(fields, nil), nil)
+
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	params := types.NewTuple(types.NewVar(0, nil, "a", a))
+	rets := types.NewTuple(types.NewVar(0, nil, "", b))
+	fn := pkg.NewFunc("fn", types.NewSignatureType(nil, nil, nil, params, rets, false))
+	bld := fn.MakeBody(1)
+	bld.Return(bld.Convert(prog.Type(b), fn.Param(0), false))
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+%B = type { i64 }
+%A = type { i64 }
+
+define %B @fn(%A %0) {
+_llgo_0:
+  %1 = alloca %A, align 8
+  store %A %0, ptr %1, align 4
+  %2 = load %B, ptr %1, align 4
+  ret %B %2
+}
+`)
+
+	defer func() {
+		if r := recover(); r != "unsupported conversion from bar.A to int" {
+			t.Fatal("Convert:", r)
+		}
+	}()
+	fn2 := pkg.NewFunc("fn2", types.NewSignatureType(nil, nil, nil, params, nil, false))
+	bld = fn2.MakeBody(1)
+	bld.Convert(prog.Int(), fn2.Param(0), false)
+}
+
+func TestTarget(t *testing.T) {
+	prog := NewProgram(&Target{GOOS: "linux", GOARCH: "arm"})
+	pkg := prog.NewPackage("bar", "foo/bar")
+	pkg.NewVar("a", types.Typ[types.Int])
+	if v := pkg.mod.Target(); v != "armv7-unknown-linux-gnueabihf" {
+		t.Fatal("Target:", v)
+	}
+	if v := prog.TypeSizes().Sizeof(types.Typ[types.Int]); v != 4 {
+		t.Fatal("Sizeof(int):", v)
+	}
+	spec := (&Target{GOOS: "darwin", GOARCH: "arm64", CPU: "apple-m1"}).Spec()
+	if spec.Triple != "arm64-apple-macosx11.0.0" || spec.CPU != "apple-m1" || spec.Features != "+neon" {
+		t.Fatal("Spec:", spec)
+	}
+	if spec := (&Target{GOOS: "android", GOARCH: "arm64"}).Spec(); spec.Triple != "aarch64-unknown-linux-android21" {
+		t.Fatal("Spec android:", spec)
+	}
+	if spec := (&Target{GOOS: "ios", GOARCH: "arm64"}).Spec(); spec.Triple != "arm64-apple-ios12.0.0" || spec.Features != "+neon" {
+		t.Fatal("Spec ios:", spec)
+	}
+	if spec := (&Target{GOOS: "linux", GOARCH: "arm", FloatABI: FloatABISoft}).Spec(); spec.Triple != "armv7-unknown-linux-gnueabi" || !strings.HasSuffix(spec.Features, ",+soft-float,-fpregs") {
+		t.Fatal("Spec soft-float:", spec)
+	}
+	if spec := (&Target{Triple: "thumbv7em-unknown-unknown-eabi", FloatABI: FloatABIHard}).Spec(); spec.Triple != "thumbv7em-unknown-unknown-eabihf" {
+		t.Fatal("Spec hard-float:", spec)
+	}
+	for goarch, triple := range map[string]string{
+		"s390x":    "s390x-unknown-linux-gnu",
+		"ppc64":    "powerpc64-unknown-linux-gnu",
+		"ppc64le":  "powerpc64le-unknown-linux-gnu",
+		"mips":     "mips-unknown-linux-gnu",
+		"mipsle":   "mipsel-unknown-linux-gnu",
+		"mips64":   "mips64-unknown-linux-gnuabi64",
+		"mips64le": "mips64el-unknown-linux-gnuabi64",
+	} {
+		if spec := (&Target{GOOS: "linux", GOARCH: goarch}).Spec(); spec.Triple != triple {
+			t.Fatal("Spec "+goarch+":", spec)
+		}
+	}
+}
+
+func TestBigEndian(t *testing.T) {
+	prog := NewProgram(&Target{GOOS: "linux", GOARCH: "s390x"})
+	pkg := prog.NewPackage("bar", "foo/bar")
+	a := pkg.NewVar("a", types.Typ[types.Int64])
+	a.Init(prog.IntVal(0x0102030405060708, prog.Type(types.Typ[types.Int64])))
+	if v := pkg.mod.Target(); v != "s390x-unknown-linux-gnu" {
+		t.Fatal("Target:", v)
+	}
+	if v := pkg.mod.DataLayout(); !strings.HasPrefix(v, "E-") {
+		t.Fatal("DataLayout:", v)
+	}
+	obj, err := pkg.CodeGen(ObjectFile)
+	if err != nil {
+		t.Fatal("CodeGen:", err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(obj))
+	if err != nil {
+		t.Fatal("elf.NewFile:", err)
+	}
+	if f.ByteOrder != binary.BigEndian {
+		t.Fatal("ByteOrder:", f.ByteOrder)
+	}
+	data, err := f.Section(".data").Data()
+	if err != nil || !bytes.Contains(data, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatalf("TestBigEndian: .data is %x, %v", data, err)
+	}
+}
+
+func TestRelocModel(t *testing.T) {
+	prog := NewProgram(&Target{GOOS: "linux", GOARCH: "amd64", RelocModel: RelocPIE})
+	pkg := prog.NewPackage("bar", "foo/bar")
+	ret := pkg.String()
+	for _, s := range []string{`!{i32 7, !"PIE Level", i32 2}`, `!{i32 7, !"PIC Level", i32 2}`} {
+		if !strings.Contains(ret, s) {
+			t.Fatalf("TestRelocModel: %s not found in:\n%s", s, ret)
+		}
+	}
+}
+
+func TestHardening(t *testing.T) {
+	prog := NewProgram(&Target{GOOS: "linux", GOARCH: "amd64"})
+	prog.SetHardening(true)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	pkg.NewFunc("fn", types.NewSignatureType(nil, nil, nil, nil, nil, false)).MakeBody(1).Return()
+	ret := pkg.String()
+	for _, s := range []string{"attributes #0 = { sspstrong }", `!{i32 4, !"cf-protection-branch", i32 1}`, `!{i32 4, !"cf-protection-return", i32 1}`} {
+		if !strings.Contains(ret, s) {
+			t.Fatalf("TestHardening: %s not found in:\n%s", s, ret)
+		}
+	}
+	prog = NewProgram(&Target{GOOS: "linux", GOARCH: "arm64"})
+	prog.SetHardening(true)
+	if ret := prog.NewPackage("bar", "foo/bar").String(); !strings.Contains(ret, `!{i32 8, !"branch-target-enforcement", i32 1}`) {
+		t.Fatalf("TestHardening: branch-target-enforcement not found in:\n%s", ret)
+	}
+}
+
+func TestUnwind(t *testing.T) {
+	for _, target := range []*Target{{GOOS: "wasip1", GOARCH: "wasm"}, {GOOS: "linux", GOARCH: "arm"}, {GOOS: "windows", GOARCH: "amd64"}} {
+		if NewProgram(target).HasUnwinding() {
+			t.Fatalf("TestUnwind: unwinding on %s/%s", target.GOOS, target.GOARCH)
+		}
+	}
+	prog := NewProgram(&Target{GOOS: "linux", GOARCH: "amd64"})
+	if !prog.HasUnwinding() {
+		t.Fatal("TestUnwind: no unwinding on linux/amd64")
+	}
+	pkg := prog.NewPackage("bar", "foo/bar")
+	sig := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+	f := pkg.NewFunc("f", sig)
+	fn := pkg.NewFunc("fn", sig)
+	b := fn.MakeBody(2)
+	lpad := fn.Block(1)
+	b.SetUnwind(lpad)
+	b.Call(f.Expr)
+	b.Return()
+	b.SetBlock(lpad)
+	b.Resume(b.LandingPad())
+	ret := pkg.String()
+	for _, s := range []string{"personality", "invoke void @f()", "to label %_llgo_2 unwind label %_llgo_1", "cleanup", "resume "} {
+		if !strings.Contains(ret, s) {
+			t.Fatalf("TestUnwind: %s not found in:\n%s", s, ret)
+		}
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	params := types.NewTuple(types.NewVar(0, nil, "a", types.Typ[types.Int]))
+	rets := types.NewTuple(types.NewVar(0, nil, "", types.Typ[types.Int]))
+	sig := types.NewSignatureType(nil, nil, nil, params, rets, false)
+	fn := pkg.NewFunc("fn", sig)
+	b := fn.MakeBody(1)
+	b.Return(b.BinOp(token.SUB, b.BinOp(token.ADD, fn.Param(0), prog.Val(1)), prog.Val(1)))
+	if err := pkg.Optimize("function(instcombine)"); err != nil {
+		t.Fatal("Optimize:", err)
+	}
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+define i64 @fn(i64 %0) {
+_llgo_0:
+  ret i64 %0
+}
+`)
+	if err := pkg.Optimize("no-such-pass"); err == nil {
+		t.Fatal("Optimize: no error for an unknown pass")
+	}
+}
+
+func TestGC(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetGC("statepoint-example")
+	pkg := prog.NewPackage("bar", "foo/bar")
+	a := pkg.NewVar("a", types.NewPointer(types.NewPointer(types.Typ[types.Int])))
+	a.Init(prog.Null(a.Type))
+	rets := types.NewTuple(types.NewVar(0, nil, "", types.NewPointer(types.Typ[types.Int])))
+	sig := types.NewSignatureType(nil, nil, nil, nil, rets, false)
+	fn := pkg.NewFunc("fn", sig)
+	b := fn.MakeBody(1)
+	x := b.Alloc(prog.Type(types.NewPointer(types.Typ[types.Int])), false)
+	b.Store(a.Expr, x)
+	b.Return(x)
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+@a = global ptr addrspace(1) null
+
+define ptr addrspace(1) @fn() #0 gc "statepoint-example" {
+_llgo_0:
+  %0 = call ptr addrspace(1) @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 0, ptr addrspace(1) %0, align 4
+  store ptr addrspace(1) %0, ptr addrspace(1) addrspacecast (ptr @a to ptr addrspace(1)), align 8
+  ret ptr addrspace(1) %0
+}
+
+declare ptr addrspace(1) @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+attributes #0 = { "frame-pointer"="all" }
+`)
+}
+
+func TestSanitizer(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetSanitizer(SanitizeAddress)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	fn := pkg.NewFunc("fn", types.NewSignatureType(nil, nil, nil, nil, nil, false))
+	fn.MakeBody(1).Return()
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+; Function Attrs: sanitize_address
+define void @fn() #0 {
+_llgo_0:
+  ret void
+}
+
+attributes #0 = { sanitize_address }
+`)
+}
+
+func TestMakeInterface(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	pkg.SetReflect(true)
+	tyAny := types.NewInterfaceType(nil, nil)
+	ptr := types.NewPointer(types.Typ[types.Int])
+	params := types.NewTuple(types.NewVar(0, nil, "a", types.Typ[types.Int]), types.NewVar(0, nil, "b", ptr))
+	rets := types.NewTuple(types.NewVar(0, nil, "", tyAny), types.NewVar(0, nil, "", tyAny))
+	sig := types.NewSignatureType(nil, nil, nil, params, rets, false)
+	fn := pkg.NewFunc("fn", sig)
+	b := fn.MakeBody(1)
+	x := b.MakeInterface(prog.Type(tyAny), fn.Param(0))
+	y := b.MakeInterface(prog.Type(tyAny), fn.Param(1))
+	b.Return(x, y)
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+@__llgo_type.int = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 2, i32 -1779859874, { ptr, i64 } { ptr @0, i64 3 }, { ptr, i64 } { ptr @0, i64 3 }, { ptr, i64 } zeroinitializer, ptr null, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.int$stub", ptr null } }
+@0 = private unnamed_addr constant [3 x i8] c"int"
+@"__llgo_type.*int" = linkonce_odr constant { i64, i64, i32, { ptr, i64 }, { ptr, i64 }, { ptr, i64 }, ptr, ptr, i64, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, i64, i64 }, { ptr, ptr }, { ptr, ptr } } { i64 8, i64 22, i32 -1446418874, { ptr, i64 } { ptr @1, i64 4 }, { ptr, i64 } zeroinitializer, { ptr, i64 } zeroinitializer, ptr @__llgo_type.int, ptr null, i64 0, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, i64, i64 } zeroinitializer, { ptr, ptr } { ptr @"__llgo_hash.*int$stub", ptr null }, { ptr, ptr } { ptr @"__llgo_equal.*int$stub", ptr null } }
+@1 = private unnamed_addr constant [4 x i8] c"*int"
+
+define { { ptr, ptr }, { ptr, ptr } } @fn(i64 %0, ptr %1) {
+_llgo_0:
+  %2 = call ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64 8)
+  store i64 %0, ptr %2, align 4
+  %3 = insertvalue { ptr, ptr } { ptr @__llgo_type.int, ptr undef }, ptr %2, 1
+  %4 = insertvalue { ptr, ptr } { ptr @"__llgo_type.*int", ptr undef }, ptr %1, 1
+  %mrv = insertvalue { { ptr, ptr }, { ptr, ptr } } poison, { ptr, ptr } %3, 0
+  %mrv1 = insertvalue { { ptr, ptr }, { ptr, ptr } } %mrv, { ptr, ptr } %4, 1
+  ret { { ptr, ptr }, { ptr, ptr } } %mrv1
+}
+
+define linkonce_odr i64 @__llgo_hash.int(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+declare i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr, i64, i64)
+
+define private i64 @"__llgo_hash.int$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @__llgo_hash.int(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @__llgo_equal.int(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+declare i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr, ptr, i64)
+
+define private i1 @"__llgo_equal.int$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @__llgo_equal.int(ptr %1, ptr %2)
+  ret i1 %3
+}
+
+declare ptr @"github.com/goplus/llgo/internal/runtime.AllocZ"(i64)
+
+define linkonce_odr i64 @"__llgo_hash.*int"(ptr %0, i64 %1) {
+_llgo_0:
+  %2 = call i64 @"github.com/goplus/llgo/internal/runtime.Memhash"(ptr %0, i64 8, i64 %1)
+  ret i64 %2
+}
+
+define private i64 @"__llgo_hash.*int$stub"(ptr %0, ptr %1, i64 %2) {
+_llgo_0:
+  %3 = tail call i64 @"__llgo_hash.*int"(ptr %1, i64 %2)
+  ret i64 %3
+}
+
+define linkonce_odr i1 @"__llgo_equal.*int"(ptr %0, ptr %1) {
+_llgo_0:
+  %2 = call i1 @"github.com/goplus/llgo/internal/runtime.Memequal"(ptr %0, ptr %1, i64 8)
+  ret i1 %2
+}
+
+define private i1 @"__llgo_equal.*int$stub"(ptr %0, ptr %1, ptr %2) {
+_llgo_0:
+  %3 = tail call i1 @"__llgo_equal.*int"(ptr %1, ptr %2)
+  ret i1 %3
+}
+`)
+}
+
+func TestTypeDescKey(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	foo := types.NewPackage("foo/bar", "bar")
+	fld := types.NewStruct([]*types.Var{types.NewField(0, foo, "x", types.Typ[types.Byte], false)}, nil)
+	fn := types.NewFunc(0, foo, "f", types.NewSignatureType(nil, nil, nil, nil, nil, false))
+	local := types.NewTypeName(0, foo, "T", nil)
+	types.NewScope(foo.Scope(), 0, 0, "").Insert(local)
+	named := types.NewNamed(local, types.Typ[types.Rune], nil)
+	for _, c := range []struct {
+		typ  types.Type
+		name string
+	}{
+		{types.NewSlice(types.Typ[types.Byte]), `@"__llgo_type.[]uint8" = linkonce_odr constant`},
+		{types.NewSlice(types.Typ[types.Uint8]), `@"__llgo_type.[]uint8" = linkonce_odr constant`},
+		{fld, `@"__llgo_type.struct{foo/bar.x uint8}" = linkonce_odr constant`},
+		{types.NewPointer(named), `@"__llgo_type.*foo/bar.T\C2\B71" = internal constant`},
+		{fn.Type(), `@"__llgo_type.func()" = linkonce_odr constant`},
+	} {
+		pkg.TypeDesc(c.typ)
+		if ir := pkg.String(); !strings.Contains(ir, c.name) {
+			t.Fatalf("TypeDesc(%v): %s not found in:\n%s", c.typ, c.name, ir)
+		}
+	}
+}
+
+func TestCFunc(t *testing.T) {
+	f32, i32, i64 := types.Typ[types.Float32], types.Typ[types.Int32], types.Typ[types.Int64]
+	f64 := types.Typ[types.Float64]
+	newStruct := func(flds ...types.Type) types.Type {
+		vars := make([]*types.Var, len(flds))
+		for i, t := range flds {
+			vars[i] = types.NewField(0, nil, "_", t, false)
+		}
+		return types.NewStruct(vars, nil)
+	}
+	pair := newStruct(i32, i32)
+	vec := newStruct(f32, f32, i32)
+	triple := newStruct(i64, i64, i64)
+	point := newStruct(f64, f64, f64)
+	params := types.NewTuple(
+		types.NewVar(0, nil, "a", pair), types.NewVar(0, nil, "b", vec),
+		types.NewVar(0, nil, "c", triple), types.NewVar(0, nil, "d", point))
+	rets := types.NewTuple(types.NewVar(0, nil, "", triple))
+	sig := types.NewSignatureType(nil, nil, nil, params, rets, false)
+	for goarch, expected := range map[string][]string{
+		"amd64": {
+			"declare void @cfn(ptr sret({ i64, i64, i64 }), i64, { <2 x float>, i32 }, ptr byval({ i64, i64, i64 }), ptr byval({ double, double, double }))",
+			"call void @cfn(ptr sret({ i64, i64, i64 }) %8, i64 %9, { <2 x float>, i32 } %10, ptr byval({ i64, i64, i64 }) %5, ptr byval({ double, double, double }) %4)",
+			"%11 = load { i64, i64, i64 }, ptr %8, align 8",
+		},
+		"arm64": {
+			"declare void @cfn(ptr sret({ i64, i64, i64 }), i64, [2 x i64], ptr, [3 x double])",
+			"call void @cfn(ptr sret({ i64, i64, i64 }) %8, i64 %9, [2 x i64] %10, ptr %5, [3 x double] %11)",
+			"%11 = load [3 x double], ptr %4, align 8",
+		},
+	} {
+		prog := NewProgram(&Target{GOOS: "linux", GOARCH: goarch})
+		pkg := prog.NewPackage("bar", "foo/bar")
+		cfn := pkg.NewCFunc("cfn", sig)
+		fn := pkg.NewFunc("fn", sig)
+		b := fn.MakeBody(1)
+		b.Return(b.Call(cfn.Expr, fn.Param(0), fn.Param(1), fn.Param(2), fn.Param(3)))
+		ir := pkg.String()
+		for _, s := range expected {
+			if !strings.Contains(ir, s) {
+				t.Fatalf("TestCFunc: %s not found in:\n%s", s, ir)
+			}
+		}
+	}
+}
+
+func TestMangle(t *testing.T) {
+	for _, c := range []struct{ pkgPath, name, sym string }{
+		{"main", "main", "main.main"},
+		{"github.com/goplus/llgo/internal/runtime", "AllocZ", "github.com/goplus/llgo/internal/runtime.AllocZ"},
+		{"gopkg.in/yaml.v3", "(*T).M", "gopkg.in/yaml%2ev3.(*T).M"},
+		{"example.com/foo", "F[int, example.com/bar.T]", "example.com/foo.F[int,%20example.com/bar.T]"},
+		{"example.com/foo", "T.M$1", "example.com/foo.T.M$1"},
+	} {
+		sym := Mangle(c.pkgPath, c.name)
+		if sym != c.sym {
+			t.Fatalf("TestMangle: Mangle(%q, %q) = %q, want %q", c.pkgPath, c.name, sym, c.sym)
+		}
+		pkgPath, name, ok := Demangle(sym)
+		if !ok || pkgPath != c.pkgPath || name != c.name {
+			t.Fatalf("TestMangle: Demangle(%q) = %q, %q, %v", sym, pkgPath, name, ok)
+		}
+	}
+	for _, sym := range []string{"printf", ".F", "foo.", "foo%2.F"} {
+		if _, _, ok := Demangle(sym); ok {
+			t.Fatalf("TestMangle: Demangle(%q) succeeded", sym)
+		}
+	}
+}
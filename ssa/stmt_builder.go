@@ -19,6 +19,7 @@ package ssa
 import (
 	"bytes"
 	"fmt"
+	"go/types"
 	"log"
 
 	"github.com/goplus/llvm"
@@ -48,9 +49,10 @@ func (p BasicBlock) Index() int {
 // -----------------------------------------------------------------------------
 
 type aBuilder struct {
-	impl llvm.Builder
-	fn   Function
-	prog Program
+	impl   llvm.Builder
+	fn     Function
+	prog   Program
+	unwind BasicBlock // landing pad of the calls, see SetUnwind
 }
 
 // Builder represents a builder for creating instructions in a function.
@@ -102,7 +104,7 @@ func (b Builder) Jump(jmpb BasicBlock) {
 	b.impl.CreateBr(jmpb.impl)
 }
 
-// If emits an if instruction.
+// If emits an if instruction, or a jump if cond is a constant (see Prune).
 func (b Builder) If(cond Expr, thenb, elseb BasicBlock) {
 	if b.fn != thenb.fn || b.fn != elseb.fn {
 		panic("mismatched function")
@@ -110,7 +112,133 @@ func (b Builder) If(cond Expr, thenb, elseb BasicBlock) {
 	if debugInstr {
 		log.Printf("If %v, _llgo_%v, _llgo_%v\n", cond.impl, thenb.idx, elseb.idx)
 	}
+	if v, ok := constBranch(cond.impl); ok {
+		if v != 0 {
+			b.impl.CreateBr(thenb.impl)
+		} else {
+			b.impl.CreateBr(elseb.impl)
+		}
+		return
+	}
 	b.impl.CreateCondBr(cond.impl, thenb.impl, elseb.impl)
 }
 
+// Switch emits a switch instruction, which jumps to blks[i] if x equals the
+// constant vals[i], which are distinct, or to dflt otherwise. It emits a jump
+// if x is a constant (see Prune).
+func (b Builder) Switch(x Expr, vals []Expr, blks []BasicBlock, dflt BasicBlock) {
+	if debugInstr {
+		log.Printf("Switch %v, _llgo_%v, %d cases\n", x.impl, dflt.idx, len(vals))
+	}
+	if v, ok := constBranch(x.impl); ok {
+		target := dflt
+		for i, val := range vals {
+			if val.impl.ZExtValue() == v {
+				target = blks[i]
+				break
+			}
+		}
+		b.impl.CreateBr(target.impl)
+		return
+	}
+	sw := b.impl.CreateSwitch(x.impl, dflt.impl, len(vals))
+	for i, v := range vals {
+		if blks[i].fn != b.fn {
+			panic("mismatched function")
+		}
+		sw.AddCase(v.impl, blks[i].impl)
+	}
+}
+
+// Trap emits a call to llvm.trap, which aborts the program, followed by an
+// unreachable instruction.
+func (b Builder) Trap() {
+	if debugInstr {
+		log.Println("Trap")
+	}
+	pkg := b.fn.pkg
+	fn := pkg.FuncOf("llvm.trap")
+	if fn == nil {
+		fn = pkg.NewFunc("llvm.trap", types.NewSignatureType(nil, nil, nil, nil, nil, false))
+	}
+	b.Call(fn.Expr)
+	b.impl.CreateUnreachable()
+}
+
+// -----------------------------------------------------------------------------
+
+// Block returns the basic block at the end of which the builder inserts
+// instructions: the block set by SetBlock, or the last of the blocks that the
+// instructions emitted since then have split it in, eg. the one after a bounds
+// check.
+func (b Builder) Block() BasicBlock {
+	cur := b.impl.GetInsertBlock()
+	blks := b.fn.blks
+	for i := len(blks) - 1; i >= 0; i-- {
+		if blks[i].impl == cur {
+			return blks[i]
+		}
+	}
+	panic("block not found")
+}
+
+// -----------------------------------------------------------------------------
+
+// Phi represents a phi node, whose value is the one of its incoming values
+// from the block that branched to its block.
+type Phi struct {
+	Expr
+}
+
+// Phi emits a phi node of type t at the insertion point, which must be at the
+// start of a block or after its other phi nodes. Its incoming values are added
+// by AddIncoming, once they are built.
+func (b Builder) Phi(t Type) Phi {
+	if debugInstr {
+		log.Printf("Phi %v\n", t.ll)
+	}
+	return Phi{Expr{b.impl.CreatePHI(t.ll, ""), t}}
+}
+
+// AddIncoming adds the incoming values vals of the phi node p from the blocks
+// preds, which end the blocks that branch to the block of p: there is one per
+// edge, so that a block that branches twice to the block of p is repeated.
+// The edges that don't exist, eg. because a branch on a constant was folded
+// to a jump (see Prune), are ignored.
+func (p Phi) AddIncoming(preds []BasicBlock, vals []Expr) {
+	blk := p.impl.InstructionParent()
+	var ins []llvm.Value
+	var blks []llvm.BasicBlock
+	edges := make(map[llvm.BasicBlock]int, len(preds))
+	for i, pred := range preds {
+		n, ok := edges[pred.impl]
+		if !ok {
+			n = branchesTo(pred.impl, blk)
+		}
+		if n == 0 {
+			continue
+		}
+		edges[pred.impl] = n - 1
+		ins = append(ins, vals[i].impl)
+		blks = append(blks, pred.impl)
+	}
+	if len(ins) > 0 {
+		p.impl.AddIncoming(ins, blks)
+	}
+}
+
+// branchesTo returns the number of edges from the block blk to the block succ.
+func branchesTo(blk, succ llvm.BasicBlock) (n int) {
+	term := blk.LastInstruction()
+	if term.IsNil() {
+		return 0
+	}
+	for i, nop := 0, term.OperandsCount(); i < nop; i++ {
+		if op := term.Operand(i); !op.IsABasicBlock().IsNil() && op.AsBasicBlock() == succ {
+			n++
+		}
+	}
+	return
+}
+
 // -----------------------------------------------------------------------------
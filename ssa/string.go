@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+var (
+	tyString = types.Typ[types.String]
+	tyBytes  = types.NewSlice(types.Typ[types.Byte])
+)
+
+// Convert converts x to type t. If one of them is a string and the other a
+// []byte:
+//
+//	[]byte(s)  =>  runtime.StringToBytes(s)
+//	string(b)  =>  runtime.BytesToString(b)
+//
+// Other conversions are the ones of numbers and pointers (see convert).
+//
+// If share, the result shares the bytes of x instead of a copy of them, as
+// gc does for the conversions whose result doesn't escape. The caller must
+// ensure that the bytes aren't written while the result is used, ie. that a
+// []byte converted from a string is only read, and that a string converted
+// from a []byte is used before the []byte is written.
+func (b Builder) Convert(t Type, x Expr, share bool) Expr {
+	if debugInstr {
+		log.Printf("Convert %v, %v, %v\n", t.t, x.impl, share)
+	}
+	switch {
+	case x.kind == vkString && t.kind == vkSlice:
+		if share {
+			n := b.impl.CreateExtractValue(x.impl, 1, "")
+			return b.aggregateValue(t, b.dataOf(x, tyUnsafePtr).impl, n, n)
+		}
+		fn := b.rtFunc("StringToBytes", []types.Type{tyString}, []types.Type{tyBytes})
+		ret := b.Call(fn, x)
+		ret.Type = t
+		return ret
+	case x.kind == vkSlice && t.kind == vkString:
+		if share {
+			n := b.impl.CreateExtractValue(x.impl, 1, "")
+			return b.aggregateValue(t, b.dataOf(x, tyUnsafePtr).impl, n)
+		}
+		fn := b.rtFunc("BytesToString", []types.Type{tyBytes}, []types.Type{tyString})
+		ret := b.Call(fn, x)
+		ret.Type = t
+		return ret
+	}
+	return b.convert(t, x)
+}
+
+// stringCmp compares the strings x and y by the predicate op:
+//
+//	x == y  =>  runtime.StringEqual(x, y)
+//	x < y   =>  runtime.StringCompare(x, y) < 0
+func (b Builder) stringCmp(op token.Token, x, y Expr) Expr {
+	prog := b.prog
+	params := []types.Type{tyString, tyString}
+	switch op {
+	case token.EQL, token.NEQ:
+		fn := b.rtFunc("StringEqual", params, []types.Type{tyBool})
+		ret := b.Call(fn, x, y)
+		if op == token.NEQ {
+			ret.impl = b.impl.CreateNot(ret.impl, "")
+		}
+		return ret
+	}
+	fn := b.rtFunc("StringCompare", params, []types.Type{tyInt})
+	ret := b.Call(fn, x, y)
+	pred := intPredOpToLLVM[op-predOpBase]
+	return Expr{llvm.CreateICmp(b.impl, pred, ret.impl, prog.Val(0).impl), prog.Bool()}
+}
+
+// -----------------------------------------------------------------------------
@@ -16,41 +16,152 @@
 
 package ssa
 
+import (
+	"runtime"
+	"strings"
+
+	"github.com/goplus/llvm"
+)
+
 // -----------------------------------------------------------------------------
 
+// A Target specifies the platform for which a program is compiled. The LLVM
+// target is derived from GOOS/GOARCH unless Triple, CPU or Features are set.
 type Target struct {
-	GOOS   string
-	GOARCH string
+	GOOS   string // empty means runtime.GOOS
+	GOARCH string // empty means runtime.GOARCH
 	GOARM  string // "5", "6", "7" (default)
+
+	Triple   string // LLVM target triple (empty means the one of GOOS/GOARCH)
+	CPU      string // target CPU (empty means the default of GOARCH)
+	Features string // target features (empty means the default of GOARCH)
+
+	// FloatABI is the ABI of floating-point numbers of ARM targets:
+	// FloatABIHard, FloatABISoft or FloatABISoftFP (empty means the one of
+	// Triple). It selects the environment of the triple, eg. eabihf for
+	// FloatABIHard, and FloatABISoft the soft-float feature, with which no FPU
+	// instruction is generated, for CPUs that have no FPU.
+	FloatABI string
+
+	// RelocModel is the relocation model of the code generated for the
+	// target: RelocStatic, RelocPIC or RelocPIE (empty means the default of
+	// the target). The modules of packages record it, so that the code that
+	// clang generates from them agrees.
+	RelocModel string
 }
 
-/*
-func (p *Program) targetMachine() llvm.TargetMachine {
+// Relocation models, see Target.RelocModel.
+const (
+	RelocStatic = "static" // code at fixed addresses
+	RelocPIC    = "pic"    // position-independent code, eg. of shared libraries
+	RelocPIE    = "pie"    // position-independent code of executables
+)
+
+// Float ABIs, see Target.FloatABI.
+const (
+	FloatABIHard   = "hard"   // computed by the FPU, passed in its registers
+	FloatABISoft   = "soft"   // computed by library functions, without FPU
+	FloatABISoftFP = "softfp" // computed by the FPU, passed in integer registers
+)
+
+// relocMode returns the LLVM relocation mode of the relocation model of p.
+func (p *Target) relocMode() llvm.RelocMode {
+	switch p.RelocModel {
+	case RelocStatic:
+		return llvm.RelocStatic
+	case RelocPIC, RelocPIE:
+		return llvm.RelocPIC
+	}
+	return llvm.RelocDefault
+}
+
+// goarch returns the GOARCH of the target.
+func (p *Target) goarch() string {
+	if p.GOARCH == "" {
+		return runtime.GOARCH
+	}
+	return p.GOARCH
+}
+
+// goos returns the GOOS of the target.
+func (p *Target) goos() string {
+	if p.GOOS == "" {
+		return runtime.GOOS
+	}
+	return p.GOOS
+}
+
+func (p Program) targetMachine() llvm.TargetMachine {
 	if p.tm.C == nil {
-		spec := p.target.toSpec()
-		target, err := llvm.GetTargetFromTriple(spec.triple)
+		spec := p.target.Spec()
+		target, err := llvm.GetTargetFromTriple(spec.Triple)
 		if err != nil {
 			panic(err)
 		}
 		p.tm = target.CreateTargetMachine(
-			spec.triple,
-			spec.cpu,
-			spec.features,
+			spec.Triple,
+			spec.CPU,
+			spec.Features,
 			llvm.CodeGenLevelDefault,
-			llvm.RelocDefault,
+			p.target.relocMode(),
 			llvm.CodeModelDefault,
 		)
 	}
 	return p.tm
 }
 
-type targetSpec struct {
-	triple   string
-	cpu      string
-	features string
+// A TargetSpec is an LLVM target: a target triple, a CPU and its features.
+type TargetSpec struct {
+	Triple   string
+	CPU      string
+	Features string
 }
 
-func (p *Target) toSpec() (spec targetSpec) {
+// Spec returns the LLVM target of p.
+func (p *Target) Spec() (spec TargetSpec) {
+	spec = p.defaultSpec()
+	if p.Triple != "" {
+		spec.Triple = p.Triple
+	}
+	if p.CPU != "" {
+		spec.CPU = p.CPU
+	}
+	if p.Features != "" {
+		spec.Features = p.Features
+	}
+	if p.FloatABI != "" && IsARM(spec.Triple) {
+		spec.Triple, spec.Features = floatABISpec(p.FloatABI, spec)
+	}
+	return
+}
+
+// IsARM reports whether triple is the one of a 32-bit ARM target, whose
+// architecture is arm* or thumb*.
+func IsARM(triple string) bool {
+	return strings.HasPrefix(triple, "arm") && !strings.HasPrefix(triple, "arm64") ||
+		strings.HasPrefix(triple, "thumb")
+}
+
+// floatABISpec returns the triple and the features of spec with the float ABI
+// abi, which is the one of the environment of the triple: eabihf for the hard
+// one, eabi for the others.
+func floatABISpec(abi string, spec TargetSpec) (triple, features string) {
+	triple = strings.TrimSuffix(spec.Triple, "hf")
+	if abi == FloatABIHard && strings.HasSuffix(triple, "eabi") {
+		triple += "hf"
+	}
+	// The last of the features that are repeated wins.
+	soft := "-soft-float"
+	if abi == FloatABISoft {
+		soft = "+soft-float,-fpregs"
+	}
+	if spec.Features == "" {
+		return triple, soft
+	}
+	return triple, spec.Features + "," + soft
+}
+
+func (p *Target) defaultSpec() (spec TargetSpec) {
 	// Configure based on GOOS/GOARCH environment variables (falling back to
 	// runtime.GOOS/runtime.GOARCH), and generate a LLVM target based on it.
 	var llvmarch string
@@ -80,6 +191,14 @@ func (p *Target) toSpec() (spec targetSpec) {
 		}
 	case "wasm":
 		llvmarch = "wasm32"
+	case "mipsle":
+		llvmarch = "mipsel"
+	case "mips64le":
+		llvmarch = "mips64el"
+	case "ppc64":
+		llvmarch = "powerpc64"
+	case "ppc64le":
+		llvmarch = "powerpc64le"
 	default:
 		llvmarch = goarch
 	}
@@ -97,48 +216,66 @@ func (p *Target) toSpec() (spec targetSpec) {
 			llvmos = "macosx11.0.0"
 		}
 		llvmvendor = "apple"
+	case "ios":
+		llvmos = "ios12.0.0"
+		if llvmarch == "aarch64" {
+			llvmarch = "arm64"
+		}
+		llvmvendor = "apple"
+	case "android":
+		llvmos = "linux"
 	case "wasip1":
 		llvmos = "wasi"
 	}
 	// Target triples (which actually have four components, but are called
 	// triples for historical reasons) have the form:
 	//   arch-vendor-os-environment
-	spec.triple = llvmarch + "-" + llvmvendor + "-" + llvmos
-	if llvmos == "windows" {
-		spec.triple += "-gnu"
+	spec.Triple = llvmarch + "-" + llvmvendor + "-" + llvmos
+	// 21 is the API level of Android 5.0, the first one with 64-bit targets.
+	if goos == "android" && goarch == "arm" {
+		spec.Triple += "-androideabi21"
+	} else if goos == "android" {
+		spec.Triple += "-android21"
+	} else if llvmos == "windows" {
+		spec.Triple += "-gnu"
 	} else if goarch == "arm" {
-		spec.triple += "-gnueabihf"
+		spec.Triple += "-gnueabihf"
+	} else if goos == "linux" && (goarch == "mips64" || goarch == "mips64le") {
+		spec.Triple += "-gnuabi64"
+	} else if goos == "linux" && (goarch == "s390x" || goarch == "ppc64" || goarch == "ppc64le" || goarch == "mips" || goarch == "mipsle") {
+		// The environment of the triples of the cross toolchains of Debian,
+		// eg. s390x-linux-gnu, whose sysroots clang finds.
+		spec.Triple += "-gnu"
 	}
 	switch goarch {
 	case "386":
-		spec.cpu = "pentium4"
-		spec.features = "+cx8,+fxsr,+mmx,+sse,+sse2,+x87"
+		spec.CPU = "pentium4"
+		spec.Features = "+cx8,+fxsr,+mmx,+sse,+sse2,+x87"
 	case "amd64":
-		spec.cpu = "x86-64"
-		spec.features = "+cx8,+fxsr,+mmx,+sse,+sse2,+x87"
+		spec.CPU = "x86-64"
+		spec.Features = "+cx8,+fxsr,+mmx,+sse,+sse2,+x87"
 	case "arm":
-		spec.cpu = "generic"
+		spec.CPU = "generic"
 		switch llvmarch {
 		case "armv5":
-			spec.features = "+armv5t,+strict-align,-aes,-bf16,-d32,-dotprod,-fp-armv8,-fp-armv8d16,-fp-armv8d16sp,-fp-armv8sp,-fp16,-fp16fml,-fp64,-fpregs,-fullfp16,-mve.fp,-neon,-sha2,-thumb-mode,-vfp2,-vfp2sp,-vfp3,-vfp3d16,-vfp3d16sp,-vfp3sp,-vfp4,-vfp4d16,-vfp4d16sp,-vfp4sp"
+			spec.Features = "+armv5t,+strict-align,-aes,-bf16,-d32,-dotprod,-fp-armv8,-fp-armv8d16,-fp-armv8d16sp,-fp-armv8sp,-fp16,-fp16fml,-fp64,-fpregs,-fullfp16,-mve.fp,-neon,-sha2,-thumb-mode,-vfp2,-vfp2sp,-vfp3,-vfp3d16,-vfp3d16sp,-vfp3sp,-vfp4,-vfp4d16,-vfp4d16sp,-vfp4sp"
 		case "armv6":
-			spec.features = "+armv6,+dsp,+fp64,+strict-align,+vfp2,+vfp2sp,-aes,-d32,-fp-armv8,-fp-armv8d16,-fp-armv8d16sp,-fp-armv8sp,-fp16,-fp16fml,-fullfp16,-neon,-sha2,-thumb-mode,-vfp3,-vfp3d16,-vfp3d16sp,-vfp3sp,-vfp4,-vfp4d16,-vfp4d16sp,-vfp4sp"
+			spec.Features = "+armv6,+dsp,+fp64,+strict-align,+vfp2,+vfp2sp,-aes,-d32,-fp-armv8,-fp-armv8d16,-fp-armv8d16sp,-fp-armv8sp,-fp16,-fp16fml,-fullfp16,-neon,-sha2,-thumb-mode,-vfp3,-vfp3d16,-vfp3d16sp,-vfp3sp,-vfp4,-vfp4d16,-vfp4d16sp,-vfp4sp"
 		case "armv7":
-			spec.features = "+armv7-a,+d32,+dsp,+fp64,+neon,+vfp2,+vfp2sp,+vfp3,+vfp3d16,+vfp3d16sp,+vfp3sp,-aes,-fp-armv8,-fp-armv8d16,-fp-armv8d16sp,-fp-armv8sp,-fp16,-fp16fml,-fullfp16,-sha2,-thumb-mode,-vfp4,-vfp4d16,-vfp4d16sp,-vfp4sp"
+			spec.Features = "+armv7-a,+d32,+dsp,+fp64,+neon,+vfp2,+vfp2sp,+vfp3,+vfp3d16,+vfp3d16sp,+vfp3sp,-aes,-fp-armv8,-fp-armv8d16,-fp-armv8d16sp,-fp-armv8sp,-fp16,-fp16fml,-fullfp16,-sha2,-thumb-mode,-vfp4,-vfp4d16,-vfp4d16sp,-vfp4sp"
 		}
 	case "arm64":
-		spec.cpu = "generic"
-		if goos == "darwin" {
-			spec.features = "+neon"
+		spec.CPU = "generic"
+		if goos == "darwin" || goos == "ios" {
+			spec.Features = "+neon"
 		} else { // windows, linux
-			spec.features = "+neon,-fmv"
+			spec.Features = "+neon,-fmv"
 		}
 	case "wasm":
-		spec.cpu = "generic"
-		spec.features = "+bulk-memory,+mutable-globals,+nontrapping-fptoint,+sign-ext"
+		spec.CPU = "generic"
+		spec.Features = "+bulk-memory,+mutable-globals,+nontrapping-fptoint,+sign-ext"
 	}
 	return
 }
-*/
 
 // -----------------------------------------------------------------------------
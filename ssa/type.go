@@ -17,6 +17,7 @@
 package ssa
 
 import (
+	"go/token"
 	"go/types"
 	"log"
 
@@ -36,7 +37,11 @@ const (
 	vkString
 	vkBool
 	vkFunc
+	vkCFunc   // a C function, see NewCFunc
+	vkClosure // a func value, see MakeClosure
+	vkFuncPtr // a C function pointer, see IsCFuncType
 	vkTuple
+	vkSlice
 )
 
 // -----------------------------------------------------------------------------
@@ -58,11 +63,11 @@ func HasVArg(t *types.Tuple, n int) bool {
 }
 
 func indexType(t types.Type) types.Type {
-	switch t := t.(type) {
+	switch t := t.Underlying().(type) {
 	case *types.Slice:
 		return t.Elem()
 	case *types.Pointer:
-		switch t := t.Elem().(type) {
+		switch t := t.Elem().Underlying().(type) {
 		case *types.Array:
 			return t.Elem()
 		}
@@ -87,10 +92,15 @@ func (p Program) Pointer(typ Type) Type {
 }
 
 func (p Program) Elem(typ Type) Type {
-	elem := typ.t.(*types.Pointer).Elem()
+	elem := typ.t.Underlying().(*types.Pointer).Elem()
 	return p.Type(elem)
 }
 
+// Field returns the type of the ith field of struct type typ.
+func (p Program) Field(typ Type, i int) Type {
+	return p.Type(typ.t.Underlying().(*types.Struct).Field(i).Type())
+}
+
 func (p Program) Index(typ Type) Type {
 	return p.Type(indexType(typ.t))
 }
@@ -104,18 +114,55 @@ func (p Program) Type(typ types.Type) Type {
 	return ret
 }
 
+// llvmSignature returns the LLVM function type of sig. Note that Type(sig) is
+// the type of func values, which are closures (see MakeClosure).
 func (p Program) llvmSignature(sig *types.Signature) Type {
-	if v := p.typs.At(sig); v != nil {
+	if v := p.sigs.At(sig); v != nil {
 		return v.(Type)
 	}
 	ret := p.toLLVMFunc(sig)
-	p.typs.Set(sig, ret)
+	p.sigs.Set(sig, ret)
 	return ret
 }
 
+// tyClosure returns the LLVM type of the func values of type sig: a pointer
+// to a function of closureSig(sig), and its context (see MakeClosure).
+func (p Program) tyClosure(sig *types.Signature) llvm.Type {
+	ft := p.llvmSignature(closureSig(sig)).ll
+	return p.ctx.StructType([]llvm.Type{llvm.PointerType(ft, 0), p.tyVoidPtr()}, false)
+}
+
+// closureSig returns the signature of the functions of the func values of
+// type sig, which take the context of the closure before the parameters of
+// sig.
+func closureSig(sig *types.Signature) *types.Signature {
+	params := make([]*types.Var, 0, sig.Params().Len()+1)
+	params = append(params, types.NewParam(token.NoPos, nil, "ctx", tyUnsafePtr))
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, sig.Params().At(i))
+	}
+	return types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), sig.Results(), sig.Variadic())
+}
+
+// IsCFuncType reports whether t is a type of C function pointers: a func type
+// named in a C package, which declares the LLGoPackage constant, eg. the type
+// of the handlers of sigaction. The values of other func types are closures,
+// which C can't call (see MakeClosure).
+func IsCFuncType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	if _, ok = named.Underlying().(*types.Signature); !ok {
+		return false
+	}
+	pkg := named.Obj().Pkg()
+	return pkg != nil && pkg.Scope().Lookup("LLGoPackage") != nil
+}
+
 func (p Program) tyVoidPtr() llvm.Type {
 	if p.voidPtrTy.IsNil() {
-		p.voidPtrTy = llvm.PointerType(p.tyVoid(), 0)
+		p.voidPtrTy = llvm.PointerType(p.tyVoid(), p.ptrAddrSpace())
 	}
 	return p.voidPtrTy
 }
@@ -176,6 +223,51 @@ func (p Program) tyInt64() llvm.Type {
 	return p.int64Type
 }
 
+func (p Program) tyComplex64() llvm.Type {
+	if p.c64Type.IsNil() {
+		ctx := p.ctx
+		f32 := ctx.FloatType()
+		p.c64Type = ctx.StructType([]llvm.Type{f32, f32}, false)
+	}
+	return p.c64Type
+}
+
+func (p Program) tyComplex128() llvm.Type {
+	if p.c128Type.IsNil() {
+		ctx := p.ctx
+		f64 := ctx.DoubleType()
+		p.c128Type = ctx.StructType([]llvm.Type{f64, f64}, false)
+	}
+	return p.c128Type
+}
+
+// A string is represented as {data *byte, len int}.
+func (p Program) tyString() llvm.Type {
+	if p.stringType.IsNil() {
+		p.stringType = p.ctx.StructType([]llvm.Type{p.tyVoidPtr(), p.tyInt()}, false)
+	}
+	return p.stringType
+}
+
+// A slice is represented as {data *T, len int, cap int}.
+func (p Program) tySlice() llvm.Type {
+	if p.sliceType.IsNil() {
+		tyInt := p.tyInt()
+		p.sliceType = p.ctx.StructType([]llvm.Type{p.tyVoidPtr(), tyInt, tyInt}, false)
+	}
+	return p.sliceType
+}
+
+// An interface is represented as {tab *itab, data unsafe.Pointer}, where tab
+// describes the dynamic type of the value.
+func (p Program) tyInterface() llvm.Type {
+	if p.ifaceType.IsNil() {
+		voidPtr := p.tyVoidPtr()
+		p.ifaceType = p.ctx.StructType([]llvm.Type{voidPtr, voidPtr}, false)
+	}
+	return p.ifaceType
+}
+
 func (p Program) toLLVMType(typ types.Type) Type {
 	switch t := typ.(type) {
 	case *types.Basic:
@@ -184,7 +276,7 @@ func (p Program) toLLVMType(typ types.Type) Type {
 			return &aType{p.tyInt(), typ, vkSigned}
 		case types.Uint, types.Uintptr:
 			return &aType{p.tyInt(), typ, vkUnsigned}
-		case types.Bool:
+		case types.Bool, types.UntypedBool: // untyped, eg. the condition of if on a constant
 			return &aType{p.tyInt1(), typ, vkBool}
 		case types.Uint8:
 			return &aType{p.tyInt8(), typ, vkUnsigned}
@@ -207,36 +299,52 @@ func (p Program) toLLVMType(typ types.Type) Type {
 		case types.Float64:
 			return &aType{p.ctx.DoubleType(), typ, vkFloat}
 		case types.Complex64:
+			return &aType{p.tyComplex64(), typ, vkComplex}
 		case types.Complex128:
-		case types.String:
+			return &aType{p.tyComplex128(), typ, vkComplex}
+		case types.String, types.UntypedString: // untyped, eg. a constant indexed by a variable
+			return &aType{p.tyString(), typ, vkString}
 		case types.UnsafePointer:
 			return &aType{p.tyVoidPtr(), typ, vkInvalid}
 		}
 	case *types.Pointer:
 		elem := p.Type(t.Elem())
-		return &aType{llvm.PointerType(elem.ll, 0), typ, vkInvalid}
+		return &aType{llvm.PointerType(elem.ll, p.ptrAddrSpace()), typ, vkInvalid}
 	case *types.Slice:
+		return &aType{p.tySlice(), typ, vkSlice}
 	case *types.Map:
+		return &aType{p.tyVoidPtr(), typ, vkInvalid}
+	case *types.Interface:
+		return &aType{p.tyInterface(), typ, vkInvalid}
 	case *types.Struct:
 		return p.toLLVMStruct(t)
 	case *types.Named:
 		return p.toLLVMNamed(t)
 	case *types.Signature:
-		return p.toLLVMFunc(t)
+		return &aType{p.tyClosure(t), typ, vkClosure}
 	case *types.Array:
 		elem := p.Type(t.Elem())
 		return &aType{llvm.ArrayType(elem.ll, int(t.Len())), typ, vkInvalid}
 	case *types.Chan:
+		return &aType{p.tyVoidPtr(), typ, vkInvalid}
+	case *types.Tuple:
+		return &aType{p.toLLVMTuple(t), typ, vkTuple}
+	}
+	if u := typ.Underlying(); u != typ { // alias types of go1.22+
+		under := p.Type(u)
+		return &aType{under.ll, typ, under.kind}
 	}
 	log.Println("toLLVMType: todo -", typ)
 	panic("todo")
 }
 
-func (p Program) toLLVMNamedStruct(name string, typ *types.Struct) llvm.Type {
-	t := p.ctx.StructCreateNamed(name)
-	fields := p.toLLVMFields(typ)
-	t.StructSetBody(fields, false)
-	return t
+func (p Program) toLLVMNamedStruct(name string, named *types.Named, typ *types.Struct) Type {
+	// Register the struct before converting its fields, so that it can refer
+	// to itself, eg. type node struct { next *node }.
+	ret := &aType{p.ctx.StructCreateNamed(name), named, vkInvalid}
+	p.typs.Set(named, ret)
+	ret.ll.StructSetBody(p.toLLVMFields(typ), false)
+	return ret
 }
 
 func (p Program) toLLVMStruct(typ *types.Struct) Type {
@@ -305,11 +413,20 @@ func (p Program) retType(sig *types.Signature) Type {
 
 func (p Program) toLLVMNamed(typ *types.Named) Type {
 	name := typ.Obj().Name()
-	switch typ := typ.Underlying().(type) {
+	switch t := typ.Underlying().(type) {
 	case *types.Struct:
-		return &aType{p.toLLVMNamedStruct(name, typ), typ, vkInvalid}
+		return p.toLLVMNamedStruct(name, typ, t)
+	case *types.Signature:
+		if IsCFuncType(typ) {
+			ft := p.cFuncOf(t).ft
+			return &aType{llvm.PointerType(ft, 0), typ, vkFuncPtr}
+		}
+		under := p.Type(t)
+		return &aType{under.ll, typ, under.kind}
+	default:
+		under := p.Type(t)
+		return &aType{under.ll, typ, under.kind}
 	}
-	panic("todo")
 }
 
 // -----------------------------------------------------------------------------
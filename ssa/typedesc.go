@@ -0,0 +1,666 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go/token"
+	"go/types"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// A type descriptor describes a Go type at run time: the dynamic type of an
+// interface value is the address of the descriptor of the type (see
+// MakeInterface), which the runtime, and the functions of the reflect package
+// that it implements, read. A descriptor is a constant global named after the
+// canonical string of the type (see writeTypeKey), which every package that uses it
+// emits, with linkonce_odr linkage so that the linker keeps only one of them:
+// the descriptors of identical types are the same in the whole program, so
+// that types can be compared by their addresses. Its layout is the one of
+// runtime.Type:
+//
+//	type Type struct {
+//		Size    uintptr
+//		Kind    uintptr // a reflect.Kind
+//		Hash    uint32  // hash of the canonical string of the type
+//		Str     string  // eg. "[]main.T"
+//		Name    string  // name of named and basic types
+//		PkgPath string  // package path of named types
+//		Elem    *Type   // element type of arrays, chans, maps, pointers and slices
+//		Key     *Type   // key type of maps
+//		Len     uintptr // length of arrays, 1 for variadic func types
+//		Fields  []StructField
+//		Methods []Method // exported methods, sorted by name
+//		In      []*Type  // parameters of func types
+//		Out     []*Type  // results of func types
+//		Hasher  func(key unsafe.Pointer, seed uintptr) uintptr
+//		Equal   func(x, y unsafe.Pointer) bool
+//	}
+//
+//	type StructField struct {
+//		Name     string
+//		Typ      *Type
+//		Offset   uintptr
+//		Embedded bool
+//	}
+//
+//	type Method struct {
+//		Name string
+//		Typ  *Type          // a func type, without the receiver
+//		Ifn  unsafe.Pointer // the method called on the data of an interface
+//		Tfn  unsafe.Pointer // the method called on a value of the type
+//		Call unsafe.Pointer // calls Ifn with its arguments in memory
+//	}
+//
+// Elem, Key, Fields, Methods, In and Out, which refer to other descriptors,
+// are only set if the package is compiled for reflection (see
+// Package.SetReflect), as they increase the size of binaries a lot. All the
+// packages of a program must be compiled with the same setting.
+//
+// The functions of methods are the ones that Package.SetMethodFunc returns:
+// Ifn, whose receiver is a pointer, is Tfn if the type is direct (see
+// isDirect), and the method of the pointer type otherwise. Call, which
+// reflect.Value.Call calls, is __llgo_rcall.F for the func type F of the
+// method (see methodCall). The functions of the methods that aren't compiled
+// as functions, eg. the ones of interfaces, are nil.
+//
+// Hasher and Equal, which hash and compare the values of the type that their
+// parameters point to, are the func values of __llgo_hash.T and
+// __llgo_equal.T (see hashFunc), or nil if the type isn't comparable: the
+// runtime hashes and compares the values of interfaces by the ones of their
+// dynamic types, eg. as the keys of a map[any]V.
+//
+// Hash, the FNV-1a hash of the canonical string, is the same for identical
+// types too, which lets type switches look the dynamic type of an interface
+// up by it (see TypeSwitch).
+
+// Kinds of types, the values of reflect.Kind.
+const (
+	kindInvalid = iota
+	kindBool
+	kindInt
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+	kindUint
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+	kindUintptr
+	kindFloat32
+	kindFloat64
+	kindComplex64
+	kindComplex128
+	kindArray
+	kindChan
+	kindFunc
+	kindInterface
+	kindMap
+	kindPointer
+	kindSlice
+	kindString
+	kindStruct
+	kindUnsafePointer
+)
+
+var basicKinds = [...]uintptr{
+	types.Bool:          kindBool,
+	types.Int:           kindInt,
+	types.Int8:          kindInt8,
+	types.Int16:         kindInt16,
+	types.Int32:         kindInt32,
+	types.Int64:         kindInt64,
+	types.Uint:          kindUint,
+	types.Uint8:         kindUint8,
+	types.Uint16:        kindUint16,
+	types.Uint32:        kindUint32,
+	types.Uint64:        kindUint64,
+	types.Uintptr:       kindUintptr,
+	types.Float32:       kindFloat32,
+	types.Float64:       kindFloat64,
+	types.Complex64:     kindComplex64,
+	types.Complex128:    kindComplex128,
+	types.String:        kindString,
+	types.UnsafePointer: kindUnsafePointer,
+}
+
+func kindOf(t types.Type) uintptr {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		if int(t.Kind()) < len(basicKinds) {
+			return basicKinds[t.Kind()]
+		}
+	case *types.Array:
+		return kindArray
+	case *types.Chan:
+		return kindChan
+	case *types.Signature:
+		return kindFunc
+	case *types.Interface:
+		return kindInterface
+	case *types.Map:
+		return kindMap
+	case *types.Pointer:
+		return kindPointer
+	case *types.Slice:
+		return kindSlice
+	case *types.Struct:
+		return kindStruct
+	}
+	return kindInvalid
+}
+
+// SetReflect sets whether the type descriptors that the package emits are
+// rich enough for reflection (see TypeDesc).
+func (p Package) SetReflect(v bool) {
+	p.reflect = v
+}
+
+// SetMethodFunc sets the function that returns the function of the method
+// sel of a type, whose first parameter is the receiver, for the method tables
+// of type descriptors (see TypeDesc), or nil if the method isn't compiled as
+// a function.
+func (p Package) SetMethodFunc(fn func(sel *types.Selection) Function) {
+	p.mfunc = fn
+}
+
+// TypeDesc returns the address, as an unsafe.Pointer, of the type descriptor
+// of t.
+func (p Package) TypeDesc(t types.Type) Expr {
+	return Expr{p.typeDesc(t), p.prog.Type(tyUnsafePtr)}
+}
+
+func (p Package) typeDesc(t types.Type) llvm.Value {
+	prog := p.prog
+	var key strings.Builder
+	local := p.writeTypeKey(&key, t)
+	name := "__llgo_type." + key.String()
+	g, ok := p.descs[name]
+	if !ok {
+		g = llvm.AddGlobal(p.mod, prog.tyTypeDesc(), name)
+		g.SetGlobalConstant(true)
+		if local {
+			g.SetLinkage(llvm.InternalLinkage)
+		} else {
+			g.SetLinkage(llvm.LinkOnceODRLinkage)
+		}
+		p.descs[name] = g // before its initializer, which may refer to it
+		g.SetInitializer(p.typeDescInit(t, typeHash(key.String())))
+	}
+	return prog.constVoidPtr(g)
+}
+
+// hashOf returns the hash of the type t in its descriptor.
+func (p Package) hashOf(t types.Type) uint32 {
+	var key strings.Builder
+	p.writeTypeKey(&key, t)
+	return typeHash(key.String())
+}
+
+// typeHash returns the hash of the type whose canonical string is key.
+func typeHash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func (p Package) typeDescInit(t types.Type, hash uint32) llvm.Value {
+	prog := p.prog
+	tyInt := prog.tyInt()
+	null := llvm.ConstNull(prog.tyVoidPtr())
+	var name, pkgPath string
+	switch t := t.(type) {
+	case *types.Named:
+		obj := t.Obj()
+		name = obj.Name()
+		if pkg := obj.Pkg(); pkg != nil {
+			pkgPath = pkg.Path()
+		}
+	case *types.Basic:
+		name = t.Name()
+	}
+	str := types.TypeString(t, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+	elem, key, n := null, null, uint64(0)
+	fields := llvm.ConstNull(prog.tySlice())
+	methods := llvm.ConstNull(prog.tySlice())
+	in, out := fields, fields
+	if p.reflect {
+		switch u := t.Underlying().(type) {
+		case *types.Array:
+			elem, n = p.typeDesc(u.Elem()), uint64(u.Len())
+		case *types.Chan:
+			elem = p.typeDesc(u.Elem())
+		case *types.Map:
+			elem, key = p.typeDesc(u.Elem()), p.typeDesc(u.Key())
+		case *types.Pointer:
+			elem = p.typeDesc(u.Elem())
+		case *types.Slice:
+			elem = p.typeDesc(u.Elem())
+		case *types.Signature:
+			in, out = p.typeDescs(u.Params()), p.typeDescs(u.Results())
+			if u.Variadic() {
+				n = 1
+			}
+		case *types.Struct:
+			fields = p.structFields(prog.Type(t), u)
+		}
+		methods = p.methods(t)
+	}
+	hasher := llvm.ConstNull(prog.Type(tyHashFunc).ll)
+	equal := llvm.ConstNull(prog.Type(tyEqualFunc).ll)
+	if types.Comparable(t) {
+		hasher, equal = p.constFuncValue(p.hashFunc(t), tyHashFunc), p.constFuncValue(p.equalFunc(t), tyEqualFunc)
+	}
+	return llvm.ConstStruct([]llvm.Value{
+		llvm.ConstInt(tyInt, prog.td.TypeAllocSize(prog.Type(t).ll), false),
+		llvm.ConstInt(tyInt, uint64(kindOf(t)), false),
+		llvm.ConstInt(prog.tyInt32(), uint64(hash), false),
+		p.constString(str),
+		p.constString(name),
+		p.constString(pkgPath),
+		elem,
+		key,
+		llvm.ConstInt(tyInt, n, false),
+		fields,
+		methods,
+		in,
+		out,
+		hasher,
+		equal,
+	}, false)
+}
+
+// constFuncValue returns the func value of type sig of the function fn of the
+// package, whose context is nil, as a constant (see MakeClosure).
+func (p Package) constFuncValue(fn Expr, sig *types.Signature) llvm.Value {
+	prog := p.prog
+	t := prog.Type(sig)
+	stub := p.closureStub(fn, t, prog.Type(types.NewStruct(nil, nil)), 0)
+	return llvm.ConstStruct([]llvm.Value{
+		llvm.ConstPointerCast(stub.impl, t.ll.StructElementTypes()[0]),
+		llvm.ConstNull(prog.tyVoidPtr()),
+	}, false)
+}
+
+// writeTypeKey writes the canonical string of the type t, which its descriptor
+// is named after, to b: identical types have the same string, whichever
+// package they are written in, and other types don't. Unlike the one of
+// types.TypeString, it qualifies the unexported names of fields and methods
+// by their package paths, spells byte and rune as uint8 and int32, and lists
+// the method sets of interfaces rather than their embedded interfaces. Types
+// declared in functions are numbered in the order in which the package uses
+// them, as they may have the same name: writeTypeKey reports whether t refers
+// to one of them, whose descriptor is local to the package.
+func (p Package) writeTypeKey(b *strings.Builder, t types.Type) (local bool) {
+	switch t := t.(type) {
+	case *types.Basic:
+		b.WriteString(types.Typ[t.Kind()].Name())
+	case *types.Named:
+		obj := t.Obj()
+		if pkg := obj.Pkg(); pkg != nil {
+			b.WriteString(pkg.Path())
+			b.WriteByte('.')
+			if local = obj.Parent() != nil && obj.Parent() != pkg.Scope(); local {
+				idx, ok := p.locals[obj]
+				if !ok {
+					idx = len(p.locals) + 1
+					p.locals[obj] = idx
+				}
+				fmt.Fprintf(b, "%s·%d", obj.Name(), idx)
+			}
+		}
+		if !local {
+			b.WriteString(obj.Name())
+		}
+		if args := t.TypeArgs(); args.Len() > 0 {
+			b.WriteByte('[')
+			for i := 0; i < args.Len(); i++ {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				local = p.writeTypeKey(b, args.At(i)) || local
+			}
+			b.WriteByte(']')
+		}
+	case *types.Pointer:
+		b.WriteByte('*')
+		local = p.writeTypeKey(b, t.Elem())
+	case *types.Slice:
+		b.WriteString("[]")
+		local = p.writeTypeKey(b, t.Elem())
+	case *types.Array:
+		fmt.Fprintf(b, "[%d]", t.Len())
+		local = p.writeTypeKey(b, t.Elem())
+	case *types.Map:
+		b.WriteString("map[")
+		local = p.writeTypeKey(b, t.Key())
+		b.WriteByte(']')
+		local = p.writeTypeKey(b, t.Elem()) || local
+	case *types.Chan:
+		switch t.Dir() {
+		case types.SendRecv:
+			b.WriteString("chan ")
+		case types.SendOnly:
+			b.WriteString("chan<- ")
+		case types.RecvOnly:
+			b.WriteString("<-chan ")
+		}
+		local = p.writeTypeKey(b, t.Elem())
+	case *types.Signature:
+		b.WriteString("func")
+		local = p.writeSigKey(b, t)
+	case *types.Struct:
+		b.WriteString("struct{")
+		for i := 0; i < t.NumFields(); i++ {
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			f := t.Field(i)
+			if f.Embedded() {
+				b.WriteString("embedded ")
+			}
+			writeNameKey(b, f.Pkg(), f.Name())
+			b.WriteByte(' ')
+			local = p.writeTypeKey(b, f.Type()) || local
+			if tag := t.Tag(i); tag != "" {
+				b.WriteByte(' ')
+				b.WriteString(strconv.Quote(tag))
+			}
+		}
+		b.WriteByte('}')
+	case *types.Interface:
+		b.WriteString("interface{")
+		for i := 0; i < t.NumMethods(); i++ { // sorted by Id
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			m := t.Method(i)
+			writeNameKey(b, m.Pkg(), m.Name())
+			local = p.writeSigKey(b, m.Type().(*types.Signature)) || local
+		}
+		b.WriteByte('}')
+	default:
+		b.WriteString(types.TypeString(t, nil))
+	}
+	return
+}
+
+// writeSigKey writes the parameters and the results of sig, as in the
+// canonical string of a func type (see writeTypeKey), to b.
+func (p Package) writeSigKey(b *strings.Builder, sig *types.Signature) (local bool) {
+	writeTuple := func(tuple *types.Tuple, variadic bool) {
+		b.WriteByte('(')
+		for i := 0; i < tuple.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			t := tuple.At(i).Type()
+			if variadic && i == tuple.Len()-1 {
+				b.WriteString("...")
+				t = t.(*types.Slice).Elem()
+			}
+			local = p.writeTypeKey(b, t) || local
+		}
+		b.WriteByte(')')
+	}
+	writeTuple(sig.Params(), sig.Variadic())
+	if sig.Results().Len() > 0 {
+		b.WriteByte(' ')
+		writeTuple(sig.Results(), false)
+	}
+	return
+}
+
+// writeNameKey writes the name of a field or a method to b, qualified by the
+// path of its package pkg if it is unexported.
+func writeNameKey(b *strings.Builder, pkg *types.Package, name string) {
+	if !token.IsExported(name) && pkg != nil {
+		b.WriteString(pkg.Path())
+		b.WriteByte('.')
+	}
+	b.WriteString(name)
+}
+
+// structFields returns the slice of StructFields of the struct type t, whose
+// underlying type is u.
+func (p Package) structFields(t Type, u *types.Struct) llvm.Value {
+	prog := p.prog
+	n := u.NumFields()
+	flds := make([]llvm.Value, n)
+	for i := 0; i < n; i++ {
+		f := u.Field(i)
+		flds[i] = llvm.ConstStruct([]llvm.Value{
+			p.constString(f.Name()),
+			p.typeDesc(f.Type()),
+			llvm.ConstInt(prog.tyInt(), prog.td.ElementOffset(t.ll, i), false),
+			llvm.ConstInt(prog.tyInt1(), boolToUint64(f.Embedded()), false),
+		}, false)
+	}
+	tyField := prog.ctx.StructType([]llvm.Type{prog.tyString(), prog.tyVoidPtr(), prog.tyInt(), prog.tyInt1()}, false)
+	return p.constSlice(tyField, flds)
+}
+
+// methods returns the slice of Methods of the exported methods of t.
+func (p Package) methods(t types.Type) llvm.Value {
+	prog := p.prog
+	mset := types.NewMethodSet(t)
+	var sels []*types.Selection
+	for i := 0; i < mset.Len(); i++ {
+		if sel := mset.At(i); sel.Obj().Exported() {
+			sels = append(sels, sel)
+		}
+	}
+	sort.Slice(sels, func(i, j int) bool {
+		return sels[i].Obj().Name() < sels[j].Obj().Name()
+	})
+	var pset *types.MethodSet // method set of *t, if t isn't direct
+	if !isInterface(t) && !isDirect(prog.Type(t)) {
+		pset = types.NewMethodSet(types.NewPointer(t))
+	}
+	null := llvm.ConstNull(prog.tyVoidPtr())
+	mthds := make([]llvm.Value, len(sels))
+	for i, sel := range sels {
+		sig := sel.Type().(*types.Signature)
+		sig = types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+		ifn, tfn, call := null, p.methodFunc(sel), null
+		if tfn != null {
+			ifn = tfn
+			if pset != nil {
+				ifn = p.methodFunc(pset.Lookup(sel.Obj().Pkg(), sel.Obj().Name()))
+			}
+			if ifn != null {
+				call = prog.constVoidPtr(p.methodCall(sig).impl)
+			}
+		}
+		mthds[i] = llvm.ConstStruct([]llvm.Value{
+			p.constString(sel.Obj().Name()),
+			p.typeDesc(sig),
+			ifn,
+			tfn,
+			call,
+		}, false)
+	}
+	voidPtr := prog.tyVoidPtr()
+	tyMethod := prog.ctx.StructType([]llvm.Type{prog.tyString(), voidPtr, voidPtr, voidPtr, voidPtr}, false)
+	return p.constSlice(tyMethod, mthds)
+}
+
+// methodFunc returns the function of the method sel as an unsafe.Pointer, or
+// nil if it isn't compiled as a Go function (see SetMethodFunc).
+func (p Package) methodFunc(sel *types.Selection) llvm.Value {
+	if p.mfunc != nil {
+		if fn := p.mfunc(sel); fn != nil && fn.kind != vkCFunc {
+			return p.prog.constVoidPtr(fn.impl)
+		}
+	}
+	return llvm.ConstNull(p.prog.tyVoidPtr())
+}
+
+// tyMethodCall is the signature of the functions that call methods for
+// reflect.Value.Call, which the runtime calls as func values: func(ctx, fn,
+// recv, args, results unsafe.Pointer), where args and results point to
+// arrays of pointers.
+var tyMethodCall = types.NewSignatureType(nil, nil, nil, newTuple(tyUnsafePtr, tyUnsafePtr, tyUnsafePtr, tyUnsafePtr, tyUnsafePtr), nil, false)
+
+// methodCall returns __llgo_rcall.F for the func type F, which calls the
+// method fn, of signature F, with the receiver recv, the values that the
+// pointers of args point to, and stores its results at the pointers of
+// results:
+//
+//	__llgo_rcall.func(int) string(ctx, fn, recv, args, results) {
+//		*(*string)(results[0]) = fn(recv, *(*int)(args[0]))
+//	}
+func (p Package) methodCall(sig *types.Signature) Expr {
+	fn, b, ok := p.keyFunc("__llgo_rcall.", sig, tyMethodCall)
+	if ok {
+		return fn.Expr
+	}
+	prog := p.prog
+	voidPtr := prog.tyVoidPtr()
+	slot := func(ptrs Expr, i int) llvm.Value {
+		idx := llvm.ConstInt(prog.tyInt(), uint64(i), false)
+		return llvm.CreateLoad(b.impl, voidPtr, llvm.CreateInBoundsGEP(b.impl, voidPtr, ptrs.impl, []llvm.Value{idx}))
+	}
+	params := make([]*types.Var, 0, sig.Params().Len()+1)
+	params = append(params, types.NewParam(token.NoPos, nil, "recv", tyUnsafePtr))
+	args := []Expr{fn.Param(2)}
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		params = append(params, param)
+		t := prog.Type(param.Type())
+		args = append(args, Expr{llvm.CreateLoad(b.impl, t.ll, slot(fn.Param(3), i)), t})
+	}
+	msig := types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), sig.Results(), sig.Variadic())
+	ft := prog.llvmSignature(msig)
+	mfn := Expr{b.impl.CreatePointerCast(fn.Param(1).impl, llvm.PointerType(ft.ll, 0), ""), ft}
+	ret := b.Call(mfn, args...)
+	switch n := sig.Results().Len(); n {
+	case 0:
+	case 1:
+		b.impl.CreateStore(ret.impl, slot(fn.Param(4), 0))
+	default:
+		for i := 0; i < n; i++ {
+			b.impl.CreateStore(b.Extract(ret, i).impl, slot(fn.Param(4), i))
+		}
+	}
+	b.Return()
+	return fn.Expr
+}
+
+// typeDescs returns the slice of the descriptors of the types of tuple.
+func (p Package) typeDescs(tuple *types.Tuple) llvm.Value {
+	descs := make([]llvm.Value, tuple.Len())
+	for i := range descs {
+		descs[i] = p.typeDesc(tuple.At(i).Type())
+	}
+	return p.constSlice(p.prog.tyVoidPtr(), descs)
+}
+
+// constString returns the constant string s, whose bytes are stored in a
+// global of the package (see constBytes).
+func (p Package) constString(s string) llvm.Value {
+	prog := p.prog
+	data := llvm.ConstNull(prog.tyVoidPtr())
+	if s != "" {
+		data = prog.constVoidPtr(p.constBytes(s))
+	}
+	return llvm.ConstStruct([]llvm.Value{data, llvm.ConstInt(prog.tyInt(), uint64(len(s)), false)}, false)
+}
+
+// constBytes returns the global that stores the bytes of s, shared by the
+// constants of the package whose bytes are equal. With Program.SetLTO, it is
+// shared by the packages of the program too: it is named by the hash of s,
+// and the linker keeps one of the globals of the same name.
+func (p Package) constBytes(s string) llvm.Value {
+	g := p.constGlobal(p.prog.ctx.ConstString(s, false))
+	if p.prog.lto && g.Name() == "" {
+		g.SetName(fmt.Sprintf("llgo.bytes.%x", sha256.Sum256([]byte(s))))
+		g.SetLinkage(llvm.LinkOnceODRLinkage)
+		g.SetVisibility(llvm.HiddenVisibility)
+	}
+	return g
+}
+
+// constGlobal returns the private global that stores the constant v, shared
+// by the constants of the package that are equal, which LLVM uniques to the
+// same value.
+func (p Package) constGlobal(v llvm.Value) llvm.Value {
+	g, ok := p.consts[v]
+	if !ok {
+		g = llvm.AddGlobal(p.mod, v.Type(), "")
+		g.SetInitializer(v)
+		g.SetGlobalConstant(true)
+		g.SetLinkage(llvm.PrivateLinkage)
+		g.SetUnnamedAddr(true)
+		p.consts[v] = g
+	}
+	return g
+}
+
+// constSlice returns a constant slice of the constant elements elts of type
+// t, which are stored in a global of the package (see constGlobal).
+func (p Package) constSlice(t llvm.Type, elts []llvm.Value) llvm.Value {
+	prog := p.prog
+	if len(elts) == 0 {
+		return llvm.ConstNull(prog.tySlice())
+	}
+	g := p.constGlobal(llvm.ConstArray(t, elts))
+	n := llvm.ConstInt(prog.tyInt(), uint64(len(elts)), false)
+	return llvm.ConstStruct([]llvm.Value{prog.constVoidPtr(g), n, n}, false)
+}
+
+// tyTypeDesc returns the LLVM type of type descriptors.
+func (p Program) tyTypeDesc() llvm.Type {
+	if p.descType.IsNil() {
+		voidPtr, tyInt, tyString, tySlice := p.tyVoidPtr(), p.tyInt(), p.tyString(), p.tySlice()
+		p.descType = p.ctx.StructType([]llvm.Type{
+			tyInt, tyInt, p.tyInt32(), tyString, tyString, tyString, voidPtr, voidPtr, tyInt, tySlice, tySlice, tySlice, tySlice,
+			p.Type(tyHashFunc).ll, p.Type(tyEqualFunc).ll,
+		}, false)
+	}
+	return p.descType
+}
+
+// constVoidPtr converts the constant pointer v, eg. the address of a global,
+// to an unsafe.Pointer.
+func (p Program) constVoidPtr(v llvm.Value) llvm.Value {
+	if t := p.tyVoidPtr(); v.Type() != t {
+		return llvm.ConstPointerCast(v, t)
+	}
+	return v
+}
+
+func boolToUint64(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// -----------------------------------------------------------------------------
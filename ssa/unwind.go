@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// A panic unwinds the stack as the exceptions of C++ do, by the unwinder of
+// the platform, which finds the frames by their unwind tables: the calls of
+// a function that has deferred calls are invokes, which continue at a landing
+// pad of the function if the callee unwinds (see SetUnwind). The landing pad
+// makes the deferred calls, and then returns from the function if one of
+// them recovered the panic, or else resumes unwinding (see LandingPad and
+// Resume). A call that doesn't unwind costs no more than a plain call, and
+// the landing pads are out of line, so that the functions with deferred calls
+// are inlined as others are.
+
+// personality is the personality function of the landing pads, the one of C,
+// which runs the cleanups that they are.
+const personality = "__gcc_personality_v0"
+
+// HasUnwinding reports whether panics unwind the stack on the target, through
+// the landing pads of the functions with deferred calls (see SetUnwind): on
+// Linux, Android and macOS on amd64 and arm64, whose unwinders, libgcc_s and
+// the one of libSystem, are linked to C programs. Elsewhere, a panic prints
+// its value and exits.
+func (p Program) HasUnwinding() bool {
+	switch p.target.goarch() {
+	case "amd64", "arm64":
+	default:
+		return false
+	}
+	switch p.target.goos() {
+	case "linux", "android", "darwin":
+		return true
+	}
+	return false
+}
+
+// SetUnwind makes the calls that b emits invokes that unwind to the landing
+// pad blk, or plain calls if blk is nil. Calls of the intrinsics of LLVM,
+// which don't unwind, are always plain calls.
+func (b Builder) SetUnwind(blk BasicBlock) {
+	b.unwind = blk
+}
+
+// call calls fn, of the LLVM function type ft, with args: by an invoke if b
+// has a landing pad (see SetUnwind), after which b is positioned at a new
+// block where the function continues.
+func (b Builder) call(ft llvm.Type, fn llvm.Value, args []llvm.Value) llvm.Value {
+	if b.unwind == nil || strings.HasPrefix(fn.Name(), "llvm.") {
+		return llvm.CreateCall(b.impl, ft, fn, args)
+	}
+	next := b.fn.MakeBlocks(1)[0]
+	ret := b.impl.CreateInvoke(ft, fn, args, next.impl, b.unwind.impl, "")
+	b.impl.SetInsertPointAtEnd(next.impl)
+	return ret
+}
+
+// tyLandingPad returns the type of the values of the landing pads: the
+// exception, a pointer to its _Unwind_Exception, and its selector.
+func (p Program) tyLandingPad() llvm.Type {
+	return p.ctx.StructType([]llvm.Type{p.tyVoidPtr(), p.ctx.Int32Type()}, false)
+}
+
+// LandingPad emits the landingpad instruction at the start of the landing
+// pad that b is positioned at (see SetUnwind), a cleanup, which all the
+// exceptions that unwind the function stop at, and returns the exception, an
+// unsafe.Pointer.
+func (b Builder) LandingPad() Expr {
+	if debugInstr {
+		log.Println("LandingPad")
+	}
+	pkg := b.fn.pkg
+	fn := pkg.FuncOf(personality)
+	if fn == nil {
+		fn = pkg.NewFunc(personality, types.NewSignatureType(nil, nil, nil, nil, newTuple(types.Typ[types.Int32]), false))
+	}
+	b.fn.impl.SetPersonality(fn.impl)
+	pad := b.impl.CreateLandingPad(b.prog.tyLandingPad(), 0, "")
+	pad.SetCleanup(true)
+	return Expr{b.impl.CreateExtractValue(pad, 0, ""), b.prog.Type(tyUnsafePtr)}
+}
+
+// Resume resumes unwinding the exception exc of the landing pad that b is in
+// (see LandingPad), to the landing pads of the callers.
+func (b Builder) Resume(exc Expr) {
+	if debugInstr {
+		log.Printf("Resume %v\n", exc.impl)
+	}
+	t := b.prog.tyLandingPad()
+	pad := b.impl.CreateInsertValue(llvm.Undef(t), exc.impl, 0, "")
+	pad = b.impl.CreateInsertValue(pad, llvm.ConstNull(t.StructElementTypes()[1]), 1, "")
+	b.impl.CreateResume(pad)
+}
+
+// Panic panics with x, an empty interface, by runtime.Panic, which unwinds
+// the stack if the target has unwinding (see Program.HasUnwinding).
+func (b Builder) Panic(x Expr) {
+	if debugInstr {
+		log.Printf("Panic %v\n", x.impl)
+	}
+	b.Call(b.rtFunc("Panic", []types.Type{tyAny}, nil), x)
+	b.impl.CreateUnreachable()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+	"strconv"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// Go has no vector types: the operations of vectors take and return arrays of
+// numbers, eg. [4]float32, whose elements are moved to an LLVM vector, eg.
+// <4 x float>, to be computed by vector instructions, and back. LLVM keeps the
+// elements in vector registers between the operations.
+
+// vectorOf returns the LLVM vector of the elements of the array x.
+func (b Builder) vectorOf(x Expr) llvm.Value {
+	t := x.ll
+	n := t.ArrayLength()
+	ret := llvm.Undef(llvm.VectorType(t.ElementType(), n))
+	for i := 0; i < n; i++ {
+		elt := b.impl.CreateExtractValue(x.impl, i, "")
+		ret = b.impl.CreateInsertElement(ret, elt, llvm.ConstInt(b.prog.tyInt32(), uint64(i), false), "")
+	}
+	return ret
+}
+
+// arrayOf returns the array of type t of the elements of the LLVM vector v.
+func (b Builder) arrayOf(v llvm.Value, t Type) Expr {
+	ret := llvm.Undef(t.ll)
+	for i, n := 0, t.ll.ArrayLength(); i < n; i++ {
+		elt := b.impl.CreateExtractElement(v, llvm.ConstInt(b.prog.tyInt32(), uint64(i), false), "")
+		ret = b.impl.CreateInsertValue(ret, elt, i, "")
+	}
+	return Expr{ret, t}
+}
+
+// vectorElem returns the element type of the array type t.
+func (b Builder) vectorElem(t Type) Type {
+	return b.prog.Type(t.t.Underlying().(*types.Array).Elem())
+}
+
+// VectorBinOp returns the array of the elementwise x op y of the arrays of
+// numbers x and y, which is computed by a vector instruction. op is token.ADD,
+// token.SUB or token.MUL.
+func (b Builder) VectorBinOp(op token.Token, x, y Expr) Expr {
+	if debugInstr {
+		log.Printf("VectorBinOp %d, %v, %v\n", op, x.impl, y.impl)
+	}
+	llop := mathOpToLLVM[mathOpIdx(op, b.vectorElem(x.Type).kind)]
+	ret := llvm.CreateBinOp(b.impl, llop, b.vectorOf(x), b.vectorOf(y))
+	return b.arrayOf(ret, x.Type)
+}
+
+// VectorShuffle returns the array of the elements of the concatenation of the
+// arrays x and y at the indexes mask, which is computed by a shufflevector
+// instruction. The indexes must be less than twice the length of x.
+func (b Builder) VectorShuffle(x, y Expr, mask []int) Expr {
+	if debugInstr {
+		log.Printf("VectorShuffle %v, %v, %v\n", x.impl, y.impl, mask)
+	}
+	idxs := make([]llvm.Value, len(mask))
+	for i, idx := range mask {
+		idxs[i] = llvm.ConstInt(b.prog.tyInt32(), uint64(idx), false)
+	}
+	ret := b.impl.CreateShuffleVector(b.vectorOf(x), b.vectorOf(y), llvm.ConstVector(idxs, false), "")
+	return b.arrayOf(ret, x.Type)
+}
+
+// VectorSum returns the sum of the elements of the array of numbers x, which
+// is computed by a vector reduction. Floats are added in order, starting from
+// zero, as a loop does.
+func (b Builder) VectorSum(x Expr) Expr {
+	if debugInstr {
+		log.Printf("VectorSum %v\n", x.impl)
+	}
+	telem := b.vectorElem(x.Type)
+	vec := b.vectorOf(x)
+	suffix := ".v" + strconv.Itoa(x.ll.ArrayLength())
+	var args []llvm.Value
+	var name string
+	if telem.kind == vkFloat {
+		name = "llvm.vector.reduce.fadd" + suffix + floatSuffix(telem.ll)
+		args = []llvm.Value{llvm.ConstFloat(telem.ll, 0), vec}
+	} else {
+		name = "llvm.vector.reduce.add" + suffix + "i" + strconv.Itoa(telem.ll.IntTypeWidth())
+		args = []llvm.Value{vec}
+	}
+	params := make([]llvm.Type, len(args))
+	for i, arg := range args {
+		params[i] = arg.Type()
+	}
+	ft := llvm.FunctionType(telem.ll, params, false)
+	mod := b.fn.pkg.mod
+	fn := mod.NamedFunction(name)
+	if fn.IsNil() {
+		fn = llvm.AddFunction(mod, name, ft)
+	}
+	return Expr{llvm.CreateCall(b.impl, ft, fn, args), telem}
+}
+
+func floatSuffix(t llvm.Type) string {
+	if t.TypeKind() == llvm.FloatTypeKind {
+		return "f32"
+	}
+	return "f64"
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+// -----------------------------------------------------------------------------
+
+// SetWasmImport makes the function p, which has no body, imported by a
+// WebAssembly module from its host as the function name of the module mod,
+// instead of being linked to a function of the module. Its parameters and
+// results must be numbers or pointers, which are passed to the host as is.
+// Other targets ignore it.
+func (p Function) SetWasmImport(mod, name string) {
+	p.impl.AddTargetDependentFunctionAttr("wasm-import-module", mod)
+	p.impl.AddTargetDependentFunctionAttr("wasm-import-name", name)
+}
+
+// SetWasmExport makes the function p exported by a WebAssembly module to its
+// host as name, so that the host can call it. Other targets ignore it.
+func (p Function) SetWasmExport(name string) {
+	p.impl.AddTargetDependentFunctionAttr("wasm-export-name", name)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vector provides vectors of numbers, whose operations llgo compiles
+// to the SIMD instructions of the target, eg. SSE or NEON, instead of to
+// loops: the compiler recognizes the methods of the vector types, and lowers
+// them to LLVM vector instructions and intrinsics. The Go implementations of
+// the methods are only used if a method isn't called directly, eg. as a method
+// value, and by the Go toolchain, so that the package builds with it too.
+//
+// A vector is an array, so that vectors are values that can be indexed, eg.
+//
+//	a := vector.Float32x4{1, 2, 3, 4}
+//	b := a.Mul(a).Add(vector.Float32x4{1, 1, 1, 1})
+//	sum := b.Sum() // 34
+package vector
+
+// Float32x4 is a vector of 4 float32s.
+type Float32x4 [4]float32
+
+// Add returns the sum of a and b, element by element.
+func (a Float32x4) Add(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the difference of a and b, element by element.
+func (a Float32x4) Sub(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the product of a and b, element by element.
+func (a Float32x4) Mul(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Shuffle returns the vector of the elements of a and b, which are indexed
+// from 0 to 7 as the ones of a single vector of a followed by b, at the
+// indexes i0, i1, i2, i3. It is compiled to a single instruction if the indexes are
+// constants.
+func (a Float32x4) Shuffle(b Float32x4, i0, i1, i2, i3 int) (ret Float32x4) {
+	ab := [8]float32{a[0], a[1], a[2], a[3], b[0], b[1], b[2], b[3]}
+	for i, idx := range [4]int{i0, i1, i2, i3} {
+		ret[i] = ab[idx]
+	}
+	return
+}
+
+// Sum returns the sum of the elements of a, which are added in order.
+func (a Float32x4) Sum() (ret float32) {
+	for _, v := range a {
+		ret += v
+	}
+	return
+}
+
+// Float64x2 is a vector of 2 float64s.
+type Float64x2 [2]float64
+
+// Add returns the sum of a and b, element by element.
+func (a Float64x2) Add(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the difference of a and b, element by element.
+func (a Float64x2) Sub(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the product of a and b, element by element.
+func (a Float64x2) Mul(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Shuffle returns the vector of the elements of a and b, which are indexed
+// from 0 to 3 as the ones of a single vector of a followed by b, at the
+// indexes i0, i1. It is compiled to a single instruction if the indexes are
+// constants.
+func (a Float64x2) Shuffle(b Float64x2, i0, i1 int) (ret Float64x2) {
+	ab := [4]float64{a[0], a[1], b[0], b[1]}
+	for i, idx := range [2]int{i0, i1} {
+		ret[i] = ab[idx]
+	}
+	return
+}
+
+// Sum returns the sum of the elements of a, which are added in order.
+func (a Float64x2) Sum() (ret float64) {
+	for _, v := range a {
+		ret += v
+	}
+	return
+}
+
+// Int32x4 is a vector of 4 int32s.
+type Int32x4 [4]int32
+
+// Add returns the sum of a and b, element by element.
+func (a Int32x4) Add(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the difference of a and b, element by element.
+func (a Int32x4) Sub(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the product of a and b, element by element.
+func (a Int32x4) Mul(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Shuffle returns the vector of the elements of a and b, which are indexed
+// from 0 to 7 as the ones of a single vector of a followed by b, at the
+// indexes i0, i1, i2, i3. It is compiled to a single instruction if the indexes are
+// constants.
+func (a Int32x4) Shuffle(b Int32x4, i0, i1, i2, i3 int) (ret Int32x4) {
+	ab := [8]int32{a[0], a[1], a[2], a[3], b[0], b[1], b[2], b[3]}
+	for i, idx := range [4]int{i0, i1, i2, i3} {
+		ret[i] = ab[idx]
+	}
+	return
+}
+
+// Sum returns the sum of the elements of a, which are added in order.
+func (a Int32x4) Sum() (ret int32) {
+	for _, v := range a {
+		ret += v
+	}
+	return
+}
+
+// Int64x2 is a vector of 2 int64s.
+type Int64x2 [2]int64
+
+// Add returns the sum of a and b, element by element.
+func (a Int64x2) Add(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the difference of a and b, element by element.
+func (a Int64x2) Sub(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the product of a and b, element by element.
+func (a Int64x2) Mul(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Shuffle returns the vector of the elements of a and b, which are indexed
+// from 0 to 3 as the ones of a single vector of a followed by b, at the
+// indexes i0, i1. It is compiled to a single instruction if the indexes are
+// constants.
+func (a Int64x2) Shuffle(b Int64x2, i0, i1 int) (ret Int64x2) {
+	ab := [4]int64{a[0], a[1], b[0], b[1]}
+	for i, idx := range [2]int{i0, i1} {
+		ret[i] = ab[idx]
+	}
+	return
+}
+
+// Sum returns the sum of the elements of a, which are added in order.
+func (a Int64x2) Sum() (ret int64) {
+	for _, v := range a {
+		ret += v
+	}
+	return
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clang
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// Node is a node of the AST of a C translation unit, as dumped by clang in
+// JSON, which is the AST that libclang exposes. Only the fields that describe
+// declarations are decoded.
+type Node struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"` // eg. FunctionDecl, RecordDecl, TypedefDecl
+	Name string `json:"name"`
+	Type *Type  `json:"type"`
+
+	IsImplicit         bool   `json:"isImplicit"`
+	StorageClass       string `json:"storageClass"` // static or extern
+	Inline             bool   `json:"inline"`
+	TagUsed            string `json:"tagUsed"` // struct or union, of a RecordDecl
+	CompleteDefinition bool   `json:"completeDefinition"`
+	IsBitfield         bool   `json:"isBitfield"`
+	Value              string `json:"value"` // of a ConstantExpr or a literal
+
+	Decl         *Node `json:"decl"`         // declaration of a RecordType, EnumType or TypedefType
+	OwnedTagDecl *Node `json:"ownedTagDecl"` // declaration of the tag defined by an ElaboratedType
+
+	Loc   *Loc   `json:"loc"`
+	Range *Range `json:"range"`
+
+	Inner []*Node `json:"inner"`
+}
+
+// Loc is a source location. clang omits the file and the line of a location
+// if they are the ones of the location dumped before it (see Positions).
+type Loc struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+
+	// of a location in a macro expansion
+	SpellingLoc  *Loc `json:"spellingLoc"`
+	ExpansionLoc *Loc `json:"expansionLoc"`
+}
+
+// Range is the source range of a Node.
+type Range struct {
+	Begin *Loc `json:"begin"`
+	End   *Loc `json:"end"`
+}
+
+// Type is the type of a Node, as spelled in C.
+type Type struct {
+	QualType          string `json:"qualType"`
+	DesugaredQualType string `json:"desugaredQualType"`
+}
+
+// AST parses the C source src, with args as clang flags, and returns its
+// TranslationUnitDecl.
+func (p *Cmd) AST(src []byte, args ...string) (*Node, error) {
+	args = append(args[:len(args):len(args)], "-fsyntax-only", "-Xclang", "-ast-dump=json", "-x", "c", "-")
+	out, err := p.Output(src, args...)
+	if err != nil {
+		return nil, err
+	}
+	var tu Node
+	if err = json.Unmarshal(out, &tu); err != nil {
+		return nil, err
+	}
+	return &tu, nil
+}
+
+// Position is the file and the line of a declaration.
+type Position struct {
+	File string
+	Line int
+}
+
+// Positions returns the positions of the declarations of the translation
+// unit tu. The position of a declaration in a macro expansion is the one of
+// the expansion.
+func Positions(tu *Node) map[*Node]Position {
+	ret := make(map[*Node]Position)
+	var last Position
+	var loc func(l *Loc)
+	loc = func(l *Loc) {
+		if l == nil {
+			return
+		}
+		loc(l.SpellingLoc)
+		loc(l.ExpansionLoc)
+		if l.File != "" {
+			last.File = l.File
+		}
+		if l.Line != 0 {
+			last.Line = l.Line
+		}
+	}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		loc(n.Loc)
+		ret[n] = last
+		if r := n.Range; r != nil {
+			loc(r.Begin)
+			loc(r.End)
+		}
+		for _, c := range n.Inner {
+			walk(c)
+		}
+	}
+	for _, n := range tu.Inner {
+		walk(n)
+	}
+	return ret
+}
+
+// Macros preprocesses the C source src, with args as clang flags, and returns
+// the bodies of the macros it defines, by name. Function-like macros are
+// omitted.
+func (p *Cmd) Macros(src []byte, args ...string) (map[string]string, error) {
+	args = append(args[:len(args):len(args)], "-E", "-dM", "-x", "c", "-")
+	out, err := p.Output(src, args...)
+	if err != nil {
+		return nil, err
+	}
+	macros := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "#define ") {
+			continue
+		}
+		name, body, _ := strings.Cut(line[len("#define "):], " ")
+		if !strings.Contains(name, "(") {
+			macros[name] = strings.TrimSpace(body)
+		}
+	}
+	return macros, s.Err()
+}
+
+// -----------------------------------------------------------------------------
+
+// Macro is an object-like macro.
+type Macro struct {
+	Name string
+	Body string
+	File string // file that defines the macro, eg. <built-in> for predefined ones
+}
+
+// MacroDefs preprocesses the C source src, with args as clang flags, and
+// returns the macros it defines in order of definition, including the ones
+// that are undefined later. Function-like macros are omitted.
+func (p *Cmd) MacroDefs(src []byte, args ...string) ([]*Macro, error) {
+	args = append(args[:len(args):len(args)], "-E", "-dD", "-x", "c", "-")
+	out, err := p.Output(src, args...)
+	if err != nil {
+		return nil, err
+	}
+	var macros []*Macro
+	file := ""
+	s := bufio.NewScanner(bytes.NewReader(out))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "# ") {
+			// line marker: # linenum "file" flags
+			if i := strings.IndexByte(line, '"'); i > 0 {
+				if j := strings.LastIndexByte(line, '"'); j > i {
+					file = line[i+1 : j]
+				}
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "#define ") {
+			continue
+		}
+		name, body, _ := strings.Cut(line[len("#define "):], " ")
+		if !strings.Contains(name, "(") {
+			macros = append(macros, &Macro{Name: name, Body: strings.TrimSpace(body), File: file})
+		}
+	}
+	return macros, s.Err()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clang
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+)
+
+// -----------------------------------------------------------------------------
+
+// Cmd represents a clang command.
+type Cmd struct {
+	app string
+}
+
+// New creates a new clang command.
+func New(app string) *Cmd {
+	if app == "" {
+		app = "clang"
+	}
+	return &Cmd{app}
+}
+
+// Exec executes a clang command, with its output written to os.Stdout and
+// os.Stderr.
+func (p *Cmd) Exec(args ...string) error {
+	cmd := exec.Command(p.app, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Output executes a clang command with stdin as its standard input, and
+// returns its standard output. Its standard error is written to os.Stderr.
+func (p *Cmd) Output(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(p.app, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+var (
+	targetCPU     = regexp.MustCompile(`"-target-cpu" "([^"]*)"`)
+	targetFeature = regexp.MustCompile(`"-target-feature" "([^"]*)"`)
+)
+
+// HostCPU returns the CPU of the host and its features, eg. "+avx2", which
+// clang detects for -march=native (-mcpu=native if the host isn't x86).
+func (p *Cmd) HostCPU() (cpu string, features []string, err error) {
+	native := "-mcpu=native"
+	if runtime.GOARCH == "amd64" || runtime.GOARCH == "386" {
+		native = "-march=native"
+	}
+	// With -###, clang prints the commands that it would run to stderr.
+	cmd := exec.Command(p.app, "-###", native, "-x", "c", "-c", os.DevNull)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return
+	}
+	if m := targetCPU.FindSubmatch(out); m != nil {
+		cpu = string(m[1])
+	}
+	for _, m := range targetFeature.FindAllSubmatch(out, -1) {
+		features = append(features, string(m[1]))
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
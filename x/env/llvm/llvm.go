@@ -19,21 +19,24 @@ package llvm
 import (
 	"os"
 
+	"github.com/goplus/llgo/x/clang"
 	"github.com/goplus/llgo/x/nm"
 )
 
 type Env struct {
 	root     string
+	binDir   string
 	nmprefix string
 }
 
 func New() *Env {
-	var nmprefix string
+	var binDir, nmprefix string
 	var root = os.Getenv("LLGO_LLVM_ROOT")
 	if root != "" {
-		nmprefix = root + "/bin/llvm-"
+		binDir = root + "/bin/"
+		nmprefix = binDir + "llvm-"
 	}
-	return &Env{root, nmprefix}
+	return &Env{root, binDir, nmprefix}
 }
 
 func (p *Env) Root() string {
@@ -43,3 +46,7 @@ func (p *Env) Root() string {
 func (p *Env) Nm() *nm.Cmd {
 	return nm.New(p.nmprefix + "nm")
 }
+
+func (p *Env) Clang() *clang.Cmd {
+	return clang.New(p.binDir + "clang")
+}
@@ -16,10 +16,43 @@
 
 package gocmd
 
+import (
+	"io"
+)
+
 // -----------------------------------------------------------------------------
 
 type BuildConfig struct {
-	Output string
+	Output       string
+	ForceRebuild bool   // -a: rebuild packages that are already up-to-date
+	Emit         string // -emit: kind of output: exe (default), obj, asm, llvm or bc
+	BuildMode    string // -buildmode: exe (default), c-archive, c-shared or plugin
+	Work         bool   // -work: keep the temporary build directory and print the commands run in it
+	TrimPath     bool   // -trimpath: remove the directories of the source files from the output
+}
+
+type InstallConfig struct {
+	ForceRebuild bool // -a: rebuild packages that are already up-to-date
+}
+
+type RunConfig struct {
+	ForceRebuild bool     // -a: rebuild packages that are already up-to-date
+	Env          []string // environment of the program (nil means the current environment)
+
+	// Stdin, Stdout and Stderr of the program (nil means os.Stdin, os.Stdout
+	// and os.Stderr respectively).
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type TestConfig struct {
+	RunConfig
+	Run     string // -run: run only the tests matching the regular expression
+	Verbose bool   // -v: print the name and status of all tests
+
+	Cover        bool   // -cover: measure the statement coverage of the package
+	CoverProfile string // -coverprofile: write the coverage profile to the file (implies -cover)
 }
 
 // -----------------------------------------------------------------------------